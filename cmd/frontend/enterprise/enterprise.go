@@ -13,6 +13,7 @@ type Services struct {
 	GitHubWebhook                    http.Handler
 	GitLabWebhook                    http.Handler
 	BitbucketServerWebhook           http.Handler
+	BitbucketCloudWebhook            http.Handler
 	NewCodeIntelUploadHandler        NewCodeIntelUploadHandler
 	NewCodeIntelInternalProxyHandler NewCodeIntelInternalProxyHandler
 	AuthzResolver                    graphqlbackend.AuthzResolver
@@ -34,6 +35,7 @@ func DefaultServices() Services {
 		GitHubWebhook:                    makeNotFoundHandler("github webhook"),
 		GitLabWebhook:                    makeNotFoundHandler("gitlab webhook"),
 		BitbucketServerWebhook:           makeNotFoundHandler("bitbucket server webhook"),
+		BitbucketCloudWebhook:            makeNotFoundHandler("bitbucket cloud webhook"),
 		NewCodeIntelUploadHandler:        func(_ bool) http.Handler { return makeNotFoundHandler("code intel upload") },
 		NewCodeIntelInternalProxyHandler: func() http.Handler { return makeNotFoundHandler("code intel internal proxy") },
 		AuthzResolver:                    graphqlbackend.DefaultAuthzResolver,
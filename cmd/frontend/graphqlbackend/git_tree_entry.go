@@ -214,6 +214,8 @@ func reposourceCloneURLToRepoName(ctx context.Context, cloneURL string) (repoNam
 			extsvc.KindBitbucketServer,
 			extsvc.KindAWSCodeCommit,
 			extsvc.KindGitolite,
+			extsvc.KindGitea,
+			extsvc.KindAzureDevOps,
 		},
 		LimitOffset: &db.LimitOffset{
 			Limit: 500, // The number is randomly chosen
@@ -247,6 +249,10 @@ func reposourceCloneURLToRepoName(ctx context.Context, cloneURL string) (repoNam
 				rs = reposource.AWS{AWSCodeCommitConnection: c}
 			case *schema.GitoliteConnection:
 				rs = reposource.Gitolite{GitoliteConnection: c}
+			case *schema.GiteaConnection:
+				rs = reposource.Gitea{GiteaConnection: c}
+			case *schema.AzureDevOpsConnection:
+				rs = reposource.AzureDevOps{AzureDevOpsConnection: c}
 			default:
 				return "", errors.Errorf("unexpected connection type: %T", cfg)
 			}
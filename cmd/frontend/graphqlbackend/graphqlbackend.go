@@ -484,6 +484,11 @@ func (r *NodeResolver) ToSavedSearch() (*savedSearchResolver, bool) {
 	return n, ok
 }
 
+func (r *NodeResolver) ToSearchJob() (*searchJobResolver, bool) {
+	n, ok := r.Node.(*searchJobResolver)
+	return n, ok
+}
+
 func (r *NodeResolver) ToSite() (*siteResolver, bool) {
 	n, ok := r.Node.(*siteResolver)
 	return n, ok
@@ -583,6 +588,8 @@ func (r *schemaResolver) nodeByID(ctx context.Context, id graphql.ID) (Node, err
 		return RegistryExtensionByID(ctx, id)
 	case "SavedSearch":
 		return savedSearchByID(ctx, id)
+	case "SearchJob":
+		return searchJobByID(ctx, id)
 	case "Site":
 		return siteByGQLID(ctx, id)
 	case "LSIFUpload":
@@ -37,6 +37,14 @@ func toJSON(node query.Node) interface{} {
 			}{
 				Concat: jsons,
 			}
+		case query.Near:
+			return struct {
+				Near     []interface{} `json:"near"`
+				Distance int           `json:"distance"`
+			}{
+				Near:     jsons,
+				Distance: n.Distance,
+			}
 		}
 	case query.Parameter:
 		return struct {
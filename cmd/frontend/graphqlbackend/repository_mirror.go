@@ -3,6 +3,7 @@ package graphqlbackend
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 
 	"github.com/graph-gophers/graphql-go"
@@ -172,6 +173,55 @@ func (r *updateQueueResolver) Total() int32 {
 	return int32(r.queue.Total)
 }
 
+func (r *repositoryMirrorInfoResolver) SyncHistory(ctx context.Context) ([]*repositorySyncResolver, error) {
+	// 🚨 SECURITY: The errors of syncs contain the output of git, which may
+	// reveal details of the code host, so only allow site admins to see them.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	info, err := r.gitserverRepoInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*repositorySyncResolver, 0, len(info.SyncHistory))
+	for i := range info.SyncHistory {
+		resolvers = append(resolvers, &repositorySyncResolver{sync: &info.SyncHistory[i]})
+	}
+	return resolvers, nil
+}
+
+type repositorySyncResolver struct {
+	sync *protocol.RepoSync
+}
+
+func (r *repositorySyncResolver) Type() string {
+	return strings.ToUpper(r.sync.Type)
+}
+
+func (r *repositorySyncResolver) StartedAt() DateTime {
+	return DateTime{Time: r.sync.StartedAt}
+}
+
+func (r *repositorySyncResolver) FinishedAt() DateTime {
+	return DateTime{Time: r.sync.FinishedAt}
+}
+
+func (r *repositorySyncResolver) ReceivedObjects() int32 {
+	return int32(r.sync.Objects)
+}
+
+func (r *repositorySyncResolver) ReceivedBytes() float64 {
+	return float64(r.sync.Bytes)
+}
+
+func (r *repositorySyncResolver) Error() *string {
+	if r.sync.Error == "" {
+		return nil
+	}
+	return &r.sync.Error
+}
+
 func (r *schemaResolver) CheckMirrorRepositoryConnection(ctx context.Context, args *struct {
 	Repository *graphql.ID
 	Name       *string
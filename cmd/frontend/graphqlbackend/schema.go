@@ -506,6 +506,27 @@ type Mutation {
     Deletes a saved search
     """
     deleteSavedSearch(id: ID!): EmptyResponse
+    """
+    (experimental) Creates a search job, which runs a search in the background over all repositories
+    matched by the query, without the time and result limits of the search field. The results can be
+    downloaded once the search job has completed.
+    """
+    createSearchJob(
+        """
+        The search query (such as "foo" or "repo:myrepo foo").
+        """
+        query: String!
+        """
+        PatternType controls the search pattern type, if and only if it is not specified in the query string using
+        the patternType: field.
+        """
+        patternType: SearchPatternType = literal
+    ): SearchJob!
+    """
+    (experimental) Deletes a search job and its results. Only the creator of the search job and site
+    admins may delete it.
+    """
+    deleteSearchJob(searchJob: ID!): EmptyResponse!
 
     """
     (experimental) The LSIF API may change substantially in the near future as we
@@ -2210,10 +2231,34 @@ type Query {
         first: Int
     ): Search
     """
+    (experimental) Previews replacing every match of a search query. Returns the resulting changes for each
+    repository revision with matches. Nothing is written to the repositories.
+    """
+    searchReplacePreview(
+        """
+        PatternType controls the search pattern type, if and only if it is not specified in the query string using
+        the patternType: field.
+        """
+        patternType: SearchPatternType
+        """
+        The search query (such as "repo:myrepo foo").
+        """
+        query: String!
+        """
+        The replacement for each match. For regexp patterns it may refer to capture groups (such as "$1" or
+        "${name}"). For structural patterns it is a rewrite template which may refer to holes (such as ":[1]").
+        """
+        replacement: String!
+    ): SearchReplacePreview!
+    """
     All saved searches configured for the current user, merged from all configurations.
     """
     savedSearches: [SavedSearch!]!
     """
+    (experimental) The search jobs created by the current user, the most recently created first.
+    """
+    searchJobs: [SearchJob!]!
+    """
     All repository groups for the current user, merged from all configurations.
     """
     repoGroups: [RepoGroup!]!
@@ -2612,6 +2657,13 @@ type SearchResults {
     """
     timedout: [Repository!]!
     """
+    Repositories searched which are sparse clones (see the gitCloneStrategies site setting). Only
+    the files in their sparse paths were searched.
+    In paginated search requests, this represents the repositories searched for the individual
+    paginated request / input cursor.
+    """
+    sparse: [Repository!]!
+    """
     True if indexed search is enabled but was not available during this search.
     """
     indexUnavailable: Boolean!
@@ -2628,12 +2680,38 @@ type SearchResults {
     """
     dynamicFilters: [SearchFilter!]!
     """
+    The number of results in each group, if the query has an aggregate: field such as aggregate:repo. The
+    groups are ordered by descending count. Null if the query has no aggregate: field.
+    Only the results which were found are counted, see limitHit. Use count: to search for more results.
+    """
+    aggregations: [SearchAggregationGroup!]
+    """
     Pagination information.
     This field is only applcable when the original request was a paginated one.
     """
     pageInfo: PageInfo!
 }
 
+"""
+A group of search results, see SearchResults.aggregations.
+"""
+type SearchAggregationGroup {
+    """
+    The value the results in this group have in common: the repository name for aggregate:repo, the directory
+    for aggregate:dir, the text matched by the capture group for aggregate:capture or the commit author for
+    aggregate:author.
+    """
+    label: String!
+    """
+    The number of matches in this group, or the number of commits for aggregate:author.
+    """
+    count: Int!
+    """
+    The repository of the results in this group for aggregate:repo and aggregate:dir, null otherwise.
+    """
+    repository: Repository
+}
+
 """
 Statistics about search results.
 """
@@ -2713,6 +2791,89 @@ type SearchAlert {
     proposedQueries: [SearchQueryDescription!]
 }
 
+"""
+The state of a search job.
+"""
+enum SearchJobState {
+    """
+    The search job is waiting to be run.
+    """
+    QUEUED
+    """
+    The search job is running.
+    """
+    PROCESSING
+    """
+    The search job has searched all repositories.
+    """
+    COMPLETED
+    """
+    The search job failed.
+    """
+    ERRORED
+}
+
+"""
+(experimental) A search which runs in the background over all repositories matched by its query.
+"""
+type SearchJob implements Node {
+    """
+    The unique ID of the search job.
+    """
+    id: ID!
+    """
+    The search query.
+    """
+    query: String!
+    """
+    The state of the search job.
+    """
+    state: SearchJobState!
+    """
+    The reason the search job failed, if it is errored.
+    """
+    failureMessage: String
+    """
+    The user who created the search job.
+    """
+    creator: User
+    """
+    The date and time the search job was created.
+    """
+    createdAt: DateTime!
+    """
+    The date and time the search job started running, if it has.
+    """
+    startedAt: DateTime
+    """
+    The date and time the search job completed or failed, if it has.
+    """
+    finishedAt: DateTime
+    """
+    The number of repositories the search job searches. It is 0 until the repositories matched by the
+    query have been resolved.
+    """
+    repositoriesTotal: Int!
+    """
+    The number of repositories searched so far.
+    """
+    repositoriesSearched: Int!
+    """
+    The number of results found so far.
+    """
+    resultCount: Int!
+    """
+    Whether the results of some repositories are incomplete, because they hit a result limit, the
+    search timed out or the repository was being cloned.
+    """
+    limitHit: Boolean!
+    """
+    The URL to download the results from as CSV. Append "?format=jsonl" to download them as
+    newline-delimited JSON. Null until the search job has completed.
+    """
+    downloadURL: String
+}
+
 """
 A saved search query, defined in settings.
 """
@@ -2907,11 +3068,15 @@ A specific kind of external service.
 """
 enum ExternalServiceKind {
     AWSCODECOMMIT
+    AZUREDEVOPS
     BITBUCKETCLOUD
     BITBUCKETSERVER
+    GITEA
     GITHUB
     GITLAB
     GITOLITE
+    MERCURIAL
+    PERFORCE
     PHABRICATOR
     OTHER
 }
@@ -3405,6 +3570,56 @@ type MirrorRepositoryInfo {
     The state of this repository in the update queue.
     """
     updateQueue: UpdateQueue
+    """
+    The most recent clones and fetches of the repository from the remote source repository, most
+    recent first. Only site admins can access this field.
+    """
+    syncHistory: [RepositorySync!]!
+}
+
+"""
+A type of sync of a repository from its remote source repository.
+"""
+enum RepositorySyncType {
+    """
+    The repository was cloned.
+    """
+    CLONE
+    """
+    The repository was updated with a fetch.
+    """
+    FETCH
+}
+
+"""
+A clone or fetch of a repository from its remote source repository.
+"""
+type RepositorySync {
+    """
+    Whether the repository was cloned or fetched.
+    """
+    type: RepositorySyncType!
+    """
+    When the sync started.
+    """
+    startedAt: DateTime!
+    """
+    When the sync finished.
+    """
+    finishedAt: DateTime!
+    """
+    The number of objects received from the remote source repository.
+    """
+    receivedObjects: Int!
+    """
+    The number of bytes received from the remote source repository.
+    """
+    receivedBytes: Float!
+    """
+    The error of the sync if it failed, with credentials redacted. It usually includes the output
+    of the failed command.
+    """
+    error: String
 }
 
 """
@@ -3585,6 +3800,25 @@ Either a preview or an actual repository comparison.
 """
 union RepositoryComparisonInterface = RepositoryComparison | PreviewRepositoryComparison
 
+"""
+A preview of replacing every match of a search query.
+"""
+type SearchReplacePreview {
+    """
+    The resulting changes for each repository revision with matches, ordered by repository name.
+    """
+    comparisons: [PreviewRepositoryComparison!]!
+    """
+    Whether some matches were not replaced, because a file or repository hit a match limit.
+    """
+    limitHit: Boolean!
+    """
+    Repositories in which we did not manage to replace all matches in time. Their comparisons only contain
+    the changes computed before the timeout. Trying again usually will work.
+    """
+    timedout: [Repository!]!
+}
+
 """
 A not-yet-committed preview of a diff on a repository.
 """
@@ -5183,6 +5417,12 @@ type FileMatch {
     """
     lineMatches: [LineMatch!]!
     """
+    The full range of each match of a regexp pattern which can match across lines (such as a pattern
+    containing \n or using the (?s) flag). lineMatches then contains one entry per line spanned by a
+    match. It is empty for all other patterns and for results of indexed search.
+    """
+    ranges: [Range!]!
+    """
     Whether or not the limit was hit.
     """
     limitHit: Boolean!
@@ -6132,6 +6372,24 @@ type Site implements SettingsSubject {
         """
         days: Int
     ): MonitoringStatistics!
+    """
+    The most recent git commands run on gitserver on behalf of other services, most recent first. Each gitserver
+    keeps its last 1000 commands, and logs every command. Only site admins may view the audit log.
+    """
+    gitserverExecAuditLog(
+        """
+        Returns the first n records.
+        """
+        first: Int = 100
+        """
+        Include only commands run in this repository.
+        """
+        repository: String
+        """
+        Include only commands run on behalf of this user.
+        """
+        user: ID
+    ): GitserverExecAuditLog!
 }
 
 """
@@ -6556,6 +6814,67 @@ type MonitoringAlert {
     average: Float!
 }
 
+"""
+The most recent git commands run on gitserver on behalf of other services.
+"""
+type GitserverExecAuditLog {
+    """
+    The records of the gitservers which could be reached.
+    """
+    records: [GitserverExecAuditRecord!]!
+    """
+    An error for each gitserver whose records are missing.
+    """
+    errors: [String!]!
+}
+
+"""
+A git command run on gitserver on behalf of another service.
+"""
+type GitserverExecAuditRecord {
+    """
+    When the command was requested.
+    """
+    timestamp: DateTime!
+    """
+    The user on whose behalf the command ran, or null for internal and anonymous requests.
+    """
+    user: User
+    """
+    Whether the command ran on behalf of Sourcegraph itself rather than a user.
+    """
+    internal: Boolean!
+    """
+    The service which requested the command, such as "frontend" or "searcher", as authenticated by its
+    service token. It is empty for requests without a valid service token.
+    """
+    service: String!
+    """
+    The name of the repository the command ran in.
+    """
+    repository: String!
+    """
+    The arguments of git.
+    """
+    arguments: [String!]!
+    """
+    How long the request took in milliseconds.
+    """
+    durationMilliseconds: Int!
+    """
+    The exit status of git, or -1 if it didn't run.
+    """
+    exitStatus: Int!
+    """
+    The number of bytes git wrote to its standard output.
+    """
+    outputBytes: Float!
+    """
+    The status of the request, such as "denied" if gitserverExecAllowlist doesn't allow the command.
+    """
+    status: String!
+}
+
 """
 A list of survey responses
 """
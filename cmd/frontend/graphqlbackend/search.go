@@ -278,6 +278,25 @@ type searchResolver struct {
 	versionContext *string
 	userSettings   *schema.Settings
 
+	// resultLimit overrides the default number of results to search for if
+	// the query does not specify count:. Zero means the default. Searches
+	// with a resultLimit use the maximum timeout, like searches with count:.
+	resultLimit int32
+
+	// maxResultLimit caps the number of results to search for, also if the
+	// query specifies a larger count:. Zero means no cap.
+	maxResultLimit int32
+
+	// sendResults, if non-nil, is called with the results of the search as
+	// they are found instead of returning them, unless the query combines
+	// results (see combinesResults). It may be called concurrently.
+	sendResults func([]SearchResultResolver) error
+
+	// combinesResults is true if the results of several searches are
+	// combined by and/or expressions of the query. Their results have to be
+	// kept until all searches are done.
+	combinesResults bool
+
 	// Cached resolveRepositories results.
 	reposMu  sync.Mutex
 	resolved resolvedRepositories
@@ -302,6 +321,16 @@ const defaultMaxSearchResults = 30
 const maxSearchResultsPerPaginatedRequest = 5000
 
 func (r *searchResolver) maxResults() int32 {
+	n := r.requestedMaxResults()
+	if r.maxResultLimit > 0 && n > r.maxResultLimit {
+		return r.maxResultLimit
+	}
+	return n
+}
+
+// requestedMaxResults returns the number of results to search for before
+// applying maxResultLimit.
+func (r *searchResolver) requestedMaxResults() int32 {
 	if r.pagination != nil {
 		// Paginated search requests always consume an entire result set for a
 		// given repository, so we do not want any limit here. See
@@ -322,6 +351,12 @@ func (r *searchResolver) maxResults() int32 {
 			return int32(n)
 		}
 	}
+	if r.resultLimit > 0 {
+		return r.resultLimit
+	}
+	if aggregate, _ := r.query.StringValue(query.FieldAggregate); aggregate != "" {
+		return defaultMaxAggregatedSearchResults
+	}
 	return defaultMaxSearchResults
 }
 
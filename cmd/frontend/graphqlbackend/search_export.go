@@ -0,0 +1,146 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SearchExportRow is a single row of exported search results. File content
+// matches have a row per matching line, other results a single row.
+type SearchExportRow struct {
+	Repo   string `json:"repo"`
+	Commit string `json:"commit"`
+
+	// Path is empty for repository and commit results.
+	Path string `json:"path"`
+
+	// Line is the 1-based line number of a file content match, and 0
+	// otherwise.
+	Line int32 `json:"line"`
+
+	// Preview is the matching line of a file content match, and the subject
+	// of a commit for commit results.
+	Preview string `json:"preview"`
+}
+
+// ExportSearchResults runs the search described by args and calls onRow with
+// every row of the results as they are found. Unless the query specifies
+// count:, up to resultLimit results are searched instead of the usual page of
+// results. A count: larger than maxResultLimit is reduced to it.
+//
+// onRow is never called concurrently. If it returns an error, the search is
+// canceled and the error is returned.
+//
+// Only searches which can be run as a whole are supported, args must not
+// request pagination.
+func ExportSearchResults(ctx context.Context, args *SearchArgs, resultLimit, maxResultLimit int32, onRow func(*SearchExportRow) error) (limitHit bool, err error) {
+	if args.First != nil || args.After != nil {
+		return false, errors.New("exporting paginated search results is not supported")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		rowErr  error
+		started bool // whether a result has been exported
+	)
+	exportResults := func(results []SearchResultResolver) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, result := range results {
+			if rowErr != nil {
+				return rowErr
+			}
+			started = true
+			if rowErr = exportSearchResult(ctx, result, onRow); rowErr != nil {
+				// Stop searching, for example because the client went
+				// away.
+				cancel()
+			}
+		}
+		return rowErr
+	}
+
+	impl, err := NewSearchImplementer(ctx, args)
+	if err != nil {
+		return false, err
+	}
+	if sr, ok := impl.(*searchResolver); ok {
+		sr.resultLimit = resultLimit
+		sr.maxResultLimit = maxResultLimit
+		sr.sendResults = exportResults
+	}
+
+	results, err := impl.Results(ctx)
+	mu.Lock()
+	exportErr := rowErr
+	mu.Unlock()
+	if exportErr != nil {
+		return false, exportErr
+	}
+	if err != nil {
+		return false, err
+	}
+	if results.alert != nil && !started && len(results.SearchResults) == 0 {
+		return false, searchAlertError(results.alert)
+	}
+
+	// The results of queries which combine the results of several searches
+	// are only known once all searches are done.
+	if err := exportResults(results.SearchResults); err != nil {
+		return false, err
+	}
+	return results.LimitHit(), nil
+}
+
+// searchAlertError returns the alert of a search without results as an error.
+func searchAlertError(alert *searchAlert) error {
+	if alert.description == "" {
+		return &badRequestError{errors.New(alert.title)}
+	}
+	return &badRequestError{fmt.Errorf("%s: %s", alert.title, alert.description)}
+}
+
+func exportSearchResult(ctx context.Context, result SearchResultResolver, onRow func(*SearchExportRow) error) error {
+	switch r := result.(type) {
+	case *RepositoryResolver:
+		return onRow(&SearchExportRow{Repo: r.Name()})
+
+	case *FileMatchResolver:
+		row := SearchExportRow{
+			Repo:   r.Repository().Name(),
+			Commit: string(r.CommitID),
+			Path:   r.path(),
+		}
+		if len(r.LineMatches()) == 0 {
+			// A path match.
+			return onRow(&row)
+		}
+		for _, lm := range r.LineMatches() {
+			row := row
+			row.Line = lm.LineNumber() + 1
+			row.Preview = lm.Preview()
+			if err := onRow(&row); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *commitSearchResultResolver:
+		subject, err := r.commit.Subject(ctx)
+		if err != nil {
+			return err
+		}
+		return onRow(&SearchExportRow{
+			Repo:    r.commit.repoResolver.Name(),
+			Commit:  string(r.commit.OID()),
+			Preview: subject,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/db"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+// maxSearchJobResultsPerRepo is the number of results a search job searches
+// for in each repository, unless the query specifies count:.
+const maxSearchJobResultsPerRepo = 100000
+
+// newSearchJobResolver returns the searchResolver for args, or an error
+// describing why the query can't be run.
+func newSearchJobResolver(ctx context.Context, args *SearchArgs) (*searchResolver, error) {
+	impl, err := NewSearchImplementer(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	sr, ok := impl.(*searchResolver)
+	if !ok {
+		// The query is invalid, the implementer only returns an alert.
+		results, err := impl.Results(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return nil, searchAlertError(results.alert)
+	}
+	return sr, nil
+}
+
+// RunSearchJob runs the search of a search job over all repositories matched
+// by its query and stores the results. Repositories searched by an earlier
+// attempt to run the job are skipped.
+func RunSearchJob(ctx context.Context, job *types.SearchJob) error {
+	// 🚨 SECURITY: Search as the user who created the job, so that the job
+	// only finds results in repositories the user has access to.
+	ctx = actor.WithActor(ctx, actor.FromUser(job.UserID))
+
+	args := &SearchArgs{Version: "V2", Query: job.Query, PatternType: &job.PatternType}
+	sr, err := newSearchJobResolver(ctx, args)
+	if err != nil {
+		return err
+	}
+	resolved, err := sr.resolveRepositories(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "resolving repositories")
+	}
+	repos := make([]*types.SearchJobRepo, 0, len(resolved.repoRevs))
+	for _, rr := range resolved.repoRevs {
+		repos = append(repos, &types.SearchJobRepo{
+			RepoID: rr.Repo.ID,
+			Revs:   strings.TrimPrefix(rr.String(), string(rr.Repo.Name)),
+		})
+	}
+	if err := db.SearchJobs.SetRepos(ctx, job.ID, repos); err != nil {
+		return err
+	}
+
+	unsearched, err := db.SearchJobs.ListUnsearchedRepos(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	for _, repo := range unsearched {
+		results, limitHit, err := searchJobRepo(ctx, args, repo)
+		if err != nil {
+			return errors.Wrapf(err, "searching %s", repo.RepoName)
+		}
+		if err := db.SearchJobs.AddRepoResults(ctx, job.ID, repo, results, limitHit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchJobRepo runs the search described by args in a single repository of a
+// search job. limitHit is true if the results are incomplete.
+func searchJobRepo(ctx context.Context, args *SearchArgs, repo *types.SearchJobRepo) (results []*types.SearchJobResult, limitHit bool, err error) {
+	sr, err := newSearchJobResolver(ctx, args)
+	if err != nil {
+		return nil, false, err
+	}
+	r, err := db.Repos.Get(ctx, repo.RepoID)
+	if err != nil {
+		return nil, false, err
+	}
+	_, revs := search.ParseRepositoryRevisions(string(r.Name) + repo.Revs)
+
+	// The repositories of the search have already been resolved, only
+	// search this one.
+	sr.resultLimit = maxSearchJobResultsPerRepo
+	sr.resolved = resolvedRepositories{
+		repoRevs: []*search.RepositoryRevisions{{Repo: r, Revs: revs}},
+	}
+
+	res, err := sr.Results(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, result := range res.SearchResults {
+		err := exportSearchResult(ctx, result, func(row *SearchExportRow) error {
+			results = append(results, &types.SearchJobResult{
+				Repo:    row.Repo,
+				Commit:  row.Commit,
+				Path:    row.Path,
+				Line:    row.Line,
+				Preview: row.Preview,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	limitHit = res.LimitHit() || len(res.timedout) > 0 || len(res.cloning) > 0
+	return results, limitHit, nil
+}
+
+func (r *schemaResolver) CreateSearchJob(ctx context.Context, args *struct {
+	Query       string
+	PatternType string
+}) (*searchJobResolver, error) {
+	// 🚨 SECURITY: Search jobs are owned by the user who creates them.
+	a := actor.FromContext(ctx)
+	if !a.IsAuthenticated() {
+		return nil, errors.New("no current user")
+	}
+
+	// Reject invalid queries now, instead of when the job runs.
+	if _, err := newSearchJobResolver(ctx, &SearchArgs{Version: "V2", Query: args.Query, PatternType: &args.PatternType}); err != nil {
+		return nil, err
+	}
+
+	job, err := db.SearchJobs.Create(ctx, &types.SearchJob{
+		UserID:      a.UID,
+		Query:       args.Query,
+		PatternType: args.PatternType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &searchJobResolver{job: job}, nil
+}
+
+func (r *schemaResolver) DeleteSearchJob(ctx context.Context, args *struct {
+	SearchJob graphql.ID
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: searchJobByID checks that the current user may access the
+	// search job.
+	job, err := searchJobByID(ctx, args.SearchJob)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SearchJobs.Delete(ctx, job.job.ID); err != nil {
+		return nil, err
+	}
+	return &EmptyResponse{}, nil
+}
+
+func (r *schemaResolver) SearchJobs(ctx context.Context) ([]*searchJobResolver, error) {
+	a := actor.FromContext(ctx)
+	if !a.IsAuthenticated() {
+		return nil, errors.New("no current user")
+	}
+	jobs, err := db.SearchJobs.ListByUserID(ctx, a.UID)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*searchJobResolver, 0, len(jobs))
+	for _, job := range jobs {
+		resolvers = append(resolvers, &searchJobResolver{job: job})
+	}
+	return resolvers, nil
+}
+
+func marshalSearchJobID(id int32) graphql.ID {
+	return relay.MarshalID("SearchJob", id)
+}
+
+func unmarshalSearchJobID(id graphql.ID) (searchJobID int32, err error) {
+	err = relay.UnmarshalSpec(id, &searchJobID)
+	return
+}
+
+func searchJobByID(ctx context.Context, id graphql.ID) (*searchJobResolver, error) {
+	jobID, err := unmarshalSearchJobID(id)
+	if err != nil {
+		return nil, err
+	}
+	job, err := db.SearchJobs.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	// 🚨 SECURITY: Only the creator of the search job and site admins may
+	// access it.
+	if err := backend.CheckSiteAdminOrSameUser(ctx, job.UserID); err != nil {
+		return nil, err
+	}
+	return &searchJobResolver{job: job}, nil
+}
+
+// searchJobResolver is a resolver for the GraphQL type `SearchJob`.
+type searchJobResolver struct {
+	job *types.SearchJob
+}
+
+func (r *searchJobResolver) ID() graphql.ID { return marshalSearchJobID(r.job.ID) }
+
+func (r *searchJobResolver) Query() string { return r.job.Query }
+
+func (r *searchJobResolver) State() string { return strings.ToUpper(r.job.State) }
+
+func (r *searchJobResolver) FailureMessage() *string { return r.job.FailureMessage }
+
+func (r *searchJobResolver) Creator(ctx context.Context) (*UserResolver, error) {
+	user, err := UserByIDInt32(ctx, r.job.UserID)
+	if errcode.IsNotFound(err) {
+		return nil, nil
+	}
+	return user, err
+}
+
+func (r *searchJobResolver) CreatedAt() DateTime { return DateTime{Time: r.job.CreatedAt} }
+
+func (r *searchJobResolver) StartedAt() *DateTime { return DateTimeOrNil(r.job.StartedAt) }
+
+func (r *searchJobResolver) FinishedAt() *DateTime { return DateTimeOrNil(r.job.FinishedAt) }
+
+func (r *searchJobResolver) RepositoriesTotal() int32 { return r.job.ReposTotal }
+
+func (r *searchJobResolver) RepositoriesSearched() int32 { return r.job.ReposSearched }
+
+func (r *searchJobResolver) ResultCount() int32 { return r.job.ResultCount }
+
+func (r *searchJobResolver) LimitHit() bool { return r.job.LimitHit }
+
+func (r *searchJobResolver) DownloadURL() *string {
+	if r.job.State != "completed" {
+		return nil
+	}
+	url := fmt.Sprintf("/.api/search/jobs/%d/export", r.job.ID)
+	return &url
+}
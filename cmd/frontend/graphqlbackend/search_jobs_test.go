@@ -0,0 +1,80 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/db"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestCreateSearchJob(t *testing.T) {
+	defer resetMocks()
+	mockDecodedViewerFinalSettings = &schema.Settings{}
+	defer func() { mockDecodedViewerFinalSettings = nil }()
+
+	var created *types.SearchJob
+	db.Mocks.SearchJobs.Create = func(ctx context.Context, job *types.SearchJob) (*types.SearchJob, error) {
+		created = job
+		j := *job
+		j.ID = 1
+		j.State = "queued"
+		return &j, nil
+	}
+
+	create := func(ctx context.Context, query string) (*searchJobResolver, error) {
+		return (&schemaResolver{}).CreateSearchJob(ctx, &struct {
+			Query       string
+			PatternType string
+		}{Query: query, PatternType: "literal"})
+	}
+
+	if _, err := create(context.Background(), "foo"); err == nil {
+		t.Fatal("expected an error for an unauthenticated user")
+	}
+
+	ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 2})
+	if _, err := create(ctx, "foo case:maybe"); err == nil || created != nil {
+		t.Fatalf("got error %v, want an error for an invalid query and no job", err)
+	}
+
+	job, err := create(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.UserID != 2 || created.Query != "foo" || created.PatternType != "literal" {
+		t.Errorf("unexpected job %+v", created)
+	}
+	if job.State() != "QUEUED" || job.DownloadURL() != nil {
+		t.Errorf("got state %q and download URL %v, want a queued job without download URL", job.State(), job.DownloadURL())
+	}
+}
+
+func TestSearchJobByID(t *testing.T) {
+	defer resetMocks()
+
+	db.Mocks.SearchJobs.GetByID = func(ctx context.Context, id int32) (*types.SearchJob, error) {
+		return &types.SearchJob{ID: id, UserID: 1, State: "completed"}, nil
+	}
+	db.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: actor.FromContext(ctx).UID}, nil
+	}
+	db.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		return &types.User{ID: id}, nil
+	}
+
+	job, err := searchJobByID(actor.WithActor(context.Background(), &actor.Actor{UID: 1}), marshalSearchJobID(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url := job.DownloadURL(); url == nil || *url != "/.api/search/jobs/5/export" {
+		t.Errorf("got download URL %v, want /.api/search/jobs/5/export", url)
+	}
+
+	// 🚨 SECURITY: Other users can't access the search job.
+	if _, err := searchJobByID(actor.WithActor(context.Background(), &actor.Actor{UID: 2}), marshalSearchJobID(5)); err == nil {
+		t.Fatal("expected an error for another user")
+	}
+}
@@ -0,0 +1,217 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neelance/parallel"
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/searcher"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// maxReplacePreviewRepos is the maximum number of repositories
+// searchReplacePreview computes changes for. Every repository requires a
+// request to searcher, so we ask the user to narrow down the query instead.
+const maxReplacePreviewRepos = 50
+
+type searchReplacePreviewArgs struct {
+	PatternType *string
+	Query       string
+	Replacement string
+}
+
+// searchReplacePreviewResolver implements the SearchReplacePreview GraphQL
+// type.
+type searchReplacePreviewResolver struct {
+	comparisons []PreviewRepositoryComparisonResolver
+	limitHit    bool
+	timedout    []*types.Repo
+}
+
+func (r *searchReplacePreviewResolver) Comparisons() []PreviewRepositoryComparisonResolver {
+	return r.comparisons
+}
+
+func (r *searchReplacePreviewResolver) LimitHit() bool { return r.limitHit }
+
+func (r *searchReplacePreviewResolver) Timedout() []*RepositoryResolver {
+	return RepositoryResolvers(r.timedout)
+}
+
+func (r *schemaResolver) SearchReplacePreview(ctx context.Context, args *searchReplacePreviewArgs) (*searchReplacePreviewResolver, error) {
+	impl, err := NewSearchImplementer(ctx, &SearchArgs{
+		Version:     "V2",
+		PatternType: args.PatternType,
+		Query:       args.Query,
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch sr := impl.(type) {
+	case *searchResolver:
+		return sr.replacePreview(ctx, args.Replacement)
+	case *searchAlert:
+		return nil, fmt.Errorf("%s: %s", sr.title, sr.description)
+	default:
+		return nil, fmt.Errorf("unexpected search implementer %T", impl)
+	}
+}
+
+// replacePreview returns the changes of replacing every match of the query
+// with replacement, one comparison per repository revision with matches.
+// Repositories which time out are reported as timed out with the changes
+// computed until then.
+func (r *searchResolver) replacePreview(ctx context.Context, replacement string) (_ *searchReplacePreviewResolver, err error) {
+	tr, ctx := trace.New(ctx, "graphql.SearchReplacePreview", r.rawQuery())
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	// The comparisons of repositories which timed out are still resolved
+	// without the search timeout.
+	resolveCtx := ctx
+	ctx, cancel, err := r.withTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	options := &getPatternInfoOptions{}
+	if r.patternType == query.SearchTypeStructural {
+		options = &getPatternInfoOptions{performStructuralSearch: true}
+	}
+	if r.patternType == query.SearchTypeLiteral {
+		options = &getPatternInfoOptions{performLiteralSearch: true}
+	}
+	p, err := r.getPatternInfo(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, &badRequestError{err}
+	}
+	if p.Pattern == "" {
+		return nil, &badRequestError{errors.New("a search pattern is required to replace matches")}
+	}
+	if p.IsNegated {
+		return nil, &badRequestError{errors.New("matches of a negated pattern can not be replaced")}
+	}
+	// Only file contents are replaced.
+	p.PatternMatchesContent = true
+	p.PatternMatchesPath = false
+
+	resolved, err := r.resolveRepositories(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resolved.repoRevs) > maxReplacePreviewRepos {
+		return nil, &badRequestError{fmt.Errorf(`replacing matches is limited to %d repositories, use the "repo:" filter to narrow down which repositories to search`, maxReplacePreviewRepos)}
+	}
+
+	// Like a search over a single repository, give each repository the
+	// remaining deadline to fetch the archive.
+	fetchTimeout := time.Minute
+	if deadline, ok := ctx.Deadline(); ok {
+		fetchTimeout = time.Until(deadline)
+	}
+
+	var (
+		run     = parallel.NewRun(8)
+		mu      sync.Mutex
+		results searchReplacePreviewResolver
+	)
+	for _, repoRev := range resolved.repoRevs {
+		for _, rev := range repoRev.RevSpecs() {
+			repoRev, rev := repoRev, rev
+			run.Acquire()
+			go func() {
+				defer run.Release()
+
+				commit, patch, limitHit, err := replaceInRepo(ctx, r.searcherURLs, repoRev.GitserverRepo(), rev, p, replacement, fetchTimeout)
+				timedout := errcode.IsTimeout(err)
+				if err != nil && !timedout {
+					// Like search, skip repositories which are not
+					// available instead of failing the whole preview.
+					if !vcs.IsRepoNotExist(err) && !gitserver.IsRevisionNotFound(err) {
+						run.Error(errors.Wrapf(err, "replacing in %s@%s", repoRev.Repo.Name, rev))
+					}
+					return
+				}
+
+				var comparison PreviewRepositoryComparisonResolver
+				if patch != "" {
+					comparison, err = NewPreviewRepositoryComparisonResolver(resolveCtx, &RepositoryResolver{repo: repoRev.Repo}, string(commit), patch)
+					if err != nil {
+						run.Error(err)
+						return
+					}
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				results.limitHit = results.limitHit || limitHit
+				if timedout {
+					results.timedout = append(results.timedout, repoRev.Repo)
+				}
+				if comparison != nil {
+					results.comparisons = append(results.comparisons, comparison)
+				}
+			}()
+		}
+	}
+	if err := run.Wait(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(results.comparisons, func(i, j int) bool {
+		return results.comparisons[i].BaseRepository().Name() < results.comparisons[j].BaseRepository().Name()
+	})
+	sort.Slice(results.timedout, func(i, j int) bool {
+		return results.timedout[i].Name < results.timedout[j].Name
+	})
+	return &results, nil
+}
+
+var mockReplaceInRepo func(ctx context.Context, gitserverRepo gitserver.Repo, rev string, p *search.TextPatternInfo, replacement string) (commit api.CommitID, patch string, limitHit bool, err error)
+
+// replaceInRepo resolves rev and returns a patch for replacing the matches of
+// p in it with replacement. limitHit is true if not all matches were
+// replaced. If the replacement times out, the patch of the matches replaced
+// until then is returned with the timeout error.
+func replaceInRepo(ctx context.Context, searcherURLs *endpoint.Map, gitserverRepo gitserver.Repo, rev string, p *search.TextPatternInfo, replacement string, fetchTimeout time.Duration) (commit api.CommitID, patch string, limitHit bool, err error) {
+	if mockReplaceInRepo != nil {
+		return mockReplaceInRepo(ctx, gitserverRepo, rev, p, replacement)
+	}
+
+	// Like searchFilesInRepo, do not trigger a repo-updater lookup.
+	commit, err = git.ResolveRevision(ctx, gitserverRepo, nil, rev, git.ResolveRevisionOptions{NoEnsureRevision: true})
+	if err != nil {
+		return "", "", false, err
+	}
+
+	diffs, limitHit, err := searcher.Replace(ctx, searcherURLs, gitserverRepo, commit, p, replacement, fetchTimeout)
+	if err != nil && !errcode.IsTimeout(err) {
+		return "", "", false, err
+	}
+	var b strings.Builder
+	for _, d := range diffs {
+		b.WriteString(d.Diff)
+	}
+	return commit, b.String(), limitHit, err
+}
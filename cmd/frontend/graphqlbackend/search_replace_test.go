@@ -0,0 +1,95 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestSearchReplacePreview(t *testing.T) {
+	mockDecodedViewerFinalSettings = &schema.Settings{}
+	defer func() { mockDecodedViewerFinalSettings = nil }()
+
+	mockResolveRepositories = func(effectiveRepoFieldValues []string) (resolvedRepositories, error) {
+		var repoRevs []*search.RepositoryRevisions
+		for i, name := range []api.RepoName{"repo/b", "repo/a", "repo/c", "repo/d"} {
+			repoRevs = append(repoRevs, &search.RepositoryRevisions{
+				Repo: &types.Repo{ID: api.RepoID(i + 1), Name: name},
+				Revs: []search.RevisionSpecifier{{RevSpec: ""}},
+			})
+		}
+		return resolvedRepositories{repoRevs: repoRevs}, nil
+	}
+	defer func() { mockResolveRepositories = nil }()
+
+	const commit = api.CommitID("deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	mockBackendCommits(t, commit)
+
+	var gotPattern *search.TextPatternInfo
+	mockReplaceInRepo = func(ctx context.Context, repo gitserver.Repo, rev string, p *search.TextPatternInfo, replacement string) (api.CommitID, string, bool, error) {
+		gotPattern = p
+		patch := fmt.Sprintf("--- a/a.go\n+++ b/a.go\n@@ -1,1 +1,1 @@\n-foo\n+%s\n", replacement)
+		switch repo.Name {
+		case "repo/b":
+			return commit, patch, true, nil
+		case "repo/c":
+			// No matches.
+			return commit, "", false, nil
+		case "repo/d":
+			// Timed out after replacing some matches.
+			return commit, patch, false, context.DeadlineExceeded
+		}
+		return commit, patch, false, nil
+	}
+	defer func() { mockReplaceInRepo = nil }()
+
+	sr := &schemaResolver{}
+	patternType := "regexp"
+	results, err := sr.SearchReplacePreview(context.Background(), &searchReplacePreviewArgs{
+		PatternType: &patternType,
+		Query:       "fo(o)",
+		Replacement: "bar",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotRepos []string
+	for _, r := range results.Comparisons() {
+		gotRepos = append(gotRepos, r.BaseRepository().Name())
+	}
+	if want := []string{"repo/a", "repo/b", "repo/d"}; !cmp.Equal(gotRepos, want) {
+		t.Errorf("unexpected repositories (-want +got):\n%s", cmp.Diff(want, gotRepos))
+	}
+	if !results.LimitHit() {
+		t.Error("expected limitHit")
+	}
+	var gotTimedout []string
+	for _, r := range results.Timedout() {
+		gotTimedout = append(gotTimedout, r.Name())
+	}
+	if want := []string{"repo/d"}; !cmp.Equal(gotTimedout, want) {
+		t.Errorf("unexpected timed out repositories (-want +got):\n%s", cmp.Diff(want, gotTimedout))
+	}
+	if gotPattern == nil || gotPattern.Pattern != "fo(o)" || !gotPattern.IsRegExp || !gotPattern.PatternMatchesContent || gotPattern.PatternMatchesPath {
+		t.Errorf("unexpected pattern %+v", gotPattern)
+	}
+
+	t.Run("negated pattern", func(t *testing.T) {
+		_, err := sr.SearchReplacePreview(context.Background(), &searchReplacePreviewArgs{
+			PatternType: &patternType,
+			Query:       "-content:foo",
+			Replacement: "bar",
+		})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/goroutine"
 	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
 	"github.com/sourcegraph/sourcegraph/internal/rcache"
@@ -42,6 +43,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 // searchResultsCommon contains fields that should be returned by all funcs
@@ -99,6 +101,40 @@ func (c *searchResultsCommon) Timedout() []*RepositoryResolver {
 	return RepositoryResolvers(c.timedout)
 }
 
+// Sparse returns the searched repositories which are sparse clones, whose
+// files outside of their sparse paths were not searched.
+func (c *searchResultsCommon) Sparse() []*RepositoryResolver {
+	return RepositoryResolvers(sparseRepos(conf.Get().GitCloneStrategies, c.searched))
+}
+
+// sparseRepos returns the repos which gitserver clones with a strategy that
+// has sparse paths. Like gitserver, it uses the first strategy whose pattern
+// matches the name of a repository.
+func sparseRepos(strategies []*schema.GitCloneStrategy, repos []*types.Repo) []*types.Repo {
+	if len(strategies) == 0 {
+		return nil
+	}
+	patterns := make([]*regexp.Regexp, len(strategies))
+	for i, s := range strategies {
+		// Invalid patterns are ignored by gitserver as well.
+		patterns[i], _ = regexp.Compile(s.Pattern)
+	}
+
+	var sparse []*types.Repo
+	for _, r := range repos {
+		name := string(protocol.NormalizeRepo(r.Name))
+		for i, p := range patterns {
+			if p != nil && p.MatchString(name) {
+				if len(strategies[i].SparsePaths) > 0 {
+					sparse = append(sparse, r)
+				}
+				break
+			}
+		}
+	}
+	return sparse
+}
+
 func (c *searchResultsCommon) IndexUnavailable() bool {
 	return c.indexUnavailable
 }
@@ -167,6 +203,10 @@ type SearchResultsResolver struct {
 	// cursor to return for paginated search requests, or nil if the request
 	// wasn't paginated.
 	cursor *searchCursor
+
+	// aggregation groups the results for the aggregations field, or nil if
+	// the query has no aggregate: field.
+	aggregation *searchAggregation
 }
 
 func (sr *SearchResultsResolver) Results() []SearchResultResolver {
@@ -178,6 +218,10 @@ func (sr *SearchResultsResolver) MatchCount() int32 {
 	for _, result := range sr.SearchResults {
 		totalResults += result.resultCount()
 	}
+	if sr.aggregation != nil {
+		// Results which were only counted by the aggregation.
+		totalResults += sr.aggregation.addedResultCount()
+	}
 	return totalResults
 }
 
@@ -955,6 +999,7 @@ func (r *searchResolver) evaluateOperator(ctx context.Context, scopeParameters [
 	if len(operator.Operands) == 0 {
 		return nil, nil
 	}
+	r.combinesResults = true
 	var result *SearchResultsResolver
 	var err error
 	if operator.Kind == query.And {
@@ -972,9 +1017,12 @@ func (r *searchResolver) evaluateOperator(ctx context.Context, scopeParameters [
 func (r *searchResolver) evaluatePatternExpression(ctx context.Context, scopeParameters []query.Node, node query.Node) (*SearchResultsResolver, error) {
 	switch term := node.(type) {
 	case query.Operator:
+		if query.IsSubquery(term) {
+			return r.evaluateSubquery(ctx, scopeParameters, term.Operands)
+		}
 		if term.Kind == query.And || term.Kind == query.Or {
 			return r.evaluateOperator(ctx, scopeParameters, term)
-		} else if term.Kind == query.Concat {
+		} else if term.Kind == query.Concat || term.Kind == query.Near {
 			q := append(scopeParameters, term)
 			r.query.(*query.AndOrQuery).Query = q
 			return r.evaluateLeaf(ctx)
@@ -991,6 +1039,33 @@ func (r *searchResolver) evaluatePatternExpression(ctx context.Context, scopePar
 	return nil, fmt.Errorf("unrecognized type %s in evaluatePatternExpression", reflect.TypeOf(node).String())
 }
 
+// evaluateSubquery evaluates a parenthesized group like (repo:a file:x foo)
+// as its own search. Its parameters scope the search in addition to the
+// parameters of the enclosing query.
+func (r *searchResolver) evaluateSubquery(ctx context.Context, scopeParameters []query.Node, nodes []query.Node) (*SearchResultsResolver, error) {
+	parameters, pattern, err := query.PartitionSearchPattern(nodes)
+	if err != nil {
+		return alertForQuery("", err).wrap(), nil
+	}
+	scope := make([]query.Node, 0, len(scopeParameters)+len(parameters))
+	scope = append(scope, scopeParameters...)
+	scope = append(scope, parameters...)
+
+	// The repositories resolved for the enclosing query don't apply to the
+	// subquery, which may have its own repo: filters.
+	r.reposMu.Lock()
+	resolved, repoErr := r.resolved, r.repoErr
+	r.resolved, r.repoErr = resolvedRepositories{}, nil
+	r.reposMu.Unlock()
+	defer func() {
+		r.reposMu.Lock()
+		r.resolved, r.repoErr = resolved, repoErr
+		r.reposMu.Unlock()
+	}()
+
+	return r.evaluatePatternExpression(ctx, scope, pattern)
+}
+
 // evaluate evaluates all expressions of a search query.
 func (r *searchResolver) evaluate(ctx context.Context, q []query.Node) (*SearchResultsResolver, error) {
 	scopeParameters, pattern, err := query.PartitionSearchPattern(q)
@@ -1298,10 +1373,18 @@ func processSearchPattern(q query.QueryInfo, opts *getPatternInfoOptions) (strin
 
 // getPatternInfo gets the search pattern info for q
 func getPatternInfo(q query.QueryInfo, opts *getPatternInfoOptions) (*search.TextPatternInfo, error) {
+	if andOrQuery, ok := q.(*query.AndOrQuery); ok {
+		if left, right, distance, ok := query.PartitionNear(andOrQuery.Query); ok {
+			return getNearPatternInfo(left, right, distance, opts)
+		}
+	}
+
 	pattern, isRegExp, isStructuralPat, isNegated := processSearchPattern(q, opts)
 
 	// Handle file: and -file: filters.
 	includePatterns, excludePatterns := q.RegexpPatterns(query.FieldFile)
+	includePatterns, includePredicates := query.PartitionFilePredicates(includePatterns)
+	excludePatterns, excludePredicates := query.PartitionFilePredicates(excludePatterns)
 	filePatternsReposMustInclude, filePatternsReposMustExclude := q.RegexpPatterns(query.FieldRepoHasFile)
 
 	if opts.forceFileSearch {
@@ -1342,9 +1425,68 @@ func getPatternInfo(q query.QueryInfo, opts *getPatternInfoOptions) (*search.Tex
 	if len(excludePatterns) > 0 {
 		patternInfo.ExcludePattern = unionRegExps(excludePatterns)
 	}
+	if err := addFilePredicates(patternInfo, includePredicates, false); err != nil {
+		return nil, err
+	}
+	if err := addFilePredicates(patternInfo, excludePredicates, true); err != nil {
+		return nil, err
+	}
 	return patternInfo, nil
 }
 
+// getNearPatternInfo returns the pattern info for a query with a near(N)
+// expression, which searches for the pattern of the left query near the
+// pattern of the right query. Both queries have the same parameters.
+func getNearPatternInfo(left, right []query.Node, distance int, opts *getPatternInfoOptions) (*search.TextPatternInfo, error) {
+	if opts.performStructuralSearch {
+		return nil, errors.New("near(N) is not supported for structural search")
+	}
+	patternInfo, err := getPatternInfo(&query.AndOrQuery{Query: left}, opts)
+	if err != nil {
+		return nil, err
+	}
+	patternInfo.NearPattern, _, _, _ = processSearchPattern(&query.AndOrQuery{Query: right}, opts)
+	patternInfo.NearDistance = distance
+	return patternInfo, nil
+}
+
+// addFilePredicates restricts the files searched by p to the ones satisfying
+// the file predicates, or the ones not satisfying them if negated is true.
+func addFilePredicates(p *search.TextPatternInfo, predicates []query.FilePredicate, negated bool) error {
+	for _, predicate := range predicates {
+		switch predicate.Name {
+		case query.FilePredicateContains:
+			if negated {
+				p.FileContentMustExclude = append(p.FileContentMustExclude, predicate.Argument)
+			} else {
+				p.FileContentMustInclude = append(p.FileContentMustInclude, predicate.Argument)
+			}
+		case query.FilePredicateHasOwner:
+			if negated {
+				p.FileOwnersMustExclude = append(p.FileOwnersMustExclude, predicate.Argument)
+			} else {
+				p.FileOwnersMustInclude = append(p.FileOwnersMustInclude, predicate.Argument)
+			}
+		case query.FilePredicateSizeMin, query.FilePredicateSizeMax:
+			if negated {
+				return fmt.Errorf("file:%s does not support negation", predicate)
+			}
+			size, err := query.ParseFileSize(predicate.Argument)
+			if err != nil {
+				return err
+			}
+			// Multiple bounds narrow down the range.
+			if predicate.Name == query.FilePredicateSizeMin && size > p.FileSizeMin {
+				p.FileSizeMin = size
+			}
+			if predicate.Name == query.FilePredicateSizeMax && (p.FileSizeMax == 0 || size < p.FileSizeMax) {
+				p.FileSizeMax = size
+			}
+		}
+	}
+	return nil
+}
+
 // langIncludeExcludePatterns returns regexps for the include/exclude path patterns given the lang:
 // and -lang: filter values in a search query. For example, a query containing "lang:go" should
 // include files whose paths match /\.go$/.
@@ -1395,8 +1537,9 @@ func (r *searchResolver) withTimeout(ctx context.Context) (context.Context, cont
 		if err != nil {
 			return nil, nil, errors.WithMessage(err, `invalid "timeout:" value (examples: "timeout:2s", "timeout:200ms")`)
 		}
-	} else if r.countIsSet() {
-		// If `count:` is set but `timeout:` is not explicitly set, use the max timeout
+	} else if r.countIsSet() || r.resultLimit > 0 {
+		// If `count:` is set but `timeout:` is not explicitly set, use the max timeout.
+		// The same applies to exports and search jobs, which set resultLimit.
 		d = maxTimeout
 	}
 	if d > maxTimeout {
@@ -1416,6 +1559,10 @@ func (r *searchResolver) determineResultTypes(args search.TextParameters, forceO
 			resultTypes = []string{"file", "path", "repo"}
 		}
 	}
+	if args.PatternInfo.NearPattern != "" {
+		// near(N) only relates lines of file content.
+		resultTypes = []string{"file"}
+	}
 	for _, resultType := range resultTypes {
 		if resultType == "file" {
 			args.PatternInfo.PatternMatchesContent = true
@@ -1552,6 +1699,28 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 	if err := args.PatternInfo.Validate(); err != nil {
 		return nil, &badRequestError{err}
 	}
+	aggregation, err := newSearchAggregation(r.query, p)
+	if err != nil {
+		return nil, &badRequestError{err}
+	}
+	// Send the results as they are found instead of keeping them, unless
+	// and/or expressions need them to be combined with the results of other
+	// searches. The results of queries with an aggregate: field are counted
+	// as well.
+	var sendResults func([]SearchResultResolver) error
+	if !r.combinesResults && (r.sendResults != nil || aggregation != nil) {
+		sendResults = func(results []SearchResultResolver) error {
+			if aggregation != nil {
+				if err := aggregation.add(ctx, results); err != nil {
+					return err
+				}
+			}
+			if r.sendResults != nil {
+				return r.sendResults(results)
+			}
+			return nil
+		}
+	}
 
 	err = validateRepoHasFileUsage(r.query)
 	if err != nil {
@@ -1566,6 +1735,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		optionalWg sync.WaitGroup
 		results    []SearchResultResolver
 		resultsMu  sync.Mutex
+		found      int // the number of results, including the ones sent to sendResults
 		common     = searchResultsCommon{maxResultsCount: r.maxResults()}
 		commonMu   sync.Mutex
 		multiErr   *multierror.Error
@@ -1579,6 +1749,28 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		seenResultTypes = make(map[string]struct{})
 	)
 
+	// addResults adds rs to the results, or sends them to sendResults if it
+	// is non-nil.
+	addResults := func(rs ...SearchResultResolver) {
+		if len(rs) == 0 {
+			return
+		}
+		resultsMu.Lock()
+		found += len(rs)
+		if sendResults == nil {
+			results = append(results, rs...)
+		}
+		resultsMu.Unlock()
+
+		if sendResults != nil {
+			if err := sendResults(rs); err != nil {
+				multiErrMu.Lock()
+				multiErr = multierror.Append(multiErr, err)
+				multiErrMu.Unlock()
+			}
+		}
+	}
+
 	waitGroup := func(required bool) *sync.WaitGroup {
 		if args.UseFullDeadline {
 			// When a custom timeout is specified, all searches are required and get the full timeout.
@@ -1619,11 +1811,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "repository search failed"))
 					multiErrMu.Unlock()
 				}
-				if repoResults != nil {
-					resultsMu.Lock()
-					results = append(results, repoResults...)
-					resultsMu.Unlock()
-				}
+				addResults(repoResults...)
 				if repoCommon != nil {
 					commonMu.Lock()
 					common.update(*repoCommon)
@@ -1650,9 +1838,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 						m.symbols = symbolFileMatch.symbols
 					} else {
 						fileMatches[key] = symbolFileMatch
-						resultsMu.Lock()
-						results = append(results, symbolFileMatch)
-						resultsMu.Unlock()
+						addResults(symbolFileMatch)
 					}
 					fileMatchesMu.Unlock()
 				}
@@ -1673,19 +1859,50 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 			goroutine.Go(func() {
 				defer wg.Done()
 
-				fileResults, fileCommon, err := searchFilesInRepos(ctx, &args)
+				addFileResults := func(fileResults []*FileMatchResolver) {
+					for _, r := range fileResults {
+						key := r.uri
+						fileMatchesMu.Lock()
+						m, ok := fileMatches[key]
+						if ok {
+							// TODO(keegan) This looks broken? It isn't merging.
+							// merge line match results with an existing symbol result
+							m.JLimitHit = m.JLimitHit || r.JLimitHit
+							m.JLineMatches = r.JLineMatches
+						} else {
+							fileMatches[key] = r
+							addResults(r)
+						}
+						fileMatchesMu.Unlock()
+					}
+				}
+
+				// When the results are sent, add the matches of each
+				// repository as soon as they are found.
+				var (
+					onMatches func([]*FileMatchResolver)
+					streamed  int
+				)
+				if sendResults != nil {
+					onMatches = func(matches []*FileMatchResolver) {
+						streamed += len(matches)
+						addFileResults(matches)
+					}
+				}
+
+				fileResults, fileCommon, err := searchFilesInReposStream(ctx, &args, onMatches)
 				// Timeouts are reported through searchResultsCommon so don't report an error for them
 				if err != nil && !isContextError(ctx, err) {
 					multiErrMu.Lock()
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "text search failed"))
 					multiErrMu.Unlock()
 				}
-				if args.PatternInfo.IsStructuralPat && args.PatternInfo.FileMatchLimit == defaultMaxSearchResults && len(fileResults) == 0 && err == nil {
+				if args.PatternInfo.IsStructuralPat && args.PatternInfo.FileMatchLimit == defaultMaxSearchResults && len(fileResults) == 0 && streamed == 0 && err == nil {
 					// No results for structural search? Automatically search again and force Zoekt to resolve
 					// more potential file matches by setting a higher FileMatchLimit.
 					args.PatternInfo.FileMatchLimit = 1000
-					fileResults, fileCommon, err = searchFilesInRepos(ctx, &args)
-					if len(fileResults) == 0 {
+					fileResults, fileCommon, err = searchFilesInReposStream(ctx, &args, onMatches)
+					if len(fileResults) == 0 && streamed == 0 {
 						// Still no results? Give up.
 						log15.Warn("Structural search gives up after more exhaustive attempt. Results may have been missed.")
 						if fileCommon != nil {
@@ -1693,23 +1910,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 						}
 					}
 				}
-				for _, r := range fileResults {
-					key := r.uri
-					fileMatchesMu.Lock()
-					m, ok := fileMatches[key]
-					if ok {
-						// TODO(keegan) This looks broken? It isn't merging.
-						// merge line match results with an existing symbol result
-						m.JLimitHit = m.JLimitHit || r.JLimitHit
-						m.JLineMatches = r.JLineMatches
-					} else {
-						fileMatches[key] = r
-						resultsMu.Lock()
-						results = append(results, r)
-						resultsMu.Unlock()
-					}
-					fileMatchesMu.Unlock()
-				}
+				addFileResults(fileResults)
 				if fileCommon != nil {
 					commonMu.Lock()
 					common.update(*fileCommon)
@@ -1744,11 +1945,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "diff search failed"))
 					multiErrMu.Unlock()
 				}
-				if diffResults != nil {
-					resultsMu.Lock()
-					results = append(results, diffResults...)
-					resultsMu.Unlock()
-				}
+				addResults(diffResults...)
 				if diffCommon != nil {
 					commonMu.Lock()
 					common.update(*diffCommon)
@@ -1784,11 +1981,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "commit search failed"))
 					multiErrMu.Unlock()
 				}
-				if commitResults != nil {
-					resultsMu.Lock()
-					results = append(results, commitResults...)
-					resultsMu.Unlock()
-				}
+				addResults(commitResults...)
 				if commitCommon != nil {
 					commonMu.Lock()
 					common.update(*commitCommon)
@@ -1813,7 +2006,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 	timer.Stop()
 
 	tr.LazyPrintf("results=%d limitHit=%v cloning=%d missing=%d excludedFork=%d excludedArchived=%d timedout=%d",
-		len(results),
+		found,
 		common.limitHit,
 		len(common.cloning),
 		len(common.missing),
@@ -1826,7 +2019,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		alert = newAlert // takes higher precedence
 	}
 
-	if len(results) == 0 && r.patternType != query.SearchTypeStructural && matchHoleRegexp.MatchString(r.originalQuery) {
+	if found == 0 && r.patternType != query.SearchTypeStructural && matchHoleRegexp.MatchString(r.originalQuery) {
 		alert = alertForStructuralSearchNotSet(r.originalQuery)
 	}
 
@@ -1834,13 +2027,13 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		alert = alertForMissingRepoRevs(r.patternType, resolved.missingRepoRevs)
 	}
 
-	if len(results) == 0 && strings.Contains(r.originalQuery, `"`) && r.patternType == query.SearchTypeLiteral {
+	if found == 0 && strings.Contains(r.originalQuery, `"`) && r.patternType == query.SearchTypeLiteral {
 		alert = alertForQuotesInQueryInLiteralMode(r.query.ParseTree())
 	}
 
 	// If we have some results, only log the error instead of returning it,
 	// because otherwise the client would not receive the partial results
-	if len(results) > 0 && multiErr != nil {
+	if found > 0 && multiErr != nil {
 		log15.Error("Errors during search", "error", multiErr)
 		multiErr = nil
 	}
@@ -1852,6 +2045,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		searchResultsCommon: common,
 		SearchResults:       results,
 		alert:               alert,
+		aggregation:         aggregation,
 	}
 
 	return &resultsResolver, multiErr.ErrorOrNil()
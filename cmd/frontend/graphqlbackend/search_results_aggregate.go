@@ -0,0 +1,296 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+// defaultMaxAggregatedSearchResults is the default number of results counted
+// by queries with an aggregate: field. It is higher than
+// defaultMaxSearchResults since the results are counted as they are found
+// instead of being kept and sent to the client.
+const defaultMaxAggregatedSearchResults = 5000
+
+var errAggregateCapture = errors.New("aggregate:capture requires a regular expression pattern with a capture group, such as patternType:regexp foo\\((\\w+)\\)")
+
+// searchAggregation describes how to group the results of a query with an
+// aggregate: field. Results can be added to it as they are found with add, so
+// that they do not have to be kept until the search is done.
+type searchAggregation struct {
+	mode string
+
+	// capture is the pattern of the query for aggregate:capture.
+	capture *regexp.Regexp
+
+	mu          sync.Mutex
+	groups      map[string]*searchAggregationGroupResolver
+	resultCount int32 // the number of matches added
+}
+
+// newSearchAggregation returns the aggregation requested by the aggregate:
+// field of q, or nil if q has none. p is the pattern of q.
+func newSearchAggregation(q query.QueryInfo, p *search.TextPatternInfo) (*searchAggregation, error) {
+	mode, _ := q.StringValue(query.FieldAggregate)
+	if mode == "" {
+		return nil, nil
+	}
+	if err := query.ValidateAggregateMode(mode); err != nil {
+		return nil, err
+	}
+
+	a := &searchAggregation{mode: mode, groups: map[string]*searchAggregationGroupResolver{}}
+	if mode == query.AggregateCapture {
+		if !p.IsRegExp || p.IsStructuralPat || p.IsNegated {
+			return nil, errAggregateCapture
+		}
+		// Like searcher, match anchors at line boundaries.
+		expr := "(?m)" + p.Pattern
+		if !p.IsCaseSensitive {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		if re.NumSubexp() == 0 {
+			return nil, errAggregateCapture
+		}
+		a.capture = re
+	}
+	return a, nil
+}
+
+// add counts results in their groups. It is safe to call concurrently.
+func (a *searchAggregation) add(ctx context.Context, results []SearchResultResolver) error {
+	var (
+		groups []*searchAggregationGroupResolver
+		count  int32
+	)
+	for _, result := range results {
+		g, err := a.groupsOf(ctx, result)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, g...)
+		count += result.resultCount()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	addGroups(a.groups, groups)
+	a.resultCount += count
+	return nil
+}
+
+// addedResultCount returns the number of matches of the results added with
+// add.
+func (a *searchAggregation) addedResultCount() int32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.resultCount
+}
+
+// aggregate returns the groups of the results added so far and of results,
+// ordered by descending count. Unlike add it does not add results to a.
+func (a *searchAggregation) aggregate(ctx context.Context, results []SearchResultResolver) ([]*searchAggregationGroupResolver, error) {
+	a.mu.Lock()
+	groups := make(map[string]*searchAggregationGroupResolver, len(a.groups))
+	for key, g := range a.groups {
+		c := *g
+		groups[key] = &c
+	}
+	a.mu.Unlock()
+
+	for _, result := range results {
+		g, err := a.groupsOf(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+		addGroups(groups, g)
+	}
+
+	order := make([]*searchAggregationGroupResolver, 0, len(groups))
+	for _, g := range groups {
+		order = append(order, g)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].count != order[j].count {
+			return order[i].count > order[j].count
+		}
+		if order[i].label != order[j].label {
+			return order[i].label < order[j].label
+		}
+		return order[i].key < order[j].key
+	})
+	return order, nil
+}
+
+// addGroups adds the counts of groups to the groups with the same key in m.
+func addGroups(m map[string]*searchAggregationGroupResolver, groups []*searchAggregationGroupResolver) {
+	for _, g := range groups {
+		if existing, ok := m[g.key]; ok {
+			existing.count += g.count
+		} else {
+			m[g.key] = g
+		}
+	}
+}
+
+// groupsOf returns the groups result is counted in.
+func (a *searchAggregation) groupsOf(ctx context.Context, result SearchResultResolver) ([]*searchAggregationGroupResolver, error) {
+	group := func(key, label string, repo *RepositoryResolver, count int32) *searchAggregationGroupResolver {
+		return &searchAggregationGroupResolver{key: key, label: label, repo: repo, count: count}
+	}
+
+	switch a.mode {
+	case query.AggregateRepo:
+		var repo *RepositoryResolver
+		switch r := result.(type) {
+		case *RepositoryResolver:
+			repo = r
+		case *FileMatchResolver:
+			repo = r.Repository()
+		case *commitSearchResultResolver:
+			repo = r.commit.repoResolver
+		}
+		if repo != nil {
+			return []*searchAggregationGroupResolver{group(repo.Name(), repo.Name(), repo, result.resultCount())}, nil
+		}
+
+	case query.AggregateDir:
+		if fm, ok := result.ToFileMatch(); ok {
+			dir := path.Dir(fm.path())
+			return []*searchAggregationGroupResolver{group(fm.Repository().Name()+"\x00"+dir, dir, fm.Repository(), fm.resultCount())}, nil
+		}
+
+	case query.AggregateCapture:
+		fm, ok := result.ToFileMatch()
+		if !ok {
+			break
+		}
+		var groups []*searchAggregationGroupResolver
+		for _, match := range matchedTexts(fm) {
+			// The capture group has to be matched again, searcher only
+			// reports the location of the whole match.
+			if m := a.capture.FindStringSubmatch(match); m != nil {
+				groups = append(groups, group(m[1], m[1], nil, 1))
+			}
+		}
+		return groups, nil
+
+	case query.AggregateAuthor:
+		c, ok := result.ToCommitSearchResult()
+		if !ok {
+			break
+		}
+		author, err := c.commit.Author(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if author == nil || author.person == nil {
+			break
+		}
+		label := fmt.Sprintf("%s <%s>", author.person.name, author.person.email)
+		return []*searchAggregationGroupResolver{group(label, label, nil, 1)}, nil
+	}
+	return nil, nil
+}
+
+// matchedTexts returns the text of every match in fm. Matches spanning
+// multiple lines are joined from the previews of their lines.
+func matchedTexts(fm *FileMatchResolver) []string {
+	lines := make(map[int][]rune, len(fm.JLineMatches))
+	for _, lm := range fm.JLineMatches {
+		lines[int(lm.JLineNumber)] = []rune(lm.JPreview)
+	}
+	// slice returns line[start:end], with end < 0 meaning the end of the
+	// line.
+	slice := func(line []rune, start, end int) (string, bool) {
+		if end < 0 {
+			end = len(line)
+		}
+		if start < 0 || end > len(line) || start > end {
+			return "", false
+		}
+		return string(line[start:end]), true
+	}
+
+	var texts []string
+	if len(fm.JRanges) == 0 {
+		for _, lm := range fm.JLineMatches {
+			for _, ol := range lm.JOffsetAndLengths {
+				if text, ok := slice([]rune(lm.JPreview), int(ol[0]), int(ol[0]+ol[1])); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return texts
+	}
+
+ranges:
+	for _, r := range fm.JRanges {
+		var b strings.Builder
+		for n := r.Start.Line; n <= r.End.Line; n++ {
+			start, end := 0, -1
+			if n == r.Start.Line {
+				start = r.Start.Character
+			}
+			if n == r.End.Line {
+				end = r.End.Character
+			}
+			line, ok := lines[n]
+			if !ok && start == 0 && end == 0 {
+				// The match ends with the newline of the previous line.
+				line, ok = nil, true
+			}
+			if !ok {
+				continue ranges
+			}
+			text, ok := slice(line, start, end)
+			if !ok {
+				continue ranges
+			}
+			if n > r.Start.Line {
+				b.WriteByte('\n')
+			}
+			b.WriteString(text)
+		}
+		texts = append(texts, b.String())
+	}
+	return texts
+}
+
+// searchAggregationGroupResolver is a resolver for the GraphQL type
+// `SearchAggregationGroup`.
+type searchAggregationGroupResolver struct {
+	key   string // identifies the group, labels may not be unique
+	label string
+	count int32
+	repo  *RepositoryResolver
+}
+
+func (g *searchAggregationGroupResolver) Label() string { return g.label }
+
+func (g *searchAggregationGroupResolver) Count() int32 { return g.count }
+
+func (g *searchAggregationGroupResolver) Repository() *RepositoryResolver { return g.repo }
+
+func (sr *SearchResultsResolver) Aggregations(ctx context.Context) (*[]*searchAggregationGroupResolver, error) {
+	if sr.aggregation == nil {
+		return nil, nil
+	}
+	groups, err := sr.aggregation.aggregate(ctx, sr.SearchResults)
+	if err != nil {
+		return nil, err
+	}
+	return &groups, nil
+}
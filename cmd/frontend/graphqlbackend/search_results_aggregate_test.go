@@ -0,0 +1,242 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/go-langserver/pkg/lsp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/db"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestSearchAggregation(t *testing.T) {
+	repoA := &types.Repo{ID: 1, Name: "a"}
+	repoB := &types.Repo{ID: 2, Name: "b"}
+
+	withMatches := func(fm *FileMatchResolver, matchCount int, lines ...*lineMatch) *FileMatchResolver {
+		fm.MatchCount = matchCount
+		fm.JLineMatches = lines
+		return fm
+	}
+	commit := func(repo *types.Repo, id, author string) *commitSearchResultResolver {
+		return &commitSearchResultResolver{
+			commit: toGitCommitResolver(&RepositoryResolver{repo: repo}, &git.Commit{
+				ID:     api.CommitID(id),
+				Author: git.Signature{Name: author, Email: author + "@example.com"},
+			}),
+		}
+	}
+
+	fileResults := []SearchResultResolver{
+		withMatches(mkFileMatch(repoA, "cmd/main.go"), 2,
+			&lineMatch{JPreview: "log.Fatal(err) // é log.Print(x)", JOffsetAndLengths: [][2]int32{{0, 14}, {20, 12}}},
+		),
+		withMatches(mkFileMatch(repoA, "cmd/util.go"), 1,
+			&lineMatch{JPreview: "	LOG.FATAL(err)", JOffsetAndLengths: [][2]int32{{1, 14}}},
+		),
+		withMatches(mkFileMatch(repoB, "cmd/main.go"), 3),
+		&RepositoryResolver{repo: repoB},
+	}
+	commitResults := []SearchResultResolver{
+		commit(repoA, "1", "alice"),
+		commit(repoA, "2", "bob"),
+		commit(repoB, "3", "alice"),
+	}
+
+	type group struct {
+		Label      string
+		Count      int32
+		Repository string
+	}
+	cases := []struct {
+		mode    string
+		results []SearchResultResolver
+		want    []group
+	}{{
+		mode:    query.AggregateRepo,
+		results: fileResults,
+		want:    []group{{"b", 4, "b"}, {"a", 3, "a"}},
+	}, {
+		mode:    query.AggregateDir,
+		results: fileResults,
+		want:    []group{{"cmd", 3, "a"}, {"cmd", 3, "b"}},
+	}, {
+		mode:    query.AggregateCapture,
+		results: fileResults,
+		want:    []group{{"FATAL", 1, ""}, {"Fatal", 1, ""}, {"Print", 1, ""}},
+	}, {
+		mode:    query.AggregateAuthor,
+		results: commitResults,
+		want:    []group{{"alice <alice@example.com>", 2, ""}, {"bob <bob@example.com>", 1, ""}},
+	}, {
+		mode:    query.AggregateRepo,
+		results: commitResults,
+		want:    []group{{"a", 2, "a"}, {"b", 1, "b"}},
+	}}
+	for _, tt := range cases {
+		t.Run(tt.mode, func(t *testing.T) {
+			q, err := query.ParseAndCheck(`log\.(\w+)\( aggregate:` + tt.mode + ` type:commit`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, err := newSearchAggregation(q, &search.TextPatternInfo{Pattern: `log\.(\w+)\(`, IsRegExp: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			groups, err := a.aggregate(context.Background(), tt.results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []group
+			for _, g := range groups {
+				var repo string
+				if g.Repository() != nil {
+					repo = g.Repository().Name()
+				}
+				got = append(got, group{g.Label(), g.Count(), repo})
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected groups (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("multiline capture", func(t *testing.T) {
+		q, err := query.ParseAndCheck(`aggregate:capture`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := newSearchAggregation(q, &search.TextPatternInfo{Pattern: `(?s)call\((\w+),.*?\)`, IsRegExp: true, IsCaseSensitive: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fm := mkFileMatch(repoA, "main.go")
+		fm.JLineMatches = []*lineMatch{
+			{JPreview: "x := call(a, b) + call(c,", JLineNumber: 2, JOffsetAndLengths: [][2]int32{{5, 10}, {18, 7}}},
+			{JPreview: "\td)", JLineNumber: 3, JOffsetAndLengths: [][2]int32{{0, 3}}},
+		}
+		fm.JRanges = []lsp.Range{
+			{Start: lsp.Position{Line: 2, Character: 5}, End: lsp.Position{Line: 2, Character: 15}},
+			{Start: lsp.Position{Line: 2, Character: 18}, End: lsp.Position{Line: 3, Character: 3}},
+		}
+		groups, err := a.aggregate(context.Background(), []SearchResultResolver{fm})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, g := range groups {
+			got = append(got, g.Label())
+		}
+		if want := []string{"a", "c"}; !cmp.Equal(want, got) {
+			t.Errorf("unexpected groups (-want +got):\n%s", cmp.Diff(want, got))
+		}
+	})
+
+	t.Run("added results", func(t *testing.T) {
+		q, err := query.ParseAndCheck("foo aggregate:repo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := newSearchAggregation(q, &search.TextPatternInfo{Pattern: "foo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := a.add(context.Background(), fileResults[:2]); err != nil {
+			t.Fatal(err)
+		}
+		if got := a.addedResultCount(); got != 3 {
+			t.Errorf("got %d added results, want 3", got)
+		}
+		for i := 0; i < 2; i++ {
+			// aggregate must not add the results to a.
+			groups, err := a.aggregate(context.Background(), fileResults[2:3])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(groups) != 2 || groups[0].Label() != "a" || groups[0].Count() != 3 || groups[1].Count() != 3 {
+				t.Fatalf("unexpected groups %+v", groups)
+			}
+		}
+	})
+
+	t.Run("no aggregate field", func(t *testing.T) {
+		q, err := query.ParseAndCheck("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := newSearchAggregation(q, &search.TextPatternInfo{Pattern: "foo"})
+		if err != nil || a != nil {
+			t.Fatalf("got %v, %v, want no aggregation", a, err)
+		}
+	})
+
+	t.Run("capture requires a capture group", func(t *testing.T) {
+		q, err := query.ParseAndCheck("foo aggregate:capture")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := newSearchAggregation(q, &search.TextPatternInfo{Pattern: "foo", IsRegExp: true}); err != errAggregateCapture {
+			t.Fatalf("got error %v, want %v", err, errAggregateCapture)
+		}
+	})
+}
+
+func TestSearchResults_aggregateWithoutKeepingResults(t *testing.T) {
+	mockDecodedViewerFinalSettings = &schema.Settings{}
+	defer func() { mockDecodedViewerFinalSettings = nil }()
+
+	repo := &types.Repo{ID: 1, Name: "repo"}
+	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+		return []*types.Repo{repo}, nil
+	}
+	defer func() { db.Mocks = db.MockStores{} }()
+	db.Mocks.Repos.MockGetByName(t, "repo", 1)
+	db.Mocks.Repos.MockGet(t, 1)
+	db.Mocks.Repos.Count = mockCount
+
+	mockSearchRepositories = func(args *search.TextParameters) ([]SearchResultResolver, *searchResultsCommon, error) {
+		return nil, &searchResultsCommon{}, nil
+	}
+	defer func() { mockSearchRepositories = nil }()
+	mockSearchFilesInRepos = func(args *search.TextParameters) ([]*FileMatchResolver, *searchResultsCommon, error) {
+		return []*FileMatchResolver{
+			mkFileMatch(repo, "a/x.go", 1),
+			mkFileMatch(repo, "a/y.go", 2),
+			mkFileMatch(repo, "b/z.go", 3),
+		}, &searchResultsCommon{repos: []*types.Repo{repo}}, nil
+	}
+	defer func() { mockSearchFilesInRepos = nil }()
+
+	r, err := (&schemaResolver{}).Search(context.Background(), &SearchArgs{Query: "foo aggregate:dir", Version: "V2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := r.Results(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.SearchResults) != 0 {
+		t.Errorf("got %d results, want them to only be counted", len(results.SearchResults))
+	}
+	if got := results.MatchCount(); got != 3 {
+		t.Errorf("got match count %d, want 3", got)
+	}
+	groups, err := results.Aggregations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, g := range *groups {
+		got = append(got, fmt.Sprintf("%s:%d", g.Label(), g.Count()))
+	}
+	if want := []string{"a:2", "b:1"}; !cmp.Equal(want, got) {
+		t.Errorf("unexpected groups (-want +got):\n%s", cmp.Diff(want, got))
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"math/rand"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/zoekt"
+	zoektquery "github.com/google/zoekt/query"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/db"
@@ -499,6 +502,19 @@ func TestSearchResolver_getPatternInfo(t *testing.T) {
 			IsRegExp:       true,
 			ExcludePattern: `f|(\.graphql$|\.gql$|\.graphqls$)`,
 		},
+		"p file:f file:contains(TODO) -file:has.owner(@alice)": {
+			Pattern:                "p",
+			IsRegExp:               true,
+			IncludePatterns:        []string{"f"},
+			FileContentMustInclude: []string{"TODO"},
+			FileOwnersMustExclude:  []string{"@alice"},
+		},
+		"p file:size.min(1kb) file:size.max(1mb) file:size.max(10kb)": {
+			Pattern:     "p",
+			IsRegExp:    true,
+			FileSizeMin: 1000,
+			FileSizeMax: 10000,
+		},
 	}
 	for queryStr, want := range tests {
 		t.Run(queryStr, func(t *testing.T) {
@@ -520,6 +536,33 @@ func TestSearchResolver_getPatternInfo(t *testing.T) {
 	}
 }
 
+func TestSearchResolver_getPatternInfo_near(t *testing.T) {
+	q, err := query.ProcessAndOr("file:f lock near(5) defer unlock", query.ParserOptions{SearchType: query.SearchTypeLiteral})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := searchResolver{query: q}
+	p, err := sr.getPatternInfo(&getPatternInfoOptions{performLiteralSearch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := search.TextPatternInfo{
+		Pattern:         "lock",
+		IsRegExp:        true,
+		IncludePatterns: []string{"f"},
+		FileMatchLimit:  defaultMaxSearchResults,
+		NearPattern:     `defer unlock`,
+		NearDistance:    5,
+	}
+	if !reflect.DeepEqual(*p, want) {
+		t.Errorf("\ngot  %+v\nwant %+v", *p, want)
+	}
+
+	if _, err := sr.getPatternInfo(&getPatternInfoOptions{performStructuralSearch: true}); err == nil {
+		t.Error("expected an error for structural search")
+	}
+}
+
 func TestSearchResolver_DynamicFilters(t *testing.T) {
 	repo := &types.Repo{Name: "testRepo"}
 	repoMatch := &RepositoryResolver{
@@ -1455,3 +1498,117 @@ func TestEvaluateAnd(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluateSubqueries(t *testing.T) {
+	minimalRepos, _, zoektRepos := generateRepos(10)
+	// Each subquery matches different files, so the results only contain
+	// both sets if the subqueries are unioned.
+	files := generateZoektMatches(3)
+	z := &searchbackend.Zoekt{
+		Client: &patternSearcher{
+			fakeSearcher: fakeSearcher{repos: zoektRepos},
+			results: map[string][]zoekt.FileMatch{
+				"foo": files[:2],
+				"bar": files[2:],
+			},
+		},
+		DisableCache: true,
+	}
+
+	var (
+		mu       sync.Mutex
+		included [][]string
+	)
+	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+		mu.Lock()
+		included = append(included, op.IncludePatterns)
+		mu.Unlock()
+		return minimalRepos, nil
+	}
+	db.Mocks.Repos.Count = func(ctx context.Context, opt db.ReposListOptions) (int, error) {
+		return len(minimalRepos), nil
+	}
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	q, err := query.ProcessAndOr("index:only ((repo:a file:x foo) or (repo:b file:y bar))", query.ParserOptions{SearchType: query.SearchTypeLiteral})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := &searchResolver{query: q, zoekt: z, userSettings: &schema.Settings{}}
+	results, err := resolver.Results(context.Background())
+	if err != nil {
+		t.Fatal("Results:", err)
+	}
+	if results.alert != nil {
+		t.Fatalf("unexpected alert %q", results.alert.title)
+	}
+	// The result sets of both subqueries are unioned by file.
+	var got []string
+	for _, r := range results.SearchResults {
+		if fm, ok := r.ToFileMatch(); ok {
+			got = append(got, fm.Repo.Name()+"/"+fm.JPath)
+		}
+	}
+	sort.Strings(got)
+	wantFiles := []string{"repo-1/foobar-1.go", "repo-2/foobar-2.go", "repo-3/foobar-3.go"}
+	if diff := cmp.Diff(wantFiles, got); diff != "" {
+		t.Errorf("unexpected results (-want +got):\n%s", diff)
+	}
+
+	// Each subquery resolves the repositories of its own repo: filter.
+	want := [][]string{{"a"}, {"b"}}
+	if diff := cmp.Diff(want, included); diff != "" {
+		t.Errorf("unexpected repo filters (-want +got):\n%s", diff)
+	}
+}
+
+// patternSearcher is a fake zoekt.Searcher which returns the file matches of
+// the first content pattern of a query found in results.
+type patternSearcher struct {
+	fakeSearcher
+	results map[string][]zoekt.FileMatch
+}
+
+func (ss *patternSearcher) Search(ctx context.Context, q zoektquery.Q, opts *zoekt.SearchOptions) (*zoekt.SearchResult, error) {
+	var files []zoekt.FileMatch
+	zoektquery.VisitAtoms(q, func(q zoektquery.Q) {
+		var pattern string
+		switch q := q.(type) {
+		case *zoektquery.Substring:
+			if q.FileName {
+				return
+			}
+			pattern = q.Pattern
+		case *zoektquery.Regexp:
+			if q.FileName {
+				return
+			}
+			pattern = q.Regexp.String()
+		}
+		if fs, ok := ss.results[pattern]; ok && files == nil {
+			files = fs
+		}
+	})
+	return &zoekt.SearchResult{Files: files}, nil
+}
+
+func TestSparseRepos(t *testing.T) {
+	repos := []*types.Repo{
+		{Name: "github.com/myorg/monorepo"},
+		{Name: "GitHub.com/myorg/Other"},
+		{Name: "github.com/myorg/full"},
+	}
+	strategies := []*schema.GitCloneStrategy{
+		{Pattern: "^github\\.com/myorg/full$", Blobless: true},
+		{Pattern: "[", SparsePaths: []string{"a"}},
+		{Pattern: "^github\\.com/myorg/", SparsePaths: []string{"services"}},
+	}
+	var got []string
+	for _, r := range sparseRepos(strategies, repos) {
+		got = append(got, string(r.Name))
+	}
+	want := []string{"github.com/myorg/monorepo", "GitHub.com/myorg/Other"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
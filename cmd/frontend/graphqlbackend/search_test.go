@@ -515,6 +515,31 @@ func TestEueryForStableResults(t *testing.T) {
 	}
 }
 
+func TestSearchResolver_maxResults(t *testing.T) {
+	cases := []struct {
+		query          string
+		resultLimit    int32
+		maxResultLimit int32
+		want           int32
+	}{
+		{query: "foo", want: defaultMaxSearchResults},
+		{query: "foo", resultLimit: 10000, maxResultLimit: 100000, want: 10000},
+		{query: "foo count:500000", want: 500000},
+		{query: "foo count:500000", resultLimit: 10000, maxResultLimit: 100000, want: 100000},
+		{query: "foo count:500", resultLimit: 10000, maxResultLimit: 100000, want: 500},
+	}
+	for _, c := range cases {
+		q, err := query.ParseAndCheck(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &searchResolver{query: q, resultLimit: c.resultLimit, maxResultLimit: c.maxResultLimit}
+		if got := r.maxResults(); got != c.want {
+			t.Errorf("%q with limits %d, %d: got %d, want %d", c.query, c.resultLimit, c.maxResultLimit, got, c.want)
+		}
+	}
+}
+
 func TestVersionContext(t *testing.T) {
 	conf.Mock(&conf.Unified{
 		SiteConfiguration: schema.SiteConfiguration{
@@ -0,0 +1,121 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/hashicorp/go-multierror"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+func (r *siteResolver) GitserverExecAuditLog(ctx context.Context, args *struct {
+	First      int32
+	Repository *string
+	User       *graphql.ID
+}) (*gitserverExecAuditLogResolver, error) {
+	// 🚨 SECURITY: Only site admins may see which users read which
+	// repositories.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	var actor string
+	if args.User != nil {
+		userID, err := UnmarshalUserID(*args.User)
+		if err != nil {
+			return nil, err
+		}
+		actor = strconv.Itoa(int(userID))
+	}
+
+	// Return the records of the gitservers which could be reached along
+	// with the errors of the others.
+	records, err := gitserver.DefaultClient.ExecAuditLog(ctx)
+	errs := []string{}
+	if err != nil {
+		merr, ok := err.(*multierror.Error)
+		if !ok {
+			return nil, err
+		}
+		for _, e := range merr.Errors {
+			errs = append(errs, e.Error())
+		}
+	}
+
+	resolvers := []*gitserverExecAuditRecordResolver{}
+	for i := range records {
+		if len(resolvers) >= int(args.First) {
+			break
+		}
+		if args.Repository != nil && string(records[i].Repo) != *args.Repository {
+			continue
+		}
+		if actor != "" && records[i].Actor != actor {
+			continue
+		}
+		resolvers = append(resolvers, &gitserverExecAuditRecordResolver{record: &records[i]})
+	}
+	return &gitserverExecAuditLogResolver{records: resolvers, errors: errs}, nil
+}
+
+type gitserverExecAuditLogResolver struct {
+	records []*gitserverExecAuditRecordResolver
+	errors  []string
+}
+
+func (r *gitserverExecAuditLogResolver) Records() []*gitserverExecAuditRecordResolver {
+	return r.records
+}
+
+func (r *gitserverExecAuditLogResolver) Errors() []string { return r.errors }
+
+type gitserverExecAuditRecordResolver struct {
+	record *protocol.ExecAuditRecord
+}
+
+func (r *gitserverExecAuditRecordResolver) Timestamp() DateTime {
+	return DateTime{Time: r.record.Time}
+}
+
+func (r *gitserverExecAuditRecordResolver) User(ctx context.Context) (*UserResolver, error) {
+	userID, err := strconv.ParseInt(r.record.Actor, 10, 32)
+	if err != nil || userID == 0 {
+		// Internal and anonymous requests have no user.
+		return nil, nil
+	}
+	user, err := UserByIDInt32(ctx, int32(userID))
+	if err != nil && errcode.IsNotFound(err) {
+		// Don't throw an error if a user has been deleted.
+		return nil, nil
+	}
+	return user, err
+}
+
+func (r *gitserverExecAuditRecordResolver) Internal() bool { return r.record.Actor == "internal" }
+
+func (r *gitserverExecAuditRecordResolver) Service() string { return r.record.Service }
+
+func (r *gitserverExecAuditRecordResolver) Repository() string { return string(r.record.Repo) }
+
+func (r *gitserverExecAuditRecordResolver) Arguments() []string {
+	if r.record.Args == nil {
+		return []string{}
+	}
+	return r.record.Args
+}
+
+func (r *gitserverExecAuditRecordResolver) DurationMilliseconds() int32 {
+	return int32(r.record.Duration.Milliseconds())
+}
+
+func (r *gitserverExecAuditRecordResolver) ExitStatus() int32 { return int32(r.record.ExitStatus) }
+
+func (r *gitserverExecAuditRecordResolver) OutputBytes() float64 {
+	return float64(r.record.StdoutBytes)
+}
+
+func (r *gitserverExecAuditRecordResolver) Status() string { return r.record.Status }
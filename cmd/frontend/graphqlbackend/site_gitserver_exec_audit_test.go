@@ -0,0 +1,146 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go/gqltesting"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+func TestSiteGitserverExecAuditLog(t *testing.T) {
+	resetMocks()
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+	db.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		return &types.User{ID: id, Username: "alice"}, nil
+	}
+
+	now := time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)
+	gitserver.MockExecAuditLog = func() ([]protocol.ExecAuditRecord, error) {
+		return []protocol.ExecAuditRecord{
+			{Time: now, Actor: "1", Service: "frontend", Repo: "github.com/foo/bar", Args: []string{"show", "HEAD:README.md"}, Duration: 12 * time.Millisecond, StdoutBytes: 42, Status: "0"},
+			{Time: now, Actor: "internal", Service: "searcher", Repo: "github.com/foo/bar", Args: []string{"archive"}, ExitStatus: -1, Status: "denied"},
+			{Time: now, Actor: "1", Service: "frontend", Repo: "github.com/foo/baz", Args: []string{"log"}, Status: "0"},
+		}, nil
+	}
+	defer func() { gitserver.MockExecAuditLog = nil }()
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: mustParseGraphQLSchema(t),
+			Query: `
+				{
+					site {
+						gitserverExecAuditLog(repository: "github.com/foo/bar") {
+							records {
+								timestamp
+								user { username }
+								internal
+								service
+								arguments
+								durationMilliseconds
+								exitStatus
+								outputBytes
+								status
+							}
+							errors
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"site": {
+						"gitserverExecAuditLog": {
+							"records": [
+								{
+									"timestamp": "2020-09-01T12:00:00Z",
+									"user": { "username": "alice" },
+									"internal": false,
+									"service": "frontend",
+									"arguments": ["show", "HEAD:README.md"],
+									"durationMilliseconds": 12,
+									"exitStatus": 0,
+									"outputBytes": 42,
+									"status": "0"
+								},
+								{
+									"timestamp": "2020-09-01T12:00:00Z",
+									"user": null,
+									"internal": true,
+									"service": "searcher",
+									"arguments": ["archive"],
+									"durationMilliseconds": 0,
+									"exitStatus": -1,
+									"outputBytes": 0,
+									"status": "denied"
+								}
+							],
+							"errors": []
+						}
+					}
+				}
+			`,
+		},
+		{
+			Schema: mustParseGraphQLSchema(t),
+			Query: `
+				{
+					site {
+						gitserverExecAuditLog(user: "VXNlcjox", first: 1) {
+							records { repository }
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"site": {
+						"gitserverExecAuditLog": {
+							"records": [{ "repository": "github.com/foo/bar" }]
+						}
+					}
+				}
+			`,
+		},
+	})
+
+	// The records of the other gitservers are returned if one fails.
+	gitserver.MockExecAuditLog = func() ([]protocol.ExecAuditRecord, error) {
+		records := []protocol.ExecAuditRecord{{Time: now, Actor: "1", Service: "frontend", Repo: "github.com/foo/bar", Status: "0"}}
+		return records, multierror.Append(nil, errors.New("gitserver gitserver-1:3178: connection refused"))
+	}
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: mustParseGraphQLSchema(t),
+			Query: `
+				{
+					site {
+						gitserverExecAuditLog {
+							records { repository }
+							errors
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"site": {
+						"gitserverExecAuditLog": {
+							"records": [{ "repository": "github.com/foo/bar" }],
+							"errors": ["gitserver gitserver-1:3178: connection refused"]
+						}
+					}
+				}
+			`,
+		},
+	})
+}
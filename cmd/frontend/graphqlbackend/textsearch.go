@@ -11,12 +11,14 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/go-langserver/pkg/lsp"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 
 	otlog "github.com/opentracing/opentracing-go/log"
 
 	"github.com/inconshreveable/log15"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/endpoint"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
@@ -41,6 +43,7 @@ type FileMatchResolver struct {
 	JPath        string       `json:"Path"`
 	JLineMatches []*lineMatch `json:"LineMatches"`
 	JLimitHit    bool         `json:"LimitHit"`
+	JRanges      []lsp.Range  `json:"Ranges,omitempty"`
 	MatchCount   int          // Number of matches. Different from len(JLineMatches), as multiple lines may correspond to one logical match.
 	symbols      []*searchSymbolResult
 	uri          string
@@ -107,6 +110,14 @@ func (fm *FileMatchResolver) LimitHit() bool {
 	return fm.JLimitHit
 }
 
+func (fm *FileMatchResolver) Ranges() []RangeResolver {
+	ranges := make([]RangeResolver, len(fm.JRanges))
+	for i, r := range fm.JRanges {
+		ranges[i] = NewRangeResolver(r)
+	}
+	return ranges
+}
+
 func (fm *FileMatchResolver) ToRepository() (*RepositoryResolver, bool) { return nil, false }
 func (fm *FileMatchResolver) ToFileMatch() (*FileMatchResolver, bool)   { return fm, true }
 func (fm *FileMatchResolver) ToCommitSearchResult() (*commitSearchResultResolver, bool) {
@@ -123,6 +134,7 @@ func (fm *FileMatchResolver) path() string {
 // counts and limit.
 func (fm *FileMatchResolver) appendMatches(src *FileMatchResolver) {
 	fm.JLineMatches = append(fm.JLineMatches, src.JLineMatches...)
+	fm.JRanges = append(fm.JRanges, src.JRanges...)
 	fm.MatchCount += src.MatchCount
 	fm.JLimitHit = fm.JLimitHit || src.JLimitHit
 }
@@ -191,15 +203,13 @@ func searchFilesInRepo(ctx context.Context, searcherURLs *endpoint.Map, repo *ty
 		return nil, false, err
 	}
 
-	matches, limitHit, err := searcher.Search(ctx, searcherURLs, gitserverRepo, commit, info, fetchTimeout)
-	if err != nil {
-		return nil, false, err
-	}
-
 	workspace := fileMatchURI(repo.Name, rev, "")
 	repoResolver := &RepositoryResolver{repo: repo}
-	resolvers := make([]*FileMatchResolver, 0, len(matches))
-	for _, fm := range matches {
+	var resolvers []*FileMatchResolver
+
+	// We stream results from searcher so that matches found before a timeout
+	// are not lost.
+	limitHit, err := searcher.StreamSearch(ctx, searcherURLs, gitserverRepo, commit, info, fetchTimeout, func(fm *protocol.FileMatch) {
 		lineMatches := make([]*lineMatch, 0, len(fm.LineMatches))
 		for _, lm := range fm.LineMatches {
 			ranges := make([][2]int32, 0, len(lm.OffsetAndLengths))
@@ -214,10 +224,19 @@ func searchFilesInRepo(ctx context.Context, searcherURLs *endpoint.Map, repo *ty
 			})
 		}
 
+		var matchRanges []lsp.Range
+		for _, r := range fm.Ranges {
+			matchRanges = append(matchRanges, lsp.Range{
+				Start: lsp.Position{Line: r.Start.Line, Character: r.Start.Column},
+				End:   lsp.Position{Line: r.End.Line, Character: r.End.Column},
+			})
+		}
+
 		resolvers = append(resolvers, &FileMatchResolver{
 			JPath:        fm.Path,
 			JLineMatches: lineMatches,
 			JLimitHit:    fm.LimitHit,
+			JRanges:      matchRanges,
 			MatchCount:   fm.MatchCount,
 
 			uri:      workspace + fm.Path,
@@ -225,8 +244,12 @@ func searchFilesInRepo(ctx context.Context, searcherURLs *endpoint.Map, repo *ty
 			CommitID: commit,
 			InputRev: &rev,
 		})
+	})
+	if err != nil && !errcode.IsTimeout(err) {
+		return nil, false, err
 	}
 
+	// On timeout we return the partial results along with the error.
 	return resolvers, limitHit, err
 }
 
@@ -289,8 +312,20 @@ var mockSearchFilesInRepos func(args *search.TextParameters) ([]*FileMatchResolv
 
 // searchFilesInRepos searches a set of repos for a pattern.
 func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res []*FileMatchResolver, common *searchResultsCommon, err error) {
+	return searchFilesInReposStream(ctx, args, nil)
+}
+
+// searchFilesInReposStream is like searchFilesInRepos, but if onMatches is
+// non-nil it is called with the matches of each repository as soon as they
+// are found, and they are not returned. onMatches is called sequentially.
+func searchFilesInReposStream(ctx context.Context, args *search.TextParameters, onMatches func([]*FileMatchResolver)) (res []*FileMatchResolver, common *searchResultsCommon, err error) {
 	if mockSearchFilesInRepos != nil {
-		return mockSearchFilesInRepos(args)
+		res, common, err = mockSearchFilesInRepos(args)
+		if onMatches != nil && len(res) > 0 {
+			onMatches(res)
+			res = nil
+		}
+		return res, common, err
 	}
 
 	tr, ctx := trace.New(ctx, "searchFilesInRepos", fmt.Sprintf("query: %s, numRepoRevs: %d", args.PatternInfo.Pattern, len(args.Repos)))
@@ -374,7 +409,11 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 				a, b := matches[i].uri, matches[j].uri
 				return a > b
 			})
-			unflattened = append(unflattened, matches)
+			if onMatches != nil {
+				onMatches(matches)
+			} else {
+				unflattened = append(unflattened, matches)
+			}
 			flattenedSize += len(matches)
 
 			// Stop searching once we have found enough matches. This does
@@ -211,6 +211,46 @@ func TestRepoShouldBeSearched(t *testing.T) {
 	}
 }
 
+func TestSearchFilesInRepo_ranges(t *testing.T) {
+	git.Mocks.ResolveRevision = func(spec string, opt git.ResolveRevisionOptions) (api.CommitID, error) {
+		return "1a2b3c", nil
+	}
+	defer git.ResetMocks()
+	searcher.MockSearch = func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*protocol.FileMatch, limitHit bool, err error) {
+		return []*protocol.FileMatch{{
+			Path: "main.go",
+			LineMatches: []protocol.LineMatch{
+				{Preview: "\tfoo(a,", LineNumber: 3, OffsetAndLengths: [][2]int{{1, 6}}},
+				{Preview: "\t\tb)", LineNumber: 4, OffsetAndLengths: [][2]int{{0, 4}}},
+			},
+			MatchCount: 1,
+			Ranges: []protocol.Range{{
+				Start: protocol.Location{Offset: 29, Line: 3, Column: 1},
+				End:   protocol.Location{Offset: 40, Line: 4, Column: 4},
+			}},
+		}}, false, nil
+	}
+	defer func() { searcher.MockSearch = nil }()
+
+	info := &search.TextPatternInfo{Pattern: `(?s)foo\(.*?\)`, IsRegExp: true, FileMatchLimit: defaultMaxSearchResults}
+	matches, _, err := searchFilesInRepo(context.Background(), nil, &types.Repo{Name: "foo/one"}, gitserver.Repo{Name: "foo/one"}, "", info, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %d file matches, want 1", len(matches))
+	}
+
+	ranges := matches[0].Ranges()
+	if len(ranges) != 1 {
+		t.Fatalf("got %d ranges, want 1", len(ranges))
+	}
+	start, end := ranges[0].Start(), ranges[0].End()
+	if got := [4]int32{start.Line(), start.Character(), end.Line(), end.Character()}; got != [4]int32{3, 1, 4, 4} {
+		t.Errorf("got range %v, want [3 1 4 4]", got)
+	}
+}
+
 func makeRepositoryRevisions(repos ...string) []*search.RepositoryRevisions {
 	r := make([]*search.RepositoryRevisions, len(repos))
 	for i, repospec := range repos {
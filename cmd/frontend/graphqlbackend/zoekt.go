@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"regexp"
 	"regexp/syntax"
+	"sort"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -87,6 +89,18 @@ func newIndexedSearchRequest(ctx context.Context, args *search.TextParameters, t
 		}, nil
 	}
 
+	// Zoekt cannot evaluate the owner and size predicates of files, so
+	// searcher has to search all repositories. Structural search only uses
+	// zoekt to find candidate files, searcher evaluates the predicates on them.
+	if args.PatternInfo != nil && args.PatternInfo.HasUnindexedFilePredicates() && !args.PatternInfo.IsStructuralPat && typ != symbolRequest {
+		if indexParam == Only {
+			return nil, errors.New("index:only can not be used with file:has.owner(...) or file size predicates")
+		}
+		return &indexedSearchRequest{
+			Unindexed: args.Repos,
+		}, nil
+	}
+
 	// Fallback to Unindexed if index:no
 	if indexParam == No {
 		return &indexedSearchRequest{
@@ -325,9 +339,23 @@ func zoektSearch(ctx context.Context, args *search.TextParameters, repos *indexe
 		limitHit = true
 	}
 
+	var near *zoektNearFilter
+	if args.PatternInfo.NearPattern != "" && typ != symbolRequest {
+		near, err = newZoektNearFilter(args.PatternInfo)
+		if err != nil {
+			return nil, false, nil, err
+		}
+	}
+
 	matches := make([]*FileMatchResolver, 0, len(resp.Files))
 	repoResolvers := make(RepositoryResolverCache)
 	for _, file := range resp.Files {
+		if near != nil {
+			if file.LineMatches = near.filter(file.LineMatches); len(file.LineMatches) == 0 {
+				continue
+			}
+		}
+
 		fileLimitHit := false
 		if len(file.LineMatches) > maxLineMatches {
 			file.LineMatches = file.LineMatches[:maxLineMatches]
@@ -373,6 +401,89 @@ func zoektSearch(ctx context.Context, args *search.TextParameters, repos *indexe
 	return matches, limitHit, reposLimitHit, nil
 }
 
+// patternToZoektQuery returns the zoekt query for pattern, which is
+// interpreted according to the options of query.
+func patternToZoektQuery(pattern string, query *search.TextPatternInfo) (zoektquery.Q, error) {
+	if query.IsRegExp {
+		fileNameOnly := query.PatternMatchesPath && !query.PatternMatchesContent
+		return parseRe(pattern, fileNameOnly, query.IsCaseSensitive)
+	}
+	return &zoektquery.Substring{
+		Pattern:       pattern,
+		CaseSensitive: query.IsCaseSensitive,
+
+		FileName: true,
+		Content:  true,
+	}, nil
+}
+
+// zoektNearFilter filters the line matches of zoekt for a near(N) query, see
+// search.TextPatternInfo.NearPattern.
+type zoektNearFilter struct {
+	re, nearRe *regexp.Regexp
+	distance   int
+}
+
+func newZoektNearFilter(p *search.TextPatternInfo) (*zoektNearFilter, error) {
+	compile := func(pattern string) (*regexp.Regexp, error) {
+		// Compile the patterns like searcher does.
+		if !p.IsRegExp {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		if p.IsWordMatch {
+			pattern = `\b` + pattern + `\b`
+		}
+		if !p.IsCaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		return regexp.Compile(pattern)
+	}
+	re, err := compile(p.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	nearRe, err := compile(p.NearPattern)
+	if err != nil {
+		return nil, err
+	}
+	return &zoektNearFilter{re: re, nearRe: nearRe, distance: p.NearDistance}, nil
+}
+
+// filter returns the line matches of a file which match one pattern at most
+// distance lines away from a line matching the other pattern.
+func (f *zoektNearFilter) filter(lines []zoekt.LineMatch) []zoekt.LineMatch {
+	var matched, nearMatched []int
+	for _, l := range lines {
+		if l.FileName {
+			continue
+		}
+		if f.re.Match(l.Line) {
+			matched = append(matched, l.LineNumber)
+		}
+		if f.nearRe.Match(l.Line) {
+			nearMatched = append(nearMatched, l.LineNumber)
+		}
+	}
+
+	// within reports whether line is at most distance lines away from one
+	// of lineNumbers, which are ordered.
+	within := func(line int, lineNumbers []int) bool {
+		i := sort.SearchInts(lineNumbers, line-f.distance)
+		return i < len(lineNumbers) && lineNumbers[i] <= line+f.distance
+	}
+
+	var filtered []zoekt.LineMatch
+	for _, l := range lines {
+		if l.FileName {
+			continue
+		}
+		if (f.re.Match(l.Line) && within(l.LineNumber, nearMatched)) || (f.nearRe.Match(l.Line) && within(l.LineNumber, matched)) {
+			filtered = append(filtered, l)
+		}
+	}
+	return filtered
+}
+
 func zoektFileMatchToLineMatches(maxLineFragmentMatches int, file *zoekt.FileMatch) ([]*lineMatch, int) {
 	var matchCount int
 	lines := make([]*lineMatch, 0, len(file.LineMatches))
@@ -506,25 +617,37 @@ func fileRe(pattern string, queryIsCaseSensitive bool) (zoektquery.Q, error) {
 	return parseRe(pattern, true, queryIsCaseSensitive)
 }
 
+// contentRe returns a query which matches pattern only in file contents.
+func contentRe(pattern string, queryIsCaseSensitive bool) (zoektquery.Q, error) {
+	q, err := parseRe(pattern, false, queryIsCaseSensitive)
+	if err != nil {
+		return nil, err
+	}
+	switch q := q.(type) {
+	case *zoektquery.Substring:
+		q.Content = true
+	case *zoektquery.Regexp:
+		q.Content = true
+	}
+	return q, nil
+}
+
 func queryToZoektQuery(query *search.TextPatternInfo, typ indexedRequestType) (zoektquery.Q, error) {
 	var and []zoektquery.Q
 
-	var q zoektquery.Q
-	var err error
-	if query.IsRegExp {
-		fileNameOnly := query.PatternMatchesPath && !query.PatternMatchesContent
-		q, err = parseRe(query.Pattern, fileNameOnly, query.IsCaseSensitive)
+	q, err := patternToZoektQuery(query.Pattern, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zoekt has no proximity operator. It finds the files containing both
+	// patterns of a near(N) query, and zoektSearch filters their lines.
+	if query.NearPattern != "" {
+		nearQ, err := patternToZoektQuery(query.NearPattern, query)
 		if err != nil {
 			return nil, err
 		}
-	} else {
-		q = &zoektquery.Substring{
-			Pattern:       query.Pattern,
-			CaseSensitive: query.IsCaseSensitive,
-
-			FileName: true,
-			Content:  true,
-		}
+		q = zoektquery.NewAnd(q, nearQ)
 	}
 
 	if query.IsNegated {
@@ -558,6 +681,24 @@ func queryToZoektQuery(query *search.TextPatternInfo, typ indexedRequestType) (z
 		and = append(and, &zoektquery.Not{Child: q})
 	}
 
+	// file:contains(...) predicates select files without contributing
+	// matches. Zoekt doesn't collect matches under a negation, so a file
+	// which must contain a pattern is expressed as a double negation.
+	for _, p := range query.FileContentMustInclude {
+		q, err := contentRe(p, query.IsCaseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, &zoektquery.Not{Child: &zoektquery.Not{Child: q}})
+	}
+	for _, p := range query.FileContentMustExclude {
+		q, err := contentRe(p, query.IsCaseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, &zoektquery.Not{Child: q})
+	}
+
 	// For conditionals that happen on a repo we can use type:repo queries. eg
 	// (type:repo file:foo) (type:repo file:bar) will match all repos which
 	// contain a filename matching "foo" and a filename matchinb "bar".
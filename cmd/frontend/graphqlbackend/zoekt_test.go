@@ -581,6 +581,17 @@ func TestQueryToZoektQuery(t *testing.T) {
 			},
 			Query: `foo (type:repo file:\.go$) (type:repo file:\.yaml$) -(type:repo file:\.java$) -(type:repo file:\.xml$)`,
 		},
+		{
+			Name: "near",
+			Type: textRequest,
+			Pattern: &search.TextPatternInfo{
+				IsRegExp:     true,
+				Pattern:      "lock",
+				NearPattern:  "unlock",
+				NearDistance: 5,
+			},
+			Query: `lock unlock case:no`,
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.Name, func(t *testing.T) {
@@ -599,6 +610,72 @@ func TestQueryToZoektQuery(t *testing.T) {
 	}
 }
 
+func TestQueryToZoektQuery_fileContains(t *testing.T) {
+	// The zoekt query syntax can't express a double negation.
+	got, err := queryToZoektQuery(&search.TextPatternInfo{
+		IsRegExp:               true,
+		Pattern:                "foo",
+		FileContentMustInclude: []string{"TODO"},
+		FileContentMustExclude: []string{"^FIXME"},
+	}, textRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `(and substr:"foo" (not (not content_substr:"TODO")) (not regex:"(?m:^FIXME)"))`
+	if got.String() != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestZoektNearFilter(t *testing.T) {
+	f, err := newZoektNearFilter(&search.TextPatternInfo{
+		IsRegExp:     true,
+		Pattern:      "lock",
+		NearPattern:  "defer",
+		NearDistance: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []zoekt.LineMatch{
+		{LineNumber: 1, Line: []byte("mu.Lock()")},
+		{LineNumber: 2, Line: []byte("defer mu.Unlock()")},
+		{LineNumber: 10, Line: []byte("mu.Lock()")},
+		{LineNumber: 20, Line: []byte("defer f.Close()")},
+		{FileName: true, Line: []byte("lock.go")},
+	}
+	var got []int
+	for _, l := range f.filter(lines) {
+		got = append(got, l.LineNumber)
+	}
+	if diff := cmp.Diff([]int{1, 2}, got); diff != "" {
+		t.Errorf("unexpected lines (-want +got):\n%s", diff)
+	}
+
+	// Word matches must not match within words, like in searcher.
+	f, err = newZoektNearFilter(&search.TextPatternInfo{
+		IsWordMatch:  true,
+		Pattern:      "lock",
+		NearPattern:  "defer",
+		NearDistance: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines = []zoekt.LineMatch{
+		{LineNumber: 1, Line: []byte("defer mu.Unlock()")},
+		{LineNumber: 10, Line: []byte("mu.Lock()")},
+		{LineNumber: 11, Line: []byte("defer mu.Unlock()")},
+	}
+	got = nil
+	for _, l := range f.filter(lines) {
+		got = append(got, l.LineNumber)
+	}
+	if diff := cmp.Diff([]int{10, 11}, got); diff != "" {
+		t.Errorf("unexpected lines for word match (-want +got):\n%s", diff)
+	}
+}
+
 func queryEqual(a, b zoektquery.Q) bool {
 	sortChildren := func(q zoektquery.Q) zoektquery.Q {
 		switch s := q.(type) {
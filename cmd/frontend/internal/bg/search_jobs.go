@@ -0,0 +1,165 @@
+package bg
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/keegancsmith/sqlf"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db/basestore"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil"
+	"github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker"
+	dbworkerstore "github.com/sourcegraph/sourcegraph/internal/workerutil/dbworker/store"
+)
+
+var searchJobWorkers, _ = strconv.Atoi(env.Get("SEARCH_JOB_WORKERS", "1", "number of search jobs each frontend runs concurrently"))
+
+// RunSearchJobWorker runs the search jobs created with the createSearchJob
+// GraphQL mutation, and resets the jobs of frontends which died while running
+// them. It does not return.
+func RunSearchJobWorker(ctx context.Context) {
+	store := newSearchJobStore()
+
+	resetter := dbworker.NewResetter(store, dbworker.ResetterOptions{
+		Name:     "search job resetter",
+		Interval: time.Minute,
+		Metrics:  newSearchJobResetterMetrics(prometheus.DefaultRegisterer),
+	})
+	go resetter.Start()
+
+	worker := dbworker.NewWorker(ctx, store, dbworker.WorkerOptions{
+		Name:        "search_job_worker",
+		Handler:     dbworker.HandlerFunc(handleSearchJob),
+		NumHandlers: searchJobWorkers,
+		Interval:    5 * time.Second,
+		Metrics: workerutil.WorkerMetrics{
+			HandleOperation: newSearchJobObservationOperation(),
+		},
+	})
+	worker.Start()
+}
+
+func newSearchJobStore() dbworkerstore.Store {
+	return dbworkerstore.NewStore(basestore.NewHandleWithDB(dbconn.Global), dbworkerstore.StoreOptions{
+		TableName: "search_jobs",
+		ColumnExpressions: append(dbworkerstore.DefaultColumnExpressions(),
+			sqlf.Sprintf("user_id"),
+			sqlf.Sprintf("query"),
+			sqlf.Sprintf("pattern_type"),
+		),
+		Scan:              scanSearchJob,
+		OrderByExpression: sqlf.Sprintf("id"),
+		StalledMaxAge:     30 * time.Second,
+		// A job which keeps crashing the frontend is marked as errored.
+		MaxNumResets: 5,
+	})
+}
+
+// runSearchJob is called by handleSearchJob, it is replaced by tests.
+var runSearchJob = graphqlbackend.RunSearchJob
+
+// searchJobRecord is a search job dequeued by the worker.
+type searchJobRecord struct {
+	types.SearchJob
+}
+
+func (r *searchJobRecord) RecordID() int {
+	return int(r.ID)
+}
+
+func handleSearchJob(ctx context.Context, _ dbworkerstore.Store, record workerutil.Record) error {
+	// The results are stored outside of the transaction of the worker, so
+	// that the progress of the job is visible while it runs. The results
+	// tables have no foreign key to search_jobs, so storing them doesn't
+	// block on the lock the worker holds on the job.
+	return runSearchJob(ctx, &record.(*searchJobRecord).SearchJob)
+}
+
+func scanSearchJob(rows *sql.Rows, queryErr error) (_ workerutil.Record, _ bool, err error) {
+	if queryErr != nil {
+		return nil, false, queryErr
+	}
+	defer func() { err = basestore.CloseRows(rows, err) }()
+
+	if rows.Next() {
+		var (
+			r            searchJobRecord
+			processAfter sql.NullTime
+			numResets    int
+		)
+		if err := rows.Scan(
+			&r.ID,
+			&r.State,
+			&r.FailureMessage,
+			&r.StartedAt,
+			&r.FinishedAt,
+			&processAfter,
+			&numResets,
+			&r.UserID,
+			&r.Query,
+			&r.PatternType,
+		); err != nil {
+			return nil, false, err
+		}
+		return &r, true, nil
+	}
+	return nil, false, nil
+}
+
+func newSearchJobObservationOperation() *observation.Operation {
+	observationContext := &observation.Context{
+		Logger:     log15.Root(),
+		Tracer:     &trace.Tracer{Tracer: opentracing.GlobalTracer()},
+		Registerer: prometheus.DefaultRegisterer,
+	}
+
+	m := metrics.NewOperationMetrics(
+		observationContext.Registerer,
+		"search_job_worker",
+		metrics.WithLabels("op"),
+		metrics.WithCountHelp("Total number of results returned"),
+	)
+
+	return observationContext.Operation(observation.Op{
+		Name:         "SearchJobWorker.Process",
+		MetricLabels: []string{"process"},
+		Metrics:      m,
+	})
+}
+
+func newSearchJobResetterMetrics(r prometheus.Registerer) dbworker.ResetterMetrics {
+	resets := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "src_search_job_queue_resets_total",
+		Help: "Total number of search jobs put back into queued state",
+	})
+	r.MustRegister(resets)
+
+	resetFailures := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "src_search_job_queue_max_resets_total",
+		Help: "Total number of search jobs that exceed the max number of resets",
+	})
+	r.MustRegister(resetFailures)
+
+	errors := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "src_search_job_queue_reset_errors_total",
+		Help: "Total number of errors when running the search job resetter",
+	})
+	r.MustRegister(errors)
+
+	return dbworker.ResetterMetrics{
+		RecordResets:        resets,
+		RecordResetFailures: resetFailures,
+		Errors:              errors,
+	}
+}
@@ -0,0 +1,82 @@
+package bg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
+)
+
+func TestHandleSearchJob(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+
+	user, err := db.Users.Create(ctx, db.NewUser{Username: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbconn.Global.ExecContext(ctx, "INSERT INTO repo(name) VALUES ('a')"); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := db.Repos.GetByName(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := db.SearchJobs.Create(ctx, &types.SearchJob{UserID: user.ID, Query: "foo", PatternType: "literal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Store results like graphqlbackend.RunSearchJob does, without running a
+	// search.
+	defer func(orig func(context.Context, *types.SearchJob) error) { runSearchJob = orig }(runSearchJob)
+	runSearchJob = func(ctx context.Context, job *types.SearchJob) error {
+		if err := db.SearchJobs.SetRepos(ctx, job.ID, []*types.SearchJobRepo{{RepoID: repo.ID}}); err != nil {
+			return err
+		}
+		unsearched, err := db.SearchJobs.ListUnsearchedRepos(ctx, job.ID)
+		if err != nil {
+			return err
+		}
+		results := []*types.SearchJobResult{{Repo: "a", Commit: "c", Path: "x.go", Line: 1, Preview: "foo"}}
+		return db.SearchJobs.AddRepoResults(ctx, job.ID, unsearched[0], results, false)
+	}
+
+	store := newSearchJobStore()
+	record, tx, ok, err := store.Dequeue(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("no search job dequeued")
+	}
+
+	// The handler must not block on the lock the worker holds on the job.
+	handleCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := handleSearchJob(handleCtx, tx, record); err != nil {
+		t.Fatal(tx.Done(err))
+	}
+	if _, err := tx.MarkComplete(ctx, record.RecordID()); err != nil {
+		t.Fatal(tx.Done(err))
+	}
+	if err := tx.Done(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err = db.SearchJobs.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != "completed" || job.ReposSearched != 1 || job.ResultCount != 1 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+}
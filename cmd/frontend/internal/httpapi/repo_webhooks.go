@@ -0,0 +1,115 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab/webhooks"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
+)
+
+// repoWebhookEvents are the webhook events of each external service kind
+// which are handled by repo-updater, keyed by the value of the header naming
+// the event.
+var repoWebhookEvents = map[string]map[string]bool{
+	extsvc.KindGitHub: {
+		"push":       true,
+		"repository": true,
+	},
+	extsvc.KindGitLab: {
+		"Push Hook":     true,
+		"Tag Push Hook": true,
+		"System Hook":   true,
+	},
+	extsvc.KindBitbucketServer: {
+		"repo:refs_changed": true,
+		"repo:modified":     true,
+		"repo:forked":       true,
+	},
+}
+
+// repoWebhookEventHeaders are the headers naming the webhook event of each
+// external service kind.
+var repoWebhookEventHeaders = map[string]string{
+	extsvc.KindGitHub:          "X-GitHub-Event",
+	extsvc.KindGitLab:          "X-Gitlab-Event",
+	extsvc.KindBitbucketServer: "X-Event-Key",
+}
+
+// repoWebhookHandler returns a handler which forwards the webhook events of
+// code hosts of the given kind which push to, create, rename or delete
+// repositories to repo-updater. All other events are served by next.
+func repoWebhookHandler(kind string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := r.Header.Get(repoWebhookEventHeaders[kind])
+		if !repoWebhookEvents[kind][event] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		payload, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// GitLab system hooks also include merge request events, which
+		// are not about repositories.
+		if kind == extsvc.KindGitLab && event == "System Hook" && !isGitLabRepoSystemHook(payload) {
+			r.Body = ioutil.NopCloser(bytes.NewReader(payload))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		req := protocol.WebhookRequest{
+			Kind:    kind,
+			Event:   event,
+			Payload: payload,
+		}
+		if kind == extsvc.KindGitLab {
+			req.Signature = r.Header.Get(webhooks.TokenHeaderName)
+		} else {
+			req.Signature = r.Header.Get("X-Hub-Signature")
+		}
+		// The ID could be blank if the webhook was set up before we added it
+		// to the URL, in which case all external services are tried.
+		if rawID := r.URL.Query().Get(extsvc.IDParam); rawID != "" {
+			req.ExternalServiceID, err = strconv.ParseInt(rawID, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid external service ID", http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, err := repoupdater.DefaultClient.Webhook(r.Context(), req)
+		if err != nil {
+			log15.Error("repo-updater webhook failed", "kind", kind, "event", event, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if result.ErrorUnauthorized {
+			http.Error(w, "could not authenticate webhook", http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// isGitLabRepoSystemHook returns true if the GitLab system hook payload is a
+// push or project event.
+func isGitLabRepoSystemHook(payload []byte) bool {
+	var e struct {
+		ObjectKind string `json:"object_kind"`
+	}
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return false
+	}
+	// Project events only have an event_name.
+	return e.ObjectKind == "" || e.ObjectKind == "push" || e.ObjectKind == "tag_push"
+}
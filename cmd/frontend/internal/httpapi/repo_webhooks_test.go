@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
+)
+
+func TestRepoWebhookHandler(t *testing.T) {
+	var forwarded []protocol.WebhookRequest
+	repoupdater.MockWebhook = func(ctx context.Context, req protocol.WebhookRequest) (*protocol.WebhookResult, error) {
+		forwarded = append(forwarded, req)
+		return &protocol.WebhookResult{ErrorUnauthorized: req.Signature != "sha256=ok"}, nil
+	}
+	defer func() { repoupdater.MockWebhook = nil }()
+
+	var served int
+	h := repoWebhookHandler(extsvc.KindGitHub, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, tc := range []struct {
+		event, signature string
+		code             int
+	}{
+		{event: "push", signature: "sha256=ok", code: http.StatusOK},
+		{event: "repository", signature: "sha256=bad", code: http.StatusUnauthorized},
+		{event: "pull_request", signature: "sha256=ok", code: http.StatusNoContent},
+	} {
+		req := httptest.NewRequest("POST", "/github-webhooks?"+extsvc.IDParam+"=7", strings.NewReader(`{}`))
+		req.Header.Set("X-GitHub-Event", tc.event)
+		req.Header.Set("X-Hub-Signature", tc.signature)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s: got status %d, want %d", tc.event, rec.Code, tc.code)
+		}
+	}
+
+	if len(forwarded) != 2 {
+		t.Fatalf("got %d forwarded events, want 2", len(forwarded))
+	}
+	if got := forwarded[0]; got.ExternalServiceID != 7 || got.Event != "push" || string(got.Payload) != "{}" {
+		t.Errorf("got forwarded request %+v", got)
+	}
+	if served != 1 {
+		t.Errorf("got %d events served by the next handler, want 1", served)
+	}
+}
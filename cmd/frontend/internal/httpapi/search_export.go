@@ -0,0 +1,193 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+// maxSearchExportResults is the number of results an export searches for
+// unless the query specifies count:.
+const maxSearchExportResults = 10000
+
+// maxSearchExportCount is the largest count: an export searches for. Larger
+// values of count: are reduced to it.
+const maxSearchExportCount = 100000
+
+// searchExportLimitHitTrailer is the HTTP trailer which reports whether the
+// exported results are incomplete because a result limit was hit.
+const searchExportLimitHitTrailer = "X-Sourcegraph-Limit-Hit"
+
+// exportSearchResults is graphqlbackend.ExportSearchResults, except in tests.
+var exportSearchResults = graphqlbackend.ExportSearchResults
+
+// serveSearchExport runs the search in the "q" query parameter and responds
+// with all results as CSV (format=csv, the default) or newline-delimited JSON
+// (format=jsonl). The optional "patternType" parameter is like the
+// patternType argument of the GraphQL search field.
+func serveSearchExport(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	// 🚨 SECURITY: Only signed in users may export search results. The search
+	// itself only returns results the user has access to.
+	if !actor.FromContext(ctx).IsAuthenticated() {
+		return &errcode.HTTPErr{Status: http.StatusUnauthorized, Err: errors.New("search export requires authentication")}
+	}
+
+	params := r.URL.Query()
+	args := &graphqlbackend.SearchArgs{
+		Version: "V2",
+		Query:   params.Get("q"),
+	}
+	if args.Query == "" {
+		return &errcode.HTTPErr{Status: http.StatusBadRequest, Err: errors.New("the q parameter is required")}
+	}
+	if patternType := params.Get("patternType"); patternType != "" {
+		args.PatternType = &patternType
+	}
+
+	newWriter, err := searchExportFormat(w, r, "search-results")
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Trailer", searchExportLimitHitTrailer)
+
+	// The response is only started once the first row is written, so that
+	// errors of the search itself result in an error response.
+	var ew searchExportWriter
+	onRow := func(row *graphqlbackend.SearchExportRow) error {
+		if ew == nil {
+			ew = newWriter(w)
+		}
+		return ew.WriteRow(row)
+	}
+
+	limitHit, err := exportSearchResults(ctx, args, maxSearchExportResults, maxSearchExportCount, onRow)
+	if err != nil && ew == nil {
+		return err
+	}
+	if err != nil {
+		// We already responded with a 200 and some rows, the best we can do
+		// is to end the response early.
+		log15.Error("search export failed", "query", args.Query, "error", err)
+		return nil
+	}
+	if ew == nil {
+		ew = newWriter(w)
+	}
+	if err := ew.Flush(); err != nil {
+		return err
+	}
+	w.Header().Set(searchExportLimitHitTrailer, strconv.FormatBool(limitHit))
+	return nil
+}
+
+// searchExportFormat sets the headers of the response for the format in the
+// "format" query parameter, and returns the constructor of the writer for it.
+func searchExportFormat(w http.ResponseWriter, r *http.Request, filename string) (func(io.Writer) searchExportWriter, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+		return newCSVSearchExportWriter, nil
+	case "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".jsonl"))
+		return newJSONLSearchExportWriter, nil
+	default:
+		return nil, &errcode.HTTPErr{Status: http.StatusBadRequest, Err: errors.Errorf("unsupported format %q, use csv or jsonl", format)}
+	}
+}
+
+// searchExportWriter writes rows of exported search results.
+type searchExportWriter interface {
+	WriteRow(*graphqlbackend.SearchExportRow) error
+	Flush() error
+}
+
+type csvSearchExportWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVSearchExportWriter(w io.Writer) searchExportWriter {
+	return &csvSearchExportWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvSearchExportWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write([]string{"repo", "commit", "path", "line", "preview"})
+}
+
+func (c *csvSearchExportWriter) WriteRow(row *graphqlbackend.SearchExportRow) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	var line string
+	if row.Line > 0 {
+		line = strconv.Itoa(int(row.Line))
+	}
+	return c.w.Write([]string{csvCell(row.Repo), csvCell(row.Commit), csvCell(row.Path), line, csvCell(row.Preview)})
+}
+
+// csvCell returns value escaped for spreadsheet applications, which would
+// otherwise evaluate it as a formula. Those are values starting with =, @, a
+// tab or a carriage return, or with + or - followed by a character which
+// starts an operand, like +A1 or -1+1. Other values, like the lines of a diff
+// starting with +++ or "- ", are returned as is.
+func csvCell(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + value
+	case '+', '-':
+		if len(value) > 1 && isFormulaOperandStart(rune(value[1])) {
+			return "'" + value
+		}
+	}
+	return value
+}
+
+// isFormulaOperandStart reports whether r can start the operand of a formula:
+// a cell reference, function name, number or parenthesized expression.
+func isFormulaOperandStart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("($.", r)
+}
+
+func (c *csvSearchExportWriter) Flush() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlSearchExportWriter struct {
+	enc *json.Encoder
+}
+
+func newJSONLSearchExportWriter(w io.Writer) searchExportWriter {
+	return &jsonlSearchExportWriter{enc: json.NewEncoder(w)}
+}
+
+func (j *jsonlSearchExportWriter) WriteRow(row *graphqlbackend.SearchExportRow) error {
+	return j.enc.Encode(row)
+}
+
+func (j *jsonlSearchExportWriter) Flush() error { return nil }
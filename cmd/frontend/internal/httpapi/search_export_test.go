@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+func TestServeSearchExport(t *testing.T) {
+	defer func() { exportSearchResults = graphqlbackend.ExportSearchResults }()
+	exportSearchResults = func(ctx context.Context, args *graphqlbackend.SearchArgs, resultLimit, maxResultLimit int32, onRow func(*graphqlbackend.SearchExportRow) error) (bool, error) {
+		if args.Query != "foo" {
+			t.Errorf("got query %q, want %q", args.Query, "foo")
+		}
+		if resultLimit != maxSearchExportResults || maxResultLimit != maxSearchExportCount {
+			t.Errorf("got result limits %d, %d, want %d, %d", resultLimit, maxResultLimit, maxSearchExportResults, maxSearchExportCount)
+		}
+		for _, row := range []*graphqlbackend.SearchExportRow{
+			{Repo: "r", Commit: "c", Path: "a.go", Line: 3, Preview: `x := "foo, bar"`},
+			{Repo: "r2"},
+			{Repo: "r3", Path: "-x.go", Line: 1, Preview: `=HYPERLINK("http://example.com")`},
+		} {
+			if err := onRow(row); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	serve := func(ctx context.Context, url string) (*httptest.ResponseRecorder, error) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", url, nil).WithContext(ctx)
+		return rec, serveSearchExport(rec, req)
+	}
+	authed := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := serve(context.Background(), "/search/export?q=foo")
+		if got := errcode.HTTP(err); got != http.StatusUnauthorized {
+			t.Fatalf("got status %d, want %d", got, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		for _, url := range []string{"/search/export", "/search/export?q=foo&format=xml"} {
+			_, err := serve(authed, url)
+			if got := errcode.HTTP(err); got != http.StatusBadRequest {
+				t.Errorf("%s: got status %d, want %d", url, got, http.StatusBadRequest)
+			}
+		}
+	})
+
+	cases := map[string]struct {
+		contentType string
+		want        string
+	}{
+		"csv": {
+			contentType: "text/csv; charset=utf-8",
+			want: `repo,commit,path,line,preview
+r,c,a.go,3,"x := ""foo, bar"""
+r2,,,,
+r3,,'-x.go,1,"'=HYPERLINK(""http://example.com"")"
+`,
+		},
+		"jsonl": {
+			contentType: "application/x-ndjson",
+			want: `{"repo":"r","commit":"c","path":"a.go","line":3,"preview":"x := \"foo, bar\""}
+{"repo":"r2","commit":"","path":"","line":0,"preview":""}
+{"repo":"r3","commit":"","path":"-x.go","line":1,"preview":"=HYPERLINK(\"http://example.com\")"}
+`,
+		},
+	}
+	for format, tt := range cases {
+		t.Run(format, func(t *testing.T) {
+			rec, err := serve(authed, "/search/export?q=foo&format="+format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("got Content-Type %q, want %q", got, tt.contentType)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("got body\n%s\nwant\n%s", got, tt.want)
+			}
+			if got := rec.Result().Trailer.Get(searchExportLimitHitTrailer); got != "true" {
+				t.Errorf("got limit hit trailer %q, want %q", got, "true")
+			}
+		})
+	}
+}
+
+func TestCSVCell(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		// Values a spreadsheet application would evaluate as formulas.
+		{value: "=1+1", want: "'=1+1"},
+		{value: "@SUM(A1:A2)", want: "'@SUM(A1:A2)"},
+		{value: "\tx", want: "'\tx"},
+		{value: "\rx", want: "'\rx"},
+		{value: "+A1", want: "'+A1"},
+		{value: "-1+1", want: "'-1+1"},
+		{value: "+cmd|' /C calc'!A0", want: "'+cmd|' /C calc'!A0"},
+		{value: "-(1)", want: "'-(1)"},
+		{value: "-$A$1", want: "'-$A$1"},
+		{value: "+.5", want: "'+.5"},
+
+		// Values which are not formulas.
+		{value: "", want: ""},
+		{value: "foo", want: "foo"},
+		{value: "a=b", want: "a=b"},
+		{value: "+", want: "+"},
+		{value: "-", want: "-"},
+		{value: "+++ b/main.go", want: "+++ b/main.go"},
+		{value: "--- a/main.go", want: "--- a/main.go"},
+		{value: "- item", want: "- item"},
+		{value: "+ item", want: "+ item"},
+		{value: "-- comment", want: "-- comment"},
+	}
+	for _, test := range tests {
+		if got := csvCell(test.value); got != test.want {
+			t.Errorf("csvCell(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/internal/db"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+// searchJobExportBatchSize is the number of results of a search job read from
+// the database at once.
+const searchJobExportBatchSize = 1000
+
+// serveSearchJobExport responds with the results of a completed search job, in
+// the formats of serveSearchExport. Whether the results are incomplete is
+// reported by the X-Sourcegraph-Limit-Hit header.
+func serveSearchJobExport(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(mux.Vars(r)["ID"], 10, 32)
+	if err != nil {
+		return &errcode.HTTPErr{Status: http.StatusBadRequest, Err: err}
+	}
+	job, err := db.SearchJobs.GetByID(ctx, int32(id))
+	if err != nil {
+		return err
+	}
+	// 🚨 SECURITY: Only the creator of the search job and site admins may
+	// download its results. Others can't tell the job exists.
+	if err := backend.CheckSiteAdminOrSameUser(ctx, job.UserID); err != nil {
+		return &errcode.HTTPErr{Status: http.StatusNotFound, Err: errors.Errorf("search job not found: %d", id)}
+	}
+	if job.State != "completed" {
+		return &errcode.HTTPErr{Status: http.StatusConflict, Err: errors.Errorf("search job %d has not completed", id)}
+	}
+
+	newWriter, err := searchExportFormat(w, r, fmt.Sprintf("search-job-%d", job.ID))
+	if err != nil {
+		return err
+	}
+	w.Header().Set(searchExportLimitHitTrailer, strconv.FormatBool(job.LimitHit))
+
+	ew := newWriter(w)
+	var afterID int64
+	for {
+		results, err := db.SearchJobs.ListResults(ctx, job.ID, afterID, searchJobExportBatchSize)
+		if err != nil {
+			if afterID == 0 {
+				return err
+			}
+			// We already responded with a 200 and some rows, the best we
+			// can do is to end the response early.
+			log15.Error("search job export failed", "id", job.ID, "error", err)
+			return nil
+		}
+		for _, res := range results {
+			err := ew.WriteRow(&graphqlbackend.SearchExportRow{
+				Repo:    res.Repo,
+				Commit:  res.Commit,
+				Path:    res.Path,
+				Line:    res.Line,
+				Preview: res.Preview,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(results) < searchJobExportBatchSize {
+			break
+		}
+		afterID = results[len(results)-1].ID
+	}
+	return ew.Flush()
+}
@@ -0,0 +1,74 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/db"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+func TestServeSearchJobExport(t *testing.T) {
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	db.Mocks.SearchJobs.GetByID = func(ctx context.Context, id int32) (*types.SearchJob, error) {
+		state := "completed"
+		if id == 2 {
+			state = "processing"
+		}
+		return &types.SearchJob{ID: id, UserID: 1, State: state, LimitHit: true}, nil
+	}
+	// More results than fit in a batch.
+	var results []*types.SearchJobResult
+	for i := 1; i <= searchJobExportBatchSize+1; i++ {
+		results = append(results, &types.SearchJobResult{ID: int64(i), Repo: "r", Path: fmt.Sprintf("f%d", i)})
+	}
+	db.Mocks.SearchJobs.ListResults = func(ctx context.Context, jobID int32, afterID int64, limit int) ([]*types.SearchJobResult, error) {
+		page := results[afterID:]
+		if len(page) > limit {
+			page = page[:limit]
+		}
+		return page, nil
+	}
+	db.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: actor.FromContext(ctx).UID}, nil
+	}
+	db.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		return &types.User{ID: id}, nil
+	}
+
+	serve := func(uid int32, id string) (*httptest.ResponseRecorder, error) {
+		rec := httptest.NewRecorder()
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: uid})
+		req := httptest.NewRequest("GET", "/search/jobs/"+id+"/export?format=jsonl", nil).WithContext(ctx)
+		req = mux.SetURLVars(req, map[string]string{"ID": id})
+		return rec, serveSearchJobExport(rec, req)
+	}
+
+	rec, err := serve(1, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Count(rec.Body.String(), "\n"), len(results); got != want {
+		t.Errorf("got %d rows, want %d", got, want)
+	}
+	if got := rec.Header().Get(searchExportLimitHitTrailer); got != "true" {
+		t.Errorf("got limit hit header %q, want %q", got, "true")
+	}
+
+	// 🚨 SECURITY: Other users can't download the results.
+	if _, err := serve(3, "1"); errcode.HTTP(err) != http.StatusNotFound {
+		t.Errorf("got error %v, want not found", err)
+	}
+
+	if _, err := serve(1, "2"); errcode.HTTP(err) != http.StatusConflict {
+		t.Errorf("got error %v, want conflict", err)
+	}
+}
@@ -4,12 +4,36 @@ import (
 	"github.com/sourcegraph/sourcegraph/schema"
 )
 
+type AWSCodeCommitConnection struct {
+	// The unique resource identifier of the external service.
+	URN string
+	*schema.AWSCodeCommitConnection
+}
+
+type AzureDevOpsConnection struct {
+	// The unique resource identifier of the external service.
+	URN string
+	*schema.AzureDevOpsConnection
+}
+
+type BitbucketCloudConnection struct {
+	// The unique resource identifier of the external service.
+	URN string
+	*schema.BitbucketCloudConnection
+}
+
 type BitbucketServerConnection struct {
 	// The unique resource identifier of the external service.
 	URN string
 	*schema.BitbucketServerConnection
 }
 
+type GiteaConnection struct {
+	// The unique resource identifier of the external service.
+	URN string
+	*schema.GiteaConnection
+}
+
 type GitHubConnection struct {
 	// The unique resource identifier of the external service.
 	URN string
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// SearchJob is a search which runs in the background over all repositories
+// matched by its query, without the time and result limits of a regular
+// search.
+type SearchJob struct {
+	ID             int32
+	UserID         int32
+	Query          string
+	PatternType    string
+	State          string // queued, processing, completed or errored
+	FailureMessage *string
+	CreatedAt      time.Time
+	StartedAt      *time.Time
+	FinishedAt     *time.Time
+
+	// The progress of the job. ReposTotal is 0 until the repositories to
+	// search have been resolved.
+	ReposTotal    int32
+	ReposSearched int32
+	ResultCount   int32
+	LimitHit      bool // true if the results of a repository were truncated
+}
+
+// SearchJobRepo is a repository, and the revisions of it, searched by a search
+// job.
+type SearchJobRepo struct {
+	ID       int32
+	RepoID   api.RepoID
+	RepoName api.RepoName
+	Revs     string // the revisions as an "@rev1:rev2" suffix of a repo: filter, or empty
+}
+
+// SearchJobResult is a row of the results of a search job, in the format of
+// exported search results.
+type SearchJobResult struct {
+	ID      int64
+	Repo    string
+	Commit  string
+	Path    string
+	Line    int32
+	Preview string
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	"github.com/inconshreveable/log15"
 
 	"github.com/sourcegraph/sourcegraph/cmd/gitserver/server"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/debugserver"
 	"github.com/sourcegraph/sourcegraph/internal/env"
 	"github.com/sourcegraph/sourcegraph/internal/logging"
@@ -29,6 +31,15 @@ var (
 	runRepoCleanup, _ = strconv.ParseBool(env.Get("SRC_RUN_REPO_CLEANUP", "", "Periodically remove inactive repositories."))
 	wantPctFree       = env.Get("SRC_REPOS_DESIRED_PERCENT_FREE", "10", "Target percentage of free space on disk.")
 	janitorInterval   = env.Get("SRC_REPOS_JANITOR_INTERVAL", "1m", "Interval between cleanup runs")
+	rebalance, _      = strconv.ParseBool(env.Get("SRC_GITSERVER_REBALANCE_FROM_PEERS", "false", "Move repositories lazily: clone a repository assigned to this gitserver from the gitserver which had it before, rather than from the code host, when it is first requested."))
+	maintenance       = env.Get("SRC_REPOS_MAINTENANCE_INTERVAL", "1h", "Minimum interval between checks whether a repository needs git repack, commit-graph, multi-pack-index or pack-refs. Repositories are maintained one at a time, at most one every 10 seconds. 0 disables maintenance.")
+	backupLocation    = env.Get("SRC_GITSERVER_BACKUP_LOCATION", "", "Directory or S3 URL (s3://bucket/prefix) to store git bundle backups of repositories in.")
+	backupEndpoint    = env.Get("SRC_GITSERVER_BACKUP_S3_ENDPOINT", "", "URL of an S3-compatible object storage for SRC_GITSERVER_BACKUP_LOCATION, if not AWS S3.")
+	restore, _        = strconv.ParseBool(env.Get("SRC_GITSERVER_RESTORE_FROM_BACKUP", "false", "Clone repositories from their backup in SRC_GITSERVER_BACKUP_LOCATION rather than from the code host."))
+	signingKey        = env.Get("SRC_GITSERVER_COMMIT_SIGNING_KEY", "", "GPG key ID, or path of an SSH private key, to sign commits created from patches with if requested.")
+	signingFormat     = env.Get("SRC_GITSERVER_COMMIT_SIGNING_FORMAT", "openpgp", "Format of SRC_GITSERVER_COMMIT_SIGNING_KEY, either openpgp or ssh. ssh requires git 2.34 or later.")
+	serviceTokens     = env.Get("SRC_GITSERVER_SERVICE_TOKENS", "", "Comma-separated service=token pairs which authenticate the services sending git commands, for gitserverExecAllowlist. Each service sets its token in SRC_GITSERVER_SERVICE_TOKEN.")
+	hostname          = env.Get("HOSTNAME", "", "Hostname of this gitserver, used to find its own address among the gitserver addresses.")
 )
 
 func main() {
@@ -48,10 +59,42 @@ func main() {
 	if err != nil {
 		log.Fatalf("parsing $SRC_REPOS_DESIRED_PERCENT_FREE: %v", err)
 	}
+	maintenanceInterval, err := time.ParseDuration(maintenance)
+	if err != nil {
+		log.Fatalf("parsing $SRC_REPOS_MAINTENANCE_INTERVAL: %v", err)
+	}
+	if signingFormat != "openpgp" && signingFormat != "ssh" {
+		log.Fatalf("invalid $SRC_GITSERVER_COMMIT_SIGNING_FORMAT %q: must be openpgp or ssh", signingFormat)
+	}
+	if signingKey != "" && signingFormat == "ssh" {
+		if err := server.CheckSSHSigning(); err != nil {
+			log.Fatalf("invalid $SRC_GITSERVER_COMMIT_SIGNING_FORMAT: %v", err)
+		}
+	}
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	tokens, err := parseServiceTokens(serviceTokens)
+	if err != nil {
+		log.Fatalf("parsing $SRC_GITSERVER_SERVICE_TOKENS: %v", err)
+	}
 	gitserver := server.Server{
 		ReposDir:                reposDir,
 		DeleteStaleRepositories: runRepoCleanup,
 		DesiredPercentFree:      wantPctFree2,
+		Hostname:                hostname,
+		RebalanceFromPeers:      rebalance,
+		MaintenanceInterval:     maintenanceInterval,
+		CommitSigningKey:        signingKey,
+		CommitSigningFormat:     signingFormat,
+		ServiceTokens:           tokens,
+	}
+	if backupLocation != "" {
+		gitserver.Backups, err = server.NewBundleStore(backupLocation, backupEndpoint)
+		if err != nil {
+			log.Fatalf("failed to open SRC_GITSERVER_BACKUP_LOCATION: %s", err)
+		}
+		gitserver.RestoreFromBackup = restore
 	}
 	gitserver.RegisterMetrics()
 
@@ -78,6 +121,12 @@ func main() {
 			time.Sleep(janitorInterval2)
 		}
 	}()
+	go gitserver.MaintainRepos()
+	go conf.Watch(func() {
+		if err := server.ValidateCloneStrategies(conf.Get().GitCloneStrategies); err != nil {
+			log15.Error("invalid site configuration, gitCloneStrategies are ignored", "error", err)
+		}
+	})
 
 	port := "3178"
 	host := ""
@@ -126,3 +175,21 @@ func parsePercent(s string) (int, error) {
 	}
 	return p, nil
 }
+
+// parseServiceTokens parses comma-separated service=token pairs into a map
+// from service names to tokens.
+func parseServiceTokens(s string) (map[string]string, error) {
+	tokens := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.Index(pair, "=")
+		if i <= 0 || i == len(pair)-1 {
+			return nil, fmt.Errorf("invalid service token %q: must have the form service=token", pair)
+		}
+		tokens[pair[:i]] = pair[i+1:]
+	}
+	return tokens, nil
+}
@@ -1,7 +1,10 @@
 // gitserver is the gitserver server.
 package main
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestParsePercent(t *testing.T) {
 	tests := []struct {
@@ -30,3 +33,20 @@ func TestParsePercent(t *testing.T) {
 		})
 	}
 }
+
+func TestParseServiceTokens(t *testing.T) {
+	got, err := parseServiceTokens("searcher=s3cret, frontend=a=b,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"searcher": "s3cret", "frontend": "a=b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, s := range []string{"searcher", "=token", "searcher="} {
+		if _, err := parseServiceTokens(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+var (
+	repoBackups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "src_gitserver_repo_backups_total",
+		Help: "number of repositories backed up as git bundles.",
+	}, []string{"status"})
+	repoRestoredCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "src_gitserver_repo_restored",
+		Help: "number of repositories cloned from their backup instead of the code host.",
+	})
+)
+
+// bundleKey is the key of the bundle of repo in the BundleStore.
+func bundleKey(repo api.RepoName) string {
+	return string(protocol.NormalizeRepo(repo)) + ".bundle"
+}
+
+// errBackupUnsupported is returned by backupRepo for repositories whose
+// bundle would be incomplete.
+type errBackupUnsupported struct {
+	reason string
+}
+
+func (e *errBackupUnsupported) Error() string {
+	return "backup not supported: " + e.reason
+}
+
+// backupRepo stores a git bundle of all refs of repo in s.Backups.
+func (s *Server) backupRepo(ctx context.Context, repo api.RepoName) error {
+	dir := s.dir(repo)
+	if !repoCloned(dir) {
+		return errors.Errorf("repo %s is not cloned", repo)
+	}
+
+	// The bundles of partial and shallow clones would lack the objects
+	// which were never fetched, so a clone restored from them would be
+	// broken.
+	if partial, _ := gitConfigGet(dir, "extensions.partialClone"); partial != "" {
+		return &errBackupUnsupported{reason: "repo is a partial clone"}
+	}
+	if isShallowClone(dir) {
+		return &errBackupUnsupported{reason: "repo is a shallow clone"}
+	}
+
+	tmp, err := s.tempDir("backup-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	bundle := filepath.Join(tmp, "repo.bundle")
+
+	// Like maintenance, the bundle is created while holding the update lock
+	// of the repository, so that a concurrent fetch doesn't change its refs
+	// partway through.
+	l, _ := s.repoUpdateLock(repo)
+	l.mu.Lock()
+	cmd := exec.CommandContext(ctx, "git", "bundle", "create", bundle, "--all")
+	dir.Set(cmd)
+	output, err := runWith(ctx, cmd, false, nil)
+	l.mu.Unlock()
+	if err != nil {
+		return errors.Wrapf(err, "bundle failed. Output: %s", string(output))
+	}
+
+	f, err := os.Open(bundle)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return errors.Wrap(s.Backups.Put(ctx, bundleKey(repo), f), "storing bundle")
+}
+
+// hasBackup reports whether a clone of repo should be restored from its
+// backup.
+func (s *Server) hasBackup(ctx context.Context, repo api.RepoName) bool {
+	if !s.RestoreFromBackup || s.Backups == nil {
+		return false
+	}
+	ok, err := s.Backups.Exists(ctx, bundleKey(repo))
+	if err != nil {
+		log15.Warn("failed to look up backup", "repo", repo, "error", err)
+	}
+	return ok
+}
+
+// fetchRestoredRepo fetches the changes since the backup into the repository
+// in dir, which was just restored from its backup, so that it doesn't serve
+// the refs of the backup until its next update. The code host may be down
+// during disaster recovery, so a failed fetch leaves the restored repository
+// as it is.
+func (s *Server) fetchRestoredRepo(ctx context.Context, repo api.RepoName, url string, dir GitDir, redactor *urlRedactor) {
+	start := time.Now()
+	output, err := vcsSyncerFor(repo, url).Fetch(ctx, url, dir)
+	if err != nil {
+		err = errors.Wrapf(err, "fetch failed. Output: %s", string(output))
+	}
+	s.recordSync(repo, "fetch", start, output, err, redactor)
+	if err != nil {
+		log15.Warn("failed to fetch restored repo", "repo", repo, "error", redactor.redact(err.Error()))
+	}
+}
+
+// downloadBundle downloads the bundle of repo into dir and returns its path.
+func (s *Server) downloadBundle(ctx context.Context, repo api.RepoName, dir string) (string, error) {
+	r, err := s.Backups.Get(ctx, bundleKey(repo))
+	if err != nil {
+		return "", errors.Wrap(err, "getting bundle")
+	}
+	defer r.Close()
+
+	bundle := filepath.Join(dir, "repo.bundle")
+	f, err := os.Create(bundle)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return "", errors.Wrap(err, "downloading bundle")
+	}
+	return bundle, f.Close()
+}
+
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	var req protocol.BackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.Backups == nil {
+		http.Error(w, "backups are not configured", http.StatusNotFound)
+		return
+	}
+
+	if req.All {
+		// Backing up all repositories takes far longer than a request
+		// should, so it runs in the background. Its results are logged and
+		// counted in src_gitserver_repo_backups_total.
+		if !atomic.CompareAndSwapInt32(&s.backingUpAll, 0, 1) {
+			http.Error(w, "a backup of all repositories is already running", http.StatusConflict)
+			return
+		}
+		dirs, err := s.findGitDirs()
+		if err != nil {
+			atomic.StoreInt32(&s.backingUpAll, 0)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		repos := make([]api.RepoName, 0, len(dirs))
+		for _, dir := range dirs {
+			repos = append(repos, s.name(dir))
+		}
+		go func() {
+			defer atomic.StoreInt32(&s.backingUpAll, 0)
+			resp := s.backupRepos(repos)
+			log15.Info("backed up all repos", "backups", len(resp.Backups), "skipped", len(resp.Skipped), "errors", len(resp.Errors))
+		}()
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	resp := s.backupRepos(req.Repos)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// backupRepos backs up repos one at a time.
+func (s *Server) backupRepos(repos []api.RepoName) *protocol.BackupResponse {
+	// Like repo updates, we don't want to cancel the git commands partway
+	// through if the request terminates.
+	ctx, cancel := s.serverContext()
+	defer cancel()
+
+	resp := &protocol.BackupResponse{
+		Backups: []api.RepoName{},
+		Skipped: map[api.RepoName]string{},
+		Errors:  map[api.RepoName]string{},
+	}
+	for _, repo := range repos {
+		repo = protocol.NormalizeRepo(repo)
+		repoCtx, cancel := context.WithTimeout(ctx, longGitCommandTimeout)
+		err := s.backupRepo(repoCtx, repo)
+		cancel()
+		if e, ok := err.(*errBackupUnsupported); ok {
+			log15.Info("skipped backup of repo", "repo", repo, "reason", e.reason)
+			repoBackups.WithLabelValues("skipped").Inc()
+			resp.Skipped[repo] = e.reason
+			continue
+		}
+		if err != nil {
+			log15.Error("failed to back up repo", "repo", repo, "error", err)
+			repoBackups.WithLabelValues("error").Inc()
+			resp.Errors[repo] = err.Error()
+			continue
+		}
+		repoBackups.WithLabelValues("success").Inc()
+		resp.Backups = append(resp.Backups, repo)
+	}
+	return resp
+}
+
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	var req protocol.RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.Backups == nil {
+		http.Error(w, "backups are not configured", http.StatusNotFound)
+		return
+	}
+	req.Repo = protocol.NormalizeRepo(req.Repo)
+
+	ctx, cancel1 := s.serverContext()
+	defer cancel1()
+	ctx, cancel2 := context.WithTimeout(ctx, longGitCommandTimeout)
+	defer cancel2()
+
+	if ok, err := s.Backups.Exists(ctx, bundleKey(req.Repo)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, "no backup of "+string(req.Repo), http.StatusNotFound)
+		return
+	}
+
+	_, err := s.cloneRepo(ctx, req.Repo, req.URL, &cloneOptions{Block: true, Overwrite: req.Overwrite, FromBackup: true})
+	if err != nil {
+		log15.Error("failed to restore repo", "repo", req.Repo, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	remote := tmpDir(t)
+	runCmd(t, remote, "git", "init", ".")
+	runCmd(t, remote, "sh", "-c", "echo hello world > hello.txt")
+	runCmd(t, remote, "git", "add", "hello.txt")
+	runCmd(t, remote, "git", "commit", "-m", "hello")
+	runCmd(t, remote, "git", "tag", "v1")
+
+	backups, err := NewBundleStore(tmpDir(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{ReposDir: tmpDir(t), Backups: backups, RestoreFromBackup: true}
+	h := s.Handler()
+
+	ctx := context.Background()
+	repo := api.RepoName("example.com/foo/bar")
+	if _, err := s.cloneRepo(ctx, repo, remote, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(path string, req interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", path, bytes.NewReader(body)))
+		return w
+	}
+
+	// Backups of all repositories run in the background.
+	if w := post("/backup", protocol.BackupRequest{All: true}); w.Code != http.StatusAccepted {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	for i := 0; atomic.LoadInt32(&s.backingUpAll) == 1; i++ {
+		if i == 100 {
+			t.Fatal("backup of all repos didn't finish")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if ok, err := backups.Exists(ctx, bundleKey(repo)); err != nil || !ok {
+		t.Fatalf("no backup of %s: %v", repo, err)
+	}
+
+	w := post("/backup", protocol.BackupRequest{Repos: []api.RepoName{repo}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	var resp protocol.BackupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	want := protocol.BackupResponse{Backups: []api.RepoName{repo}, Skipped: map[api.RepoName]string{}, Errors: map[api.RepoName]string{}}
+	if !reflect.DeepEqual(resp, want) {
+		t.Fatalf("got %+v, want %+v", resp, want)
+	}
+
+	// The restored clone fetches the commits made since the backup.
+	runCmd(t, remote, "git", "commit", "--allow-empty", "-m", "after backup")
+	if err := os.RemoveAll(filepath.Dir(string(s.dir(repo)))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.cloneRepo(ctx, repo, remote, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(string(s.dir(repo)))
+	for _, rev := range []string{"HEAD", "v1"} {
+		if got, want := runCmd(t, dir, "git", "rev-parse", rev), runCmd(t, remote, "git", "rev-parse", rev); got != want {
+			t.Errorf("got %s at %s, want %s", rev, got, want)
+		}
+	}
+
+	// The clone is restored from the backup if the code host is gone.
+	if err := os.RemoveAll(filepath.Dir(string(s.dir(repo)))); err != nil {
+		t.Fatal(err)
+	}
+	codeHost := filepath.Join(tmpDir(t), "gone")
+	if _, err := s.cloneRepo(ctx, repo, codeHost, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := runCmd(t, dir, "git", "rev-parse", "HEAD"), runCmd(t, remote, "git", "rev-parse", "HEAD~1"); got != want {
+		t.Errorf("got HEAD %s, want the backed up %s", got, want)
+	}
+	if got := strings.TrimSpace(runCmd(t, dir, "git", "config", "remote.origin.url")); got != codeHost {
+		t.Errorf("got remote URL %q, want the code host URL %q", got, codeHost)
+	}
+
+	if w := post("/restore", protocol.RestoreRequest{Repo: "example.com/foo/missing", URL: codeHost}); w.Code != http.StatusNotFound {
+		t.Errorf("got status %d restoring a repo without backup, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := post("/restore", protocol.RestoreRequest{Repo: repo, URL: codeHost, Overwrite: true}); w.Code != http.StatusOK {
+		t.Errorf("got status %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestBackup_SkipsShallowClones(t *testing.T) {
+	remote := tmpDir(t)
+	runCmd(t, remote, "git", "init", ".")
+	runCmd(t, remote, "git", "commit", "--allow-empty", "-m", "one")
+	runCmd(t, remote, "git", "commit", "--allow-empty", "-m", "two")
+
+	backups, err := NewBundleStore(tmpDir(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{ReposDir: tmpDir(t), Backups: backups}
+	h := s.Handler()
+
+	repo := api.RepoName("example.com/foo/shallow")
+	runCmd(t, s.ReposDir, "git", "clone", "--mirror", "--depth=1", "file://"+remote, string(s.dir(repo)))
+
+	body, err := json.Marshal(protocol.BackupRequest{Repos: []api.RepoName{repo}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/backup", bytes.NewReader(body)))
+	var resp protocol.BackupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	want := protocol.BackupResponse{
+		Backups: []api.RepoName{},
+		Skipped: map[api.RepoName]string{repo: "repo is a shallow clone"},
+		Errors:  map[api.RepoName]string{},
+	}
+	if !reflect.DeepEqual(resp, want) {
+		t.Fatalf("got %+v, want %+v", resp, want)
+	}
+}
+
+func TestBackup_WaitsForRepoUpdate(t *testing.T) {
+	remote := tmpDir(t)
+	runCmd(t, remote, "git", "init", ".")
+	runCmd(t, remote, "git", "commit", "--allow-empty", "-m", "one")
+
+	backups, err := NewBundleStore(tmpDir(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{ReposDir: tmpDir(t), Backups: backups}
+	_ = s.Handler()
+
+	ctx := context.Background()
+	repo := api.RepoName("example.com/foo/updating")
+	if _, err := s.cloneRepo(ctx, repo, remote, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hold the update lock like a fetch of the repository would.
+	l, _ := s.repoUpdateLock(repo)
+	l.mu.Lock()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.backupRepo(ctx, repo)
+	}()
+
+	select {
+	case err := <-done:
+		l.mu.Unlock()
+		t.Fatalf("backup finished during an update of the repo: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	l.mu.Unlock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := backups.Exists(ctx, bundleKey(repo)); err != nil || !ok {
+		t.Fatalf("no backup of %s: %v", repo, err)
+	}
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/awserr"
+	"github.com/aws/aws-sdk-go-v2/aws/external"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/s3manager"
+	"github.com/pkg/errors"
+)
+
+// BundleStore stores the git bundles which back up repositories.
+type BundleStore interface {
+	// Put stores the bundle read from r under key.
+	Put(ctx context.Context, key string, r io.ReadSeeker) error
+	// Get returns the bundle stored under key. It returns an error for which
+	// os.IsNotExist is true if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Exists reports whether there is a bundle stored under key.
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// NewBundleStore returns the store for location, which is either a local
+// directory or an S3 URL of the form s3://bucket/prefix. endpoint, if not
+// empty, is the URL of an S3-compatible object storage, such as MinIO.
+func NewBundleStore(location, endpoint string) (BundleStore, error) {
+	if !strings.HasPrefix(location, "s3://") {
+		if err := os.MkdirAll(location, os.ModePerm); err != nil {
+			return nil, err
+		}
+		return &localBundleStore{dir: location}, nil
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid S3 URL")
+	}
+	cfg, err := external.LoadDefaultAWSConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "loading AWS config")
+	}
+	if endpoint != "" {
+		cfg.EndpointResolver = aws.ResolveWithEndpointURL(endpoint)
+	}
+	client := s3.New(cfg)
+	// S3-compatible object storages usually don't support buckets as
+	// subdomains.
+	client.ForcePathStyle = endpoint != ""
+	return &s3BundleStore{
+		client: client,
+		// A single PutObject is limited to 5 GB, so bundles are uploaded in
+		// parts. With the default of at most 10,000 parts, 64 MB parts allow
+		// bundles of up to 640 GB.
+		uploader: s3manager.NewUploaderWithClient(client, func(u *s3manager.Uploader) {
+			u.PartSize = 64 * 1024 * 1024
+		}),
+		bucket: u.Host,
+		prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+type localBundleStore struct {
+	dir string
+}
+
+func (s *localBundleStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+func (s *localBundleStore) Put(ctx context.Context, key string, r io.ReadSeeker) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a failed backup doesn't
+	// replace the previous one.
+	f, err := os.Create(p + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return renameAndSync(f.Name(), p)
+}
+
+func (s *localBundleStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+func (s *localBundleStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := os.Stat(s.path(key))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type s3BundleStore struct {
+	client   *s3.Client
+	uploader *s3manager.Uploader
+	bucket   string
+	prefix   string
+}
+
+func (s *s3BundleStore) key(key string) *string {
+	return aws.String(path.Join(s.prefix, key))
+}
+
+func (s *s3BundleStore) Put(ctx context.Context, key string, r io.ReadSeeker) error {
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    s.key(key),
+		Body:   r,
+	})
+	return err
+}
+
+func (s *s3BundleStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    s.key(key),
+	}).Send(ctx)
+	if isS3NotFound(err) {
+		return nil, &os.PathError{Op: "get", Path: *s.key(key), Err: os.ErrNotExist}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *s3BundleStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.HeadObjectRequest(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    s.key(key),
+	}).Send(ctx)
+	if isS3NotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func isS3NotFound(err error) bool {
+	e, ok := err.(awserr.RequestFailure)
+	return ok && e.StatusCode() == 404
+}
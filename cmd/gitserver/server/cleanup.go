@@ -48,14 +48,20 @@ var (
 		Name: "src_gitserver_repos_removed_disk_pressure",
 		Help: "number of repos removed due to not enough disk space",
 	})
+	reposRemovedUnassigned = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "src_gitserver_repos_removed_unassigned",
+		Help: "number of repos removed after they moved to another gitserver",
+	})
 )
 
 // cleanupRepos walks the repos directory and performs maintenance tasks:
 //
 // 1. Remove corrupt repos.
-// 2. Remove stale lock files.
-// 3. Remove inactive repos on sourcegraph.com
-// 4. Reclone repos after a while. (simulate git gc)
+// 2. Remove repos which moved to another gitserver.
+// 3. Remove stale lock files.
+// 4. Remove inactive repos on sourcegraph.com
+// 5. Update the index of repo sizes and enforce quotas.
+// 6. Reclone repos after a while. (simulate git gc)
 func (s *Server) cleanupRepos() {
 	bCtx, bCancel := s.serverContext()
 	defer bCancel()
@@ -76,10 +82,88 @@ func (s *Server) cleanupRepos() {
 		return true, nil
 	}
 
+	maybeRemoveUnassigned := func(dir GitDir) (done bool, err error) {
+		repo := s.name(dir)
+		owner := s.assignedOwner(repo)
+		if owner == "" {
+			return false, nil
+		}
+
+		// Keep the repo until its new gitserver has it, since it may be
+		// cloned from here.
+		ctx, cancel := context.WithTimeout(bCtx, time.Minute)
+		defer cancel()
+		if err := s.isCloneable(ctx, repo, "http://"+owner+"/git/"+string(repo)); err != nil {
+			return false, nil
+		}
+
+		log15.Info("removing repo which moved to another gitserver", "repo", repo, "gitserver", owner)
+		if err := s.removeRepoDirectory(dir); err != nil {
+			return true, err
+		}
+		reposRemovedUnassigned.Inc()
+		return true, nil
+	}
+
 	ensureGitAttributes := func(dir GitDir) (done bool, err error) {
 		return false, setGitAttributes(dir)
 	}
 
+	// cloned are the repositories on disk, whose sizes are kept in the
+	// index.
+	cloned := make(map[api.RepoName]bool)
+
+	updateRepoSize := func(dir GitDir) (done bool, err error) {
+		repo := s.name(dir)
+		cloned[repo] = true
+		if rs, ok := s.repoSizes.get(s.ReposDir, repo); ok && time.Since(rs.UpdatedAt) < repoSizeTTL {
+			return false, nil
+		}
+
+		remoteURL, err := repoRemoteURL(bCtx, dir)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get remote URL")
+		}
+		_, err = s.updateRepoSize(repo, dir, remoteURL)
+		return false, err
+	}
+
+	maybeEnforceQuota := func(dir GitDir) (done bool, err error) {
+		repo := s.name(dir)
+		rs, ok := s.repoSizes.get(s.ReposDir, repo)
+		if !ok {
+			return false, nil
+		}
+		q := repoQuotaFor(rs.URL)
+		if q == nil || !rs.exceeded(q) || isShallowClone(dir) {
+			return false, nil
+		}
+
+		if !q.shallow {
+			log15.Info("removing repo exceeding its quota", "repo", repo, "size", rs.ExceededSize, "quota", q.url)
+			if err := s.removeRepoDirectory(dir); err != nil {
+				return true, err
+			}
+			reposOverQuota.WithLabelValues("skip").Inc()
+			return true, nil
+		}
+
+		ctx, cancel := context.WithTimeout(bCtx, longGitCommandTimeout)
+		defer cancel()
+
+		remoteURL, err := repoRemoteURL(ctx, dir)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get remote URL")
+		}
+		// cloneRepo shallow clones the repository, since it exceeded its
+		// quota.
+		log15.Info("recloning repo exceeding its quota", "repo", repo, "size", rs.ExceededSize, "quota", q.url)
+		if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true, Overwrite: true}); err != nil {
+			return true, err
+		}
+		return true, nil
+	}
+
 	maybeReclone := func(dir GitDir) (done bool, err error) {
 		recloneTime, err := getRecloneTime(dir)
 		if err != nil {
@@ -173,12 +257,22 @@ func (s *Server) cleanupRepos() {
 	cleanups := []cleanupFn{
 		// Do some sanity checks on the repository.
 		{"maybe remove corrupt", maybeRemoveCorrupt},
+		// Repositories which are assigned to another gitserver, for example
+		// after gitservers were added, are removed once that gitserver has
+		// cloned them.
+		{"maybe remove unassigned", maybeRemoveUnassigned},
 		// If git is interrupted it can leave lock files lying around. It does
 		// not clean these up, and instead fails commands.
 		{"remove stale locks", removeStaleLocks},
 		// We always want to have the same git attributes file at
 		// info/attributes.
 		{"ensure git attributes", ensureGitAttributes},
+		// The sizes of repositories are kept in an index to enforce quotas
+		// without walking all repositories.
+		{"update repo size", updateRepoSize},
+		// Repositories which grew beyond the size of their quota are
+		// removed or shallow recloned.
+		{"maybe enforce quota", maybeEnforceQuota},
 		// Old git clones accumulate loose git objects that waste space and
 		// slow down git operations. Periodically do a fresh clone to avoid
 		// these problems. git gc is slow and resource intensive. It is
@@ -215,6 +309,13 @@ func (s *Server) cleanupRepos() {
 	})
 	if err != nil {
 		log15.Error("cleanup: error iterating over repositories", "error", err)
+	} else {
+		// Repositories can disappear without gitserver removing them, for
+		// example when the disk is replaced.
+		s.repoSizes.prune(s.ReposDir, cloned)
+	}
+	if err := s.repoSizes.save(s.ReposDir); err != nil {
+		log15.Error("cleanup: error saving repo sizes", "error", err)
 	}
 
 	if s.DiskSizer == nil {
@@ -414,6 +515,7 @@ func (s *Server) removeRepoDirectory(gitDir GitDir) error {
 	if err := renameAndSync(dir, filepath.Join(tmp, "repo")); err != nil {
 		return err
 	}
+	s.repoSizes.removed(s.ReposDir, s.name(gitDir))
 
 	// Everything after this point is just cleanup, so any error that occurs
 	// should not be returned, just logged.
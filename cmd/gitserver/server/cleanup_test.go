@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
@@ -16,6 +17,8 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
 )
 
 const (
@@ -205,6 +208,9 @@ func TestCleanupOldLocks(t *testing.T) {
 	s.cleanupRepos()
 
 	assertPaths(t, root,
+		// The index of repo sizes.
+		repoSizesFile,
+
 		"github.com/foo/empty/.git/HEAD",
 		"github.com/foo/empty/.git/info/attributes",
 
@@ -230,6 +236,58 @@ func TestCleanupOldLocks(t *testing.T) {
 	)
 }
 
+func TestCleanupUnassigned(t *testing.T) {
+	root := tmpDir(t)
+
+	addrs := []string{"gitserver-0:3178", "gitserver-1:3178"}
+	origAddrs, origRendezvous := gitserverAddrs, rendezvousHashing
+	gitserverAddrs = func() []string { return addrs }
+	rendezvousHashing = func() bool { return true }
+	defer func() { gitserverAddrs, rendezvousHashing = origAddrs, origRendezvous }()
+
+	// Find repos assigned to this gitserver and to the other one.
+	var ours, moved, cloned string
+	for i := 0; ours == "" || moved == "" || cloned == ""; i++ {
+		name := fmt.Sprintf("github.com/foo/repo-%d", i)
+		switch {
+		case gitserver.AddrsForRepo(addrs, api.RepoName(name), true)[0] == addrs[0]:
+			ours = name
+		case moved == "":
+			moved = name
+		default:
+			cloned = name
+		}
+	}
+	mkFiles(t, root, ours+"/.git/HEAD", moved+"/.git/HEAD", cloned+"/.git/HEAD")
+
+	// Only cloned is on the gitserver it moved to.
+	testRepoExists = func(ctx context.Context, url string) error {
+		if url != "http://"+addrs[1]+"/git/"+cloned {
+			return errors.Errorf("%s not found", url)
+		}
+		return nil
+	}
+	defer func() { testRepoExists = nil }()
+
+	s := &Server{ReposDir: root, Hostname: "gitserver-0"}
+	s.Handler() // Handler as a side-effect sets up Server
+	s.cleanupRepos()
+
+	assertPaths(t, root,
+		// The index of repo sizes.
+		repoSizesFile,
+
+		ours+"/.git/HEAD",
+		ours+"/.git/info/attributes",
+
+		// The repo is kept until the other gitserver has it.
+		moved+"/.git/HEAD",
+		moved+"/.git/info/attributes",
+
+		".tmp",
+	)
+}
+
 func TestSetupAndClearTmp(t *testing.T) {
 	root := tmpDir(t)
 
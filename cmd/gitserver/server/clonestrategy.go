@@ -0,0 +1,227 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// cloneStrategy is how a repository is cloned and fetched if a full clone
+// takes too long. It is configured with the gitCloneStrategies site
+// configuration.
+type cloneStrategy struct {
+	pattern *regexp.Regexp
+
+	// blobless clones without file contents, which are fetched from the
+	// code host when they are read.
+	blobless bool
+
+	// depth when non-zero limits the history of each ref to that many
+	// commits.
+	depth int
+
+	// sparsePaths when non-empty are the only directories whose file
+	// contents are fetched. It implies blobless.
+	sparsePaths []string
+}
+
+var cloneStrategies = conf.Cached(func() interface{} {
+	return buildCloneStrategies(conf.Get().GitCloneStrategies)
+})
+
+// ValidateCloneStrategies returns an error if the gitCloneStrategies site
+// configuration c can't be used by this gitserver.
+func ValidateCloneStrategies(c []*schema.GitCloneStrategy) error {
+	if len(c) > 0 && useRefspecOverrides() {
+		// Clones and fetches with refspec overrides ignore the strategies.
+		return errors.New("gitCloneStrategies can't be used together with SRC_GITSERVER_REFSPECS")
+	}
+	for _, s := range c {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return errors.Wrapf(err, "invalid gitCloneStrategies pattern %q", s.Pattern)
+		}
+	}
+	return nil
+}
+
+func buildCloneStrategies(c []*schema.GitCloneStrategy) []*cloneStrategy {
+	if len(c) > 0 && useRefspecOverrides() {
+		return nil // reported by ValidateCloneStrategies
+	}
+	strategies := make([]*cloneStrategy, 0, len(c))
+	for _, s := range c {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			log15.Error("invalid gitCloneStrategies pattern", "pattern", s.Pattern, "error", err)
+			continue
+		}
+		strategies = append(strategies, &cloneStrategy{
+			pattern:     pattern,
+			blobless:    s.Blobless,
+			depth:       s.Depth,
+			sparsePaths: s.SparsePaths,
+		})
+	}
+	return strategies
+}
+
+// cloneStrategyFor returns the strategy for repo, or nil if repo is fully
+// cloned.
+func cloneStrategyFor(repo api.RepoName) *cloneStrategy {
+	repo = protocol.NormalizeRepo(repo)
+	for _, s := range cloneStrategies().([]*cloneStrategy) {
+		if s.pattern.MatchString(string(repo)) {
+			return s
+		}
+	}
+	return nil
+}
+
+// partial returns true if file contents are left out of clones.
+func (c *cloneStrategy) partial() bool {
+	return c != nil && (c.blobless || len(c.sparsePaths) > 0)
+}
+
+// cloneArgs returns the arguments to add to git clone.
+func (c *cloneStrategy) cloneArgs() []string {
+	var args []string
+	if c.partial() {
+		args = append(args, "--filter=blob:none")
+	}
+	if c != nil && c.depth > 0 {
+		args = append(args, "--depth="+strconv.Itoa(c.depth))
+	}
+	return args
+}
+
+// fetchArgs returns the arguments to add to git fetch of the clone in dir. A
+// strategy only applies to clones which were cloned with it, since fetching
+// with a filter or depth would turn a full clone into a partial one.
+func (c *cloneStrategy) fetchArgs(ctx context.Context, dir GitDir) []string {
+	var args []string
+	if c.partial() && isPartialClone(ctx, dir) {
+		args = append(args, "--filter=blob:none")
+	}
+	if c != nil && c.depth > 0 && isShallowClone(dir) {
+		args = append(args, "--depth="+strconv.Itoa(c.depth))
+	}
+	return args
+}
+
+// archivePaths returns the paths to archive for the requested paths. Sparse
+// clones only archive the requested paths within their sparse paths, since the
+// contents of other files are not fetched. If none of the requested paths are
+// within the sparse paths, they are archived anyway.
+func (c *cloneStrategy) archivePaths(paths []string) []string {
+	if c == nil || len(c.sparsePaths) == 0 {
+		return paths
+	}
+	if len(paths) == 0 {
+		return c.sparsePaths
+	}
+
+	var inSparse []string
+	for _, p := range paths {
+		for _, sp := range c.sparsePaths {
+			if isPathIn(p, sp) {
+				inSparse = append(inSparse, p)
+				break
+			}
+			if isPathIn(sp, p) {
+				inSparse = append(inSparse, sp)
+			}
+		}
+	}
+	if len(inSparse) == 0 {
+		return paths
+	}
+	return inSparse
+}
+
+// isPathIn returns true if p is dir or inside of it.
+func isPathIn(p, dir string) bool {
+	p, dir = path.Clean(p), path.Clean(dir)
+	return p == dir || dir == "." || strings.HasPrefix(p, dir+"/")
+}
+
+func isPartialClone(ctx context.Context, dir GitDir) bool {
+	cmd := exec.CommandContext(ctx, "git", "config", "--get", "remote.origin.promisor")
+	dir.Set(cmd)
+	out, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+func isShallowClone(dir GitDir) bool {
+	_, err := os.Stat(dir.Path("shallow"))
+	return err == nil
+}
+
+// prefetchBlobs fetches the missing file contents of paths at treeish from the
+// code host in one request. Otherwise git fetches them one at a time when they
+// are read.
+func prefetchBlobs(ctx context.Context, dir GitDir, treeish string, paths []string) error {
+	// rev-list lists missing objects without fetching them, but without
+	// their paths. ls-tree lists the objects in paths.
+	cmd := exec.CommandContext(ctx, "git", "rev-list", "--objects", "--missing=print", "--no-walk", treeish, "--")
+	dir.Set(cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		return errors.Wrap(err, "listing missing objects")
+	}
+	missing := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "?") {
+			missing[line[1:]] = true
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	cmd = exec.CommandContext(ctx, "git", append([]string{"ls-tree", "-r", "--full-tree", "-z", treeish, "--"}, paths...)...)
+	dir.Set(cmd)
+	out, err = cmd.Output()
+	if err != nil {
+		return errors.Wrap(err, "listing objects")
+	}
+	var wants []string
+	for _, entry := range bytes.Split(out, []byte{0}) {
+		// <mode> SP <type> SP <object> TAB <file>
+		fields := strings.Fields(string(bytes.SplitN(entry, []byte{'\t'}, 2)[0]))
+		if len(fields) == 3 && missing[fields[2]] {
+			wants = append(wants, fields[2])
+			delete(missing, fields[2])
+		}
+	}
+	if len(wants) == 0 {
+		return nil
+	}
+
+	// This is the fetch git runs for missing objects, for all of them at
+	// once.
+	cmd = exec.CommandContext(ctx, "git", "-c", "fetch.negotiationAlgorithm=noop", "fetch", "origin",
+		"--no-tags", "--no-write-fetch-head", "--recurse-submodules=no", "--filter=blob:none", "--stdin")
+	dir.Set(cmd)
+	cmd.Stdin = strings.NewReader(strings.Join(wants, "\n") + "\n")
+	if output, err := runWithRemoteOpts(ctx, cmd, nil); err != nil {
+		// 🚨 SECURITY: The output could include the remote URL, which may
+		// contain a token.
+		remoteURL, _ := repoRemoteURL(ctx, dir)
+		return errors.Wrapf(err, "fetching %d objects. Output: %s", len(wants), newURLRedactor(remoteURL).redact(string(output)))
+	}
+	return nil
+}
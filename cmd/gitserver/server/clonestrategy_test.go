@@ -0,0 +1,147 @@
+package server
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestCloneStrategy_archivePaths(t *testing.T) {
+	s := &cloneStrategy{sparsePaths: []string{"a", "b/c"}}
+	for _, tc := range []struct {
+		paths, want []string
+	}{
+		{nil, []string{"a", "b/c"}},
+		{[]string{"a/x", "d"}, []string{"a/x"}},
+		{[]string{"b"}, []string{"b/c"}},
+		{[]string{"ab"}, []string{"ab"}},
+	} {
+		if got := s.archivePaths(tc.paths); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("archivePaths(%q) = %q, want %q", tc.paths, got, tc.want)
+		}
+	}
+
+	if got := (*cloneStrategy)(nil).archivePaths([]string{"d"}); !reflect.DeepEqual(got, []string{"d"}) {
+		t.Errorf("got %q for a full clone, want the requested paths", got)
+	}
+}
+
+func TestValidateCloneStrategies(t *testing.T) {
+	strategies := []*schema.GitCloneStrategy{{Pattern: "^example\\.com/", Blobless: true}}
+	if err := ValidateCloneStrategies(strategies); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateCloneStrategies([]*schema.GitCloneStrategy{{Pattern: "["}}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+
+	defer func(orig []string) { refspecOverrides = orig }(refspecOverrides)
+	refspecOverrides = []string{"+refs/heads/master:refs/heads/master"}
+	if err := ValidateCloneStrategies(strategies); err == nil {
+		t.Error("expected an error for strategies with refspec overrides")
+	}
+	if got := buildCloneStrategies(strategies); len(got) != 0 {
+		t.Errorf("got %d strategies with refspec overrides, want none", len(got))
+	}
+	if err := ValidateCloneStrategies(nil); err != nil {
+		t.Errorf("got %v without strategies, want no error", err)
+	}
+}
+
+func TestCloneRepo_partial(t *testing.T) {
+	remote := tmpDir(t)
+	for _, args := range [][]string{
+		{"git", "init", "."},
+		{"git", "config", "uploadpack.allowFilter", "true"},
+		{"git", "config", "uploadpack.allowAnySHA1InWant", "true"},
+		{"mkdir", "a", "b"},
+		{"sh", "-c", "echo 1 > a/x && echo 2 > b/y"},
+		{"git", "add", "."},
+		{"git", "commit", "-m", "one"},
+		{"sh", "-c", "echo 3 > a/x"},
+		{"git", "commit", "-am", "two"},
+	} {
+		runCmd(t, remote, args[0], args[1:]...)
+	}
+
+	orig := cloneStrategies
+	cloneStrategies = func() interface{} {
+		return buildCloneStrategies([]*schema.GitCloneStrategy{
+			{Pattern: `^example\.com/other$`},
+			{Pattern: `^example\.com/`, Depth: 1, SparsePaths: []string{"a"}},
+		})
+	}
+	defer func() { cloneStrategies = orig }()
+
+	s := &Server{ReposDir: tmpDir(t)}
+	h := s.Handler()
+
+	repo := api.RepoName("example.com/foo/bar")
+	if _, err := s.cloneRepo(context.Background(), repo, "file://"+remote, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := s.dir(repo)
+	if !isShallowClone(dir) {
+		t.Error("expected a shallow clone")
+	}
+	if !isPartialClone(context.Background(), dir) {
+		t.Error("expected a partial clone")
+	}
+	if got := strings.TrimSpace(runCmd(t, string(dir), "git", "rev-list", "--count", "HEAD")); got != "1" {
+		t.Errorf("got %s commits, want 1", got)
+	}
+
+	// The archive of a sparse clone only contains the sparse paths, whose
+	// contents are fetched.
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/archive?repo="+string(repo)+"&treeish=HEAD&format=tar", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	var files []string
+	tr := tar.NewReader(w.Body)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag != tar.TypeXGlobalHeader {
+			files = append(files, hdr.Name)
+		}
+	}
+	sort.Strings(files)
+	if want := []string{"a/", "a/x"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("got archive %q, want %q", files, want)
+	}
+	if got := strings.Count(runCmd(t, string(dir), "git", "rev-list", "--objects", "--missing=print", "--no-walk", "HEAD"), "?"); got != 1 {
+		t.Errorf("got %d missing objects, want only the contents of b/y", got)
+	}
+
+	// Updates stay shallow.
+	runCmd(t, remote, "sh", "-c", "echo 4 > b/y")
+	runCmd(t, remote, "git", "commit", "-am", "three")
+	if err := s.doRepoUpdate2(repo, "file://"+remote); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(runCmd(t, string(dir), "git", "rev-list", "--count", "HEAD")); got != "1" {
+		t.Errorf("got %s commits after update, want 1", got)
+	}
+	if _, err := os.Stat(filepath.Join(string(dir), "shallow")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,495 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// repoSizesFile is the file in ReposDir which stores the index of the sizes of
+// the repositories as JSON. Its name starts with a dot, so that it can't clash
+// with the directory of a repository.
+const repoSizesFile = ".repo-sizes.json"
+
+// repoSizeTTL is how long the janitor trusts the size of a repository in the
+// index. Clones and fetches update the size, but maintenance and reclones
+// change it too.
+const repoSizeTTL = time.Hour
+
+var (
+	reposSizeBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "src_gitserver_repos_size_bytes",
+		Help: "total size of the cloned repositories on disk",
+	})
+	repoQuotaSizeBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "src_gitserver_repo_quota_size_bytes",
+		Help: "total size of the cloned repositories of each quota on disk",
+	}, []string{"quota"})
+	reposOverQuota = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "src_gitserver_repos_over_quota",
+		Help: "number of repos which exceeded their quota, by the action taken",
+	}, []string{"action"})
+)
+
+// repoSize is the entry of a repository in the index of repository sizes.
+type repoSize struct {
+	// Size is the size of the repository on disk in bytes, or 0 if it is not
+	// cloned.
+	Size int64
+	// URL is the remote URL of the repository without credentials, which
+	// quotas are matched against.
+	URL string
+	// UpdatedAt is when Size was computed.
+	UpdatedAt time.Time
+
+	// ExceededSize is the size of the last full clone of the repository which
+	// exceeded the maximum repository size of its quota, at ExceededAt.
+	// Until the size is stale, the repository is skipped or shallow cloned
+	// without cloning it in full first.
+	ExceededSize int64
+	ExceededAt   time.Time
+}
+
+// exceeded returns true if the repository exceeded the maximum repository size
+// of q recently.
+func (rs repoSize) exceeded(q *repoQuota) bool {
+	return q.maxRepoSize > 0 && rs.ExceededSize > q.maxRepoSize && time.Since(rs.ExceededAt) < repoTTL
+}
+
+// repoSizes is the index of the sizes of the repositories in ReposDir. It is
+// loaded from ReposDir when it is first used, and saved by the janitor. The
+// zero value is ready to use.
+type repoSizes struct {
+	mu     sync.Mutex
+	loaded bool
+	sizes  map[api.RepoName]repoSize
+}
+
+// load reads the index from reposDir, unless it is already loaded. It must be
+// called with mu held.
+func (r *repoSizes) load(reposDir string) {
+	if r.loaded {
+		return
+	}
+	r.loaded = true
+	r.sizes = make(map[api.RepoName]repoSize)
+
+	b, err := ioutil.ReadFile(filepath.Join(reposDir, repoSizesFile))
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(b, &r.sizes)
+	}
+	if err != nil {
+		// The janitor rebuilds the index.
+		log15.Warn("failed to read repo sizes", "error", err)
+		r.sizes = make(map[api.RepoName]repoSize)
+	}
+}
+
+// get returns the entry of repo in the index.
+func (r *repoSizes) get(reposDir string, repo api.RepoName) (repoSize, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.load(reposDir)
+	rs, ok := r.sizes[protocol.NormalizeRepo(repo)]
+	return rs, ok
+}
+
+// update replaces the entry of repo in the index with the result of f, which
+// is passed the current entry.
+func (r *repoSizes) update(reposDir string, repo api.RepoName, f func(repoSize) repoSize) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.load(reposDir)
+	repo = protocol.NormalizeRepo(repo)
+	rs := f(r.sizes[repo])
+	if rs == (repoSize{}) {
+		delete(r.sizes, repo)
+		return
+	}
+	r.sizes[repo] = rs
+}
+
+// removed records that repo was removed from disk. The entry is kept if the
+// repository exceeded its quota, so that it is not cloned in full again.
+func (r *repoSizes) removed(reposDir string, repo api.RepoName) {
+	r.update(reposDir, repo, func(rs repoSize) repoSize {
+		if rs.ExceededSize == 0 {
+			return repoSize{}
+		}
+		rs.Size = 0
+		rs.UpdatedAt = time.Now()
+		return rs
+	})
+}
+
+// prune removes the entries of the repositories which are not in cloned,
+// which are the repositories on disk.
+func (r *repoSizes) prune(reposDir string, cloned map[api.RepoName]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.load(reposDir)
+	for repo, rs := range r.sizes {
+		if cloned[repo] || rs.Size == 0 {
+			continue
+		}
+		if rs.ExceededSize == 0 {
+			delete(r.sizes, repo)
+			continue
+		}
+		rs.Size = 0
+		r.sizes[repo] = rs
+	}
+}
+
+// total returns the total size of the repositories of quota q, except for
+// repo.
+func (r *repoSizes) total(reposDir string, q *repoQuota, except api.RepoName) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.load(reposDir)
+	except = protocol.NormalizeRepo(except)
+	var total int64
+	for repo, rs := range r.sizes {
+		if repo != except && q.matches(rs.URL) {
+			total += rs.Size
+		}
+	}
+	return total
+}
+
+// save writes the index to reposDir and reports the sizes to Prometheus.
+func (r *repoSizes) save(reposDir string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.load(reposDir)
+
+	var total int64
+	quotas := repoQuotas().([]*repoQuota)
+	quotaTotals := make([]int64, len(quotas))
+	for _, rs := range r.sizes {
+		total += rs.Size
+		for i, q := range quotas {
+			if q.matches(rs.URL) {
+				quotaTotals[i] += rs.Size
+				break
+			}
+		}
+	}
+	reposSizeBytes.Set(float64(total))
+	repoQuotaSizeBytes.Reset()
+	for i, q := range quotas {
+		repoQuotaSizeBytes.WithLabelValues(q.url).Set(float64(quotaTotals[i]))
+	}
+
+	b, err := json.Marshal(r.sizes)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a crash doesn't leave a
+	// truncated index behind.
+	tmp := filepath.Join(reposDir, repoSizesFile+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "failed to write repo sizes")
+	}
+	return os.Rename(tmp, filepath.Join(reposDir, repoSizesFile))
+}
+
+// updateRepoSize computes the size of repo, which is stored in dir and was
+// synced from remoteURL, and records it in the index. If the repository is a
+// full clone which exceeds the maximum repository size of its quota, the
+// janitor enforces the quota.
+func (s *Server) updateRepoSize(repo api.RepoName, dir GitDir, remoteURL string) (int64, error) {
+	size, err := dirSize(string(dir))
+	if err != nil {
+		return 0, err
+	}
+	u := remoteURLWithoutCredentials(remoteURL)
+	q := repoQuotaFor(u)
+	exceeded := q != nil && q.maxRepoSize > 0 && size > q.maxRepoSize && !isShallowClone(dir)
+	s.repoSizes.update(s.ReposDir, repo, func(rs repoSize) repoSize {
+		rs.Size = size
+		rs.URL = u
+		rs.UpdatedAt = time.Now()
+		if exceeded {
+			rs.ExceededSize = size
+			rs.ExceededAt = rs.UpdatedAt
+		}
+		return rs
+	})
+	return size, nil
+}
+
+// repoQuota limits the disk space used by the repositories of an external
+// service. It is configured with the gitserverRepoQuotas site configuration.
+type repoQuota struct {
+	// url is the prefix of the remote URLs, without credentials, of the
+	// repositories of the quota.
+	url string
+
+	// maxRepoSize is the maximum size of a repository in bytes, or 0 if
+	// unlimited.
+	maxRepoSize int64
+
+	// maxTotalSize is the maximum total size of the repositories in bytes,
+	// or 0 if unlimited.
+	maxTotalSize int64
+
+	// shallow is true if repositories exceeding the quota are shallow
+	// cloned. Otherwise they are skipped.
+	shallow bool
+}
+
+var repoQuotas = conf.Cached(func() interface{} {
+	return buildRepoQuotas(conf.Get().GitserverRepoQuotas)
+})
+
+func buildRepoQuotas(c []*schema.GitserverRepoQuota) []*repoQuota {
+	const mb = 1024 * 1024
+	quotas := make([]*repoQuota, 0, len(c))
+	for _, q := range c {
+		quotas = append(quotas, &repoQuota{
+			url:          q.Url,
+			maxRepoSize:  int64(q.MaxRepoSizeMB) * mb,
+			maxTotalSize: int64(q.MaxTotalSizeMB) * mb,
+			shallow:      q.Action == "shallow",
+		})
+	}
+	return quotas
+}
+
+// repoQuotaFor returns the quota of the repository with remoteURL, or nil if
+// it has none.
+func repoQuotaFor(remoteURL string) *repoQuota {
+	remoteURL = remoteURLWithoutCredentials(remoteURL)
+	for _, q := range repoQuotas().([]*repoQuota) {
+		if q.matches(remoteURL) {
+			return q
+		}
+	}
+	return nil
+}
+
+// matches returns true if the quota applies to the repository with remoteURL,
+// which must not have credentials.
+func (q *repoQuota) matches(remoteURL string) bool {
+	return strings.HasPrefix(remoteURL, q.url)
+}
+
+// remoteURLWithoutCredentials returns remoteURL without its user info and
+// without the hg:: prefix of Mercurial repositories, which is what quotas are
+// matched against.
+func remoteURLWithoutCredentials(remoteURL string) string {
+	remoteURL = strings.TrimPrefix(remoteURL, hgURLPrefix)
+	u, err := url.Parse(remoteURL)
+	if err != nil || u.User == nil {
+		// For example an SCP-style git remote (git@host:repo), whose user
+		// is not a secret.
+		return remoteURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// errRepoQuotaExceeded is returned for repositories which are not cloned
+// because they exceed their quota.
+type errRepoQuotaExceeded struct {
+	quota  string
+	reason string
+}
+
+func (e *errRepoQuotaExceeded) Error() string {
+	return fmt.Sprintf("repository exceeds the quota of %s: %s", e.quota, e.reason)
+}
+
+// checkRepoQuota checks the quota of repo before it is cloned from remoteURL.
+// It returns an error if the repository must not be cloned, and shallow is
+// true if it must be shallow cloned.
+func (s *Server) checkRepoQuota(ctx context.Context, repo api.RepoName, remoteURL string) (shallow bool, err error) {
+	q := repoQuotaFor(remoteURL)
+	if q == nil {
+		return false, nil
+	}
+	if rs, ok := s.repoSizes.get(s.ReposDir, repo); ok && rs.exceeded(q) {
+		return repoQuotaAction(q, fmt.Sprintf("its size of %d bytes exceeds %d bytes", rs.ExceededSize, q.maxRepoSize))
+	}
+	if q.maxRepoSize > 0 {
+		// The size the code host reports is only an estimate, but it
+		// spares cloning repositories which are far too large.
+		if size, ok := codeHostRepoSize(ctx, remoteURL); ok && size > q.maxRepoSize {
+			s.recordExceededRepoSize(repo, remoteURL, size)
+			return repoQuotaAction(q, fmt.Sprintf("its size of %d bytes on the code host exceeds %d bytes", size, q.maxRepoSize))
+		}
+	}
+	if q.maxTotalSize > 0 {
+		if total := s.repoSizes.total(s.ReposDir, q, repo); total >= q.maxTotalSize {
+			return repoQuotaAction(q, fmt.Sprintf("the total size of %d bytes exceeds %d bytes", total, q.maxTotalSize))
+		}
+	}
+	return false, nil
+}
+
+// checkClonedRepoQuota checks the quota of repo after it was cloned in full
+// from remoteURL into the temporary directory tmp, or after its clone was
+// canceled at the size canceledAt because it exceeded its quota.
+func (s *Server) checkClonedRepoQuota(repo api.RepoName, remoteURL string, tmp GitDir, canceledAt int64) (shallow bool, err error) {
+	q := repoQuotaFor(remoteURL)
+	if q == nil || q.maxRepoSize == 0 {
+		return false, nil
+	}
+	size := canceledAt
+	if size == 0 {
+		if isShallowClone(tmp) {
+			return false, nil
+		}
+		if size, err = dirSize(string(tmp)); err != nil {
+			return false, err
+		}
+	}
+	if size <= q.maxRepoSize {
+		return false, nil
+	}
+	s.recordExceededRepoSize(repo, remoteURL, size)
+	return repoQuotaAction(q, fmt.Sprintf("its size of at least %d bytes exceeds %d bytes", size, q.maxRepoSize))
+}
+
+// recordExceededRepoSize records in the index that repo exceeded the maximum
+// repository size of its quota with size, so that it is skipped or shallow
+// cloned without cloning it in full first.
+func (s *Server) recordExceededRepoSize(repo api.RepoName, remoteURL string, size int64) {
+	s.repoSizes.update(s.ReposDir, repo, func(rs repoSize) repoSize {
+		rs.URL = remoteURLWithoutCredentials(remoteURL)
+		rs.ExceededSize = size
+		rs.ExceededAt = time.Now()
+		if rs.UpdatedAt.IsZero() {
+			rs.UpdatedAt = rs.ExceededAt
+		}
+		return rs
+	})
+}
+
+// cloneSizeCheckInterval is how often watchCloneSize checks the size of a
+// clone. It is replaced in tests.
+var cloneSizeCheckInterval = 5 * time.Second
+
+// watchCloneSize calls cancel once the size of the clone in dir exceeds max.
+// The returned function stops watching and returns the size at which the
+// clone was canceled, or 0 if it wasn't.
+func watchCloneSize(dir string, max int64, cancel context.CancelFunc) (stop func() int64) {
+	done := make(chan struct{})
+	var canceledAt int64
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(cloneSizeCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if size, err := dirSize(dir); err == nil && size > max {
+				canceledAt = size
+				cancel()
+				return
+			}
+		}
+	}()
+	return func() int64 {
+		close(done)
+		wg.Wait()
+		return canceledAt
+	}
+}
+
+// codeHostRepoSize returns the size of the repository with remoteURL which
+// its code host reports, if the code host is GitHub.com. The size is that of
+// the repository on the code host, which is close to that of a clone. It is
+// replaced in tests.
+var codeHostRepoSize = func(ctx context.Context, remoteURL string) (int64, bool) {
+	u, err := url.Parse(remoteURL)
+	if err != nil || u.Hostname() != "github.com" {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	nameWithOwner := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	req, err := http.NewRequest("GET", "https://api.github.com/repos/"+nameWithOwner, nil)
+	if err != nil {
+		return 0, false
+	}
+	// The token of the external service is the user or the password of
+	// its clone URLs.
+	if u.User != nil {
+		token, ok := u.User.Password()
+		if !ok {
+			token = u.User.Username()
+		}
+		req.Header.Set("Authorization", "token "+token)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		log15.Debug("failed to get repo size from code host", "url", remoteURLWithoutCredentials(remoteURL), "error", err)
+		return 0, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, false
+	}
+	var repo struct {
+		Size int64 `json:"size"` // in KiB
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&repo); err != nil {
+		return 0, false
+	}
+	return repo.Size * 1024, true
+}
+
+func repoQuotaAction(q *repoQuota, reason string) (shallow bool, err error) {
+	if q.shallow {
+		reposOverQuota.WithLabelValues("shallow").Inc()
+		return true, nil
+	}
+	reposOverQuota.WithLabelValues("skip").Inc()
+	return false, &errRepoQuotaExceeded{quota: q.url, reason: reason}
+}
+
+// shallowSyncer returns the syncer which shallow clones with syncer, or nil if
+// syncer can't shallow clone.
+func shallowSyncer(syncer VCSSyncer) VCSSyncer {
+	s, ok := syncer.(*gitRepoSyncer)
+	if !ok || useRefspecOverrides() {
+		// Clones with refspec overrides ignore the clone strategy.
+		return nil
+	}
+	strategy := cloneStrategy{depth: 1}
+	if s.strategy != nil {
+		strategy = *s.strategy
+		if strategy.depth == 0 {
+			strategy.depth = 1
+		}
+	}
+	return &gitRepoSyncer{strategy: &strategy}
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestRepoSizes(t *testing.T) {
+	reposDir := tmpDir(t)
+	q := &repoQuota{url: "https://github.com/"}
+
+	var r repoSizes
+	r.update(reposDir, "github.com/a/b", func(rs repoSize) repoSize {
+		return repoSize{Size: 10, URL: "https://github.com/a/b"}
+	})
+	r.update(reposDir, "github.com/c/d", func(rs repoSize) repoSize {
+		return repoSize{Size: 20, URL: "https://github.com/c/d", ExceededSize: 20}
+	})
+	r.update(reposDir, "gitlab.com/e/f", func(rs repoSize) repoSize {
+		return repoSize{Size: 40, URL: "https://gitlab.com/e/f"}
+	})
+	if err := r.save(reposDir); err != nil {
+		t.Fatal(err)
+	}
+
+	// The index survives restarts.
+	var loaded repoSizes
+	if got := loaded.total(reposDir, q, ""); got != 30 {
+		t.Errorf("got total %d, want 30", got)
+	}
+	if got := loaded.total(reposDir, q, "github.com/a/b"); got != 20 {
+		t.Errorf("got total %d without github.com/a/b, want 20", got)
+	}
+
+	// Removed repositories are dropped from the index, unless they exceeded
+	// their quota.
+	loaded.prune(reposDir, map[api.RepoName]bool{"gitlab.com/e/f": true})
+	if _, ok := loaded.get(reposDir, "github.com/a/b"); ok {
+		t.Error("expected github.com/a/b to be removed")
+	}
+	if rs, ok := loaded.get(reposDir, "github.com/c/d"); !ok || rs.Size != 0 || rs.ExceededSize != 20 {
+		t.Errorf("got %+v for github.com/c/d, want the exceeded size to be kept", rs)
+	}
+	if got := loaded.total(reposDir, q, ""); got != 0 {
+		t.Errorf("got total %d, want 0", got)
+	}
+}
+
+func TestRemoteURLWithoutCredentials(t *testing.T) {
+	for remoteURL, want := range map[string]string{
+		"https://token@github.com/a/b":           "https://github.com/a/b",
+		"https://github.com/a/b":                 "https://github.com/a/b",
+		"git@github.com:a/b.git":                 "git@github.com:a/b.git",
+		"hg::https://user:pw@hg.example.com/a":   "https://hg.example.com/a",
+		"perforce://admin:pw@p4:1666//depot/a/b": "perforce://p4:1666//depot/a/b",
+	} {
+		if got := remoteURLWithoutCredentials(remoteURL); got != want {
+			t.Errorf("%s: got %q, want %q", remoteURL, got, want)
+		}
+	}
+}
+
+func TestCloneRepo_quota(t *testing.T) {
+	remote := tmpDir(t)
+	for _, args := range [][]string{
+		{"git", "init", "."},
+		{"sh", "-c", "head -c 2000000 /dev/urandom > big"},
+		{"git", "add", "."},
+		{"git", "commit", "-m", "one"},
+		{"git", "rm", "big"},
+		{"git", "commit", "-m", "two"},
+	} {
+		runCmd(t, remote, args[0], args[1:]...)
+	}
+	remoteURL := "file://" + remote
+
+	setQuota := func(q *schema.GitserverRepoQuota) {
+		repoQuotas = func() interface{} {
+			return buildRepoQuotas([]*schema.GitserverRepoQuota{q})
+		}
+	}
+	orig := repoQuotas
+	defer func() { repoQuotas = orig }()
+
+	ctx := context.Background()
+	s := &Server{ReposDir: tmpDir(t)}
+	s.Handler() // Handler as a side-effect sets up Server
+	repo := api.RepoName("example.com/foo/bar")
+
+	// The history of the repository is larger than 1 MB, but its most recent
+	// commit is not.
+	setQuota(&schema.GitserverRepoQuota{Url: "file://", MaxRepoSizeMB: 1})
+	if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true}); err == nil {
+		t.Fatal("expected the clone exceeding its quota to fail")
+	}
+	if repoCloned(s.dir(repo)) {
+		t.Fatal("expected repo not to be cloned")
+	}
+	if rs, ok := s.repoSizes.get(s.ReposDir, repo); !ok || rs.ExceededSize == 0 {
+		t.Fatalf("got %+v, want the exceeded size to be recorded", rs)
+	}
+
+	// With the shallow action the repository is shallow cloned without
+	// cloning it in full again.
+	setQuota(&schema.GitserverRepoQuota{Url: "file://", MaxRepoSizeMB: 1, Action: "shallow"})
+	if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !isShallowClone(s.dir(repo)) {
+		t.Error("expected a shallow clone")
+	}
+	if rs, _ := s.repoSizes.get(s.ReposDir, repo); rs.Size == 0 || rs.Size > 1024*1024 {
+		t.Errorf("got size %d, want the size of the shallow clone", rs.Size)
+	}
+
+	// Once the total size of the quota is reached, new repositories are
+	// skipped.
+	setQuota(&schema.GitserverRepoQuota{Url: "file://", MaxTotalSizeMB: 1})
+	s.repoSizes.update(s.ReposDir, repo, func(rs repoSize) repoSize {
+		rs.Size = 1024 * 1024
+		return rs
+	})
+	_, err := s.cloneRepo(ctx, "example.com/foo/other", remoteURL, &cloneOptions{Block: true})
+	if err == nil {
+		t.Fatal("expected the clone exceeding the total size to fail")
+	}
+}
+
+func TestCloneRepo_quotaBeforeClone(t *testing.T) {
+	remote := tmpDir(t)
+	runCmd(t, remote, "git", "init", ".")
+	runCmd(t, remote, "git", "commit", "--allow-empty", "-m", "one")
+	runCmd(t, remote, "git", "commit", "--allow-empty", "-m", "two")
+	remoteURL := "file://" + remote
+
+	origQuotas, origSize := repoQuotas, codeHostRepoSize
+	defer func() { repoQuotas, codeHostRepoSize = origQuotas, origSize }()
+	repoQuotas = func() interface{} {
+		return buildRepoQuotas([]*schema.GitserverRepoQuota{{Url: "file://", MaxRepoSizeMB: 1, Action: "shallow"}})
+	}
+	// The code host reports a size above the quota, so the repository is
+	// shallow cloned without cloning it in full first.
+	codeHostRepoSize = func(ctx context.Context, remoteURL string) (int64, bool) {
+		return 10 * 1024 * 1024, true
+	}
+
+	s := &Server{ReposDir: tmpDir(t)}
+	s.Handler() // Handler as a side-effect sets up Server
+	repo := api.RepoName("example.com/foo/bar")
+	if _, err := s.cloneRepo(context.Background(), repo, remoteURL, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !isShallowClone(s.dir(repo)) {
+		t.Error("expected a shallow clone")
+	}
+	if rs, _ := s.repoSizes.get(s.ReposDir, repo); rs.ExceededSize != 10*1024*1024 {
+		t.Errorf("got exceeded size %d, want the size on the code host", rs.ExceededSize)
+	}
+}
+
+func TestCloneRepo_quotaFromBackup(t *testing.T) {
+	remote := tmpDir(t)
+	for _, args := range [][]string{
+		{"git", "init", "."},
+		{"sh", "-c", "head -c 2000000 /dev/urandom > big"},
+		{"git", "add", "."},
+		{"git", "commit", "-m", "one"},
+		{"git", "rm", "big"},
+		{"git", "commit", "-m", "two"},
+	} {
+		runCmd(t, remote, args[0], args[1:]...)
+	}
+	remoteURL := "file://" + remote
+
+	backups, err := NewBundleStore(tmpDir(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{ReposDir: tmpDir(t), Backups: backups, RestoreFromBackup: true}
+	s.Handler() // Handler as a side-effect sets up Server
+
+	ctx := context.Background()
+	repo := api.RepoName("example.com/foo/bar")
+	if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.backupRepo(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Dir(string(s.dir(repo)))); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := repoQuotas
+	defer func() { repoQuotas = orig }()
+	repoQuotas = func() interface{} {
+		return buildRepoQuotas([]*schema.GitserverRepoQuota{{Url: "file://", MaxRepoSizeMB: 1, Action: "shallow"}})
+	}
+
+	// Bundles can't be shallow cloned, so the repository is shallow cloned
+	// from the code host instead of being restored in full.
+	if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true, FromBackup: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !isShallowClone(s.dir(repo)) {
+		t.Error("expected a shallow clone")
+	}
+	dir := filepath.Dir(string(s.dir(repo)))
+	if got := strings.TrimSpace(runCmd(t, dir, "git", "config", "remote.origin.url")); got != remoteURL {
+		t.Errorf("got remote URL %q, want the code host URL %q", got, remoteURL)
+	}
+}
+
+func TestWatchCloneSize(t *testing.T) {
+	defer func(orig time.Duration) { cloneSizeCheckInterval = orig }(cloneSizeCheckInterval)
+	cloneSizeCheckInterval = 10 * time.Millisecond
+
+	dir := tmpDir(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := watchCloneSize(dir, 1000, cancel)
+	if err := ioutil.WriteFile(filepath.Join(dir, "big"), make([]byte, 2000), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the clone to be canceled")
+	}
+	if got := stop(); got < 2000 {
+		t.Errorf("got canceled at %d bytes, want at least 2000", got)
+	}
+
+	// Clones within the quota are not canceled.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	stop = watchCloneSize(dir, 1000000, cancel)
+	time.Sleep(50 * time.Millisecond)
+	if got := stop(); got != 0 || ctx.Err() != nil {
+		t.Errorf("got canceled at %d bytes, want no cancellation", got)
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+// execAuditLogSize is the number of recent exec requests kept in memory for
+// the /exec-audit endpoint.
+const execAuditLogSize = 1000
+
+// execAuditLog is a ring buffer of the most recent exec requests. The zero
+// value is ready to use.
+type execAuditLog struct {
+	mu      sync.Mutex
+	records []protocol.ExecAuditRecord
+	next    int
+}
+
+func (l *execAuditLog) add(rec protocol.ExecAuditRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.records) < execAuditLogSize {
+		l.records = append(l.records, rec)
+		return
+	}
+	l.records[l.next] = rec
+	l.next = (l.next + 1) % execAuditLogSize
+}
+
+// list returns the records, most recent first.
+func (l *execAuditLog) list() []protocol.ExecAuditRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	records := make([]protocol.ExecAuditRecord, 0, len(l.records))
+	for i := len(l.records) - 1; i >= 0; i-- {
+		records = append(records, l.records[(l.next+i)%len(l.records)])
+	}
+	return records
+}
+
+// auditExec records an exec request in the audit log. The log only keeps
+// the most recent requests, so every request is logged as well.
+func (s *Server) auditExec(rec protocol.ExecAuditRecord) {
+	s.execAudit.add(rec)
+	log15.Info("gitserver exec audit",
+		"actor", rec.Actor,
+		"service", rec.Service,
+		"repo", rec.Repo,
+		"args", rec.Args,
+		"duration", rec.Duration,
+		"exit_status", rec.ExitStatus,
+		"stdout_bytes", rec.StdoutBytes,
+		"status", rec.Status,
+	)
+}
+
+// execService returns the name of the service which sent r, as proven by the
+// token in its X-Sourcegraph-Service-Token header, or "" if r has no valid
+// token. The User-Agent header is not used, because any client can set it.
+func (s *Server) execService(r *http.Request) string {
+	token := r.Header.Get("X-Sourcegraph-Service-Token")
+	if token == "" {
+		return ""
+	}
+	for service, t := range s.ServiceTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			return service
+		}
+	}
+	return ""
+}
+
+// execAllowlist returns the allowlist of the gitserverExecAllowlist site
+// configuration. It is replaced in tests.
+var execAllowlist = func() map[string][]string {
+	return conf.Get().GitserverExecAllowlist
+}
+
+// execAllowed reports whether service may run the git subcommand in args.
+// Requests of unauthenticated services, whose service is "", are subject to
+// the "*" entry of the allowlist.
+func execAllowed(service string, args []string) bool {
+	allowlist := execAllowlist()
+	if allowlist == nil {
+		return true
+	}
+	commands, ok := allowlist[service]
+	if !ok || service == "" {
+		commands = allowlist["*"]
+	}
+	if len(args) == 0 {
+		return false
+	}
+	for _, c := range commands {
+		if c == "*" || c == args[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// denyExec responds with 403 Forbidden to a request to run a git command
+// which the service isn't allowed to run.
+func denyExec(w http.ResponseWriter, service string) {
+	if service == "" {
+		service = "unauthenticated services"
+	}
+	http.Error(w, "git command not allowed for "+service+" by gitserverExecAllowlist", http.StatusForbidden)
+}
+
+func (s *Server) handleExecAudit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.execAudit.list()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
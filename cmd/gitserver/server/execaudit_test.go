@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+func TestExecAuditLog(t *testing.T) {
+	var l execAuditLog
+	for i := 0; i < execAuditLogSize+2; i++ {
+		l.add(protocol.ExecAuditRecord{ExitStatus: i})
+	}
+	records := l.list()
+	if len(records) != execAuditLogSize {
+		t.Fatalf("got %d records, want %d", len(records), execAuditLogSize)
+	}
+	if first, last := records[0].ExitStatus, records[len(records)-1].ExitStatus; first != execAuditLogSize+1 || last != 2 {
+		t.Errorf("got records %d to %d, want %d to 2", first, last, execAuditLogSize+1)
+	}
+}
+
+func TestExecAllowed(t *testing.T) {
+	orig := execAllowlist
+	defer func() { execAllowlist = orig }()
+
+	execAllowlist = func() map[string][]string { return nil }
+	if !execAllowed("searcher", []string{"log"}) {
+		t.Error("commands are denied without allowlist")
+	}
+
+	execAllowlist = func() map[string][]string {
+		return map[string][]string{
+			"searcher": {"archive", "rev-parse"},
+			"frontend": {"*"},
+		}
+	}
+	for _, tc := range []struct {
+		service string
+		args    []string
+		want    bool
+	}{
+		{"searcher", []string{"archive", "HEAD"}, true},
+		{"searcher", []string{"log"}, false},
+		{"frontend", []string{"log"}, true},
+		{"frontend", nil, false},
+		{"query-runner", []string{"rev-parse"}, false},
+		{"", []string{"rev-parse"}, false},
+	} {
+		if got := execAllowed(tc.service, tc.args); got != tc.want {
+			t.Errorf("execAllowed(%q, %q) = %v, want %v", tc.service, tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestServer_execService(t *testing.T) {
+	s := &Server{ServiceTokens: map[string]string{"searcher": "s3cret"}}
+	for _, tc := range []struct {
+		token string
+		want  string
+	}{
+		{"s3cret", "searcher"},
+		{"wrong", ""},
+		{"", ""},
+	} {
+		r := httptest.NewRequest("POST", "/exec", nil)
+		r.Header.Set("User-Agent", "frontend")
+		if tc.token != "" {
+			r.Header.Set("X-Sourcegraph-Service-Token", tc.token)
+		}
+		if got := s.execService(r); got != tc.want {
+			t.Errorf("token %q: got service %q, want %q", tc.token, got, tc.want)
+		}
+	}
+}
+
+func TestServer_exec_denied(t *testing.T) {
+	orig := execAllowlist
+	execAllowlist = func() map[string][]string {
+		return map[string][]string{
+			"searcher": {"rev-parse"},
+			"frontend": {"*"},
+		}
+	}
+	defer func() { execAllowlist = orig }()
+
+	s := &Server{ReposDir: "/testroot", ServiceTokens: map[string]string{"searcher": "s3cret"}}
+	h := s.Handler()
+
+	body, err := json.Marshal(protocol.ExecRequest{Repo: "github.com/gorilla/mux", Args: []string{"show", "HEAD:README.md"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/exec", bytes.NewReader(body))
+	req.Header.Set("X-Sourcegraph-Service-Token", "s3cret")
+	req.Header.Set("X-Sourcegraph-Actor", "1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	// A User-Agent doesn't authenticate a service.
+	req = httptest.NewRequest("POST", "/exec", bytes.NewReader(body))
+	req.Header.Set("User-Agent", "frontend")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d for a spoofed User-Agent, want %d", w.Code, http.StatusForbidden)
+	}
+
+	// Archives are subject to the allowlist too.
+	req = httptest.NewRequest("GET", "/archive?repo=github.com/gorilla/mux&treeish=HEAD&format=tar", nil)
+	req.Header.Set("X-Sourcegraph-Service-Token", "s3cret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d for an archive, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/exec-audit", nil))
+	var records []protocol.ExecAuditRecord
+	if err := json.NewDecoder(w.Body).Decode(&records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	for i := range records {
+		records[i].Time, records[i].Duration = time.Time{}, 0
+	}
+	want := []protocol.ExecAuditRecord{
+		{
+			Service:    "searcher",
+			Repo:       api.RepoName("github.com/gorilla/mux"),
+			Args:       []string{"archive", "--worktree-attributes", "--format=tar", "HEAD"},
+			ExitStatus: -1,
+			Status:     "denied",
+		},
+		{
+			Repo:       api.RepoName("github.com/gorilla/mux"),
+			Args:       []string{"show", "HEAD:README.md"},
+			ExitStatus: -1,
+			Status:     "denied",
+		},
+		{
+			Actor:      "1",
+			Service:    "searcher",
+			Repo:       api.RepoName("github.com/gorilla/mux"),
+			Args:       []string{"show", "HEAD:README.md"},
+			ExitStatus: -1,
+			Status:     "denied",
+		},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %+v, want %+v", records, want)
+	}
+}
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+	"golang.org/x/time/rate"
+)
+
+// Thresholds of the repository statistics at which maintenance tasks run. They
+// match the defaults of git gc --auto, except that we write commit-graphs and
+// multi-pack-indexes which git gc --auto does not.
+const (
+	// maintenanceLooseObjects is the number of loose objects at which a
+	// repository is repacked.
+	maintenanceLooseObjects = 6700
+	// maintenancePacks is the number of packs at which a repository is
+	// repacked into a single pack.
+	maintenancePacks = 50
+	// maintenanceLooseRefs is the number of loose refs at which refs are
+	// packed.
+	maintenanceLooseRefs = 1000
+)
+
+// maintenanceStatusFile is the file in $GIT_DIR which stores the status of the
+// last maintenance as JSON. Its mtime is the time of the last maintenance.
+const maintenanceStatusFile = "sg_maintenance"
+
+// repoStats are the statistics of a repository which decide which
+// maintenance tasks run.
+type repoStats struct {
+	LooseObjects   int
+	Packs          int
+	LooseRefs      int
+	HasCommitGraph bool
+	HasMultiPack   bool
+}
+
+// maintenanceTask is a git command run to keep a repository fast.
+type maintenanceTask struct {
+	Name string
+	Args [][]string
+	// Due reports whether the task should run for a repository with the
+	// given statistics.
+	Due func(repoStats) bool
+}
+
+var maintenanceTasks = []maintenanceTask{
+	{
+		// Packs all objects into a single pack with a bitmap index, which
+		// speeds up fetches and clones of the repository from gitserver. The
+		// loose objects which are now packed and unreachable objects older
+		// than git gc's default are removed.
+		Name: "repack",
+		Args: [][]string{
+			{"repack", "-a", "-d", "-b"},
+			{"prune-packed"},
+			{"prune", "--expire=2.weeks.ago"},
+		},
+		Due: func(s repoStats) bool {
+			return s.LooseObjects >= maintenanceLooseObjects || s.Packs >= maintenancePacks
+		},
+	},
+	{
+		// Packed refs are faster to read than many loose refs.
+		Name: "pack-refs",
+		Args: [][]string{{"pack-refs", "--all", "--prune"}},
+		Due: func(s repoStats) bool {
+			return s.LooseRefs >= maintenanceLooseRefs
+		},
+	},
+	{
+		// A multi-pack-index speeds up object lookups across the packs
+		// which accumulate between repacks.
+		Name: "multi-pack-index",
+		Args: [][]string{{"multi-pack-index", "write"}},
+		Due: func(s repoStats) bool {
+			return s.Packs > 1 && !s.HasMultiPack
+		},
+	},
+	{
+		// The commit-graph speeds up commit walks, such as git log and
+		// git blame. It is rewritten whenever other tasks ran, since they
+		// indicate many new commits.
+		Name: "commit-graph",
+		Args: [][]string{{"commit-graph", "write", "--reachable"}},
+		Due: func(s repoStats) bool {
+			return !s.HasCommitGraph
+		},
+	},
+}
+
+// maintenanceLimiter limits how often repositories are maintained. The first
+// maintenance of a repository always writes its commit-graph, so without a
+// limit the first pass would be one long burst of git commands.
+var maintenanceLimiter = rate.NewLimiter(rate.Every(10*time.Second), 1)
+
+// MaintainRepos maintains the repositories on disk one at a time, separate
+// from the janitor, until the server is stopped. Maintenance is skipped if
+// MaintenanceInterval is not positive.
+func (s *Server) MaintainRepos() {
+	if s.MaintenanceInterval <= 0 {
+		return
+	}
+	for {
+		err := bestEffortWalk(s.ReposDir, func(dir string, fi os.FileInfo) error {
+			if s.ctx.Err() != nil {
+				return s.ctx.Err()
+			}
+			if s.ignorePath(dir) {
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !fi.IsDir() || fi.Name() != ".git" {
+				return nil
+			}
+
+			gitDir := GitDir(dir)
+			status, err := s.maintainRepo(gitDir)
+			if err != nil {
+				log15.Error("failed to maintain repo", "repo", s.name(gitDir), "error", err)
+			} else if status != nil && len(status.Tasks) > 0 {
+				log15.Info("maintained repo", "repo", s.name(gitDir), "tasks", status.Tasks, "looseObjects", status.LooseObjects, "packs", status.Packs)
+			}
+			return filepath.SkipDir
+		})
+		if err != nil && s.ctx.Err() == nil {
+			log15.Error("maintenance: error iterating over repositories", "error", err)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(time.Minute):
+		}
+	}
+}
+
+// maintainRepo maintains the repository in dir while holding its lock, so
+// that maintenance doesn't run concurrently with a clone or fetch of the
+// repository. Repositories which are being cloned are skipped.
+func (s *Server) maintainRepo(dir GitDir) (*protocol.RepoMaintenance, error) {
+	lock, ok := s.locker.TryAcquire(dir, "maintaining")
+	if !ok {
+		return nil, nil
+	}
+	defer lock.Release()
+
+	l, _ := s.repoUpdateLock(s.name(dir))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(s.ctx, longGitCommandTimeout)
+	defer cancel()
+	return maintainRepo(ctx, dir, s.MaintenanceInterval)
+}
+
+// maintainRepo runs the maintenance tasks which are due for the repository in
+// dir, unless it was maintained less than interval ago. It returns the status
+// of the maintenance, or nil if it didn't run. The caller must hold the lock
+// of the repository.
+func maintainRepo(ctx context.Context, dir GitDir, interval time.Duration) (*protocol.RepoMaintenance, error) {
+	if fi, err := os.Stat(dir.Path(maintenanceStatusFile)); err == nil && time.Since(fi.ModTime()) < interval {
+		return nil, nil
+	}
+
+	stats, err := getRepoStats(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range maintenanceTasks {
+		if task.Due(stats) {
+			if err := maintenanceLimiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	status := &protocol.RepoMaintenance{
+		LooseObjects: stats.LooseObjects,
+		Packs:        stats.Packs,
+		LooseRefs:    stats.LooseRefs,
+	}
+
+	for _, task := range maintenanceTasks {
+		// Once a task ran, the repository changed enough for the
+		// commit-graph to be outdated.
+		if !task.Due(stats) && !(len(status.Tasks) > 0 && task.Name == "commit-graph") {
+			continue
+		}
+		status.Tasks = append(status.Tasks, task.Name)
+
+		start := time.Now()
+		err := runMaintenanceTask(ctx, dir, task)
+		maintenanceDuration.WithLabelValues(task.Name).Observe(time.Since(start).Seconds())
+		if err != nil {
+			maintenanceTasksRun.WithLabelValues(task.Name, "error").Inc()
+			status.Error = err.Error()
+			break
+		}
+		maintenanceTasksRun.WithLabelValues(task.Name, "success").Inc()
+
+		// The following tasks depend on what this task changed, for
+		// example a repack leaves a single pack.
+		if stats, err = getRepoStats(ctx, dir); err != nil {
+			status.Error = err.Error()
+			break
+		}
+	}
+	status.FinishedAt = time.Now()
+
+	b, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(dir.Path(maintenanceStatusFile), b, 0600); err != nil {
+		return nil, errors.Wrap(err, "failed to write maintenance status")
+	}
+	if status.Error != "" {
+		return status, errors.New(status.Error)
+	}
+	return status, nil
+}
+
+func runMaintenanceTask(ctx context.Context, dir GitDir, task maintenanceTask) error {
+	for _, args := range task.Args {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		dir.Set(cmd)
+		if output, err := runWith(ctx, cmd, false, nil); err != nil {
+			return errors.Wrapf(err, "%s failed. Output: %s", task.Name, string(output))
+		}
+	}
+	return nil
+}
+
+// getRepoStats returns the statistics of the repository in dir.
+func getRepoStats(ctx context.Context, dir GitDir) (repoStats, error) {
+	var stats repoStats
+
+	cmd := exec.CommandContext(ctx, "git", "count-objects", "-v")
+	dir.Set(cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		return stats, errors.Wrap(wrapCmdError(cmd, err), "failed to count objects")
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ": ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		n, _ := strconv.Atoi(parts[1])
+		switch parts[0] {
+		case "count":
+			stats.LooseObjects = n
+		case "packs":
+			stats.Packs = n
+		}
+	}
+
+	_ = bestEffortWalk(dir.Path("refs"), func(path string, fi os.FileInfo) error {
+		if !fi.IsDir() && !strings.HasSuffix(path, ".lock") {
+			stats.LooseRefs++
+		}
+		return nil
+	})
+
+	stats.HasCommitGraph = fileExists(dir.Path("objects", "info", "commit-graph")) || fileExists(dir.Path("objects", "info", "commit-graphs"))
+	stats.HasMultiPack = fileExists(dir.Path("objects", "pack", "multi-pack-index"))
+	return stats, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// repoMaintenance returns the status of the last maintenance of the
+// repository in dir, or nil if it was never maintained.
+func repoMaintenance(dir GitDir) (*protocol.RepoMaintenance, error) {
+	b, err := ioutil.ReadFile(filepath.Join(string(dir), maintenanceStatusFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var status protocol.RepoMaintenance
+	if err := json.Unmarshal(b, &status); err != nil {
+		return nil, errors.Wrap(err, "failed to parse maintenance status")
+	}
+	return &status, nil
+}
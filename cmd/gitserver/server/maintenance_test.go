@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"golang.org/x/time/rate"
+)
+
+// unlimitMaintenance disables maintenanceLimiter for the duration of a test.
+func unlimitMaintenance(t *testing.T) {
+	orig := maintenanceLimiter
+	maintenanceLimiter = rate.NewLimiter(rate.Inf, 1)
+	t.Cleanup(func() { maintenanceLimiter = orig })
+}
+
+func TestMaintainRepo(t *testing.T) {
+	unlimitMaintenance(t)
+	root := tmpDir(t)
+	for _, args := range [][]string{
+		{"git", "init", "."},
+		{"sh", "-c", "echo 1 > a"},
+		{"git", "add", "a"},
+		{"git", "commit", "-m", "one"},
+		{"git", "repack", "-d"},
+		{"sh", "-c", "echo 2 > a"},
+		{"git", "commit", "-am", "two"},
+		{"git", "repack", "-d"},
+	} {
+		runCmd(t, root, args[0], args[1:]...)
+	}
+	dir := GitDir(filepath.Join(root, ".git"))
+	ctx := context.Background()
+
+	if status, err := repoMaintenance(dir); err != nil || status != nil {
+		t.Fatalf("got status %+v and error %v before maintenance", status, err)
+	}
+
+	status, err := maintainRepo(ctx, dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"multi-pack-index", "commit-graph"}; !reflect.DeepEqual(status.Tasks, want) {
+		t.Errorf("got tasks %q, want %q", status.Tasks, want)
+	}
+	if status.Packs != 2 {
+		t.Errorf("got %d packs, want 2", status.Packs)
+	}
+	for _, name := range []string{"objects/pack/multi-pack-index", "objects/info/commit-graph"} {
+		if _, err := os.Stat(dir.Path(name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	got, err := repoMaintenance(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Tasks, status.Tasks) || !got.FinishedAt.Equal(status.FinishedAt) {
+		t.Errorf("got stored status %+v, want %+v", got, status)
+	}
+
+	// Maintenance doesn't run again within the interval.
+	if status, err := maintainRepo(ctx, dir, time.Hour); err != nil || status != nil {
+		t.Fatalf("got status %+v and error %v within the interval", status, err)
+	}
+
+	// Once the interval passed, nothing is due.
+	status, err = maintainRepo(ctx, dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Tasks) != 0 {
+		t.Errorf("got tasks %q, want none", status.Tasks)
+	}
+}
+
+func TestServer_maintainRepo_SkipsLockedRepo(t *testing.T) {
+	unlimitMaintenance(t)
+	root := tmpDir(t)
+	runCmd(t, root, "git", "init", "repo")
+	dir := GitDir(filepath.Join(root, "repo", ".git"))
+	s := &Server{
+		ReposDir:            root,
+		MaintenanceInterval: time.Hour,
+		ctx:                 context.Background(),
+		locker:              &RepositoryLocker{},
+		repoUpdateLocks:     make(map[api.RepoName]*locks),
+	}
+
+	lock, _ := s.locker.TryAcquire(dir, "cloning")
+	if status, err := s.maintainRepo(dir); err != nil || status != nil {
+		t.Fatalf("got status %+v and error %v for a locked repo", status, err)
+	}
+	lock.Release()
+
+	status, err := s.maintainRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == nil {
+		t.Fatal("repo wasn't maintained once unlocked")
+	}
+	if _, locked := s.locker.Status(dir); locked {
+		t.Error("repo is still locked after maintenance")
+	}
+}
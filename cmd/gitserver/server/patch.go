@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -66,7 +67,23 @@ func (s *Server) createCommitFromPatch(ctx context.Context, req protocol.CreateC
 		return http.StatusInternalServerError, resp
 	}
 
+	// The target ref is pushed to the remote of the repository, unless the
+	// request asks to push to another repository, such as a fork.
+	pushURL := remoteURL
+	if req.PushRemoteURL != "" {
+		// 🚨 SECURITY: Only push to the code host of the repository, so
+		// that the commits and the credentials of the remote URL can't be
+		// sent anywhere else.
+		if !strings.EqualFold(remoteHost(req.PushRemoteURL), remoteHost(remoteURL)) {
+			resp.SetError(repo, "", "", errors.New("gitserver: push remote URL is not on the code host of the repository"))
+			return http.StatusBadRequest, resp
+		}
+		pushURL = req.PushRemoteURL
+	}
+
 	redactor := newURLRedactor(remoteURL)
+	// 🚨 SECURITY: The push URL may contain a different token.
+	redactor.sensitive = append(redactor.sensitive, newURLRedactor(pushURL).sensitive...)
 	defer func() {
 		if resp.Error != nil {
 			resp.Error.Command = redactor.redact(resp.Error.Command)
@@ -104,7 +121,7 @@ func (s *Server) createCommitFromPatch(ctx context.Context, req protocol.CreateC
 	}
 
 	if req.UniqueRef {
-		refs, err := repoRemoteRefs(ctx, remoteURL, ref)
+		refs, err := repoRemoteRefs(ctx, pushURL, ref)
 		if err != nil {
 			log15.Error("Failed to get remote refs", "ref", ref, "err", err)
 			resp.SetError(repo, "", "", errors.Wrap(err, "repoRemoteRefs"))
@@ -151,54 +168,46 @@ func (s *Server) createCommitFromPatch(ctx context.Context, req protocol.CreateC
 		return http.StatusInternalServerError, resp
 	}
 
-	applyArgs := append([]string{"apply", "--cached"}, req.GitApplyArgs...)
-	cmd = exec.CommandContext(ctx, "git", applyArgs...)
-	cmd.Dir = tmpRepoDir
-	cmd.Env = append(os.Environ(), tmpGitPathEnv, altObjectsEnv)
-	cmd.Stdin = strings.NewReader(req.Patch)
-
-	if out, err := run(cmd, "applying patch"); err != nil {
-		log15.Error("Failed to apply patch.", "ref", ref, "output", string(out))
-		return http.StatusInternalServerError, resp
+	commits := req.Commits
+	if len(commits) == 0 {
+		commits = []protocol.PatchCommit{{Patch: req.Patch, CommitInfo: req.CommitInfo}}
 	}
 
-	message := req.CommitInfo.Message
-	if message == "" {
-		message = "<Sourcegraph> Creating commit from patch"
-	}
-	authorName := req.CommitInfo.AuthorName
-	if authorName == "" {
-		authorName = "Sourcegraph"
-	}
-	authorEmail := req.CommitInfo.AuthorEmail
-	if authorEmail == "" {
-		authorEmail = "support@sourcegraph.com"
-	}
-	committerName := req.CommitInfo.CommitterName
-	if committerName == "" {
-		committerName = authorName
-	}
-	committerEmail := req.CommitInfo.CommitterEmail
-	if committerEmail == "" {
-		committerEmail = authorEmail
+	var signArgs []string
+	if req.Sign {
+		if s.CommitSigningKey == "" {
+			resp.SetError(repo, "", "", errors.New("gitserver: commit signing is not configured"))
+			return http.StatusBadRequest, resp
+		}
+		signArgs = []string{"-c", "gpg.format=" + s.commitSigningFormat(), "-c", "user.signingkey=" + s.CommitSigningKey}
 	}
 
-	cmd = exec.CommandContext(ctx, "git", "commit", "-m", message)
-	cmd.Dir = tmpRepoDir
-	cmd.Env = append(os.Environ(), []string{
-		tmpGitPathEnv,
-		altObjectsEnv,
-		fmt.Sprintf("GIT_COMMITTER_NAME=%s", committerName),
-		fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", committerEmail),
-		fmt.Sprintf("GIT_AUTHOR_NAME=%s", authorName),
-		fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", authorEmail),
-		fmt.Sprintf("GIT_COMMITTER_DATE=%v", req.CommitInfo.Date),
-		fmt.Sprintf("GIT_AUTHOR_DATE=%v", req.CommitInfo.Date),
-	}...)
+	// Each patch is applied to the index left by the previous commit.
+	for i, commit := range commits {
+		applyArgs := append([]string{"apply", "--cached"}, req.GitApplyArgs...)
+		cmd = exec.CommandContext(ctx, "git", applyArgs...)
+		cmd.Dir = tmpRepoDir
+		cmd.Env = append(os.Environ(), tmpGitPathEnv, altObjectsEnv)
+		cmd.Stdin = strings.NewReader(commit.Patch)
 
-	if out, err := run(cmd, "committing patch"); err != nil {
-		log15.Error("Failed to commit patch.", "ref", ref, "output", out)
-		return http.StatusInternalServerError, resp
+		if out, err := run(cmd, fmt.Sprintf("applying patch %d", i+1)); err != nil {
+			log15.Error("Failed to apply patch.", "ref", ref, "patch", i+1, "output", string(out))
+			return http.StatusInternalServerError, resp
+		}
+
+		args := append(append([]string{}, signArgs...), "commit", "-m", patchCommitMessage(commit.CommitInfo))
+		if req.Sign {
+			args = append(args, "-S")
+		}
+		cmd = exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = tmpRepoDir
+		cmd.Env = append(os.Environ(), tmpGitPathEnv, altObjectsEnv)
+		cmd.Env = append(cmd.Env, patchCommitEnv(commit.CommitInfo)...)
+
+		if out, err := run(cmd, fmt.Sprintf("committing patch %d", i+1)); err != nil {
+			log15.Error("Failed to commit patch.", "ref", ref, "patch", i+1, "output", string(out))
+			return http.StatusInternalServerError, resp
+		}
 	}
 
 	cmd = exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
@@ -242,7 +251,7 @@ func (s *Server) createCommitFromPatch(ctx context.Context, req protocol.CreateC
 	}
 
 	if req.Push {
-		cmd = exec.CommandContext(ctx, "git", "push", "--force", remoteURL, fmt.Sprintf("%s:%s", cmtHash, ref))
+		cmd = exec.CommandContext(ctx, "git", "push", "--force", pushURL, fmt.Sprintf("%s:%s", cmtHash, ref))
 		cmd.Dir = repoGitDir
 
 		if out, err = run(cmd, "pushing ref"); err != nil {
@@ -264,6 +273,96 @@ func (s *Server) createCommitFromPatch(ctx context.Context, req protocol.CreateC
 	return http.StatusOK, resp
 }
 
+// patchCommitMessage returns the message of a commit created from a patch,
+// with a trailer for each co-author.
+func patchCommitMessage(info protocol.PatchCommitInfo) string {
+	message := info.Message
+	if message == "" {
+		message = "<Sourcegraph> Creating commit from patch"
+	}
+	if len(info.CoAuthors) == 0 {
+		return message
+	}
+	message = strings.TrimRight(message, "\n") + "\n\n"
+	for _, a := range info.CoAuthors {
+		message += fmt.Sprintf("Co-authored-by: %s <%s>\n", a.Name, a.Email)
+	}
+	return message
+}
+
+// patchCommitEnv returns the environment which sets the author, committer and
+// date of a commit created from a patch.
+func patchCommitEnv(info protocol.PatchCommitInfo) []string {
+	authorName := info.AuthorName
+	if authorName == "" {
+		authorName = "Sourcegraph"
+	}
+	authorEmail := info.AuthorEmail
+	if authorEmail == "" {
+		authorEmail = "support@sourcegraph.com"
+	}
+	committerName := info.CommitterName
+	if committerName == "" {
+		committerName = authorName
+	}
+	committerEmail := info.CommitterEmail
+	if committerEmail == "" {
+		committerEmail = authorEmail
+	}
+
+	return []string{
+		fmt.Sprintf("GIT_COMMITTER_NAME=%s", committerName),
+		fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", committerEmail),
+		fmt.Sprintf("GIT_AUTHOR_NAME=%s", authorName),
+		fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", authorEmail),
+		fmt.Sprintf("GIT_COMMITTER_DATE=%v", info.Date),
+		fmt.Sprintf("GIT_AUTHOR_DATE=%v", info.Date),
+	}
+}
+
+// commitSigningFormat returns the gpg.format of s.CommitSigningKey.
+func (s *Server) commitSigningFormat() string {
+	if s.CommitSigningFormat == "" {
+		return "openpgp"
+	}
+	return s.CommitSigningFormat
+}
+
+// remoteHost returns the host (and port) of the git remote URL rawurl, which
+// is empty for local paths.
+func remoteHost(rawurl string) string {
+	if strings.Contains(rawurl, "://") {
+		if u, err := url.Parse(rawurl); err == nil {
+			return u.Host
+		}
+		return ""
+	}
+	// scp-like syntax: [user@]host:path
+	if i := strings.Index(rawurl, ":"); i > 0 && !strings.Contains(rawurl[:i], "/") {
+		host := rawurl[:i]
+		return host[strings.LastIndex(host, "@")+1:]
+	}
+	return ""
+}
+
+// CheckSSHSigning returns an error if the installed git can't sign commits
+// with SSH keys, which requires git 2.34 or later.
+func CheckSSHSigning() error {
+	out, err := exec.Command("git", "version").Output()
+	if err != nil {
+		return errors.Wrap(err, "git version")
+	}
+	// git version 2.34.1
+	var major, minor int
+	if _, err := fmt.Sscanf(string(out), "git version %d.%d", &major, &minor); err != nil {
+		return errors.Wrapf(err, "parsing %q", out)
+	}
+	if major < 2 || (major == 2 && minor < 34) {
+		return errors.Errorf("signing commits with SSH keys requires git 2.34 or later, but %s is installed", strings.TrimPrefix(strings.TrimSpace(string(out)), "git version "))
+	}
+	return nil
+}
+
 func cleanUpTmpRepo(path string) {
 	err := os.RemoveAll(path)
 	if err != nil {
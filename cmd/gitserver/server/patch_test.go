@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+func TestCreateCommitFromPatch_series(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not found")
+	}
+	if err := CheckSSHSigning(); err != nil {
+		t.Skip(err)
+	}
+
+	remote := tmpDir(t)
+	for _, args := range [][]string{
+		{"git", "init", "."},
+		{"sh", "-c", "echo hello > a"},
+		{"git", "add", "a"},
+		{"git", "commit", "-m", "base"},
+	} {
+		runCmd(t, remote, args[0], args[1:]...)
+	}
+	base := strings.TrimSpace(runCmd(t, remote, "git", "rev-parse", "HEAD"))
+	fork := tmpDir(t)
+	runCmd(t, fork, "git", "init", "--bare", ".")
+
+	key := filepath.Join(tmpDir(t), "id_ed25519")
+	runCmd(t, filepath.Dir(key), "ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key)
+
+	reposDir := tmpDir(t)
+	s := &Server{
+		ReposDir:            reposDir,
+		CommitSigningKey:    key,
+		CommitSigningFormat: "ssh",
+	}
+	runCmd(t, reposDir, "git", "clone", "--mirror", remote, filepath.Join("example.com", "foo", "bar", ".git"))
+
+	status, resp := s.createCommitFromPatch(context.Background(), protocol.CreateCommitFromPatchRequest{
+		Repo:       "example.com/foo/bar",
+		BaseCommit: api.CommitID(base),
+		TargetRef:  "campaign",
+		Commits: []protocol.PatchCommit{{
+			Patch: "diff --git a/a b/a\n--- a/a\n+++ b/a\n@@ -1 +1 @@\n-hello\n+hello world\n",
+			CommitInfo: protocol.PatchCommitInfo{
+				Message:     "first",
+				AuthorName:  "Alice",
+				AuthorEmail: "alice@example.com",
+				Date:        time.Now(),
+				CoAuthors:   []protocol.PatchCommitAuthor{{Name: "Bob", Email: "bob@example.com"}},
+			},
+		}, {
+			Patch: "diff --git a/b b/b\nnew file mode 100644\n--- /dev/null\n+++ b/b\n@@ -0,0 +1 @@\n+bye\n",
+			CommitInfo: protocol.PatchCommitInfo{
+				Message:     "second",
+				AuthorName:  "Carol",
+				AuthorEmail: "carol@example.com",
+				Date:        time.Now(),
+			},
+		}},
+		Push:          true,
+		Sign:          true,
+		PushRemoteURL: fork,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("got status %d: %+v", status, resp.Error)
+	}
+	if resp.Rev != "refs/heads/campaign" {
+		t.Errorf("got rev %q", resp.Rev)
+	}
+
+	log := runCmd(t, fork, "git", "log", "--format=%an %s%n%b", base+"..refs/heads/campaign")
+	want := "Carol second\n\nAlice first\nCo-authored-by: Bob <bob@example.com>\n"
+	if strings.TrimSpace(log) != strings.TrimSpace(want) {
+		t.Errorf("got log %q, want %q", log, want)
+	}
+	for _, rev := range []string{"refs/heads/campaign", "refs/heads/campaign~1"} {
+		if commit := runCmd(t, fork, "git", "cat-file", "commit", rev); !strings.Contains(commit, "-----BEGIN SSH SIGNATURE-----") {
+			t.Errorf("commit %s is not signed:\n%s", rev, commit)
+		}
+	}
+
+	// The pushed ref is only in the fork.
+	if out, err := exec.Command("git", "-C", remote, "rev-parse", "--verify", "refs/heads/campaign").CombinedOutput(); err == nil {
+		t.Errorf("ref was pushed to the remote of the repository: %s", out)
+	}
+}
+
+func TestCreateCommitFromPatch_pushRemoteURL(t *testing.T) {
+	remote := tmpDir(t)
+	runCmd(t, remote, "git", "init", ".")
+	runCmd(t, remote, "git", "commit", "--allow-empty", "-m", "base")
+	base := strings.TrimSpace(runCmd(t, remote, "git", "rev-parse", "HEAD"))
+
+	reposDir := tmpDir(t)
+	s := &Server{ReposDir: reposDir}
+	runCmd(t, reposDir, "git", "clone", "--mirror", remote, filepath.Join("example.com", "foo", "bar", ".git"))
+	runCmd(t, filepath.Join(reposDir, "example.com", "foo", "bar"), "git", "remote", "set-url", "origin", "https://token@github.com/foo/bar")
+
+	for _, pushURL := range []string{"https://evil.example.com/foo/bar", "git@evil.example.com:foo/bar.git", remote} {
+		status, resp := s.createCommitFromPatch(context.Background(), protocol.CreateCommitFromPatchRequest{
+			Repo:          "example.com/foo/bar",
+			BaseCommit:    api.CommitID(base),
+			TargetRef:     "campaign",
+			Push:          true,
+			PushRemoteURL: pushURL,
+		})
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: got status %d (%+v), want %d", pushURL, status, resp.Error, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestRemoteHost(t *testing.T) {
+	for rawurl, want := range map[string]string{
+		"https://token@github.com/foo/bar":   "github.com",
+		"ssh://git@example.com:2222/foo/bar": "example.com:2222",
+		"git@github.com:foo/bar.git":         "github.com",
+		"github.com:foo/bar.git":             "github.com",
+		"/data/repos/foo":                    "",
+		"./foo:bar":                          "",
+	} {
+		if got := remoteHost(rawurl); got != want {
+			t.Errorf("remoteHost(%q) = %q, want %q", rawurl, got, want)
+		}
+	}
+}
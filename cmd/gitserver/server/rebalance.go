@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+)
+
+// maxRebalancePeers is the number of gitservers following this one for a repo
+// which are asked for the repo. More than one gitserver may have been added at
+// once, in which case the repo was on a gitserver further down the list.
+const maxRebalancePeers = 3
+
+// gitserverAddrs returns the addresses of all gitservers. It is replaced in
+// tests.
+var gitserverAddrs = func() []string {
+	return conf.Get().ServiceConnections.GitServers
+}
+
+// rendezvousHashing returns true if repos are assigned to gitservers with
+// rendezvous hashing. It is replaced in tests.
+var rendezvousHashing = func() bool {
+	return conf.Get().GitserverRendezvousHashing
+}
+
+// peerCloneURL returns the URL of repo on the gitserver which had it before
+// the assignment of repos to gitservers changed, or "" if cloning from peers
+// is disabled or no other gitserver has it.
+//
+// Repos are moved lazily: a repo is only cloned from its previous gitserver
+// once it is requested from this one. The janitor of the previous gitserver
+// removes its clone once this one has it.
+func (s *Server) peerCloneURL(ctx context.Context, repo api.RepoName) string {
+	if !s.RebalanceFromPeers {
+		return ""
+	}
+
+	for _, addr := range s.peerAddrs(repo) {
+		u := "http://" + addr + "/git/" + string(repo)
+		if err := s.isCloneable(ctx, repo, u); err != nil {
+			log15.Debug("repo not on gitserver peer", "repo", repo, "peer", addr, "error", err)
+			continue
+		}
+		log15.Info("cloning repo from gitserver peer", "repo", repo, "peer", addr)
+		return u
+	}
+	return ""
+}
+
+// peerAddrs returns the addresses of the gitservers which may have had repo
+// before it was assigned to this gitserver, in the order they should be asked.
+// It returns nil if repo is not assigned to this gitserver.
+//
+// A repo which was assigned by hashing its name modulo the number of
+// gitservers is on the gitserver it was assigned to that way (see
+// gitserver.LegacyAddrForRepo). With rendezvous hashing (see
+// gitserver.AddrsForRepo), a repo which moved to this gitserver after
+// gitservers were added is on one of the gitservers following it for the repo.
+func (s *Server) peerAddrs(repo api.RepoName) []string {
+	all := gitserverAddrs()
+	if len(all) < 2 || !hostnameMatches(gitserver.AddrsForRepo(all, repo, rendezvousHashing())[0], s.Hostname) {
+		// The repo is not assigned to us, so we can't tell where it was
+		// before.
+		return nil
+	}
+
+	var peers []string
+	if legacy := gitserver.LegacyAddrForRepo(all, repo); !hostnameMatches(legacy, s.Hostname) {
+		peers = append(peers, legacy)
+	}
+	followers := 0
+	for _, addr := range gitserver.AddrsForRepo(all, repo, true) {
+		if followers == maxRebalancePeers {
+			break
+		}
+		if hostnameMatches(addr, s.Hostname) {
+			continue
+		}
+		followers++
+		if len(peers) == 0 || addr != peers[0] {
+			peers = append(peers, addr)
+		}
+	}
+	return peers
+}
+
+// assignedOwner returns the address of the gitserver repo is assigned to, or
+// "" if repo is assigned to this gitserver, either as its first gitserver or
+// as one of its replicas. Repos are also considered assigned to this gitserver
+// if it can't find its own address among the gitservers.
+func (s *Server) assignedOwner(repo api.RepoName) string {
+	all := gitserverAddrs()
+	self := false
+	for _, addr := range all {
+		if hostnameMatches(addr, s.Hostname) {
+			self = true
+			break
+		}
+	}
+	if !self || len(all) < 2 {
+		return ""
+	}
+
+	addrs := gitserver.ReplicaAddrs(all, repo, replicationFactor(), rendezvousHashing())
+	for _, addr := range addrs {
+		if hostnameMatches(addr, s.Hostname) {
+			return ""
+		}
+	}
+	return addrs[0]
+}
+
+// hostnameMatches returns true if addr is the address of hostname. The
+// hostname can be less qualified than the address. For example in k8s
+// $HOSTNAME will be "gitserver-0", while the address is
+// "gitserver-0.gitserver:3178".
+func hostnameMatches(addr, hostname string) bool {
+	if hostname == "" || !strings.HasPrefix(addr, hostname) {
+		return false
+	}
+	if len(hostname) == len(addr) {
+		return true
+	}
+	c := addr[len(hostname)]
+	return c == '.' || c == ':'
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/mutablelimiter"
+)
+
+func TestCloneRepoFromPeer(t *testing.T) {
+	remote := tmpDir(t)
+	runCmd(t, remote, "git", "init", ".")
+	runCmd(t, remote, "sh", "-c", "echo hello world > hello.txt")
+	runCmd(t, remote, "git", "add", "hello.txt")
+	runCmd(t, remote, "git", "commit", "-m", "hello")
+	wantCommit := runCmd(t, remote, "git", "rev-parse", "HEAD")
+
+	newServer := func(hostname string) *Server {
+		return &Server{
+			ReposDir:           tmpDir(t),
+			Hostname:           hostname,
+			RebalanceFromPeers: true,
+			ctx:                context.Background(),
+			locker:             &RepositoryLocker{},
+			cloneLimiter:       mutablelimiter.New(1),
+			cloneableLimiter:   mutablelimiter.New(1),
+		}
+	}
+
+	peer := newServer("")
+	srv := httptest.NewServer(peer.Handler())
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	addrs := []string{"gitserver-0.gitserver:3178", u.Host}
+	origAddrs, origRendezvous := gitserverAddrs, rendezvousHashing
+	gitserverAddrs = func() []string { return addrs }
+	rendezvousHashing = func() bool { return true }
+	defer func() { gitserverAddrs, rendezvousHashing = origAddrs, origRendezvous }()
+
+	// Find a repo which moves from the peer to gitserver-0.
+	var repo api.RepoName
+	for i := 0; repo == ""; i++ {
+		name := api.RepoName(fmt.Sprintf("example.com/foo/bar-%d", i))
+		if gitserver.AddrsForRepo(addrs, name, true)[0] == addrs[0] {
+			repo = name
+		}
+	}
+
+	ctx := context.Background()
+	if _, err := peer.cloneRepo(ctx, repo, remote, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The code host URL is not cloneable, so the clone must come from the
+	// peer.
+	codeHost := filepath.Join(tmpDir(t), "missing")
+	s := newServer("gitserver-0")
+	if _, err := s.cloneRepo(ctx, repo, codeHost, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Dir(string(s.dir(repo)))
+	if got := runCmd(t, dir, "git", "rev-parse", "HEAD"); got != wantCommit {
+		t.Fatalf("got commit %s, want %s", got, wantCommit)
+	}
+	if got := strings.TrimSpace(runCmd(t, dir, "git", "config", "remote.origin.url")); got != codeHost {
+		t.Fatalf("got remote URL %q, want the code host URL %q", got, codeHost)
+	}
+
+	// Without rebalancing the clone fails, since the code host URL is not
+	// cloneable.
+	s = newServer("gitserver-0")
+	s.RebalanceFromPeers = false
+	if _, err := s.cloneRepo(ctx, repo, codeHost, &cloneOptions{Block: true}); err == nil {
+		t.Fatal("expected clone from the code host to fail")
+	}
+}
+
+func TestPeerAddrs(t *testing.T) {
+	var addrs []string
+	for i := 0; i < 8; i++ {
+		addrs = append(addrs, fmt.Sprintf("gitserver-%d.gitserver:3178", i))
+	}
+	origAddrs, origRendezvous := gitserverAddrs, rendezvousHashing
+	gitserverAddrs = func() []string { return addrs }
+	rendezvous := true
+	rendezvousHashing = func() bool { return rendezvous }
+	defer func() { gitserverAddrs, rendezvousHashing = origAddrs, origRendezvous }()
+
+	// Find a repo whose legacy gitserver is not among the gitservers following
+	// its current one.
+	var repo api.RepoName
+	var ranked []string
+	var legacy string
+	for i := 0; repo == ""; i++ {
+		name := api.RepoName(fmt.Sprintf("example.com/foo/bar-%d", i))
+		ranked = gitserver.AddrsForRepo(addrs, name, true)
+		legacy = gitserver.LegacyAddrForRepo(addrs, name)
+		if legacy != ranked[0] && legacy != ranked[1] && legacy != ranked[2] && legacy != ranked[3] {
+			repo = name
+		}
+	}
+
+	s := &Server{Hostname: strings.Split(ranked[0], ".")[0]}
+	want := append([]string{legacy}, ranked[1:1+maxRebalancePeers]...)
+	if diff := cmp.Diff(want, s.peerAddrs(repo)); diff != "" {
+		t.Errorf("peers (-want +got):\n%s", diff)
+	}
+
+	// Other gitservers don't look for the repo.
+	s = &Server{Hostname: strings.Split(ranked[1], ".")[0]}
+	if peers := s.peerAddrs(repo); peers != nil {
+		t.Errorf("got peers %v for a repo of another gitserver", peers)
+	}
+
+	// Without rendezvous hashing the repo is assigned to its legacy
+	// gitserver, which looks for it on the gitservers it moves to with
+	// rendezvous hashing, in case rendezvous hashing was switched off again.
+	rendezvous = false
+	s = &Server{Hostname: strings.Split(legacy, ".")[0]}
+	if diff := cmp.Diff(ranked[:maxRebalancePeers], s.peerAddrs(repo)); diff != "" {
+		t.Errorf("legacy peers (-want +got):\n%s", diff)
+	}
+}
+
+func TestAssignedOwner(t *testing.T) {
+	addrs := []string{"gitserver-0.gitserver:3178", "gitserver-1.gitserver:3178", "gitserver-2.gitserver:3178"}
+	origAddrs, origRendezvous, origFactor := gitserverAddrs, rendezvousHashing, replicationFactor
+	gitserverAddrs = func() []string { return addrs }
+	rendezvousHashing = func() bool { return true }
+	factor := 1
+	replicationFactor = func() int { return factor }
+	defer func() { gitserverAddrs, rendezvousHashing, replicationFactor = origAddrs, origRendezvous, origFactor }()
+
+	repo := api.RepoName("example.com/foo/bar")
+	ranked := gitserver.AddrsForRepo(addrs, repo, true)
+	hostname := func(addr string) string { return strings.Split(addr, ".")[0] }
+
+	for _, tc := range []struct {
+		hostname string
+		factor   int
+		want     string
+	}{
+		{hostname: hostname(ranked[0]), factor: 1, want: ""},
+		{hostname: hostname(ranked[1]), factor: 1, want: ranked[0]},
+		{hostname: hostname(ranked[1]), factor: 2, want: ""},
+		{hostname: hostname(ranked[2]), factor: 2, want: ranked[0]},
+		// A gitserver which can't find its own address keeps all repos.
+		{hostname: "gitserver-9", factor: 1, want: ""},
+		{hostname: "", factor: 1, want: ""},
+	} {
+		factor = tc.factor
+		s := &Server{Hostname: tc.hostname}
+		if got := s.assignedOwner(repo); got != tc.want {
+			t.Errorf("%s with replication factor %d: got owner %q, want %q", tc.hostname, tc.factor, got, tc.want)
+		}
+	}
+}
+
+func TestHostnameMatches(t *testing.T) {
+	for _, tc := range []struct {
+		addr, hostname string
+		want           bool
+	}{
+		{"gitserver-0", "gitserver-0", true},
+		{"gitserver-0:3178", "gitserver-0", true},
+		{"gitserver-0.gitserver:3178", "gitserver-0", true},
+		{"gitserver-0.gitserver:3178", "gitserver-0.gitserver:3178", true},
+		{"gitserver-10:3178", "gitserver-1", false},
+		{"gitserver-0:3178", "", false},
+	} {
+		if got := hostnameMatches(tc.addr, tc.hostname); got != tc.want {
+			t.Errorf("hostnameMatches(%q, %q) = %v, want %v", tc.addr, tc.hostname, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+// replicationFactor returns the number of gitservers each repository is stored
+// on. It is replaced in tests.
+var replicationFactor = func() int {
+	return conf.Get().GitserverReplicationFactor
+}
+
+var (
+	replicateSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "src_gitserver_replicate_sent_total",
+		Help: "number of replication requests sent to other gitservers.",
+	}, []string{"status"})
+	replicateReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "src_gitserver_replicate_received_total",
+		Help: "number of replication requests handled for other gitservers.",
+	}, []string{"status"})
+)
+
+// replicaAddrs returns the address of this gitserver and of the gitservers repo
+// is replicated to from it. If this gitserver is not the first gitserver of
+// repo, it doesn't replicate repo and no replicas are returned.
+func (s *Server) replicaAddrs(repo api.RepoName) (self string, replicas []string) {
+	addrs := gitserver.ReplicaAddrs(gitserverAddrs(), repo, replicationFactor(), rendezvousHashing())
+	if len(addrs) < 2 || !hostnameMatches(addrs[0], s.Hostname) {
+		return "", nil
+	}
+	return addrs[0], addrs[1:]
+}
+
+// replicate asks the replicas of repo to fetch it from this gitserver, if this
+// gitserver is the first gitserver of repo. It does not block. Replication is
+// best-effort: a replica which misses an update catches up with the next one.
+func (s *Server) replicate(repo api.RepoName, url string) {
+	self, replicas := s.replicaAddrs(repo)
+	if len(replicas) == 0 {
+		return
+	}
+
+	if url == "" {
+		var err error
+		url, err = repoRemoteURL(context.Background(), s.dir(repo))
+		if err != nil || url == "" {
+			log15.Warn("Failed to determine Git remote URL for replication", "repo", repo, "error", err)
+			return
+		}
+	}
+
+	req := &protocol.ReplicateRequest{Repo: repo, URL: url, Source: self}
+	for _, addr := range replicas {
+		go func(addr string) {
+			ctx, cancel := s.serverContext()
+			defer cancel()
+			if err := sendReplicate(ctx, addr, req); err != nil {
+				log15.Warn("failed to replicate repo", "repo", repo, "replica", addr, "error", err)
+				replicateSent.WithLabelValues("error").Inc()
+				return
+			}
+			replicateSent.WithLabelValues("success").Inc()
+		}(addr)
+	}
+}
+
+func sendReplicate(ctx context.Context, addr string, req *protocol.ReplicateRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequest("POST", "http://"+addr+"/replicate", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(r.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// best-effort inclusion of body in error message
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("replicate: http status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// isReplicaOf reports whether source is the first gitserver of repo and this
+// gitserver is one of its replicas.
+func (s *Server) isReplicaOf(repo api.RepoName, source string) bool {
+	addrs := gitserver.ReplicaAddrs(gitserverAddrs(), repo, replicationFactor(), rendezvousHashing())
+	if len(addrs) < 2 || addrs[0] != source {
+		return false
+	}
+	for _, addr := range addrs[1:] {
+		if hostnameMatches(addr, s.Hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleReplicate updates the replica of a repository from the gitserver in
+// the request, cloning it from there if it doesn't exist yet.
+func (s *Server) handleReplicate(w http.ResponseWriter, r *http.Request) {
+	var req protocol.ReplicateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Repo = protocol.NormalizeRepo(req.Repo)
+
+	// Only the first gitserver of the repository may replicate it, and only to
+	// its replicas. Otherwise anyone who can reach gitserver could point a
+	// replica at an arbitrary source.
+	if !s.isReplicaOf(req.Repo, req.Source) {
+		replicateReceived.WithLabelValues("rejected").Inc()
+		http.Error(w, fmt.Sprintf("%s is not the source of replica %s on %s", req.Source, req.Repo, s.Hostname), http.StatusForbidden)
+		return
+	}
+
+	// Like repo updates, we don't want to cancel the git commands partway
+	// through if the request terminates.
+	ctx, cancel1 := s.serverContext()
+	defer cancel1()
+	ctx, cancel2 := context.WithTimeout(ctx, longGitCommandTimeout)
+	defer cancel2()
+
+	source := "http://" + req.Source + "/git/" + string(req.Repo)
+	var err error
+	if !repoCloned(s.dir(req.Repo)) {
+		_, err = s.cloneRepo(ctx, req.Repo, req.URL, &cloneOptions{Block: true, CloneFrom: source})
+	} else {
+		err = s.fetchReplica(ctx, req.Repo, source)
+	}
+	if err != nil {
+		log15.Error("failed to replicate repo", "repo", req.Repo, "source", req.Source, "error", err)
+		replicateReceived.WithLabelValues("error").Inc()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	replicateReceived.WithLabelValues("success").Inc()
+}
+
+// fetchReplica updates all refs of the clone of repo to those of the clone at
+// source.
+func (s *Server) fetchReplica(ctx context.Context, repo api.RepoName, source string) error {
+	ctx, cancel, err := s.acquireCloneLimiter(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	dir := s.dir(repo)
+	defer s.cleanTmpFiles(dir)
+
+	cmd := exec.CommandContext(ctx, "git", "fetch", "--prune", source, "+refs/*:refs/*")
+	dir.Set(cmd)
+	if output, err := runWith(ctx, cmd, false, nil); err != nil {
+		return errors.Wrapf(err, "failed to fetch replica. Output: %s", string(output))
+	}
+
+	// Update the last-changed stamp.
+	if err := setLastChanged(dir); err != nil {
+		log15.Warn("Failed to update last changed time", "repo", repo, "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+)
+
+func TestReplicate(t *testing.T) {
+	remote := tmpDir(t)
+	runCmd(t, remote, "git", "init", ".")
+	runCmd(t, remote, "sh", "-c", "echo hello world > hello.txt")
+	runCmd(t, remote, "git", "add", "hello.txt")
+	runCmd(t, remote, "git", "commit", "-m", "hello")
+
+	newServer := func() (*Server, string) {
+		s := &Server{ReposDir: tmpDir(t)}
+		srv := httptest.NewServer(s.Handler())
+		t.Cleanup(srv.Close)
+		u, _ := url.Parse(srv.URL)
+		// Both servers listen on the same IP, so the hostname must be the
+		// whole address.
+		s.Hostname = u.Host
+		return s, u.Host
+	}
+	primary, primaryAddr := newServer()
+	replica, replicaAddr := newServer()
+
+	addrs := []string{primaryAddr, replicaAddr}
+	origAddrs, origFactor := gitserverAddrs, replicationFactor
+	gitserverAddrs = func() []string { return addrs }
+	replicationFactor = func() int { return 2 }
+	defer func() { gitserverAddrs, replicationFactor = origAddrs, origFactor }()
+
+	// Find a repo which the primary fetches from the code host.
+	var repo api.RepoName
+	for i := 0; repo == ""; i++ {
+		name := api.RepoName(fmt.Sprintf("example.com/foo/bar-%d", i))
+		if gitserver.AddrsForRepo(addrs, name, false)[0] == primaryAddr {
+			repo = name
+		}
+	}
+
+	replicaDir := filepath.Dir(string(replica.dir(repo)))
+	waitForReplica := func(want string) {
+		t.Helper()
+		var got string
+		for i := 0; i < 500; i++ {
+			if repoCloned(replica.dir(repo)) {
+				if got = runCmd(t, replicaDir, "git", "rev-parse", "HEAD"); got == want {
+					return
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("replica is at commit %q, want %q", got, want)
+	}
+
+	ctx := context.Background()
+	if _, err := primary.cloneRepo(ctx, repo, remote, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+	waitForReplica(runCmd(t, remote, "git", "rev-parse", "HEAD"))
+
+	// The replica fetches from the code host if it is used directly.
+	if got := strings.TrimSpace(runCmd(t, replicaDir, "git", "config", "remote.origin.url")); got != remote {
+		t.Fatalf("got remote URL %q, want the code host URL %q", got, remote)
+	}
+
+	// Updates of the primary are replicated.
+	runCmd(t, remote, "sh", "-c", "echo hello again > hello.txt")
+	runCmd(t, remote, "git", "commit", "-am", "hello again")
+	if err := primary.doRepoUpdate(ctx, repo, remote); err != nil {
+		t.Fatal(err)
+	}
+	waitForReplica(runCmd(t, remote, "git", "rev-parse", "HEAD"))
+
+	// The replica doesn't replicate further.
+	if self, replicas := replica.replicaAddrs(repo); self != "" || len(replicas) != 0 {
+		t.Fatalf("replica replicates to %v", replicas)
+	}
+}
+
+func TestHandleReplicate_RejectsUnknownSource(t *testing.T) {
+	addrs := []string{"gitserver-0:3178", "gitserver-1:3178", "gitserver-2:3178"}
+	origAddrs, origFactor := gitserverAddrs, replicationFactor
+	gitserverAddrs = func() []string { return addrs }
+	replicationFactor = func() int { return 2 }
+	defer func() { gitserverAddrs, replicationFactor = origAddrs, origFactor }()
+
+	repo := api.RepoName("example.com/foo/bar")
+	ranked := gitserver.ReplicaAddrs(addrs, repo, 2, false)
+	primary, replica := ranked[0], ranked[1]
+	var other string
+	for _, addr := range addrs {
+		if addr != primary && addr != replica {
+			other = addr
+		}
+	}
+
+	tests := []struct {
+		name     string
+		hostname string
+		source   string
+	}{
+		{name: "source is not the primary", hostname: replica, source: other},
+		{name: "receiver is not a replica", hostname: other, source: primary},
+		{name: "receiver is the primary", hostname: primary, source: primary},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &Server{ReposDir: tmpDir(t), Hostname: test.hostname}
+			body := fmt.Sprintf(`{"repo": %q, "url": "https://example.com/foo/bar", "source": %q}`, repo, test.source)
+			w := httptest.NewRecorder()
+			s.handleReplicate(w, httptest.NewRequest("POST", "/replicate", strings.NewReader(body)))
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+			}
+			if repoCloned(s.dir(repo)) {
+				t.Fatal("repo was cloned")
+			}
+		})
+	}
+}
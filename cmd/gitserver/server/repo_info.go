@@ -47,6 +47,21 @@ func (s *Server) repoInfo(ctx context.Context, repo api.RepoName) (*protocol.Rep
 		} else {
 			resp.LastChanged = &lastChanged
 		}
+
+		if rs, ok := s.repoSizes.get(s.ReposDir, repo); ok {
+			resp.Size = rs.Size
+		}
+
+		if maintenance, err := repoMaintenance(dir); err != nil {
+			log15.Warn("error getting maintenance status", "repo", repo, "err", err)
+		} else {
+			resp.Maintenance = maintenance
+		}
+	}
+	if history, err := s.syncHistory.list(protocol.NormalizeRepo(repo), dir); err != nil {
+		log15.Warn("error getting sync history", "repo", repo, "err", err)
+	} else {
+		resp.SyncHistory = history
 	}
 	return &resp, nil
 }
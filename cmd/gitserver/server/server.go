@@ -117,6 +117,43 @@ type Server struct {
 	// DiskSizer tells how much disk is free and how large the disk is.
 	DiskSizer DiskSizer
 
+	// Hostname is the hostname of this gitserver. It identifies this
+	// gitserver in the list of gitserver addresses.
+	Hostname string
+
+	// RebalanceFromPeers when true moves repositories lazily: a repository
+	// which was reassigned to this gitserver is cloned from the gitserver
+	// which had it before, rather than from the code host, when it is first
+	// requested.
+	RebalanceFromPeers bool
+
+	// MaintenanceInterval is the minimum interval between checks whether a
+	// repository needs maintenance, such as git repack. Maintenance is
+	// disabled if it is 0.
+	MaintenanceInterval time.Duration
+
+	// Backups when non-nil stores git bundles of repositories backed up with
+	// the /backup endpoint.
+	Backups BundleStore
+
+	// RestoreFromBackup when true clones repositories which have a backup in
+	// Backups from it rather than from the code host.
+	RestoreFromBackup bool
+
+	// CommitSigningKey is the key which signs commits created from patches
+	// if requested. It is a GPG key ID, or the path of an SSH private key if
+	// CommitSigningFormat is "ssh". Signing is disabled if it is empty.
+	CommitSigningKey string
+
+	// CommitSigningFormat is the format of CommitSigningKey, either
+	// "openpgp" (the default) or "ssh".
+	CommitSigningFormat string
+
+	// ServiceTokens maps the names of services to the tokens they
+	// authenticate exec requests with, which gitserverExecAllowlist applies
+	// to. Requests without a valid token are subject to the "*" entry.
+	ServiceTokens map[string]string
+
 	// skipCloneForTests is set by tests to avoid clones.
 	skipCloneForTests bool
 
@@ -139,6 +176,21 @@ type Server struct {
 
 	repoUpdateLocksMu sync.Mutex // protects the map below and also updates to locks.once
 	repoUpdateLocks   map[api.RepoName]*locks
+
+	// execAudit records the most recent exec requests.
+	execAudit execAuditLog
+
+	// backingUpAll is 1 while all repositories are backed up in the
+	// background. It is accessed atomically.
+	backingUpAll int32
+
+	// syncHistory records the most recent clones and fetches of each
+	// repository.
+	syncHistory syncHistory
+
+	// repoSizes is the index of the sizes of the repositories on disk,
+	// which quotas are enforced with.
+	repoSizes repoSizes
 }
 
 type locks struct {
@@ -226,6 +278,7 @@ func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/archive", s.handleArchive)
 	mux.HandleFunc("/exec", s.handleExec)
+	mux.HandleFunc("/exec-audit", s.handleExecAudit)
 	mux.HandleFunc("/list", s.handleList)
 	mux.HandleFunc("/list-gitolite", s.handleListGitolite)
 	mux.HandleFunc("/is-repo-cloneable", s.handleIsRepoCloneable)
@@ -234,6 +287,9 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/repo-clone-progress", s.handleRepoCloneProgress)
 	mux.HandleFunc("/delete", s.handleRepoDelete)
 	mux.HandleFunc("/repo-update", s.handleRepoUpdate)
+	mux.HandleFunc("/replicate", s.handleReplicate)
+	mux.HandleFunc("/backup", s.handleBackup)
+	mux.HandleFunc("/restore", s.handleRestore)
 	mux.HandleFunc("/getGitolitePhabricatorMetadata", s.handleGetGitolitePhabricatorMetadata)
 	mux.HandleFunc("/create-commit-from-patch", s.handleCreateCommitFromPatch)
 	mux.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) {
@@ -357,7 +413,7 @@ func (s *Server) handleIsRepoCloneable(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var resp protocol.IsRepoCloneableResponse
-	if err := s.isCloneable(r.Context(), req.URL); err == nil {
+	if err := s.isCloneable(r.Context(), req.Repo, req.URL); err == nil {
 		resp = protocol.IsRepoCloneableResponse{Cloneable: true}
 	} else {
 		resp = protocol.IsRepoCloneableResponse{
@@ -500,6 +556,33 @@ func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
 		req.Args = append(req.Args, "-0")
 	}
 
+	// 🚨 SECURITY: Check the allowlist before prefetching file contents, and
+	// audit denied requests, which exec never sees.
+	if service := s.execService(r); !execAllowed(service, req.Args) {
+		s.auditExec(protocol.ExecAuditRecord{
+			Time:       time.Now(),
+			Actor:      r.Header.Get("X-Sourcegraph-Actor"),
+			Service:    service,
+			Repo:       protocol.NormalizeRepo(req.Repo),
+			Args:       append(req.Args, treeish),
+			ExitStatus: -1,
+			Status:     "denied",
+		})
+		denyExec(w, service)
+		return
+	}
+
+	// Archives of partial clones would fetch missing file contents one at a
+	// time, so we fetch them upfront.
+	if strategy := cloneStrategyFor(req.Repo); strategy.partial() {
+		paths = strategy.archivePaths(paths)
+		if dir := s.dir(req.Repo); repoCloned(dir) {
+			if err := prefetchBlobs(r.Context(), dir, treeish, paths); err != nil {
+				log15.Warn("failed to prefetch file contents for archive", "repo", repo, "treeish", treeish, "error", err)
+			}
+		}
+	}
+
 	req.Args = append(req.Args, treeish, "--")
 	req.Args = append(req.Args, paths...)
 
@@ -535,6 +618,7 @@ func (s *Server) exec(w http.ResponseWriter, r *http.Request, req *protocol.Exec
 	var ensureRevisionStatus string
 
 	req.Repo = protocol.NormalizeRepo(req.Repo)
+	service := s.execService(r)
 
 	// Instrumentation
 	{
@@ -568,6 +652,24 @@ func (s *Server) exec(w http.ResponseWriter, r *http.Request, req *protocol.Exec
 			execRunning.WithLabelValues(cmd, repo).Dec()
 			execDuration.WithLabelValues(cmd, repo, status).Observe(duration.Seconds())
 
+			// 🚨 SECURITY: The audit log records which users read which
+			// repositories' contents.
+			auditExitStatus := exitStatus
+			if auditExitStatus == -10810 {
+				auditExitStatus = -1
+			}
+			s.auditExec(protocol.ExecAuditRecord{
+				Time:        start,
+				Actor:       r.Header.Get("X-Sourcegraph-Actor"),
+				Service:     service,
+				Repo:        req.Repo,
+				Args:        req.Args,
+				Duration:    duration,
+				ExitStatus:  auditExitStatus,
+				StdoutBytes: stdoutN,
+				Status:      status,
+			})
+
 			var cmdDuration time.Duration
 			var fetchDuration time.Duration
 			if !cmdStart.IsZero() {
@@ -624,6 +726,12 @@ func (s *Server) exec(w http.ResponseWriter, r *http.Request, req *protocol.Exec
 		}()
 	}
 
+	if !execAllowed(service, req.Args) {
+		status = "denied"
+		denyExec(w, service)
+		return
+	}
+
 	dir := s.dir(req.Repo)
 	if !repoCloned(dir) {
 		cloneProgress, cloneInProgress := s.locker.Status(dir)
@@ -681,6 +789,7 @@ func (s *Server) exec(w http.ResponseWriter, r *http.Request, req *protocol.Exec
 			w.Header().Set("X-Exec-Error", "")
 			w.Header().Set("X-Exec-Exit-Status", "0")
 			w.Header().Set("X-Exec-Stderr", "")
+			exitStatus, status, stdoutN = 0, "0", int64(len(resolved))
 			return
 		}
 	}
@@ -693,6 +802,7 @@ func (s *Server) exec(w http.ResponseWriter, r *http.Request, req *protocol.Exec
 			w.Header().Set("X-Exec-Error", "")
 			w.Header().Set("X-Exec-Exit-Status", "0")
 			w.Header().Set("X-Exec-Stderr", "")
+			exitStatus, status, stdoutN = 0, "0", int64(len(resolved))
 			return
 		}
 	}
@@ -754,6 +864,15 @@ type cloneOptions struct {
 
 	// Overwrite will overwrite the existing clone.
 	Overwrite bool
+
+	// CloneFrom is the URL to clone from instead of the code host, such as
+	// the URL of the repository on another gitserver. The remote origin of
+	// the clone is still the code host.
+	CloneFrom string
+
+	// FromBackup clones from the backup of the repository in
+	// Server.Backups, even if Server.RestoreFromBackup is false.
+	FromBackup bool
 }
 
 // cloneRepo issues a git clone command for the given repo. It is
@@ -782,8 +901,23 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		return "", err // err will be a context error
 	}
 	defer cancel()
-	if err := s.isCloneable(ctx, url); err != nil {
-		return "", fmt.Errorf("error cloning repo: repo %s not cloneable: %s", repo, redactor.redact(err.Error()))
+	// If another gitserver had the repo before the gitserver addresses
+	// changed, we clone from it to spare the code host.
+	var peerURL string
+	if opts != nil && opts.CloneFrom != "" {
+		peerURL = opts.CloneFrom
+	} else {
+		peerURL = s.peerCloneURL(ctx, repo)
+	}
+	// During disaster recovery we restore repositories from their backups,
+	// which also spares the code host.
+	fromBackup := peerURL == "" && ((opts != nil && opts.FromBackup) || s.hasBackup(ctx, repo))
+	if peerURL == "" && !fromBackup {
+		start := time.Now()
+		if err := s.isCloneable(ctx, repo, url); err != nil {
+			s.recordSync(repo, "clone", start, nil, err, redactor)
+			return "", fmt.Errorf("error cloning repo: repo %s not cloneable: %s", repo, redactor.redact(err.Error()))
+		}
 	}
 
 	// Mark this repo as currently being cloned. We have to check again if someone else isn't already
@@ -804,7 +938,7 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 	// We clone to a temporary location first to avoid having incomplete
 	// clones in the repo tree. This also avoids leaving behind corrupt clones
 	// if the clone is interrupted.
-	doClone := func(ctx context.Context) error {
+	doClone := func(ctx context.Context) (err error) {
 		defer lock.Release()
 
 		ctx, cancel1, err := s.acquireCloneLimiter(ctx)
@@ -829,6 +963,12 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 			}
 		}
 
+		start := time.Now()
+		var output []byte
+		defer func() {
+			s.recordSync(repo, "clone", start, output, err, redactor)
+		}()
+
 		tmpPath, err := s.tempDir("clone-")
 		if err != nil {
 			return err
@@ -837,25 +977,111 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		tmpPath = filepath.Join(tmpPath, ".git")
 		tmp := GitDir(tmpPath)
 
-		var cmd *exec.Cmd
-		if useRefspecOverrides() {
-			cmd, err = refspecOverridesCloneCmd(ctx, url, tmpPath)
+		shallow, err := s.checkRepoQuota(ctx, repo, url)
+		if err != nil {
+			return err
+		}
+
+		cloneURL := url
+		if peerURL != "" {
+			cloneURL = peerURL
+		} else if fromBackup && !shallow {
+			// Bundles can't be shallow cloned, so repositories which
+			// exceed their quota are cloned from the code host instead.
+			cloneURL, err = s.downloadBundle(ctx, repo, filepath.Dir(tmpPath))
 			if err != nil {
 				return err
 			}
-		} else {
-			cmd = exec.CommandContext(ctx, "git", "clone", "--mirror", "--progress", url, tmpPath)
 		}
-		// see issue #7322: skip LFS content in repositories with Git LFS configured
-		cmd.Env = append(os.Environ(), "GIT_LFS_SKIP_SMUDGE=1")
-		log15.Info("cloning repo", "repo", repo, "tmp", tmpPath, "dst", dstPath)
 
-		pr, pw := io.Pipe()
-		defer pw.Close()
-		go readCloneProgress(redactor, lock, pr)
+		// Peers and backups are git repositories, whatever the code host.
+		syncer := vcsSyncerFor(repo, cloneURL)
+		// canceledAt is the size at which a full clone was canceled
+		// because it exceeded the maximum repository size of its quota.
+		var canceledAt int64
+		runClone := func(syncer VCSSyncer) error {
+			if syncer == nil {
+				return &errRepoQuotaExceeded{quota: repoQuotaFor(url).url, reason: "only git repositories can be shallow cloned"}
+			}
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			cmd, err := syncer.CloneCommand(ctx, cloneURL, tmpPath)
+			if err != nil {
+				return err
+			}
+			// see issue #7322: skip LFS content in repositories with Git LFS configured
+			if cmd.Env == nil {
+				cmd.Env = os.Environ()
+			}
+			cmd.Env = append(cmd.Env, "GIT_LFS_SKIP_SMUDGE=1")
+			log15.Info("cloning repo", "repo", repo, "tmp", tmpPath, "dst", dstPath, "shallow", shallow)
+
+			// An oversized repository must not fill the disk before its
+			// size is checked, so a full clone is canceled once it
+			// exceeds the maximum size of its quota.
+			if q := repoQuotaFor(url); !shallow && q != nil && q.maxRepoSize > 0 {
+				stop := watchCloneSize(tmpPath, q.maxRepoSize, cancel)
+				defer func() { canceledAt = stop() }()
+			}
+
+			pr, pw := io.Pipe()
+			defer pw.Close()
+			go readCloneProgress(redactor, lock, pr)
 
-		if output, err := runWithRemoteOpts(ctx, cmd, pw); err != nil {
-			return errors.Wrapf(err, "clone failed. Output: %s", string(output))
+			if output, err = runWithRemoteOpts(ctx, cmd, pw); err != nil {
+				return errors.Wrapf(err, "clone failed. Output: %s", string(output))
+			}
+			return nil
+		}
+		if shallow {
+			syncer = shallowSyncer(syncer)
+		}
+		if err := runClone(syncer); err != nil && canceledAt == 0 {
+			return err
+		}
+		if !shallow {
+			// Full clones can exceed the size of their quota between the
+			// checks of the size during the clone.
+			shallow, err = s.checkClonedRepoQuota(repo, url, tmp, canceledAt)
+			if err != nil {
+				return err
+			}
+			if shallow {
+				if err := os.RemoveAll(tmpPath); err != nil {
+					return err
+				}
+				if cloneURL != url && peerURL == "" {
+					// Bundles can't be shallow cloned.
+					cloneURL = url
+					syncer = vcsSyncerFor(repo, url)
+				}
+				if err := runClone(shallowSyncer(syncer)); err != nil {
+					return err
+				}
+			}
+		}
+		if cloneURL != url {
+			// Future fetches use the code host, like those of a clone
+			// from it.
+			cmd := exec.CommandContext(ctx, "git", "remote", "set-url", "origin", "--", storedRemoteURL(url))
+			tmp.Set(cmd)
+			if output, err := runWith(ctx, cmd, false, nil); err != nil {
+				return errors.Wrapf(err, "failed to set remote URL. Output: %s", redactor.redact(string(output)))
+			}
+			if peerURL != "" {
+				repoRebalancedCounter.Inc()
+			} else {
+				repoRestoredCounter.Inc()
+				s.fetchRestoredRepo(ctx, repo, url, tmp, redactor)
+			}
+		} else if current, _ := repoRemoteURL(ctx, tmp); current == "" {
+			// Imports from other version control systems, such as git p4,
+			// don't add the remote which fetches fall back to.
+			cmd := exec.CommandContext(ctx, "git", "remote", "add", "origin", "--", storedRemoteURL(url))
+			tmp.Set(cmd)
+			if output, err := runWith(ctx, cmd, false, nil); err != nil {
+				return errors.Wrapf(err, "failed to add remote URL. Output: %s", redactor.redact(string(output)))
+			}
 		}
 
 		removeBadRefs(ctx, tmp)
@@ -871,6 +1097,11 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		}
 
 		if overwrite {
+			// Keep the sync history of the current repo.
+			if err := os.Rename(dir.Path(syncHistoryFile), tmp.Path(syncHistoryFile)); err != nil && !os.IsNotExist(err) {
+				log15.Warn("failed to keep sync history", "repo", repo, "error", err)
+			}
+
 			// remove the current repo by putting it into our temporary directory
 			err := renameAndSync(dstPath, filepath.Join(filepath.Dir(tmpPath), "old"))
 			if err != nil && !os.IsNotExist(err) {
@@ -888,6 +1119,12 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		log15.Info("repo cloned", "repo", repo)
 		repoClonedCounter.Inc()
 
+		if _, err := s.updateRepoSize(repo, dir, url); err != nil {
+			log15.Warn("failed to update repo size", "repo", repo, "error", err)
+		}
+
+		s.replicate(repo, url)
+
 		return nil
 	}
 
@@ -946,7 +1183,9 @@ type urlRedactor struct {
 // credentials found in rawurl, and the rawurl itself.
 func newURLRedactor(rawurl string) *urlRedactor {
 	var sensitive []string
-	parsedURL, _ := url.Parse(rawurl)
+	// The credentials of Mercurial remotes are in the URL after the hg::
+	// prefix, which url.Parse would take for the scheme.
+	parsedURL, _ := url.Parse(strings.TrimPrefix(rawurl, hgURLPrefix))
 	if parsedURL != nil {
 		pw, _ := parsedURL.User.Password()
 		u := parsedURL.User.Username()
@@ -1007,11 +1246,7 @@ func scanCRLF(data []byte, atEOF bool) (advance int, token []byte, err error) {
 var testRepoExists func(ctx context.Context, url string) error
 
 // isCloneable checks to see if the Git remote URL is cloneable.
-func (s *Server) isCloneable(ctx context.Context, url string) error {
-	args := []string{"ls-remote", url, "HEAD"}
-	ctx, cancel := context.WithTimeout(ctx, shortGitCommandTimeout(args))
-	defer cancel()
-
+func (s *Server) isCloneable(ctx context.Context, repo api.RepoName, url string) error {
 	if strings.ToLower(string(protocol.NormalizeRepo(api.RepoName(url)))) == "github.com/sourcegraphtest/alwayscloningtest" {
 		return nil
 	}
@@ -1019,18 +1254,7 @@ func (s *Server) isCloneable(ctx context.Context, url string) error {
 		return testRepoExists(ctx, url)
 	}
 
-	cmd := exec.CommandContext(ctx, "git", args...)
-	out, err := runWithRemoteOpts(ctx, cmd, nil)
-	if err != nil {
-		if ctxerr := ctx.Err(); ctxerr != nil {
-			err = ctxerr
-		}
-		if len(out) > 0 {
-			err = fmt.Errorf("%s (output follows)\n\n%s", err, out)
-		}
-		return err
-	}
-	return nil
+	return vcsSyncerFor(repo, url).IsCloneable(ctx, url)
 }
 
 var (
@@ -1055,6 +1279,10 @@ var (
 		Name: "src_gitserver_repo_cloned",
 		Help: "number of successful git clones run",
 	})
+	repoRebalancedCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "src_gitserver_repo_rebalanced",
+		Help: "number of successful git clones of repos moved from another gitserver instead of the code host",
+	})
 )
 
 func init() {
@@ -1063,6 +1291,7 @@ func init() {
 	prometheus.MustRegister(cloneQueue)
 	prometheus.MustRegister(lsRemoteQueue)
 	prometheus.MustRegister(repoClonedCounter)
+	prometheus.MustRegister(repoRebalancedCounter)
 }
 
 var headBranchPattern = lazyregexp.New(`HEAD branch: (.+?)\n`)
@@ -1073,18 +1302,8 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 	span.SetTag("url", url)
 	defer span.Finish()
 
-	s.repoUpdateLocksMu.Lock()
-	l, ok := s.repoUpdateLocks[repo]
-	if !ok {
-		l = &locks{
-			once: new(sync.Once),
-			mu:   new(sync.Mutex),
-		}
-		s.repoUpdateLocks[repo] = l
-	}
-	once := l.once
+	l, once := s.repoUpdateLock(repo)
 	mu := l.mu
-	s.repoUpdateLocksMu.Unlock()
 
 	// doRepoUpdate2 can block longer than our context deadline. done will
 	// close when its done. We can return when either done is closed or our
@@ -1102,6 +1321,9 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 			s.repoUpdateLocksMu.Unlock()
 
 			err = s.doRepoUpdate2(repo, url)
+			if err == nil {
+				s.replicate(repo, url)
+			}
 		})
 	}()
 
@@ -1114,6 +1336,22 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 	}
 }
 
+// repoUpdateLock returns the locks of repo which serialize its updates, and
+// the current value of its once.
+func (s *Server) repoUpdateLock(repo api.RepoName) (*locks, *sync.Once) {
+	s.repoUpdateLocksMu.Lock()
+	defer s.repoUpdateLocksMu.Unlock()
+	l, ok := s.repoUpdateLocks[repo]
+	if !ok {
+		l = &locks{
+			once: new(sync.Once),
+			mu:   new(sync.Mutex),
+		}
+		s.repoUpdateLocks[repo] = l
+	}
+	return l, l.once
+}
+
 var (
 	badRefsOnce sync.Once
 	badRefs     []string
@@ -1308,11 +1546,12 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 	if !urlIsGitRemote {
 		// Note: We do not use CommandContext since it is a fast operation.
 		var cmd *exec.Cmd
+		stored := storedRemoteURL(url)
 		if current, _ := repoRemoteURL(ctx, dir); current == "" {
-			cmd = exec.Command("git", "remote", "add", "origin", url)
-		} else if current != url {
+			cmd = exec.Command("git", "remote", "add", "origin", stored)
+		} else if current != stored {
 			log15.Debug("repository remote URL changed", "repo", repo)
-			cmd = exec.Command("git", "remote", "set-url", "origin", "--", url)
+			cmd = exec.Command("git", "remote", "set-url", "origin", "--", stored)
 		}
 		if cmd != nil {
 			dir.Set(cmd)
@@ -1322,27 +1561,7 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 		}
 	}
 
-	configRemoteOpts := true
-	var cmd *exec.Cmd
-	if customCmd := customFetchCmd(ctx, url); customCmd != nil {
-		cmd = customCmd
-		configRemoteOpts = false
-	} else if useRefspecOverrides() {
-		cmd = refspecOverridesFetchCmd(ctx, url)
-	} else {
-		cmd = exec.CommandContext(ctx, "git", "fetch", "--prune", url,
-			// Normal git refs
-			"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*",
-			// GitHub pull requests
-			"+refs/pull/*:refs/pull/*",
-			// GitLab merge requests
-			"+refs/merge-requests/*:refs/merge-requests/*",
-			// Bitbucket pull requests
-			"+refs/pull-requests/*:refs/pull-requests/*",
-			// Possibly deprecated refs for sourcegraph zap experiment?
-			"+refs/sourcegraph/*:refs/sourcegraph/*")
-	}
-	dir.Set(cmd)
+	syncer := vcsSyncerFor(repo, url)
 
 	// drop temporary pack files after a fetch. this function won't
 	// return until this fetch has completed or definitely-failed,
@@ -1350,10 +1569,18 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 	// when the cleanup happens, just that it does.
 	defer s.cleanTmpFiles(dir)
 
-	if output, err := runWith(ctx, cmd, configRemoteOpts, nil); err != nil {
+	start := time.Now()
+	output, err := syncer.Fetch(ctx, url, dir)
+	if err != nil {
+		s.recordSync(repo, "fetch", start, output, errors.Wrapf(err, "fetch failed. Output: %s", string(output)), newURLRedactor(url))
 		log15.Error("Failed to update", "repo", repo, "error", err, "output", string(output))
 		return errors.Wrap(err, "failed to update")
 	}
+	s.recordSync(repo, "fetch", start, output, nil, newURLRedactor(url))
+
+	if _, err := s.updateRepoSize(repo, dir, url); err != nil {
+		log15.Warn("failed to update repo size", "repo", repo, "error", err)
+	}
 
 	removeBadRefs(ctx, dir)
 
@@ -1365,24 +1592,27 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 	// Fallback to git's default branch name if git remote show fails.
 	headBranch := "master"
 
-	// try to fetch HEAD from origin
-	cmd = exec.CommandContext(ctx, "git", "remote", "show", url)
-	cmd.Dir = path.Join(s.ReposDir, string(repo))
-	output, err := runWithRemoteOpts(ctx, cmd, nil)
-	if err != nil {
-		log15.Error("Failed to fetch remote info", "repo", repo, "error", err, "output", string(output))
-		return errors.Wrap(err, "failed to fetch remote info")
-	}
-	submatches := headBranchPattern.FindSubmatch(output)
-	if len(submatches) == 2 {
-		submatch := string(submatches[1])
-		if submatch != "(unknown)" {
-			headBranch = string(submatch)
+	// try to fetch HEAD from origin. Only git remotes have a HEAD, imports
+	// from other version control systems use the default branch name.
+	if syncer.Type() == "git" {
+		cmd := exec.CommandContext(ctx, "git", "remote", "show", url)
+		cmd.Dir = path.Join(s.ReposDir, string(repo))
+		output, err = runWithRemoteOpts(ctx, cmd, nil)
+		if err != nil {
+			log15.Error("Failed to fetch remote info", "repo", repo, "error", err, "output", string(output))
+			return errors.Wrap(err, "failed to fetch remote info")
+		}
+		submatches := headBranchPattern.FindSubmatch(output)
+		if len(submatches) == 2 {
+			submatch := string(submatches[1])
+			if submatch != "(unknown)" {
+				headBranch = string(submatch)
+			}
 		}
 	}
 
 	// check if branch pointed to by HEAD exists
-	cmd = exec.CommandContext(ctx, "git", "rev-parse", headBranch, "--")
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", headBranch, "--")
 	cmd.Dir = path.Join(s.ReposDir, string(repo))
 	if err := cmd.Run(); err != nil {
 		// branch does not exist, pick first branch
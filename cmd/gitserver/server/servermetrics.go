@@ -7,9 +7,22 @@ import (
 
 	"github.com/inconshreveable/log15"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/sourcegraph/sourcegraph/internal/metrics"
 )
 
+var (
+	maintenanceTasksRun = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "src_gitserver_maintenance_tasks_total",
+		Help: "Number of repository maintenance tasks run, such as git repack.",
+	}, []string{"task", "status"})
+	maintenanceDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "src_gitserver_maintenance_duration_seconds",
+		Help:    "Duration of repository maintenance tasks.",
+		Buckets: []float64{1, 5, 10, 30, 60, 300, 600, 1800, 3600},
+	}, []string{"task"})
+)
+
 func (s *Server) RegisterMetrics() {
 	// test the latency of exec, which may increase under certain memory
 	// conditions
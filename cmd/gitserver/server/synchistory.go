@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+// syncHistoryFile is the file in $GIT_DIR which stores the most recent clones
+// and fetches of the repository as JSON.
+const syncHistoryFile = "sg_sync_history"
+
+// syncHistorySize is the number of clones and fetches kept per repository.
+const syncHistorySize = 20
+
+// maxSyncErrorLength is the maximum length of the error of a sync kept in the
+// history. The output of git commands can be long, but the reason of a
+// failure is usually at the end of the output, so the end of the error is
+// kept.
+const maxSyncErrorLength = 4096
+
+// maxUnclonedRepos is the maximum number of repositories which are not cloned
+// whose history is kept in memory. When it is exceeded, the history of the
+// repository synced least recently is dropped.
+const maxUnclonedRepos = 1000
+
+// syncHistory records the clones and fetches of repositories. The history of
+// cloned repositories is stored in the repository, so that it survives
+// restarts. The history of repositories which failed to clone is kept in
+// memory until the repository is cloned, for at most maxUnclonedRepos
+// repositories. The zero value is ready to use.
+type syncHistory struct {
+	mu sync.Mutex
+	// uncloned are the syncs of repositories which are not cloned.
+	uncloned map[api.RepoName][]protocol.RepoSync
+}
+
+// add records the sync of repo, which is stored in dir.
+func (h *syncHistory) add(repo api.RepoName, dir GitDir, rs protocol.RepoSync) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !repoCloned(dir) {
+		if h.uncloned == nil {
+			h.uncloned = make(map[api.RepoName][]protocol.RepoSync)
+		}
+		if _, ok := h.uncloned[repo]; !ok && len(h.uncloned) >= maxUnclonedRepos {
+			h.evictUncloned()
+		}
+		h.uncloned[repo] = prependSync(h.uncloned[repo], rs)
+		return nil
+	}
+
+	history, err := readSyncHistory(dir)
+	if err != nil {
+		return err
+	}
+	// The failed clones before the repository was cloned are part of its
+	// history.
+	history = append(history, h.uncloned[repo]...)
+	delete(h.uncloned, repo)
+	history = prependSync(history, rs)
+
+	b, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(dir.Path(syncHistoryFile), b, 0600); err != nil {
+		return errors.Wrap(err, "failed to write sync history")
+	}
+	return nil
+}
+
+// list returns the syncs of repo, which is stored in dir, most recent first.
+func (h *syncHistory) list(repo api.RepoName, dir GitDir) ([]protocol.RepoSync, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history, err := readSyncHistory(dir)
+	if err != nil {
+		return nil, err
+	}
+	return append(history, h.uncloned[repo]...), nil
+}
+
+// evictUncloned drops the history of the repository which is not cloned and
+// was synced least recently. The caller must hold h.mu.
+func (h *syncHistory) evictUncloned() {
+	var (
+		oldest     api.RepoName
+		oldestTime time.Time
+	)
+	for repo, history := range h.uncloned {
+		// The history is never empty, and the most recent sync is first.
+		if t := history[0].FinishedAt; oldest == "" || t.Before(oldestTime) {
+			oldest, oldestTime = repo, t
+		}
+	}
+	delete(h.uncloned, oldest)
+}
+
+func prependSync(history []protocol.RepoSync, rs protocol.RepoSync) []protocol.RepoSync {
+	history = append([]protocol.RepoSync{rs}, history...)
+	if len(history) > syncHistorySize {
+		history = history[:syncHistorySize]
+	}
+	return history
+}
+
+func readSyncHistory(dir GitDir) ([]protocol.RepoSync, error) {
+	b, err := ioutil.ReadFile(dir.Path(syncHistoryFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []protocol.RepoSync
+	if err := json.Unmarshal(b, &history); err != nil {
+		return nil, errors.Wrap(err, "failed to parse sync history")
+	}
+	return history, nil
+}
+
+// recordSync records a clone or fetch of repo, which started at start and
+// printed output. err is the error of the sync, if it failed.
+func (s *Server) recordSync(repo api.RepoName, typ string, start time.Time, output []byte, err error, redactor *urlRedactor) {
+	rs := protocol.RepoSync{
+		Type:       typ,
+		StartedAt:  start,
+		FinishedAt: time.Now(),
+	}
+	rs.Objects, rs.Bytes = receivedStats(output)
+	if err != nil {
+		// 🚨 SECURITY: The error could include the remote URL, which may
+		// contain a token.
+		rs.Error = truncateSyncError(redactor.redact(err.Error()))
+	}
+	repo = protocol.NormalizeRepo(repo)
+	if err := s.syncHistory.add(repo, s.dir(repo), rs); err != nil {
+		log15.Warn("failed to record sync", "repo", repo, "error", err)
+	}
+}
+
+// truncateSyncError returns the last maxSyncErrorLength bytes of msg, starting
+// at a UTF-8 character boundary.
+func truncateSyncError(msg string) string {
+	if len(msg) <= maxSyncErrorLength {
+		return msg
+	}
+	i := len(msg) - maxSyncErrorLength
+	for i < len(msg) && !utf8.RuneStart(msg[i]) {
+		i++
+	}
+	return msg[i:]
+}
+
+// receivedObjectsPattern matches the progress git clone and git fetch print
+// when they receive objects, for example
+//
+//	Receiving objects: 100% (2148/2148), 473.65 KiB | 366.00 KiB/s, done.
+var receivedObjectsPattern = regexp.MustCompile(`Receiving objects:\s+\d+% \((\d+)/\d+\)(?:, ([\d.]+) (bytes|KiB|MiB|GiB))?`)
+
+var byteUnits = map[string]float64{
+	"bytes": 1,
+	"KiB":   1 << 10,
+	"MiB":   1 << 20,
+	"GiB":   1 << 30,
+}
+
+// receivedStats returns the number of objects and bytes received according to
+// the last progress line in output.
+func receivedStats(output []byte) (objects int, size int64) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Split(scanCRLF)
+	for scanner.Scan() {
+		m := receivedObjectsPattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		objects, _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			n, _ := strconv.ParseFloat(m[2], 64)
+			size = int64(n * byteUnits[m[3]])
+		}
+	}
+	return objects, size
+}
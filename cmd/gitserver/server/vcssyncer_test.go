@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVCSSyncerFor(t *testing.T) {
+	for url, want := range map[string]string{
+		"https://github.com/gorilla/mux":                      "git",
+		"git@github.com:gorilla/mux.git":                      "git",
+		"hg::https://hg.mozilla.org/mozilla-central":          "hg",
+		"perforce://admin:pw@p4.example.com:1666//depot/main": "perforce",
+		"perforce+ssl://admin:pw@p4.example.com:1666//depot":  "perforce",
+	} {
+		if got := vcsSyncerFor("repo", url).Type(); got != want {
+			t.Errorf("%s: got syncer %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestPerforceDepotSyncer(t *testing.T) {
+	s := &perforceDepotSyncer{}
+	ctx := context.Background()
+
+	cmd, err := s.CloneCommand(ctx, "perforce+ssl://admin:pw@p4.example.com:1666//depot/main/", "/tmp/clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantArgs := []string{"git", "p4", "clone", "--bare", "--branch", "refs/heads/master", "//depot/main/@all", "/tmp/clone"}
+	if !reflect.DeepEqual(cmd.Args, wantArgs) {
+		t.Errorf("got args %q, want %q", cmd.Args, wantArgs)
+	}
+	env := cmd.Env[len(cmd.Env)-3:]
+	wantEnv := []string{"P4PORT=ssl:p4.example.com:1666", "P4USER=admin", "P4PASSWD=pw"}
+	if !reflect.DeepEqual(env, wantEnv) {
+		t.Errorf("got env %q, want %q", env, wantEnv)
+	}
+
+	for _, url := range []string{
+		"perforce://admin:pw@p4.example.com:1666/depot",
+		"perforce:////depot",
+	} {
+		if _, err := s.CloneCommand(ctx, url, "/tmp/clone"); err == nil {
+			t.Errorf("%s: expected error", url)
+		}
+	}
+}
+
+func TestStoredRemoteURL(t *testing.T) {
+	for url, want := range map[string]string{
+		"https://user:pw@github.com/gorilla/mux":                  "https://user:pw@github.com/gorilla/mux",
+		"hg::https://hg.mozilla.org/mozilla-central":              "hg::https://hg.mozilla.org/mozilla-central",
+		"perforce://admin:pw@p4.example.com:1666//depot/main":     "perforce://admin@p4.example.com:1666//depot/main",
+		"perforce+ssl://admin:pw@p4.example.com:1666//depot/main": "perforce+ssl://admin@p4.example.com:1666//depot/main",
+		"perforce://admin@p4.example.com:1666//depot/main":        "perforce://admin@p4.example.com:1666//depot/main",
+	} {
+		if got := storedRemoteURL(url); got != want {
+			t.Errorf("%s: got %q, want %q", url, got, want)
+		}
+	}
+}
+
+// requireCommands skips the test unless all the given commands are
+// installed. Commands of the form git-foo may also be installed as git
+// subcommands in the exec path of git.
+func requireCommands(t *testing.T, names ...string) {
+	t.Helper()
+	execPath, _ := exec.Command("git", "--exec-path").Output()
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err == nil {
+			continue
+		}
+		if strings.HasPrefix(name, "git-") {
+			if _, err := os.Stat(filepath.Join(strings.TrimSpace(string(execPath)), name)); err == nil {
+				continue
+			}
+		}
+		t.Skipf("%s is not installed", name)
+	}
+}
+
+func TestHgRepoSyncer(t *testing.T) {
+	requireCommands(t, "hg", "git-remote-hg")
+
+	ctx := context.Background()
+	remote := tmpDir(t)
+	runCmd(t, remote, "hg", "init")
+	hgCommit := func(msg string) {
+		if err := ioutil.WriteFile(filepath.Join(remote, "README"), []byte(msg), 0600); err != nil {
+			t.Fatal(err)
+		}
+		runCmd(t, remote, "hg", "commit", "--addremove", "--user", "a <a@a.com>", "-m", msg)
+	}
+	hgCommit("first")
+
+	s := &hgRepoSyncer{}
+	remoteURL := hgURLPrefix + remote
+	if err := s.IsCloneable(ctx, remoteURL); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(tmpDir(t), ".git")
+	cmd, err := s.CloneCommand(ctx, remoteURL, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("clone failed: %s\n%s", err, out)
+	}
+	if got := runCmd(t, dst, "git", "log", "--format=%s", "master"); got != "first\n" {
+		t.Fatalf("got log %q after clone", got)
+	}
+
+	hgCommit("second")
+	if out, err := s.Fetch(ctx, remoteURL, GitDir(dst)); err != nil {
+		t.Fatalf("fetch failed: %s\n%s", err, out)
+	}
+	if got := runCmd(t, dst, "git", "log", "--format=%s", "master"); got != "second\nfirst\n" {
+		t.Fatalf("got log %q after fetch", got)
+	}
+}
+
+func TestPerforceDepotSyncer_CloneAndFetch(t *testing.T) {
+	requireCommands(t, "p4", "p4d", "git-p4")
+
+	ctx := context.Background()
+
+	// Run a Perforce server on a free port.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().String()
+	l.Close()
+	p4d := exec.Command("p4d", "-r", tmpDir(t), "-p", port, "-L", "log")
+	if err := p4d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = p4d.Process.Kill()
+		_ = p4d.Wait()
+	}()
+
+	ws := tmpDir(t)
+	p4 := func(stdin string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("p4", args...)
+		cmd.Dir = ws
+		cmd.Env = append(os.Environ(), "P4PORT="+port, "P4USER=admin", "P4CLIENT=ws")
+		cmd.Stdin = strings.NewReader(stdin)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("p4 %s failed: %s\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	var ready bool
+	for i := 0; i < 50 && !ready; i++ {
+		ready = exec.Command("p4", "-p", port, "info").Run() == nil
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !ready {
+		t.Fatal("p4d did not start")
+	}
+	p4("Client: ws\nRoot: "+ws+"\nView:\n\t//depot/... //ws/...\n", "client", "-i")
+	p4Submit := func(msg string, add bool) {
+		if !add {
+			p4("", "edit", "README")
+		}
+		if err := ioutil.WriteFile(filepath.Join(ws, "README"), []byte(msg), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if add {
+			p4("", "add", "README")
+		}
+		p4("", "submit", "-d", msg)
+	}
+	p4Submit("first", true)
+
+	s := &perforceDepotSyncer{}
+	remoteURL := "perforce://admin@" + port + "//depot"
+	if err := s.IsCloneable(ctx, remoteURL); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(tmpDir(t), ".git")
+	cmd, err := s.CloneCommand(ctx, remoteURL, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("clone failed: %s\n%s", err, out)
+	}
+	if got := runCmd(t, dst, "git", "log", "--format=%s", "master"); got != "first\n" {
+		t.Fatalf("got log %q after clone", got)
+	}
+
+	p4Submit("second", false)
+	if out, err := s.Fetch(ctx, remoteURL, GitDir(dst)); err != nil {
+		t.Fatalf("fetch failed: %s\n%s", err, out)
+	}
+	if got := runCmd(t, dst, "git", "log", "--format=%s", "master"); got != "second\nfirst\n" {
+		t.Fatalf("got log %q after fetch", got)
+	}
+}
@@ -124,6 +124,10 @@ func (s *AWSCodeCommitSource) makeRepo(r *awscodecommit.Repository) (*Repo, erro
 		URI:          string(reposource.AWSRepoName("", r.Name)),
 		ExternalRepo: awscodecommit.ExternalRepoSpec(r, serviceID),
 		Description:  r.Description,
+		// Access to AWS CodeCommit repositories is governed by IAM policies.
+		// They are only treated as private if those are enforced, otherwise
+		// all users would be denied access to them.
+		Private: s.config.Authorization != nil,
 		Sources: map[string]*SourceInfo{
 			urn: {
 				ID:       urn,
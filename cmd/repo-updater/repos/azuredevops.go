@@ -0,0 +1,303 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/azuredevops"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// An AzureDevOpsSource yields repositories from a single Azure DevOps
+// connection configured in Sourcegraph via the external services
+// configuration.
+type AzureDevOpsSource struct {
+	svc     *ExternalService
+	config  *schema.AzureDevOpsConnection
+	exclude excludeFunc
+	baseURL *url.URL
+	client  *azuredevops.Client
+}
+
+// NewAzureDevOpsSource returns a new AzureDevOpsSource from the given external service.
+func NewAzureDevOpsSource(svc *ExternalService, cf *httpcli.Factory) (*AzureDevOpsSource, error) {
+	var c schema.AzureDevOpsConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, fmt.Errorf("external service id=%d config error: %s", svc.ID, err)
+	}
+	return newAzureDevOpsSource(svc, &c, cf)
+}
+
+func newAzureDevOpsSource(svc *ExternalService, c *schema.AzureDevOpsConnection, cf *httpcli.Factory) (*AzureDevOpsSource, error) {
+	baseURL, err := url.Parse(c.Url)
+	if err != nil {
+		return nil, err
+	}
+	baseURL = extsvc.NormalizeBaseURL(baseURL)
+
+	if cf == nil {
+		cf = httpcli.NewExternalHTTPClientFactory()
+	}
+
+	cli, err := cf.Doer()
+	if err != nil {
+		return nil, err
+	}
+
+	var eb excludeBuilder
+	for _, r := range c.Exclude {
+		eb.Exact(r.Name)
+		eb.Exact(r.Id)
+		eb.Pattern(r.Pattern)
+	}
+	exclude, err := eb.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureDevOpsSource{
+		svc:     svc,
+		config:  c,
+		exclude: exclude,
+		baseURL: baseURL,
+		client:  azuredevops.NewClient(baseURL, c.Token, cli),
+	}, nil
+}
+
+// ListRepos returns all Azure DevOps repositories accessible to all connections
+// configured in Sourcegraph via the external services configuration.
+func (s AzureDevOpsSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	type project struct{ org, name string }
+
+	var projects []project
+	if len(s.config.Projects) > 0 {
+		for _, p := range s.config.Projects {
+			parts := strings.SplitN(p, "/", 2)
+			if len(parts) != 2 {
+				results <- SourceResult{Source: s, Err: errors.Errorf("invalid Azure DevOps project %q, want org/project", p)}
+				continue
+			}
+			projects = append(projects, project{org: parts[0], name: parts[1]})
+		}
+	} else {
+		for _, org := range s.config.Orgs {
+			// An empty project name lists the repositories of all projects.
+			projects = append(projects, project{org: org})
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range projects {
+		repos, err := s.client.ListRepos(ctx, p.org, p.name)
+		if err != nil {
+			results <- SourceResult{Source: s, Err: errors.Wrapf(err, "azuredevops.list: org=%q, project=%q", p.org, p.name)}
+			continue
+		}
+
+		for _, r := range repos {
+			if !seen[r.ID] && !s.excludes(r) {
+				results <- SourceResult{Source: s, Repo: s.makeRepo(r)}
+				seen[r.ID] = true
+			}
+		}
+	}
+}
+
+// GetRepo returns the Azure DevOps repository with the given "org/project/repo" name.
+func (s AzureDevOpsSource) GetRepo(ctx context.Context, nameWithOwner string) (*Repo, error) {
+	parts := strings.SplitN(nameWithOwner, "/", 3)
+	if len(parts) != 3 {
+		return nil, errors.Errorf("invalid Azure DevOps repository name %q, want org/project/repo", nameWithOwner)
+	}
+
+	r, err := s.client.GetRepo(ctx, parts[0], parts[1], parts[2])
+	if err != nil {
+		return nil, err
+	}
+	return s.makeRepo(r), nil
+}
+
+// ExternalServices returns a singleton slice containing the external service.
+func (s AzureDevOpsSource) ExternalServices() ExternalServices {
+	return ExternalServices{s.svc}
+}
+
+func (s AzureDevOpsSource) makeRepo(r *azuredevops.Repository) *Repo {
+	urn := s.svc.URN()
+	return &Repo{
+		Name: string(reposource.AzureDevOpsRepoName(
+			s.config.RepositoryPathPattern,
+			s.baseURL.Hostname(),
+			r.Org,
+			r.Project.Name,
+			r.Name,
+		)),
+		URI: string(reposource.AzureDevOpsRepoName(
+			"",
+			s.baseURL.Hostname(),
+			r.Org,
+			r.Project.Name,
+			r.Name,
+		)),
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          r.ID,
+			ServiceType: extsvc.TypeAzureDevOps,
+			ServiceID:   s.baseURL.String(),
+		},
+		Description: r.Project.Description,
+		Fork:        r.IsFork,
+		Private:     r.Project.Visibility != "public",
+		Sources: map[string]*SourceInfo{
+			urn: {
+				ID:       urn,
+				CloneURL: s.authenticatedRemoteURL(r),
+			},
+		},
+		Metadata: r,
+	}
+}
+
+// authenticatedRemoteURL returns the repository's Git remote URL with the
+// configured personal access token inserted in the URL userinfo.
+func (s AzureDevOpsSource) authenticatedRemoteURL(r *azuredevops.Repository) string {
+	if s.config.GitURLType == "ssh" {
+		return r.SSHURL
+	}
+
+	u, err := url.Parse(r.RemoteURL)
+	if err != nil || r.RemoteURL == "" {
+		u = s.baseURL.ResolveReference(&url.URL{Path: r.Org + "/" + r.Project.Name + "/_git/" + r.Name})
+	}
+	// Azure DevOps accepts any non-empty username with a personal access
+	// token as the password.
+	u.User = url.UserPassword("sourcegraph", s.config.Token)
+	return u.String()
+}
+
+func (s AzureDevOpsSource) excludes(r *azuredevops.Repository) bool {
+	// Disabled repositories can't be cloned.
+	return r.IsDisabled || s.exclude(r.NameWithOwner()) || s.exclude(r.ID)
+}
+
+var _ ChangesetSource = AzureDevOpsSource{}
+
+// CreateChangeset creates an Azure DevOps pull request. If it already exists,
+// *Changeset will be populated and the return value will be true.
+func (s AzureDevOpsSource) CreateChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	repo := c.Repo.Metadata.(*azuredevops.Repository)
+	exists := false
+	source := git.EnsureRefPrefix(c.HeadRef)
+	target := git.EnsureRefPrefix(c.BaseRef)
+
+	pr, err := s.client.CreatePullRequest(ctx, repo, &azuredevops.CreatePullRequestInput{
+		SourceRefName: source,
+		TargetRefName: target,
+		Title:         c.Title,
+		Description:   c.Body,
+	})
+	if err != nil {
+		if err != azuredevops.ErrPullRequestAlreadyExists {
+			return exists, errors.Wrap(err, "creating the pull request")
+		}
+
+		exists = true
+		if pr, err = s.client.GetActivePullRequestByRefs(ctx, repo, source, target); err != nil {
+			return exists, errors.Wrap(err, "retrieving an extant pull request")
+		}
+	}
+
+	if err := c.SetMetadata(pr); err != nil {
+		return exists, errors.Wrap(err, "setting changeset metadata")
+	}
+	return exists, nil
+}
+
+// CloseChangeset abandons the pull request on Azure DevOps.
+func (s AzureDevOpsSource) CloseChangeset(ctx context.Context, c *Changeset) error {
+	pr, ok := c.Changeset.Metadata.(*azuredevops.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not an Azure DevOps pull request")
+	}
+
+	updated, err := s.client.UpdatePullRequest(ctx, c.Repo.Metadata.(*azuredevops.Repository), pr.PullRequestID, &azuredevops.UpdatePullRequestInput{
+		Status: azuredevops.PullRequestStatusAbandoned,
+	})
+	if err != nil {
+		return errors.Wrap(err, "abandoning Azure DevOps pull request")
+	}
+
+	if err := c.SetMetadata(updated); err != nil {
+		return errors.Wrap(err, "setting changeset metadata")
+	}
+	return nil
+}
+
+// LoadChangesets loads the given pull requests from Azure DevOps and updates
+// them.
+func (s AzureDevOpsSource) LoadChangesets(ctx context.Context, cs ...*Changeset) error {
+	var notFound []*Changeset
+
+	for _, c := range cs {
+		id, err := strconv.ParseInt(c.ExternalID, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "parsing changeset external ID %s", c.ExternalID)
+		}
+
+		pr, err := s.client.GetPullRequest(ctx, c.Repo.Metadata.(*azuredevops.Repository), id)
+		if err != nil {
+			if azuredevops.IsNotFound(err) {
+				notFound = append(notFound, c)
+				continue
+			}
+			return errors.Wrapf(err, "retrieving pull request %d", id)
+		}
+
+		if err := c.SetMetadata(pr); err != nil {
+			return errors.Wrapf(err, "setting changeset metadata for pull request %d", id)
+		}
+	}
+
+	if len(notFound) > 0 {
+		return ChangesetsNotFoundError{Changesets: notFound}
+	}
+
+	return nil
+}
+
+// UpdateChangeset updates the pull request on Azure DevOps to reflect the
+// local state of the Changeset.
+func (s AzureDevOpsSource) UpdateChangeset(ctx context.Context, c *Changeset) error {
+	pr, ok := c.Changeset.Metadata.(*azuredevops.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not an Azure DevOps pull request")
+	}
+
+	in := &azuredevops.UpdatePullRequestInput{
+		Title:       c.Title,
+		Description: c.Body,
+	}
+	// Only retarget the pull request if the base ref changed.
+	if target := git.EnsureRefPrefix(c.BaseRef); target != pr.TargetRefName {
+		in.TargetRefName = target
+	}
+
+	updated, err := s.client.UpdatePullRequest(ctx, c.Repo.Metadata.(*azuredevops.Repository), pr.PullRequestID, in)
+	if err != nil {
+		return errors.Wrap(err, "updating Azure DevOps pull request")
+	}
+
+	if err := c.SetMetadata(updated); err != nil {
+		return errors.Wrap(err, "setting changeset metadata")
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package repos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/azuredevops"
+)
+
+func TestAzureDevOpsSource_ListRepos(t *testing.T) {
+	var srv *httptest.Server
+	repo := func(id, project, name string, disabled bool) *azuredevops.Repository {
+		return &azuredevops.Repository{
+			ID:         id,
+			Name:       name,
+			Project:    azuredevops.Project{Name: project, Visibility: "private"},
+			IsDisabled: disabled,
+			RemoteURL:  srv.URL + "/myorg/" + project + "/_git/" + name,
+		}
+	}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var repos []*azuredevops.Repository
+		switch r.URL.Path {
+		case "/myorg/_apis/git/repositories":
+			repos = []*azuredevops.Repository{
+				repo("1", "web", "frontend", false),
+				repo("2", "web", "legacy", true),
+				repo("3", "web", "secret", false),
+				repo("4", "infra", "deploy", false),
+				repo("5", "infra", "terraform", false),
+			}
+		case "/otherorg/_apis/git/repositories":
+			repos = []*azuredevops.Repository{
+				repo("1", "web", "frontend", false),
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"count": len(repos), "value": repos})
+	}))
+	defer srv.Close()
+
+	svc := &ExternalService{
+		ID:   1,
+		Kind: extsvc.KindAzureDevOps,
+		Config: fmt.Sprintf(`{
+			"url": %q,
+			"token": "secret",
+			"orgs": ["myorg", "otherorg"],
+			"exclude": [{"name": "myorg/web/secret"}, {"id": "5"}]
+		}`, srv.URL),
+	}
+	src, err := NewAzureDevOpsSource(svc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repos, err := listAll(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.Name+" "+r.ExternalRepo.ID)
+	}
+	sort.Strings(names)
+
+	want := []string{
+		"127.0.0.1/myorg/infra/deploy 4",
+		"127.0.0.1/myorg/web/frontend 1",
+	}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, r := range repos {
+		if r.ExternalRepo.ServiceType != extsvc.TypeAzureDevOps || r.ExternalRepo.ServiceID != srv.URL+"/" {
+			t.Errorf("have external repo %+v", r.ExternalRepo)
+		}
+		if !r.Private {
+			t.Errorf("repo %s is not private", r.Name)
+		}
+		meta := r.Metadata.(*azuredevops.Repository)
+		want := "http://sourcegraph:secret@" + srv.Listener.Addr().String() + "/myorg/" + meta.Project.Name + "/_git/" + meta.Name
+		if have := r.Sources[svc.URN()].CloneURL; have != want {
+			t.Errorf("have clone URL %q, want %q", have, want)
+		}
+	}
+}
+
+func TestAzureDevOpsSource_CreateChangeset(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v interface{}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /myorg/p1/_apis/git/repositories/r1/pullrequests":
+			var in azuredevops.CreatePullRequestInput
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				t.Error(err)
+			}
+			if in.SourceRefName == "refs/heads/exists" {
+				w.WriteHeader(http.StatusConflict)
+				v = map[string]string{"message": "An active pull request for the source and target branch already exists."}
+				break
+			}
+			v = &azuredevops.PullRequest{PullRequestID: 1, SourceRefName: in.SourceRefName, TargetRefName: in.TargetRefName}
+		case "GET /myorg/p1/_apis/git/repositories/r1/pullrequests":
+			v = map[string]interface{}{"value": []*azuredevops.PullRequest{{
+				PullRequestID: 2,
+				SourceRefName: "refs/heads/exists",
+				TargetRefName: "refs/heads/master",
+			}}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v)
+	}))
+	defer srv.Close()
+
+	svc := &ExternalService{
+		ID:     1,
+		Kind:   extsvc.KindAzureDevOps,
+		Config: fmt.Sprintf(`{"url": %q, "token": "secret", "orgs": ["myorg"]}`, srv.URL),
+	}
+	src, err := NewAzureDevOpsSource(svc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &Repo{Metadata: &azuredevops.Repository{
+		ID:      "r1",
+		Name:    "repo",
+		Org:     "myorg",
+		Project: azuredevops.Project{ID: "p1", Name: "project"},
+	}}
+
+	for _, tc := range []struct {
+		head       string
+		wantExists bool
+		wantID     string
+	}{
+		{head: "refs/heads/new", wantExists: false, wantID: "1"},
+		{head: "exists", wantExists: true, wantID: "2"},
+	} {
+		cs := &Changeset{
+			Title:     "title",
+			HeadRef:   tc.head,
+			BaseRef:   "master",
+			Repo:      repo,
+			Changeset: &campaigns.Changeset{},
+		}
+
+		exists, err := src.CreateChangeset(context.Background(), cs)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.head, err)
+		}
+		if exists != tc.wantExists {
+			t.Errorf("%s: have exists=%t, want %t", tc.head, exists, tc.wantExists)
+		}
+		if cs.ExternalID != tc.wantID {
+			t.Errorf("%s: have external ID %q, want %q", tc.head, cs.ExternalID, tc.wantID)
+		}
+		if cs.ExternalServiceType != extsvc.TypeAzureDevOps {
+			t.Errorf("%s: have external service type %q", tc.head, cs.ExternalServiceType)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strconv"
 	"sync"
 
 	"github.com/inconshreveable/log15"
@@ -14,6 +15,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
 	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 	"github.com/sourcegraph/sourcegraph/schema"
 )
 
@@ -231,3 +233,148 @@ func (s *BitbucketCloudSource) listAllRepos(ctx context.Context, results chan So
 		}
 	}
 }
+
+var _ ChangesetSource = BitbucketCloudSource{}
+
+// CreateChangeset creates a Bitbucket Cloud pull request. If an open pull
+// request between the same branches already exists, *Changeset will be
+// populated with it and the return value will be true.
+func (s BitbucketCloudSource) CreateChangeset(ctx context.Context, c *Changeset) (bool, error) {
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+	source := git.AbbreviateRef(c.HeadRef)
+	destination := git.AbbreviateRef(c.BaseRef)
+
+	exists := true
+	pr, err := s.client.FindOpenPullRequest(ctx, repo, source, destination)
+	if err == bitbucketcloud.ErrPullRequestNotFound {
+		exists = false
+		pr, err = s.client.CreatePullRequest(ctx, repo, &bitbucketcloud.CreatePullRequestInput{
+			Title:       c.Title,
+			Description: c.Body,
+			Source:      bitbucketcloud.PullRequestEndpoint{Branch: bitbucketcloud.Branch{Name: source}},
+			Destination: bitbucketcloud.PullRequestEndpoint{Branch: bitbucketcloud.Branch{Name: destination}},
+		})
+		if err != nil {
+			return exists, errors.Wrap(err, "creating the pull request")
+		}
+	} else if err != nil {
+		return exists, errors.Wrap(err, "looking up an extant pull request")
+	}
+
+	if err := s.loadPullRequestData(ctx, repo, pr); err != nil {
+		return exists, errors.Wrap(err, "loading pull request data")
+	}
+	if err := c.SetMetadata(pr); err != nil {
+		return exists, errors.Wrap(err, "setting changeset metadata")
+	}
+	return exists, nil
+}
+
+// CloseChangeset declines the pull request on Bitbucket Cloud.
+func (s BitbucketCloudSource) CloseChangeset(ctx context.Context, c *Changeset) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketcloud.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Cloud pull request")
+	}
+
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+	declined, err := s.client.DeclinePullRequest(ctx, repo, pr.ID)
+	if err != nil {
+		return errors.Wrap(err, "declining Bitbucket Cloud pull request")
+	}
+
+	if err := s.loadPullRequestData(ctx, repo, declined); err != nil {
+		return errors.Wrap(err, "loading pull request data")
+	}
+	if err := c.SetMetadata(declined); err != nil {
+		return errors.Wrap(err, "setting changeset metadata")
+	}
+	return nil
+}
+
+// LoadChangesets loads the given pull requests from Bitbucket Cloud and
+// updates them.
+func (s BitbucketCloudSource) LoadChangesets(ctx context.Context, cs ...*Changeset) error {
+	var notFound []*Changeset
+
+	for _, c := range cs {
+		id, err := strconv.ParseInt(c.ExternalID, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "parsing changeset external ID %s", c.ExternalID)
+		}
+
+		repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+		pr, err := s.client.GetPullRequest(ctx, repo, id)
+		if err != nil {
+			if bitbucketcloud.IsNotFound(err) {
+				notFound = append(notFound, c)
+				continue
+			}
+			return errors.Wrapf(err, "retrieving pull request %d", id)
+		}
+
+		if err := s.loadPullRequestData(ctx, repo, pr); err != nil {
+			return errors.Wrapf(err, "loading data of pull request %d", id)
+		}
+		if err := c.SetMetadata(pr); err != nil {
+			return errors.Wrapf(err, "setting changeset metadata for pull request %d", id)
+		}
+	}
+
+	if len(notFound) > 0 {
+		return ChangesetsNotFoundError{Changesets: notFound}
+	}
+
+	return nil
+}
+
+// UpdateChangeset updates the pull request on Bitbucket Cloud to reflect the
+// local state of the Changeset.
+func (s BitbucketCloudSource) UpdateChangeset(ctx context.Context, c *Changeset) error {
+	pr, ok := c.Changeset.Metadata.(*bitbucketcloud.PullRequest)
+	if !ok {
+		return errors.New("Changeset is not a Bitbucket Cloud pull request")
+	}
+
+	repo := c.Repo.Metadata.(*bitbucketcloud.Repo)
+	updated, err := s.client.UpdatePullRequest(ctx, repo, pr.ID, &bitbucketcloud.UpdatePullRequestInput{
+		Title:       c.Title,
+		Description: c.Body,
+		Destination: bitbucketcloud.PullRequestEndpoint{
+			Branch: bitbucketcloud.Branch{Name: git.AbbreviateRef(c.BaseRef)},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "updating Bitbucket Cloud pull request")
+	}
+
+	if err := s.loadPullRequestData(ctx, repo, updated); err != nil {
+		return errors.Wrap(err, "loading pull request data")
+	}
+	if err := c.SetMetadata(updated); err != nil {
+		return errors.Wrap(err, "setting changeset metadata")
+	}
+	return nil
+}
+
+// loadPullRequestData loads the comments of the pull request and the build
+// statuses of its head commit, which aren't included in the pull request
+// itself.
+func (s BitbucketCloudSource) loadPullRequestData(ctx context.Context, repo *bitbucketcloud.Repo, pr *bitbucketcloud.PullRequest) error {
+	comments, err := s.client.ListPullRequestComments(ctx, repo, pr.ID)
+	if err != nil {
+		return errors.Wrap(err, "loading pr comments")
+	}
+	pr.Comments = comments
+
+	pr.Statuses = nil
+	if pr.Source.Commit != nil && pr.Source.Commit.Hash != "" {
+		statuses, err := s.client.ListCommitStatuses(ctx, repo, pr.Source.Commit.Hash)
+		if err != nil {
+			return errors.Wrap(err, "loading pr build statuses")
+		}
+		pr.Statuses = statuses
+	}
+
+	return nil
+}
@@ -5,14 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/testutil"
@@ -247,3 +252,121 @@ func TestBitbucketCloudSource_Exclude(t *testing.T) {
 		})
 	}
 }
+
+func TestBitbucketCloudSource_Changesets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v interface{}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /2.0/repositories/sglocal/mux/pullrequests":
+			var prs []*bitbucketcloud.PullRequest
+			if strings.Contains(r.URL.Query().Get("q"), `"exists"`) {
+				prs = append(prs, &bitbucketcloud.PullRequest{ID: 2, State: bitbucketcloud.PullRequestStateOpen})
+			}
+			v = map[string]interface{}{"values": prs}
+		case "POST /2.0/repositories/sglocal/mux/pullrequests":
+			var in bitbucketcloud.CreatePullRequestInput
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				t.Error(err)
+			}
+			v = &bitbucketcloud.PullRequest{
+				ID:          1,
+				Title:       in.Title,
+				State:       bitbucketcloud.PullRequestStateOpen,
+				Source:      bitbucketcloud.PullRequestEndpoint{Branch: in.Source.Branch, Commit: &bitbucketcloud.Commit{Hash: "deadbeef"}},
+				Destination: in.Destination,
+			}
+		case "GET /2.0/repositories/sglocal/mux/pullrequests/1":
+			v = &bitbucketcloud.PullRequest{ID: 1, State: bitbucketcloud.PullRequestStateMerged}
+		case "GET /2.0/repositories/sglocal/mux/pullrequests/1/comments",
+			"GET /2.0/repositories/sglocal/mux/pullrequests/2/comments":
+			v = map[string]interface{}{"values": []*bitbucketcloud.Comment{{ID: 10}}}
+		case "GET /2.0/repositories/sglocal/mux/commit/deadbeef/statuses":
+			v = map[string]interface{}{"values": []*bitbucketcloud.BuildStatus{{Key: "ci"}}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v)
+	}))
+	defer srv.Close()
+
+	svc := &ExternalService{
+		ID:   1,
+		Kind: extsvc.KindBitbucketCloud,
+		Config: fmt.Sprintf(
+			`{"url": "https://bitbucket.org", "apiURL": %q, "username": "user", "appPassword": "secret"}`,
+			srv.URL,
+		),
+	}
+	src, err := NewBitbucketCloudSource(svc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &Repo{Metadata: &bitbucketcloud.Repo{FullName: "sglocal/mux", UUID: "{mux}"}}
+
+	t.Run("CreateChangeset", func(t *testing.T) {
+		for _, tc := range []struct {
+			head         string
+			wantExists   bool
+			wantID       string
+			wantStatuses int
+		}{
+			{head: "refs/heads/new", wantExists: false, wantID: "1", wantStatuses: 1},
+			{head: "exists", wantExists: true, wantID: "2", wantStatuses: 0},
+		} {
+			cs := &Changeset{
+				Title:     "title",
+				HeadRef:   tc.head,
+				BaseRef:   "refs/heads/master",
+				Repo:      repo,
+				Changeset: &campaigns.Changeset{},
+			}
+
+			exists, err := src.CreateChangeset(context.Background(), cs)
+			if err != nil {
+				t.Fatalf("%s: %v", tc.head, err)
+			}
+			if exists != tc.wantExists {
+				t.Errorf("%s: have exists=%t, want %t", tc.head, exists, tc.wantExists)
+			}
+			if cs.ExternalID != tc.wantID {
+				t.Errorf("%s: have external ID %q, want %q", tc.head, cs.ExternalID, tc.wantID)
+			}
+			if cs.ExternalServiceType != extsvc.TypeBitbucketCloud {
+				t.Errorf("%s: have external service type %q", tc.head, cs.ExternalServiceType)
+			}
+
+			pr := cs.Changeset.Metadata.(*bitbucketcloud.PullRequest)
+			if len(pr.Comments) != 1 {
+				t.Errorf("%s: have %d comments, want 1", tc.head, len(pr.Comments))
+			}
+			if len(pr.Statuses) != tc.wantStatuses {
+				t.Errorf("%s: have %d statuses, want %d", tc.head, len(pr.Statuses), tc.wantStatuses)
+			}
+		}
+	})
+
+	t.Run("LoadChangesets", func(t *testing.T) {
+		found := &Changeset{Repo: repo, Changeset: &campaigns.Changeset{ExternalID: "1"}}
+		missing := &Changeset{Repo: repo, Changeset: &campaigns.Changeset{ExternalID: "3"}}
+
+		err := src.LoadChangesets(context.Background(), found, missing)
+
+		var notFound ChangesetsNotFoundError
+		if !errors.As(err, &notFound) {
+			t.Fatalf("expected ChangesetsNotFoundError, got %v", err)
+		}
+		if len(notFound.Changesets) != 1 || notFound.Changesets[0] != missing {
+			t.Errorf("wrong changesets not found: %+v", notFound.Changesets)
+		}
+
+		pr, ok := found.Changeset.Metadata.(*bitbucketcloud.PullRequest)
+		if !ok {
+			t.Fatalf("wrong metadata: %T", found.Changeset.Metadata)
+		}
+		if pr.State != bitbucketcloud.PullRequestStateMerged {
+			t.Errorf("wrong state: %s", pr.State)
+		}
+	})
+}
@@ -229,6 +229,54 @@ func (s BitbucketServerSource) UpdateChangeset(ctx context.Context, c *Changeset
 	return nil
 }
 
+// GetRepo returns the Bitbucket Server repository with the given project key
+// and slug ("PROJECT/repo-slug").
+func (s BitbucketServerSource) GetRepo(ctx context.Context, projectKeyAndSlug string) (*Repo, error) {
+	ps := strings.SplitN(projectKeyAndSlug, "/", 2)
+	if len(ps) != 2 {
+		return nil, errors.Errorf("invalid Bitbucket Server repository name %q", projectKeyAndSlug)
+	}
+
+	repo, err := s.client.Repo(ctx, ps[0], ps[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the labels of this repository are looked up, instead of listing
+	// all repositories with the "archived" label like listAllRepos does.
+	isArchived, err := s.repoHasLabel(ctx, ps[0], ps[1], "archived")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list labels of repo")
+	}
+	return s.makeRepo(repo, isArchived), nil
+}
+
+// repoHasLabel returns true if the repository with the given project key and
+// slug has label.
+func (s BitbucketServerSource) repoHasLabel(ctx context.Context, projectKey, repoSlug, label string) (bool, error) {
+	next := &bitbucketserver.PageToken{Limit: 1000}
+	for next.HasMore() {
+		labels, page, err := s.client.RepoLabels(ctx, next, projectKey, repoSlug)
+		if err != nil {
+			// Older versions of bitbucket do not support labels, so their
+			// repos have none.
+			if bitbucketserver.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		for _, l := range labels {
+			if l.Name == label {
+				return true, nil
+			}
+		}
+
+		next = page
+	}
+	return false, nil
+}
+
 // ExternalServices returns a singleton slice containing the external service.
 func (s BitbucketServerSource) ExternalServices() ExternalServices {
 	return ExternalServices{s.svc}
@@ -302,6 +350,13 @@ func (s BitbucketServerSource) makeRepo(repo *bitbucketserver.Repo, isArchived b
 	}
 }
 
+// ExcludesRepo returns true if the configuration of the source excludes the
+// repository, which must have been returned by GetRepo.
+func (s *BitbucketServerSource) ExcludesRepo(r *Repo) bool {
+	br, ok := r.Metadata.(*bitbucketserver.Repo)
+	return ok && s.excludes(br)
+}
+
 func (s *BitbucketServerSource) excludes(r *bitbucketserver.Repo) bool {
 	name := r.Slug
 	if r.Project != nil {
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -72,6 +75,61 @@ func TestBitbucketServerSource_MakeRepo(t *testing.T) {
 	}
 }
 
+func TestBitbucketServerSource_GetRepo(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+"?start="+r.URL.Query().Get("start"))
+		switch r.URL.Path {
+		case "/rest/api/1.0/projects/SG/repos/archived", "/rest/api/1.0/projects/SG/repos/active":
+			slug := path.Base(r.URL.Path)
+			fmt.Fprintf(w, `{"id": 1, "slug": %q, "name": %q, "state": "AVAILABLE", "project": {"key": "SG"}}`, slug, slug)
+		case "/rest/api/1.0/projects/SG/repos/archived/labels":
+			// The labels are paginated.
+			if r.URL.Query().Get("start") == "" {
+				fmt.Fprint(w, `{"values": [{"name": "foo"}], "isLastPage": false, "nextPageStart": 1}`)
+			} else {
+				fmt.Fprint(w, `{"values": [{"name": "archived"}], "isLastPage": true}`)
+			}
+		case "/rest/api/1.0/projects/SG/repos/active/labels":
+			fmt.Fprint(w, `{"values": [{"name": "foo"}], "isLastPage": true}`)
+		default:
+			http.Error(w, `{"errors": [{"message": "not found"}]}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	svc := ExternalService{ID: 1, Kind: extsvc.KindBitbucketServer}
+	s, err := newBitbucketServerSource(&svc, &schema.BitbucketServerConnection{
+		Url:   srv.URL,
+		Token: "secret",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]bool{"archived": true, "active": false} {
+		requests = nil
+		repo, err := s.GetRepo(context.Background(), "SG/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if repo.Archived != want {
+			t.Errorf("got archived %t for %s, want %t", repo.Archived, name, want)
+		}
+		// Only the labels of the repository are requested, not all
+		// repositories with the archived label.
+		for _, r := range requests {
+			if strings.HasPrefix(r, "/rest/api/1.0/labels/") {
+				t.Errorf("unexpected request %s", r)
+			}
+		}
+	}
+
+	if _, err := s.GetRepo(context.Background(), "SG/missing"); !bitbucketserver.IsNotFound(err) {
+		t.Errorf("got error %v, want not found", err)
+	}
+}
+
 func TestBitbucketServerSource_Exclude(t *testing.T) {
 	b, err := ioutil.ReadFile(filepath.Join("testdata", "bitbucketserver-repos.json"))
 	if err != nil {
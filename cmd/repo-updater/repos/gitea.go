@@ -0,0 +1,233 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitea"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// A GiteaSource yields repositories from a single Gitea connection configured
+// in Sourcegraph via the external services configuration.
+type GiteaSource struct {
+	svc             *ExternalService
+	config          *schema.GiteaConnection
+	exclude         excludeFunc
+	excludeArchived bool
+	excludeForks    bool
+	baseURL         *url.URL
+	client          *gitea.Client
+}
+
+// NewGiteaSource returns a new GiteaSource from the given external service.
+func NewGiteaSource(svc *ExternalService, cf *httpcli.Factory) (*GiteaSource, error) {
+	var c schema.GiteaConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, fmt.Errorf("external service id=%d config error: %s", svc.ID, err)
+	}
+	return newGiteaSource(svc, &c, cf)
+}
+
+func newGiteaSource(svc *ExternalService, c *schema.GiteaConnection, cf *httpcli.Factory) (*GiteaSource, error) {
+	baseURL, err := url.Parse(c.Url)
+	if err != nil {
+		return nil, err
+	}
+	baseURL = extsvc.NormalizeBaseURL(baseURL)
+
+	if cf == nil {
+		cf = httpcli.NewExternalHTTPClientFactory()
+	}
+
+	cli, err := cf.Doer()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		eb              excludeBuilder
+		excludeArchived bool
+		excludeForks    bool
+	)
+	for _, r := range c.Exclude {
+		eb.Exact(r.Name)
+		if r.Id != 0 {
+			eb.Exact(strconv.Itoa(r.Id))
+		}
+		eb.Pattern(r.Pattern)
+
+		if r.Archived {
+			excludeArchived = true
+		}
+		if r.Forks {
+			excludeForks = true
+		}
+	}
+	exclude, err := eb.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return &GiteaSource{
+		svc:             svc,
+		config:          c,
+		exclude:         exclude,
+		excludeArchived: excludeArchived,
+		excludeForks:    excludeForks,
+		baseURL:         baseURL,
+		client:          gitea.NewClient(baseURL, c.Token, cli),
+	}, nil
+}
+
+// ListRepos returns all Gitea repositories accessible to all connections configured
+// in Sourcegraph via the external services configuration.
+func (s GiteaSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	seen := make(map[int64]bool)
+	yield := func(repos []*gitea.Repository) {
+		for _, r := range repos {
+			if !seen[r.ID] && !s.excludes(r) {
+				results <- SourceResult{Source: s, Repo: s.makeRepo(r)}
+				seen[r.ID] = true
+			}
+		}
+	}
+
+	for _, org := range s.config.Orgs {
+		org := org
+		s.paginate(ctx, results, "org="+org, func(page int) ([]*gitea.Repository, bool, error) {
+			return s.client.ListOrgRepos(ctx, org, page)
+		}, yield)
+	}
+
+	for _, user := range s.config.Users {
+		user := user
+		s.paginate(ctx, results, "user="+user, func(page int) ([]*gitea.Repository, bool, error) {
+			return s.client.ListUserRepos(ctx, user, page)
+		}, yield)
+	}
+
+	for _, query := range s.config.RepositoryQuery {
+		switch query {
+		case "none":
+			continue
+		case "all":
+			query = ""
+		}
+		query := query
+		s.paginate(ctx, results, "query="+query, func(page int) ([]*gitea.Repository, bool, error) {
+			return s.client.SearchRepos(ctx, query, page)
+		}, yield)
+	}
+}
+
+// paginate calls list with increasing page numbers and yields the listed
+// repositories, until there are no more pages or an error occurs.
+func (s GiteaSource) paginate(
+	ctx context.Context,
+	results chan SourceResult,
+	item string,
+	list func(page int) ([]*gitea.Repository, bool, error),
+	yield func([]*gitea.Repository),
+) {
+	for page, hasNextPage := 1, true; hasNextPage; page++ {
+		var repos []*gitea.Repository
+		var err error
+		if repos, hasNextPage, err = list(page); err != nil {
+			results <- SourceResult{Source: s, Err: errors.Wrapf(err, "gitea.list: item=%q, page=%d", item, page)}
+			return
+		}
+		yield(repos)
+	}
+}
+
+// GetRepo returns the Gitea repository with the given "owner/name".
+func (s GiteaSource) GetRepo(ctx context.Context, nameWithOwner string) (*Repo, error) {
+	parts := strings.SplitN(nameWithOwner, "/", 2)
+	if len(parts) != 2 {
+		return nil, errors.Errorf("invalid Gitea repository name %q, want owner/name", nameWithOwner)
+	}
+
+	r, err := s.client.GetRepo(ctx, parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+	return s.makeRepo(r), nil
+}
+
+// ExternalServices returns a singleton slice containing the external service.
+func (s GiteaSource) ExternalServices() ExternalServices {
+	return ExternalServices{s.svc}
+}
+
+func (s GiteaSource) makeRepo(r *gitea.Repository) *Repo {
+	urn := s.svc.URN()
+	return &Repo{
+		Name: string(reposource.GiteaRepoName(
+			s.config.RepositoryPathPattern,
+			s.baseURL.Hostname(),
+			r.FullName,
+		)),
+		URI: string(reposource.GiteaRepoName(
+			"",
+			s.baseURL.Hostname(),
+			r.FullName,
+		)),
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          strconv.FormatInt(r.ID, 10),
+			ServiceType: extsvc.TypeGitea,
+			ServiceID:   s.baseURL.String(),
+		},
+		Description: r.Description,
+		Fork:        r.Fork,
+		Archived:    r.Archived,
+		Private:     r.Private,
+		Sources: map[string]*SourceInfo{
+			urn: {
+				ID:       urn,
+				CloneURL: s.authenticatedRemoteURL(r),
+			},
+		},
+		Metadata: r,
+	}
+}
+
+// authenticatedRemoteURL returns the repository's Git remote URL with the
+// configured Gitea token inserted in the URL userinfo.
+func (s GiteaSource) authenticatedRemoteURL(r *gitea.Repository) string {
+	if s.config.GitURLType == "ssh" {
+		return r.SSHURL
+	}
+
+	u, err := url.Parse(r.CloneURL)
+	if err != nil || r.CloneURL == "" {
+		u = s.baseURL.ResolveReference(&url.URL{Path: r.FullName + ".git"})
+	}
+	u.User = url.UserPassword(s.config.Token, "x-oauth-basic")
+	return u.String()
+}
+
+func (s GiteaSource) excludes(r *gitea.Repository) bool {
+	if s.exclude(r.FullName) || s.exclude(strconv.FormatInt(r.ID, 10)) {
+		return true
+	}
+
+	if s.excludeArchived && r.Archived {
+		return true
+	}
+
+	if s.excludeForks && r.Fork {
+		return true
+	}
+
+	return false
+}
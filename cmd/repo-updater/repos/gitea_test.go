@@ -0,0 +1,109 @@
+package repos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitea"
+)
+
+func TestGiteaSource_ListRepos(t *testing.T) {
+	var srv *httptest.Server
+	repo := func(id int64, fullName string, fork, archived bool) *gitea.Repository {
+		return &gitea.Repository{
+			ID:       id,
+			FullName: fullName,
+			Fork:     fork,
+			Archived: archived,
+			CloneURL: srv.URL + "/" + fullName + ".git",
+			SSHURL:   "git@gitea.example.com:" + fullName + ".git",
+		}
+	}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			_, _ = w.Write([]byte("[]"))
+			return
+		}
+
+		var v interface{}
+		switch r.URL.Path {
+		case "/api/v1/orgs/sourcegraph/repos":
+			v = []*gitea.Repository{
+				repo(1, "sourcegraph/sourcegraph", false, false),
+				repo(2, "sourcegraph/old", false, true),
+				repo(3, "sourcegraph/secret", false, false),
+			}
+		case "/api/v1/users/alice/repos":
+			v = []*gitea.Repository{
+				repo(4, "alice/sourcegraph", true, false),
+				repo(5, "alice/dotfiles", false, false),
+			}
+		case "/api/v1/repos/search":
+			v = map[string]interface{}{
+				"ok": true,
+				"data": []*gitea.Repository{
+					repo(1, "sourcegraph/sourcegraph", false, false),
+					repo(6, "bob/docs", false, false),
+				},
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v)
+	}))
+	defer srv.Close()
+
+	svc := &ExternalService{
+		ID:   1,
+		Kind: extsvc.KindGitea,
+		Config: fmt.Sprintf(`{
+			"url": %q,
+			"token": "secret",
+			"orgs": ["sourcegraph"],
+			"users": ["alice"],
+			"repositoryQuery": ["docs", "none"],
+			"exclude": [{"archived": true}, {"forks": true}, {"name": "sourcegraph/secret"}, {"id": 5}]
+		}`, srv.URL),
+	}
+	src, err := NewGiteaSource(svc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repos, err := listAll(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.Name+" "+r.ExternalRepo.ID)
+	}
+	sort.Strings(names)
+
+	want := []string{
+		"127.0.0.1/bob/docs 6",
+		"127.0.0.1/sourcegraph/sourcegraph 1",
+	}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, r := range repos {
+		if r.ExternalRepo.ServiceType != extsvc.TypeGitea || r.ExternalRepo.ServiceID != srv.URL+"/" {
+			t.Errorf("have external repo %+v", r.ExternalRepo)
+		}
+		want := "http://secret:x-oauth-basic@" + srv.Listener.Addr().String() + "/" + r.Metadata.(*gitea.Repository).FullName + ".git"
+		if have := r.Sources[svc.URN()].CloneURL; have != want {
+			t.Errorf("have clone URL %q, want %q", have, want)
+		}
+	}
+}
@@ -317,6 +317,13 @@ func (s *GithubSource) authenticatedRemoteURL(repo *github.Repository) string {
 	return u.String()
 }
 
+// ExcludesRepo returns true if the configuration of the source excludes the
+// repository, which must have been returned by GetRepo.
+func (s *GithubSource) ExcludesRepo(r *Repo) bool {
+	gr, ok := r.Metadata.(*github.Repository)
+	return ok && s.excludes(gr)
+}
+
 func (s *GithubSource) excludes(r *github.Repository) bool {
 	if s.exclude(r.NameWithOwner) || s.exclude(r.ID) {
 		return true
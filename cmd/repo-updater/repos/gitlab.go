@@ -161,6 +161,13 @@ func (s *GitLabSource) authenticatedRemoteURL(proj *gitlab.Project) string {
 	return u.String()
 }
 
+// ExcludesRepo returns true if the configuration of the source excludes the
+// repository, which must have been returned by GetRepo.
+func (s *GitLabSource) ExcludesRepo(r *Repo) bool {
+	p, ok := r.Metadata.(*gitlab.Project)
+	return ok && s.excludes(p)
+}
+
 func (s *GitLabSource) excludes(p *gitlab.Project) bool {
 	return s.exclude(p.PathWithNamespace) || s.exclude(strconv.Itoa(p.ID))
 }
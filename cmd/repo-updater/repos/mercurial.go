@@ -0,0 +1,90 @@
+package repos
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/jsonc"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// A MercurialSource yields repositories from a single Mercurial connection
+// configured in Sourcegraph via the external services configuration.
+type MercurialSource struct {
+	svc  *ExternalService
+	conn *schema.MercurialConnection
+	base *url.URL
+}
+
+// NewMercurialSource returns a new MercurialSource from the given external
+// service.
+func NewMercurialSource(svc *ExternalService, cf *httpcli.Factory) (*MercurialSource, error) {
+	var c schema.MercurialConnection
+	if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+		return nil, errors.Wrapf(err, "external service id=%d config error", svc.ID)
+	}
+	base, err := url.Parse(c.Url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "external service id=%d config error", svc.ID)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	return &MercurialSource{svc: svc, conn: &c, base: base}, nil
+}
+
+// ListRepos returns all Mercurial repositories configured in the connection.
+func (s MercurialSource) ListRepos(ctx context.Context, results chan SourceResult) {
+	urn := s.svc.URN()
+	for _, repo := range s.conn.Repos {
+		r, err := s.makeRepo(urn, repo)
+		if err != nil {
+			results <- SourceResult{Source: s, Err: err}
+			return
+		}
+		results <- SourceResult{Source: s, Repo: r}
+	}
+}
+
+// ExternalServices returns a singleton slice containing the external service.
+func (s MercurialSource) ExternalServices() ExternalServices {
+	return ExternalServices{s.svc}
+}
+
+func (s MercurialSource) makeRepo(urn, repo string) (*Repo, error) {
+	u, err := s.base.Parse(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// The host and path, without credentials.
+	base := url.URL{Host: s.base.Host, Path: s.base.Path}
+	relativePath := strings.TrimPrefix(u.Path, s.base.Path)
+	name := reposource.OtherRepoName(s.conn.RepositoryPathPattern, base.String(), relativePath)
+	uri := reposource.OtherRepoName("", base.String(), relativePath)
+
+	return &Repo{
+		Name: name,
+		URI:  uri,
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          path.Clean("/" + relativePath),
+			ServiceType: extsvc.TypeMercurial,
+			ServiceID:   extsvc.NormalizeBaseURL(&url.URL{Scheme: s.base.Scheme, Host: s.base.Host, Path: s.base.Path}).String(),
+		},
+		Sources: map[string]*SourceInfo{
+			urn: {
+				ID: urn,
+				// gitserver converts repositories with URLs of the form
+				// hg::<url> with git-remote-hg.
+				CloneURL: "hg::" + u.String(),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,63 @@
+package repos
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+)
+
+func TestMercurialSource_ListRepos(t *testing.T) {
+	svc := &ExternalService{
+		ID:   1,
+		Kind: extsvc.KindMercurial,
+		Config: `{
+			"url": "https://user:pw@hg.example.com/repos",
+			"repos": ["core", "tools/build"]
+		}`,
+	}
+	src, err := NewMercurialSource(svc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repos, err := listAll(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*Repo{{
+		Name: "hg.example.com/repos/core",
+		URI:  "hg.example.com/repos/core",
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          "/core",
+			ServiceType: extsvc.TypeMercurial,
+			ServiceID:   "https://hg.example.com/repos/",
+		},
+		Sources: map[string]*SourceInfo{
+			"extsvc:mercurial:1": {
+				ID:       "extsvc:mercurial:1",
+				CloneURL: "hg::https://user:pw@hg.example.com/repos/core",
+			},
+		},
+	}, {
+		Name: "hg.example.com/repos/tools/build",
+		URI:  "hg.example.com/repos/tools/build",
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          "/tools/build",
+			ServiceType: extsvc.TypeMercurial,
+			ServiceID:   "https://hg.example.com/repos/",
+		},
+		Sources: map[string]*SourceInfo{
+			"extsvc:mercurial:1": {
+				ID:       "extsvc:mercurial:1",
+				CloneURL: "hg::https://user:pw@hg.example.com/repos/tools/build",
+			},
+		},
+	}}
+	if diff := cmp.Diff(want, repos); diff != "" {
+		t.Fatalf("unexpected repos (-want +got):\n%s", diff)
+	}
+}
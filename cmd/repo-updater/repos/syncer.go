@@ -164,7 +164,8 @@ func (s *Syncer) Sync(ctx context.Context) (err error) {
 
 // SyncSubset runs the syncer on a subset of the stored repositories. It will
 // only sync the repositories with the same name or external service spec as
-// sourcedSubset repositories.
+// sourcedSubset repositories. Sourced repositories without sources are no
+// longer yielded by any external service, so they are deleted.
 func (s *Syncer) SyncSubset(ctx context.Context, sourcedSubset ...*Repo) (err error) {
 	var diff Diff
 
@@ -223,7 +224,14 @@ func (s *Syncer) syncSubset(ctx context.Context, insertOnly bool, sourcedSubset
 	// NewDiff modifies the stored slice so we clone it before passing it
 	storedCopy := storedSubset.Clone()
 
-	diff = NewDiff(sourcedSubset, storedSubset)
+	sourced := make([]*Repo, 0, len(sourcedSubset))
+	for _, r := range sourcedSubset {
+		if len(r.Sources) > 0 {
+			sourced = append(sourced, r)
+		}
+	}
+
+	diff = NewDiff(sourced, storedSubset)
 	upserts := s.upserts(diff)
 
 	if err = store.UpsertRepos(ctx, upserts...); err != nil {
@@ -483,14 +483,22 @@ func (e ExternalService) schema() string {
 	switch strings.ToUpper(e.Kind) {
 	case extsvc.KindAWSCodeCommit:
 		return schema.AWSCodeCommitSchemaJSON
+	case extsvc.KindAzureDevOps:
+		return schema.AzureDevOpsSchemaJSON
 	case extsvc.KindBitbucketServer:
 		return schema.BitbucketServerSchemaJSON
+	case extsvc.KindGitea:
+		return schema.GiteaSchemaJSON
 	case extsvc.KindGitHub:
 		return schema.GitHubSchemaJSON
 	case extsvc.KindGitLab:
 		return schema.GitLabSchemaJSON
 	case extsvc.KindGitolite:
 		return schema.GitoliteSchemaJSON
+	case extsvc.KindMercurial:
+		return schema.MercurialSchemaJSON
+	case extsvc.KindPerforce:
+		return schema.PerforceSchemaJSON
 	case extsvc.KindPhabricator:
 		return schema.PhabricatorSchemaJSON
 	case extsvc.KindOther:
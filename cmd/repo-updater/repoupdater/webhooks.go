@@ -0,0 +1,407 @@
+package repoupdater
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	gh "github.com/google/go-github/v28/github"
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// webhookAction is what a webhook event did to a repository.
+type webhookAction int
+
+const (
+	// webhookIgnored events don't change repositories.
+	webhookIgnored webhookAction = iota
+	// webhookPush events changed the refs of the repository.
+	webhookPush
+	// webhookCreated events created the repository.
+	webhookCreated
+	// webhookChanged events changed the metadata of the repository, for
+	// example its name.
+	webhookChanged
+	// webhookDeleted events deleted the repository.
+	webhookDeleted
+)
+
+// webhookEvent is a webhook event of a code host, normalized to what
+// repo-updater does with it.
+type webhookEvent struct {
+	action webhookAction
+	// externalID is the ID of the repository on the code host, which is the
+	// ID of its api.ExternalRepoSpec.
+	externalID string
+	// name is the name of created and changed repositories after the event,
+	// in the form the GetRepo method of their source takes.
+	name string
+}
+
+// repoGetter is a Source which can get a single repository from the code host.
+type repoGetter interface {
+	GetRepo(ctx context.Context, name string) (*repos.Repo, error)
+	// ExcludesRepo returns true if the configuration of the source excludes
+	// a repository returned by GetRepo.
+	ExcludesRepo(r *repos.Repo) bool
+}
+
+// newRepoGetter returns the source of the repositories of svc, which webhooks
+// of created and changed repositories get them from. It is replaced in tests.
+var newRepoGetter = func(svc *repos.ExternalService) (repoGetter, error) {
+	src, err := repos.NewSource(svc, httpcli.NewExternalHTTPClientFactory())
+	if err != nil {
+		return nil, err
+	}
+	g, ok := src.(repoGetter)
+	if !ok {
+		return nil, errors.Errorf("external service kind %q does not support getting repositories", svc.Kind)
+	}
+	return g, nil
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	var req protocol.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := s.webhook(r.Context(), &req)
+	if err != nil {
+		respond(w, http.StatusInternalServerError, err)
+		return
+	}
+	respond(w, http.StatusOK, result)
+}
+
+// webhook handles a webhook event of a code host. Pushes schedule an update of
+// the repository with high priority, and repositories which were created,
+// renamed or deleted are synced with the code host.
+func (s *Server) webhook(ctx context.Context, req *protocol.WebhookRequest) (*protocol.WebhookResult, error) {
+	svc, err := s.webhookExternalService(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if svc == nil {
+		return &protocol.WebhookResult{ErrorUnauthorized: true}, nil
+	}
+
+	ev, err := parseWebhookEvent(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s webhook event %q", req.Kind, req.Event)
+	}
+
+	var result protocol.WebhookResult
+	switch ev.action {
+	case webhookIgnored:
+		return &result, nil
+	case webhookCreated:
+		sourced, err := webhookSourcedRepo(ctx, svc, ev.name)
+		if err != nil {
+			return nil, err
+		}
+		if sourced == nil {
+			log15.Debug("webhook event for repository which is excluded", "kind", req.Kind, "event", req.Event, "name", ev.name)
+			return &result, nil
+		}
+		// A fork or transfer may already be mirrored by other external
+		// services.
+		stored, err := s.webhookRepo(ctx, svc, sourced.ExternalRepo.ID)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			addOtherSources(sourced, stored)
+		}
+		if err := s.Syncer.SyncSubset(ctx, sourced); err != nil {
+			return nil, err
+		}
+		result.Synced = append(result.Synced, api.RepoName(sourced.Name))
+		return &result, nil
+	}
+
+	repo, err := s.webhookRepo(ctx, svc, ev.externalID)
+	if err != nil {
+		return nil, err
+	}
+	if repo == nil {
+		log15.Debug("webhook event for repository which is not mirrored", "kind", req.Kind, "event", req.Event, "externalID", ev.externalID)
+		return &result, nil
+	}
+
+	switch ev.action {
+	case webhookPush:
+		var url string
+		if si := repo.Sources[svc.URN()]; si != nil {
+			url = si.CloneURL
+		} else if urls := repo.CloneURLs(); len(urls) > 0 {
+			url = urls[0]
+		}
+		s.Scheduler.UpdateOnce(repo.ID, api.RepoName(repo.Name), url)
+		result.Updated = append(result.Updated, api.RepoName(repo.Name))
+
+	case webhookChanged:
+		sourced, err := webhookSourcedRepo(ctx, svc, ev.name)
+		if err != nil {
+			return nil, err
+		}
+		if sourced == nil {
+			// The change excludes the repository from svc, for example
+			// because it was archived.
+			sourced = repo.Clone()
+			delete(sourced.Sources, svc.URN())
+		} else {
+			// The repository may also be mirrored by other external
+			// services.
+			addOtherSources(sourced, repo)
+		}
+		if err := s.Syncer.SyncSubset(ctx, sourced); err != nil {
+			return nil, err
+		}
+		result.Synced = append(result.Synced, api.RepoName(sourced.Name))
+
+	case webhookDeleted:
+		// The repository no longer exists on the code host, whichever
+		// external services mirrored it.
+		sourced := repo.Clone()
+		sourced.Sources = map[string]*repos.SourceInfo{}
+		if err := s.Syncer.SyncSubset(ctx, sourced); err != nil {
+			return nil, err
+		}
+		result.Synced = append(result.Synced, api.RepoName(repo.Name))
+	}
+
+	return &result, nil
+}
+
+// webhookSourcedRepo returns the repository with the name from the code host
+// of svc, or nil if the configuration of svc excludes it.
+func webhookSourcedRepo(ctx context.Context, svc *repos.ExternalService, name string) (*repos.Repo, error) {
+	g, err := newRepoGetter(svc)
+	if err != nil {
+		return nil, err
+	}
+	sourced, err := g.GetRepo(ctx, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting repository %q", name)
+	}
+	if g.ExcludesRepo(sourced) {
+		return nil, nil
+	}
+	return sourced, nil
+}
+
+// addOtherSources adds the sources of stored which sourced doesn't have to
+// sourced.
+func addOtherSources(sourced, stored *repos.Repo) {
+	for urn, si := range stored.Sources {
+		if _, ok := sourced.Sources[urn]; !ok {
+			sourced.Sources[urn] = si
+		}
+	}
+}
+
+// webhookExternalService returns the external service whose webhook secret
+// authenticates the payload of req, or nil if there is none.
+func (s *Server) webhookExternalService(ctx context.Context, req *protocol.WebhookRequest) (*repos.ExternalService, error) {
+	args := repos.StoreListExternalServicesArgs{Kinds: []string{req.Kind}}
+	if req.ExternalServiceID != 0 {
+		args.IDs = []int64{req.ExternalServiceID}
+	}
+	es, err := s.Store.ListExternalServices(ctx, args)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing external services")
+	}
+
+	// 🚨 SECURITY: Try to authenticate the request with any of the secrets
+	// of the external services. Since there are usually few of them, it's ok
+	// for this to be linear.
+	for _, e := range es {
+		c, err := e.Configuration()
+		if err != nil {
+			log15.Warn("webhook: invalid external service config", "id", e.ID, "error", err)
+			continue
+		}
+
+		var secrets []string
+		switch c := c.(type) {
+		case *schema.GitHubConnection:
+			for _, hook := range c.Webhooks {
+				secrets = append(secrets, hook.Secret)
+			}
+		case *schema.GitLabConnection:
+			for _, hook := range c.Webhooks {
+				secrets = append(secrets, hook.Secret)
+			}
+		case *schema.BitbucketServerConnection:
+			secrets = append(secrets, c.WebhookSecret())
+		}
+
+		for _, secret := range secrets {
+			if validWebhookSignature(req, secret) {
+				return e, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// validWebhookSignature returns true if the signature of req was made with
+// secret. An empty secret never succeeds.
+func validWebhookSignature(req *protocol.WebhookRequest, secret string) bool {
+	if secret == "" || req.Signature == "" {
+		return false
+	}
+	if req.Kind == extsvc.KindGitLab {
+		// GitLab sends the secret token itself.
+		return subtle.ConstantTimeCompare([]byte(req.Signature), []byte(secret)) == 1
+	}
+	// GitHub and Bitbucket Server sign the payload with an HMAC.
+	return gh.ValidateSignature(req.Signature, req.Payload, []byte(secret)) == nil
+}
+
+// webhookRepo returns the stored repository of svc's code host with the
+// external ID, or nil if it is not mirrored.
+func (s *Server) webhookRepo(ctx context.Context, svc *repos.ExternalService, externalID string) (*repos.Repo, error) {
+	baseURL, err := extsvc.ExtractBaseURL(svc.Kind, svc.Config)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := s.Store.ListRepos(ctx, repos.StoreListReposArgs{
+		ExternalRepos: []api.ExternalRepoSpec{{
+			ID:          externalID,
+			ServiceType: extsvc.KindToType(svc.Kind),
+			ServiceID:   baseURL.String(),
+		}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "store.list-repos")
+	}
+	if len(rs) == 0 {
+		return nil, nil
+	}
+	return rs[0], nil
+}
+
+// parseWebhookEvent parses the payload of req into the event.
+func parseWebhookEvent(req *protocol.WebhookRequest) (webhookEvent, error) {
+	switch req.Kind {
+	case extsvc.KindGitHub:
+		return parseGitHubWebhookEvent(req)
+	case extsvc.KindGitLab:
+		return parseGitLabWebhookEvent(req)
+	case extsvc.KindBitbucketServer:
+		return parseBitbucketServerWebhookEvent(req)
+	default:
+		return webhookEvent{}, errors.Errorf("webhooks of external service kind %q are not supported", req.Kind)
+	}
+}
+
+func parseGitHubWebhookEvent(req *protocol.WebhookRequest) (webhookEvent, error) {
+	e, err := gh.ParseWebHook(req.Event, req.Payload)
+	if err != nil {
+		return webhookEvent{}, err
+	}
+
+	switch e := e.(type) {
+	case *gh.PushEvent:
+		return webhookEvent{action: webhookPush, externalID: e.GetRepo().GetNodeID()}, nil
+	case *gh.RepositoryEvent:
+		repo := e.GetRepo()
+		ev := webhookEvent{externalID: repo.GetNodeID(), name: repo.GetFullName()}
+		switch e.GetAction() {
+		case "created":
+			ev.action = webhookCreated
+		case "deleted":
+			ev.action = webhookDeleted
+		case "renamed", "transferred", "edited", "archived", "unarchived", "publicized", "privatized":
+			ev.action = webhookChanged
+		}
+		return ev, nil
+	}
+	return webhookEvent{}, nil
+}
+
+func parseGitLabWebhookEvent(req *protocol.WebhookRequest) (webhookEvent, error) {
+	// Project webhooks have an object_kind, system hooks of projects have an
+	// event_name.
+	var e struct {
+		ObjectKind        string `json:"object_kind"`
+		EventName         string `json:"event_name"`
+		ProjectID         int    `json:"project_id"`
+		PathWithNamespace string `json:"path_with_namespace"`
+	}
+	if err := json.Unmarshal(req.Payload, &e); err != nil {
+		return webhookEvent{}, err
+	}
+
+	ev := webhookEvent{externalID: strconv.Itoa(e.ProjectID), name: e.PathWithNamespace}
+	switch {
+	case e.ObjectKind == "push", e.ObjectKind == "tag_push", e.EventName == "repository_update":
+		ev.action = webhookPush
+	case e.EventName == "project_create":
+		ev.action = webhookCreated
+	case e.EventName == "project_destroy":
+		ev.action = webhookDeleted
+	case e.EventName == "project_rename", e.EventName == "project_transfer", e.EventName == "project_update":
+		ev.action = webhookChanged
+	}
+	return ev, nil
+}
+
+func parseBitbucketServerWebhookEvent(req *protocol.WebhookRequest) (webhookEvent, error) {
+	switch req.Event {
+	case "repo:refs_changed":
+		var e struct {
+			Repository bitbucketserver.Repo `json:"repository"`
+		}
+		if err := json.Unmarshal(req.Payload, &e); err != nil {
+			return webhookEvent{}, err
+		}
+		return webhookEvent{action: webhookPush, externalID: strconv.Itoa(e.Repository.ID)}, nil
+
+	case "repo:modified":
+		var e struct {
+			New bitbucketserver.Repo `json:"new"`
+		}
+		if err := json.Unmarshal(req.Payload, &e); err != nil {
+			return webhookEvent{}, err
+		}
+		if e.New.Project == nil {
+			return webhookEvent{}, errors.New("repository without project")
+		}
+		return webhookEvent{
+			action:     webhookChanged,
+			externalID: strconv.Itoa(e.New.ID),
+			name:       e.New.Project.Key + "/" + e.New.Slug,
+		}, nil
+
+	case "repo:forked":
+		var e struct {
+			Repository bitbucketserver.Repo `json:"repository"`
+		}
+		if err := json.Unmarshal(req.Payload, &e); err != nil {
+			return webhookEvent{}, err
+		}
+		if e.Repository.Project == nil {
+			return webhookEvent{}, errors.New("repository without project")
+		}
+		return webhookEvent{
+			action:     webhookCreated,
+			externalID: strconv.Itoa(e.Repository.ID),
+			name:       e.Repository.Project.Key + "/" + e.Repository.Slug,
+		}, nil
+	}
+	return webhookEvent{}, nil
+}
@@ -0,0 +1,441 @@
+package repoupdater
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater/protocol"
+)
+
+func TestServer_webhook(t *testing.T) {
+	github := &repos.ExternalService{
+		ID:     1,
+		Kind:   extsvc.KindGitHub,
+		Config: `{"url": "https://github.com", "token": "abc", "webhooks": [{"org": "sourcegraph", "secret": "hunter2"}]}`,
+	}
+	gitlab := &repos.ExternalService{
+		ID:     2,
+		Kind:   extsvc.KindGitLab,
+		Config: `{"url": "https://gitlab.com", "token": "abc", "projectQuery": ["none"], "webhooks": [{"secret": "hunter3"}]}`,
+	}
+	githubRepo := &repos.Repo{
+		ID:   10,
+		Name: "github.com/sourcegraph/sourcegraph",
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          "MDEwOlJlcG9zaXRvcnk0MTI4ODcwOA==",
+			ServiceType: extsvc.TypeGitHub,
+			ServiceID:   "https://github.com/",
+		},
+		Sources: map[string]*repos.SourceInfo{
+			github.URN(): {ID: github.URN(), CloneURL: "https://abc@github.com/sourcegraph/sourcegraph"},
+		},
+	}
+	gitlabRepo := &repos.Repo{
+		ID:   11,
+		Name: "gitlab.com/gitlab-org/gitaly",
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          "2009901",
+			ServiceType: extsvc.TypeGitLab,
+			ServiceID:   "https://gitlab.com/",
+		},
+		Sources: map[string]*repos.SourceInfo{
+			gitlab.URN(): {ID: gitlab.URN(), CloneURL: "https://abc@gitlab.com/gitlab-org/gitaly"},
+		},
+	}
+
+	githubPush := []byte(`{"ref": "refs/heads/master", "repository": {"node_id": "MDEwOlJlcG9zaXRvcnk0MTI4ODcwOA==", "full_name": "sourcegraph/sourcegraph"}}`)
+	gitlabPush := []byte(`{"object_kind": "push", "project_id": 2009901}`)
+
+	for _, tc := range []struct {
+		name    string
+		req     protocol.WebhookRequest
+		result  *protocol.WebhookResult
+		updated map[api.RepoName]string
+	}{
+		{
+			name: "github push",
+			req: protocol.WebhookRequest{
+				Kind:      extsvc.KindGitHub,
+				Event:     "push",
+				Signature: sign("hunter2", githubPush),
+				Payload:   githubPush,
+			},
+			result:  &protocol.WebhookResult{Updated: []api.RepoName{"github.com/sourcegraph/sourcegraph"}},
+			updated: map[api.RepoName]string{"github.com/sourcegraph/sourcegraph": "https://abc@github.com/sourcegraph/sourcegraph"},
+		},
+		{
+			name: "github push with wrong secret",
+			req: protocol.WebhookRequest{
+				Kind:      extsvc.KindGitHub,
+				Event:     "push",
+				Signature: sign("wrong", githubPush),
+				Payload:   githubPush,
+			},
+			result: &protocol.WebhookResult{ErrorUnauthorized: true},
+		},
+		{
+			name: "github push to other external service",
+			req: protocol.WebhookRequest{
+				Kind:              extsvc.KindGitHub,
+				ExternalServiceID: 3,
+				Event:             "push",
+				Signature:         sign("hunter2", githubPush),
+				Payload:           githubPush,
+			},
+			result: &protocol.WebhookResult{ErrorUnauthorized: true},
+		},
+		{
+			name: "github push to repo which is not mirrored",
+			req: protocol.WebhookRequest{
+				Kind:      extsvc.KindGitHub,
+				Event:     "push",
+				Signature: sign("hunter2", []byte(`{"repository": {"node_id": "other"}}`)),
+				Payload:   []byte(`{"repository": {"node_id": "other"}}`),
+			},
+			result: &protocol.WebhookResult{},
+		},
+		{
+			name: "gitlab push",
+			req: protocol.WebhookRequest{
+				Kind:              extsvc.KindGitLab,
+				ExternalServiceID: 2,
+				Event:             "Push Hook",
+				Signature:         "hunter3",
+				Payload:           gitlabPush,
+			},
+			result:  &protocol.WebhookResult{Updated: []api.RepoName{"gitlab.com/gitlab-org/gitaly"}},
+			updated: map[api.RepoName]string{"gitlab.com/gitlab-org/gitaly": "https://abc@gitlab.com/gitlab-org/gitaly"},
+		},
+		{
+			name: "gitlab push with wrong token",
+			req: protocol.WebhookRequest{
+				Kind:      extsvc.KindGitLab,
+				Event:     "Push Hook",
+				Signature: "hunter2",
+				Payload:   gitlabPush,
+			},
+			result: &protocol.WebhookResult{ErrorUnauthorized: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sched := &recordingScheduler{}
+			s := &Server{
+				Store: &webhookStore{
+					svcs:  []*repos.ExternalService{github, gitlab},
+					repos: []*repos.Repo{githubRepo, gitlabRepo},
+				},
+				Scheduler: sched,
+			}
+
+			result, err := s.webhook(context.Background(), &tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.result, result); diff != "" {
+				t.Errorf("result:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.updated, sched.updated); diff != "" {
+				t.Errorf("updated:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServer_webhookSync(t *testing.T) {
+	github := &repos.ExternalService{
+		ID:     1,
+		Kind:   extsvc.KindGitHub,
+		Config: `{"url": "https://github.com", "token": "abc", "webhooks": [{"org": "sourcegraph", "secret": "hunter2"}]}`,
+	}
+	stored := &repos.Repo{
+		ID:   10,
+		Name: "github.com/sourcegraph/old",
+		ExternalRepo: api.ExternalRepoSpec{
+			ID:          "abc",
+			ServiceType: extsvc.TypeGitHub,
+			ServiceID:   "https://github.com/",
+		},
+		Sources: map[string]*repos.SourceInfo{
+			github.URN(): {ID: github.URN(), CloneURL: "https://abc@github.com/sourcegraph/old"},
+		},
+	}
+	sourced := func(name, externalID string) *repos.Repo {
+		return &repos.Repo{
+			Name: "github.com/" + name,
+			ExternalRepo: api.ExternalRepoSpec{
+				ID:          externalID,
+				ServiceType: extsvc.TypeGitHub,
+				ServiceID:   "https://github.com/",
+			},
+			Sources: map[string]*repos.SourceInfo{
+				github.URN(): {ID: github.URN(), CloneURL: "https://abc@github.com/" + name},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		payload  string
+		sourced  *repos.Repo
+		excluded bool
+		result   *protocol.WebhookResult
+		upserted []string
+		deleted  bool
+	}{
+		{
+			name:     "created",
+			payload:  `{"action": "created", "repository": {"node_id": "def", "full_name": "sourcegraph/new"}}`,
+			sourced:  sourced("sourcegraph/new", "def"),
+			result:   &protocol.WebhookResult{Synced: []api.RepoName{"github.com/sourcegraph/new"}},
+			upserted: []string{"github.com/sourcegraph/new"},
+		},
+		{
+			name:     "created and excluded",
+			payload:  `{"action": "created", "repository": {"node_id": "def", "full_name": "sourcegraph/new"}}`,
+			sourced:  sourced("sourcegraph/new", "def"),
+			excluded: true,
+			result:   &protocol.WebhookResult{},
+		},
+		{
+			name:     "renamed",
+			payload:  `{"action": "renamed", "repository": {"node_id": "abc", "full_name": "sourcegraph/renamed"}}`,
+			sourced:  sourced("sourcegraph/renamed", "abc"),
+			result:   &protocol.WebhookResult{Synced: []api.RepoName{"github.com/sourcegraph/renamed"}},
+			upserted: []string{"github.com/sourcegraph/renamed"},
+		},
+		{
+			name:     "archived and excluded",
+			payload:  `{"action": "archived", "repository": {"node_id": "abc", "full_name": "sourcegraph/old"}}`,
+			sourced:  sourced("sourcegraph/old", "abc"),
+			excluded: true,
+			result:   &protocol.WebhookResult{Synced: []api.RepoName{"github.com/sourcegraph/old"}},
+			upserted: []string{"github.com/sourcegraph/old"},
+			deleted:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func(orig func(*repos.ExternalService) (repoGetter, error)) { newRepoGetter = orig }(newRepoGetter)
+			newRepoGetter = func(*repos.ExternalService) (repoGetter, error) {
+				return &fakeWebhookRepoGetter{repo: tc.sourced, excluded: tc.excluded}, nil
+			}
+
+			store := &webhookStore{
+				svcs:  []*repos.ExternalService{github},
+				repos: []*repos.Repo{stored},
+			}
+			s := &Server{
+				Store:  store,
+				Syncer: &repos.Syncer{Store: store, Now: time.Now},
+			}
+
+			payload := []byte(tc.payload)
+			result, err := s.webhook(context.Background(), &protocol.WebhookRequest{
+				Kind:      extsvc.KindGitHub,
+				Event:     "repository",
+				Signature: sign("hunter2", payload),
+				Payload:   payload,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.result, result); diff != "" {
+				t.Errorf("result:\n%s", diff)
+			}
+
+			var upserted []string
+			for _, r := range store.upserted {
+				upserted = append(upserted, r.Name)
+				if deleted := !r.DeletedAt.IsZero(); deleted != tc.deleted {
+					t.Errorf("repo %s: have deleted %t, want %t", r.Name, deleted, tc.deleted)
+				}
+			}
+			if diff := cmp.Diff(tc.upserted, upserted); diff != "" {
+				t.Errorf("upserted:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseWebhookEvent(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		req  protocol.WebhookRequest
+		want webhookEvent
+	}{
+		{
+			name: "github repository renamed",
+			req: protocol.WebhookRequest{
+				Kind:    extsvc.KindGitHub,
+				Event:   "repository",
+				Payload: []byte(`{"action": "renamed", "repository": {"node_id": "abc", "full_name": "sourcegraph/new"}}`),
+			},
+			want: webhookEvent{action: webhookChanged, externalID: "abc", name: "sourcegraph/new"},
+		},
+		{
+			name: "github repository deleted",
+			req: protocol.WebhookRequest{
+				Kind:    extsvc.KindGitHub,
+				Event:   "repository",
+				Payload: []byte(`{"action": "deleted", "repository": {"node_id": "abc", "full_name": "sourcegraph/old"}}`),
+			},
+			want: webhookEvent{action: webhookDeleted, externalID: "abc", name: "sourcegraph/old"},
+		},
+		{
+			name: "github other event",
+			req: protocol.WebhookRequest{
+				Kind:    extsvc.KindGitHub,
+				Event:   "issues",
+				Payload: []byte(`{"action": "opened"}`),
+			},
+			want: webhookEvent{action: webhookIgnored},
+		},
+		{
+			name: "gitlab project created",
+			req: protocol.WebhookRequest{
+				Kind:    extsvc.KindGitLab,
+				Event:   "System Hook",
+				Payload: []byte(`{"event_name": "project_create", "project_id": 74, "path_with_namespace": "jsmith/storecloud"}`),
+			},
+			want: webhookEvent{action: webhookCreated, externalID: "74", name: "jsmith/storecloud"},
+		},
+		{
+			name: "gitlab project renamed",
+			req: protocol.WebhookRequest{
+				Kind:    extsvc.KindGitLab,
+				Event:   "System Hook",
+				Payload: []byte(`{"event_name": "project_rename", "project_id": 74, "path_with_namespace": "jsmith/underscore"}`),
+			},
+			want: webhookEvent{action: webhookChanged, externalID: "74", name: "jsmith/underscore"},
+		},
+		{
+			name: "gitlab project destroyed",
+			req: protocol.WebhookRequest{
+				Kind:    extsvc.KindGitLab,
+				Event:   "System Hook",
+				Payload: []byte(`{"event_name": "project_destroy", "project_id": 73, "path_with_namespace": "jsmith/underscore"}`),
+			},
+			want: webhookEvent{action: webhookDeleted, externalID: "73", name: "jsmith/underscore"},
+		},
+		{
+			name: "bitbucket server refs changed",
+			req: protocol.WebhookRequest{
+				Kind:    extsvc.KindBitbucketServer,
+				Event:   "repo:refs_changed",
+				Payload: []byte(`{"repository": {"id": 84, "slug": "repo", "project": {"key": "PRJ"}}}`),
+			},
+			want: webhookEvent{action: webhookPush, externalID: "84"},
+		},
+		{
+			name: "bitbucket server repo modified",
+			req: protocol.WebhookRequest{
+				Kind:    extsvc.KindBitbucketServer,
+				Event:   "repo:modified",
+				Payload: []byte(`{"old": {"id": 84, "slug": "repo", "project": {"key": "PRJ"}}, "new": {"id": 84, "slug": "repo2", "project": {"key": "PRJ"}}}`),
+			},
+			want: webhookEvent{action: webhookChanged, externalID: "84", name: "PRJ/repo2"},
+		},
+		{
+			name: "bitbucket server repo forked",
+			req: protocol.WebhookRequest{
+				Kind:    extsvc.KindBitbucketServer,
+				Event:   "repo:forked",
+				Payload: []byte(`{"repository": {"id": 85, "slug": "repo", "project": {"key": "~JSMITH"}}}`),
+			},
+			want: webhookEvent{action: webhookCreated, externalID: "85", name: "~JSMITH/repo"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			have, err := parseWebhookEvent(&tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != tc.want {
+				t.Errorf("have %+v, want %+v", have, tc.want)
+			}
+		})
+	}
+}
+
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhookStore struct {
+	repos.Store
+
+	svcs     []*repos.ExternalService
+	repos    []*repos.Repo
+	upserted []*repos.Repo
+}
+
+func (s *webhookStore) ListExternalServices(ctx context.Context, args repos.StoreListExternalServicesArgs) ([]*repos.ExternalService, error) {
+	var es []*repos.ExternalService
+	for _, e := range s.svcs {
+		if len(args.IDs) > 0 && args.IDs[0] != e.ID {
+			continue
+		}
+		if len(args.Kinds) > 0 && args.Kinds[0] != e.Kind {
+			continue
+		}
+		es = append(es, e)
+	}
+	return es, nil
+}
+
+func (s *webhookStore) ListRepos(ctx context.Context, args repos.StoreListReposArgs) ([]*repos.Repo, error) {
+	var rs []*repos.Repo
+	for _, r := range s.repos {
+		for _, spec := range args.ExternalRepos {
+			if r.ExternalRepo == spec {
+				rs = append(rs, r.Clone())
+			}
+		}
+	}
+	return rs, nil
+}
+
+func (s *webhookStore) UpsertRepos(ctx context.Context, rs ...*repos.Repo) error {
+	for _, r := range rs {
+		s.upserted = append(s.upserted, r.Clone())
+	}
+	return nil
+}
+
+func (s *webhookStore) UpsertSources(ctx context.Context, inserts, updates, deletes map[api.RepoID][]repos.SourceInfo) error {
+	return nil
+}
+
+type fakeWebhookRepoGetter struct {
+	repo     *repos.Repo
+	excluded bool
+}
+
+func (g *fakeWebhookRepoGetter) GetRepo(context.Context, string) (*repos.Repo, error) {
+	return g.repo.Clone(), nil
+}
+
+func (g *fakeWebhookRepoGetter) ExcludesRepo(*repos.Repo) bool {
+	return g.excluded
+}
+
+type recordingScheduler struct {
+	fakeScheduler
+	updated map[api.RepoName]string
+}
+
+func (s *recordingScheduler) UpdateOnce(_ api.RepoID, name api.RepoName, url string) {
+	if s.updated == nil {
+		s.updated = map[api.RepoName]string{}
+	}
+	s.updated[name] = url
+}
@@ -29,6 +29,7 @@ import (
 
 var cacheDir = env.Get("CACHE_DIR", "/tmp", "directory to store cached archives.")
 var cacheSizeMB = env.Get("SEARCHER_CACHE_SIZE_MB", "100000", "maximum size of the on disk cache in megabytes")
+var structuralEngine = env.Get("SEARCHER_STRUCTURAL_ENGINE", search.StructuralEngineAuto, "engine for structural search: comby, native, or auto to use comby if it is installed")
 
 const port = "3181"
 
@@ -48,6 +49,12 @@ func main() {
 		cacheSizeBytes = i * 1000 * 1000
 	}
 
+	switch structuralEngine {
+	case search.StructuralEngineAuto, search.StructuralEngineComby, search.StructuralEngineNative:
+	default:
+		log.Fatalf("invalid value %q for SEARCHER_STRUCTURAL_ENGINE", structuralEngine)
+	}
+
 	service := &search.Service{
 		Store: &store.Store{
 			FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
@@ -56,10 +63,12 @@ func main() {
 			Path:              filepath.Join(cacheDir, "searcher-archives"),
 			MaxCacheSizeBytes: cacheSizeBytes,
 		},
-		Log: log15.Root(),
+		Log:              log15.Root(),
+		StructuralEngine: structuralEngine,
 	}
 	service.Store.Start()
 	handler := ot.Middleware(service)
+	replaceHandler := ot.Middleware(http.HandlerFunc(service.ServeReplace))
 
 	host := ""
 	if env.InsecureDev {
@@ -75,6 +84,10 @@ func main() {
 				_, _ = w.Write([]byte("ok"))
 				return
 			}
+			if r.URL.Path == "/replace" {
+				replaceHandler.ServeHTTP(w, r)
+				return
+			}
 			handler.ServeHTTP(w, r)
 		}),
 	}
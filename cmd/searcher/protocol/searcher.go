@@ -41,6 +41,11 @@ type Request struct {
 	// The deadline for the search request.
 	// It is parsed with time.Time.UnmarshalText.
 	Deadline string
+
+	// Stream if true will make searcher write newline-delimited JSON
+	// StreamEvents as matches are found, instead of a single Response once
+	// the search is complete.
+	Stream bool
 }
 
 // PatternInfo describes a search request on a repo. Most of the fields
@@ -105,6 +110,32 @@ type PatternInfo struct {
 
 	// CombyRule is a rule that constrains matching for structural search. It only applies when IsStructuralPat is true.
 	CombyRule string
+
+	// NearPattern is set for near(N) queries like "lock near(5) unlock".
+	// Only lines matching Pattern at most NearDistance lines away from a
+	// line matching NearPattern, and vice versa, are returned. NearPattern
+	// is interpreted like Pattern.
+	NearPattern  string
+	NearDistance int
+
+	// FileContentMustInclude is a list of regular expressions that must
+	// *all* match the content of returned files. FileContentMustExclude is a
+	// list of regular expressions that may not match the content of returned
+	// files. They respect IsCaseSensitive.
+	FileContentMustInclude []string
+	FileContentMustExclude []string
+
+	// FileOwnersMustInclude is a list of owners that must *all* own the
+	// returned files according to the repository's CODEOWNERS file.
+	// FileOwnersMustExclude is a list of owners that may not own them. eg
+	// "@alice" or "team@example.com"
+	FileOwnersMustInclude []string
+	FileOwnersMustExclude []string
+
+	// FileSizeMin and FileSizeMax are inclusive bounds on the size in bytes
+	// of returned files. Zero means no bound.
+	FileSizeMin int64
+	FileSizeMax int64
 }
 
 func (p *PatternInfo) String() string {
@@ -119,6 +150,9 @@ func (p *PatternInfo) String() string {
 			args = append(args, "comby")
 		}
 	}
+	if p.NearPattern != "" {
+		args = append(args, fmt.Sprintf("near(%d):%q", p.NearDistance, p.NearPattern))
+	}
 	if p.IsWordMatch {
 		args = append(args, "word")
 	}
@@ -151,6 +185,24 @@ func (p *PatternInfo) String() string {
 	for _, inc := range p.IncludePatterns {
 		args = append(args, fmt.Sprintf("%s:%q", path, inc))
 	}
+	for _, c := range p.FileContentMustInclude {
+		args = append(args, fmt.Sprintf("contains:%q", c))
+	}
+	for _, c := range p.FileContentMustExclude {
+		args = append(args, fmt.Sprintf("-contains:%q", c))
+	}
+	for _, o := range p.FileOwnersMustInclude {
+		args = append(args, fmt.Sprintf("owner:%q", o))
+	}
+	for _, o := range p.FileOwnersMustExclude {
+		args = append(args, fmt.Sprintf("-owner:%q", o))
+	}
+	if p.FileSizeMin > 0 {
+		args = append(args, fmt.Sprintf("minsize:%d", p.FileSizeMin))
+	}
+	if p.FileSizeMax > 0 {
+		args = append(args, fmt.Sprintf("maxsize:%d", p.FileSizeMax))
+	}
 
 	return fmt.Sprintf("PatternInfo{%s}", strings.Join(args, ","))
 }
@@ -166,6 +218,67 @@ type Response struct {
 	DeadlineHit bool
 }
 
+// ReplaceRequest represents a request to searcher to preview replacing the
+// matches of a pattern. It is sent to the /replace endpoint.
+type ReplaceRequest struct {
+	Request
+
+	// Replacement is what every match is replaced with. For structural
+	// patterns it is a comby rewrite template. For regular expressions it may
+	// refer to capture groups like $1 or ${name} (see regexp.Regexp.Expand).
+	Replacement string
+}
+
+// ReplaceResponse represents the response from a Replace request.
+type ReplaceResponse struct {
+	Diffs []FileDiff
+
+	// LimitHit is true if Diffs may not include all files with matches
+	// because a match limit was hit.
+	LimitHit bool
+
+	// DeadlineHit is true if Diffs may not include all files with matches
+	// because a deadline was hit.
+	DeadlineHit bool
+}
+
+// FileDiff is the result of replacing matches in a single file.
+type FileDiff struct {
+	Path string
+
+	// Diff is a unified diff of the file. It uses git style "a/" and "b/"
+	// prefixes in its header so that the diffs of several files can be
+	// concatenated into a patch.
+	Diff string
+}
+
+// StreamEvent is a single newline-delimited JSON message in the response to a
+// Request with Stream set. Exactly one of the fields is set. The last event
+// of a successful response always has Done set.
+type StreamEvent struct {
+	// Match is a file match found so far.
+	Match *FileMatch `json:",omitempty"`
+
+	// Done is set on the final event, once the search has completed.
+	Done *StreamDone `json:",omitempty"`
+}
+
+// StreamDone describes how a streaming search completed.
+type StreamDone struct {
+	// LimitHit is true if the streamed matches may not include all
+	// FileMatches because a match limit was hit.
+	LimitHit bool
+
+	// DeadlineHit is true if the streamed matches may not include all
+	// FileMatches because a deadline was hit.
+	DeadlineHit bool
+
+	// Error is non-empty if the search failed after matches were already
+	// streamed to the client. Errors which occur before any match is sent
+	// are reported with a non-200 HTTP status code instead.
+	Error string `json:",omitempty"`
+}
+
 // FileMatch is the struct used by vscode to receive search results
 type FileMatch struct {
 	Path        string
@@ -175,6 +288,32 @@ type FileMatch struct {
 
 	// LimitHit is true if LineMatches may not include all LineMatches.
 	LimitHit bool
+
+	// Ranges is the full range of each match. It is only set for regexp
+	// patterns which can match across lines (for example patterns using the
+	// (?s) flag or containing \n). LineMatches then contains one entry per
+	// line spanned by a match.
+	Ranges []Range `json:",omitempty"`
+}
+
+// Location is a position in a file.
+type Location struct {
+	// Offset is the 0-based byte offset from the start of the file.
+	Offset int
+
+	// Line is the 0-based line number.
+	Line int
+
+	// Column is the 0-based column, measured in characters like
+	// LineMatch.OffsetAndLengths.
+	Column int
+}
+
+// Range is the range between the Start and End locations of a match. End is
+// exclusive.
+type Range struct {
+	Start Location
+	End   Location
 }
 
 // LineMatch is the struct used by vscode to receive search results for a line.
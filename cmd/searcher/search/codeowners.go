@@ -0,0 +1,144 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/store"
+)
+
+// codeownersPaths are the locations of a CODEOWNERS file in a repository, in
+// the order in which they are looked up.
+var codeownersPaths = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeownersRule assigns owners to the files matching pattern.
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeowners is a parsed CODEOWNERS file. See
+// https://docs.github.com/en/github/creating-cloning-and-archiving-repositories/about-code-owners
+type codeowners []codeownersRule
+
+// findCodeowners returns the parsed CODEOWNERS file of the archive zf, or nil
+// if it has none.
+func findCodeowners(zf *store.ZipFile) codeowners {
+	for _, path := range codeownersPaths {
+		for i := range zf.Files {
+			if zf.Files[i].Name == path {
+				return parseCodeowners(zf.DataFor(&zf.Files[i]))
+			}
+		}
+	}
+	return nil
+}
+
+// parseCodeowners parses the content of a CODEOWNERS file. Lines with invalid
+// patterns are ignored.
+func parseCodeowners(data []byte) codeowners {
+	var rules codeowners
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		var owners []string
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				// Trailing comment.
+				break
+			}
+			owners = append(owners, owner)
+		}
+		pattern, err := regexp.Compile(codeownersPatternToRegexp(fields[0]))
+		if err != nil {
+			continue
+		}
+		rules = append(rules, codeownersRule{pattern: pattern, owners: owners})
+	}
+	return rules
+}
+
+// codeownersPatternToRegexp converts a CODEOWNERS pattern, which uses the
+// syntax of .gitignore files, to a regular expression matching file paths.
+func codeownersPatternToRegexp(pattern string) string {
+	pattern = strings.TrimPrefix(pattern, `\`)
+
+	// A trailing slash only matches directories, so files below them.
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	// A slash at the start or in the middle anchors the pattern at the root of
+	// the repository, otherwise it matches at any depth.
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	last := pattern[strings.LastIndex(pattern, "/")+1:]
+	switch {
+	case dirOnly:
+		b.WriteString("/")
+	case strings.Contains(last, "*") && !strings.Contains(last, "**"):
+		// Like GitHub, docs/* matches the files in docs but not in its
+		// subdirectories.
+		b.WriteString("$")
+	default:
+		// A pattern matching a directory matches all files below it.
+		b.WriteString("(?:$|/)")
+	}
+	return b.String()
+}
+
+// owners returns the owners of the file at path. Like GitHub, the last
+// matching rule wins.
+func (c codeowners) owners(path string) []string {
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].pattern.MatchString(path) {
+			return c[i].owners
+		}
+	}
+	return nil
+}
+
+// hasOwner returns true if owner is one of the owners of the file at path.
+// Owners are compared case insensitively, and the leading @ of user and team
+// names is optional.
+func (c codeowners) hasOwner(path, owner string) bool {
+	owner = normalizeOwner(owner)
+	for _, o := range c.owners(path) {
+		if normalizeOwner(o) == owner {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeOwner(owner string) string {
+	return strings.ToLower(strings.TrimPrefix(owner, "@"))
+}
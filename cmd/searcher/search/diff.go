@@ -0,0 +1,113 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change
+// in a unified diff. It is the same default as git diff.
+const diffContextLines = 3
+
+// lineEdit replaces the lines [start, end) of a file with lines. Lines
+// include their trailing newline, except possibly the last line of a file.
+type lineEdit struct {
+	start, end int
+	lines      []string
+}
+
+// splitLines splits b into lines, keeping the trailing newline of each line.
+func splitLines(b []byte) []string {
+	var lines []string
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			lines = append(lines, string(b))
+			break
+		}
+		lines = append(lines, string(b[:i+1]))
+		b = b[i+1:]
+	}
+	return lines
+}
+
+// unifiedDiff returns a unified diff for applying edits to a file consisting of
+// lines. edits must be sorted and must not overlap. The file headers use git
+// style "a/" and "b/" prefixes so that the diff can be used as a patch.
+func unifiedDiff(path string, lines []string, edits []lineEdit) string {
+	if len(edits) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+
+	// delta is the difference in line count between the new and the old file
+	// caused by the edits before the current hunk.
+	delta := 0
+	for len(edits) > 0 {
+		// Group edits whose context overlaps into a single hunk.
+		n := 1
+		for n < len(edits) && edits[n].start-edits[n-1].end <= 2*diffContextLines {
+			n++
+		}
+		hunk := edits[:n]
+		edits = edits[n:]
+
+		start := hunk[0].start - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := hunk[n-1].end + diffContextLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		// The hunk covers the old lines [start, end). hunkDelta is the
+		// difference in line count the hunk introduces.
+		hunkDelta := 0
+		for _, e := range hunk {
+			hunkDelta += len(e.lines) - (e.end - e.start)
+		}
+		oldCount := end - start
+		newCount := oldCount + hunkDelta
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(start, oldCount), hunkRange(start+delta, newCount))
+
+		pos := start
+		for _, e := range hunk {
+			for ; pos < e.start; pos++ {
+				writeDiffLine(&b, ' ', lines[pos])
+			}
+			for ; pos < e.end; pos++ {
+				writeDiffLine(&b, '-', lines[pos])
+			}
+			for _, l := range e.lines {
+				writeDiffLine(&b, '+', l)
+			}
+		}
+		for ; pos < end; pos++ {
+			writeDiffLine(&b, ' ', lines[pos])
+		}
+
+		delta += hunkDelta
+	}
+	return b.String()
+}
+
+// hunkRange formats the range of a hunk starting at the 0-based line start.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before it.
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func writeDiffLine(b *strings.Builder, op byte, line string) {
+	b.WriteByte(op)
+	b.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		b.WriteString("\n\\ No newline at end of file\n")
+	}
+}
@@ -0,0 +1,198 @@
+package search
+
+import (
+	"archive/zip"
+	"context"
+	"io/ioutil"
+	"os"
+	"regexp"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/pathmatch"
+	"github.com/sourcegraph/sourcegraph/internal/store"
+)
+
+// filePredicates select the files to search by their content, owners or size
+// rather than their path. See the File* fields of protocol.PatternInfo.
+type filePredicates struct {
+	contentInclude []*regexp.Regexp
+	contentExclude []*regexp.Regexp
+	ownersInclude  []string
+	ownersExclude  []string
+	sizeMin        int64
+	sizeMax        int64
+}
+
+// compileFilePredicates returns the file predicates of p, or nil if p has
+// none.
+func compileFilePredicates(p *protocol.PatternInfo) (*filePredicates, error) {
+	if len(p.FileContentMustInclude) == 0 && len(p.FileContentMustExclude) == 0 &&
+		len(p.FileOwnersMustInclude) == 0 && len(p.FileOwnersMustExclude) == 0 &&
+		p.FileSizeMin <= 0 && p.FileSizeMax <= 0 {
+		return nil, nil
+	}
+
+	compileAll := func(exprs []string) ([]*regexp.Regexp, error) {
+		var res []*regexp.Regexp
+		for _, expr := range exprs {
+			expr = "(?m:" + expr + ")"
+			if !p.IsCaseSensitive {
+				expr = "(?i)" + expr
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, re)
+		}
+		return res, nil
+	}
+	contentInclude, err := compileAll(p.FileContentMustInclude)
+	if err != nil {
+		return nil, err
+	}
+	contentExclude, err := compileAll(p.FileContentMustExclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return &filePredicates{
+		contentInclude: contentInclude,
+		contentExclude: contentExclude,
+		ownersInclude:  p.FileOwnersMustInclude,
+		ownersExclude:  p.FileOwnersMustExclude,
+		sizeMin:        p.FileSizeMin,
+		sizeMax:        p.FileSizeMax,
+	}, nil
+}
+
+// filter returns the names of the files in zf which satisfy fp. Only files
+// for which candidate returns true are considered, so that we do not read
+// files which will not be searched anyway.
+func (fp *filePredicates) filter(ctx context.Context, zf *store.ZipFile, candidate func(name string) bool) (map[string]struct{}, error) {
+	var owners codeowners
+	if len(fp.ownersInclude) > 0 || len(fp.ownersExclude) > 0 {
+		owners = findCodeowners(zf)
+	}
+
+	names := map[string]struct{}{}
+	for i := range zf.Files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		f := &zf.Files[i]
+		if candidate(f.Name) && fp.match(zf, f, owners) {
+			names[f.Name] = struct{}{}
+		}
+	}
+	return names, nil
+}
+
+// match returns true if the file f in zf satisfies fp. The cheap checks come
+// first, the content is only read if needed.
+func (fp *filePredicates) match(zf *store.ZipFile, f *store.SrcFile, owners codeowners) bool {
+	size := int64(f.Len)
+	if fp.sizeMin > 0 && size < fp.sizeMin {
+		return false
+	}
+	if fp.sizeMax > 0 && size > fp.sizeMax {
+		return false
+	}
+
+	for _, owner := range fp.ownersInclude {
+		if !owners.hasOwner(f.Name, owner) {
+			return false
+		}
+	}
+	for _, owner := range fp.ownersExclude {
+		if owners.hasOwner(f.Name, owner) {
+			return false
+		}
+	}
+
+	if len(fp.contentInclude) == 0 && len(fp.contentExclude) == 0 {
+		return true
+	}
+	fileBuf := zf.DataFor(f)
+	for _, re := range fp.contentInclude {
+		if !re.Match(fileBuf) {
+			return false
+		}
+	}
+	for _, re := range fp.contentExclude {
+		if re.Match(fileBuf) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyFilePredicates returns the names of the files in zf which satisfy fp
+// and are searched for p. Regexp searches only search the paths accepted by
+// rg.matchPath, which is narrowed to the returned files. Structural searches
+// only search the files with one of the suffixes in p.IncludePatterns.
+func applyFilePredicates(ctx context.Context, p *protocol.PatternInfo, fp *filePredicates, rg *readerGrep, zf *store.ZipFile) (map[string]struct{}, error) {
+	if p.IsStructuralPat {
+		includePatterns := p.IncludePatterns
+		return fp.filter(ctx, zf, func(name string) bool {
+			return hasAnySuffix(name, includePatterns)
+		})
+	}
+
+	names, err := fp.filter(ctx, zf, rg.matchPath.MatchPath)
+	if err != nil {
+		return nil, err
+	}
+	rg.matchPath = &filesMatcher{PathMatcher: rg.matchPath, names: names}
+	return names, nil
+}
+
+// zipFiles writes the files of zf with the given names to a new temporary
+// zip archive and returns its path. comby can only filter the files of an
+// archive by their suffix, so it searches this archive instead. The caller
+// must remove the archive.
+func zipFiles(zf *store.ZipFile, names map[string]struct{}) (path string, err error) {
+	f, err := ioutil.TempFile("", "searcher-files-*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+	for i := range zf.Files {
+		file := &zf.Files[i]
+		if _, ok := names[file.Name]; !ok {
+			continue
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: file.Name, Method: zip.Store})
+		if err != nil {
+			f.Close()
+			return "", err
+		}
+		if _, err := w.Write(zf.DataFor(file)); err != nil {
+			f.Close()
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// filesMatcher is a pathmatch.PathMatcher which only matches the paths in
+// names.
+type filesMatcher struct {
+	pathmatch.PathMatcher
+	names map[string]struct{}
+}
+
+func (m *filesMatcher) MatchPath(path string) bool {
+	_, ok := m.names[path]
+	return ok
+}
@@ -0,0 +1,188 @@
+package search
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/store"
+	"github.com/sourcegraph/sourcegraph/internal/testutil"
+)
+
+func TestApplyFilePredicates(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"CODEOWNERS":         "* @everyone\n/api/ @alice @Bob\n*.md docs@example.com\n",
+		"api/handler.go":     "package api\n\nimport \"net/http\"\n",
+		"api/README.md":      "# API\n",
+		"cmd/main.go":        "package main\n\nimport \"net/http\"\n\nfunc main() {}\n",
+		"internal/big.go":    "package internal\n\n// " + string(make([]byte, 100)) + "\n",
+		"internal/empty.go":  "",
+		"internal/server.go": "package internal\n\nimport \"NET/HTTP\"\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		p    protocol.PatternInfo
+		want []string
+	}{{
+		name: "contains",
+		p: protocol.PatternInfo{
+			FileContentMustInclude: []string{`net/http`},
+			IsCaseSensitive:        true,
+		},
+		want: []string{"api/handler.go", "cmd/main.go"},
+	}, {
+		name: "contains ignores case",
+		p: protocol.PatternInfo{
+			FileContentMustInclude: []string{`net/http`},
+		},
+		want: []string{"api/handler.go", "cmd/main.go", "internal/server.go"},
+	}, {
+		name: "contains and not contains",
+		p: protocol.PatternInfo{
+			FileContentMustInclude: []string{`^package`},
+			FileContentMustExclude: []string{`func main`},
+			IncludePatterns:        []string{`\.go$`},
+		},
+		want: []string{"api/handler.go", "internal/big.go", "internal/server.go"},
+	}, {
+		name: "owners",
+		p: protocol.PatternInfo{
+			FileOwnersMustInclude: []string{"bob"},
+		},
+		want: []string{"api/handler.go"},
+	}, {
+		name: "not owned",
+		p: protocol.PatternInfo{
+			FileOwnersMustExclude: []string{"@everyone", "docs@example.com"},
+		},
+		want: []string{"api/handler.go"},
+	}, {
+		name: "size",
+		p: protocol.PatternInfo{
+			FileSizeMin:     1,
+			FileSizeMax:     100,
+			IncludePatterns: []string{`^internal/`},
+		},
+		want: []string{"internal/server.go"},
+	}, {
+		name: "no files",
+		p: protocol.PatternInfo{
+			FileOwnersMustInclude: []string{"@nobody"},
+		},
+	}}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.PathPatternsAreRegExps = true
+			fp, err := compileFilePredicates(&tt.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Regexp searches.
+			rg, err := compile(&tt.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files, err := applyFilePredicates(context.Background(), &tt.p, fp, rg, zf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != len(tt.want) {
+				t.Errorf("got %d files, want %d", len(files), len(tt.want))
+			}
+			var got []string
+			for _, f := range zf.Files {
+				if rg.matchPath.MatchPath(f.Name) {
+					got = append(got, f.Name)
+				}
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected files (-want +got):\n%s", diff)
+			}
+
+			// Structural searches use include patterns as suffixes.
+			if len(tt.p.IncludePatterns) > 0 {
+				return
+			}
+			tt.p.IsStructuralPat = true
+			files, err = applyFilePredicates(context.Background(), &tt.p, fp, nil, zf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// comby searches an archive of just these files.
+			path, err := zipFiles(zf, files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(path)
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			subset, err := store.MockZipFile(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = nil
+			for _, f := range subset.Files {
+				got = append(got, f.Name)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected files in archive (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodeowners(t *testing.T) {
+	owners := parseCodeowners([]byte(`# Comment
+*       @global
+*.js    @js # trailing comment
+/build/ @build
+docs/*  @docs
+apps/   @apps
+**/logs @logs
+/script
+`))
+
+	cases := []struct {
+		path string
+		want []string
+	}{
+		{"README.md", []string{"@global"}},
+		{"web/app.js", []string{"@js"}},
+		{"build/out/app.js", []string{"@build"}},
+		{"src/build/x.go", []string{"@global"}},
+		{"docs/index.md", []string{"@docs"}},
+		{"docs/api/index.md", []string{"@global"}},
+		{"src/apps/main.go", []string{"@apps"}},
+		{"apps", []string{"@global"}},
+		{"a/b/logs/today.txt", []string{"@logs"}},
+		{"script/run.sh", nil},
+	}
+	for _, tt := range cases {
+		if diff := cmp.Diff(tt.want, owners.owners(tt.path)); diff != "" {
+			t.Errorf("%s: unexpected owners (-want +got):\n%s", tt.path, diff)
+		}
+	}
+
+	if !owners.hasOwner("web/app.js", "JS") {
+		t.Error("expected owners to be compared case insensitively without @")
+	}
+}
@@ -0,0 +1,375 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/comby"
+	"github.com/sourcegraph/sourcegraph/internal/search/structural"
+	"github.com/sourcegraph/sourcegraph/internal/store"
+	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
+)
+
+// ServeReplace handles HTTP based requests to preview replacing the matches of
+// a pattern. The response contains a unified diff per changed file. Nothing
+// is written to the repository.
+func (s *Service) ServeReplace(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	running.Inc()
+	defer running.Dec()
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "failed to parse form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p protocol.ReplaceRequest
+	err = decoder.Decode(&p, r.Form)
+	if err != nil {
+		http.Error(w, "failed to decode form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel, err := withDeadline(ctx, p.Deadline)
+	if err != nil {
+		http.Error(w, "invalid deadline: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+	// Replacing only applies to file content.
+	p.PatternMatchesContent = true
+	p.PatternMatchesPath = false
+	if err = validateParams(&p.Request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p.Pattern == "" {
+		http.Error(w, "Pattern must be non-empty", http.StatusBadRequest)
+		return
+	}
+	if p.IsNegated {
+		http.Error(w, "Negated patterns can not be replaced", http.StatusBadRequest)
+		return
+	}
+
+	diffs, limitHit, deadlineHit, err := s.replace(ctx, &p)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if isBadRequest(err) || ctx.Err() == context.Canceled {
+			code = http.StatusBadRequest
+		} else if isTemporary(err) {
+			code = http.StatusServiceUnavailable
+		} else {
+			log.Printf("internal error serving %#+v: %s", p, err)
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	if diffs == nil {
+		// Return an empty list
+		diffs = make([]protocol.FileDiff, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resp := protocol.ReplaceResponse{
+		Diffs:       diffs,
+		LimitHit:    limitHit,
+		DeadlineHit: deadlineHit,
+	}
+	// See ServeHTTP for why we ignore the error.
+	_ = json.NewEncoder(w).Encode(&resp)
+}
+
+func (s *Service) replace(ctx context.Context, p *protocol.ReplaceRequest) (diffs []protocol.FileDiff, limitHit, deadlineHit bool, err error) {
+	span, ctx := ot.StartSpanFromContext(ctx, "Replace")
+	ext.Component.Set(span, "service")
+	span.SetTag("repo", p.Repo)
+	span.SetTag("commit", p.Commit)
+	span.SetTag("pattern", p.Pattern)
+	span.SetTag("replacement", p.Replacement)
+	defer func() {
+		if ctx.Err() == context.DeadlineExceeded {
+			deadlineHit = true
+			err = nil // error is fully described by deadlineHit=true return value
+		} else if err != nil {
+			ext.Error.Set(span, true)
+			span.SetTag("err", err.Error())
+		}
+		span.SetTag("diffs", len(diffs))
+		span.SetTag("limitHit", limitHit)
+		span.SetTag("deadlineHit", deadlineHit)
+		span.Finish()
+	}()
+
+	// Compile pattern before fetching from store incase it is bad.
+	var rg *readerGrep
+	if !p.IsStructuralPat {
+		rg, err = compile(&p.PatternInfo)
+		if err != nil {
+			return nil, false, false, badRequestError{err.Error()}
+		}
+	}
+	fp, err := compileFilePredicates(&p.PatternInfo)
+	if err != nil {
+		return nil, false, false, badRequestError{err.Error()}
+	}
+
+	zipPath, zf, err := s.getZipFile(ctx, &p.Request)
+	if err != nil {
+		return nil, false, false, errors.Wrap(err, "failed to get archive")
+	}
+	defer zf.Close()
+
+	// files are the files which satisfy the file predicates, if any.
+	var files map[string]struct{}
+	if fp != nil {
+		files, err = applyFilePredicates(ctx, &p.PatternInfo, fp, rg, zf)
+		if err != nil || len(files) == 0 {
+			return nil, false, false, err
+		}
+	}
+
+	if p.IsStructuralPat && s.useNativeStructural() {
+		diffs, limitHit, err = nativeStructuralReplace(ctx, zf, files, p)
+		return diffs, limitHit, false, err
+	}
+	if p.IsStructuralPat {
+		if files != nil {
+			if zipPath, err = zipFiles(zf, files); err != nil {
+				return nil, false, false, err
+			}
+			defer os.Remove(zipPath)
+		}
+		diffs, err = structuralReplace(ctx, zipPath, p)
+		return diffs, false, false, err
+	}
+	diffs, limitHit, err = regexReplace(ctx, rg, zf, p.FileMatchLimit, []byte(p.Replacement))
+	return diffs, limitHit, false, err
+}
+
+// regexReplace returns a diff for each file in zf in which rg matches,
+// replacing each match with the expansion of template.
+func regexReplace(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMatchLimit int, template []byte) (diffs []protocol.FileDiff, limitHit bool, err error) {
+	if fileMatchLimit > maxFileMatches || fileMatchLimit <= 0 {
+		fileMatchLimit = maxFileMatches
+	}
+
+	for i := range zf.Files {
+		if err := ctx.Err(); err != nil {
+			return diffs, limitHit, err
+		}
+
+		f := &zf.Files[i]
+		if !rg.matchPath.MatchPath(f.Name) {
+			continue
+		}
+		diff, fileLimitHit := rg.Replace(zf, f, template)
+		if diff == "" {
+			continue
+		}
+		if len(diffs) == fileMatchLimit {
+			limitHit = true
+			break
+		}
+		limitHit = limitHit || fileLimitHit
+		diffs = append(diffs, protocol.FileDiff{Path: f.Name, Diff: diff})
+	}
+	return diffs, limitHit, nil
+}
+
+// Replace returns a unified diff for replacing the matches of rg in f with the
+// expansion of template (see regexp.Regexp.Expand). It returns an empty string
+// if there are no matches. Only the first maxReplaceMatches matches are
+// replaced, limitHit is true if there are more.
+// NOTE: This is not safe to use concurrently.
+func (rg *readerGrep) Replace(zf *store.ZipFile, f *store.SrcFile, template []byte) (diff string, limitHit bool) {
+	fileBuf, fileMatchBuf := rg.buffers(zf, f)
+	if rg.re == nil || !bytes.Contains(fileMatchBuf, rg.literalSubstring) {
+		return "", false
+	}
+
+	locs := rg.re.FindAllSubmatchIndex(fileMatchBuf, maxReplaceMatches+1)
+	if len(locs) > maxReplaceMatches {
+		locs = locs[:maxReplaceMatches]
+		limitHit = true
+	}
+	return replaceMatches(f.Name, fileBuf, locs, func(dst []byte, loc []int) []byte {
+		return rg.re.Expand(dst, template, fileBuf, loc)
+	}), limitHit
+}
+
+// replaceMatches returns a unified diff for replacing the matches locs in the
+// file path with contents fileBuf. loc[0] and loc[1] of each match are its
+// start and end offsets, expand appends the replacement of a match to dst.
+// locs must be sorted and must not overlap.
+func replaceMatches(path string, fileBuf []byte, locs [][]int, expand func(dst []byte, loc []int) []byte) string {
+	if len(locs) == 0 {
+		return ""
+	}
+
+	lines := splitLines(fileBuf)
+	li := lineIndex{buf: fileBuf}
+
+	// lineOffsets[i] is the offset of line i. The extra last entry is the end
+	// of the file.
+	lineOffsets := make([]int, len(lines)+1)
+	for i, l := range lines {
+		lineOffsets[i+1] = lineOffsets[i] + len(l)
+	}
+
+	var (
+		edits []lineEdit
+		// newText is the replaced content of the current edit, up to the
+		// offset last.
+		newText []byte
+		last    int
+	)
+	// join joins the following line into the current edit if a replacement
+	// removed the newline at its end.
+	join := func() {
+		e := &edits[len(edits)-1]
+		if last == lineOffsets[e.end] && e.end < len(lines) && len(newText) > 0 && newText[len(newText)-1] != '\n' {
+			e.end++
+		}
+	}
+	// flush completes the current edit up to the end of its last line.
+	flush := func() {
+		e := &edits[len(edits)-1]
+		newText = append(newText, fileBuf[last:lineOffsets[e.end]]...)
+		last = lineOffsets[e.end]
+		e.lines = splitLines(newText)
+	}
+
+	for _, loc := range locs {
+		start := li.locate(loc[0])
+		if start.Line >= len(lines) {
+			// An empty match at the very end of a file with a trailing
+			// newline. There is no line to edit.
+			break
+		}
+		endLine := li.locate(loc[1]).Line
+		if loc[1] > loc[0] && fileBuf[loc[1]-1] == '\n' {
+			// The match ends with the newline of the previous line.
+			endLine--
+		}
+
+		if len(edits) > 0 {
+			join()
+		}
+		if len(edits) > 0 && start.Line < edits[len(edits)-1].end {
+			// The match continues the current edit.
+			newText = append(newText, fileBuf[last:loc[0]]...)
+		} else {
+			if len(edits) > 0 {
+				flush()
+			}
+			edits = append(edits, lineEdit{start: start.Line, end: start.Line + 1})
+			newText = append([]byte(nil), fileBuf[lineOffsets[start.Line]:loc[0]]...)
+		}
+		newText = expand(newText, loc)
+		last = loc[1]
+		if e := &edits[len(edits)-1]; endLine+1 > e.end {
+			e.end = endLine + 1
+		}
+	}
+	if len(edits) == 0 {
+		return ""
+	}
+	join()
+	flush()
+
+	// Drop edits which did not change anything, eg when the replacement is
+	// the same as the match.
+	changed := edits[:0]
+	for _, e := range edits {
+		if strings.Join(e.lines, "") != strings.Join(lines[e.start:e.end], "") {
+			changed = append(changed, e)
+		}
+	}
+	return unifiedDiff(path, lines, changed)
+}
+
+// structuralReplace runs comby in rewrite mode over the archive at zipPath.
+func structuralReplace(ctx context.Context, zipPath string, p *protocol.ReplaceRequest) ([]protocol.FileDiff, error) {
+	var matcher string
+	if len(p.Languages) > 0 {
+		// Like structuralSearch, we only support a single language.
+		matcher = lookupMatcher(p.Languages[0])
+	}
+
+	args := comby.Args{
+		Input:           comby.ZipPath(zipPath),
+		Matcher:         matcher,
+		MatchTemplate:   p.Pattern,
+		RewriteTemplate: p.Replacement,
+		FilePatterns:    p.IncludePatterns,
+		Rule:            p.CombyRule,
+		NumWorkers:      4,
+	}
+
+	combyDiffs, err := comby.Diffs(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := make([]protocol.FileDiff, 0, len(combyDiffs))
+	for _, d := range combyDiffs {
+		diffs = append(diffs, protocol.FileDiff{
+			Path: d.URI,
+			Diff: withGitDiffHeader(d.URI, d.Diff),
+		})
+	}
+	return diffs, nil
+}
+
+// nativeStructuralReplace is like structuralReplace, but uses the native
+// engine on the already opened archive zf instead of running comby. If files
+// is non-nil, only the files with these names are replaced in.
+func nativeStructuralReplace(ctx context.Context, zf *store.ZipFile, files map[string]struct{}, p *protocol.ReplaceRequest) (diffs []protocol.FileDiff, limitHit bool, err error) {
+	tmpl, err := parseNativeTemplate(p.Pattern, p.CombyRule)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = nativeStructuralMatches(ctx, zf, tmpl, matcherForLanguages(p.Languages), p.IncludePatterns, files, func(f *store.SrcFile, fileBuf []byte, matches []structural.Match) {
+		if len(matches) > maxReplaceMatches {
+			matches = matches[:maxReplaceMatches]
+			limitHit = true
+		}
+		locs := make([][]int, len(matches))
+		environments := make(map[int]map[string]string, len(matches))
+		for i, m := range matches {
+			locs[i] = []int{m.Start, m.End}
+			environments[m.Start] = m.Environment
+		}
+		diff := replaceMatches(f.Name, fileBuf, locs, func(dst []byte, loc []int) []byte {
+			return append(dst, structural.Rewrite(p.Replacement, environments[loc[0]])...)
+		})
+		if diff != "" {
+			diffs = append(diffs, protocol.FileDiff{Path: f.Name, Diff: diff})
+		}
+	})
+	return diffs, limitHit, err
+}
+
+// withGitDiffHeader replaces the file header comby emits for a diff with git
+// style "a/" and "b/" headers, so that all diffs returned by searcher can be
+// applied the same way.
+func withGitDiffHeader(path, diff string) string {
+	if i := strings.Index(diff, "@@"); i > 0 {
+		diff = diff[i:]
+	}
+	if diff != "" && !strings.HasSuffix(diff, "\n") {
+		diff += "\n"
+	}
+	return "--- a/" + path + "\n+++ b/" + path + "\n" + diff
+}
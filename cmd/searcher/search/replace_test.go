@@ -0,0 +1,235 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/store"
+	"github.com/sourcegraph/sourcegraph/internal/testutil"
+)
+
+func TestReplace(t *testing.T) {
+	cases := []struct {
+		name        string
+		data        string
+		pattern     protocol.PatternInfo
+		replacement string
+		want        string
+	}{{
+		name:        "no match",
+		data:        "foo\n",
+		pattern:     protocol.PatternInfo{Pattern: "bar"},
+		replacement: "baz",
+		want:        "",
+	}, {
+		name:        "replacement equal to match",
+		data:        "foo\n",
+		pattern:     protocol.PatternInfo{Pattern: "foo"},
+		replacement: "foo",
+		want:        "",
+	}, {
+		name:        "capture groups keep original case",
+		data:        "package main\n\nfunc main() {\n\tFmt.Println(x)\n}\n",
+		pattern:     protocol.PatternInfo{Pattern: `fmt\.(\w+)\(`, IsRegExp: true},
+		replacement: "log.${1}(",
+		want:        "--- a/a.go\n+++ b/a.go\n@@ -1,5 +1,5 @@\n package main\n \n func main() {\n-\tFmt.Println(x)\n+\tlog.Println(x)\n }\n",
+	}, {
+		name:        "separate hunks and multiple matches per line",
+		data:        "a a\n1\n2\n3\n4\n5\n6\n7\n8\na\n",
+		pattern:     protocol.PatternInfo{Pattern: "a", IsCaseSensitive: true},
+		replacement: "b",
+		want: `--- a/a.go
++++ b/a.go
+@@ -1,4 +1,4 @@
+-a a
++b b
+ 1
+ 2
+ 3
+@@ -7,4 +7,4 @@
+ 6
+ 7
+ 8
+-a
++b
+`,
+	}, {
+		name:        "match spanning lines",
+		data:        "x\nfoo(a,\n\tb)\ny\n",
+		pattern:     protocol.PatternInfo{Pattern: `(?s)foo\((.*?)\)`, IsRegExp: true},
+		replacement: "bar($1)",
+		want: `--- a/a.go
++++ b/a.go
+@@ -1,4 +1,4 @@
+ x
+-foo(a,
+-	b)
++bar(a,
++	b)
+ y
+`,
+	}, {
+		name:        "removed newline joins lines",
+		data:        "a\nfoo\nb\nc\n",
+		pattern:     protocol.PatternInfo{Pattern: `foo\n`, IsRegExp: true},
+		replacement: "bar ",
+		want: `--- a/a.go
++++ b/a.go
+@@ -1,4 +1,3 @@
+ a
+-foo
+-b
++bar b
+ c
+`,
+	}, {
+		name:        "removed lines",
+		data:        "a\nfoo\nb\n",
+		pattern:     protocol.PatternInfo{Pattern: `foo\n`, IsRegExp: true},
+		replacement: "",
+		want: `--- a/a.go
++++ b/a.go
+@@ -1,3 +1,2 @@
+ a
+-foo
+ b
+`,
+	}, {
+		name:        "no newline at end of file",
+		data:        "a\nfoo",
+		pattern:     protocol.PatternInfo{Pattern: "foo"},
+		replacement: "bar",
+		want: `--- a/a.go
++++ b/a.go
+@@ -1,2 +1,2 @@
+ a
+-foo
+\ No newline at end of file
++bar
+\ No newline at end of file
+`,
+	}}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			zipData, err := testutil.CreateZip(map[string]string{"a.go": tt.data})
+			if err != nil {
+				t.Fatal(err)
+			}
+			zf, err := store.MockZipFile(zipData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rg, err := compile(&tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			diffs, _, err := regexReplace(context.Background(), rg, zf, 0, []byte(tt.replacement))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if len(diffs) > 0 {
+				got = diffs[0].Diff
+			}
+			if got != tt.want {
+				d, err := testutil.Diff(tt.want, got)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Fatalf("unexpected diff:\n%s", d)
+			}
+		})
+	}
+}
+
+func TestNativeStructuralReplace(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"main.go": "package main\n\n// fmt.Sprintf(\"%d\", x)\nvar s = fmt.Sprintf(\"%d\",\n\tf(x, y))\n",
+		"main.py": "fmt.Sprintf(x, y)\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &protocol.ReplaceRequest{
+		Request: protocol.Request{
+			PatternInfo: protocol.PatternInfo{
+				Pattern:         "fmt.Sprintf(:[format], :[args])",
+				IncludePatterns: []string{".go"},
+			},
+		},
+		Replacement: "fmt.Sprint(:[args])",
+	}
+	diffs, limitHit, err := nativeStructuralReplace(context.Background(), zf, nil, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limitHit {
+		t.Error("unexpected limitHit")
+	}
+
+	want := []protocol.FileDiff{{
+		Path: "main.go",
+		Diff: `--- a/main.go
++++ b/main.go
+@@ -1,5 +1,4 @@
+ package main
+` + " " + `
+ // fmt.Sprintf("%d", x)
+-var s = fmt.Sprintf("%d",
+-	f(x, y))
++var s = fmt.Sprint(f(x, y))
+`,
+	}}
+	if diff := cmp.Diff(want, diffs); diff != "" {
+		t.Fatalf("unexpected diffs (-want +got):\n%s", diff)
+	}
+}
+
+func TestReplace_limitHit(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"a.txt": strings.Repeat("foo\n", maxReplaceMatches+1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rg, err := compile(&protocol.PatternInfo{Pattern: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, limitHit, err := regexReplace(context.Background(), rg, zf, 0, []byte("bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limitHit {
+		t.Error("expected limitHit")
+	}
+	if len(diffs) != 1 {
+		t.Fatalf("got %d diffs, want 1", len(diffs))
+	}
+	if n := strings.Count(diffs[0].Diff, "\n+bar"); n != maxReplaceMatches {
+		t.Errorf("got %d replaced lines, want %d", n, maxReplaceMatches)
+	}
+}
+
+func TestWithGitDiffHeader(t *testing.T) {
+	got := withGitDiffHeader("a.go", "--- a.go\n+++ a.go\n@@ -1,1 +1,1 @@\n-foo\n+bar")
+	want := "--- a/a.go\n+++ b/a.go\n@@ -1,1 +1,1 @@\n-foo\n+bar\n"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"time"
 
@@ -44,6 +45,10 @@ const (
 	// file.
 	maxLineMatches = 100
 
+	// maxReplaceMatches is the limit on number of matches to replace in a
+	// file.
+	maxReplaceMatches = 1000
+
 	// numWorkers is how many concurrent readerGreps run in the case of
 	// regexSearch, and the number of parallel workers in the case of
 	// structuralSearch.
@@ -54,6 +59,11 @@ const (
 type Service struct {
 	Store *store.Store
 	Log   log15.Logger
+
+	// StructuralEngine is the engine used for structural search, one of the
+	// StructuralEngine constants. The zero value is the same as
+	// StructuralEngineAuto.
+	StructuralEngine string
 }
 
 var decoder = schema.NewDecoder()
@@ -80,16 +90,12 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to decode form: "+err.Error(), http.StatusBadRequest)
 		return
 	}
-	if p.Deadline != "" {
-		var deadline time.Time
-		if err := deadline.UnmarshalText([]byte(p.Deadline)); err != nil {
-			http.Error(w, "invalid deadline: "+err.Error(), http.StatusBadRequest)
-			return
-		}
-		dctx, cancel := context.WithDeadline(ctx, deadline)
-		defer cancel()
-		ctx = dctx
+	ctx, cancel, err := withDeadline(ctx, p.Deadline)
+	if err != nil {
+		http.Error(w, "invalid deadline: "+err.Error(), http.StatusBadRequest)
+		return
 	}
+	defer cancel()
 	if !p.PatternMatchesContent && !p.PatternMatchesPath {
 		// BACKCOMPAT: Old frontends send neither of these fields, but we still want to
 		// search file content in that case.
@@ -100,7 +106,22 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matches, limitHit, deadlineHit, err := s.search(ctx, &p)
+	var (
+		sw      *streamWriter
+		onMatch onMatchFunc
+	)
+	if p.Stream {
+		sw = newStreamWriter(w)
+		onMatch = sw.Match
+	}
+
+	matches, limitHit, deadlineHit, err := s.search(ctx, &p, onMatch)
+	if err != nil && sw != nil && sw.Started() {
+		// We have already sent a 200 response with some matches, so the
+		// error can only be reported in the final event.
+		sw.Done(&protocol.StreamDone{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		code := http.StatusInternalServerError
 		if isBadRequest(err) || ctx.Err() == context.Canceled {
@@ -113,6 +134,14 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), code)
 		return
 	}
+	if sw != nil {
+		// All matches have already been sent.
+		sw.Done(&protocol.StreamDone{
+			LimitHit:    limitHit,
+			DeadlineHit: deadlineHit,
+		})
+		return
+	}
 	if matches == nil {
 		// Return an empty list
 		matches = make([]protocol.FileMatch, 0)
@@ -131,7 +160,9 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(&resp)
 }
 
-func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []protocol.FileMatch, limitHit, deadlineHit bool, err error) {
+// search runs the search described by p. If onMatch is non-nil, it is called
+// with each FileMatch as soon as it is found.
+func (s *Service) search(ctx context.Context, p *protocol.Request, onMatch onMatchFunc) (matches []protocol.FileMatch, limitHit, deadlineHit bool, err error) {
 	tr := nettrace.New("search", fmt.Sprintf("%s@%s", p.Repo, p.Commit))
 	tr.LazyPrintf("%s", p.Pattern)
 
@@ -152,6 +183,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	span.SetTag("patternMatchesContent", p.PatternMatchesContent)
 	span.SetTag("patternMatchesPath", p.PatternMatchesPath)
 	span.SetTag("deadline", p.Deadline)
+	span.SetTag("stream", p.Stream)
 	defer func(start time.Time) {
 		code := "200"
 		// We often have canceled and timed out requests. We do not want to
@@ -197,32 +229,26 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 			return nil, false, false, badRequestError{err.Error()}
 		}
 	}
-
-	if p.FetchTimeout == "" {
-		p.FetchTimeout = "500ms"
-	}
-	fetchTimeout, err := time.ParseDuration(p.FetchTimeout)
+	fp, err := compileFilePredicates(&p.PatternInfo)
 	if err != nil {
-		return nil, false, false, err
+		return nil, false, false, badRequestError{err.Error()}
 	}
-	prepareCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
-	defer cancel()
 
-	getZf := func() (string, *store.ZipFile, error) {
-		path, err := s.Store.PrepareZip(prepareCtx, gitserver.Repo{Name: p.Repo}, p.Commit)
-		if err != nil {
-			return "", nil, err
-		}
-		zf, err := s.Store.ZipCache.Get(path)
-		return path, zf, err
-	}
-
-	zipPath, zf, err := store.GetZipFileWithRetry(getZf)
+	zipPath, zf, err := s.getZipFile(ctx, p)
 	if err != nil {
 		return nil, false, false, errors.Wrap(err, "failed to get archive")
 	}
 	defer zf.Close()
 
+	// files are the files which satisfy the file predicates, if any.
+	var files map[string]struct{}
+	if fp != nil {
+		files, err = applyFilePredicates(ctx, &p.PatternInfo, fp, rg, zf)
+		if err != nil || len(files) == 0 {
+			return nil, false, false, err
+		}
+	}
+
 	nFiles := uint64(len(zf.Files))
 	bytes := int64(len(zf.Data))
 	tr.LazyPrintf("files=%d bytes=%d", nFiles, bytes)
@@ -232,14 +258,63 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	archiveFiles.Observe(float64(nFiles))
 	archiveSize.Observe(float64(bytes))
 
-	if p.IsStructuralPat {
-		matches, limitHit, err = structuralSearch(ctx, zipPath, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, p.Repo)
+	if p.IsStructuralPat && s.useNativeStructural() {
+		matches, limitHit, err = nativeStructuralSearch(ctx, zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, files, p.Repo, onMatch)
+	} else if p.IsStructuralPat {
+		if files != nil {
+			if zipPath, err = zipFiles(zf, files); err != nil {
+				return nil, false, false, err
+			}
+			defer os.Remove(zipPath)
+		}
+		matches, limitHit, err = structuralSearch(ctx, zipPath, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, p.Repo, onMatch)
 	} else {
-		matches, limitHit, err = regexSearch(ctx, rg, zf, p.FileMatchLimit, p.PatternMatchesContent, p.PatternMatchesPath, p.IsNegated)
+		// near(N) only relates lines of file content, not file paths.
+		patternMatchesPath := p.PatternMatchesPath && p.NearPattern == ""
+		matches, limitHit, err = regexSearch(ctx, rg, zf, p.FileMatchLimit, p.PatternMatchesContent, patternMatchesPath, p.IsNegated, onMatch)
 	}
 	return matches, limitHit, false, err
 }
 
+// withDeadline returns a context with the deadline encoded in deadline (see
+// protocol.Request.Deadline). If deadline is empty ctx is returned as is.
+func withDeadline(ctx context.Context, deadline string) (context.Context, context.CancelFunc, error) {
+	if deadline == "" {
+		return ctx, func() {}, nil
+	}
+	var t time.Time
+	if err := t.UnmarshalText([]byte(deadline)); err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithDeadline(ctx, t)
+	return ctx, cancel, nil
+}
+
+// getZipFile fetches the archive for the repository and commit of p, waiting
+// at most p.FetchTimeout. The caller must close the returned ZipFile.
+func (s *Service) getZipFile(ctx context.Context, p *protocol.Request) (string, *store.ZipFile, error) {
+	if p.FetchTimeout == "" {
+		p.FetchTimeout = "500ms"
+	}
+	fetchTimeout, err := time.ParseDuration(p.FetchTimeout)
+	if err != nil {
+		return "", nil, err
+	}
+	prepareCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	getZf := func() (string, *store.ZipFile, error) {
+		path, err := s.Store.PrepareZip(prepareCtx, gitserver.Repo{Name: p.Repo}, p.Commit)
+		if err != nil {
+			return "", nil, err
+		}
+		zf, err := s.Store.ZipCache.Get(path)
+		return path, zf, err
+	}
+
+	return store.GetZipFileWithRetry(getZf)
+}
+
 func validateParams(p *protocol.Request) error {
 	if p.Repo == "" {
 		return errors.New("Repo must be non-empty")
@@ -254,6 +329,14 @@ func validateParams(p *protocol.Request) error {
 	if p.IsNegated && p.IsStructuralPat {
 		return errors.New("Negated patterns are not supported for structural searches")
 	}
+	if p.NearPattern != "" {
+		if p.Pattern == "" || p.IsNegated || p.IsStructuralPat {
+			return errors.New("NearPattern requires a non-negated, non-structural Pattern")
+		}
+		if p.NearDistance < 0 {
+			return errors.Errorf("NearDistance must be non-negative (NearDistance=%d)", p.NearDistance)
+		}
+	}
 	return nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"io"
 	"regexp"
 	"regexp/syntax"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -61,6 +62,22 @@ type readerGrep struct {
 	// re. It is the output of the longestLiteral function. It is only set if
 	// the regex has an empty LiteralPrefix.
 	literalSubstring []byte
+
+	// multiline is true if re can match a newline. In that case we use
+	// FindMultiline to report the full range of matches.
+	multiline bool
+
+	// near is set for near(N) queries like "lock near(5) unlock", see
+	// protocol.PatternInfo.NearPattern.
+	near *nearGrep
+}
+
+// nearGrep is the second pattern of a near(N) query. It is immutable, so it
+// is shared between copies of a readerGrep.
+type nearGrep struct {
+	re               *regexp.Regexp
+	literalSubstring []byte
+	distance         int
 }
 
 // compile returns a readerGrep for matching p.
@@ -68,47 +85,25 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 	var (
 		re               *regexp.Regexp
 		literalSubstring []byte
+		multiline        bool
+		near             *nearGrep
 	)
 	if p.Pattern != "" {
-		expr := p.Pattern
-		if !p.IsRegExp {
-			expr = regexp.QuoteMeta(expr)
-		}
-		if p.IsWordMatch {
-			expr = `\b` + expr + `\b`
-		}
-		if p.IsRegExp {
-			// We don't do the search line by line, therefore we want the
-			// regex engine to consider newlines for anchors (^$).
-			expr = "(?m:" + expr + ")"
-		}
-		if !p.IsCaseSensitive {
-			// We don't just use (?i) because regexp library doesn't seem
-			// to contain good optimizations for case insensitive
-			// search. Instead we lowercase the input and pattern.
-			re, err := syntax.Parse(expr, syntax.Perl)
-			if err != nil {
-				return nil, err
-			}
-			lowerRegexpASCII(re)
-			expr = re.String()
-		}
-
 		var err error
-		re, err = regexp.Compile(expr)
+		re, literalSubstring, multiline, err = compilePattern(p.Pattern, p)
 		if err != nil {
 			return nil, err
 		}
-
-		// Only use literalSubstring optimization if the regex engine doesn't
-		// have a prefix to use.
-		if pre, _ := re.LiteralPrefix(); pre == "" {
-			ast, err := syntax.Parse(expr, syntax.Perl)
-			if err != nil {
-				return nil, err
-			}
-			ast = ast.Simplify()
-			literalSubstring = []byte(longestLiteral(ast))
+	}
+	if p.NearPattern != "" {
+		nearRe, nearLiteralSubstring, _, err := compilePattern(p.NearPattern, p)
+		if err != nil {
+			return nil, err
+		}
+		near = &nearGrep{
+			re:               nearRe,
+			literalSubstring: nearLiteralSubstring,
+			distance:         p.NearDistance,
 		}
 	}
 
@@ -126,9 +121,61 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 		ignoreCase:       !p.IsCaseSensitive,
 		matchPath:        matchPath,
 		literalSubstring: literalSubstring,
+		multiline:        multiline,
+		near:             near,
 	}, nil
 }
 
+// compilePattern compiles pattern, which is interpreted according to the
+// options of p.
+func compilePattern(pattern string, p *protocol.PatternInfo) (re *regexp.Regexp, literalSubstring []byte, multiline bool, err error) {
+	expr := pattern
+	if !p.IsRegExp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if p.IsWordMatch {
+		expr = `\b` + expr + `\b`
+	}
+	// Decide on the pattern as written, since lowering it below expands
+	// classes such as \s into ranges which contain a newline.
+	multiline = matchesNewline(expr)
+	if p.IsRegExp {
+		// We don't do the search line by line, therefore we want the
+		// regex engine to consider newlines for anchors (^$).
+		expr = "(?m:" + expr + ")"
+	}
+	if !p.IsCaseSensitive {
+		// We don't just use (?i) because regexp library doesn't seem
+		// to contain good optimizations for case insensitive
+		// search. Instead we lowercase the input and pattern.
+		re, err := syntax.Parse(expr, syntax.Perl)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		lowerRegexpASCII(re)
+		expr = re.String()
+	}
+
+	re, err = regexp.Compile(expr)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	ast, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	ast = ast.Simplify()
+
+	// Only use literalSubstring optimization if the regex engine doesn't
+	// have a prefix to use.
+	if pre, _ := re.LiteralPrefix(); pre == "" {
+		literalSubstring = []byte(longestLiteral(ast))
+	}
+
+	return re, literalSubstring, multiline, nil
+}
+
 // Copy returns a copied version of rg that is safe to use from another
 // goroutine.
 func (rg *readerGrep) Copy() *readerGrep {
@@ -137,6 +184,8 @@ func (rg *readerGrep) Copy() *readerGrep {
 		ignoreCase:       rg.ignoreCase,
 		matchPath:        rg.matchPath,
 		literalSubstring: rg.literalSubstring,
+		multiline:        rg.multiline,
+		near:             rg.near,
 	}
 }
 
@@ -158,22 +207,20 @@ func (rg *readerGrep) matchString(s string) bool {
 func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []protocol.LineMatch, limitHit bool, err error) {
 	// fileMatchBuf is what we run match on, fileBuf is the original
 	// data (for Preview).
-	fileBuf := zf.DataFor(f)
-	fileMatchBuf := fileBuf
+	fileBuf, fileMatchBuf := rg.buffers(zf, f)
 
-	// If we are ignoring case, we transform the input instead of
-	// relying on the regular expression engine which can be
-	// slow. compile has already lowercased the pattern. We also
-	// trade some correctness for perf by using a non-utf8 aware
-	// lowercase function.
-	if rg.ignoreCase {
-		if rg.transformBuf == nil {
-			rg.transformBuf = make([]byte, zf.MaxLen)
-		}
-		fileMatchBuf = rg.transformBuf[:len(fileBuf)]
-		bytesToLowerASCII(fileMatchBuf, fileBuf)
+	matches, limitHit = findLineMatches(rg.re, rg.literalSubstring, fileBuf, fileMatchBuf)
+	if rg.near != nil && len(matches) > 0 {
+		nearMatches, nearLimitHit := findLineMatches(rg.near.re, rg.near.literalSubstring, fileBuf, fileMatchBuf)
+		matches = filterNear(matches, nearMatches, rg.near.distance)
+		limitHit = limitHit || nearLimitHit
 	}
+	return matches, limitHit, nil
+}
 
+// findLineMatches returns a LineMatch for each match of re in fileMatchBuf.
+// literalSubstring must appear in any match of re.
+func findLineMatches(re *regexp.Regexp, literalSubstring, fileBuf, fileMatchBuf []byte) (matches []protocol.LineMatch, limitHit bool) {
 	// Most files will not have a match and we bound the number of matched
 	// files we return. So we can avoid the overhead of parsing out new lines
 	// and repeatedly running the regex engine by running a single match over
@@ -181,11 +228,11 @@ func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []proto
 	// searching for results. We use the same approach when we search
 	// per-line. Additionally if we have a non-empty literalSubstring, we use
 	// that to prune out files since doing bytes.Index is very fast.
-	if !bytes.Contains(fileMatchBuf, rg.literalSubstring) {
-		return nil, false, nil
+	if !bytes.Contains(fileMatchBuf, literalSubstring) {
+		return nil, false
 	}
 
-	locs := rg.re.FindAllIndex(fileMatchBuf, maxLineMatches+1)
+	locs := re.FindAllIndex(fileMatchBuf, maxLineMatches+1)
 	lastStart := 0
 	lastLineNumber := 0
 	lastMatchIndex := 0
@@ -224,7 +271,166 @@ func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []proto
 			break
 		}
 	}
-	return matches, limitHit, nil
+	return matches, limitHit
+}
+
+// filterNear returns the matches of a and b, which are ordered by line, that
+// are at most distance lines away from a match of the other. The result has a
+// single LineMatch per line, ordered by line.
+func filterNear(a, b []protocol.LineMatch, distance int) []protocol.LineMatch {
+	var near []protocol.LineMatch
+	near = appendNear(near, a, b, distance)
+	near = appendNear(near, b, a, distance)
+	sort.SliceStable(near, func(i, j int) bool {
+		return near[i].LineNumber < near[j].LineNumber
+	})
+
+	var matches []protocol.LineMatch
+	for _, m := range near {
+		if n := len(matches); n > 0 && matches[n-1].LineNumber == m.LineNumber {
+			matches[n-1].OffsetAndLengths = append(matches[n-1].OffsetAndLengths, m.OffsetAndLengths...)
+			continue
+		}
+		m.OffsetAndLengths = append([][2]int{}, m.OffsetAndLengths...)
+		matches = append(matches, m)
+	}
+	for _, m := range matches {
+		sort.Slice(m.OffsetAndLengths, func(i, j int) bool {
+			return m.OffsetAndLengths[i][0] < m.OffsetAndLengths[j][0]
+		})
+	}
+	return matches
+}
+
+// appendNear appends the matches of a which are at most distance lines away
+// from a match of b, which is ordered by line.
+func appendNear(matches, a, b []protocol.LineMatch, distance int) []protocol.LineMatch {
+	for _, m := range a {
+		i := sort.Search(len(b), func(i int) bool { return b[i].LineNumber >= m.LineNumber-distance })
+		if i < len(b) && b[i].LineNumber <= m.LineNumber+distance {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// buffers returns the data for f (for Preview) and the data we should run
+// the regexp against.
+func (rg *readerGrep) buffers(zf *store.ZipFile, f *store.SrcFile) (fileBuf, fileMatchBuf []byte) {
+	fileBuf = zf.DataFor(f)
+	fileMatchBuf = fileBuf
+
+	// If we are ignoring case, we transform the input instead of
+	// relying on the regular expression engine which can be
+	// slow. compile has already lowercased the pattern. We also
+	// trade some correctness for perf by using a non-utf8 aware
+	// lowercase function.
+	if rg.ignoreCase {
+		if rg.transformBuf == nil {
+			rg.transformBuf = make([]byte, zf.MaxLen)
+		}
+		fileMatchBuf = rg.transformBuf[:len(fileBuf)]
+		bytesToLowerASCII(fileMatchBuf, fileBuf)
+	}
+	return fileBuf, fileMatchBuf
+}
+
+// FindMultiline is like Find, but also returns the Range of every match.
+// Matches spanning multiple lines are chopped up into a LineMatch per line,
+// similar to what highlightMultipleLines does for structural search. Unlike
+// Find, highlights on the same line are merged into a single LineMatch.
+// NOTE: This is not safe to use concurrently.
+func (rg *readerGrep) FindMultiline(zf *store.ZipFile, f *store.SrcFile) (matches []protocol.LineMatch, ranges []protocol.Range, limitHit bool, err error) {
+	fileBuf, fileMatchBuf := rg.buffers(zf, f)
+
+	// See Find for why we first check the literal substring.
+	if !bytes.Contains(fileMatchBuf, rg.literalSubstring) {
+		return nil, nil, false, nil
+	}
+
+	locs := rg.re.FindAllIndex(fileMatchBuf, maxLineMatches+1)
+	if len(locs) > maxLineMatches {
+		locs = locs[:maxLineMatches]
+		limitHit = true
+	}
+
+	// Matches are returned in order and do not overlap, so we can compute
+	// locations incrementally.
+	li := lineIndex{buf: fileBuf}
+	for _, loc := range locs {
+		r := protocol.Range{Start: li.locate(loc[0]), End: li.locate(loc[1])}
+		ranges = append(ranges, r)
+		matches = appendRange(matches, fileBuf, r)
+	}
+	return matches, ranges, limitHit, nil
+}
+
+// lineIndex converts byte offsets into Locations. Offsets passed to locate
+// must be non-decreasing.
+type lineIndex struct {
+	buf       []byte
+	line      int
+	lineStart int
+}
+
+func (li *lineIndex) locate(offset int) protocol.Location {
+	for {
+		idx := bytes.IndexByte(li.buf[li.lineStart:offset], '\n')
+		if idx < 0 {
+			break
+		}
+		li.lineStart += idx + 1
+		li.line++
+	}
+	return protocol.Location{
+		Offset: offset,
+		Line:   li.line,
+		Column: utf8.RuneCount(li.buf[li.lineStart:offset]),
+	}
+}
+
+// appendRange appends a LineMatch for each line r spans. A match ending
+// directly after a newline does not highlight the following line.
+func appendRange(matches []protocol.LineMatch, fileBuf []byte, r protocol.Range) []protocol.LineMatch {
+	lineStart := bytes.LastIndexByte(fileBuf[:r.Start.Offset], '\n') + 1
+	for line := r.Start.Line; line <= r.End.Line; line++ {
+		if line > r.Start.Line && line == r.End.Line && r.End.Column == 0 {
+			break
+		}
+
+		lineEnd := len(fileBuf)
+		if idx := bytes.IndexByte(fileBuf[lineStart:], '\n'); idx >= 0 {
+			lineEnd = lineStart + idx
+		}
+
+		start, end := lineStart, lineEnd
+		if line == r.Start.Line {
+			start = r.Start.Offset
+		}
+		if line == r.End.Line {
+			end = r.End.Offset
+		}
+		offsetAndLength := [2]int{
+			utf8.RuneCount(fileBuf[lineStart:start]),
+			utf8.RuneCount(fileBuf[start:end]),
+		}
+
+		// Ranges are ordered, so we only ever need to merge with the last
+		// LineMatch.
+		if n := len(matches); n > 0 && matches[n-1].LineNumber == line {
+			matches[n-1].OffsetAndLengths = append(matches[n-1].OffsetAndLengths, offsetAndLength)
+		} else {
+			matches = append(matches, protocol.LineMatch{
+				// Copy, see the comment on Preview in appendMatches.
+				Preview:          string(fileBuf[lineStart:lineEnd]),
+				LineNumber:       line,
+				OffsetAndLengths: [][2]int{offsetAndLength},
+			})
+		}
+
+		lineStart = lineEnd + 1
+	}
+	return matches
 }
 
 func hydrateLineNumbers(fileBuf []byte, lastLineNumber, lastMatchIndex, lineStart int, match []int) (lineNumber, matchIndex int) {
@@ -283,8 +489,20 @@ func appendMatches(matches []protocol.LineMatch, fileBuf []byte, matchLineBuf []
 	return matches
 }
 
-// FindZip is a convenience function to run Find on f.
+// FindZip is a convenience function to run Find (or FindMultiline if the
+// pattern can match across lines) on f.
 func (rg *readerGrep) FindZip(zf *store.ZipFile, f *store.SrcFile) (protocol.FileMatch, error) {
+	if rg.multiline && rg.near == nil {
+		lm, ranges, limitHit, err := rg.FindMultiline(zf, f)
+		return protocol.FileMatch{
+			Path:        f.Name,
+			LineMatches: lm,
+			MatchCount:  len(ranges),
+			LimitHit:    limitHit,
+			Ranges:      ranges,
+		}, err
+	}
+
 	lm, limitHit, err := rg.Find(zf, f)
 	return protocol.FileMatch{
 		Path:        f.Name,
@@ -295,7 +513,8 @@ func (rg *readerGrep) FindZip(zf *store.ZipFile, f *store.SrcFile) (protocol.Fil
 }
 
 // regexSearch concurrently searches files in zr looking for matches using rg.
-func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMatchLimit int, patternMatchesContent, patternMatchesPaths bool, isPatternNegated bool) (fm []protocol.FileMatch, limitHit bool, err error) {
+// If onMatch is non-nil, it is called with each FileMatch as it is found.
+func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMatchLimit int, patternMatchesContent, patternMatchesPaths bool, isPatternNegated bool, onMatch onMatchFunc) (fm []protocol.FileMatch, limitHit bool, err error) {
 	span, ctx := ot.StartSpanFromContext(ctx, "RegexSearch")
 	ext.Component.Set(span, "regex_search")
 	if rg.re != nil {
@@ -343,7 +562,11 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 		for _, f := range files {
 			if match := rg.matchPath.MatchPath(f.Name) && rg.matchString(f.Name); match == !isPatternNegated {
 				if len(matches) < fileMatchLimit {
-					matches = append(matches, protocol.FileMatch{Path: f.Name})
+					fm := protocol.FileMatch{Path: f.Name}
+					matches = append(matches, fm)
+					if onMatch != nil {
+						onMatch(fm)
+					}
 				} else {
 					limitHit = true
 					break
@@ -413,13 +636,21 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 				}
 				if match == !isPatternNegated {
 					matchesmu.Lock()
-					if len(matches) < fileMatchLimit {
+					added := len(matches) < fileMatchLimit
+					if added {
 						matches = append(matches, fm)
 					} else {
 						limitHit = true
 						cancel()
 					}
 					matchesmu.Unlock()
+
+					// onMatch may write to the network, so call it
+					// without holding matchesmu to not block the
+					// other workers on a slow client.
+					if added && onMatch != nil {
+						onMatch(fm)
+					}
 				}
 			}
 		}(rg.Copy())
@@ -584,6 +815,51 @@ func lowerRegexpASCII(re *syntax.Regexp) {
 	}
 }
 
+// matchesNewline returns true if expr explicitly matches a newline, in which
+// case matches may span multiple lines. This is the case for patterns
+// containing \n and patterns using the (?s) flag for ".". Patterns which only
+// incidentally match a newline, such as \s or [^a], are still matched line by
+// line.
+func matchesNewline(expr string) bool {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	explicit := strings.Contains(expr, `\n`) || strings.Contains(expr, "\n")
+	return containsNewlineOp(re.Simplify(), explicit)
+}
+
+// containsNewlineOp returns true if re contains a "." matching newlines or a
+// literal newline. Character classes containing a newline only count if
+// classes is true, since they may be written without an explicit \n.
+func containsNewlineOp(re *syntax.Regexp, classes bool) bool {
+	switch re.Op {
+	case syntax.OpAnyChar:
+		return true
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if r == '\n' {
+				return true
+			}
+		}
+	case syntax.OpCharClass:
+		if !classes {
+			break
+		}
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			if re.Rune[i] <= '\n' && '\n' <= re.Rune[i+1] {
+				return true
+			}
+		}
+	}
+	for _, sub := range re.Sub {
+		if containsNewlineOp(sub, classes) {
+			return true
+		}
+	}
+	return false
+}
+
 // longestLiteral finds the longest substring that is guaranteed to appear in
 // a match of re.
 //
@@ -265,7 +265,7 @@ func benchSearchRegex(b *testing.B, p *protocol.Request) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		_, _, err := regexSearch(ctx, rg, zf, 0, p.PatternMatchesContent, p.PatternMatchesPath, p.IsNegated)
+		_, _, err := regexSearch(ctx, rg, zf, 0, p.PatternMatchesContent, p.PatternMatchesPath, p.IsNegated, nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -455,7 +455,7 @@ func TestMaxMatches(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	fileMatches, limitHit, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, false)
+	fileMatches, limitHit, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, false, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -507,7 +507,7 @@ func TestPathMatches(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	fileMatches, _, err := regexSearch(context.Background(), rg, zf, 10, true, true, false)
+	fileMatches, _, err := regexSearch(context.Background(), rg, zf, 10, true, true, false, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -582,7 +582,7 @@ func TestRegexSearch(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotFm, gotLimitHit, err := regexSearch(tt.args.ctx, tt.args.rg, tt.args.zf, tt.args.fileMatchLimit, tt.args.patternMatchesContent, tt.args.patternMatchesPaths, false)
+			gotFm, gotLimitHit, err := regexSearch(tt.args.ctx, tt.args.rg, tt.args.zf, tt.args.fileMatchLimit, tt.args.patternMatchesContent, tt.args.patternMatchesPaths, false, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("regexSearch() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -596,3 +596,111 @@ func TestRegexSearch(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchesNewline(t *testing.T) {
+	cases := map[string]bool{
+		"foo":          false,
+		"foo.*bar":     false,
+		"[^a]":         false,
+		`foo\nbar`:     true,
+		`foo[\t\n]bar`: true,
+		`foo\s+bar`:    false,
+		`foo\s*\n`:     true,
+		`(?s)foo.*bar`: true,
+		`(?s:foo).*`:   false,
+		`[a-z]+`:       false,
+		`^$`:           false,
+	}
+	for pattern, want := range cases {
+		if got := matchesNewline(pattern); got != want {
+			t.Errorf("matchesNewline(%q) == %v != %v", pattern, got, want)
+		}
+	}
+}
+
+func TestFindMultiline(t *testing.T) {
+	cases := []struct {
+		name        string
+		data        string
+		pattern     string
+		wantMatches []protocol.LineMatch
+		wantRanges  []protocol.Range
+	}{
+		{
+			name:    "match spanning lines",
+			data:    "package main\n\nfunc main() {\n\tfoo(a,\n\t\tb)\n}\n",
+			pattern: `(?s)foo\(.*?\)`,
+			wantMatches: []protocol.LineMatch{
+				{Preview: "\tfoo(a,", LineNumber: 3, OffsetAndLengths: [][2]int{{1, 6}}},
+				{Preview: "\t\tb)", LineNumber: 4, OffsetAndLengths: [][2]int{{0, 4}}},
+			},
+			wantRanges: []protocol.Range{{
+				Start: protocol.Location{Offset: 29, Line: 3, Column: 1},
+				End:   protocol.Location{Offset: 40, Line: 4, Column: 4},
+			}},
+		},
+		{
+			name:    "same line matches are merged and trailing newline is not highlighted",
+			data:    "fooo\nbo",
+			pattern: `(?s)o.`,
+			wantMatches: []protocol.LineMatch{
+				{Preview: "fooo", LineNumber: 0, OffsetAndLengths: [][2]int{{1, 2}, {3, 1}}},
+			},
+			wantRanges: []protocol.Range{{
+				Start: protocol.Location{Offset: 1, Line: 0, Column: 1},
+				End:   protocol.Location{Offset: 3, Line: 0, Column: 3},
+			}, {
+				Start: protocol.Location{Offset: 3, Line: 0, Column: 3},
+				End:   protocol.Location{Offset: 5, Line: 1, Column: 0},
+			}},
+		},
+		{
+			name:    "columns are measured in characters",
+			data:    "héllo\nwörld",
+			pattern: `LLO\nW`,
+			wantMatches: []protocol.LineMatch{
+				{Preview: "héllo", LineNumber: 0, OffsetAndLengths: [][2]int{{2, 3}}},
+				{Preview: "wörld", LineNumber: 1, OffsetAndLengths: [][2]int{{0, 1}}},
+			},
+			wantRanges: []protocol.Range{{
+				Start: protocol.Location{Offset: 3, Line: 0, Column: 2},
+				End:   protocol.Location{Offset: 8, Line: 1, Column: 1},
+			}},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			zipData, err := testutil.CreateZip(map[string]string{"a.go": tt.data})
+			if err != nil {
+				t.Fatal(err)
+			}
+			zf, err := store.MockZipFile(zipData)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rg, err := compile(&protocol.PatternInfo{Pattern: tt.pattern, IsRegExp: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !rg.multiline {
+				t.Fatalf("expected %q to be a multiline pattern", tt.pattern)
+			}
+
+			fm, err := rg.FindZip(zf, &zf.Files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fm.LineMatches, tt.wantMatches) {
+				t.Errorf("got line matches %+v, want %+v", fm.LineMatches, tt.wantMatches)
+			}
+			if !reflect.DeepEqual(fm.Ranges, tt.wantRanges) {
+				t.Errorf("got ranges %+v, want %+v", fm.Ranges, tt.wantRanges)
+			}
+			if fm.MatchCount != len(tt.wantRanges) {
+				t.Errorf("got match count %d, want %d", fm.MatchCount, len(tt.wantRanges))
+			}
+		})
+	}
+}
@@ -11,8 +11,35 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/comby"
+	"github.com/sourcegraph/sourcegraph/internal/search/structural"
+	"github.com/sourcegraph/sourcegraph/internal/store"
 )
 
+// The engines which can run structural searches, see Service.StructuralEngine.
+const (
+	// StructuralEngineAuto uses comby if it is installed and the native
+	// engine otherwise.
+	StructuralEngineAuto = "auto"
+	// StructuralEngineComby runs the comby binary.
+	StructuralEngineComby = "comby"
+	// StructuralEngineNative uses the Go implementation in
+	// internal/search/structural. It does not support rules.
+	StructuralEngineNative = "native"
+)
+
+// useNativeStructural returns true if structural searches should use the
+// native engine instead of comby.
+func (s *Service) useNativeStructural() bool {
+	switch s.StructuralEngine {
+	case StructuralEngineNative:
+		return true
+	case StructuralEngineComby:
+		return false
+	default:
+		return !comby.Exists()
+	}
+}
+
 // The Sourcegraph frontend and interface only allow LineMatches (matches on a
 // single line) and it isn't possible to specify a line and column range
 // spanning multiple lines for highlighting. This function chops up potentially
@@ -67,21 +94,24 @@ func highlightMultipleLines(r *comby.Match) (matches []protocol.LineMatch) {
 
 func ToFileMatch(combyMatches []comby.FileMatch) (matches []protocol.FileMatch) {
 	for _, m := range combyMatches {
-		var lineMatches []protocol.LineMatch
-		for _, r := range m.Matches {
-			lineMatches = append(lineMatches, highlightMultipleLines(&r)...)
-		}
-		matches = append(matches,
-			protocol.FileMatch{
-				Path:        m.URI,
-				LineMatches: lineMatches,
-				MatchCount:  len(m.Matches),
-				LimitHit:    false,
-			})
+		matches = append(matches, toFileMatch(&m))
 	}
 	return matches
 }
 
+func toFileMatch(m *comby.FileMatch) protocol.FileMatch {
+	var lineMatches []protocol.LineMatch
+	for _, r := range m.Matches {
+		lineMatches = append(lineMatches, highlightMultipleLines(&r)...)
+	}
+	return protocol.FileMatch{
+		Path:        m.URI,
+		LineMatches: lineMatches,
+		MatchCount:  len(m.Matches),
+		LimitHit:    false,
+	}
+}
+
 // lookupMatcher looks up a key for specifying -matcher in comby. Comby accepts
 // a representative file extension to set a language, so this lookup does not
 // need to consider all possible file extensions for a language. There is a generic
@@ -180,20 +210,15 @@ func languageMetric(matcher string, includePatterns *[]string) string {
 	return "inferred:.generic"
 }
 
-func structuralSearch(ctx context.Context, zipPath, pattern, rule string, languages, includePatterns []string, repo api.RepoName) (matches []protocol.FileMatch, limitHit bool, err error) {
+// structuralSearch runs comby over the archive at zipPath. If onMatch is
+// non-nil, it is called with each FileMatch as comby reports it.
+func structuralSearch(ctx context.Context, zipPath, pattern, rule string, languages, includePatterns []string, repo api.RepoName, onMatch onMatchFunc) (matches []protocol.FileMatch, limitHit bool, err error) {
 	log15.Info("structural search", "repo", string(repo))
 
 	// Cap the number of forked processes to limit the size of zip contents being mapped to memory. Resolving #7133 could help to lift this restriction.
 	numWorkers := 4
 
-	var matcher string
-	if len(languages) > 0 {
-		// Pick the first language, there is no support for applying
-		// multiple language matchers in a single search query.
-		matcher = lookupMatcher(languages[0])
-		log15.Debug("structural search", "language", languages[0], "matcher", matcher)
-	}
-
+	matcher := matcherForLanguages(languages)
 	v := languageMetric(matcher, &includePatterns)
 	requestTotalStructuralSearch.WithLabelValues(v).Inc()
 
@@ -207,16 +232,139 @@ func structuralSearch(ctx context.Context, zipPath, pattern, rule string, langua
 		NumWorkers:    numWorkers,
 	}
 
-	combyMatches, err := comby.Matches(ctx, args)
+	err = comby.StreamMatches(ctx, args, func(m comby.FileMatch) {
+		fm := toFileMatch(&m)
+		matches = append(matches, fm)
+		if onMatch != nil {
+			onMatch(fm)
+		}
+	})
 	if err != nil {
 		return nil, false, err
 	}
+	return matches, false, nil
+}
 
-	matches = ToFileMatch(combyMatches)
+// matcherForLanguages returns the comby matcher for the languages of a
+// search, or an empty string to infer the language from the file extension.
+func matcherForLanguages(languages []string) string {
+	if len(languages) == 0 {
+		return ""
+	}
+	// Pick the first language, there is no support for applying
+	// multiple language matchers in a single search query.
+	matcher := lookupMatcher(languages[0])
+	log15.Debug("structural search", "language", languages[0], "matcher", matcher)
+	return matcher
+}
+
+// nativeStructuralSearch is like structuralSearch, but uses the native engine
+// on the already opened archive zf instead of running comby. If files is
+// non-nil, only the files with these names are searched.
+func nativeStructuralSearch(ctx context.Context, zf *store.ZipFile, pattern, rule string, languages, includePatterns []string, files map[string]struct{}, repo api.RepoName, onMatch onMatchFunc) (matches []protocol.FileMatch, limitHit bool, err error) {
+	log15.Info("structural search", "repo", string(repo), "engine", StructuralEngineNative)
+
+	tmpl, err := parseNativeTemplate(pattern, rule)
 	if err != nil {
 		return nil, false, err
 	}
-	return matches, false, err
+
+	matcher := matcherForLanguages(languages)
+	v := languageMetric(matcher, &includePatterns)
+	requestTotalStructuralSearch.WithLabelValues(v).Inc()
+
+	err = nativeStructuralMatches(ctx, zf, tmpl, matcher, includePatterns, files, func(f *store.SrcFile, fileBuf []byte, structuralMatches []structural.Match) {
+		fm := protocol.FileMatch{
+			Path:       f.Name,
+			MatchCount: len(structuralMatches),
+		}
+		li := lineIndex{buf: fileBuf}
+		for _, m := range structuralMatches {
+			r := protocol.Range{Start: li.locate(m.Start), End: li.locate(m.End)}
+			fm.Ranges = append(fm.Ranges, r)
+			fm.LineMatches = appendRange(fm.LineMatches, fileBuf, r)
+		}
+		matches = append(matches, fm)
+		if onMatch != nil {
+			onMatch(fm)
+		}
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return matches, false, nil
+}
+
+// parseNativeTemplate parses a structural search pattern for the native
+// engine.
+func parseNativeTemplate(pattern, rule string) (*structural.Template, error) {
+	if rule != "" {
+		return nil, badRequestError{"rules are not supported by the native structural search engine"}
+	}
+	tmpl, err := structural.ParseTemplate(pattern)
+	if err != nil {
+		return nil, badRequestError{err.Error()}
+	}
+	return tmpl, nil
+}
+
+// nativeStructuralMatches calls onFile with the matches of tmpl for every file
+// in zf with matches. Like for comby, includePatterns are file name suffixes
+// and an empty matcher infers the language of each file from its extension.
+// If files is non-nil, only the files with these names are matched.
+func nativeStructuralMatches(ctx context.Context, zf *store.ZipFile, tmpl *structural.Template, matcher string, includePatterns []string, files map[string]struct{}, onFile func(f *store.SrcFile, fileBuf []byte, matches []structural.Match)) error {
+	var lang *structural.Language
+	if matcher != "" {
+		lang = structural.LanguageForExtension(matcher)
+	}
+
+	for i := range zf.Files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		f := &zf.Files[i]
+		if !hasAnySuffix(f.Name, includePatterns) {
+			continue
+		}
+		if _, ok := files[f.Name]; files != nil && !ok {
+			continue
+		}
+		fileLang := lang
+		if fileLang == nil {
+			fileLang = structural.LanguageForExtension(filepath.Ext(f.Name))
+		}
+
+		fileBuf := zf.DataFor(f)
+		matches, err := tmpl.Matches(fileBuf, fileLang)
+		if err == structural.ErrTooComplex {
+			// Skip the file rather than failing the whole search, this
+			// usually happens for large generated files.
+			log15.Warn("structural search: skipping file", "path", f.Name, "error", err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(matches) > 0 {
+			onFile(f, fileBuf, matches)
+		}
+	}
+	return nil
+}
+
+// hasAnySuffix returns true if name has one of the suffixes, or if there are
+// no suffixes.
+func hasAnySuffix(name string, suffixes []string) bool {
+	if len(suffixes) == 0 {
+		return true
+	}
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
 }
 
 var requestTotalStructuralSearch = prometheus.NewCounterVec(prometheus.CounterOpts{
@@ -11,6 +11,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/comby"
+	"github.com/sourcegraph/sourcegraph/internal/store"
 	"github.com/sourcegraph/sourcegraph/internal/testutil"
 )
 
@@ -67,7 +68,7 @@ func foo(go string) {}
 	for _, tt := range cases {
 		t.Run(tt.Name, func(t *testing.T) {
 			p.Languages = tt.Languages
-			matches, _, err := structuralSearch(context.Background(), zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, "repo_foo")
+			matches, _, err := structuralSearch(context.Background(), zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, "repo_foo", nil)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -119,7 +120,7 @@ func foo(real string) {}
 		Pattern:         pattern,
 		IncludePatterns: includePatterns,
 	}
-	m, _, err := structuralSearch(context.Background(), zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, "foo")
+	m, _, err := structuralSearch(context.Background(), zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, "foo", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -219,7 +220,7 @@ func TestIncludePatterns(t *testing.T) {
 		Pattern:         "",
 		IncludePatterns: includePatterns,
 	}
-	fileMatches, _, err := structuralSearch(context.Background(), zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, "foo")
+	fileMatches, _, err := structuralSearch(context.Background(), zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, "foo", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -260,7 +261,7 @@ func TestRule(t *testing.T) {
 		CombyRule:       `where :[args] == "success"`,
 	}
 
-	got, _, err := structuralSearch(context.Background(), zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, "repo")
+	got, _, err := structuralSearch(context.Background(), zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, "repo", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -414,7 +415,7 @@ func bar() {
 	defer cleanup()
 
 	t.Run("Strutural search match count", func(t *testing.T) {
-		matches, _, err := structuralSearch(context.Background(), zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, "repo_foo")
+		matches, _, err := structuralSearch(context.Background(), zf, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, "repo_foo", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -427,3 +428,86 @@ func bar() {
 		}
 	})
 }
+
+func TestNativeStructuralSearch(t *testing.T) {
+	input := map[string]string{
+		"file_without_extension": `
+/* This foo(plain string) {} is in a Go comment should not match in Go, but should match in plaintext */
+func foo(go string) {}
+`,
+		"main.go": `
+/* foo(ignore string) */
+func foo(real string,
+	other int) {}
+`,
+		"main.c": "foo(c)",
+	}
+
+	cases := []struct {
+		Name            string
+		Languages       []string
+		IncludePatterns []string
+		Want            map[string][]string
+	}{
+		{
+			Name:            "Language test for no language",
+			IncludePatterns: []string{"file_without_extension"},
+			Want: map[string][]string{
+				"file_without_extension": {"foo(plain string)", "foo(go string)"},
+			},
+		},
+		{
+			Name:            "Language test for Go",
+			Languages:       []string{"go"},
+			IncludePatterns: []string{"file_without_extension"},
+			Want: map[string][]string{
+				"file_without_extension": {"foo(go string)"},
+			},
+		},
+		{
+			Name:            "Inferred matcher and multiline match",
+			IncludePatterns: []string{".go"},
+			Want: map[string][]string{
+				"main.go": {"foo(real string,\n\tother int)"},
+			},
+		},
+	}
+
+	zipData, err := testutil.CreateZip(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			matches, _, err := nativeStructuralSearch(context.Background(), zf, "foo(:[args])", "", tt.Languages, tt.IncludePatterns, nil, "repo", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string][]string{}
+			for _, fm := range matches {
+				data := input[fm.Path]
+				for _, r := range fm.Ranges {
+					got[fm.Path] = append(got[fm.Path], data[r.Start.Offset:r.End.Offset])
+				}
+				if fm.MatchCount != len(fm.Ranges) {
+					t.Errorf("got MatchCount %d, want %d", fm.MatchCount, len(fm.Ranges))
+				}
+			}
+			if diff := cmp.Diff(tt.Want, got); diff != "" {
+				t.Fatalf("unexpected matches (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Rules are not supported", func(t *testing.T) {
+		_, _, err := nativeStructuralSearch(context.Background(), zf, "foo(:[args])", `where :[args] == "c"`, nil, nil, nil, "repo", nil)
+		if !isBadRequest(err) {
+			t.Fatalf("expected a bad request error, got %v", err)
+		}
+	})
+}
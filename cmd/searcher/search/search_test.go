@@ -181,6 +181,19 @@ main.go:7:}
 `},
 
 		{protocol.PatternInfo{Pattern: "^$", IsRegExp: true}, ``},
+
+		{protocol.PatternInfo{Pattern: "fmt", NearPattern: "world"}, `
+main.go:6:	fmt.Println("Hello world")
+`},
+		{protocol.PatternInfo{Pattern: "import", NearPattern: "println", NearDistance: 2}, ``},
+		{protocol.PatternInfo{Pattern: "import", NearPattern: "println", NearDistance: 3}, `
+main.go:3:import "fmt"
+main.go:6:	fmt.Println("Hello world")
+`},
+		{protocol.PatternInfo{Pattern: "^func", NearPattern: `^\}`, NearDistance: 2, IsRegExp: true}, `
+main.go:5:func main() {
+main.go:7:}
+`},
 		{protocol.PatternInfo{
 			Pattern:         "filename contains regex metachars",
 			IncludePatterns: []string{"file++.plus"},
@@ -259,6 +272,71 @@ milton.png
 	}
 }
 
+func TestSearch_stream(t *testing.T) {
+	files := map[string]string{
+		"README.md": `# Hello World
+
+Hello world example in go`,
+		"main.go": `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("Hello world")
+}
+`,
+		"abc.txt": "w",
+	}
+
+	cases := []protocol.PatternInfo{
+		{Pattern: "foo"},
+		{Pattern: "world"},
+		{Pattern: "fmt", IsNegated: true},
+		{Pattern: "", IncludePatterns: []string{"go"}},
+	}
+
+	store, cleanup, err := newStore(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	ts := httptest.NewServer(&search.Service{Store: store})
+	defer ts.Close()
+
+	for i, test := range cases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			test.PatternMatchesContent = true
+			req := protocol.Request{
+				Repo:         "foo",
+				URL:          "u",
+				Commit:       "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+				PatternInfo:  test,
+				FetchTimeout: "2000ms",
+			}
+			want, err := doSearch(ts.URL, &req)
+			if err != nil {
+				t.Fatalf("%v failed: %s", test, err)
+			}
+
+			req.Stream = true
+			got, err := doSearch(ts.URL, &req)
+			if err != nil {
+				t.Fatalf("%v failed to stream: %s", test, err)
+			}
+
+			sort.Sort(sortByPath(want))
+			sort.Sort(sortByPath(got))
+			if toString(got) != toString(want) {
+				d, err := testutil.Diff(toString(want), toString(got))
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Fatalf("%s streamed response differs:\n%s", test.String(), d)
+			}
+		})
+	}
+}
+
 func TestSearch_badrequest(t *testing.T) {
 	cases := []protocol.Request{
 		// Bad regexp
@@ -382,12 +460,15 @@ func TestSearch_badrequest(t *testing.T) {
 
 	for _, p := range cases {
 		p.PatternInfo.PatternMatchesContent = true
-		_, err := doSearch(ts.URL, &p)
-		if err == nil {
-			t.Fatalf("%v expected to fail", p)
-		}
-		if !strings.HasPrefix(err.Error(), "non-200 response: code=400 ") {
-			t.Fatalf("%v expected to have HTTP 400 response. Got %s", p, err)
+		for _, stream := range []bool{false, true} {
+			p.Stream = stream
+			_, err := doSearch(ts.URL, &p)
+			if err == nil {
+				t.Fatalf("%v expected to fail", p)
+			}
+			if !strings.HasPrefix(err.Error(), "non-200 response: code=400 ") {
+				t.Fatalf("%v expected to have HTTP 400 response. Got %s", p, err)
+			}
 		}
 	}
 }
@@ -429,6 +510,13 @@ func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 	if p.IsNegated {
 		form.Set("IsNegated", "true")
 	}
+	if p.NearPattern != "" {
+		form.Set("NearPattern", p.NearPattern)
+		form.Set("NearDistance", strconv.Itoa(p.NearDistance))
+	}
+	if p.Stream {
+		form.Set("Stream", "true")
+	}
 	resp, err := http.PostForm(u, form)
 	if err != nil {
 		return nil, err
@@ -442,6 +530,10 @@ func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 		return nil, fmt.Errorf("non-200 response: code=%d body=%s", resp.StatusCode, string(body))
 	}
 
+	if p.Stream {
+		return decodeStream(body)
+	}
+
 	var r protocol.Response
 	err = json.Unmarshal(body, &r)
 	if err != nil {
@@ -450,6 +542,33 @@ func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 	return r.Matches, err
 }
 
+// decodeStream decodes the newline-delimited events of a streaming response,
+// checking that it is terminated by a successful Done event.
+func decodeStream(body []byte) ([]protocol.FileMatch, error) {
+	var matches []protocol.FileMatch
+	dec := json.NewDecoder(bytes.NewReader(body))
+	for {
+		var event protocol.StreamEvent
+		if err := dec.Decode(&event); err != nil {
+			return nil, fmt.Errorf("stream ended without done event: %v", err)
+		}
+		if event.Match != nil {
+			matches = append(matches, *event.Match)
+			continue
+		}
+		if event.Done == nil {
+			return nil, errors.New("stream event without match or done")
+		}
+		if event.Done.Error != "" {
+			return nil, errors.New(event.Done.Error)
+		}
+		if dec.More() {
+			return nil, errors.New("stream has events after done event")
+		}
+		return matches, nil
+	}
+}
+
 func newStore(files map[string]string) (*store.Store, func(), error) {
 	buf := new(bytes.Buffer)
 	w := tar.NewWriter(buf)
@@ -0,0 +1,65 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+// onMatchFunc is called with each FileMatch as soon as it is found, before the
+// search has completed. A nil onMatchFunc means the caller is only
+// interested in the final result. It may be called concurrently.
+type onMatchFunc func(protocol.FileMatch)
+
+// streamWriter writes protocol.StreamEvents as newline-delimited JSON to an
+// http.ResponseWriter. Every event is flushed immediately so that the client
+// can make use of matches while the rest of the archive is still being
+// searched.
+type streamWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	started bool
+}
+
+func newStreamWriter(w http.ResponseWriter) *streamWriter {
+	return &streamWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+// Match sends fm to the client. It is an onMatchFunc.
+func (sw *streamWriter) Match(fm protocol.FileMatch) {
+	sw.send(&protocol.StreamEvent{Match: &fm})
+}
+
+// Done sends the final event to the client.
+func (sw *streamWriter) Done(done *protocol.StreamDone) {
+	sw.send(&protocol.StreamEvent{Done: done})
+}
+
+// Started returns true if an event has been written. Once that is the case it
+// is no longer possible to report errors via the HTTP status code.
+func (sw *streamWriter) Started() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.started
+}
+
+func (sw *streamWriter) send(event *protocol.StreamEvent) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if !sw.started {
+		sw.w.Header().Set("Content-Type", "application/x-ndjson")
+		sw.w.Header().Set("X-Content-Type-Options", "nosniff")
+		sw.started = true
+	}
+
+	// Like in the non-streaming case the only reasonable error is the client
+	// going away, which we can't report to anyone. So we ignore it.
+	_ = sw.enc.Encode(event)
+	if f, ok := sw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -43,8 +43,9 @@ func Init(d dbutil.DB, clock func() time.Time) {
 			return nil
 		}
 
-		// We currently support three types of authz providers: GitHub, GitLab and Bitbucket Server.
-		authzTypes := make(map[string]struct{}, 3)
+		// We currently support seven types of authz providers: GitHub, GitLab, Bitbucket Server,
+		// Bitbucket Cloud, Gitea, Azure DevOps and AWS CodeCommit.
+		authzTypes := make(map[string]struct{}, 7)
 		for _, p := range providers {
 			authzTypes[p.ServiceType()] = struct{}{}
 		}
@@ -58,6 +59,14 @@ func Init(d dbutil.DB, clock func() time.Time) {
 				authzNames = append(authzNames, "GitLab")
 			case extsvc.TypeBitbucketServer:
 				authzNames = append(authzNames, "Bitbucket Server")
+			case extsvc.TypeBitbucketCloud:
+				authzNames = append(authzNames, "Bitbucket Cloud")
+			case extsvc.TypeGitea:
+				authzNames = append(authzNames, "Gitea")
+			case extsvc.TypeAzureDevOps:
+				authzNames = append(authzNames, "Azure DevOps")
+			case extsvc.TypeAWSCodeCommit:
+				authzNames = append(authzNames, "AWS CodeCommit")
 			default:
 				authzNames = append(authzNames, t)
 			}
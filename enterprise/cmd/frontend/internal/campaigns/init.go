@@ -31,6 +31,7 @@ func Init(ctx context.Context, enterpriseServices *enterprise.Services) error {
 		"sourcegraph-"+globalState.SiteID,
 	)
 	enterpriseServices.GitLabWebhook = campaigns.NewGitLabWebhook(campaignsStore, repositories, msResolutionClock)
+	enterpriseServices.BitbucketCloudWebhook = campaigns.NewBitbucketCloudWebhook(campaignsStore, repositories, msResolutionClock)
 
 	return nil
 }
@@ -288,6 +288,14 @@ func (s *PermsSyncer) syncRepoPerms(ctx context.Context, repoID api.RepoID, noPe
 		ExternalRepoSpec: repo.ExternalRepo,
 	})
 
+	// The provider only supports syncing permissions per user, which also updates the
+	// permissions of this repository. Only record the sync, so the repository isn't
+	// scheduled again right away.
+	if err == authz.ErrUnimplemented {
+		log15.Debug("PermsSyncer.syncRepoPerms.unimplemented", "repoID", repo.ID, "provider", provider.URN())
+		return errors.Wrap(s.permsStore.TouchRepoPermissions(ctx, int32(repoID)), "touch repository permissions")
+	}
+
 	// Detect 404 error (i.e. not authorized to call given APIs) that often happens with GitHub.com
 	// when the owner of the token only has READ access. However, we don't want to fail entirely
 	// so the scheduler won't keep trying to fetch permissions of this same repository.
@@ -238,6 +238,57 @@ func TestPermsSyncer_syncRepoPerms(t *testing.T) {
 		}
 	})
 
+	t.Run("TouchRepoPermissions is called when FetchRepoPerms is unimplemented", func(t *testing.T) {
+		p := &mockProvider{
+			serviceType: extsvc.TypeAWSCodeCommit,
+			serviceID:   "arn:aws:codecommit:us-west-1:999999999999:",
+			fetchRepoPerms: func(ctx context.Context, repo *extsvc.Repository) ([]extsvc.AccountID, error) {
+				return nil, authz.ErrUnimplemented
+			},
+		}
+		authz.SetProviders(false, []authz.Provider{p})
+		defer authz.SetProviders(true, nil)
+
+		var touched int32
+		edb.Mocks.Perms.TouchRepoPermissions = func(_ context.Context, repoID int32) error {
+			touched = repoID
+			return nil
+		}
+		edb.Mocks.Perms.SetRepoPermissions = func(_ context.Context, p *authz.RepoPermissions) error {
+			t.Fatal("SetRepoPermissions must not be called")
+			return nil
+		}
+		defer func() {
+			edb.Mocks.Perms = edb.MockPerms{}
+		}()
+
+		reposStore := &mockReposStore{
+			listRepos: func(context.Context, repos.StoreListReposArgs) ([]*repos.Repo, error) {
+				return []*repos.Repo{
+					{
+						ID:      1,
+						Private: true,
+						ExternalRepo: api.ExternalRepoSpec{
+							ServiceID: p.serviceID,
+						},
+						Sources: map[string]*repos.SourceInfo{
+							p.URN(): {},
+						},
+					},
+				}, nil
+			},
+		}
+		s := newPermsSyncer(reposStore)
+
+		err := s.syncRepoPerms(context.Background(), 1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if touched != 1 {
+			t.Fatalf("touched repository %d, want 1", touched)
+		}
+	})
+
 	t.Run("identify authz provider by URN", func(t *testing.T) {
 		// Even though both p1 and p2 are pointing to the same code host,
 		// but p2 should not be used because it is not responsible for listing
@@ -11,7 +11,11 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/authz/awscodecommit"
+	"github.com/sourcegraph/sourcegraph/internal/authz/azuredevops"
+	"github.com/sourcegraph/sourcegraph/internal/authz/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/authz/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/authz/gitea"
 	"github.com/sourcegraph/sourcegraph/internal/authz/github"
 	"github.com/sourcegraph/sourcegraph/internal/authz/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
@@ -51,6 +55,10 @@ func ProvidersFromConfig(
 			extsvc.KindGitHub,
 			extsvc.KindGitLab,
 			extsvc.KindBitbucketServer,
+			extsvc.KindGitea,
+			extsvc.KindAzureDevOps,
+			extsvc.KindBitbucketCloud,
+			extsvc.KindAWSCodeCommit,
 		},
 		LimitOffset: &db.LimitOffset{
 			Limit: 500, // The number is randomly chosen
@@ -61,6 +69,10 @@ func ProvidersFromConfig(
 		gitHubConns          []*types.GitHubConnection
 		gitLabConns          []*types.GitLabConnection
 		bitbucketServerConns []*types.BitbucketServerConnection
+		giteaConns           []*types.GiteaConnection
+		azureDevOpsConns     []*types.AzureDevOpsConnection
+		bitbucketCloudConns  []*types.BitbucketCloudConnection
+		awsCodeCommitConns   []*types.AWSCodeCommitConnection
 	)
 	for {
 		svcs, err := store.List(ctx, opt)
@@ -96,6 +108,26 @@ func ProvidersFromConfig(
 					URN:                       svc.URN(),
 					BitbucketServerConnection: c,
 				})
+			case *schema.GiteaConnection:
+				giteaConns = append(giteaConns, &types.GiteaConnection{
+					URN:             svc.URN(),
+					GiteaConnection: c,
+				})
+			case *schema.AzureDevOpsConnection:
+				azureDevOpsConns = append(azureDevOpsConns, &types.AzureDevOpsConnection{
+					URN:                   svc.URN(),
+					AzureDevOpsConnection: c,
+				})
+			case *schema.BitbucketCloudConnection:
+				bitbucketCloudConns = append(bitbucketCloudConns, &types.BitbucketCloudConnection{
+					URN:                      svc.URN(),
+					BitbucketCloudConnection: c,
+				})
+			case *schema.AWSCodeCommitConnection:
+				awsCodeCommitConns = append(awsCodeCommitConns, &types.AWSCodeCommitConnection{
+					URN:                     svc.URN(),
+					AWSCodeCommitConnection: c,
+				})
 			default:
 				log15.Error("ProvidersFromConfig", "error", errors.Errorf("unexpected connection type: %T", cfg))
 				continue
@@ -129,6 +161,34 @@ func ProvidersFromConfig(
 		warnings = append(warnings, bbsWarnings...)
 	}
 
+	if len(giteaConns) > 0 {
+		gtProviders, gtProblems, gtWarnings := gitea.NewAuthzProviders(giteaConns)
+		providers = append(providers, gtProviders...)
+		seriousProblems = append(seriousProblems, gtProblems...)
+		warnings = append(warnings, gtWarnings...)
+	}
+
+	if len(azureDevOpsConns) > 0 {
+		adoProviders, adoProblems, adoWarnings := azuredevops.NewAuthzProviders(azureDevOpsConns)
+		providers = append(providers, adoProviders...)
+		seriousProblems = append(seriousProblems, adoProblems...)
+		warnings = append(warnings, adoWarnings...)
+	}
+
+	if len(bitbucketCloudConns) > 0 {
+		bbcProviders, bbcProblems, bbcWarnings := bitbucketcloud.NewAuthzProviders(bitbucketCloudConns)
+		providers = append(providers, bbcProviders...)
+		seriousProblems = append(seriousProblems, bbcProblems...)
+		warnings = append(warnings, bbcWarnings...)
+	}
+
+	if len(awsCodeCommitConns) > 0 {
+		awsProviders, awsProblems, awsWarnings := awscodecommit.NewAuthzProviders(awsCodeCommitConns)
+		providers = append(providers, awsProviders...)
+		seriousProblems = append(seriousProblems, awsProblems...)
+		warnings = append(warnings, awsWarnings...)
+	}
+
 	// 🚨 SECURITY: Warn the admin when both code host authz provider and the permissions user mapping are configured.
 	if cfg.SiteConfiguration.PermissionsUserMapping != nil &&
 		cfg.SiteConfiguration.PermissionsUserMapping.Enabled && len(providers) > 0 {
@@ -498,6 +498,10 @@ type fakeStore struct {
 	gitlabs          []*schema.GitLabConnection
 	githubs          []*schema.GitHubConnection
 	bitbucketServers []*schema.BitbucketServerConnection
+	giteas           []*schema.GiteaConnection
+	azureDevOps      []*schema.AzureDevOpsConnection
+	bitbucketClouds  []*schema.BitbucketCloudConnection
+	awsCodeCommits   []*schema.AWSCodeCommitConnection
 }
 
 func (s fakeStore) List(ctx context.Context, opt db.ExternalServicesListOptions) ([]*types.ExternalService, error) {
@@ -533,6 +537,34 @@ func (s fakeStore) List(ctx context.Context, opt db.ExternalServicesListOptions)
 					Config: mustMarshalJSONString(bbs),
 				})
 			}
+		case extsvc.KindGitea:
+			for _, gt := range s.giteas {
+				svcs = append(svcs, &types.ExternalService{
+					Kind:   kind,
+					Config: mustMarshalJSONString(gt),
+				})
+			}
+		case extsvc.KindAzureDevOps:
+			for _, ado := range s.azureDevOps {
+				svcs = append(svcs, &types.ExternalService{
+					Kind:   kind,
+					Config: mustMarshalJSONString(ado),
+				})
+			}
+		case extsvc.KindBitbucketCloud:
+			for _, bbc := range s.bitbucketClouds {
+				svcs = append(svcs, &types.ExternalService{
+					Kind:   kind,
+					Config: mustMarshalJSONString(bbc),
+				})
+			}
+		case extsvc.KindAWSCodeCommit:
+			for _, aws := range s.awsCodeCommits {
+				svcs = append(svcs, &types.ExternalService{
+					Kind:   kind,
+					Config: mustMarshalJSONString(aws),
+				})
+			}
 		default:
 			return nil, errors.Errorf("unexpected kind: %s", kind)
 		}
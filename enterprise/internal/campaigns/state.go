@@ -14,6 +14,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/db"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/azuredevops"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
@@ -104,6 +106,9 @@ func computeCheckState(c *campaigns.Changeset, events ChangesetEvents) campaigns
 
 	case *gitlab.MergeRequest:
 		return computeGitLabCheckState(c.UpdatedAt, m, events)
+
+	case *bitbucketcloud.PullRequest:
+		return computeBitbucketCloudCheckState(c.UpdatedAt, m, events)
 	}
 
 	return campaigns.ChangesetCheckStateUnknown
@@ -196,6 +201,54 @@ func parseBitbucketBuildState(s string) campaigns.ChangesetCheckState {
 	}
 }
 
+func computeBitbucketCloudCheckState(lastSynced time.Time, pr *bitbucketcloud.PullRequest, events []*campaigns.ChangesetEvent) campaigns.ChangesetCheckState {
+	var head string
+	if pr.Source.Commit != nil {
+		head = pr.Source.Commit.Hash
+	}
+
+	stateMap := make(map[string]campaigns.ChangesetCheckState)
+
+	// States from last sync
+	for _, status := range pr.Statuses {
+		stateMap[status.Key()] = parseBitbucketCloudBuildState(status.Status.State)
+	}
+
+	// Add any events we've received since our last sync
+	for _, e := range events {
+		switch m := e.Metadata.(type) {
+		case *bitbucketcloud.CommitStatus:
+			if m.Commit != head {
+				continue
+			}
+			if m.Status.UpdatedOn.Before(lastSynced) {
+				continue
+			}
+			stateMap[m.Key()] = parseBitbucketCloudBuildState(m.Status.State)
+		}
+	}
+
+	states := make([]campaigns.ChangesetCheckState, 0, len(stateMap))
+	for _, v := range stateMap {
+		states = append(states, v)
+	}
+
+	return combineCheckStates(states)
+}
+
+func parseBitbucketCloudBuildState(s bitbucketcloud.BuildStatusState) campaigns.ChangesetCheckState {
+	switch s {
+	case bitbucketcloud.BuildStatusStateFailed, bitbucketcloud.BuildStatusStateStopped:
+		return campaigns.ChangesetCheckStateFailed
+	case bitbucketcloud.BuildStatusStateInProgress:
+		return campaigns.ChangesetCheckStatePending
+	case bitbucketcloud.BuildStatusStateSuccessful:
+		return campaigns.ChangesetCheckStatePassed
+	default:
+		return campaigns.ChangesetCheckStateUnknown
+	}
+}
+
 func computeGitHubCheckState(lastSynced time.Time, pr *github.PullRequest, events []*campaigns.ChangesetEvent) campaigns.ChangesetCheckState {
 	// We should only consider the latest commit. This could be from a sync or a webhook that
 	// has occurred later
@@ -441,6 +494,28 @@ func computeSingleChangesetExternalState(c *campaigns.Changeset) (s campaigns.Ch
 		default:
 			return "", errors.Errorf("unknown GitLab merge request state: %s", m.State)
 		}
+	case *azuredevops.PullRequest:
+		switch m.Status {
+		case azuredevops.PullRequestStatusAbandoned:
+			s = campaigns.ChangesetExternalStateClosed
+		case azuredevops.PullRequestStatusCompleted:
+			s = campaigns.ChangesetExternalStateMerged
+		case azuredevops.PullRequestStatusActive:
+			s = campaigns.ChangesetExternalStateOpen
+		default:
+			return "", errors.Errorf("unknown Azure DevOps pull request status: %s", m.Status)
+		}
+	case *bitbucketcloud.PullRequest:
+		switch m.State {
+		case bitbucketcloud.PullRequestStateDeclined, bitbucketcloud.PullRequestStateSuperseded:
+			s = campaigns.ChangesetExternalStateClosed
+		case bitbucketcloud.PullRequestStateMerged:
+			s = campaigns.ChangesetExternalStateMerged
+		case bitbucketcloud.PullRequestStateOpen:
+			s = campaigns.ChangesetExternalStateOpen
+		default:
+			return "", errors.Errorf("unknown Bitbucket Cloud pull request state: %s", m.State)
+		}
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -498,6 +573,32 @@ func computeSingleChangesetReviewState(c *campaigns.Changeset) (s campaigns.Chan
 		}
 		return campaigns.ChangesetReviewStatePending, nil
 
+	case *azuredevops.PullRequest:
+		for _, r := range m.Reviewers {
+			switch {
+			case r.Vote >= azuredevops.VoteApprovedWithSuggestions:
+				states[campaigns.ChangesetReviewStateApproved] = true
+			case r.Vote <= azuredevops.VoteWaitingForAuthor:
+				states[campaigns.ChangesetReviewStateChangesRequested] = true
+			default:
+				states[campaigns.ChangesetReviewStatePending] = true
+			}
+		}
+
+	case *bitbucketcloud.PullRequest:
+		for _, p := range m.Participants {
+			switch {
+			case p.State == bitbucketcloud.ParticipantStateChangesRequested:
+				states[campaigns.ChangesetReviewStateChangesRequested] = true
+			case p.State == bitbucketcloud.ParticipantStateApproved || p.Approved:
+				states[campaigns.ChangesetReviewStateApproved] = true
+			case p.Role == "REVIEWER":
+				// Participants that merely commented on the pull request
+				// don't affect its review state.
+				states[campaigns.ChangesetReviewStatePending] = true
+			}
+		}
+
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -9,6 +9,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/campaigns"
 	cmpgn "github.com/sourcegraph/sourcegraph/internal/campaigns"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/azuredevops"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
@@ -263,6 +265,83 @@ func TestComputeBitbucketBuildStatus(t *testing.T) {
 	}
 }
 
+func TestComputeBitbucketCloudCheckState(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	lastSynced := now.Add(-1 * time.Minute)
+
+	status := func(commit, key string, state bitbucketcloud.BuildStatusState, updatedOn time.Time) *bitbucketcloud.CommitStatus {
+		return &bitbucketcloud.CommitStatus{
+			Commit: commit,
+			Status: bitbucketcloud.BuildStatus{Key: key, State: state, UpdatedOn: updatedOn},
+		}
+	}
+	statusEvent := func(commit, key string, state bitbucketcloud.BuildStatusState, updatedOn time.Time) *cmpgn.ChangesetEvent {
+		return &cmpgn.ChangesetEvent{
+			Kind:     cmpgn.ChangesetEventKindBitbucketCloudCommitStatus,
+			Metadata: status(commit, key, state, updatedOn),
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		statuses []*bitbucketcloud.CommitStatus
+		events   []*cmpgn.ChangesetEvent
+		want     cmpgn.ChangesetCheckState
+	}{
+		"no statuses": {
+			want: cmpgn.ChangesetCheckStateUnknown,
+		},
+		"synced success": {
+			statuses: []*bitbucketcloud.CommitStatus{
+				status("head", "ci", bitbucketcloud.BuildStatusStateSuccessful, lastSynced),
+			},
+			want: cmpgn.ChangesetCheckStatePassed,
+		},
+		"synced stopped": {
+			statuses: []*bitbucketcloud.CommitStatus{
+				status("head", "ci", bitbucketcloud.BuildStatusStateSuccessful, lastSynced),
+				status("head", "lint", bitbucketcloud.BuildStatusStateStopped, lastSynced),
+			},
+			want: cmpgn.ChangesetCheckStateFailed,
+		},
+		"newer event overrides synced status": {
+			statuses: []*bitbucketcloud.CommitStatus{
+				status("head", "ci", bitbucketcloud.BuildStatusStateInProgress, lastSynced),
+			},
+			events: []*cmpgn.ChangesetEvent{
+				statusEvent("head", "ci", bitbucketcloud.BuildStatusStateSuccessful, now),
+			},
+			want: cmpgn.ChangesetCheckStatePassed,
+		},
+		"older event is ignored": {
+			statuses: []*bitbucketcloud.CommitStatus{
+				status("head", "ci", bitbucketcloud.BuildStatusStateInProgress, lastSynced),
+			},
+			events: []*cmpgn.ChangesetEvent{
+				statusEvent("head", "ci", bitbucketcloud.BuildStatusStateFailed, lastSynced.Add(-time.Minute)),
+			},
+			want: cmpgn.ChangesetCheckStatePending,
+		},
+		"event for other commit is ignored": {
+			events: []*cmpgn.ChangesetEvent{
+				statusEvent("old", "ci", bitbucketcloud.BuildStatusStateFailed, now),
+			},
+			want: cmpgn.ChangesetCheckStateUnknown,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pr := &bitbucketcloud.PullRequest{
+				Source:   bitbucketcloud.PullRequestEndpoint{Commit: &bitbucketcloud.Commit{Hash: "head"}},
+				Statuses: tc.statuses,
+			}
+
+			have := computeBitbucketCloudCheckState(lastSynced, pr, tc.events)
+			if diff := cmp.Diff(tc.want, have); diff != "" {
+				t.Fatalf(diff)
+			}
+		})
+	}
+}
+
 func TestComputeGitLabCheckState(t *testing.T) {
 	t.Run("no events", func(t *testing.T) {
 		for name, tc := range map[string]struct {
@@ -518,6 +597,50 @@ func TestComputeReviewState(t *testing.T) {
 			},
 			want: cmpgn.ChangesetReviewStateChangesRequested,
 		},
+		{
+			name:      "azuredevops - no events, no votes",
+			changeset: azureDevOpsChangeset(daysAgo(0), azuredevops.PullRequestStatusActive, azuredevops.VoteNoVote),
+			history:   []changesetStatesAtTime{},
+			want:      cmpgn.ChangesetReviewStatePending,
+		},
+		{
+			name:      "azuredevops - no events, approved with suggestions",
+			changeset: azureDevOpsChangeset(daysAgo(0), azuredevops.PullRequestStatusActive, azuredevops.VoteApprovedWithSuggestions),
+			history:   []changesetStatesAtTime{},
+			want:      cmpgn.ChangesetReviewStateApproved,
+		},
+		{
+			name:      "azuredevops - no events, waiting for author and approved",
+			changeset: azureDevOpsChangeset(daysAgo(0), azuredevops.PullRequestStatusActive, azuredevops.VoteWaitingForAuthor, azuredevops.VoteApproved),
+			history:   []changesetStatesAtTime{},
+			want:      cmpgn.ChangesetReviewStateChangesRequested,
+		},
+		{
+			name: "bitbucketcloud - no events, reviewer without review",
+			changeset: bitbucketCloudChangeset(daysAgo(0), bitbucketcloud.PullRequestStateOpen,
+				bitbucketcloud.Participant{Role: "REVIEWER"},
+				bitbucketcloud.Participant{Role: "PARTICIPANT", State: bitbucketcloud.ParticipantStateApproved},
+			),
+			history: []changesetStatesAtTime{},
+			want:    cmpgn.ChangesetReviewStateApproved,
+		},
+		{
+			name: "bitbucketcloud - no events, changes requested and approved",
+			changeset: bitbucketCloudChangeset(daysAgo(0), bitbucketcloud.PullRequestStateOpen,
+				bitbucketcloud.Participant{Role: "REVIEWER", State: bitbucketcloud.ParticipantStateApproved},
+				bitbucketcloud.Participant{Role: "REVIEWER", State: bitbucketcloud.ParticipantStateChangesRequested},
+			),
+			history: []changesetStatesAtTime{},
+			want:    cmpgn.ChangesetReviewStateChangesRequested,
+		},
+		{
+			name: "bitbucketcloud - no events, only commenters",
+			changeset: bitbucketCloudChangeset(daysAgo(0), bitbucketcloud.PullRequestStateOpen,
+				bitbucketcloud.Participant{Role: "PARTICIPANT"},
+			),
+			history: []changesetStatesAtTime{},
+			want:    cmpgn.ChangesetReviewStatePending,
+		},
 	}
 
 	for i, tc := range tests {
@@ -646,6 +769,24 @@ func TestComputeExternalState(t *testing.T) {
 			},
 			want: cmpgn.ChangesetExternalStateMerged,
 		},
+		{
+			name:      "bitbucketcloud - no events, declined",
+			changeset: bitbucketCloudChangeset(daysAgo(0), bitbucketcloud.PullRequestStateDeclined),
+			history:   []changesetStatesAtTime{},
+			want:      cmpgn.ChangesetExternalStateClosed,
+		},
+		{
+			name:      "bitbucketcloud - no events, superseded",
+			changeset: bitbucketCloudChangeset(daysAgo(0), bitbucketcloud.PullRequestStateSuperseded),
+			history:   []changesetStatesAtTime{},
+			want:      cmpgn.ChangesetExternalStateClosed,
+		},
+		{
+			name:      "bitbucketcloud - no events, merged",
+			changeset: bitbucketCloudChangeset(daysAgo(0), bitbucketcloud.PullRequestStateMerged),
+			history:   []changesetStatesAtTime{},
+			want:      cmpgn.ChangesetExternalStateMerged,
+		},
 	}
 
 	for i, tc := range tests {
@@ -794,6 +935,29 @@ func gitLabChangeset(updatedAt time.Time, state gitlab.MergeRequestState, notes
 	}
 }
 
+func azureDevOpsChangeset(updatedAt time.Time, status azuredevops.PullRequestStatus, votes ...int) *campaigns.Changeset {
+	pr := &azuredevops.PullRequest{Status: status}
+	for _, v := range votes {
+		pr.Reviewers = append(pr.Reviewers, &azuredevops.Reviewer{Vote: v})
+	}
+	return &campaigns.Changeset{
+		ExternalServiceType: extsvc.TypeAzureDevOps,
+		UpdatedAt:           updatedAt,
+		Metadata:            pr,
+	}
+}
+
+func bitbucketCloudChangeset(updatedAt time.Time, state bitbucketcloud.PullRequestState, participants ...bitbucketcloud.Participant) *campaigns.Changeset {
+	return &campaigns.Changeset{
+		ExternalServiceType: extsvc.TypeBitbucketCloud,
+		UpdatedAt:           updatedAt,
+		Metadata: &bitbucketcloud.PullRequest{
+			State:        state,
+			Participants: participants,
+		},
+	}
+}
+
 func setDeletedAt(c *campaigns.Changeset, deletedAt time.Time) *campaigns.Changeset {
 	c.ExternalDeletedAt = deletedAt
 	return c
@@ -13,6 +13,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/db/basestore"
 	"github.com/sourcegraph/sourcegraph/internal/db/dbutil"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/azuredevops"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
@@ -385,6 +387,9 @@ type ListChangesetsOpts struct {
 	ExternalCheckState   *campaigns.ChangesetCheckState
 	OwnedByCampaignID    int64
 	OnlyWithoutDiffStats bool
+	RepoID               api.RepoID
+	ExternalServiceType  string
+	ExternalBranch       string
 }
 
 // ListChangesets lists Changesets with the given filters.
@@ -464,6 +469,16 @@ func listChangesetsQuery(opts *ListChangesetsOpts) *sqlf.Query {
 		preds = append(preds, sqlf.Sprintf("(changesets.diff_stat_added IS NULL OR changesets.diff_stat_changed IS NULL OR changesets.diff_stat_deleted IS NULL)"))
 	}
 
+	if opts.RepoID != 0 {
+		preds = append(preds, sqlf.Sprintf("changesets.repo_id = %s", opts.RepoID))
+	}
+	if opts.ExternalServiceType != "" {
+		preds = append(preds, sqlf.Sprintf("changesets.external_service_type = %s", opts.ExternalServiceType))
+	}
+	if opts.ExternalBranch != "" {
+		preds = append(preds, sqlf.Sprintf("changesets.external_branch = %s", opts.ExternalBranch))
+	}
+
 	return sqlf.Sprintf(
 		listChangesetsQueryFmtstr+opts.LimitOpts.ToDB(),
 		sqlf.Join(changesetColumns, ", "),
@@ -610,6 +625,10 @@ func scanChangeset(t *campaigns.Changeset, s scanner) error {
 		t.Metadata = new(bitbucketserver.PullRequest)
 	case extsvc.TypeGitLab:
 		t.Metadata = new(gitlab.MergeRequest)
+	case extsvc.TypeAzureDevOps:
+		t.Metadata = new(azuredevops.PullRequest)
+	case extsvc.TypeBitbucketCloud:
+		t.Metadata = new(bitbucketcloud.PullRequest)
 	default:
 		return errors.New("unknown external service type")
 	}
@@ -387,6 +387,21 @@ func testStoreChangesets(t *testing.T, ctx context.Context, s *Store, reposStore
 			}
 		}
 
+		{
+			have, _, err := s.ListChangesets(ctx, ListChangesetsOpts{
+				RepoID:              repo.ID,
+				ExternalServiceType: extsvc.TypeGitHub,
+				ExternalBranch:      changesets[1].ExternalBranch,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(have) != 1 || have[0].ID != changesets[1].ID {
+				t.Fatalf("have %+v; want changeset %d", have, changesets[1].ID)
+			}
+		}
+
 		{
 			have, _, err := s.ListChangesets(ctx, ListChangesetsOpts{WithoutDeleted: true})
 			if err != nil {
@@ -0,0 +1,235 @@
+package campaigns
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
+	"github.com/sourcegraph/sourcegraph/internal/repoupdater"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// BitbucketCloudWebhook receives Bitbucket Cloud repository webhook events
+// that are relevant to campaigns and enqueues a sync of the changesets they
+// affect.
+type BitbucketCloudWebhook struct{ *Webhook }
+
+func NewBitbucketCloudWebhook(store *Store, repos repos.Store, now func() time.Time) *BitbucketCloudWebhook {
+	return &BitbucketCloudWebhook{&Webhook{store, repos, now, extsvc.TypeBitbucketCloud}}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *BitbucketCloudWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Look up the external service.
+	extSvc, err := h.getExternalServiceFromRawID(r.Context(), r.FormValue(extsvc.IDParam))
+	if err == errExternalServiceNotFound {
+		respond(w, http.StatusUnauthorized, err)
+		return
+	} else if e, ok := err.(*httpError); ok {
+		respond(w, e.code, e)
+		return
+	} else if err != nil {
+		respond(w, http.StatusInternalServerError, errors.Wrap(err, "getting external service"))
+		return
+	}
+
+	// 🚨 SECURITY: Bitbucket Cloud doesn't sign webhook payloads, so the
+	// shared secret is passed in the webhook URL instead. If there isn't a
+	// webhook defined in the service with this secret, or the parameter is
+	// empty, then we return a 401 to the client.
+	if ok, err := validateBitbucketCloudSecret(extSvc, r.URL.Query().Get("secret")); err != nil {
+		respond(w, http.StatusInternalServerError, errors.Wrap(err, "validating the shared secret"))
+		return
+	} else if !ok {
+		respond(w, http.StatusUnauthorized, "shared secret is incorrect")
+		return
+	}
+
+	// Parse the event proper.
+	if r.Body == nil {
+		respond(w, http.StatusBadRequest, "missing request body")
+		return
+	}
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respond(w, http.StatusInternalServerError, errors.Wrap(err, "reading payload"))
+		return
+	}
+
+	event, err := bitbucketcloud.ParseWebhookEvent(bitbucketcloud.WebhookEventType(r), payload)
+	if err != nil {
+		// We don't want to return a non-2XX status code and have Bitbucket
+		// Cloud disable the webhook for events we don't care about, so we'll
+		// log that we don't know what to do and return 204.
+		log15.Debug("unknown Bitbucket Cloud webhook event", "err", err)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		fmt.Fprintf(w, "%v", err)
+		return
+	}
+
+	if err := h.handleEvent(r.Context(), extSvc, event); err != nil {
+		respond(w, err.code, err)
+	} else {
+		respond(w, http.StatusNoContent, nil)
+	}
+}
+
+// getExternalServiceFromRawID retrieves the Bitbucket Cloud external service
+// matching the given raw ID, which is usually going to be the string in the
+// externalServiceID URL parameter.
+//
+// On failure, errExternalServiceNotFound is returned if the ID doesn't match
+// any Bitbucket Cloud service, and an *httpError with a 400 status code if the
+// ID is missing or malformed.
+func (h *BitbucketCloudWebhook) getExternalServiceFromRawID(ctx context.Context, raw string) (*repos.ExternalService, error) {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, &httpError{http.StatusBadRequest, errors.Wrap(err, "invalid external service id")}
+	}
+
+	es, err := h.Repos.ListExternalServices(ctx, repos.StoreListExternalServicesArgs{
+		IDs:   []int64{id},
+		Kinds: []string{extsvc.KindBitbucketCloud},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing external services")
+	}
+
+	if len(es) == 0 {
+		return nil, errExternalServiceNotFound
+	} else if len(es) > 1 {
+		// This _really_ shouldn't happen, since we provided only one ID above.
+		return nil, errors.New("too many external services found")
+	}
+
+	return es[0], nil
+}
+
+// handleEvent dispatches based on the event type. Bitbucket Cloud webhook
+// payloads include neither the comments nor the build statuses of a pull
+// request in the form we get from the API, so rather than trying to patch
+// changeset events we ask repo-updater to prioritize the sync of the affected
+// changesets.
+func (h *BitbucketCloudWebhook) handleEvent(ctx context.Context, extSvc *repos.ExternalService, event interface{}) *httpError {
+	log15.Debug("Bitbucket Cloud webhook received", "type", fmt.Sprintf("%T", event))
+
+	esID, err := extractExternalServiceID(extSvc)
+	if err != nil {
+		return &httpError{
+			code: http.StatusInternalServerError,
+			err:  err,
+		}
+	}
+
+	var ids []int64
+	switch e := event.(type) {
+	case *bitbucketcloud.PullRequestEvent:
+		ids, err = h.changesetIDsForPR(ctx, esID, e)
+	case *bitbucketcloud.CommitStatusEvent:
+		ids, err = h.changesetIDsForCommitStatus(ctx, esID, e)
+	}
+	if err != nil {
+		return &httpError{
+			code: http.StatusInternalServerError,
+			err:  err,
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := repoupdater.DefaultClient.EnqueueChangesetSync(ctx, ids); err != nil {
+		return &httpError{
+			code: http.StatusInternalServerError,
+			err:  errors.Wrap(err, "enqueuing changeset sync"),
+		}
+	}
+	return nil
+}
+
+func (h *BitbucketCloudWebhook) changesetIDsForPR(ctx context.Context, esID string, e *bitbucketcloud.PullRequestEvent) ([]int64, error) {
+	pr := PR{ID: e.PullRequest.ID, RepoExternalID: e.Repository.UUID}
+	repo, err := h.getRepoForPR(ctx, h.Store, pr, esID)
+	if err != nil {
+		log15.Debug("Webhook event could not be matched to repo", "err", err)
+		return nil, nil
+	}
+
+	c, err := h.Store.GetChangeset(ctx, GetChangesetOpts{
+		RepoID:              repo.ID,
+		ExternalID:          strconv.FormatInt(pr.ID, 10),
+		ExternalServiceType: h.ServiceType,
+	})
+	if err == ErrNoResults {
+		return nil, nil // Nothing to do
+	} else if err != nil {
+		return nil, errors.Wrap(err, "getting changeset")
+	}
+
+	return []int64{c.ID}, nil
+}
+
+func (h *BitbucketCloudWebhook) changesetIDsForCommitStatus(ctx context.Context, esID string, e *bitbucketcloud.CommitStatusEvent) ([]int64, error) {
+	// Commit statuses aren't tied to a pull request, but the payload names
+	// the branch they were reported for, which is the source branch of the
+	// pull request.
+	if e.CommitStatus.RefName == "" {
+		return nil, nil
+	}
+
+	repo, err := h.getRepoForPR(ctx, h.Store, PR{RepoExternalID: e.Repository.UUID}, esID)
+	if err != nil {
+		log15.Debug("Webhook event could not be matched to repo", "err", err)
+		return nil, nil
+	}
+
+	// Several pull requests, for example to different destination branches,
+	// can have the same source branch.
+	cs, _, err := h.Store.ListChangesets(ctx, ListChangesetsOpts{
+		RepoID:              repo.ID,
+		ExternalServiceType: h.ServiceType,
+		ExternalBranch:      e.CommitStatus.RefName,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing changesets")
+	}
+
+	return cs.IDs(), nil
+}
+
+// validateBitbucketCloudSecret validates that the given secret matches one of
+// the webhooks in the external service.
+func validateBitbucketCloudSecret(extSvc *repos.ExternalService, secret string) (bool, error) {
+	// An empty secret never succeeds.
+	if secret == "" {
+		return false, nil
+	}
+
+	c, err := extSvc.Configuration()
+	if err != nil {
+		return false, errors.Wrap(err, "getting external service configuration")
+	}
+
+	config, ok := c.(*schema.BitbucketCloudConnection)
+	if !ok {
+		return false, errExternalServiceWrongKind
+	}
+
+	for _, webhook := range config.Webhooks {
+		if subtle.ConstantTimeCompare([]byte(webhook.Secret), []byte(secret)) == 1 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
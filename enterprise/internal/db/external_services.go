@@ -1,7 +1,11 @@
 package db
 
 import (
+	"github.com/sourcegraph/sourcegraph/internal/authz/awscodecommit"
+	"github.com/sourcegraph/sourcegraph/internal/authz/azuredevops"
+	"github.com/sourcegraph/sourcegraph/internal/authz/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/authz/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/internal/authz/gitea"
 	"github.com/sourcegraph/sourcegraph/internal/authz/github"
 	"github.com/sourcegraph/sourcegraph/internal/authz/gitlab"
 	"github.com/sourcegraph/sourcegraph/internal/db"
@@ -21,5 +25,17 @@ func NewExternalServicesStore() *db.ExternalServicesStore {
 		BitbucketServerValidators: []func(*schema.BitbucketServerConnection) error{
 			bitbucketserver.ValidateAuthz,
 		},
+		GiteaValidators: []func(*schema.GiteaConnection) error{
+			gitea.ValidateAuthz,
+		},
+		AzureDevOpsValidators: []func(*schema.AzureDevOpsConnection) error{
+			azuredevops.ValidateAuthz,
+		},
+		BitbucketCloudValidators: []func(*schema.BitbucketCloudConnection) error{
+			bitbucketcloud.ValidateAuthz,
+		},
+		AWSCodeCommitValidators: []func(*schema.AWSCodeCommitConnection) error{
+			awscodecommit.ValidateAuthz,
+		},
 	}
 }
@@ -107,6 +107,22 @@ func TestExternalServices_ValidateConfig(t *testing.T) {
 			config: `{"region": "eu-west-2", "accessKeyID": "bar", "secretAccessKey": "baz", "gitCredentials": {"username": "user", "password": "pw"}}`,
 			assert: equals("<nil>"),
 		},
+		{
+			kind:   extsvc.KindAWSCodeCommit,
+			desc:   "authorization without identityProvider nor accountID",
+			config: `{"region": "eu-west-2", "accessKeyID": "bar", "secretAccessKey": "baz", "gitCredentials": {"username": "user", "password": "pw"}, "authorization": {}}`,
+			assert: includes(
+				"authorization: identityProvider is required",
+				"authorization: accountID is required",
+				"No identityProvider was specified",
+			),
+		},
+		{
+			kind:   extsvc.KindAWSCodeCommit,
+			desc:   "valid with username identity provider",
+			config: `{"region": "eu-west-2", "accessKeyID": "bar", "secretAccessKey": "baz", "gitCredentials": {"username": "user", "password": "pw"}, "authorization": {"identityProvider": {"type": "username"}, "accountID": "999999999999"}}`,
+			assert: equals("<nil>"),
+		},
 		{
 			kind: extsvc.KindAWSCodeCommit,
 			desc: "valid exclude",
@@ -384,6 +400,27 @@ func TestExternalServices_ValidateConfig(t *testing.T) {
 			config: `{"exclude": [{"pattern": "["}]}`,
 			assert: includes(`exclude.0.pattern: Does not match format 'regex'`),
 		},
+		{
+			kind:   extsvc.KindBitbucketCloud,
+			desc:   "authorization without identityProvider",
+			config: `{"url": "https://bitbucket.org", "username": "admin", "appPassword": "secret", "authorization": {}}`,
+			assert: includes(
+				"authorization: identityProvider is required",
+				"No identityProvider was specified",
+			),
+		},
+		{
+			kind:   extsvc.KindBitbucketCloud,
+			desc:   "authorization with username identity provider",
+			config: `{"url": "https://bitbucket.org", "username": "admin", "appPassword": "secret", "teams": ["sourcegraph"], "authorization": {"identityProvider": {"type": "username"}}}`,
+			assert: includes(`tagged union type must have a "type" property whose value is one of [oauth]`),
+		},
+		{
+			kind:   extsvc.KindBitbucketCloud,
+			desc:   "valid with oauth identity provider",
+			config: `{"url": "https://bitbucket.org", "username": "admin", "appPassword": "secret", "teams": ["sourcegraph"], "authorization": {"identityProvider": {"type": "oauth"}}}`,
+			assert: equals("<nil>"),
+		},
 		{
 			kind: extsvc.KindBitbucketServer,
 			desc: "valid with url, username, token, repositoryQuery",
@@ -1249,6 +1286,55 @@ func TestExternalServices_ValidateConfig(t *testing.T) {
 			config: `{"url": "https://github.com/", "repos": ["foo/", "bar", "/baz", "bam.git"]}`,
 			assert: equals("<nil>"),
 		},
+		{
+			kind:   extsvc.KindGitea,
+			desc:   "without url, token, orgs, users nor repositoryQuery",
+			config: `{}`,
+			assert: includes(
+				"url is required",
+				"token is required",
+				"at least one of orgs, users or repositoryQuery must be set",
+			),
+		},
+		{
+			kind:   extsvc.KindGitea,
+			desc:   "authorization without identityProvider",
+			config: `{"url": "https://gitea.sgdev.org", "token": "abc", "orgs": ["sourcegraph"], "authorization": {}}`,
+			assert: includes(
+				"authorization: identityProvider is required",
+				"No identityProvider was specified",
+			),
+		},
+		{
+			kind:   extsvc.KindGitea,
+			desc:   "valid with username identity provider",
+			config: `{"url": "https://gitea.sgdev.org", "token": "abc", "users": ["alice"], "authorization": {"identityProvider": {"type": "username"}}}`,
+			assert: equals("<nil>"),
+		},
+		{
+			kind:   extsvc.KindAzureDevOps,
+			desc:   "without token nor orgs",
+			config: `{"url": "https://dev.azure.com"}`,
+			assert: includes(
+				"token is required",
+				"orgs is required",
+			),
+		},
+		{
+			kind:   extsvc.KindAzureDevOps,
+			desc:   "authorization without identityProvider",
+			config: `{"url": "https://dev.azure.com", "token": "abc", "orgs": ["sourcegraph"], "authorization": {"domain": "sourcegraph.com"}}`,
+			assert: includes(
+				"authorization: identityProvider is required",
+				"No identityProvider was specified",
+			),
+		},
+		{
+			kind:   extsvc.KindAzureDevOps,
+			desc:   "valid with username identity provider",
+			config: `{"url": "https://dev.azure.com", "token": "abc", "orgs": ["sourcegraph"], "authorization": {"identityProvider": {"type": "username"}, "domain": "sourcegraph.com"}}`,
+			assert: equals("<nil>"),
+		},
 	} {
 		tc := tc
 		t.Run(tc.kind+"/"+tc.desc, func(t *testing.T) {
@@ -26,6 +26,7 @@ func TestIntegration_PermsStore(t *testing.T) {
 		{"PermsStore/LoadRepoPermissions", testPermsStore_LoadRepoPermissions(db)},
 		{"PermsStore/SetUserPermissions", testPermsStore_SetUserPermissions(db)},
 		{"PermsStore/SetRepoPermissions", testPermsStore_SetRepoPermissions(db)},
+		{"PermsStore/TouchRepoPermissions", testPermsStore_TouchRepoPermissions(db)},
 		{"PermsStore/LoadUserPendingPermissions", testPermsStore_LoadUserPendingPermissions(db)},
 		{"PermsStore/SetRepoPendingPermissions", testPermsStore_SetRepoPendingPermissions(db)},
 		{"PermsStore/ListPendingUsers", testPermsStore_ListPendingUsers(db)},
@@ -495,6 +495,56 @@ DO UPDATE SET
 	), nil
 }
 
+// TouchRepoPermissions only updates the value of "synced_at" of the permissions
+// of the given repository, creating empty permissions if there are none. It is
+// used for repositories whose permissions are only synced per user, so that the
+// scheduler doesn't keep picking them.
+func (s *PermsStore) TouchRepoPermissions(ctx context.Context, repoID int32) (err error) {
+	if Mocks.Perms.TouchRepoPermissions != nil {
+		return Mocks.Perms.TouchRepoPermissions(ctx, repoID)
+	}
+
+	ctx, save := s.observe(ctx, "TouchRepoPermissions", "")
+	defer func() { save(&err, otlog.Int32("repoID", repoID)) }()
+
+	q, err := touchRepoPermissionsQuery(repoID, s.clock())
+	if err != nil {
+		return err
+	}
+	if err = s.execute(ctx, q); err != nil {
+		return errors.Wrap(err, "execute touch repo permissions query")
+	}
+	return nil
+}
+
+func touchRepoPermissionsQuery(repoID int32, syncedAt time.Time) (*sqlf.Query, error) {
+	const format = `
+-- source: enterprise/internal/db/perms_store.go:touchRepoPermissionsQuery
+INSERT INTO repo_permissions
+  (repo_id, permission, user_ids, updated_at, synced_at)
+VALUES
+  (%s, %s, %s, %s, %s)
+ON CONFLICT ON CONSTRAINT
+  repo_permissions_perm_unique
+DO UPDATE SET
+  synced_at = excluded.synced_at
+`
+
+	ids, err := roaring.NewBitmap().ToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlf.Sprintf(
+		format,
+		repoID,
+		authz.Read.String(),
+		ids,
+		syncedAt.UTC(),
+		syncedAt.UTC(),
+	), nil
+}
+
 // LoadUserPendingPermissions returns pending permissions found by given parameters.
 // An ErrPermsNotFound is returned when there are no pending permissions available.
 func (s *PermsStore) LoadUserPendingPermissions(ctx context.Context, p *authz.UserPendingPermissions) (err error) {
@@ -14,6 +14,7 @@ type MockPerms struct {
 	LoadUserPendingPermissions   func(ctx context.Context, p *authz.UserPendingPermissions) error
 	SetUserPermissions           func(ctx context.Context, p *authz.UserPermissions) error
 	SetRepoPermissions           func(ctx context.Context, p *authz.RepoPermissions) error
+	TouchRepoPermissions         func(ctx context.Context, repoID int32) error
 	SetRepoPendingPermissions    func(ctx context.Context, accounts *extsvc.Accounts, p *authz.RepoPermissions) error
 	ListPendingUsers             func(ctx context.Context) ([]string, error)
 	ListExternalAccounts         func(ctx context.Context, userID int32) ([]*extsvc.Account, error)
@@ -645,6 +645,47 @@ func testPermsStore_SetRepoPermissions(db *sql.DB) func(*testing.T) {
 	}
 }
 
+func testPermsStore_TouchRepoPermissions(db *sql.DB) func(*testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		s := NewPermsStore(db, clock)
+		t.Cleanup(func() {
+			cleanupPermsTables(t, s)
+		})
+
+		if err := s.SetRepoPermissions(ctx, &authz.RepoPermissions{
+			RepoID:  1,
+			Perm:    authz.Read,
+			UserIDs: toBitmap(2),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		later := clock().Add(time.Hour)
+		s = NewPermsStore(db, func() time.Time { return later })
+		for _, repoID := range []int32{1, 2} {
+			if err := s.TouchRepoPermissions(ctx, repoID); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// The users of existing permissions are kept, missing permissions
+		// are created empty.
+		for repoID, wantUserIDs := range map[int32][]int{1: {2}, 2: {}} {
+			rp := &authz.RepoPermissions{RepoID: repoID, Perm: authz.Read}
+			if err := s.LoadRepoPermissions(ctx, rp); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(wantUserIDs, bitmapToArray(rp.UserIDs)); diff != "" {
+				t.Errorf("repo %d: user IDs mismatch (-want +got):\n%s", repoID, diff)
+			}
+			if !rp.SyncedAt.Equal(later) {
+				t.Errorf("repo %d: have synced_at %v, want %v", repoID, rp.SyncedAt, later)
+			}
+		}
+	}
+}
+
 func testPermsStore_LoadUserPendingPermissions(db *sql.DB) func(*testing.T) {
 	return func(t *testing.T) {
 		t.Run("no matching with different account ID", func(t *testing.T) {
@@ -0,0 +1,84 @@
+package awscodecommit
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/defaults"
+	"github.com/aws/aws-sdk-go-v2/aws/endpoints"
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/awscodecommit"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// NewAuthzProviders returns the set of AWS CodeCommit authz providers derived from the connections.
+// It also returns any validation problems with the config, separating these into "serious problems" and
+// "warnings". "Serious problems" are those that should make Sourcegraph set authz.allowAccessByDefault
+// to false. "Warnings" are all other validation problems.
+func NewAuthzProviders(
+	conns []*types.AWSCodeCommitConnection,
+) (ps []authz.Provider, problems []string, warnings []string) {
+	// Authorization (i.e., permissions) providers
+	for _, c := range conns {
+		p, err := newAuthzProvider(c.URN, c.AWSCodeCommitConnection)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else if p != nil {
+			ps = append(ps, p)
+		}
+	}
+
+	for _, p := range ps {
+		for _, problem := range p.Validate() {
+			warnings = append(warnings, fmt.Sprintf("AWS CodeCommit config for %s was invalid: %s", p.ServiceID(), problem))
+		}
+	}
+
+	return ps, problems, warnings
+}
+
+func newAuthzProvider(urn string, c *schema.AWSCodeCommitConnection) (authz.Provider, error) {
+	if c.Authorization == nil {
+		return nil, nil
+	}
+
+	if c.Authorization.IdentityProvider.Username == nil {
+		return nil, errors.New("No identityProvider was specified")
+	}
+
+	if c.Authorization.AccountID == "" {
+		return nil, errors.New("No accountID was specified")
+	}
+
+	awsPartition, ok := endpoints.DefaultPartitions().ForRegion(c.Region)
+	var awsRegion endpoints.Region
+	if ok {
+		awsRegion, ok = awsPartition.Regions()[c.Region]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unrecognized AWS region name: %q", c.Region)
+	}
+
+	awsConfig := defaults.Config()
+	awsConfig.Region = c.Region
+	awsConfig.Credentials = aws.StaticCredentialsProvider{
+		Value: aws.Credentials{
+			AccessKeyID:     c.AccessKeyID,
+			SecretAccessKey: c.SecretAccessKey,
+			Source:          "sourcegraph-site-configuration",
+		},
+	}
+
+	serviceID := awscodecommit.ServiceID(awsPartition, awsRegion, c.Authorization.AccountID)
+	return NewProvider(urn, awscodecommit.NewClient(awsConfig), serviceID, c.Authorization.AccountID), nil
+}
+
+// ValidateAuthz validates the authorization fields of the given AWS
+// CodeCommit external service config.
+func ValidateAuthz(c *schema.AWSCodeCommitConnection) error {
+	_, err := newAuthzProvider("", c)
+	return err
+}
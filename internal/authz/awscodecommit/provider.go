@@ -0,0 +1,191 @@
+// Package awscodecommit contains an authorization provider for AWS CodeCommit.
+package awscodecommit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/awscodecommit"
+)
+
+// client defines the set of AWS API client methods used by the authz provider.
+//
+// NOTE: All methods are sorted in alphabetical order.
+type client interface {
+	CallerAccountID(ctx context.Context) (string, error)
+	GetIAMUser(ctx context.Context, name string) (*awscodecommit.IAMUser, error)
+	ListIAMUsers(ctx context.Context, marker string) (users []*awscodecommit.IAMUser, nextMarker string, err error)
+	ListRepositories(ctx context.Context, nextToken string) (repos []*awscodecommit.Repository, nextNextToken string, err error)
+	SimulateGitPull(ctx context.Context, principalARN string, repoARNs []string) ([]string, error)
+}
+
+var _ client = (*awscodecommit.Client)(nil)
+
+// Provider is an implementation of authz.Provider that provides repository
+// permissions as determined by evaluating the IAM policies of IAM users.
+//
+// An IAM user is granted read access to a repository if its policies allow
+// the codecommit:GitPull action on the repository.
+type Provider struct {
+	urn       string
+	client    client
+	codeHost  *extsvc.CodeHost
+	accountID string
+}
+
+var _ authz.Provider = (*Provider)(nil)
+
+// NewProvider returns a new AWS CodeCommit authorization provider for the
+// repositories of the given service ID, which identifies the AWS account with
+// the given ID in a region. It uses the given awscodecommit.Client, whose
+// credentials must belong to that account and be allowed to read the
+// repositories and the IAM users of the account, and to simulate their
+// policies.
+func NewProvider(urn string, cli *awscodecommit.Client, serviceID, accountID string) *Provider {
+	return &Provider{
+		urn:    urn,
+		client: cli,
+		codeHost: &extsvc.CodeHost{
+			ServiceID:   serviceID,
+			ServiceType: extsvc.TypeAWSCodeCommit,
+		},
+		accountID: accountID,
+	}
+}
+
+// Validate validates that the credentials of the Provider belong to the
+// configured account and have access to its IAM users. Repositories of another
+// account would never match the permissions of this provider.
+func (p *Provider) Validate() (problems []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if accountID, err := p.client.CallerAccountID(ctx); err != nil {
+		problems = append(problems, fmt.Sprintf("unable to determine the AWS account of the access key: %s", err))
+	} else if accountID != p.accountID {
+		problems = append(problems, fmt.Sprintf("the access key belongs to AWS account %q, but authorization.accountID is %q", accountID, p.accountID))
+	}
+
+	if _, _, err := p.client.ListIAMUsers(ctx, ""); err != nil {
+		problems = append(problems, fmt.Sprintf("unable to list IAM users: %s", err))
+	}
+	return problems
+}
+
+func (p *Provider) URN() string {
+	return p.urn
+}
+
+// ServiceID returns the ARN prefix that identifies the AWS account and region
+// this provider is configured with.
+func (p *Provider) ServiceID() string { return p.codeHost.ServiceID }
+
+// ServiceType returns the type of this Provider, namely, "awscodecommit".
+func (p *Provider) ServiceType() string { return p.codeHost.ServiceType }
+
+// FetchAccount returns the IAM user whose name is the username of the given
+// user, or nil if there is none. The ARN of the IAM user is used as the
+// account ID.
+//
+// 🚨 SECURITY: Users are mapped to IAM users by name alone, without any
+// verification that the Sourcegraph user is the IAM user, so usernames must
+// not be changeable by users.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, _ []*extsvc.Account) (*extsvc.Account, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	iamUser, err := p.client.GetIAMUser(ctx, user.Username)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting IAM user %q", user.Username)
+	}
+	if iamUser == nil {
+		return nil, nil
+	}
+
+	accountData, err := json.Marshal(iamUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &extsvc.Account{
+		UserID: user.ID,
+		AccountSpec: extsvc.AccountSpec{
+			ServiceType: p.codeHost.ServiceType,
+			ServiceID:   p.codeHost.ServiceID,
+			AccountID:   iamUser.ARN,
+		},
+		AccountData: extsvc.AccountData{
+			Data: (*json.RawMessage)(&accountData),
+		},
+	}, nil
+}
+
+// FetchUserPerms returns a list of repository IDs (on code host) that the given account
+// has read access on the code host. The repository ID has the same value as it would be
+// used as api.ExternalRepoSpec.ID. All AWS CodeCommit repositories are private.
+//
+// Access is determined by simulating the IAM policies of the IAM user without any
+// context keys, so policy statements with conditions on context keys (such as
+// aws:SourceIp or aws:MultiFactorAuthPresent) are evaluated as if the keys were
+// absent.
+//
+// This method may return partial but valid results in case of error, and it is up to
+// callers to decide whether to discard.
+func (p *Provider) FetchUserPerms(ctx context.Context, account *extsvc.Account) ([]extsvc.RepoID, error) {
+	switch {
+	case account == nil:
+		return nil, errors.New("no account provided")
+	case !extsvc.IsHostOfAccount(p.codeHost, account):
+		return nil, fmt.Errorf("not a code host of the account: want %q but have %q",
+			p.codeHost.ServiceID, account.AccountSpec.ServiceID)
+	}
+
+	var repoIDs []extsvc.RepoID
+	var nextToken string
+	for {
+		repos, token, err := p.client.ListRepositories(ctx, nextToken)
+		if err != nil {
+			return repoIDs, err
+		}
+
+		ids := make(map[string]string, len(repos))
+		arns := make([]string, 0, len(repos))
+		for _, r := range repos {
+			ids[r.ARN] = r.ID
+			arns = append(arns, r.ARN)
+		}
+
+		allowed, err := p.client.SimulateGitPull(ctx, account.AccountID, arns)
+		if err != nil {
+			return repoIDs, err
+		}
+		for _, arn := range allowed {
+			if id, ok := ids[arn]; ok {
+				repoIDs = append(repoIDs, extsvc.RepoID(id))
+			}
+		}
+
+		if len(repos) == 0 || token == "" {
+			break // last page
+		}
+		nextToken = token
+	}
+
+	return repoIDs, nil
+}
+
+// FetchRepoPerms is not implemented, because it would have to evaluate the
+// policies of every IAM user of the account for every repository. Permissions
+// are only synced per user by FetchUserPerms instead, which evaluates the
+// policies of one IAM user for all repositories at once.
+func (p *Provider) FetchRepoPerms(ctx context.Context, repo *extsvc.Repository) ([]extsvc.AccountID, error) {
+	return nil, authz.ErrUnimplemented
+}
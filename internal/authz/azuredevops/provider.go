@@ -0,0 +1,304 @@
+// Package azuredevops contains an authorization provider for Azure DevOps.
+package azuredevops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/azuredevops"
+)
+
+// client defines the set of Azure DevOps API client methods used by the authz provider.
+//
+// NOTE: All methods are sorted in alphabetical order.
+type client interface {
+	FindIdentity(ctx context.Context, org, accountName string) (*azuredevops.Identity, error)
+	ListProjects(ctx context.Context, org string, page int) (projects []*azuredevops.Project, hasNextPage bool, err error)
+	ListRepos(ctx context.Context, org, project string) ([]*azuredevops.Repository, error)
+	ListTeamMembers(ctx context.Context, org, project, team string, page int) (members []*azuredevops.TeamMember, hasNextPage bool, err error)
+	ListTeams(ctx context.Context, org, project string, page int) (teams []*azuredevops.Team, hasNextPage bool, err error)
+}
+
+var _ client = (*azuredevops.Client)(nil)
+
+// Provider is an implementation of authz.Provider that provides repository
+// permissions as determined from the Azure DevOps API.
+//
+// A user is granted read access to all repositories of a private project if
+// they are a direct member of any team of the project. Only team membership
+// grants access: groups that are members of a team are skipped rather than
+// expanded, and permissions granted to users or groups outside of teams are
+// ignored.
+type Provider struct {
+	urn      string
+	client   client
+	codeHost *extsvc.CodeHost
+	orgs     []string
+	domain   string
+
+	// membersTTL is how long the members of a project are cached, so that a
+	// sync of the permissions of all users lists the members of each project
+	// once rather than once per user.
+	membersTTL time.Duration
+
+	mu      sync.Mutex
+	members map[string]cachedMembers
+}
+
+type cachedMembers struct {
+	ids     map[string]bool
+	fetched time.Time
+}
+
+// defaultMembersTTL is the default duration the members of a project are cached.
+const defaultMembersTTL = 10 * time.Minute
+
+var _ authz.Provider = (*Provider)(nil)
+
+// NewProvider returns a new Azure DevOps authorization provider that uses the
+// given azuredevops.Client, whose token must be able to read the projects,
+// teams and identities of the given organizations. It assumes the principal
+// name of the Azure DevOps user of a Sourcegraph account is its username
+// followed by "@" and the given domain.
+func NewProvider(urn string, cli *azuredevops.Client, orgs []string, domain string) *Provider {
+	return &Provider{
+		urn:      urn,
+		client:   cli,
+		codeHost: extsvc.NewCodeHost(cli.URL, extsvc.TypeAzureDevOps),
+		orgs:     orgs,
+		domain:   domain,
+
+		membersTTL: defaultMembersTTL,
+		members:    make(map[string]cachedMembers),
+	}
+}
+
+// Validate validates that the Provider has access to the projects of all
+// configured organizations.
+func (p *Provider) Validate() (problems []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, org := range p.orgs {
+		if _, _, err := p.client.ListProjects(ctx, org, 1); err != nil {
+			problems = append(problems, fmt.Sprintf("unable to list projects of organization %q: %s", org, err))
+		}
+	}
+	return problems
+}
+
+func (p *Provider) URN() string {
+	return p.urn
+}
+
+// ServiceID returns the absolute URL that identifies the Azure DevOps
+// instance this provider is configured with.
+func (p *Provider) ServiceID() string { return p.codeHost.ServiceID }
+
+// ServiceType returns the type of this Provider, namely, "azuredevops".
+func (p *Provider) ServiceType() string { return p.codeHost.ServiceType }
+
+// FetchAccount returns the Azure DevOps identity whose principal name is the
+// username of the given user followed by "@" and the configured domain, or nil
+// if there is none in any of the configured organizations.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, _ []*extsvc.Account) (*extsvc.Account, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	principalName := user.Username + "@" + p.domain
+	for _, org := range p.orgs {
+		identity, err := p.client.FindIdentity(ctx, org, principalName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "finding identity %q in organization %q", principalName, org)
+		}
+		if identity == nil {
+			continue
+		}
+
+		accountData, err := json.Marshal(identity)
+		if err != nil {
+			return nil, err
+		}
+
+		return &extsvc.Account{
+			UserID: user.ID,
+			AccountSpec: extsvc.AccountSpec{
+				ServiceType: p.codeHost.ServiceType,
+				ServiceID:   p.codeHost.ServiceID,
+				AccountID:   identity.ID,
+			},
+			AccountData: extsvc.AccountData{
+				Data: (*json.RawMessage)(&accountData),
+			},
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// FetchUserPerms returns a list of repository IDs (on code host) that the given account
+// has read access on the code host. The repository ID has the same value as it would be
+// used as api.ExternalRepoSpec.ID. The returned list only includes private repository IDs.
+//
+// This method may return partial but valid results in case of error, and it is up to
+// callers to decide whether to discard.
+func (p *Provider) FetchUserPerms(ctx context.Context, account *extsvc.Account) ([]extsvc.RepoID, error) {
+	switch {
+	case account == nil:
+		return nil, errors.New("no account provided")
+	case !extsvc.IsHostOfAccount(p.codeHost, account):
+		return nil, fmt.Errorf("not a code host of the account: want %q but have %q",
+			p.codeHost.ServiceID, account.AccountSpec.ServiceID)
+	}
+
+	var repoIDs []extsvc.RepoID
+	for _, org := range p.orgs {
+		hasNextPage := true
+		for page := 1; hasNextPage; page++ {
+			var projects []*azuredevops.Project
+			var err error
+			projects, hasNextPage, err = p.client.ListProjects(ctx, org, page)
+			if err != nil {
+				return repoIDs, err
+			}
+
+			for _, project := range projects {
+				if project.Visibility == "public" {
+					continue
+				}
+
+				members, err := p.projectMembers(ctx, org, project.ID)
+				if err != nil {
+					return repoIDs, err
+				}
+				if !members[account.AccountID] {
+					continue
+				}
+
+				repos, err := p.client.ListRepos(ctx, org, project.ID)
+				if err != nil {
+					return repoIDs, err
+				}
+				for _, r := range repos {
+					repoIDs = append(repoIDs, extsvc.RepoID(r.ID))
+				}
+			}
+		}
+	}
+
+	return repoIDs, nil
+}
+
+// FetchRepoPerms returns a list of user IDs (on code host) who have read access to
+// the given repository on the code host. The user ID has the same value as it would
+// be used as extsvc.Account.AccountID. The returned list includes the direct members
+// of all teams of the project of the repository. Groups which are members of a team
+// are not expanded, so their members are not included.
+//
+// This method may return partial but valid results in case of error, and it is up to
+// callers to decide whether to discard.
+func (p *Provider) FetchRepoPerms(ctx context.Context, repo *extsvc.Repository) ([]extsvc.AccountID, error) {
+	switch {
+	case repo == nil:
+		return nil, errors.New("no repository provided")
+	case !extsvc.IsHostOfRepo(p.codeHost, &repo.ExternalRepoSpec):
+		return nil, fmt.Errorf("not a code host of the repository: want %q but have %q",
+			p.codeHost.ServiceID, repo.ServiceID)
+	}
+
+	// NOTE: We do not store port or scheme in our URI, so stripping the hostname alone is enough.
+	nameWithOwner := strings.TrimPrefix(repo.URI, p.codeHost.BaseURL.Hostname())
+	nameWithOwner = strings.TrimPrefix(nameWithOwner, "/")
+
+	parts := strings.SplitN(nameWithOwner, "/", 3)
+	if len(parts) != 3 {
+		return nil, errors.Errorf("invalid Azure DevOps repository name %q", nameWithOwner)
+	}
+
+	members, err := p.projectMembers(ctx, parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]extsvc.AccountID, 0, len(members))
+	for id := range members {
+		userIDs = append(userIDs, extsvc.AccountID(id))
+	}
+	return userIDs, nil
+}
+
+// projectMembers returns the set of identity IDs of the direct members of all
+// teams of the project, which may be given by name or ID. The set is cached for
+// membersTTL and must not be modified.
+func (p *Provider) projectMembers(ctx context.Context, org, project string) (map[string]bool, error) {
+	key := org + "/" + project
+
+	p.mu.Lock()
+	cached, ok := p.members[key]
+	p.mu.Unlock()
+	if ok && time.Since(cached.fetched) < p.membersTTL {
+		return cached.ids, nil
+	}
+
+	members, err := p.fetchProjectMembers(ctx, org, project)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Evict expired entries so projects which no longer exist are dropped.
+	for k, c := range p.members {
+		if time.Since(c.fetched) >= p.membersTTL {
+			delete(p.members, k)
+		}
+	}
+	p.members[key] = cachedMembers{ids: members, fetched: time.Now()}
+	return members, nil
+}
+
+// fetchProjectMembers fetches the set of identity IDs of the direct members of
+// all teams of the project. Groups are skipped, because only users who are team
+// members are granted access.
+func (p *Provider) fetchProjectMembers(ctx context.Context, org, project string) (map[string]bool, error) {
+	members := make(map[string]bool)
+
+	hasNextPage := true
+	for page := 1; hasNextPage; page++ {
+		var teams []*azuredevops.Team
+		var err error
+		teams, hasNextPage, err = p.client.ListTeams(ctx, org, project, page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, team := range teams {
+			hasNextMembersPage := true
+			for membersPage := 1; hasNextMembersPage; membersPage++ {
+				var ms []*azuredevops.TeamMember
+				ms, hasNextMembersPage, err = p.client.ListTeamMembers(ctx, org, project, team.ID, membersPage)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, m := range ms {
+					if !m.Identity.IsContainer {
+						members[m.Identity.ID] = true
+					}
+				}
+			}
+		}
+	}
+
+	return members, nil
+}
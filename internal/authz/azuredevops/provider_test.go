@@ -0,0 +1,189 @@
+package azuredevops
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/azuredevops"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/httptestutil"
+)
+
+var update = flag.Bool("update", false, "update testdata")
+
+// newTestProvider returns a Provider for the organizations sgtest and sgtest2
+// whose client replays the Azure DevOps API interactions recorded in
+// testdata/vcr/{name}.yaml.
+//
+// The recorded interactions are those of the users alice, bob, carol and dave
+// of the domain example.com and the organizations sgtest, with the private
+// projects p1 (team members alice and the group Readers), p2 (team members
+// bob, carol and dave) and "My Project" and the public project p3, and
+// sgtest2, with the private project p4 whose team only has the group Readers
+// as member. To update them, set AZURE_DEVOPS_TOKEN to a token that can read
+// both organizations and run the tests with -update=true.
+func newTestProvider(t *testing.T, name string) *Provider {
+	t.Helper()
+
+	rec, err := httptestutil.NewRecorder(filepath.Join("testdata/vcr", name), *update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Stop(); err != nil {
+			t.Errorf("failed to update test data: %s", err)
+		}
+	})
+
+	hc, err := httpcli.NewFactory(nil, httptestutil.NewRecorderOpt(rec)).Doer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse("https://dev.azure.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := azuredevops.NewClient(u, os.Getenv("AZURE_DEVOPS_TOKEN"), hc)
+	cli.PageSize = 2 // Exercise pagination
+	return NewProvider("extsvc:azuredevops:1", cli, []string{"sgtest", "sgtest2"}, "example.com")
+}
+
+const (
+	aliceID = "8b3c9f01-5b6a-4d2e-9f1a-2c7d4e6f8a01"
+	bobID   = "8b3c9f02-5b6a-4d2e-9f1a-2c7d4e6f8a02"
+)
+
+func TestProvider_Validate(t *testing.T) {
+	// The token cannot read the projects of sgtest2.
+	problems := newTestProvider(t, "Validate").Validate()
+	if len(problems) != 1 || !strings.Contains(problems[0], `"sgtest2"`) || !strings.Contains(problems[0], "401") {
+		t.Errorf("have problems %q, want one for sgtest2 with status 401", problems)
+	}
+}
+
+func TestProvider_FetchAccount(t *testing.T) {
+	p := newTestProvider(t, "FetchAccount")
+
+	// alice only has an identity in sgtest2.
+	acct, err := p.FetchAccount(context.Background(), &types.User{ID: 1, Username: "alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := extsvc.AccountSpec{
+		ServiceType: extsvc.TypeAzureDevOps,
+		ServiceID:   "https://dev.azure.com/",
+		AccountID:   aliceID,
+	}
+	if diff := cmp.Diff(want, acct.AccountSpec); diff != "" {
+		t.Error(diff)
+	}
+
+	var identity azuredevops.Identity
+	if err := json.Unmarshal(*acct.Data, &identity); err != nil {
+		t.Fatal(err)
+	}
+	if identity.ProviderDisplayName != "Alice" {
+		t.Errorf("have account data for %q, want Alice", identity.ProviderDisplayName)
+	}
+
+	acct, err = p.FetchAccount(context.Background(), &types.User{ID: 2, Username: "bob"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct != nil {
+		t.Errorf("have account %+v, want nil", acct)
+	}
+}
+
+func TestProvider_FetchUserPerms(t *testing.T) {
+	p := newTestProvider(t, "FetchUserPerms")
+
+	acct := &extsvc.Account{
+		AccountSpec: extsvc.AccountSpec{
+			ServiceType: extsvc.TypeAzureDevOps,
+			ServiceID:   "https://dev.azure.com/",
+			AccountID:   aliceID,
+		},
+	}
+
+	repoIDs, err := p.FetchUserPerms(context.Background(), acct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The repositories of p1. The public project p3 is skipped, and being a
+	// member of the group Readers does not grant access to p4.
+	want := []extsvc.RepoID{"8b3c9f29-5b6a-4d2e-9f1a-2c7d4e6f8a29", "8b3c9f2a-5b6a-4d2e-9f1a-2c7d4e6f8a2a"}
+	if diff := cmp.Diff(want, repoIDs); diff != "" {
+		t.Error(diff)
+	}
+
+	// The members of the projects are cached for the next user, so only the
+	// projects and the repositories of p2 are requested again.
+	bob := *acct
+	bob.AccountID = bobID
+	repoIDs, err = p.FetchUserPerms(context.Background(), &bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]extsvc.RepoID{"8b3c9f2b-5b6a-4d2e-9f1a-2c7d4e6f8a2b"}, repoIDs); diff != "" {
+		t.Error(diff)
+	}
+
+	acct.ServiceID = "https://ado.example.com/"
+	if _, err := p.FetchUserPerms(context.Background(), acct); err == nil {
+		t.Error("expected an error for an account of another code host")
+	}
+}
+
+func TestProvider_FetchRepoPerms(t *testing.T) {
+	p := newTestProvider(t, "FetchRepoPerms")
+
+	tests := []struct {
+		uri      string
+		want     []extsvc.AccountID
+		notFound bool
+	}{
+		// The team members except for the group Readers.
+		{uri: "dev.azure.com/sgtest/My Project/repo", want: []extsvc.AccountID{aliceID, bobID}},
+		{uri: "dev.azure.com/sgtest/Missing/repo", notFound: true},
+	}
+	for _, test := range tests {
+		t.Run(test.uri, func(t *testing.T) {
+			userIDs, err := p.FetchRepoPerms(context.Background(), &extsvc.Repository{
+				URI: test.uri,
+				ExternalRepoSpec: api.ExternalRepoSpec{
+					ID:          "r1",
+					ServiceType: extsvc.TypeAzureDevOps,
+					ServiceID:   "https://dev.azure.com/",
+				},
+			})
+			if test.notFound {
+				if !azuredevops.IsNotFound(err) {
+					t.Fatalf("have error %v, want not found", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+			if diff := cmp.Diff(test.want, userIDs); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
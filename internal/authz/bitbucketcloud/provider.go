@@ -0,0 +1,164 @@
+// Package bitbucketcloud contains an authorization provider for Bitbucket Cloud.
+package bitbucketcloud
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
+)
+
+// client defines the set of Bitbucket Cloud API client methods used by the authz provider.
+//
+// NOTE: All methods are sorted in alphabetical order.
+type client interface {
+	ListRepositoryPermissions(ctx context.Context, workspace, repoSlug string) ([]*bitbucketcloud.RepositoryPermission, error)
+	ListUserRepositoryPermissions(ctx context.Context, workspace, userUUID string) ([]*bitbucketcloud.RepositoryPermission, error)
+	ListWorkspaceMembers(ctx context.Context, workspace string) ([]*bitbucketcloud.WorkspaceMembership, error)
+}
+
+var _ client = (*bitbucketcloud.Client)(nil)
+
+// Provider is an implementation of authz.Provider that provides repository
+// permissions as determined from the workspace permissions of Bitbucket Cloud.
+//
+// A user is granted read access to a repository if they have any explicit or
+// group-based permission on it, as reported by the workspace permissions API.
+type Provider struct {
+	urn        string
+	client     client
+	codeHost   *extsvc.CodeHost
+	workspaces []string
+}
+
+var _ authz.Provider = (*Provider)(nil)
+
+// NewProvider returns a new Bitbucket Cloud authorization provider for the
+// Bitbucket Cloud instance at baseURL. It uses the given bitbucketcloud.Client,
+// whose credentials must belong to an administrator of all given workspaces.
+func NewProvider(urn string, baseURL *url.URL, cli *bitbucketcloud.Client, workspaces []string) *Provider {
+	return &Provider{
+		urn:        urn,
+		client:     cli,
+		codeHost:   extsvc.NewCodeHost(baseURL, extsvc.TypeBitbucketCloud),
+		workspaces: workspaces,
+	}
+}
+
+// Validate validates that the Provider has access to the members of all
+// configured workspaces.
+func (p *Provider) Validate() (problems []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, ws := range p.workspaces {
+		if _, err := p.client.ListWorkspaceMembers(ctx, ws); err != nil {
+			problems = append(problems, fmt.Sprintf("unable to list members of workspace %q: %s", ws, err))
+		}
+	}
+	return problems
+}
+
+func (p *Provider) URN() string {
+	return p.urn
+}
+
+// ServiceID returns the absolute URL that identifies the Bitbucket Cloud
+// instance this provider is configured with.
+func (p *Provider) ServiceID() string { return p.codeHost.ServiceID }
+
+// ServiceType returns the type of this Provider, namely, "bitbucketCloud".
+func (p *Provider) ServiceType() string { return p.codeHost.ServiceType }
+
+// FetchAccount returns the Bitbucket Cloud account of this code host the given
+// user has linked via OAuth, or nil if there is none. It never maps users to
+// Bitbucket Cloud accounts by name, because nicknames are chosen by their users
+// and are not unique.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, current []*extsvc.Account) (*extsvc.Account, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	for _, acct := range current {
+		if extsvc.IsHostOfAccount(p.codeHost, acct) {
+			return acct, nil
+		}
+	}
+	return nil, nil
+}
+
+// FetchUserPerms returns a list of repository IDs (on code host) that the given account
+// has read access on the code host. The repository ID has the same value as it would be
+// used as api.ExternalRepoSpec.ID. The returned list includes all repositories of the
+// configured workspaces the account has any permission on, whether they're private or not.
+//
+// This method may return partial but valid results in case of error, and it is up to
+// callers to decide whether to discard.
+func (p *Provider) FetchUserPerms(ctx context.Context, account *extsvc.Account) ([]extsvc.RepoID, error) {
+	switch {
+	case account == nil:
+		return nil, errors.New("no account provided")
+	case !extsvc.IsHostOfAccount(p.codeHost, account):
+		return nil, fmt.Errorf("not a code host of the account: want %q but have %q",
+			p.codeHost.ServiceID, account.AccountSpec.ServiceID)
+	}
+
+	var repoIDs []extsvc.RepoID
+	for _, ws := range p.workspaces {
+		perms, err := p.client.ListUserRepositoryPermissions(ctx, ws, account.AccountID)
+		if err != nil {
+			return repoIDs, err
+		}
+
+		for _, perm := range perms {
+			repoIDs = append(repoIDs, extsvc.RepoID(perm.Repository.UUID))
+		}
+	}
+
+	return repoIDs, nil
+}
+
+// FetchRepoPerms returns a list of user IDs (on code host) who have read access to
+// the given repository on the code host. The user ID has the same value as it would
+// be used as extsvc.Account.AccountID. The returned list includes all users with any
+// permission on the repository.
+//
+// This method may return partial but valid results in case of error, and it is up to
+// callers to decide whether to discard.
+func (p *Provider) FetchRepoPerms(ctx context.Context, repo *extsvc.Repository) ([]extsvc.AccountID, error) {
+	switch {
+	case repo == nil:
+		return nil, errors.New("no repository provided")
+	case !extsvc.IsHostOfRepo(p.codeHost, &repo.ExternalRepoSpec):
+		return nil, fmt.Errorf("not a code host of the repository: want %q but have %q",
+			p.codeHost.ServiceID, repo.ServiceID)
+	}
+
+	// NOTE: We do not store port or scheme in our URI, so stripping the hostname alone is enough.
+	fullName := strings.TrimPrefix(repo.URI, p.codeHost.BaseURL.Hostname())
+	fullName = strings.TrimPrefix(fullName, "/")
+
+	parts := strings.SplitN(fullName, "/", 2)
+	if len(parts) != 2 {
+		return nil, errors.Errorf("invalid Bitbucket Cloud repository name %q", fullName)
+	}
+
+	perms, err := p.client.ListRepositoryPermissions(ctx, parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]extsvc.AccountID, 0, len(perms))
+	for _, perm := range perms {
+		userIDs = append(userIDs, extsvc.AccountID(perm.User.UUID))
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,171 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"flag"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
+)
+
+var update = flag.Bool("update", false, "update testdata")
+
+// newTestProvider returns a Provider for the workspaces sglocal and sgtest
+// whose client replays the Bitbucket Cloud API interactions recorded in
+// testdata/vcr/{name}.yaml.
+//
+// The recorded interactions are those of the workspace sglocal with the
+// members alice, bob and carol and the repositories mux (alice admin, bob
+// read, carol write), gorilla (alice write) and notes (alice admin), and the
+// workspace sgtest, whose members the credentials may not list. To update
+// them, set BITBUCKET_CLOUD_USERNAME and BITBUCKET_CLOUD_APP_PASSWORD to the
+// credentials of an admin of sglocal and run the tests with -update=true.
+func newTestProvider(t *testing.T, name string) *Provider {
+	t.Helper()
+
+	baseURL, err := url.Parse("https://bitbucket.org/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiURL, err := url.Parse("https://api.bitbucket.org/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cli, save := bitbucketcloud.NewTestClient(t, name, *update, apiURL)
+	t.Cleanup(save)
+	cli.PageLen = 2 // Exercise pagination
+
+	return NewProvider("extsvc:bitbucketCloud:1", baseURL, cli, []string{"sglocal", "sgtest"})
+}
+
+const (
+	aliceUUID = "{6f8a2701-0e3a-4b8e-9c7d-1a2b3c4d5e01}"
+	bobUUID   = "{6f8a2702-0e3a-4b8e-9c7d-1a2b3c4d5e02}"
+	carolUUID = "{6f8a2703-0e3a-4b8e-9c7d-1a2b3c4d5e03}"
+)
+
+func TestProvider_Validate(t *testing.T) {
+	problems := newTestProvider(t, "Validate").Validate()
+	if len(problems) != 1 || !strings.Contains(problems[0], `"sgtest"`) || !strings.Contains(problems[0], "code=403") {
+		t.Errorf("have problems %q, want one for sgtest with code=403", problems)
+	}
+}
+
+func TestProvider_FetchAccount(t *testing.T) {
+	// The recording has no interactions, because accounts are never looked
+	// up through the API.
+	p := newTestProvider(t, "FetchAccount")
+
+	// Accounts are not mapped by nickname.
+	acct, err := p.FetchAccount(context.Background(), &types.User{ID: 1, Username: "alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct != nil {
+		t.Errorf("have account %+v, want nil", acct)
+	}
+
+	// Accounts of other code hosts are ignored.
+	other := &extsvc.Account{AccountSpec: extsvc.AccountSpec{
+		ServiceType: extsvc.TypeGitHub,
+		ServiceID:   "https://github.com/",
+		AccountID:   "1",
+	}}
+	acct, err = p.FetchAccount(context.Background(), &types.User{ID: 1, Username: "alice"}, []*extsvc.Account{other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct != nil {
+		t.Errorf("have account %+v, want nil", acct)
+	}
+
+	// The account linked via OAuth is used.
+	linked := &extsvc.Account{AccountSpec: extsvc.AccountSpec{
+		ServiceType: extsvc.TypeBitbucketCloud,
+		ServiceID:   "https://bitbucket.org/",
+		AccountID:   carolUUID,
+	}}
+	acct, err = p.FetchAccount(context.Background(), &types.User{ID: 2, Username: "carol"}, []*extsvc.Account{other, linked})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct != linked {
+		t.Errorf("have account %+v, want %+v", acct, linked)
+	}
+}
+
+func TestProvider_FetchUserPerms(t *testing.T) {
+	p := newTestProvider(t, "FetchUserPerms")
+
+	acct := &extsvc.Account{
+		AccountSpec: extsvc.AccountSpec{
+			ServiceType: extsvc.TypeBitbucketCloud,
+			ServiceID:   "https://bitbucket.org/",
+			AccountID:   aliceUUID,
+		},
+	}
+
+	repoIDs, err := p.FetchUserPerms(context.Background(), acct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The repositories mux, gorilla and notes of sglocal.
+	want := []extsvc.RepoID{
+		"{6f8a2729-0e3a-4b8e-9c7d-1a2b3c4d5e29}",
+		"{6f8a272a-0e3a-4b8e-9c7d-1a2b3c4d5e2a}",
+		"{6f8a272b-0e3a-4b8e-9c7d-1a2b3c4d5e2b}",
+	}
+	if diff := cmp.Diff(want, repoIDs); diff != "" {
+		t.Error(diff)
+	}
+
+	acct.ServiceID = "https://bitbucket.example.com/"
+	if _, err := p.FetchUserPerms(context.Background(), acct); err == nil {
+		t.Error("expected an error for an account of another code host")
+	}
+}
+
+func TestProvider_FetchRepoPerms(t *testing.T) {
+	p := newTestProvider(t, "FetchRepoPerms")
+
+	tests := []struct {
+		uri      string
+		want     []extsvc.AccountID
+		notFound bool
+	}{
+		{uri: "bitbucket.org/sglocal/mux", want: []extsvc.AccountID{aliceUUID, bobUUID, carolUUID}},
+		{uri: "bitbucket.org/sglocal/missing", notFound: true},
+	}
+	for _, test := range tests {
+		t.Run(test.uri, func(t *testing.T) {
+			userIDs, err := p.FetchRepoPerms(context.Background(), &extsvc.Repository{
+				URI: test.uri,
+				ExternalRepoSpec: api.ExternalRepoSpec{
+					ID:          "{mux}",
+					ServiceType: extsvc.TypeBitbucketCloud,
+					ServiceID:   "https://bitbucket.org/",
+				},
+			})
+			if test.notFound {
+				if !bitbucketcloud.IsNotFound(err) {
+					t.Fatalf("have error %v, want not found", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, userIDs); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Package gitea contains an authorization provider for Gitea.
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/authz"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitea"
+)
+
+// client defines the set of Gitea API client methods used by the authz provider.
+//
+// NOTE: All methods are sorted in alphabetical order.
+type client interface {
+	CurrentUser(ctx context.Context) (*gitea.User, error)
+	GetOrg(ctx context.Context, name string) (*gitea.Organization, error)
+	GetUser(ctx context.Context, username string) (*gitea.User, error)
+	ListAccessibleRepos(ctx context.Context, page int) (repos []*gitea.Repository, hasNextPage bool, err error)
+	ListRepoCollaborators(ctx context.Context, owner, name string, page int) (users []*gitea.User, hasNextPage bool, err error)
+	ListRepoTeams(ctx context.Context, owner, name string) ([]*gitea.Team, error)
+	ListTeamMembers(ctx context.Context, teamID int64, page int) (users []*gitea.User, hasNextPage bool, err error)
+	Sudo(username string) client
+}
+
+var _ client = (*ClientAdapter)(nil)
+
+// ClientAdapter is an adapter for Gitea API client.
+type ClientAdapter struct {
+	*gitea.Client
+}
+
+func (c *ClientAdapter) Sudo(username string) client {
+	return &ClientAdapter{Client: c.Client.Sudo(username)}
+}
+
+// Provider is an implementation of authz.Provider that provides repository
+// permissions as determined from a Gitea instance API.
+type Provider struct {
+	urn      string
+	client   client
+	codeHost *extsvc.CodeHost
+}
+
+var _ authz.Provider = (*Provider)(nil)
+
+// NewProvider returns a new Gitea authorization provider that uses the given
+// gitea.Client, whose token must belong to a site admin, to talk to the Gitea
+// API that is the source of truth for permissions. It assumes usernames of
+// Sourcegraph accounts match 1-1 with usernames of Gitea users.
+func NewProvider(urn string, cli *gitea.Client) *Provider {
+	return &Provider{
+		urn:      urn,
+		client:   &ClientAdapter{Client: cli},
+		codeHost: extsvc.NewCodeHost(cli.URL, extsvc.TypeGitea),
+	}
+}
+
+// Validate validates that the Provider has access to the Gitea API as a site
+// admin, which is required to fetch permissions of other users.
+func (p *Provider) Validate() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	u, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if !u.IsAdmin {
+		return []string{fmt.Sprintf("the token of user %q is not a site admin token", u.Login)}
+	}
+	return nil
+}
+
+func (p *Provider) URN() string {
+	return p.urn
+}
+
+// ServiceID returns the absolute URL that identifies the Gitea instance this
+// provider is configured with.
+func (p *Provider) ServiceID() string { return p.codeHost.ServiceID }
+
+// ServiceType returns the type of this Provider, namely, "gitea".
+func (p *Provider) ServiceType() string { return p.codeHost.ServiceType }
+
+// FetchAccount returns the Gitea user with the same username as the given
+// user, or nil if there is none.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, _ []*extsvc.Account) (*extsvc.Account, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	giteaUser, err := p.client.GetUser(ctx, user.Username)
+	if err != nil {
+		if gitea.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	accountData, err := json.Marshal(giteaUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &extsvc.Account{
+		UserID: user.ID,
+		AccountSpec: extsvc.AccountSpec{
+			ServiceType: p.codeHost.ServiceType,
+			ServiceID:   p.codeHost.ServiceID,
+			AccountID:   strconv.FormatInt(giteaUser.ID, 10),
+		},
+		AccountData: extsvc.AccountData{
+			Data: (*json.RawMessage)(&accountData),
+		},
+	}, nil
+}
+
+// FetchUserPerms returns a list of repository IDs (on code host) that the given account
+// has read access on the code host. The repository ID has the same value as it would be
+// used as api.ExternalRepoSpec.ID. The returned list only includes private repository IDs.
+//
+// This method may return partial but valid results in case of error, and it is up to
+// callers to decide whether to discard.
+//
+// API docs: https://try.gitea.io/api/swagger#/user/userCurrentListRepos
+func (p *Provider) FetchUserPerms(ctx context.Context, account *extsvc.Account) ([]extsvc.RepoID, error) {
+	switch {
+	case account == nil:
+		return nil, errors.New("no account provided")
+	case account.Data == nil:
+		return nil, errors.New("no account data provided")
+	case !extsvc.IsHostOfAccount(p.codeHost, account):
+		return nil, fmt.Errorf("not a code host of the account: want %q but have %q",
+			p.codeHost.ServiceID, account.AccountSpec.ServiceID)
+	}
+
+	var user gitea.User
+	if err := json.Unmarshal(*account.Data, &user); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling account data")
+	}
+
+	// 🚨 SECURITY: Make the requests as the user to only list repositories
+	// the user has access to.
+	client := p.client.Sudo(user.Login)
+
+	var repoIDs []extsvc.RepoID
+	hasNextPage := true
+	for page := 1; hasNextPage; page++ {
+		var repos []*gitea.Repository
+		var err error
+		repos, hasNextPage, err = client.ListAccessibleRepos(ctx, page)
+		if err != nil {
+			return repoIDs, err
+		}
+
+		for _, r := range repos {
+			if r.Private {
+				repoIDs = append(repoIDs, extsvc.RepoID(strconv.FormatInt(r.ID, 10)))
+			}
+		}
+	}
+
+	return repoIDs, nil
+}
+
+// FetchRepoPerms returns a list of user IDs (on code host) who have read access to
+// the given repository on the code host. The user ID has the same value as it would
+// be used as extsvc.Account.AccountID. The returned list includes the collaborators
+// of the repository and either its owner, if it is owned by a user, or the members
+// of the teams with access to it, if it is owned by an organization. Site admins of
+// Gitea are not included.
+//
+// This method may return partial but valid results in case of error, and it is up to
+// callers to decide whether to discard.
+//
+// API docs: https://try.gitea.io/api/swagger#/repository/repoListCollaborators
+func (p *Provider) FetchRepoPerms(ctx context.Context, repo *extsvc.Repository) ([]extsvc.AccountID, error) {
+	switch {
+	case repo == nil:
+		return nil, errors.New("no repository provided")
+	case !extsvc.IsHostOfRepo(p.codeHost, &repo.ExternalRepoSpec):
+		return nil, fmt.Errorf("not a code host of the repository: want %q but have %q",
+			p.codeHost.ServiceID, repo.ServiceID)
+	}
+
+	// NOTE: We do not store port or scheme in our URI, so stripping the hostname alone is enough.
+	nameWithOwner := strings.TrimPrefix(repo.URI, p.codeHost.BaseURL.Hostname())
+	nameWithOwner = strings.TrimPrefix(nameWithOwner, "/")
+
+	parts := strings.SplitN(nameWithOwner, "/", 2)
+	if len(parts) != 2 {
+		return nil, errors.Errorf("invalid Gitea repository name %q", nameWithOwner)
+	}
+	owner, name := parts[0], parts[1]
+
+	var userIDs []extsvc.AccountID
+	seen := make(map[int64]bool)
+	addUsers := func(users ...*gitea.User) {
+		for _, u := range users {
+			if !seen[u.ID] {
+				seen[u.ID] = true
+				userIDs = append(userIDs, extsvc.AccountID(strconv.FormatInt(u.ID, 10)))
+			}
+		}
+	}
+
+	hasNextPage := true
+	for page := 1; hasNextPage; page++ {
+		var users []*gitea.User
+		var err error
+		users, hasNextPage, err = p.client.ListRepoCollaborators(ctx, owner, name, page)
+		if err != nil {
+			return userIDs, err
+		}
+		addUsers(users...)
+	}
+
+	org, err := p.client.GetOrg(ctx, owner)
+	if err != nil {
+		return userIDs, errors.Wrapf(err, "getting organization %q", owner)
+	}
+	if org == nil {
+		// The repository is owned by a user.
+		u, err := p.client.GetUser(ctx, owner)
+		if err != nil {
+			return userIDs, errors.Wrapf(err, "getting owner %q", owner)
+		}
+		addUsers(u)
+		return userIDs, nil
+	}
+
+	teams, err := p.client.ListRepoTeams(ctx, owner, name)
+	if err != nil {
+		return userIDs, err
+	}
+	for _, t := range teams {
+		hasNextPage := true
+		for page := 1; hasNextPage; page++ {
+			var users []*gitea.User
+			users, hasNextPage, err = p.client.ListTeamMembers(ctx, t.ID, page)
+			if err != nil {
+				return userIDs, err
+			}
+			addUsers(users...)
+		}
+	}
+
+	return userIDs, nil
+}
@@ -0,0 +1,188 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitea"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/httptestutil"
+)
+
+var update = flag.Bool("update", false, "update testdata")
+
+// newTestProvider returns a Provider whose client replays the Gitea API
+// interactions recorded in testdata/vcr/{name}.yaml.
+//
+// The recorded interactions are those of a Gitea instance on port 3000 with
+// the site admin gitea-admin, the users alice, bob, carol, dave and erin, and
+// the organization sourcegraph with the teams Owners (alice) and Developers
+// (bob, dave and erin). The private repository sourcegraph/sourcegraph has
+// the collaborators bob and carol, the private repository alice/notes the
+// collaborator bob. To update them, start such an instance with docker, set
+// GITEA_TOKEN to a token of gitea-admin and run the tests with -update=true.
+func newTestProvider(t *testing.T, name string) *Provider {
+	t.Helper()
+
+	rec, err := httptestutil.NewRecorder(filepath.Join("testdata/vcr", name), *update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Stop(); err != nil {
+			t.Errorf("failed to update test data: %s", err)
+		}
+	})
+
+	hc, err := httpcli.NewFactory(nil, httptestutil.NewRecorderOpt(rec)).Doer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse("http://127.0.0.1:3000/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := gitea.NewClient(u, os.Getenv("GITEA_TOKEN"), hc)
+	cli.PageSize = 2 // Exercise pagination
+	return NewProvider("extsvc:gitea:1", cli)
+}
+
+func TestProvider_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		problem string
+	}{
+		{name: "admin"},
+		{name: "unauthorized", problem: "code=401"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems := newTestProvider(t, "Validate-"+test.name).Validate()
+			if test.problem == "" {
+				if len(problems) != 0 {
+					t.Errorf("unexpected problems %q", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || !strings.Contains(problems[0], test.problem) {
+				t.Errorf("have problems %q, want one containing %q", problems, test.problem)
+			}
+		})
+	}
+}
+
+func TestProvider_FetchAccount(t *testing.T) {
+	p := newTestProvider(t, "FetchAccount")
+
+	acct, err := p.FetchAccount(context.Background(), &types.User{ID: 1, Username: "alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := extsvc.AccountSpec{
+		ServiceType: extsvc.TypeGitea,
+		ServiceID:   "http://127.0.0.1:3000/",
+		AccountID:   "2",
+	}
+	if diff := cmp.Diff(want, acct.AccountSpec); diff != "" {
+		t.Error(diff)
+	}
+
+	var user gitea.User
+	if err := json.Unmarshal(*acct.Data, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Login != "alice" {
+		t.Errorf("have account data for %q, want alice", user.Login)
+	}
+
+	// A user without Gitea account has no account, instead of an error.
+	acct, err = p.FetchAccount(context.Background(), &types.User{ID: 2, Username: "nobody"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct != nil {
+		t.Errorf("have account %+v, want nil", acct)
+	}
+}
+
+func TestProvider_FetchUserPerms(t *testing.T) {
+	p := newTestProvider(t, "FetchUserPerms")
+
+	data := json.RawMessage(`{"id": 2, "login": "alice"}`)
+	acct := &extsvc.Account{
+		AccountSpec: extsvc.AccountSpec{
+			ServiceType: extsvc.TypeGitea,
+			ServiceID:   "http://127.0.0.1:3000/",
+			AccountID:   "2",
+		},
+		AccountData: extsvc.AccountData{Data: &data},
+	}
+
+	// 🚨 SECURITY: The recorded requests list the repositories as alice.
+	repoIDs, err := p.FetchUserPerms(context.Background(), acct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The public repository 2 is not included.
+	if diff := cmp.Diff([]extsvc.RepoID{"1", "3"}, repoIDs); diff != "" {
+		t.Error(diff)
+	}
+
+	acct.ServiceID = "https://gitea.example.com/"
+	if _, err := p.FetchUserPerms(context.Background(), acct); err == nil {
+		t.Error("expected an error for an account of another code host")
+	}
+}
+
+func TestProvider_FetchRepoPerms(t *testing.T) {
+	p := newTestProvider(t, "FetchRepoPerms")
+
+	tests := []struct {
+		uri      string
+		want     []extsvc.AccountID
+		notFound bool
+	}{
+		// The collaborators and the members of the teams with access.
+		{uri: "127.0.0.1/sourcegraph/sourcegraph", want: []extsvc.AccountID{"3", "4", "2", "5", "6"}},
+		// The collaborators and the owner.
+		{uri: "127.0.0.1/alice/notes", want: []extsvc.AccountID{"3", "2"}},
+		{uri: "127.0.0.1/sourcegraph/missing", notFound: true},
+	}
+	for _, test := range tests {
+		t.Run(test.uri, func(t *testing.T) {
+			userIDs, err := p.FetchRepoPerms(context.Background(), &extsvc.Repository{
+				URI: test.uri,
+				ExternalRepoSpec: api.ExternalRepoSpec{
+					ID:          "1",
+					ServiceType: extsvc.TypeGitea,
+					ServiceID:   "http://127.0.0.1:3000/",
+				},
+			})
+			if test.notFound {
+				if !gitea.IsNotFound(err) {
+					t.Fatalf("have error %v, want not found", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, userIDs); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -5,10 +5,16 @@ package authz
 import (
 	"context"
 
+	"github.com/pkg/errors"
+
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 )
 
+// ErrUnimplemented is returned by Provider.FetchRepoPerms if the provider only
+// supports syncing permissions per user.
+var ErrUnimplemented = errors.New("unimplemented")
+
 // Provider defines a source of truth of which repositories a user is authorized to view. The
 // user is identified by an extsvc.Account instance. Examples of authz providers include the
 // following:
@@ -51,6 +57,9 @@ type Provider interface {
 	// Because permissions fetching APIs are often expensive, the implementation should
 	// try to return partial but valid results in case of error, and it is up to callers
 	// to decide whether to discard.
+	//
+	// Implementations which can't fetch the permissions of a repository efficiently
+	// should return ErrUnimplemented, so permissions are only synced per user.
 	FetchRepoPerms(ctx context.Context, repo *extsvc.Repository) ([]extsvc.AccountID, error)
 
 	// ServiceType returns the service type (e.g., "gitlab") of this authz provider.
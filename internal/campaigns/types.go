@@ -17,6 +17,8 @@ import (
 	"github.com/sourcegraph/go-diff/diff"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/azuredevops"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
@@ -36,6 +38,8 @@ var SupportedExternalServices = map[string]struct{}{
 	extsvc.TypeGitHub:          {},
 	extsvc.TypeBitbucketServer: {},
 	extsvc.TypeGitLab:          {},
+	extsvc.TypeAzureDevOps:     {},
+	extsvc.TypeBitbucketCloud:  {},
 }
 
 // IsRepoSupported returns whether the given ExternalRepoSpec is supported by
@@ -368,6 +372,18 @@ func (c *Changeset) SetMetadata(meta interface{}) error {
 		c.ExternalServiceType = extsvc.TypeGitLab
 		c.ExternalBranch = pr.SourceBranch
 		c.ExternalUpdatedAt = pr.UpdatedAt.Time
+	case *azuredevops.PullRequest:
+		c.Metadata = pr
+		c.ExternalID = strconv.FormatInt(pr.PullRequestID, 10)
+		c.ExternalServiceType = extsvc.TypeAzureDevOps
+		c.ExternalBranch = git.AbbreviateRef(pr.SourceRefName)
+		c.ExternalUpdatedAt = pr.UpdatedAt()
+	case *bitbucketcloud.PullRequest:
+		c.Metadata = pr
+		c.ExternalID = strconv.FormatInt(pr.ID, 10)
+		c.ExternalServiceType = extsvc.TypeBitbucketCloud
+		c.ExternalBranch = pr.Source.Branch.Name
+		c.ExternalUpdatedAt = pr.UpdatedOn
 	default:
 		return errors.New("unknown changeset type")
 	}
@@ -393,6 +409,10 @@ func (c *Changeset) Title() (string, error) {
 		return m.Title, nil
 	case *gitlab.MergeRequest:
 		return m.Title, nil
+	case *azuredevops.PullRequest:
+		return m.Title, nil
+	case *bitbucketcloud.PullRequest:
+		return m.Title, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -409,6 +429,10 @@ func (c *Changeset) ExternalCreatedAt() time.Time {
 		return unixMilliToTime(int64(m.CreatedDate))
 	case *gitlab.MergeRequest:
 		return m.CreatedAt.Time
+	case *azuredevops.PullRequest:
+		return m.CreationDate
+	case *bitbucketcloud.PullRequest:
+		return m.CreatedOn
 	default:
 		return time.Time{}
 	}
@@ -423,6 +447,10 @@ func (c *Changeset) Body() (string, error) {
 		return m.Description, nil
 	case *gitlab.MergeRequest:
 		return m.Description, nil
+	case *azuredevops.PullRequest:
+		return m.Description, nil
+	case *bitbucketcloud.PullRequest:
+		return m.Description, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -467,6 +495,28 @@ func (c *Changeset) externalState() (s ChangesetExternalState, err error) {
 		default:
 			return "", errors.Errorf("unknown merge request state: %s", m.State)
 		}
+	case *azuredevops.PullRequest:
+		switch m.Status {
+		case azuredevops.PullRequestStatusActive:
+			s = ChangesetExternalStateOpen
+		case azuredevops.PullRequestStatusAbandoned:
+			s = ChangesetExternalStateClosed
+		case azuredevops.PullRequestStatusCompleted:
+			s = ChangesetExternalStateMerged
+		default:
+			return "", errors.Errorf("unknown pull request status: %s", m.Status)
+		}
+	case *bitbucketcloud.PullRequest:
+		switch m.State {
+		case bitbucketcloud.PullRequestStateOpen:
+			s = ChangesetExternalStateOpen
+		case bitbucketcloud.PullRequestStateDeclined, bitbucketcloud.PullRequestStateSuperseded:
+			s = ChangesetExternalStateClosed
+		case bitbucketcloud.PullRequestStateMerged:
+			s = ChangesetExternalStateMerged
+		default:
+			return "", errors.Errorf("unknown pull request state: %s", m.State)
+		}
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -491,6 +541,10 @@ func (c *Changeset) URL() (s string, err error) {
 		return selfLink.Href, nil
 	case *gitlab.MergeRequest:
 		return m.WebURL, nil
+	case *azuredevops.PullRequest:
+		return m.WebURL, nil
+	case *bitbucketcloud.PullRequest:
+		return m.Links.HTML.Href, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -655,6 +709,23 @@ func (c *Changeset) Events() (events []*ChangesetEvent) {
 				Metadata:    pipeline,
 			})
 		}
+
+	case *bitbucketcloud.PullRequest:
+		events = make([]*ChangesetEvent, 0, len(m.Comments)+len(m.Statuses))
+		addEvent := func(e Keyer) {
+			events = append(events, &ChangesetEvent{
+				ChangesetID: c.ID,
+				Key:         e.Key(),
+				Kind:        ChangesetEventKindFor(e),
+				Metadata:    e,
+			})
+		}
+		for _, comment := range m.Comments {
+			addEvent(comment)
+		}
+		for _, s := range m.Statuses {
+			addEvent(s)
+		}
 	}
 	return events
 }
@@ -670,6 +741,16 @@ func (c *Changeset) HeadRefOid() (string, error) {
 		return "", nil
 	case *gitlab.MergeRequest:
 		return m.DiffRefs.HeadSHA, nil
+	case *azuredevops.PullRequest:
+		if m.LastMergeSourceCommit == nil {
+			return "", nil
+		}
+		return m.LastMergeSourceCommit.CommitID, nil
+	case *bitbucketcloud.PullRequest:
+		if m.Source.Commit == nil {
+			return "", nil
+		}
+		return m.Source.Commit.Hash, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -685,6 +766,10 @@ func (c *Changeset) HeadRef() (string, error) {
 		return m.FromRef.ID, nil
 	case *gitlab.MergeRequest:
 		return "refs/heads/" + m.SourceBranch, nil
+	case *azuredevops.PullRequest:
+		return m.SourceRefName, nil
+	case *bitbucketcloud.PullRequest:
+		return "refs/heads/" + m.Source.Branch.Name, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -701,6 +786,16 @@ func (c *Changeset) BaseRefOid() (string, error) {
 		return "", nil
 	case *gitlab.MergeRequest:
 		return m.DiffRefs.BaseSHA, nil
+	case *azuredevops.PullRequest:
+		if m.LastMergeTargetCommit == nil {
+			return "", nil
+		}
+		return m.LastMergeTargetCommit.CommitID, nil
+	case *bitbucketcloud.PullRequest:
+		if m.Destination.Commit == nil {
+			return "", nil
+		}
+		return m.Destination.Commit.Hash, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -716,6 +811,10 @@ func (c *Changeset) BaseRef() (string, error) {
 		return m.ToRef.ID, nil
 	case *gitlab.MergeRequest:
 		return "refs/heads/" + m.TargetBranch, nil
+	case *azuredevops.PullRequest:
+		return m.TargetRefName, nil
+	case *bitbucketcloud.PullRequest:
+		return "refs/heads/" + m.Destination.Branch.Name, nil
 	default:
 		return "", errors.New("unknown changeset type")
 	}
@@ -973,6 +1072,10 @@ func (e *ChangesetEvent) Timestamp() time.Time {
 		t = unixMilliToTime(int64(ev.CreatedDate))
 	case *bitbucketserver.CommitStatus:
 		t = unixMilliToTime(int64(ev.Status.DateAdded))
+	case *bitbucketcloud.Comment:
+		return ev.UpdatedOn
+	case *bitbucketcloud.CommitStatus:
+		return ev.Status.UpdatedOn
 	case *gitlab.ReviewApproved:
 		return ev.CreatedAt.Time
 	case *gitlab.ReviewUnapproved:
@@ -1369,6 +1472,16 @@ func (e *ChangesetEvent) Update(o *ChangesetEvent) error {
 		// We always get the full event, so safe to replace it
 		*e = *o
 
+	case *bitbucketcloud.Comment:
+		o := o.Metadata.(*bitbucketcloud.Comment)
+		// We always get the full event, so safe to replace it
+		*e = *o
+
+	case *bitbucketcloud.CommitStatus:
+		o := o.Metadata.(*bitbucketcloud.CommitStatus)
+		// We always get the full event, so safe to replace it
+		*e = *o
+
 	default:
 		return errors.Errorf("unknown changeset event metadata %T", e)
 	}
@@ -1526,6 +1639,10 @@ func ChangesetEventKindFor(e interface{}) ChangesetEventKind {
 		return ChangesetEventKindGitLabMerged
 	case *gitlabwebhooks.MergeRequestReopenEvent:
 		return ChangesetEventKindGitLabReopened
+	case *bitbucketcloud.Comment:
+		return ChangesetEventKindBitbucketCloudCommented
+	case *bitbucketcloud.CommitStatus:
+		return ChangesetEventKindBitbucketCloudCommitStatus
 	default:
 		panic(errors.Errorf("unknown changeset event kind for %T", e))
 	}
@@ -1535,6 +1652,13 @@ func ChangesetEventKindFor(e interface{}) ChangesetEventKind {
 // ChangesetEventKind.
 func NewChangesetEventMetadata(k ChangesetEventKind) (interface{}, error) {
 	switch {
+	case strings.HasPrefix(string(k), "bitbucketcloud"):
+		switch k {
+		case ChangesetEventKindBitbucketCloudCommented:
+			return new(bitbucketcloud.Comment), nil
+		case ChangesetEventKindBitbucketCloudCommitStatus:
+			return new(bitbucketcloud.CommitStatus), nil
+		}
 	case strings.HasPrefix(string(k), "bitbucketserver"):
 		switch k {
 		case ChangesetEventKindBitbucketServerCommitStatus:
@@ -1650,6 +1774,9 @@ const (
 	ChangesetEventKindGitLabPipeline   ChangesetEventKind = "gitlab:pipeline"
 	ChangesetEventKindGitLabReopened   ChangesetEventKind = "gitlab:reopened"
 	ChangesetEventKindGitLabUnapproved ChangesetEventKind = "gitlab:unapproved"
+
+	ChangesetEventKindBitbucketCloudCommented    ChangesetEventKind = "bitbucketcloud:commented"
+	ChangesetEventKindBitbucketCloudCommitStatus ChangesetEventKind = "bitbucketcloud:commit_status"
 )
 
 // ChangesetSyncData represents data about the sync status of a changeset
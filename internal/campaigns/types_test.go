@@ -9,6 +9,8 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/sourcegraph/go-diff/diff"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/azuredevops"
+	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitlab"
@@ -338,6 +340,50 @@ func TestChangesetEvents(t *testing.T) {
 		})
 	}
 
+	{ // Bitbucket Cloud
+		comments := []*bitbucketcloud.Comment{
+			{ID: 1, Content: bitbucketcloud.CommentContent{Raw: "foo"}},
+			{ID: 2, Content: bitbucketcloud.CommentContent{Raw: "bar"}},
+		}
+
+		statuses := []*bitbucketcloud.CommitStatus{
+			{Commit: "deadbeef", Status: bitbucketcloud.BuildStatus{Key: "ci"}},
+		}
+
+		pr := &bitbucketcloud.PullRequest{
+			Comments: comments,
+			Statuses: statuses,
+		}
+
+		cases = append(cases, testCase{
+			name: "bitbucketcloud",
+			changeset: Changeset{
+				ID:       1234,
+				Metadata: pr,
+			},
+			events: []*ChangesetEvent{
+				{
+					ChangesetID: 1234,
+					Kind:        ChangesetEventKindBitbucketCloudCommented,
+					Key:         "1",
+					Metadata:    comments[0],
+				},
+				{
+					ChangesetID: 1234,
+					Kind:        ChangesetEventKindBitbucketCloudCommented,
+					Key:         "2",
+					Metadata:    comments[1],
+				},
+				{
+					ChangesetID: 1234,
+					Kind:        ChangesetEventKindBitbucketCloudCommitStatus,
+					Key:         "deadbeef:ci",
+					Metadata:    statuses[0],
+				},
+			},
+		})
+	}
+
 	for _, tc := range cases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
@@ -509,6 +555,33 @@ func TestChangeset_SetMetadata(t *testing.T) {
 				ExternalUpdatedAt:   time.Unix(10, 0),
 			},
 		},
+		"Azure DevOps": {
+			meta: &azuredevops.PullRequest{
+				PullRequestID: 12345,
+				SourceRefName: "refs/heads/branch",
+				CreationDate:  time.Unix(5, 0),
+				ClosedDate:    time.Unix(10, 0),
+			},
+			want: &Changeset{
+				ExternalID:          "12345",
+				ExternalServiceType: extsvc.TypeAzureDevOps,
+				ExternalBranch:      "branch",
+				ExternalUpdatedAt:   time.Unix(10, 0),
+			},
+		},
+		"Bitbucket Cloud": {
+			meta: &bitbucketcloud.PullRequest{
+				ID:        12345,
+				Source:    bitbucketcloud.PullRequestEndpoint{Branch: bitbucketcloud.Branch{Name: "branch"}},
+				UpdatedOn: time.Unix(10, 0),
+			},
+			want: &Changeset{
+				ExternalID:          "12345",
+				ExternalServiceType: extsvc.TypeBitbucketCloud,
+				ExternalBranch:      "branch",
+				ExternalUpdatedAt:   time.Unix(10, 0),
+			},
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			have := &Changeset{}
@@ -537,6 +610,12 @@ func TestChangeset_Title(t *testing.T) {
 		"GitLab": &gitlab.MergeRequest{
 			Title: want,
 		},
+		"Azure DevOps": &azuredevops.PullRequest{
+			Title: want,
+		},
+		"Bitbucket Cloud": &bitbucketcloud.PullRequest{
+			Title: want,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			c := &Changeset{Metadata: meta}
@@ -570,6 +649,12 @@ func TestChangeset_ExternalCreatedAt(t *testing.T) {
 		"GitLab": &gitlab.MergeRequest{
 			CreatedAt: gitlab.Time{Time: want},
 		},
+		"Azure DevOps": &azuredevops.PullRequest{
+			CreationDate: want,
+		},
+		"Bitbucket Cloud": &bitbucketcloud.PullRequest{
+			CreatedOn: want,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			c := &Changeset{Metadata: meta}
@@ -600,6 +685,12 @@ func TestChangeset_Body(t *testing.T) {
 		"GitLab": &gitlab.MergeRequest{
 			Description: want,
 		},
+		"Azure DevOps": &azuredevops.PullRequest{
+			Description: want,
+		},
+		"Bitbucket Cloud": &bitbucketcloud.PullRequest{
+			Description: want,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			c := &Changeset{Metadata: meta}
@@ -668,6 +759,48 @@ func TestChangeset_externalState(t *testing.T) {
 			},
 			want: ChangesetExternalStateMerged,
 		},
+		"Azure DevOps: active": {
+			meta: &azuredevops.PullRequest{
+				Status: azuredevops.PullRequestStatusActive,
+			},
+			want: ChangesetExternalStateOpen,
+		},
+		"Azure DevOps: abandoned": {
+			meta: &azuredevops.PullRequest{
+				Status: azuredevops.PullRequestStatusAbandoned,
+			},
+			want: ChangesetExternalStateClosed,
+		},
+		"Azure DevOps: completed": {
+			meta: &azuredevops.PullRequest{
+				Status: azuredevops.PullRequestStatusCompleted,
+			},
+			want: ChangesetExternalStateMerged,
+		},
+		"Bitbucket Cloud: open": {
+			meta: &bitbucketcloud.PullRequest{
+				State: bitbucketcloud.PullRequestStateOpen,
+			},
+			want: ChangesetExternalStateOpen,
+		},
+		"Bitbucket Cloud: declined": {
+			meta: &bitbucketcloud.PullRequest{
+				State: bitbucketcloud.PullRequestStateDeclined,
+			},
+			want: ChangesetExternalStateClosed,
+		},
+		"Bitbucket Cloud: superseded": {
+			meta: &bitbucketcloud.PullRequest{
+				State: bitbucketcloud.PullRequestStateSuperseded,
+			},
+			want: ChangesetExternalStateClosed,
+		},
+		"Bitbucket Cloud: merged": {
+			meta: &bitbucketcloud.PullRequest{
+				State: bitbucketcloud.PullRequestStateMerged,
+			},
+			want: ChangesetExternalStateMerged,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			c := &Changeset{Metadata: tc.meta}
@@ -729,6 +862,12 @@ func TestChangeset_URL(t *testing.T) {
 		"GitLab": &gitlab.MergeRequest{
 			WebURL: want,
 		},
+		"Azure DevOps": &azuredevops.PullRequest{
+			WebURL: want,
+		},
+		"Bitbucket Cloud": &bitbucketcloud.PullRequest{
+			Links: bitbucketcloud.Links{HTML: bitbucketcloud.Link{Href: want}},
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			c := &Changeset{Metadata: meta}
@@ -769,6 +908,18 @@ func TestChangeset_HeadRefOid(t *testing.T) {
 			},
 			want: "foo",
 		},
+		"Azure DevOps": {
+			meta: &azuredevops.PullRequest{
+				LastMergeSourceCommit: &azuredevops.CommitRef{CommitID: "foo"},
+			},
+			want: "foo",
+		},
+		"Bitbucket Cloud": {
+			meta: &bitbucketcloud.PullRequest{
+				Source: bitbucketcloud.PullRequestEndpoint{Commit: &bitbucketcloud.Commit{Hash: "foo"}},
+			},
+			want: "foo",
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			c := &Changeset{Metadata: tc.meta}
@@ -811,6 +962,18 @@ func TestChangeset_HeadRef(t *testing.T) {
 			},
 			want: "refs/heads/foo",
 		},
+		"Azure DevOps": {
+			meta: &azuredevops.PullRequest{
+				SourceRefName: "refs/heads/foo",
+			},
+			want: "refs/heads/foo",
+		},
+		"Bitbucket Cloud": {
+			meta: &bitbucketcloud.PullRequest{
+				Source: bitbucketcloud.PullRequestEndpoint{Branch: bitbucketcloud.Branch{Name: "foo"}},
+			},
+			want: "refs/heads/foo",
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			c := &Changeset{Metadata: tc.meta}
@@ -851,6 +1014,18 @@ func TestChangeset_BaseRefOid(t *testing.T) {
 			},
 			want: "foo",
 		},
+		"Azure DevOps": {
+			meta: &azuredevops.PullRequest{
+				LastMergeTargetCommit: &azuredevops.CommitRef{CommitID: "foo"},
+			},
+			want: "foo",
+		},
+		"Bitbucket Cloud": {
+			meta: &bitbucketcloud.PullRequest{
+				Destination: bitbucketcloud.PullRequestEndpoint{Commit: &bitbucketcloud.Commit{Hash: "foo"}},
+			},
+			want: "foo",
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			c := &Changeset{Metadata: tc.meta}
@@ -893,6 +1068,18 @@ func TestChangeset_BaseRef(t *testing.T) {
 			},
 			want: "refs/heads/foo",
 		},
+		"Azure DevOps": {
+			meta: &azuredevops.PullRequest{
+				TargetRefName: "refs/heads/foo",
+			},
+			want: "refs/heads/foo",
+		},
+		"Bitbucket Cloud": {
+			meta: &bitbucketcloud.PullRequest{
+				Destination: bitbucketcloud.PullRequestEndpoint{Branch: bitbucketcloud.Branch{Name: "foo"}},
+			},
+			want: "refs/heads/foo",
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			c := &Changeset{Metadata: tc.meta}
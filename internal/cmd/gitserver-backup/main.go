@@ -0,0 +1,121 @@
+// Command gitserver-backup backs up gitserver repositories as git bundles and
+// restores them.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
+)
+
+func main() {
+	addrs := flag.String("addrs", os.Getenv("SRC_GIT_SERVERS"), "Addresses of the gitservers (space-separated)")
+	all := flag.Bool("all", false, "Back up all repositories on all gitservers")
+	restore := flag.Bool("restore", false, "Restore the repository given as argument instead of backing it up")
+	url := flag.String("url", "", "Remote URL on the code host of the restored repository")
+	overwrite := flag.Bool("overwrite", false, "Replace the restored repository if it exists")
+	rendezvous := flag.Bool("rendezvous", false, "Repositories are assigned to gitservers with rendezvous hashing (the gitserverRendezvousHashing site configuration)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [-all | repo...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	gitservers := strings.Fields(*addrs)
+	if len(gitservers) == 0 {
+		log.Fatal("no gitserver addresses: set -addrs or SRC_GIT_SERVERS")
+	}
+	if *all == (flag.NArg() > 0) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *restore {
+		if *all || flag.NArg() != 1 || *url == "" {
+			log.Fatal("-restore takes one repository and -url")
+		}
+		repo := api.RepoName(flag.Arg(0))
+		req := protocol.RestoreRequest{Repo: repo, URL: *url, Overwrite: *overwrite}
+		if err := post(gitserver.AddrsForRepo(gitservers, repo, *rendezvous)[0], "restore", req, nil); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("restored %s", repo)
+		return
+	}
+
+	// Each gitserver backs up the repositories it has.
+	reqs := map[string]*protocol.BackupRequest{}
+	for _, addr := range gitservers {
+		reqs[addr] = &protocol.BackupRequest{All: *all}
+	}
+	for _, repo := range flag.Args() {
+		addr := gitserver.AddrsForRepo(gitservers, api.RepoName(repo), *rendezvous)[0]
+		reqs[addr].Repos = append(reqs[addr].Repos, api.RepoName(repo))
+	}
+
+	var failed bool
+	for _, addr := range gitservers {
+		if req := reqs[addr]; !req.All && len(req.Repos) == 0 {
+			continue
+		}
+		if req := reqs[addr]; req.All {
+			// gitserver backs up all its repositories in the background.
+			if err := post(addr, "backup", req, nil); err != nil {
+				log.Printf("%s: %s", addr, err)
+				failed = true
+				continue
+			}
+			log.Printf("%s: backing up all repositories in the background, see its logs for the results", addr)
+			continue
+		}
+		var resp protocol.BackupResponse
+		if err := post(addr, "backup", reqs[addr], &resp); err != nil {
+			log.Printf("%s: %s", addr, err)
+			failed = true
+			continue
+		}
+		for _, repo := range resp.Backups {
+			log.Printf("backed up %s", repo)
+		}
+		for repo, reason := range resp.Skipped {
+			log.Printf("skipped %s: %s", repo, reason)
+		}
+		for repo, err := range resp.Errors {
+			log.Printf("failed to back up %s: %s", repo, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func post(addr, method string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := http.Post("http://"+addr+"/"+method, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK && r.StatusCode != http.StatusAccepted {
+		b, _ := ioutil.ReadAll(r.Body)
+		return fmt.Errorf("%s failed with status %d: %s", method, r.StatusCode, bytes.TrimSpace(b))
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.NewDecoder(r.Body).Decode(resp)
+}
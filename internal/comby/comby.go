@@ -2,7 +2,6 @@ package comby
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -20,7 +19,8 @@ import (
 
 const combyPath = "comby"
 
-func exists() bool {
+// Exists returns true if the comby binary can be found on the PATH.
+func Exists() bool {
 	_, err := exec.LookPath(combyPath)
 	return err == nil
 }
@@ -104,7 +104,7 @@ func kill(pid int) {
 }
 
 func PipeTo(ctx context.Context, args Args, w io.Writer) (err error) {
-	if !exists() {
+	if !Exists() {
 		log15.Error("comby is not installed (it could not be found on the PATH)")
 		return errors.New("comby is not installed")
 	}
@@ -157,37 +157,79 @@ func PipeTo(ctx context.Context, args Args, w io.Writer) (err error) {
 
 // Matches returns all matches in all files for which comby finds matches.
 func Matches(ctx context.Context, args Args) (matches []FileMatch, err error) {
-	b := new(bytes.Buffer)
-	w := bufio.NewWriter(b)
-
-	args.MatchOnly = true
-
-	err = PipeTo(ctx, args, w)
+	err = StreamMatches(ctx, args, func(m FileMatch) {
+		matches = append(matches, m)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	scanner := bufio.NewScanner(b)
-	// increase the scanner buffer size for potentially long lines
-	scanner.Buffer(make([]byte, 100), 10*bufio.MaxScanTokenSize)
-	for scanner.Scan() {
-		b := scanner.Bytes()
-		if err := scanner.Err(); err != nil {
-			// warn on scanner errors and skip
-			log15.Warn("comby error: skipping scanner error line", "err", err.Error())
-			continue
-		}
+	if len(matches) > 0 {
+		log15.Info("comby invocation", "num_matches", strconv.Itoa(len(matches)))
+	}
+	return matches, nil
+}
+
+// StreamMatches calls onMatch for every file in which comby finds matches, as
+// soon as comby reports it. onMatch is called sequentially.
+func StreamMatches(ctx context.Context, args Args, onMatch func(FileMatch)) error {
+	args.MatchOnly = true
+	return streamLines(ctx, args, func(b []byte) {
 		var m *FileMatch
 		if err := json.Unmarshal(b, &m); err != nil {
 			// warn on decode errors and skip
 			log15.Warn("comby error: skipping unmarshaling error", "err", err.Error())
-			continue
+			return
+		}
+		onMatch(*m)
+	})
+}
+
+// Diffs returns a diff for every file in which comby rewrites matches of
+// args.MatchTemplate with args.RewriteTemplate. Files are not modified.
+func Diffs(ctx context.Context, args Args) (diffs []FileDiff, err error) {
+	args.MatchOnly = false
+	err = streamLines(ctx, args, func(b []byte) {
+		var d *FileDiff
+		if err := json.Unmarshal(b, &d); err != nil {
+			// warn on decode errors and skip
+			log15.Warn("comby error: skipping unmarshaling error", "err", err.Error())
+			return
 		}
-		matches = append(matches, *m)
+		diffs = append(diffs, *d)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return diffs, nil
+}
 
-	if len(matches) > 0 {
-		log15.Info("comby invocation", "num_matches", strconv.Itoa(len(matches)))
+// streamLines runs comby with args and calls onLine with every line of its
+// JSON lines output as soon as it is written.
+func streamLines(ctx context.Context, args Args, onLine func([]byte)) error {
+	r, w := io.Pipe()
+	defer r.Close()
+
+	errC := make(chan error, 1)
+	go func() {
+		err := PipeTo(ctx, args, w)
+		// Unblock the scanner below once comby is done.
+		w.CloseWithError(err)
+		errC <- err
+	}()
+
+	scanner := bufio.NewScanner(r)
+	// increase the scanner buffer size for potentially long lines
+	scanner.Buffer(make([]byte, 100), 10*bufio.MaxScanTokenSize)
+	for scanner.Scan() {
+		onLine(scanner.Bytes())
 	}
-	return matches, nil
+	if err := scanner.Err(); err != nil {
+		// warn on scanner errors. Closing the reader stops comby from
+		// blocking on writing the rest of its output.
+		log15.Warn("comby error: stopping on scanner error", "err", err.Error())
+		r.CloseWithError(err)
+	}
+
+	return <-errC
 }
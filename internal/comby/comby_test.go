@@ -12,7 +12,7 @@ import (
 
 func TestMatchesUnmarshalling(t *testing.T) {
 	// If we are not on CI skip the test if comby is not installed.
-	if os.Getenv("CI") == "" && !exists() {
+	if os.Getenv("CI") == "" && !Exists() {
 		t.Skip("comby is not installed on the PATH. Try running 'bash <(curl -sL get.comby.dev)'.")
 	}
 
@@ -66,7 +66,7 @@ func main() {
 
 func TestMatchesInZip(t *testing.T) {
 	// If we are not on CI skip the test if comby is not installed.
-	if os.Getenv("CI") == "" && !exists() {
+	if os.Getenv("CI") == "" && !Exists() {
 		t.Skip("comby is not installed on the PATH. Try running 'bash <(curl -sL get.comby.dev)'.")
 	}
 
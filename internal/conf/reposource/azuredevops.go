@@ -0,0 +1,61 @@
+package reposource
+
+import (
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+type AzureDevOps struct {
+	*schema.AzureDevOpsConnection
+}
+
+var _ RepoSource = AzureDevOps{}
+
+func (c AzureDevOps) CloneURLToRepoName(cloneURL string) (repoName api.RepoName, err error) {
+	parsedCloneURL, baseURL, match, err := parseURLs(cloneURL, c.Url)
+	if err != nil {
+		return "", err
+	}
+	if baseURL == nil {
+		return "", nil
+	}
+
+	path := strings.Trim(parsedCloneURL.Path, "/")
+	if match {
+		// HTTP(S) clone URLs, and SSH clone URLs of Azure DevOps Server,
+		// are of the form {baseURL}/org/project/_git/repo.
+		path = strings.TrimPrefix(path, strings.Trim(baseURL.Path, "/"))
+	} else if hostname(parsedCloneURL) == "ssh."+hostname(baseURL) {
+		// Azure DevOps Services serves SSH from a separate host, with clone
+		// URLs of the form git@ssh.dev.azure.com:v3/org/project/repo.
+		path = strings.TrimPrefix(path, "v3/")
+	} else {
+		return "", nil
+	}
+
+	var parts []string
+	for _, p := range strings.Split(strings.TrimSuffix(path, ".git"), "/") {
+		if p != "" && p != "_git" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) != 3 {
+		return "", nil
+	}
+	return AzureDevOpsRepoName(c.RepositoryPathPattern, baseURL.Hostname(), parts[0], parts[1], parts[2]), nil
+}
+
+func AzureDevOpsRepoName(repositoryPathPattern, host, org, project, repo string) api.RepoName {
+	if repositoryPathPattern == "" {
+		repositoryPathPattern = "{host}/{org}/{project}/{repo}"
+	}
+
+	return api.RepoName(strings.NewReplacer(
+		"{host}", host,
+		"{org}", org,
+		"{project}", project,
+		"{repo}", repo,
+	).Replace(repositoryPathPattern))
+}
@@ -0,0 +1,49 @@
+package reposource
+
+import (
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestGitea_cloneURLToRepoName(t *testing.T) {
+	tests := []struct {
+		conn schema.GiteaConnection
+		urls []urlToRepoName
+	}{
+		{
+			conn: schema.GiteaConnection{
+				Url: "https://gitea.example.com",
+			},
+			urls: []urlToRepoName{
+				{"git@gitea.example.com:gorilla/mux.git", "gitea.example.com/gorilla/mux"},
+				{"https://gitea.example.com/gorilla/mux.git", "gitea.example.com/gorilla/mux"},
+				{"https://token@gitea.example.com/gorilla/mux.git", "gitea.example.com/gorilla/mux"},
+
+				{"git@asdf.com:gorilla/mux.git", ""},
+				{"https://asdf.com/gorilla/mux.git", ""},
+			},
+		},
+		{
+			conn: schema.GiteaConnection{
+				Url:                   "https://gitea.example.com",
+				RepositoryPathPattern: "gt/{nameWithOwner}",
+			},
+			urls: []urlToRepoName{
+				{"https://gitea.example.com/gorilla/mux.git", "gt/gorilla/mux"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		for _, u := range test.urls {
+			repoName, err := Gitea{&test.conn}.CloneURLToRepoName(u.cloneURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.repoName != string(repoName) {
+				t.Errorf("expected %q but got %q for clone URL %q (connection: %+v)", u.repoName, repoName, u.cloneURL, test.conn)
+			}
+		}
+	}
+}
@@ -33,17 +33,25 @@ type ExternalServicesStore struct {
 	GitHubValidators          []func(*schema.GitHubConnection) error
 	GitLabValidators          []func(*schema.GitLabConnection, []schema.AuthProviders) error
 	BitbucketServerValidators []func(*schema.BitbucketServerConnection) error
+	GiteaValidators           []func(*schema.GiteaConnection) error
+	AzureDevOpsValidators     []func(*schema.AzureDevOpsConnection) error
+	BitbucketCloudValidators  []func(*schema.BitbucketCloudConnection) error
+	AWSCodeCommitValidators   []func(*schema.AWSCodeCommitConnection) error
 }
 
 // ExternalServiceKinds contains a map of all supported kinds of
 // external services.
 var ExternalServiceKinds = map[string]ExternalServiceKind{
 	extsvc.KindAWSCodeCommit:   {CodeHost: true, JSONSchema: schema.AWSCodeCommitSchemaJSON},
+	extsvc.KindAzureDevOps:     {CodeHost: true, JSONSchema: schema.AzureDevOpsSchemaJSON},
 	extsvc.KindBitbucketCloud:  {CodeHost: true, JSONSchema: schema.BitbucketCloudSchemaJSON},
 	extsvc.KindBitbucketServer: {CodeHost: true, JSONSchema: schema.BitbucketServerSchemaJSON},
+	extsvc.KindGitea:           {CodeHost: true, JSONSchema: schema.GiteaSchemaJSON},
 	extsvc.KindGitHub:          {CodeHost: true, JSONSchema: schema.GitHubSchemaJSON},
 	extsvc.KindGitLab:          {CodeHost: true, JSONSchema: schema.GitLabSchemaJSON},
 	extsvc.KindGitolite:        {CodeHost: true, JSONSchema: schema.GitoliteSchemaJSON},
+	extsvc.KindMercurial:       {CodeHost: true, JSONSchema: schema.MercurialSchemaJSON},
+	extsvc.KindPerforce:        {CodeHost: true, JSONSchema: schema.PerforceSchemaJSON},
 	extsvc.KindPhabricator:     {CodeHost: true, JSONSchema: schema.PhabricatorSchemaJSON},
 	extsvc.KindOther:           {CodeHost: true, JSONSchema: schema.OtherExternalServiceSchemaJSON},
 }
@@ -195,6 +203,27 @@ func (e *ExternalServicesStore) ValidateConfig(ctx context.Context, opt Validate
 		}
 		err = e.validateBitbucketCloudConnection(ctx, opt.ID, &c)
 
+	case extsvc.KindGitea:
+		var c schema.GiteaConnection
+		if err = json.Unmarshal(normalized, &c); err != nil {
+			return err
+		}
+		err = e.validateGiteaConnection(&c)
+
+	case extsvc.KindAzureDevOps:
+		var c schema.AzureDevOpsConnection
+		if err = json.Unmarshal(normalized, &c); err != nil {
+			return err
+		}
+		err = e.validateAzureDevOpsConnection(&c)
+
+	case extsvc.KindAWSCodeCommit:
+		var c schema.AWSCodeCommitConnection
+		if err = json.Unmarshal(normalized, &c); err != nil {
+			return err
+		}
+		err = e.validateAWSCodeCommitConnection(&c)
+
 	case extsvc.KindOther:
 		var c schema.OtherExternalServiceConnection
 		if err = json.Unmarshal(normalized, &c); err != nil {
@@ -277,7 +306,43 @@ func (e *ExternalServicesStore) validateBitbucketServerConnection(ctx context.Co
 }
 
 func (e *ExternalServicesStore) validateBitbucketCloudConnection(ctx context.Context, id int64, c *schema.BitbucketCloudConnection) error {
-	return e.validateDuplicateRateLimits(ctx, id, extsvc.KindBitbucketCloud, c)
+	err := new(multierror.Error)
+	for _, validate := range e.BitbucketCloudValidators {
+		err = multierror.Append(err, validate(c))
+	}
+
+	err = multierror.Append(err, e.validateDuplicateRateLimits(ctx, id, extsvc.KindBitbucketCloud, c))
+
+	return err.ErrorOrNil()
+}
+
+func (e *ExternalServicesStore) validateGiteaConnection(c *schema.GiteaConnection) error {
+	err := new(multierror.Error)
+	for _, validate := range e.GiteaValidators {
+		err = multierror.Append(err, validate(c))
+	}
+
+	if c.Orgs == nil && c.Users == nil && c.RepositoryQuery == nil {
+		err = multierror.Append(err, errors.New("at least one of orgs, users or repositoryQuery must be set"))
+	}
+
+	return err.ErrorOrNil()
+}
+
+func (e *ExternalServicesStore) validateAzureDevOpsConnection(c *schema.AzureDevOpsConnection) error {
+	err := new(multierror.Error)
+	for _, validate := range e.AzureDevOpsValidators {
+		err = multierror.Append(err, validate(c))
+	}
+	return err.ErrorOrNil()
+}
+
+func (e *ExternalServicesStore) validateAWSCodeCommitConnection(c *schema.AWSCodeCommitConnection) error {
+	err := new(multierror.Error)
+	for _, validate := range e.AWSCodeCommitValidators {
+		err = multierror.Append(err, validate(c))
+	}
+	return err.ErrorOrNil()
 }
 
 // validateDuplicateRateLimits returns an error if given config has duplicated non-default rate limit
@@ -10,6 +10,7 @@ type MockStores struct {
 	Orgs          MockOrgs
 	OrgMembers    MockOrgMembers
 	SavedSearches MockSavedSearches
+	SearchJobs    MockSearchJobs
 	Settings      MockSettings
 	Users         MockUsers
 	UserEmails    MockUserEmails
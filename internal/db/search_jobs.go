@@ -0,0 +1,291 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/keegancsmith/sqlf"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbutil"
+)
+
+// searchJobResultsBatchSize is the number of results inserted by a single
+// statement, which keeps the number of query parameters below the limit of
+// Postgres.
+const searchJobResultsBatchSize = 5000
+
+type searchJobs struct{}
+
+type searchJobNotFoundError struct {
+	id int32
+}
+
+func (e searchJobNotFoundError) Error() string {
+	return fmt.Sprintf("search job not found: %d", e.id)
+}
+
+func (e searchJobNotFoundError) NotFound() bool {
+	return true
+}
+
+// Create creates a new queued search job. The ID field must be zero, or an
+// error will be returned.
+//
+// 🚨 SECURITY: This method does NOT verify the user's identity. It is the
+// callers responsibility to ensure job.UserID is the current user.
+func (s *searchJobs) Create(ctx context.Context, job *types.SearchJob) (*types.SearchJob, error) {
+	if Mocks.SearchJobs.Create != nil {
+		return Mocks.SearchJobs.Create(ctx, job)
+	}
+
+	if job.ID != 0 {
+		return nil, errors.New("job.ID must be zero")
+	}
+
+	var id int32
+	err := dbconn.Global.QueryRowContext(ctx,
+		"INSERT INTO search_jobs(user_id, query, pattern_type) VALUES($1, $2, $3) RETURNING id",
+		job.UserID, job.Query, job.PatternType,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+// GetByID returns the search job with the given ID, including its progress.
+//
+// 🚨 SECURITY: This method does NOT verify the user's identity. It is the
+// callers responsibility to ensure only the user who created the search job
+// or site admins can access it.
+func (s *searchJobs) GetByID(ctx context.Context, id int32) (*types.SearchJob, error) {
+	if Mocks.SearchJobs.GetByID != nil {
+		return Mocks.SearchJobs.GetByID(ctx, id)
+	}
+
+	jobs, err := s.list(ctx, sqlf.Sprintf("j.id=%d", id))
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, searchJobNotFoundError{id: id}
+	}
+	return jobs[0], nil
+}
+
+// ListByUserID returns the search jobs of a user, the most recently created
+// first.
+//
+// 🚨 SECURITY: This method does NOT verify the user's identity. It is the
+// callers responsibility to ensure only the specified user or site admins can
+// access the returned search jobs.
+func (s *searchJobs) ListByUserID(ctx context.Context, userID int32) ([]*types.SearchJob, error) {
+	if Mocks.SearchJobs.ListByUserID != nil {
+		return Mocks.SearchJobs.ListByUserID(ctx, userID)
+	}
+	return s.list(ctx, sqlf.Sprintf("j.user_id=%d", userID))
+}
+
+func (s *searchJobs) list(ctx context.Context, cond *sqlf.Query) ([]*types.SearchJob, error) {
+	q := sqlf.Sprintf(`
+SELECT
+	j.id,
+	j.user_id,
+	j.query,
+	j.pattern_type,
+	j.state,
+	j.failure_message,
+	j.created_at,
+	j.started_at,
+	j.finished_at,
+	COUNT(r.id),
+	COUNT(r.searched_at),
+	COALESCE(SUM(r.result_count), 0),
+	COALESCE(bool_or(r.limit_hit), false)
+FROM search_jobs j
+LEFT JOIN search_job_repos r ON r.search_job_id = j.id
+WHERE %s
+GROUP BY j.id
+ORDER BY j.id DESC
+`, cond)
+
+	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, errors.Wrap(err, "QueryContext")
+	}
+	defer rows.Close()
+
+	var jobs []*types.SearchJob
+	for rows.Next() {
+		var j types.SearchJob
+		if err := rows.Scan(
+			&j.ID,
+			&j.UserID,
+			&j.Query,
+			&j.PatternType,
+			&j.State,
+			&j.FailureMessage,
+			&j.CreatedAt,
+			&j.StartedAt,
+			&j.FinishedAt,
+			&j.ReposTotal,
+			&j.ReposSearched,
+			&j.ResultCount,
+			&j.LimitHit,
+		); err != nil {
+			return nil, errors.Wrap(err, "Scan")
+		}
+		jobs = append(jobs, &j)
+	}
+	return jobs, rows.Err()
+}
+
+// Delete deletes a search job and its results. Deleting a job which is being
+// processed waits until the worker is done with it.
+//
+// 🚨 SECURITY: This method does NOT verify the user's identity. It is the
+// callers responsibility to ensure only the user who created the search job
+// or site admins can delete it.
+func (s *searchJobs) Delete(ctx context.Context, id int32) error {
+	res, err := dbconn.Global.ExecContext(ctx, "DELETE FROM search_jobs WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	nrows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if nrows == 0 {
+		return searchJobNotFoundError{id: id}
+	}
+	return nil
+}
+
+// SetRepos records the repositories a search job searches, unless they have
+// already been recorded by an earlier attempt to run the job.
+func (s *searchJobs) SetRepos(ctx context.Context, jobID int32, repos []*types.SearchJobRepo) error {
+	return dbutil.Transaction(ctx, dbconn.Global, func(tx *sql.Tx) error {
+		// There are no concurrent calls for the same job, since only the
+		// worker holding the lock on the job calls SetRepos.
+		var exists bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM search_job_repos WHERE search_job_id=$1)", jobID,
+		).Scan(&exists)
+		if err != nil || exists {
+			return err
+		}
+
+		for len(repos) > 0 {
+			batch := repos
+			if len(batch) > searchJobResultsBatchSize {
+				batch = batch[:searchJobResultsBatchSize]
+			}
+			repos = repos[len(batch):]
+
+			values := make([]*sqlf.Query, 0, len(batch))
+			for _, r := range batch {
+				values = append(values, sqlf.Sprintf("(%d, %d, %s)", jobID, r.RepoID, r.Revs))
+			}
+			q := sqlf.Sprintf("INSERT INTO search_job_repos(search_job_id, repo_id, revs) VALUES %s", sqlf.Join(values, ", "))
+			if _, err := tx.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListUnsearchedRepos returns the repositories of a search job which have not
+// been searched yet, in the order they were recorded by SetRepos.
+func (s *searchJobs) ListUnsearchedRepos(ctx context.Context, jobID int32) ([]*types.SearchJobRepo, error) {
+	rows, err := dbconn.Global.QueryContext(ctx, `
+SELECT r.id, r.repo_id, repo.name, r.revs
+FROM search_job_repos r
+JOIN repo ON repo.id = r.repo_id
+WHERE r.search_job_id = $1 AND r.searched_at IS NULL AND repo.deleted_at IS NULL
+ORDER BY r.id
+`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var repos []*types.SearchJobRepo
+	for rows.Next() {
+		var r types.SearchJobRepo
+		if err := rows.Scan(&r.ID, &r.RepoID, &r.RepoName, &r.Revs); err != nil {
+			return nil, err
+		}
+		repos = append(repos, &r)
+	}
+	return repos, rows.Err()
+}
+
+// AddRepoResults stores the results of searching a repository of a search job
+// and marks the repository as searched.
+func (s *searchJobs) AddRepoResults(ctx context.Context, jobID int32, repo *types.SearchJobRepo, results []*types.SearchJobResult, limitHit bool) error {
+	return dbutil.Transaction(ctx, dbconn.Global, func(tx *sql.Tx) error {
+		q := sqlf.Sprintf(
+			"UPDATE search_job_repos SET searched_at=now(), result_count=%d, limit_hit=%t WHERE id=%d AND search_job_id=%d",
+			len(results), limitHit, repo.ID, jobID,
+		)
+		if _, err := tx.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...); err != nil {
+			return err
+		}
+
+		for len(results) > 0 {
+			batch := results
+			if len(batch) > searchJobResultsBatchSize {
+				batch = batch[:searchJobResultsBatchSize]
+			}
+			results = results[len(batch):]
+
+			values := make([]*sqlf.Query, 0, len(batch))
+			for _, r := range batch {
+				values = append(values, sqlf.Sprintf("(%d, %s, %s, %s, %d, %s)", jobID, r.Repo, r.Commit, r.Path, r.Line, r.Preview))
+			}
+			q := sqlf.Sprintf("INSERT INTO search_job_results(search_job_id, repo, commit, path, line, preview) VALUES %s", sqlf.Join(values, ", "))
+			if _, err := tx.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListResults returns up to limit results of a search job with an ID greater
+// than afterID, ordered by ID.
+//
+// 🚨 SECURITY: This method does NOT verify the user's identity. It is the
+// callers responsibility to ensure only the user who created the search job
+// or site admins can access its results.
+func (s *searchJobs) ListResults(ctx context.Context, jobID int32, afterID int64, limit int) ([]*types.SearchJobResult, error) {
+	if Mocks.SearchJobs.ListResults != nil {
+		return Mocks.SearchJobs.ListResults(ctx, jobID, afterID, limit)
+	}
+
+	rows, err := dbconn.Global.QueryContext(ctx, `
+SELECT id, repo, commit, path, line, preview
+FROM search_job_results
+WHERE search_job_id = $1 AND id > $2
+ORDER BY id
+LIMIT $3
+`, jobID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*types.SearchJobResult
+	for rows.Next() {
+		var r types.SearchJobResult
+		if err := rows.Scan(&r.ID, &r.Repo, &r.Commit, &r.Path, &r.Line, &r.Preview); err != nil {
+			return nil, err
+		}
+		results = append(results, &r)
+	}
+	return results, rows.Err()
+}
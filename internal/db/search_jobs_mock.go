@@ -0,0 +1,14 @@
+package db
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+type MockSearchJobs struct {
+	Create       func(ctx context.Context, job *types.SearchJob) (*types.SearchJob, error)
+	GetByID      func(ctx context.Context, id int32) (*types.SearchJob, error)
+	ListByUserID func(ctx context.Context, userID int32) ([]*types.SearchJob, error)
+	ListResults  func(ctx context.Context, jobID int32, afterID int64, limit int) ([]*types.SearchJobResult, error)
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+func TestSearchJobs(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+
+	user, err := Users.Create(ctx, NewUser{Username: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos := mustCreate(ctx, t, &types.Repo{Name: "a"}, &types.Repo{Name: "b"})
+
+	job, err := SearchJobs.Create(ctx, &types.SearchJob{UserID: user.ID, Query: "foo", PatternType: "literal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != "queued" || job.ReposTotal != 0 {
+		t.Fatalf("got new job %+v, want queued job without repos", job)
+	}
+
+	for i := 0; i < 2; i++ {
+		// The repositories are only recorded once.
+		err := SearchJobs.SetRepos(ctx, job.ID, []*types.SearchJobRepo{
+			{RepoID: repos[0].ID},
+			{RepoID: repos[1].ID, Revs: "dev"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	unsearched, err := SearchJobs.ListUnsearchedRepos(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsearched) != 2 || unsearched[0].RepoName != "a" || unsearched[1].RepoName != "b" || unsearched[1].Revs != "dev" {
+		t.Fatalf("unexpected unsearched repos %+v", unsearched)
+	}
+
+	results := []*types.SearchJobResult{
+		{Repo: "a", Commit: "c", Path: "x.go", Line: 1, Preview: "foo"},
+		{Repo: "a", Commit: "c", Path: "y.go", Line: 2, Preview: "foo()"},
+	}
+	if err := SearchJobs.AddRepoResults(ctx, job.ID, unsearched[0], results, true); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err = SearchJobs.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ReposTotal != 2 || job.ReposSearched != 1 || job.ResultCount != 2 || !job.LimitHit {
+		t.Fatalf("unexpected progress %+v", job)
+	}
+
+	unsearched, err = SearchJobs.ListUnsearchedRepos(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsearched) != 1 || unsearched[0].RepoName != "b" {
+		t.Fatalf("unexpected unsearched repos %+v", unsearched)
+	}
+
+	page, err := SearchJobs.ListResults(ctx, job.ID, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest, err := SearchJobs.ListResults(ctx, job.ID, page[0].ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := append(page, rest...)
+	for _, r := range got {
+		r.ID = 0
+	}
+	if diff := cmp.Diff(results, got); diff != "" {
+		t.Fatalf("unexpected results (-want +got):\n%s", diff)
+	}
+
+	jobs, err := SearchJobs.ListByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Fatalf("unexpected jobs %+v", jobs)
+	}
+
+	if err := SearchJobs.Delete(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SearchJobs.GetByID(ctx, job.ID); !errcode.IsNotFound(err) {
+		t.Fatalf("got error %v, want not found", err)
+	}
+	if results, err := SearchJobs.ListResults(ctx, job.ID, 0, 10); err != nil || len(results) != 0 {
+		t.Fatalf("got results %+v and error %v after deleting the job", results, err)
+	}
+}
@@ -10,6 +10,7 @@ var (
 	Orgs             = &orgs{}
 	OrgMembers       = &orgMembers{}
 	SavedSearches    = &savedSearches{}
+	SearchJobs       = &searchJobs{}
 	Settings         = &settings{}
 	Users            = &users{}
 	UserEmails       = &userEmails{}
@@ -0,0 +1,131 @@
+package awscodecommit
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws/awserr"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// IAMUser is an AWS IAM user.
+type IAMUser struct {
+	ARN  string // the ARN (Amazon Resource Name) of the user
+	ID   string // the stable and unique ID of the user
+	Name string // the friendly name of the user
+}
+
+// GitPullAction is the IAM action that grants read access to the Git
+// contents of an AWS CodeCommit repository.
+const GitPullAction = "codecommit:GitPull"
+
+// GetIAMUser calls the GetUser API method of AWS IAM. It returns nil if there
+// is no IAM user with the given name.
+func (c *Client) GetIAMUser(ctx context.Context, name string) (*IAMUser, error) {
+	svc := iam.New(c.aws)
+	req := svc.GetUserRequest(&iam.GetUserInput{UserName: &name})
+	req.SetContext(ctx)
+	result, err := req.Send(ctx)
+	if e, ok := err.(awserr.Error); ok && e.Code() == iam.ErrCodeNoSuchEntityException {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromIAMUser(result.User), nil
+}
+
+// CallerAccountID calls the GetCallerIdentity API method of AWS STS and
+// returns the ID of the AWS account the credentials of the client belong to.
+func (c *Client) CallerAccountID(ctx context.Context) (string, error) {
+	svc := sts.New(c.aws)
+	req := svc.GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	req.SetContext(ctx)
+	result, err := req.Send(ctx)
+	if err != nil {
+		return "", err
+	}
+	if result.Account == nil {
+		return "", nil
+	}
+	return *result.Account, nil
+}
+
+// ListIAMUsers calls the ListUsers API method of AWS IAM.
+func (c *Client) ListIAMUsers(ctx context.Context, marker string) (users []*IAMUser, nextMarker string, err error) {
+	svc := iam.New(c.aws)
+
+	var input iam.ListUsersInput
+	if marker != "" {
+		input.Marker = &marker
+	}
+	req := svc.ListUsersRequest(&input)
+	req.SetContext(ctx)
+	result, err := req.Send(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if result.IsTruncated != nil && *result.IsTruncated && result.Marker != nil {
+		nextMarker = *result.Marker
+	}
+
+	users = make([]*IAMUser, 0, len(result.Users))
+	for i := range result.Users {
+		users = append(users, fromIAMUser(&result.Users[i]))
+	}
+	return users, nextMarker, nil
+}
+
+// SimulateGitPull evaluates the IAM policies of the principal with the given
+// ARN and returns the ARNs of the given repositories the principal is allowed
+// to perform GitPullAction on. No context keys are passed to the simulation,
+// so conditions on them are evaluated as if the keys were absent.
+func (c *Client) SimulateGitPull(ctx context.Context, principalARN string, repoARNs []string) ([]string, error) {
+	if len(repoARNs) == 0 {
+		return nil, nil
+	}
+
+	svc := iam.New(c.aws)
+	input := iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: &principalARN,
+		ActionNames:     []string{GitPullAction},
+		ResourceArns:    repoARNs,
+	}
+
+	var allowed []string
+	for {
+		req := svc.SimulatePrincipalPolicyRequest(&input)
+		req.SetContext(ctx)
+		result, err := req.Send(ctx)
+		if err != nil {
+			return allowed, err
+		}
+
+		for _, r := range result.EvaluationResults {
+			if r.EvalDecision == iam.PolicyEvaluationDecisionTypeAllowed && r.EvalResourceName != nil {
+				allowed = append(allowed, *r.EvalResourceName)
+			}
+		}
+
+		if result.IsTruncated == nil || !*result.IsTruncated || result.Marker == nil {
+			break // last page
+		}
+		input.Marker = result.Marker
+	}
+
+	return allowed, nil
+}
+
+func fromIAMUser(u *iam.User) *IAMUser {
+	user := IAMUser{}
+	if u.Arn != nil {
+		user.ARN = *u.Arn
+	}
+	if u.UserId != nil {
+		user.ID = *u.UserId
+	}
+	if u.UserName != nil {
+		user.Name = *u.UserName
+	}
+	return &user
+}
@@ -0,0 +1,268 @@
+// Package azuredevops implements an Azure DevOps API client for Azure DevOps
+// Services and Azure DevOps Server.
+package azuredevops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
+)
+
+var requestCounter = metrics.NewRequestMeter("azuredevops_requests_count", "Total number of requests sent to the Azure DevOps API.")
+
+// APIVersion is the version of the REST API used by the client. It is the
+// latest version supported by both Azure DevOps Services and Azure DevOps
+// Server 2019.
+const APIVersion = "5.0"
+
+// DefaultPageSize is the number of items requested per page from paginated
+// endpoints unless the PageSize of a client is set.
+const DefaultPageSize = 100
+
+// servicesHost is the host of Azure DevOps Services. Its identity APIs are
+// served from a different host than the rest of the API.
+const servicesHost = "dev.azure.com"
+
+// Client access Azure DevOps via the REST API.
+type Client struct {
+	// HTTP Client used to communicate with the API
+	httpClient httpcli.Doer
+
+	// URL is the base URL of Azure DevOps, such as https://dev.azure.com/ or
+	// the URL of an Azure DevOps Server without the collection.
+	URL *url.URL
+
+	// Token is the personal access token used to authenticate requests.
+	Token string
+
+	// PageSize is the number of items requested per page from paginated
+	// endpoints.
+	PageSize int
+}
+
+// NewClient creates a new Azure DevOps API client with the given base URL.
+// If a nil httpClient is provided, http.DefaultClient will be used.
+func NewClient(baseURL *url.URL, token string, httpClient httpcli.Doer) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	httpClient = requestCounter.Doer(httpClient, func(u *url.URL) string {
+		// The component after _apis in the Path ({org}/_apis/{area}) maps
+		// to the area of the API request we are making.
+		var category string
+		if i := strings.Index(u.Path, "/_apis/"); i >= 0 {
+			category = strings.SplitN(u.Path[i+len("/_apis/"):], "/", 2)[0]
+		}
+		return category
+	})
+
+	return &Client{
+		httpClient: httpClient,
+		URL:        baseURL,
+		Token:      token,
+		PageSize:   DefaultPageSize,
+	}
+}
+
+// Project is an Azure DevOps project.
+type Project struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	State       string `json:"state,omitempty"`
+	Visibility  string `json:"visibility,omitempty"`
+}
+
+// Repository is an Azure DevOps Git repository.
+type Repository struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	URL           string  `json:"url"`
+	Project       Project `json:"project"`
+	DefaultBranch string  `json:"defaultBranch,omitempty"`
+	Size          int64   `json:"size,omitempty"`
+	RemoteURL     string  `json:"remoteUrl,omitempty"`
+	SSHURL        string  `json:"sshUrl,omitempty"`
+	WebURL        string  `json:"webUrl,omitempty"`
+	IsDisabled    bool    `json:"isDisabled,omitempty"`
+	IsFork        bool    `json:"isFork,omitempty"`
+
+	// Org is the name of the organization (or project collection) of the
+	// repository. It isn't part of API responses and is set by the Client.
+	Org string `json:"org"`
+}
+
+// NameWithOwner returns the "org/project/repo" name of the repository.
+func (r *Repository) NameWithOwner() string {
+	return r.Org + "/" + r.Project.Name + "/" + r.Name
+}
+
+// ListProjects returns a page of the projects of the organization.
+//
+// API docs: https://docs.microsoft.com/en-us/rest/api/azure/devops/core/projects/list
+func (c *Client) ListProjects(ctx context.Context, org string, page int) (projects []*Project, hasNextPage bool, err error) {
+	var result struct {
+		Value []*Project `json:"value"`
+	}
+	if err := c.get(ctx, c.URL, org+"/_apis/projects", c.paginate(nil, page), &result); err != nil {
+		return nil, false, err
+	}
+	return result.Value, len(result.Value) == c.PageSize, nil
+}
+
+// ListRepos returns all repositories of the project of the organization. If
+// project is empty, the repositories of all projects are returned.
+//
+// API docs: https://docs.microsoft.com/en-us/rest/api/azure/devops/git/repositories/list
+func (c *Client) ListRepos(ctx context.Context, org, project string) ([]*Repository, error) {
+	path := org + "/_apis/git/repositories"
+	if project != "" {
+		path = org + "/" + project + "/_apis/git/repositories"
+	}
+
+	var result struct {
+		Value []*Repository `json:"value"`
+	}
+	if err := c.get(ctx, c.URL, path, nil, &result); err != nil {
+		return nil, err
+	}
+	for _, r := range result.Value {
+		r.Org = org
+	}
+	return result.Value, nil
+}
+
+// GetRepo returns the repository with the given name or ID in the project of
+// the organization.
+//
+// API docs: https://docs.microsoft.com/en-us/rest/api/azure/devops/git/repositories/get%20repository
+func (c *Client) GetRepo(ctx context.Context, org, project, name string) (*Repository, error) {
+	var repo Repository
+	if err := c.get(ctx, c.URL, org+"/"+project+"/_apis/git/repositories/"+name, nil, &repo); err != nil {
+		return nil, err
+	}
+	repo.Org = org
+	return &repo, nil
+}
+
+func (c *Client) paginate(qry url.Values, page int) url.Values {
+	if qry == nil {
+		qry = url.Values{}
+	}
+	qry.Set("$top", strconv.Itoa(c.PageSize))
+	qry.Set("$skip", strconv.Itoa((page-1)*c.PageSize))
+	return qry
+}
+
+func (c *Client) get(ctx context.Context, baseURL *url.URL, path string, qry url.Values, result interface{}) error {
+	return c.send(ctx, "GET", baseURL, path, qry, nil, result)
+}
+
+func (c *Client) send(ctx context.Context, method string, baseURL *url.URL, path string, qry url.Values, payload, result interface{}) error {
+	if qry == nil {
+		qry = url.Values{}
+	}
+	qry.Set("api-version", APIVersion)
+
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	u := baseURL.ResolveReference(&url.URL{Path: path, RawQuery: qry.Encode()})
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.do(ctx, req, result)
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.SetBasicAuth("", c.Token)
+	}
+
+	req, ht := nethttp.TraceRequest(ot.GetTracer(ctx),
+		req.WithContext(ctx),
+		nethttp.OperationName("Azure DevOps"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return errors.WithStack(&httpError{
+			URL:        req.URL,
+			StatusCode: resp.StatusCode,
+			Body:       bs,
+		})
+	}
+
+	if result != nil {
+		return json.Unmarshal(bs, result)
+	}
+	return nil
+}
+
+type httpError struct {
+	StatusCode int
+	URL        *url.URL
+	Body       []byte
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("Azure DevOps API HTTP error: code=%d url=%q body=%q", e.StatusCode, e.URL, e.Body)
+}
+
+func (e *httpError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *httpError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsNotFound reports whether err is an Azure DevOps API not found error.
+func IsNotFound(err error) bool {
+	switch e := errors.Cause(err).(type) {
+	case *httpError:
+		return e.NotFound()
+	}
+	return false
+}
+
+func statusCode(err error) int {
+	if e, ok := errors.Cause(err).(*httpError); ok {
+		return e.StatusCode
+	}
+	return 0
+}
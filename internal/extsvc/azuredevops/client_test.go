@@ -0,0 +1,203 @@
+package azuredevops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request) interface{}) (*Client, func()) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, token, _ := r.BasicAuth(); token != "secret" {
+			t.Errorf("have token %q, want secret", token)
+		}
+		if have := r.URL.Query().Get("api-version"); have != APIVersion {
+			t.Errorf("have api-version %q, want %q", have, APIVersion)
+		}
+
+		v := handler(w, r)
+		if v == nil {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v)
+	}))
+
+	u, _ := url.Parse(srv.URL + "/tfs/")
+	return NewClient(u, "secret", nil), srv.Close
+}
+
+func TestClient_ListProjects(t *testing.T) {
+	cli, done := newTestServer(t, func(w http.ResponseWriter, r *http.Request) interface{} {
+		if r.URL.Path != "/tfs/myorg/_apis/projects" {
+			return nil
+		}
+		// A full first page, so the client requests the second page.
+		projects := make([]*Project, 0, DefaultPageSize)
+		if r.URL.Query().Get("$skip") == "0" {
+			for i := 0; i < DefaultPageSize; i++ {
+				projects = append(projects, &Project{ID: fmt.Sprint(i), Name: fmt.Sprintf("project-%d", i)})
+			}
+		}
+		return map[string]interface{}{"count": len(projects), "value": projects}
+	})
+	defer done()
+
+	ctx := context.Background()
+	projects, hasNextPage, err := cli.ListProjects(ctx, "myorg", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != DefaultPageSize || !hasNextPage {
+		t.Errorf("have %d projects and hasNextPage=%t, want %d and true", len(projects), hasNextPage, DefaultPageSize)
+	}
+
+	projects, hasNextPage, err = cli.ListProjects(ctx, "myorg", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 0 || hasNextPage {
+		t.Errorf("have %d projects and hasNextPage=%t, want 0 and false", len(projects), hasNextPage)
+	}
+}
+
+func TestClient_ListRepos(t *testing.T) {
+	cli, done := newTestServer(t, func(w http.ResponseWriter, r *http.Request) interface{} {
+		switch r.URL.Path {
+		case "/tfs/myorg/My Project/_apis/git/repositories":
+			return map[string]interface{}{"value": []*Repository{{ID: "1", Name: "repo", Project: Project{Name: "My Project"}}}}
+		case "/tfs/myorg/_apis/git/repositories":
+			return map[string]interface{}{"value": []*Repository{
+				{ID: "1", Name: "repo", Project: Project{Name: "My Project"}},
+				{ID: "2", Name: "other", Project: Project{Name: "Other"}},
+			}}
+		}
+		return nil
+	})
+	defer done()
+
+	ctx := context.Background()
+	repos, err := cli.ListRepos(ctx, "myorg", "My Project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 || repos[0].NameWithOwner() != "myorg/My Project/repo" {
+		t.Errorf("unexpected repos %+v", repos)
+	}
+
+	repos, err = cli.ListRepos(ctx, "myorg", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.NameWithOwner())
+	}
+	if diff := cmp.Diff([]string{"myorg/My Project/repo", "myorg/Other/other"}, names); diff != "" {
+		t.Error(diff)
+	}
+
+	if _, err := cli.GetRepo(ctx, "myorg", "My Project", "missing"); !IsNotFound(err) {
+		t.Errorf("have error %v, want not found", err)
+	}
+}
+
+func TestClient_PullRequests(t *testing.T) {
+	repo := &Repository{
+		ID:      "r1",
+		Name:    "repo",
+		Org:     "myorg",
+		Project: Project{ID: "p1", Name: "My Project"},
+	}
+
+	cli, done := newTestServer(t, func(w http.ResponseWriter, r *http.Request) interface{} {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /tfs/myorg/p1/_apis/git/repositories/r1/pullrequests":
+			var in CreatePullRequestInput
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				t.Error(err)
+			}
+			if in.SourceRefName == "refs/heads/exists" {
+				w.WriteHeader(http.StatusConflict)
+				return map[string]string{"message": "TF401179: An active pull request for the source and target branch already exists."}
+			}
+			return &PullRequest{PullRequestID: 1, Title: in.Title, SourceRefName: in.SourceRefName, Status: PullRequestStatusActive}
+		case "GET /tfs/myorg/p1/_apis/git/repositories/r1/pullrequests":
+			q := r.URL.Query()
+			if q.Get("searchCriteria.sourceRefName") != "refs/heads/exists" || q.Get("searchCriteria.status") != "active" {
+				return map[string]interface{}{"value": []*PullRequest{}}
+			}
+			return map[string]interface{}{"value": []*PullRequest{{PullRequestID: 2}}}
+		case "PATCH /tfs/myorg/p1/_apis/git/repositories/r1/pullrequests/2":
+			var in UpdatePullRequestInput
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				t.Error(err)
+			}
+			return &PullRequest{PullRequestID: 2, Status: in.Status}
+		}
+		return nil
+	})
+	defer done()
+
+	ctx := context.Background()
+	pr, err := cli.CreatePullRequest(ctx, repo, &CreatePullRequestInput{
+		SourceRefName: "refs/heads/new",
+		TargetRefName: "refs/heads/master",
+		Title:         "Title",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.PullRequestID != 1 || pr.Title != "Title" {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+	if want := cli.URL.String() + "myorg/My%20Project/_git/repo/pullrequest/1"; pr.WebURL != want {
+		t.Errorf("have web URL %q, want %q", pr.WebURL, want)
+	}
+
+	_, err = cli.CreatePullRequest(ctx, repo, &CreatePullRequestInput{
+		SourceRefName: "refs/heads/exists",
+		TargetRefName: "refs/heads/master",
+	})
+	if err != ErrPullRequestAlreadyExists {
+		t.Fatalf("have error %v, want %v", err, ErrPullRequestAlreadyExists)
+	}
+
+	pr, err = cli.GetActivePullRequestByRefs(ctx, repo, "refs/heads/exists", "refs/heads/master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.PullRequestID != 2 {
+		t.Errorf("have pull request %d, want 2", pr.PullRequestID)
+	}
+
+	if _, err = cli.GetActivePullRequestByRefs(ctx, repo, "refs/heads/other", "refs/heads/master"); err != ErrPullRequestNotFound {
+		t.Errorf("have error %v, want %v", err, ErrPullRequestNotFound)
+	}
+
+	pr, err = cli.UpdatePullRequest(ctx, repo, 2, &UpdatePullRequestInput{Status: PullRequestStatusAbandoned})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Status != PullRequestStatusAbandoned {
+		t.Errorf("have status %q, want %q", pr.Status, PullRequestStatusAbandoned)
+	}
+}
+
+func TestClient_identityURL(t *testing.T) {
+	for rawURL, want := range map[string]string{
+		"https://dev.azure.com/":       "https://vssps.dev.azure.com/",
+		"https://ado.example.com/tfs/": "https://ado.example.com/tfs/",
+	} {
+		u, _ := url.Parse(rawURL)
+		if have := NewClient(u, "", nil).identityURL().String(); have != want {
+			t.Errorf("%s: have identity URL %q, want %q", rawURL, have, want)
+		}
+	}
+}
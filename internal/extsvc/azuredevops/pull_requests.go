@@ -0,0 +1,175 @@
+package azuredevops
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PullRequestStatus is the status of an Azure DevOps pull request.
+type PullRequestStatus string
+
+const (
+	PullRequestStatusActive    PullRequestStatus = "active"
+	PullRequestStatusAbandoned PullRequestStatus = "abandoned"
+	PullRequestStatusCompleted PullRequestStatus = "completed"
+)
+
+// Reviewer votes of Azure DevOps pull request reviewers.
+const (
+	VoteApproved                = 10
+	VoteApprovedWithSuggestions = 5
+	VoteNoVote                  = 0
+	VoteWaitingForAuthor        = -5
+	VoteRejected                = -10
+)
+
+// PullRequest is an Azure DevOps pull request.
+type PullRequest struct {
+	PullRequestID         int64             `json:"pullRequestId"`
+	Status                PullRequestStatus `json:"status"`
+	CreatedBy             IdentityRef       `json:"createdBy"`
+	CreationDate          time.Time         `json:"creationDate"`
+	ClosedDate            time.Time         `json:"closedDate"`
+	Title                 string            `json:"title"`
+	Description           string            `json:"description"`
+	SourceRefName         string            `json:"sourceRefName"`
+	TargetRefName         string            `json:"targetRefName"`
+	MergeStatus           string            `json:"mergeStatus,omitempty"`
+	IsDraft               bool              `json:"isDraft,omitempty"`
+	LastMergeSourceCommit *CommitRef        `json:"lastMergeSourceCommit,omitempty"`
+	LastMergeTargetCommit *CommitRef        `json:"lastMergeTargetCommit,omitempty"`
+	Reviewers             []*Reviewer       `json:"reviewers"`
+	URL                   string            `json:"url"`
+
+	// WebURL is the URL of the pull request in the Azure DevOps web
+	// interface. It isn't part of API responses and is set by the Client.
+	WebURL string `json:"webUrl"`
+}
+
+// UpdatedAt returns the time of the last status change of the pull request,
+// since Azure DevOps doesn't track when pull requests are updated.
+func (pr *PullRequest) UpdatedAt() time.Time {
+	if !pr.ClosedDate.IsZero() {
+		return pr.ClosedDate
+	}
+	return pr.CreationDate
+}
+
+// CommitRef is a reference to a Git commit.
+type CommitRef struct {
+	CommitID string `json:"commitId"`
+}
+
+// Reviewer is a reviewer of an Azure DevOps pull request.
+type Reviewer struct {
+	IdentityRef
+	Vote       int  `json:"vote"`
+	IsRequired bool `json:"isRequired,omitempty"`
+}
+
+// CreatePullRequestInput is the input of CreatePullRequest.
+type CreatePullRequestInput struct {
+	SourceRefName string `json:"sourceRefName"`
+	TargetRefName string `json:"targetRefName"`
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+}
+
+// UpdatePullRequestInput is the input of UpdatePullRequest. Empty fields are
+// left unchanged.
+type UpdatePullRequestInput struct {
+	Title         string            `json:"title,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	TargetRefName string            `json:"targetRefName,omitempty"`
+	Status        PullRequestStatus `json:"status,omitempty"`
+}
+
+// ErrPullRequestAlreadyExists is returned by CreatePullRequest when an active
+// pull request with the same source and target refs already exists.
+var ErrPullRequestAlreadyExists = errors.New("pull request already exists")
+
+// ErrPullRequestNotFound is returned by GetActivePullRequestByRefs when no
+// active pull request with the given refs exists.
+var ErrPullRequestNotFound = errors.New("pull request not found")
+
+// CreatePullRequest creates a pull request in the repository.
+//
+// API docs: https://docs.microsoft.com/en-us/rest/api/azure/devops/git/pull%20requests/create
+func (c *Client) CreatePullRequest(ctx context.Context, repo *Repository, in *CreatePullRequestInput) (*PullRequest, error) {
+	var pr PullRequest
+	if err := c.send(ctx, "POST", c.URL, pullRequestsPath(repo), nil, in, &pr); err != nil {
+		if statusCode(err) == http.StatusConflict {
+			return nil, ErrPullRequestAlreadyExists
+		}
+		return nil, err
+	}
+	c.setWebURL(repo, &pr)
+	return &pr, nil
+}
+
+// GetPullRequest returns the pull request with the given ID in the repository.
+//
+// API docs: https://docs.microsoft.com/en-us/rest/api/azure/devops/git/pull%20requests/get%20pull%20request
+func (c *Client) GetPullRequest(ctx context.Context, repo *Repository, id int64) (*PullRequest, error) {
+	var pr PullRequest
+	if err := c.get(ctx, c.URL, pullRequestsPath(repo)+"/"+strconv.FormatInt(id, 10), nil, &pr); err != nil {
+		return nil, err
+	}
+	c.setWebURL(repo, &pr)
+	return &pr, nil
+}
+
+// GetActivePullRequestByRefs returns the active pull request in the
+// repository with the given source and target refs.
+//
+// API docs: https://docs.microsoft.com/en-us/rest/api/azure/devops/git/pull%20requests/get%20pull%20requests
+func (c *Client) GetActivePullRequestByRefs(ctx context.Context, repo *Repository, source, target string) (*PullRequest, error) {
+	qry := url.Values{}
+	qry.Set("searchCriteria.status", string(PullRequestStatusActive))
+	qry.Set("searchCriteria.sourceRefName", source)
+	qry.Set("searchCriteria.targetRefName", target)
+
+	var result struct {
+		Value []*PullRequest `json:"value"`
+	}
+	if err := c.get(ctx, c.URL, pullRequestsPath(repo), qry, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Value) == 0 {
+		return nil, ErrPullRequestNotFound
+	}
+
+	pr := result.Value[0]
+	c.setWebURL(repo, pr)
+	return pr, nil
+}
+
+// UpdatePullRequest updates the pull request with the given ID in the
+// repository. Setting the status to abandoned closes the pull request.
+//
+// API docs: https://docs.microsoft.com/en-us/rest/api/azure/devops/git/pull%20requests/update
+func (c *Client) UpdatePullRequest(ctx context.Context, repo *Repository, id int64, in *UpdatePullRequestInput) (*PullRequest, error) {
+	var pr PullRequest
+	if err := c.send(ctx, "PATCH", c.URL, pullRequestsPath(repo)+"/"+strconv.FormatInt(id, 10), nil, in, &pr); err != nil {
+		return nil, err
+	}
+	c.setWebURL(repo, &pr)
+	return &pr, nil
+}
+
+func pullRequestsPath(repo *Repository) string {
+	return repo.Org + "/" + repo.Project.ID + "/_apis/git/repositories/" + repo.ID + "/pullrequests"
+}
+
+func (c *Client) setWebURL(repo *Repository, pr *PullRequest) {
+	webURL := repo.WebURL
+	if webURL == "" {
+		webURL = c.URL.ResolveReference(&url.URL{Path: repo.Org + "/" + repo.Project.Name + "/_git/" + repo.Name}).String()
+	}
+	pr.WebURL = webURL + "/pullrequest/" + strconv.FormatInt(pr.PullRequestID, 10)
+}
@@ -0,0 +1,102 @@
+package azuredevops
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// IdentityRef is a reference to an Azure DevOps identity, which is either a
+// user or a group.
+type IdentityRef struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName,omitempty"`
+	UniqueName  string `json:"uniqueName,omitempty"`
+	IsContainer bool   `json:"isContainer,omitempty"`
+}
+
+// Team is a team of an Azure DevOps project.
+type Team struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// TeamMember is a member of an Azure DevOps team.
+type TeamMember struct {
+	Identity    IdentityRef `json:"identity"`
+	IsTeamAdmin bool        `json:"isTeamAdmin,omitempty"`
+}
+
+// Identity is an Azure DevOps identity as returned by the identities API.
+type Identity struct {
+	ID                  string `json:"id"`
+	Descriptor          string `json:"descriptor,omitempty"`
+	ProviderDisplayName string `json:"providerDisplayName,omitempty"`
+	IsActive            bool   `json:"isActive"`
+	IsContainer         bool   `json:"isContainer,omitempty"`
+}
+
+// ListTeams returns a page of the teams of the project of the organization.
+//
+// API docs: https://docs.microsoft.com/en-us/rest/api/azure/devops/core/teams/get%20teams
+func (c *Client) ListTeams(ctx context.Context, org, project string, page int) (teams []*Team, hasNextPage bool, err error) {
+	var result struct {
+		Value []*Team `json:"value"`
+	}
+	path := org + "/_apis/projects/" + project + "/teams"
+	if err := c.get(ctx, c.URL, path, c.paginate(nil, page), &result); err != nil {
+		return nil, false, err
+	}
+	return result.Value, len(result.Value) == c.PageSize, nil
+}
+
+// ListTeamMembers returns a page of the direct members of the team of the
+// project of the organization.
+//
+// API docs: https://docs.microsoft.com/en-us/rest/api/azure/devops/core/teams/get%20team%20members%20with%20extended%20properties
+func (c *Client) ListTeamMembers(ctx context.Context, org, project, team string, page int) (members []*TeamMember, hasNextPage bool, err error) {
+	var result struct {
+		Value []*TeamMember `json:"value"`
+	}
+	path := org + "/_apis/projects/" + project + "/teams/" + team + "/members"
+	if err := c.get(ctx, c.URL, path, c.paginate(nil, page), &result); err != nil {
+		return nil, false, err
+	}
+	return result.Value, len(result.Value) == c.PageSize, nil
+}
+
+// FindIdentity returns the identity with the given account name in the
+// organization, which is the principal name of a user, such as
+// alice@example.com. It returns nil if there is no such identity.
+//
+// API docs: https://docs.microsoft.com/en-us/rest/api/azure/devops/ims/identities/read%20identities
+func (c *Client) FindIdentity(ctx context.Context, org, accountName string) (*Identity, error) {
+	qry := url.Values{}
+	qry.Set("searchFilter", "AccountName")
+	qry.Set("filterValue", accountName)
+	qry.Set("queryMembership", "None")
+
+	var result struct {
+		Value []*Identity `json:"value"`
+	}
+	if err := c.get(ctx, c.identityURL(), org+"/_apis/identities", qry, &result); err != nil {
+		return nil, err
+	}
+	for _, id := range result.Value {
+		if !id.IsContainer {
+			return id, nil
+		}
+	}
+	return nil, nil
+}
+
+// identityURL returns the base URL of the identity APIs, which Azure DevOps
+// Services serves from a separate host.
+func (c *Client) identityURL() *url.URL {
+	if !strings.EqualFold(c.URL.Host, servicesHost) {
+		return c.URL
+	}
+	u := *c.URL
+	u.Host = "vssps." + servicesHost
+	return &u
+}
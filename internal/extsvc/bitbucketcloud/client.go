@@ -1,9 +1,11 @@
 package bitbucketcloud
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -38,6 +40,11 @@ const (
 	RateLimitMaxBurstRequests  = 500
 )
 
+// DefaultPageLen is the number of items requested per page from the paginated
+// permissions endpoints unless the PageLen of a client is set. It is the
+// maximum these endpoints allow.
+const DefaultPageLen = 100
+
 // Client access a Bitbucket Cloud via the REST API 2.0.
 type Client struct {
 	// HTTP Client used to communicate with the API
@@ -52,6 +59,10 @@ type Client struct {
 	// RateLimit is the self-imposed rate limiter (since Bitbucket does not have a concept
 	// of rate limiting in HTTP response headers).
 	RateLimit *rate.Limiter
+
+	// PageLen is the number of items requested per page from the paginated
+	// permissions endpoints.
+	PageLen int
 }
 
 // NewClient creates a new Bitbucket Cloud API client with given apiURL. If a nil httpClient
@@ -82,6 +93,7 @@ func NewClient(apiURL *url.URL, httpClient httpcli.Doer) *Client {
 		httpClient: httpClient,
 		URL:        apiURL,
 		RateLimit:  l,
+		PageLen:    DefaultPageLen,
 	}
 }
 
@@ -143,6 +155,31 @@ func (c *Client) reqPage(ctx context.Context, url string, results interface{}) (
 	return &next, nil
 }
 
+// send marshals the given payload as JSON and sends it to the given path with
+// the given HTTP method, decoding the response into result if it's not nil.
+func (c *Client) send(ctx context.Context, method, path string, qry url.Values, payload, result interface{}) error {
+	if qry == nil {
+		qry = make(url.Values)
+	}
+
+	var body io.Reader
+	if payload != nil {
+		bs, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(bs)
+	}
+
+	u := url.URL{Path: path, RawQuery: qry.Encode()}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return err
+	}
+
+	return c.do(ctx, req, result)
+}
+
 func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
 	req.URL = c.URL.ResolveReference(req.URL)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
@@ -276,3 +313,9 @@ func (e *httpError) Unauthorized() bool {
 func (e *httpError) NotFound() bool {
 	return e.StatusCode == http.StatusNotFound
 }
+
+// IsNotFound reports whether err is a Bitbucket Cloud API not found error.
+func IsNotFound(err error) bool {
+	e, ok := errors.Cause(err).(*httpError)
+	return ok && e.NotFound()
+}
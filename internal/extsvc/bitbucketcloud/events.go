@@ -0,0 +1,49 @@
+package bitbucketcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	eventTypeHeader = "X-Event-Key"
+)
+
+// WebhookEventType returns the type of the webhook event sent in the given
+// request.
+func WebhookEventType(r *http.Request) string {
+	return r.Header.Get(eventTypeHeader)
+}
+
+// ParseWebhookEvent unmarshals the payload of a webhook event of the given
+// type. Only pull request and commit status events are supported.
+func ParseWebhookEvent(eventType string, payload []byte) (e interface{}, err error) {
+	switch {
+	case strings.HasPrefix(eventType, "pullrequest:"):
+		e = &PullRequestEvent{}
+		return e, json.Unmarshal(payload, e)
+	case eventType == "repo:commit_status_created", eventType == "repo:commit_status_updated":
+		e = &CommitStatusEvent{}
+		return e, json.Unmarshal(payload, e)
+	default:
+		return nil, fmt.Errorf("unknown webhook event type: %q", eventType)
+	}
+}
+
+// PullRequestEvent is the payload of all pullrequest:* webhook events, such
+// as pullrequest:created, pullrequest:approved or pullrequest:comment_created.
+type PullRequestEvent struct {
+	Actor       Account     `json:"actor"`
+	PullRequest PullRequest `json:"pullrequest"`
+	Repository  Repo        `json:"repository"`
+}
+
+// CommitStatusEvent is the payload of the repo:commit_status_created and
+// repo:commit_status_updated webhook events.
+type CommitStatusEvent struct {
+	Actor        Account     `json:"actor"`
+	CommitStatus BuildStatus `json:"commit_status"`
+	Repository   Repo        `json:"repository"`
+}
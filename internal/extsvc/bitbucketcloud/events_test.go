@@ -0,0 +1,49 @@
+package bitbucketcloud
+
+import (
+	"testing"
+)
+
+func TestParseWebhookEvent(t *testing.T) {
+	t.Run("pull request", func(t *testing.T) {
+		e, err := ParseWebhookEvent("pullrequest:approved", []byte(`{
+			"pullrequest": {"id": 42, "state": "OPEN"},
+			"repository": {"uuid": "{mux}"}
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pre, ok := e.(*PullRequestEvent)
+		if !ok {
+			t.Fatalf("unexpected event type %T", e)
+		}
+		if pre.PullRequest.ID != 42 || pre.Repository.UUID != "{mux}" {
+			t.Errorf("unexpected event: %+v", pre)
+		}
+	})
+
+	t.Run("commit status", func(t *testing.T) {
+		e, err := ParseWebhookEvent("repo:commit_status_updated", []byte(`{
+			"commit_status": {"key": "ci", "state": "FAILED", "refname": "my-branch"},
+			"repository": {"uuid": "{mux}"}
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cse, ok := e.(*CommitStatusEvent)
+		if !ok {
+			t.Fatalf("unexpected event type %T", e)
+		}
+		if cse.CommitStatus.State != BuildStatusStateFailed || cse.CommitStatus.RefName != "my-branch" {
+			t.Errorf("unexpected event: %+v", cse)
+		}
+	})
+
+	t.Run("unknown event", func(t *testing.T) {
+		if _, err := ParseWebhookEvent("repo:push", []byte(`{}`)); err == nil {
+			t.Error("unexpected nil error")
+		}
+	})
+}
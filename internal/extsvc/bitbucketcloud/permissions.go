@@ -0,0 +1,66 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// WorkspaceMembership is the membership of a user in a workspace.
+type WorkspaceMembership struct {
+	User Account `json:"user"`
+}
+
+// RepositoryPermission is the permission of a user on a repository of a
+// workspace. Permission is one of "read", "write" or "admin".
+type RepositoryPermission struct {
+	Permission string  `json:"permission"`
+	User       Account `json:"user"`
+	Repository Repo    `json:"repository"`
+}
+
+// ListWorkspaceMembers returns all members of the given workspace.
+func (c *Client) ListWorkspaceMembers(ctx context.Context, workspace string) ([]*WorkspaceMembership, error) {
+	path := fmt.Sprintf("/2.0/workspaces/%s/members", workspace)
+
+	var all []*WorkspaceMembership
+	next, err := c.page(ctx, path, nil, &PageToken{Pagelen: c.PageLen}, &all)
+	for err == nil && next.HasMore() {
+		var page []*WorkspaceMembership
+		next, err = c.reqPage(ctx, next.Next, &page)
+		all = append(all, page...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// ListUserRepositoryPermissions returns the permissions of the user with the
+// given UUID on all repositories of the given workspace. The credentials of
+// the client must belong to an administrator of the workspace.
+func (c *Client) ListUserRepositoryPermissions(ctx context.Context, workspace, userUUID string) ([]*RepositoryPermission, error) {
+	qry := url.Values{"q": []string{fmt.Sprintf("user.uuid = %q", userUUID)}}
+	return c.listRepositoryPermissions(ctx, fmt.Sprintf("/2.0/workspaces/%s/permissions/repositories", workspace), qry)
+}
+
+// ListRepositoryPermissions returns the permissions of all users on the
+// repository with the given slug in the given workspace. The credentials of
+// the client must belong to an administrator of the workspace.
+func (c *Client) ListRepositoryPermissions(ctx context.Context, workspace, repoSlug string) ([]*RepositoryPermission, error) {
+	return c.listRepositoryPermissions(ctx, fmt.Sprintf("/2.0/workspaces/%s/permissions/repositories/%s", workspace, repoSlug), nil)
+}
+
+func (c *Client) listRepositoryPermissions(ctx context.Context, path string, qry url.Values) ([]*RepositoryPermission, error) {
+	var all []*RepositoryPermission
+	next, err := c.page(ctx, path, qry, &PageToken{Pagelen: c.PageLen}, &all)
+	for err == nil && next.HasMore() {
+		var page []*RepositoryPermission
+		next, err = c.reqPage(ctx, next.Next, &page)
+		all = append(all, page...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
@@ -0,0 +1,61 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClient_ListUserRepositoryPermissions(t *testing.T) {
+	var query string
+	cli := newPullRequestsTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if have, want := r.URL.Path, "/2.0/workspaces/sglocal/permissions/repositories"; have != want {
+			t.Errorf("wrong path: have %q, want %q", have, want)
+		}
+		query = r.URL.Query().Get("q")
+
+		fmt.Fprint(w, `{"values": [
+			{"permission": "read", "user": {"uuid": "{alice}"}, "repository": {"full_name": "sglocal/mux", "uuid": "{mux}"}}
+		]}`)
+	})
+
+	have, err := cli.ListUserRepositoryPermissions(context.Background(), "sglocal", "{alice}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `user.uuid = "{alice}"`; query != want {
+		t.Errorf("wrong query: have %q, want %q", query, want)
+	}
+
+	want := []*RepositoryPermission{{
+		Permission: "read",
+		User:       Account{UUID: "{alice}"},
+		Repository: Repo{FullName: "sglocal/mux", UUID: "{mux}"},
+	}}
+	if diff := cmp.Diff(want, have); diff != "" {
+		t.Errorf("wrong permissions:\n%s", diff)
+	}
+}
+
+func TestClient_ListWorkspaceMembers(t *testing.T) {
+	cli := newPullRequestsTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if have, want := r.URL.Path, "/2.0/workspaces/sglocal/members"; have != want {
+			t.Errorf("wrong path: have %q, want %q", have, want)
+		}
+		fmt.Fprint(w, `{"values": [{"user": {"uuid": "{alice}", "nickname": "alice"}}]}`)
+	})
+
+	have, err := cli.ListWorkspaceMembers(context.Background(), "sglocal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*WorkspaceMembership{{User: Account{UUID: "{alice}", Nickname: "alice"}}}
+	if diff := cmp.Diff(want, have); diff != "" {
+		t.Errorf("wrong members:\n%s", diff)
+	}
+}
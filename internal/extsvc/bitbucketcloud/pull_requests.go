@@ -0,0 +1,279 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPullRequestNotFound is returned by FindOpenPullRequest when no open pull
+// request matches the given branches.
+var ErrPullRequestNotFound = errors.New("pull request not found")
+
+// PullRequestState is the state of a Bitbucket Cloud pull request.
+type PullRequestState string
+
+// Known PullRequestStates.
+const (
+	PullRequestStateOpen       PullRequestState = "OPEN"
+	PullRequestStateMerged     PullRequestState = "MERGED"
+	PullRequestStateDeclined   PullRequestState = "DECLINED"
+	PullRequestStateSuperseded PullRequestState = "SUPERSEDED"
+)
+
+// ParticipantState is the review state of a participant of a pull request.
+type ParticipantState string
+
+// Known ParticipantStates. A participant that hasn't reviewed the pull request
+// has an empty state.
+const (
+	ParticipantStateApproved         ParticipantState = "approved"
+	ParticipantStateChangesRequested ParticipantState = "changes_requested"
+)
+
+// Account is a Bitbucket Cloud user or team account.
+type Account struct {
+	UUID        string `json:"uuid"`
+	AccountID   string `json:"account_id,omitempty"`
+	Nickname    string `json:"nickname,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	Links       Links  `json:"links"`
+}
+
+// PullRequest is a Bitbucket Cloud pull request.
+type PullRequest struct {
+	ID                int64               `json:"id"`
+	Title             string              `json:"title"`
+	Description       string              `json:"description"`
+	State             PullRequestState    `json:"state"`
+	Author            Account             `json:"author"`
+	Source            PullRequestEndpoint `json:"source"`
+	Destination       PullRequestEndpoint `json:"destination"`
+	MergeCommit       *Commit             `json:"merge_commit,omitempty"`
+	CloseSourceBranch bool                `json:"close_source_branch"`
+	Participants      []Participant       `json:"participants"`
+	CreatedOn         time.Time           `json:"created_on"`
+	UpdatedOn         time.Time           `json:"updated_on"`
+	Links             Links               `json:"links"`
+
+	// Comments and Statuses aren't part of the pull request API response,
+	// they're loaded separately by the changeset source.
+	Comments []*Comment      `json:"comments,omitempty"`
+	Statuses []*CommitStatus `json:"statuses,omitempty"`
+}
+
+// PullRequestEndpoint is the source or destination of a pull request.
+type PullRequestEndpoint struct {
+	Branch     Branch  `json:"branch"`
+	Commit     *Commit `json:"commit,omitempty"`
+	Repository *Repo   `json:"repository,omitempty"`
+}
+
+// Branch is a named branch of a repository.
+type Branch struct {
+	Name string `json:"name"`
+}
+
+// Commit is a reference to a commit.
+type Commit struct {
+	Hash string `json:"hash"`
+}
+
+// Participant is a reviewer or participant of a pull request.
+type Participant struct {
+	User           Account          `json:"user"`
+	Role           string           `json:"role"`
+	Approved       bool             `json:"approved"`
+	State          ParticipantState `json:"state"`
+	ParticipatedOn time.Time        `json:"participated_on"`
+}
+
+// Comment is a comment on a pull request.
+type Comment struct {
+	ID        int64          `json:"id"`
+	Content   CommentContent `json:"content"`
+	User      Account        `json:"user"`
+	Deleted   bool           `json:"deleted"`
+	CreatedOn time.Time      `json:"created_on"`
+	UpdatedOn time.Time      `json:"updated_on"`
+	Links     Links          `json:"links"`
+}
+
+// CommentContent is the content of a comment.
+type CommentContent struct {
+	Raw string `json:"raw"`
+}
+
+// Key is a unique key identifying this comment in the context of its
+// pull request.
+func (c *Comment) Key() string { return strconv.FormatInt(c.ID, 10) }
+
+// BuildStatus is the state of a build of a commit, as reported by a CI system.
+type BuildStatus struct {
+	UUID        string           `json:"uuid"`
+	Key         string           `json:"key"`
+	Name        string           `json:"name"`
+	RefName     string           `json:"refname,omitempty"`
+	State       BuildStatusState `json:"state"`
+	URL         string           `json:"url"`
+	Description string           `json:"description"`
+	CreatedOn   time.Time        `json:"created_on"`
+	UpdatedOn   time.Time        `json:"updated_on"`
+}
+
+// BuildStatusState is the state of a BuildStatus.
+type BuildStatusState string
+
+// Known BuildStatusStates.
+const (
+	BuildStatusStateSuccessful BuildStatusState = "SUCCESSFUL"
+	BuildStatusStateFailed     BuildStatusState = "FAILED"
+	BuildStatusStateInProgress BuildStatusState = "INPROGRESS"
+	BuildStatusStateStopped    BuildStatusState = "STOPPED"
+)
+
+// CommitStatus is a BuildStatus of a specific commit.
+type CommitStatus struct {
+	Commit string      `json:"commit,omitempty"`
+	Status BuildStatus `json:"status,omitempty"`
+}
+
+// Key is a unique key identifying this commit status in the context of its
+// pull request.
+func (s *CommitStatus) Key() string { return s.Commit + ":" + s.Status.Key }
+
+// CreatePullRequestInput is the payload of CreatePullRequest.
+type CreatePullRequestInput struct {
+	Title             string              `json:"title"`
+	Description       string              `json:"description"`
+	Source            PullRequestEndpoint `json:"source"`
+	Destination       PullRequestEndpoint `json:"destination"`
+	CloseSourceBranch bool                `json:"close_source_branch"`
+}
+
+// UpdatePullRequestInput is the payload of UpdatePullRequest.
+type UpdatePullRequestInput struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Destination PullRequestEndpoint `json:"destination"`
+}
+
+// CreatePullRequest creates a pull request in the given repository.
+func (c *Client) CreatePullRequest(ctx context.Context, repo *Repo, in *CreatePullRequestInput) (*PullRequest, error) {
+	var pr PullRequest
+	err := c.send(ctx, "POST", pullRequestsPath(repo), nil, in, &pr)
+	if err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// GetPullRequest returns the pull request with the given ID in the given
+// repository.
+func (c *Client) GetPullRequest(ctx context.Context, repo *Repo, id int64) (*PullRequest, error) {
+	var pr PullRequest
+	err := c.send(ctx, "GET", pullRequestPath(repo, id), nil, nil, &pr)
+	if err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// FindOpenPullRequest returns the open pull request from the source branch to
+// the destination branch in the given repository. It returns
+// ErrPullRequestNotFound if none exists.
+func (c *Client) FindOpenPullRequest(ctx context.Context, repo *Repo, source, destination string) (*PullRequest, error) {
+	qry := url.Values{
+		"q": []string{fmt.Sprintf(
+			"source.branch.name = %q AND destination.branch.name = %q AND state = %q",
+			source, destination, PullRequestStateOpen,
+		)},
+	}
+
+	var prs []*PullRequest
+	if _, err := c.page(ctx, pullRequestsPath(repo), qry, nil, &prs); err != nil {
+		return nil, err
+	}
+
+	for _, pr := range prs {
+		// Repositories with the same full name may exist in forks, so make
+		// sure the pull request originates from the same repository.
+		if pr.Source.Repository != nil && pr.Source.Repository.UUID != repo.UUID {
+			continue
+		}
+		return pr, nil
+	}
+
+	return nil, ErrPullRequestNotFound
+}
+
+// UpdatePullRequest updates the title, description and destination branch of
+// the pull request with the given ID.
+func (c *Client) UpdatePullRequest(ctx context.Context, repo *Repo, id int64, in *UpdatePullRequestInput) (*PullRequest, error) {
+	var pr PullRequest
+	err := c.send(ctx, "PUT", pullRequestPath(repo, id), nil, in, &pr)
+	if err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// DeclinePullRequest declines the pull request with the given ID.
+func (c *Client) DeclinePullRequest(ctx context.Context, repo *Repo, id int64) (*PullRequest, error) {
+	var pr PullRequest
+	err := c.send(ctx, "POST", pullRequestPath(repo, id)+"/decline", nil, nil, &pr)
+	if err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// ListPullRequestComments returns all comments of the pull request with the
+// given ID.
+func (c *Client) ListPullRequestComments(ctx context.Context, repo *Repo, id int64) ([]*Comment, error) {
+	var all []*Comment
+	next, err := c.page(ctx, pullRequestPath(repo, id)+"/comments", nil, nil, &all)
+	for err == nil && next.HasMore() {
+		var page []*Comment
+		next, err = c.reqPage(ctx, next.Next, &page)
+		all = append(all, page...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// ListCommitStatuses returns all build statuses of the given commit.
+func (c *Client) ListCommitStatuses(ctx context.Context, repo *Repo, commit string) ([]*CommitStatus, error) {
+	path := fmt.Sprintf("/2.0/repositories/%s/commit/%s/statuses", repo.FullName, commit)
+
+	var all []*BuildStatus
+	next, err := c.page(ctx, path, nil, nil, &all)
+	for err == nil && next.HasMore() {
+		var page []*BuildStatus
+		next, err = c.reqPage(ctx, next.Next, &page)
+		all = append(all, page...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*CommitStatus, 0, len(all))
+	for _, s := range all {
+		statuses = append(statuses, &CommitStatus{Commit: commit, Status: *s})
+	}
+	return statuses, nil
+}
+
+func pullRequestsPath(repo *Repo) string {
+	return fmt.Sprintf("/2.0/repositories/%s/pullrequests", repo.FullName)
+}
+
+func pullRequestPath(repo *Repo, id int64) string {
+	return fmt.Sprintf("%s/%d", pullRequestsPath(repo), id)
+}
@@ -0,0 +1,153 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+func newPullRequestsTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewClient(u, srv.Client())
+}
+
+func TestClient_FindOpenPullRequest(t *testing.T) {
+	repo := &Repo{FullName: "sglocal/mux", UUID: "{mux}"}
+
+	var query string
+	cli := newPullRequestsTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if have, want := r.URL.Path, "/2.0/repositories/sglocal/mux/pullrequests"; have != want {
+			t.Errorf("wrong path: have %q, want %q", have, want)
+		}
+		query = r.URL.Query().Get("q")
+
+		fmt.Fprint(w, `{"values": [
+			{"id": 1, "source": {"branch": {"name": "my-branch"}, "repository": {"uuid": "{fork}"}}},
+			{"id": 2, "source": {"branch": {"name": "my-branch"}, "repository": {"uuid": "{mux}"}}}
+		]}`)
+	})
+
+	pr, err := cli.FindOpenPullRequest(context.Background(), repo, "my-branch", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := pr.ID, int64(2); have != want {
+		t.Errorf("wrong pull request: have %d, want %d", have, want)
+	}
+
+	wantQuery := `source.branch.name = "my-branch" AND destination.branch.name = "master" AND state = "OPEN"`
+	if query != wantQuery {
+		t.Errorf("wrong query: have %q, want %q", query, wantQuery)
+	}
+
+	cli = newPullRequestsTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values": []}`)
+	})
+
+	if _, err := cli.FindOpenPullRequest(context.Background(), repo, "my-branch", "master"); err != ErrPullRequestNotFound {
+		t.Errorf("wrong error: have %v, want %v", err, ErrPullRequestNotFound)
+	}
+}
+
+func TestClient_CreatePullRequest(t *testing.T) {
+	repo := &Repo{FullName: "sglocal/mux"}
+	in := &CreatePullRequestInput{
+		Title:       "My PR",
+		Description: "Hello",
+		Source:      PullRequestEndpoint{Branch: Branch{Name: "my-branch"}},
+		Destination: PullRequestEndpoint{Branch: Branch{Name: "master"}},
+	}
+
+	cli := newPullRequestsTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("wrong method: %s", r.Method)
+		}
+
+		var have CreatePullRequestInput
+		if err := json.NewDecoder(r.Body).Decode(&have); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(*in, have); diff != "" {
+			t.Errorf("wrong payload:\n%s", diff)
+		}
+
+		fmt.Fprint(w, `{"id": 42, "title": "My PR", "state": "OPEN"}`)
+	})
+
+	pr, err := cli.CreatePullRequest(context.Background(), repo, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &PullRequest{ID: 42, Title: "My PR", State: PullRequestStateOpen}
+	if diff := cmp.Diff(want, pr); diff != "" {
+		t.Errorf("wrong pull request:\n%s", diff)
+	}
+}
+
+func TestClient_GetPullRequest_NotFound(t *testing.T) {
+	cli := newPullRequestsTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	_, err := cli.GetPullRequest(context.Background(), &Repo{FullName: "sglocal/mux"}, 1)
+	if !IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	if IsNotFound(errors.New("boom")) {
+		t.Error("unexpected not found error")
+	}
+}
+
+func TestClient_ListCommitStatuses(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if have, want := r.URL.Path, "/2.0/repositories/sglocal/mux/commit/deadbeef/statuses"; have != want {
+			t.Errorf("wrong path: have %q, want %q", have, want)
+		}
+
+		if r.URL.Query().Get("page") == "" {
+			fmt.Fprintf(w, `{"values": [{"key": "ci", "state": "SUCCESSFUL"}], "next": %q}`, srv.URL+r.URL.Path+"?page=2")
+			return
+		}
+		fmt.Fprint(w, `{"values": [{"key": "lint", "state": "FAILED"}]}`)
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := NewClient(u, srv.Client())
+
+	have, err := cli.ListCommitStatuses(context.Background(), &Repo{FullName: "sglocal/mux"}, "deadbeef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*CommitStatus{
+		{Commit: "deadbeef", Status: BuildStatus{Key: "ci", State: BuildStatusStateSuccessful}},
+		{Commit: "deadbeef", Status: BuildStatus{Key: "lint", State: BuildStatusStateFailed}},
+	}
+	if diff := cmp.Diff(want, have); diff != "" {
+		t.Errorf("wrong statuses:\n%s", diff)
+	}
+}
@@ -682,6 +682,16 @@ func (c *Client) LabeledRepos(ctx context.Context, pageToken *PageToken, label s
 	return repos, next, err
 }
 
+// RepoLabels returns the labels of the repository with the given project key
+// and slug.
+func (c *Client) RepoLabels(ctx context.Context, pageToken *PageToken, projectKey, repoSlug string) ([]*Label, *PageToken, error) {
+	u := fmt.Sprintf("rest/api/1.0/projects/%s/repos/%s/labels", projectKey, repoSlug)
+
+	var labels []*Label
+	next, err := c.page(ctx, u, nil, pageToken, &labels)
+	return labels, next, err
+}
+
 // RepoIDs fetches a list of repository IDs that the user token has permission for.
 // Permission: ["admin", "read", "write"]
 func (c *Client) RepoIDs(ctx context.Context, permission string) ([]uint32, error) {
@@ -994,6 +1004,11 @@ type GroupProjectPermission struct {
 	Project *Project
 }
 
+// Label is a label of a repository.
+type Label struct {
+	Name string `json:"name"`
+}
+
 type Repo struct {
 	Slug          string   `json:"slug"`
 	ID            int      `json:"id"`
@@ -0,0 +1,340 @@
+// Package gitea implements a Gitea API client. Gogs serves a subset of the
+// same API, which is enough to list its repositories.
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/httpcli"
+	"github.com/sourcegraph/sourcegraph/internal/metrics"
+	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
+)
+
+var requestCounter = metrics.NewRequestMeter("gitea_requests_count", "Total number of requests sent to the Gitea API.")
+
+// DefaultPageSize is the number of items requested per page unless the
+// PageSize of a client is set. It is the default maximum page size of Gitea.
+const DefaultPageSize = 50
+
+// Client access a Gitea instance via the REST API v1.
+type Client struct {
+	// HTTP Client used to communicate with the API
+	httpClient httpcli.Doer
+
+	// URL is the base URL of the Gitea instance.
+	URL *url.URL
+
+	// Token is the access token used to authenticate requests.
+	Token string
+
+	// PageSize is the number of items requested per page of paginated
+	// requests.
+	PageSize int
+
+	// sudo is the username of the user the requests are made as. Only site
+	// admins can make requests as other users.
+	sudo string
+}
+
+// NewClient creates a new Gitea API client for the instance with the given
+// base URL. If a nil httpClient is provided, http.DefaultClient will be used.
+func NewClient(baseURL *url.URL, token string, httpClient httpcli.Doer) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	httpClient = requestCounter.Doer(httpClient, func(u *url.URL) string {
+		// The third component of the Path (/api/v1/{category}) mostly maps to
+		// the type of API request we are making.
+		var category string
+		if parts := strings.SplitN(u.Path, "/", 5); len(parts) > 3 {
+			category = parts[3]
+		}
+		return category
+	})
+
+	return &Client{
+		httpClient: httpClient,
+		URL:        baseURL,
+		Token:      token,
+		PageSize:   DefaultPageSize,
+	}
+}
+
+// Sudo returns a copy of the client which makes requests as the user with
+// the given username. The token of the client must belong to a site admin.
+func (c *Client) Sudo(username string) *Client {
+	sc := *c
+	sc.sudo = username
+	return &sc
+}
+
+// Repository is a Gitea repository.
+type Repository struct {
+	ID            int64  `json:"id"`
+	Owner         *User  `json:"owner"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Description   string `json:"description"`
+	Empty         bool   `json:"empty"`
+	Private       bool   `json:"private"`
+	Fork          bool   `json:"fork"`
+	Mirror        bool   `json:"mirror"`
+	Archived      bool   `json:"archived"`
+	HTMLURL       string `json:"html_url"`
+	CloneURL      string `json:"clone_url"`
+	SSHURL        string `json:"ssh_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// User is a Gitea user or organization.
+type User struct {
+	ID       int64  `json:"id"`
+	Login    string `json:"login"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+	IsAdmin  bool   `json:"is_admin"`
+}
+
+// Organization is a Gitea organization.
+type Organization struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+}
+
+// Team is a team of a Gitea organization.
+type Team struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Permission string `json:"permission"`
+}
+
+// ListOrgRepos returns a page of the repositories of the organization.
+//
+// API docs: https://try.gitea.io/api/swagger#/organization/orgListRepos
+func (c *Client) ListOrgRepos(ctx context.Context, org string, page int) (repos []*Repository, hasNextPage bool, err error) {
+	return c.listRepos(ctx, "orgs/"+org+"/repos", page)
+}
+
+// ListUserRepos returns a page of the repositories owned by the user.
+//
+// API docs: https://try.gitea.io/api/swagger#/user/userListRepos
+func (c *Client) ListUserRepos(ctx context.Context, username string, page int) (repos []*Repository, hasNextPage bool, err error) {
+	return c.listRepos(ctx, "users/"+username+"/repos", page)
+}
+
+// ListAccessibleRepos returns a page of the repositories the authenticated
+// user, or the user the client makes requests as, has access to.
+//
+// API docs: https://try.gitea.io/api/swagger#/user/userCurrentListRepos
+func (c *Client) ListAccessibleRepos(ctx context.Context, page int) (repos []*Repository, hasNextPage bool, err error) {
+	return c.listRepos(ctx, "user/repos", page)
+}
+
+// SearchRepos returns a page of the repositories matching the keyword query.
+// An empty query matches all repositories visible to the authenticated user.
+//
+// API docs: https://try.gitea.io/api/swagger#/repository/repoSearch
+func (c *Client) SearchRepos(ctx context.Context, query string, page int) (repos []*Repository, hasNextPage bool, err error) {
+	qry := url.Values{}
+	if query != "" {
+		qry.Set("q", query)
+	}
+
+	var result struct {
+		OK   bool          `json:"ok"`
+		Data []*Repository `json:"data"`
+	}
+	if err := c.get(ctx, "repos/search", c.paginate(qry, page), &result); err != nil {
+		return nil, false, err
+	}
+	return result.Data, len(result.Data) == c.PageSize, nil
+}
+
+// GetRepo returns the repository with the given owner and name.
+//
+// API docs: https://try.gitea.io/api/swagger#/repository/repoGet
+func (c *Client) GetRepo(ctx context.Context, owner, name string) (*Repository, error) {
+	var repo Repository
+	if err := c.get(ctx, "repos/"+owner+"/"+name, nil, &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// ListRepoCollaborators returns a page of the collaborators of the repository.
+//
+// API docs: https://try.gitea.io/api/swagger#/repository/repoListCollaborators
+func (c *Client) ListRepoCollaborators(ctx context.Context, owner, name string, page int) (users []*User, hasNextPage bool, err error) {
+	path := "repos/" + owner + "/" + name + "/collaborators"
+	if err := c.get(ctx, path, c.paginate(nil, page), &users); err != nil {
+		return nil, false, err
+	}
+	return users, len(users) == c.PageSize, nil
+}
+
+// ListRepoTeams returns the teams of the organization that owns the repository
+// which have access to it.
+//
+// API docs: https://try.gitea.io/api/swagger#/repository/repoListTeams
+func (c *Client) ListRepoTeams(ctx context.Context, owner, name string) ([]*Team, error) {
+	var teams []*Team
+	if err := c.get(ctx, "repos/"+owner+"/"+name+"/teams", nil, &teams); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+// ListTeamMembers returns a page of the members of the team.
+//
+// API docs: https://try.gitea.io/api/swagger#/organization/orgListTeamMembers
+func (c *Client) ListTeamMembers(ctx context.Context, teamID int64, page int) (users []*User, hasNextPage bool, err error) {
+	path := "teams/" + strconv.FormatInt(teamID, 10) + "/members"
+	if err := c.get(ctx, path, c.paginate(nil, page), &users); err != nil {
+		return nil, false, err
+	}
+	return users, len(users) == c.PageSize, nil
+}
+
+// GetOrg returns the organization with the given name. It returns nil if there
+// is no organization with that name, such as when it is the name of a user.
+//
+// API docs: https://try.gitea.io/api/swagger#/organization/orgGet
+func (c *Client) GetOrg(ctx context.Context, name string) (*Organization, error) {
+	var org Organization
+	if err := c.get(ctx, "orgs/"+name, nil, &org); IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// GetUser returns the user with the given username.
+//
+// API docs: https://try.gitea.io/api/swagger#/user/userGet
+func (c *Client) GetUser(ctx context.Context, username string) (*User, error) {
+	var user User
+	if err := c.get(ctx, "users/"+username, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CurrentUser returns the authenticated user.
+//
+// API docs: https://try.gitea.io/api/swagger#/user/userGetCurrent
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.get(ctx, "user", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) listRepos(ctx context.Context, path string, page int) ([]*Repository, bool, error) {
+	var repos []*Repository
+	if err := c.get(ctx, path, c.paginate(nil, page), &repos); err != nil {
+		return nil, false, err
+	}
+	return repos, len(repos) == c.PageSize, nil
+}
+
+func (c *Client) paginate(qry url.Values, page int) url.Values {
+	if qry == nil {
+		qry = url.Values{}
+	}
+	qry.Set("page", strconv.Itoa(page))
+	qry.Set("limit", strconv.Itoa(c.PageSize))
+	return qry
+}
+
+func (c *Client) get(ctx context.Context, path string, qry url.Values, result interface{}) error {
+	if qry == nil {
+		qry = url.Values{}
+	}
+	if c.sudo != "" {
+		qry.Set("sudo", c.sudo)
+	}
+
+	u := c.URL.ResolveReference(&url.URL{Path: "api/v1/" + path, RawQuery: qry.Encode()})
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, req, result)
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+
+	req, ht := nethttp.TraceRequest(ot.GetTracer(ctx),
+		req.WithContext(ctx),
+		nethttp.OperationName("Gitea"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return errors.WithStack(&httpError{
+			URL:        req.URL,
+			StatusCode: resp.StatusCode,
+			Body:       bs,
+		})
+	}
+
+	if result != nil {
+		return json.Unmarshal(bs, result)
+	}
+	return nil
+}
+
+type httpError struct {
+	StatusCode int
+	URL        *url.URL
+	Body       []byte
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("Gitea API HTTP error: code=%d url=%q body=%q", e.StatusCode, e.URL, e.Body)
+}
+
+func (e *httpError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *httpError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsNotFound reports whether err is a Gitea API not found error.
+func IsNotFound(err error) bool {
+	switch e := errors.Cause(err).(type) {
+	case *httpError:
+		return e.NotFound()
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClient(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if have, want := r.Header.Get("Authorization"), "token secret"; have != want {
+			t.Errorf("have Authorization %q, want %q", have, want)
+		}
+		requests = append(requests, r.URL.String())
+
+		var v interface{}
+		switch r.URL.Path {
+		case "/gitea/api/v1/orgs/sourcegraph/repos":
+			// A full first page, so the client requests the second page.
+			repos := make([]*Repository, 0, DefaultPageSize)
+			if r.URL.Query().Get("page") == "1" {
+				for i := 0; i < DefaultPageSize; i++ {
+					repos = append(repos, &Repository{ID: int64(i), FullName: fmt.Sprintf("sourcegraph/repo-%d", i)})
+				}
+			}
+			v = repos
+		case "/gitea/api/v1/repos/search":
+			v = map[string]interface{}{
+				"ok":   true,
+				"data": []*Repository{{ID: 100, FullName: "alice/docs", Archived: true}},
+			}
+		case "/gitea/api/v1/repos/alice/docs/collaborators":
+			v = []*User{{ID: 2, Login: "bob"}}
+		case "/gitea/api/v1/orgs/sourcegraph":
+			v = &Organization{ID: 1, Username: "sourcegraph"}
+		case "/gitea/api/v1/repos/sourcegraph/docs/teams":
+			v = []*Team{{ID: 3, Name: "Owners", Permission: "owner"}}
+		case "/gitea/api/v1/teams/3/members":
+			v = []*User{{ID: 4, Login: "carol"}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL + "/gitea/")
+	cli := NewClient(u, "secret", nil)
+	ctx := context.Background()
+
+	repos, hasNextPage, err := cli.ListOrgRepos(ctx, "sourcegraph", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != DefaultPageSize || !hasNextPage {
+		t.Errorf("have %d repos and hasNextPage=%t, want %d and true", len(repos), hasNextPage, DefaultPageSize)
+	}
+	if repos, hasNextPage, err = cli.ListOrgRepos(ctx, "sourcegraph", 2); err != nil || len(repos) != 0 || hasNextPage {
+		t.Errorf("have %d repos, hasNextPage=%t and error %v for the last page", len(repos), hasNextPage, err)
+	}
+
+	repos, _, err = cli.Sudo("alice").SearchRepos(ctx, "docs", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*Repository{{ID: 100, FullName: "alice/docs", Archived: true}}, repos); diff != "" {
+		t.Error(diff)
+	}
+
+	users, _, err := cli.ListRepoCollaborators(ctx, "alice", "docs", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*User{{ID: 2, Login: "bob"}}, users); diff != "" {
+		t.Error(diff)
+	}
+
+	org, err := cli.GetOrg(ctx, "sourcegraph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&Organization{ID: 1, Username: "sourcegraph"}, org); diff != "" {
+		t.Error(diff)
+	}
+	if org, err = cli.GetOrg(ctx, "alice"); org != nil || err != nil {
+		t.Errorf("have organization %+v and error %v for a user, want nil", org, err)
+	}
+
+	teams, err := cli.ListRepoTeams(ctx, "sourcegraph", "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*Team{{ID: 3, Name: "Owners", Permission: "owner"}}, teams); diff != "" {
+		t.Error(diff)
+	}
+
+	users, _, err = cli.ListTeamMembers(ctx, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*User{{ID: 4, Login: "carol"}}, users); diff != "" {
+		t.Error(diff)
+	}
+
+	_, err = cli.GetRepo(ctx, "alice", "missing")
+	if !IsNotFound(err) {
+		t.Errorf("have error %v, want a not found error", err)
+	}
+
+	want := []string{
+		"/gitea/api/v1/orgs/sourcegraph/repos?limit=50&page=1",
+		"/gitea/api/v1/orgs/sourcegraph/repos?limit=50&page=2",
+		"/gitea/api/v1/repos/search?limit=50&page=1&q=docs&sudo=alice",
+		"/gitea/api/v1/repos/alice/docs/collaborators?limit=50&page=1",
+		"/gitea/api/v1/orgs/sourcegraph",
+		"/gitea/api/v1/orgs/alice",
+		"/gitea/api/v1/repos/sourcegraph/docs/teams",
+		"/gitea/api/v1/teams/3/members?limit=50&page=1",
+		"/gitea/api/v1/repos/alice/missing",
+	}
+	if diff := cmp.Diff(want, requests); diff != "" {
+		t.Errorf("requests:\n%s", diff)
+	}
+}
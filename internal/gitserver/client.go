@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,6 +30,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/actor"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/env"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc/gitolite"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/httpcli"
@@ -37,6 +39,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/vcs"
 )
 
+var serviceToken = env.Get("SRC_GITSERVER_SERVICE_TOKEN", "", "Token which authenticates this service to gitserver, for gitserverExecAllowlist. It must match the token of the service in SRC_GITSERVER_SERVICE_TOKENS of gitserver.")
+
 var requestMeter = metrics.NewRequestMeter("gitserver", "Total number of requests sent to gitserver.")
 
 // defaultTransport is the default transport used in the default client and the
@@ -61,12 +65,19 @@ func NewClient(cli httpcli.Doer) *Client {
 		Addrs: func(ctx context.Context) []string {
 			return conf.Get().ServiceConnections.GitServers
 		},
+		ReplicationFactor: func() int {
+			return conf.Get().GitserverReplicationFactor
+		},
+		RendezvousHashing: func() bool {
+			return conf.Get().GitserverRendezvousHashing
+		},
 		HTTPClient:  cli,
 		HTTPLimiter: parallel.NewRun(500),
 		// Use the binary name for UserAgent. This should effectively identify
 		// which service is making the request (excluding requests proxied via the
 		// frontend internal API)
-		UserAgent: filepath.Base(os.Args[0]),
+		UserAgent:    filepath.Base(os.Args[0]),
+		ServiceToken: serviceToken,
 	}
 }
 
@@ -83,9 +94,24 @@ type Client struct {
 	// concurrent use. It may return different results at different times.
 	Addrs func(ctx context.Context) []string
 
+	// ReplicationFactor is a function which should return the number of
+	// gitservers each repository is stored on. If nil, repositories are
+	// stored on a single gitserver.
+	ReplicationFactor func() int
+
+	// RendezvousHashing is a function which should return true if repositories
+	// are assigned to gitservers with rendezvous hashing (see AddrsForRepo). If
+	// nil, they are assigned with LegacyAddrForRepo.
+	RendezvousHashing func() bool
+
 	// UserAgent is a string identifing who the client is. It will be logged in
 	// the telemetry in gitserver.
 	UserAgent string
+
+	// ServiceToken authenticates the service to gitserver, which applies
+	// gitserverExecAllowlist to the service it belongs to. Unlike UserAgent,
+	// it can't be chosen freely by other clients.
+	ServiceToken string
 }
 
 // AddrForRepo returns the gitserver address to use for the given repo name.
@@ -101,13 +127,108 @@ func (c *Client) addrForKey(ctx context.Context, key string) string {
 	if len(addrs) == 0 {
 		panic("unexpected state: no gitserver addresses")
 	}
-	return addrForKey(addrs, key)
+	return addrForKey(addrs, key, c.rendezvousHashing())
+}
+
+func (c *Client) rendezvousHashing() bool {
+	return c.RendezvousHashing != nil && c.RendezvousHashing()
+}
+
+// addrForKey returns the address in addrs which key is assigned to. If
+// rendezvous is false, the key is hashed modulo the number of addresses, so
+// most keys move whenever an address is added or removed. Otherwise keys are
+// assigned with rendezvous hashing: each address is scored by hashing it
+// together with the key, and the address with the highest score wins. Adding
+// an address only moves the keys which it now wins, and removing an address
+// only moves the keys it had.
+func addrForKey(addrs []string, key string, rendezvous bool) string {
+	if !rendezvous {
+		sum := md5.Sum([]byte(key))
+		return addrs[binary.BigEndian.Uint64(sum[:])%uint64(len(addrs))]
+	}
+
+	var (
+		best      string
+		bestScore uint64
+	)
+	for i, addr := range addrs {
+		if score := addrScore(addr, key); i == 0 || score > bestScore {
+			best, bestScore = addr, score
+		}
+	}
+	return best
+}
+
+// AddrsForRepo returns addrs ordered by preference for repo. The first address
+// is the one AddrForRepo returns if the client uses the same value of
+// rendezvous, the others are ordered by their rendezvous hashing score.
+//
+// With rendezvous hashing, a repo only moves to an added address from the
+// address following it, so a gitserver can find the existing clone of a repo
+// it now owns on the addresses after its own.
+func AddrsForRepo(addrs []string, repo api.RepoName, rendezvous bool) []string {
+	key := string(protocol.NormalizeRepo(repo))
+	scores := make(map[string]uint64, len(addrs))
+	for _, addr := range addrs {
+		scores[addr] = addrScore(addr, key)
+	}
+	ranked := append([]string(nil), addrs...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	if !rendezvous && len(ranked) > 0 {
+		first := addrForKey(addrs, key, false)
+		for i, addr := range ranked {
+			if addr == first {
+				copy(ranked[1:i+1], ranked[:i])
+				ranked[0] = first
+				break
+			}
+		}
+	}
+	return ranked
+}
+
+// ReplicaAddrsForRepo returns the addresses of the gitservers repo is stored on,
+// in the order reads should try them. The first address is the one
+// AddrForRepo returns.
+func (c *Client) ReplicaAddrsForRepo(ctx context.Context, repo api.RepoName) []string {
+	factor := 1
+	if c.ReplicationFactor != nil {
+		factor = c.ReplicationFactor()
+	}
+	return ReplicaAddrs(c.Addrs(ctx), repo, factor, c.rendezvousHashing())
 }
 
-func addrForKey(addrs []string, key string) string {
-	sum := md5.Sum([]byte(key))
-	serverIndex := binary.BigEndian.Uint64(sum[:]) % uint64(len(addrs))
-	return addrs[serverIndex]
+// ReplicaAddrs returns the first factor addresses of AddrsForRepo. A factor
+// less than 1 is treated as 1.
+func ReplicaAddrs(addrs []string, repo api.RepoName, factor int, rendezvous bool) []string {
+	addrs = AddrsForRepo(addrs, repo, rendezvous)
+	if factor < 1 {
+		factor = 1
+	}
+	if len(addrs) > factor {
+		addrs = addrs[:factor]
+	}
+	return addrs
+}
+
+// LegacyAddrForRepo returns the address in addrs which repo is assigned to
+// without rendezvous hashing, when its name is hashed modulo the number of
+// addresses. After switching to rendezvous hashing, a gitserver which now
+// owns a repo can find an existing clone there if the addresses didn't change
+// since.
+func LegacyAddrForRepo(addrs []string, repo api.RepoName) string {
+	if len(addrs) == 0 {
+		return ""
+	}
+	return addrForKey(addrs, string(protocol.NormalizeRepo(repo)), false)
+}
+
+// addrScore is the rendezvous hashing score of key for addr.
+func addrScore(addr, key string) uint64 {
+	sum := md5.Sum([]byte(addr + "\x00" + key))
+	return binary.BigEndian.Uint64(sum[:])
 }
 
 // ArchiveOptions contains options for the Archive func.
@@ -145,6 +266,15 @@ func (a *archiveReader) Close() error {
 // ArchiveURL returns a URL from which an archive of the given Git repository can
 // be downloaded from.
 func (c *Client) ArchiveURL(ctx context.Context, repo Repo, opt ArchiveOptions) *url.URL {
+	return &url.URL{
+		Scheme:   "http",
+		Host:     c.AddrForRepo(ctx, repo.Name),
+		Path:     "/archive",
+		RawQuery: archiveQuery(repo, opt).Encode(),
+	}
+}
+
+func archiveQuery(repo Repo, opt ArchiveOptions) url.Values {
 	q := url.Values{
 		"repo":    {string(repo.Name)},
 		"treeish": {opt.Treeish},
@@ -154,13 +284,7 @@ func (c *Client) ArchiveURL(ctx context.Context, repo Repo, opt ArchiveOptions)
 	for _, path := range opt.Paths {
 		q.Add("path", path)
 	}
-
-	return &url.URL{
-		Scheme:   "http",
-		Host:     c.AddrForRepo(ctx, repo.Name),
-		Path:     "/archive",
-		RawQuery: q.Encode(),
-	}
+	return q
 }
 
 // Archive produces an archive from a Git repository.
@@ -182,8 +306,7 @@ func (c *Client) Archive(ctx context.Context, repo Repo, opt ArchiveOptions) (_
 		return nil, err
 	}
 
-	u := c.ArchiveURL(ctx, repo, opt)
-	resp, err := c.do(ctx, repo.Name, "GET", u.String(), nil)
+	resp, err := c.doRead(ctx, repo.Name, "GET", "archive?"+archiveQuery(repo, opt).Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -250,7 +373,7 @@ func (c *Cmd) sendExec(ctx context.Context) (_ io.ReadCloser, _ http.Header, err
 		EnsureRevision: c.EnsureRevision,
 		Args:           c.Args[1:],
 	}
-	resp, err := c.client.httpPost(ctx, repoName, "exec", req)
+	resp, err := c.client.doRead(ctx, repoName, "POST", "exec", req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -268,6 +391,11 @@ func (c *Cmd) sendExec(ctx context.Context) (_ io.ReadCloser, _ http.Header, err
 		resp.Body.Close()
 		return nil, nil, &vcs.RepoNotExistError{Repo: repoName, CloneInProgress: payload.CloneInProgress, CloneProgress: payload.CloneProgress}
 
+	case http.StatusForbidden:
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("git command denied: %s", bytes.TrimSpace(body))
+
 	default:
 		resp.Body.Close()
 		return nil, nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
@@ -486,6 +614,7 @@ func (c *Client) ListCloned(ctx context.Context) ([]string, error) {
 		repos []string
 	)
 	addrs := c.Addrs(ctx)
+	rendezvous := c.rendezvousHashing()
 	for _, addr := range addrs {
 		wg.Add(1)
 		go func(addr string) {
@@ -496,7 +625,7 @@ func (c *Client) ListCloned(ctx context.Context) ([]string, error) {
 			if len(r) > 0 {
 				filtered := r[:0]
 				for _, repo := range r {
-					if addrForKey(addrs, repo) == addr {
+					if addrForKey(addrs, repo, rendezvous) == addr {
 						filtered = append(filtered, repo)
 					}
 				}
@@ -514,6 +643,66 @@ func (c *Client) ListCloned(ctx context.Context) ([]string, error) {
 	return repos, err
 }
 
+// MockExecAuditLog mocks (*Client).ExecAuditLog for tests.
+var MockExecAuditLog func() ([]protocol.ExecAuditRecord, error)
+
+// ExecAuditLog returns the most recent exec requests of all gitservers, most
+// recent first.
+//
+// If some gitservers fail, the records of the others are returned along with
+// a *multierror.Error which has an error per failed gitserver.
+func (c *Client) ExecAuditLog(ctx context.Context) ([]protocol.ExecAuditRecord, error) {
+	if MockExecAuditLog != nil {
+		return MockExecAuditLog()
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		err     = new(multierror.Error)
+		records []protocol.ExecAuditRecord
+	)
+	for _, addr := range c.Addrs(ctx) {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			r, e := c.doExecAuditOne(ctx, addr)
+			mu.Lock()
+			defer mu.Unlock()
+			if e != nil {
+				err = multierror.Append(err, errors.Wrapf(e, "gitserver %s", addr))
+				return
+			}
+			records = append(records, r...)
+		}(addr)
+	}
+	wg.Wait()
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.After(records[j].Time)
+	})
+	return records, err.ErrorOrNil()
+}
+
+func (c *Client) doExecAuditOne(ctx context.Context, addr string) ([]protocol.ExecAuditRecord, error) {
+	req, err := http.NewRequest("GET", "http://"+addr+"/exec-audit", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var records []protocol.ExecAuditRecord
+	err = json.NewDecoder(resp.Body).Decode(&records)
+	return records, err
+}
+
 // GetGitolitePhabricatorMetadata returns Phabricator metadata for a Gitolite repository fetched via
 // a user-provided command.
 func (c *Client) GetGitolitePhabricatorMetadata(ctx context.Context, gitoliteHost string, repoName api.RepoName) (*protocol.GitolitePhabricatorMetadataResponse, error) {
@@ -805,12 +994,31 @@ func (c *Client) RepoInfo(ctx context.Context, repos ...api.RepoName) (*protocol
 	return &res, err.ErrorOrNil()
 }
 
-// Remove removes the repository clone from gitserver.
+// Remove removes the repository clone from all gitservers it is stored on.
 func (c *Client) Remove(ctx context.Context, repo api.RepoName) error {
 	req := &protocol.RepoDeleteRequest{
 		Repo: repo,
 	}
-	resp, err := c.httpPost(ctx, repo, "delete", req)
+	addrs := c.ReplicaAddrsForRepo(ctx, repo)
+	if len(addrs) == 0 {
+		return errors.New("no gitservers configured")
+	}
+	if err := c.removeFrom(ctx, addrs[0], req); err != nil {
+		return err
+	}
+	// Removing the replicas is best-effort: an unavailable replica must not
+	// prevent the removal of the repository. A stale replica is only used if
+	// the first gitserver is unavailable.
+	for _, addr := range addrs[1:] {
+		if err := c.removeFrom(ctx, addr, req); err != nil {
+			log15.Warn("failed to remove repo from replica", "repo", repo, "replica", addr, "error", err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) removeFrom(ctx context.Context, addr string, req *protocol.RepoDeleteRequest) error {
+	resp, err := c.doAddr(ctx, addr, req.Repo, "POST", "delete", req)
 	if err != nil {
 		return err
 	}
@@ -830,6 +1038,41 @@ func (c *Client) httpPost(ctx context.Context, repo api.RepoName, op string, pay
 // do performs a request to a gitserver, sharding based on the given
 // repo name (the repo name is otherwise not used).
 func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, payload interface{}) (resp *http.Response, err error) {
+	var addr string
+	if !strings.HasPrefix(op, "http") {
+		addr = c.AddrForRepo(ctx, repo)
+	}
+	return c.doAddr(ctx, addr, repo, method, op, payload)
+}
+
+// doRead is like do for requests which only read repo. If the gitserver of
+// repo can't be reached, the request is sent to the next gitserver repo is
+// replicated to.
+func (c *Client) doRead(ctx context.Context, repo api.RepoName, method, op string, payload interface{}) (resp *http.Response, err error) {
+	addrs := c.ReplicaAddrsForRepo(ctx, repo)
+	for i, addr := range addrs {
+		resp, err = c.doAddr(ctx, addr, repo, method, op, payload)
+		if err == nil || ctx.Err() != nil || i == len(addrs)-1 {
+			break
+		}
+		log15.Warn("gitserver unavailable, failing over to replica", "repo", repo, "addr", addr, "replica", addrs[i+1], "error", err)
+		failoverCounter.Inc()
+	}
+	return resp, err
+}
+
+var failoverCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "src_gitserver_client_failover_total",
+	Help: "Times that a read request was retried on a replica because a gitserver was unavailable.",
+})
+
+func init() {
+	prometheus.MustRegister(failoverCounter)
+}
+
+// doAddr performs a request to the gitserver at addr. If op is a URL, addr is
+// ignored.
+func (c *Client) doAddr(ctx context.Context, addr string, repo api.RepoName, method, op string, payload interface{}) (resp *http.Response, err error) {
 	span, ctx := ot.StartSpanFromContext(ctx, "Client.do")
 	defer func() {
 		span.LogKV("repo", string(repo), "method", method, "op", op)
@@ -847,7 +1090,7 @@ func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, p
 
 	uri := op
 	if !strings.HasPrefix(op, "http") {
-		uri = "http://" + c.AddrForRepo(ctx, repo) + "/" + op
+		uri = "http://" + addr + "/" + op
 	}
 
 	req, err := http.NewRequest(method, uri, bytes.NewReader(reqBody))
@@ -858,6 +1101,9 @@ func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, p
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", c.UserAgent)
 	req.Header.Set("X-Sourcegraph-Actor", userFromContext(ctx))
+	if c.ServiceToken != "" {
+		req.Header.Set("X-Sourcegraph-Service-Token", c.ServiceToken)
+	}
 	req = req.WithContext(ctx)
 
 	if c.HTTPLimiter != nil {
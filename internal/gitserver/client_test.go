@@ -26,34 +26,173 @@ import (
 )
 
 func TestClient_ListCloned(t *testing.T) {
-	addrs := []string{"gitserver-0", "gitserver-1"}
+	for _, tc := range []struct {
+		name       string
+		rendezvous bool
+		cloned     map[string]string
+		want       []string
+	}{
+		{
+			name: "legacy",
+			cloned: map[string]string{
+				"gitserver-0": `["repo0-a", "repo0-b"]`,
+				"gitserver-1": `["repo1-a", "repo1-b"]`,
+			},
+			want: []string{"repo0-a", "repo1-a", "repo1-b"},
+		},
+		{
+			name:       "rendezvous",
+			rendezvous: true,
+			cloned: map[string]string{
+				"gitserver-0": `["repo-a", "repo-d"]`,
+				"gitserver-1": `["repo-b", "repo-c"]`,
+			},
+			want: []string{"repo-b", "repo-c", "repo-d"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			addrs := []string{"gitserver-0", "gitserver-1"}
+			cli := &gitserver.Client{
+				Addrs:             func(ctx context.Context) []string { return addrs },
+				RendezvousHashing: func() bool { return tc.rendezvous },
+				HTTPClient: httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
+					for _, addr := range addrs {
+						if r.URL.String() == "http://"+addr+"/list?cloned" {
+							return &http.Response{
+								Body: ioutil.NopCloser(bytes.NewBufferString(tc.cloned[addr])),
+							}, nil
+						}
+					}
+					return nil, fmt.Errorf("unexpected url: %s", r.URL.String())
+				}),
+			}
+
+			got, err := cli.ListCloned(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(got)
+			if !cmp.Equal(tc.want, got, cmpopts.EquateEmpty()) {
+				t.Errorf("mismatch for (-want +got):\n%s", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
+
+func TestAddrsForRepo(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+	scaled := append(addrs, "gitserver-3")
+	cli := &gitserver.Client{
+		Addrs:             func(ctx context.Context) []string { return scaled },
+		RendezvousHashing: func() bool { return true },
+	}
+	legacyCli := &gitserver.Client{
+		Addrs: func(ctx context.Context) []string { return scaled },
+	}
+
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		repo := api.RepoName(fmt.Sprintf("github.com/foo/repo-%d", i))
+		ranked := gitserver.AddrsForRepo(scaled, repo, true)
+		if got := cli.AddrForRepo(context.Background(), repo); got != ranked[0] {
+			t.Fatalf("%s: AddrForRepo returned %q, want the first of %v", repo, got, ranked)
+		}
+
+		// Without rendezvous hashing, repos stay on their legacy address
+		// and the other addresses keep their order.
+		legacy := gitserver.AddrsForRepo(scaled, repo, false)
+		if got, want := legacyCli.AddrForRepo(context.Background(), repo), gitserver.LegacyAddrForRepo(scaled, repo); got != want || legacy[0] != want {
+			t.Fatalf("%s: AddrForRepo returned %q, ranked %v, want %q first", repo, got, legacy, want)
+		}
+		var others []string
+		for _, addr := range ranked {
+			if addr != legacy[0] {
+				others = append(others, addr)
+			}
+		}
+		if diff := cmp.Diff(others, legacy[1:]); diff != "" {
+			t.Fatalf("%s: other addresses (-want +got):\n%s", repo, diff)
+		}
+
+		// Adding an address only moves repos to it, from the address
+		// following it.
+		before := gitserver.AddrsForRepo(addrs, repo, true)[0]
+		if ranked[0] == before {
+			continue
+		}
+		moved++
+		if ranked[0] != "gitserver-3" || ranked[1] != before {
+			t.Fatalf("%s: moved from %q to %q, ranked %v", repo, before, ranked[0], ranked)
+		}
+	}
+
+	// Roughly a quarter of the repos move to the added address.
+	if moved < 150 || moved > 350 {
+		t.Fatalf("%d of 1000 repos moved, want about 250", moved)
+	}
+}
+
+func TestLegacyAddrForRepo(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+	for repo, want := range map[api.RepoName]string{
+		"github.com/foo/bar":                 "gitserver-2",
+		"github.com/foo/baz":                 "gitserver-1",
+		"github.com/sourcegraph/sourcegraph": "gitserver-1",
+		"GitHub.com/Foo/Bar":                 "gitserver-2",
+	} {
+		if got := gitserver.LegacyAddrForRepo(addrs, repo); got != want {
+			t.Errorf("%s: got %q, want %q", repo, got, want)
+		}
+	}
+}
+
+func TestClient_failover(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2"}
+	repo := api.RepoName("github.com/foo/bar")
+	replicas := gitserver.ReplicaAddrs(addrs, repo, 2, false)
+
+	var requested []string
 	cli := &gitserver.Client{
-		Addrs: func(ctx context.Context) []string { return addrs },
+		Addrs:             func(ctx context.Context) []string { return addrs },
+		ReplicationFactor: func() int { return 2 },
 		HTTPClient: httpcli.DoerFunc(func(r *http.Request) (*http.Response, error) {
-			switch r.URL.String() {
-			case "http://gitserver-0/list?cloned":
-				return &http.Response{
-					Body: ioutil.NopCloser(bytes.NewBufferString(`["repo0-a", "repo0-b"]`)),
-				}, nil
-			case "http://gitserver-1/list?cloned":
-				return &http.Response{
-					Body: ioutil.NopCloser(bytes.NewBufferString(`["repo1-a", "repo1-b"]`)),
-				}, nil
-			default:
-				return nil, fmt.Errorf("unexpected url: %s", r.URL.String())
+			requested = append(requested, r.URL.Host)
+			if r.URL.Host == replicas[0] {
+				return nil, errors.New("connection refused")
 			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewBufferString("out")),
+				Trailer:    http.Header{"X-Exec-Exit-Status": {"0"}},
+			}, nil
 		}),
 	}
 
-	want := []string{"repo0-a", "repo1-a", "repo1-b"}
-	got, err := cli.ListCloned(context.Background())
+	cmd := func() *gitserver.Cmd {
+		c := cli.Command("git", "log")
+		c.Repo = gitserver.Repo{Name: repo}
+		return c
+	}
+
+	out, err := cmd().Output(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
-	sort.Strings(got)
-	sort.Strings(want)
-	if !cmp.Equal(want, got, cmpopts.EquateEmpty()) {
-		t.Errorf("mismatch for (-want +got):\n%s", cmp.Diff(want, got))
+	if string(out) != "out" {
+		t.Errorf("got output %q, want %q", out, "out")
+	}
+	if !cmp.Equal(requested, replicas) {
+		t.Errorf("got requests to %v, want %v", requested, replicas)
+	}
+
+	// Without replicas the error is returned.
+	requested = nil
+	cli.ReplicationFactor = nil
+	if _, err := cmd().Output(context.Background()); err == nil {
+		t.Fatal("expected an error without replicas")
+	}
+	if !cmp.Equal(requested, replicas[:1]) {
+		t.Errorf("got requests to %v, want %v", requested, replicas[:1])
 	}
 }
 
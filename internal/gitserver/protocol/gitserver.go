@@ -102,6 +102,63 @@ type RepoDeleteRequest struct {
 	Repo api.RepoName
 }
 
+// ReplicateRequest is a request to update a replica of a repository from the
+// gitserver which fetches it from the code host, or clone it from there if it
+// doesn't exist.
+type ReplicateRequest struct {
+	// Repo is the repository to update.
+	Repo api.RepoName
+	// URL is the repository's remote URL on the code host.
+	URL string
+	// Source is the address of the gitserver to fetch the repository from.
+	Source string
+}
+
+// ExecAuditRecord is the record of an exec request in the audit log of
+// gitserver.
+type ExecAuditRecord struct {
+	Time        time.Time     // when the request started
+	Actor       string        // the user ID, "internal" or "0" for anonymous users
+	Service     string        // the authenticated service which sent the request, such as "frontend", or "" if unauthenticated
+	Repo        api.RepoName  // the repository the command ran in
+	Args        []string      // the arguments of git
+	Duration    time.Duration // how long the request took
+	ExitStatus  int           // the exit status of git, or -1 if it didn't run
+	StdoutBytes int64         // the number of bytes git wrote to stdout
+	Status      string        // the status of the request, such as "denied" or "clone-in-progress"
+}
+
+// BackupRequest is a request to back up repositories as git bundles.
+type BackupRequest struct {
+	// Repos are the repositories to back up.
+	Repos []api.RepoName
+	// All backs up all repositories on the gitserver instead of Repos. The
+	// backup runs in the background, so the request has no response.
+	All bool
+}
+
+// BackupResponse is the response to a BackupRequest.
+type BackupResponse struct {
+	// Backups are the repositories which were backed up.
+	Backups []api.RepoName
+	// Skipped maps the repositories which can't be backed up, such as
+	// partial clones, to the reason.
+	Skipped map[api.RepoName]string
+	// Errors maps the repositories which failed to back up to the error.
+	Errors map[api.RepoName]string
+}
+
+// RestoreRequest is a request to restore a repository from its backup.
+type RestoreRequest struct {
+	// Repo is the repository to restore.
+	Repo api.RepoName
+	// URL is the repository's remote URL on the code host, which is fetched
+	// from afterwards.
+	URL string
+	// Overwrite replaces the repository if it exists.
+	Overwrite bool
+}
+
 // RepoInfoRequest is a request for information about multiple repositories on gitserver.
 type RepoInfoRequest struct {
 	// Repos are the repositories to get information about.
@@ -122,6 +179,41 @@ type RepoInfo struct {
 	// recloned automatically, so this time is likely to move forward
 	// periodically.
 	CloneTime *time.Time
+
+	// Maintenance is the status of the last maintenance of the repository,
+	// or nil if it was never maintained.
+	Maintenance *RepoMaintenance
+
+	// SyncHistory are the most recent clones and fetches of the repository,
+	// most recent first. It includes failed clones of repositories which are
+	// not cloned.
+	SyncHistory []RepoSync
+
+	// Size is the size of the repository on disk in bytes, or 0 if it is
+	// not known yet.
+	Size int64
+}
+
+// RepoSync is a clone or fetch of a repository from its remote.
+type RepoSync struct {
+	Type       string    // "clone" or "fetch"
+	StartedAt  time.Time // when the sync started
+	FinishedAt time.Time // when the sync finished
+	Objects    int       // the number of objects received
+	Bytes      int64     // the number of bytes received
+	Error      string    // the error of a failed sync, with credentials redacted
+}
+
+// RepoMaintenance is the status of the maintenance of a repository, which runs
+// tasks such as git repack once the repository accumulated enough loose
+// objects or packs.
+type RepoMaintenance struct {
+	FinishedAt   time.Time // when the maintenance finished
+	Tasks        []string  // the tasks which ran, such as "repack"
+	LooseObjects int       // the number of loose objects before the maintenance
+	Packs        int       // the number of packs before the maintenance
+	LooseRefs    int       // the number of loose refs before the maintenance
+	Error        string    // the error of the task which failed, if any
 }
 
 // RepoInfoResponse is the response to a repository information request
@@ -170,6 +262,27 @@ type CreateCommitFromPatchRequest struct {
 	// GitApplyArgs are the arguments that will be passed to `git apply` along
 	// with `--cached`.
 	GitApplyArgs []string
+	// Commits is a series of commits to create on top of BaseCommit, each
+	// from its own patch. If set, Patch and CommitInfo are ignored.
+	Commits []PatchCommit
+	// Sign specifies whether the commits are signed with the signing key
+	// configured for gitserver.
+	Sign bool
+	// PushRemoteURL is the URL of the repository to push the target ref to,
+	// such as a fork of the repository. It must be on the same host as the
+	// remote of the repository. If empty, the target ref is pushed to the
+	// remote of the repository.
+	PushRemoteURL string
+}
+
+// PatchCommit is a commit of a series created from patches.
+type PatchCommit struct {
+	// Patch is the diff contents of the commit, relative to the previous
+	// commit of the series.
+	Patch string
+	// CommitInfo is the information that will be used when creating the
+	// commit.
+	CommitInfo PatchCommitInfo
 }
 
 // PatchCommitInfo will be used for commit information when creating a commit from a patch
@@ -180,6 +293,14 @@ type PatchCommitInfo struct {
 	CommitterName  string
 	CommitterEmail string
 	Date           time.Time
+	// CoAuthors are added to the message as Co-authored-by trailers.
+	CoAuthors []PatchCommitAuthor
+}
+
+// PatchCommitAuthor is an author of a commit created from a patch.
+type PatchCommitAuthor struct {
+	Name  string
+	Email string
 }
 
 // CreateCommitFromPatchResponse is the response type returned after creating
@@ -174,6 +174,37 @@ func (c *Client) EnqueueRepoUpdate(ctx context.Context, repo gitserver.Repo) (*p
 	return &res, nil
 }
 
+// MockWebhook mocks (*Client).Webhook for tests.
+var MockWebhook func(ctx context.Context, req protocol.WebhookRequest) (*protocol.WebhookResult, error)
+
+// Webhook forwards a webhook event of a code host which changes repositories,
+// such as a push, to repo-updater.
+func (c *Client) Webhook(ctx context.Context, req protocol.WebhookRequest) (*protocol.WebhookResult, error) {
+	if MockWebhook != nil {
+		return MockWebhook(ctx, req)
+	}
+
+	resp, err := c.httpPost(ctx, "webhook", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+
+	var res protocol.WebhookResult
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return nil, errors.New(string(bs))
+	} else if err = json.Unmarshal(bs, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
 // MockEnqueueChangesetSync mocks (*Client).EnqueueChangesetSync for tests.
 var MockEnqueueChangesetSync func(ctx context.Context, ids []int64) error
 
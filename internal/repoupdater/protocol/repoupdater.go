@@ -157,6 +157,39 @@ type RepoUpdateResponse struct {
 	URL string `json:"url"`
 }
 
+// WebhookRequest is a webhook event of a code host which changes repositories,
+// such as a push. The frontend receives the event and forwards it to
+// repo-updater.
+type WebhookRequest struct {
+	// Kind is the kind of the external services which send the event, such
+	// as GITHUB.
+	Kind string
+	// ExternalServiceID is the ID of the external service in the webhook
+	// URL, or 0 if the URL has none.
+	ExternalServiceID int64
+	// Event is the type of the event, from the X-GitHub-Event,
+	// X-Gitlab-Event or X-Event-Key header.
+	Event string
+	// Signature authenticates the payload. It is the X-Hub-Signature header
+	// for GitHub and Bitbucket Server, and the X-Gitlab-Token header for
+	// GitLab.
+	Signature string
+	// Payload is the body of the webhook request.
+	Payload []byte
+}
+
+// WebhookResult is the result of a WebhookRequest.
+type WebhookResult struct {
+	// Updated are the repositories whose update was scheduled.
+	Updated []api.RepoName
+	// Synced are the repositories which were synced with the code host,
+	// because they were renamed, deleted or otherwise changed.
+	Synced []api.RepoName
+	// ErrorUnauthorized is true if no external service of the kind has a
+	// webhook secret which authenticates the payload.
+	ErrorUnauthorized bool
+}
+
 // ChangesetSyncRequest is a request to sync a number of changesets
 type ChangesetSyncRequest struct {
 	IDs []int64
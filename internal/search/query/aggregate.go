@@ -0,0 +1,38 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The values of the aggregate: field. A query with an aggregate: field
+// returns the number of results in each group instead of only a list of
+// results.
+const (
+	// AggregateRepo groups results by repository.
+	AggregateRepo = "repo"
+
+	// AggregateDir groups file results by the directory containing the file.
+	AggregateDir = "dir"
+
+	// AggregateCapture groups content matches by the text matched by the
+	// first capture group of the regular expression pattern.
+	AggregateCapture = "capture"
+
+	// AggregateAuthor groups commit and diff results by the commit author.
+	AggregateAuthor = "author"
+)
+
+// AggregateModes are the valid values of the aggregate: field.
+var AggregateModes = []string{AggregateRepo, AggregateDir, AggregateCapture, AggregateAuthor}
+
+// ValidateAggregateMode returns an error if mode is not a valid value of the
+// aggregate: field.
+func ValidateAggregateMode(mode string) error {
+	for _, m := range AggregateModes {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid value %q for field %q, use one of %s", mode, FieldAggregate, strings.Join(AggregateModes, ", "))
+}
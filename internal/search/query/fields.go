@@ -36,6 +36,8 @@ var allFields = map[string]struct{}{
 	FieldMax:                empty,
 	FieldTimeout:            empty,
 	FieldCombyRule:          empty,
+	FieldAggregate:          empty,
+	"select":                empty,
 	FieldRev:                empty,
 	"revision":              empty,
 }
@@ -1,12 +1,16 @@
 package query
 
 import (
+	"regexp"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
 
+// nearKeyword matches a near(N) keyword, see parser.scanNear.
+var nearKeyword = regexp.MustCompile(`(?i)\snear\(\d+\)\s`)
+
 // ScanAnyPatternLiteral consumes all characters up to a whitespace character
 // and returns the string and how much it consumed.
 func ScanAnyPatternLiteral(buf []byte) (scanned string, count int) {
@@ -116,9 +120,9 @@ loop:
 	}
 
 	scanned = string(result)
-	if ContainsAndOrKeyword(scanned) {
-		// Reject if we scanned 'and' or 'or'. Preceding parentheses
-		// likely refer to a group, not a pattern.
+	if ContainsAndOrKeyword(scanned) || nearKeyword.MatchString(scanned) {
+		// Reject if we scanned 'and', 'or' or 'near(N)'. Preceding
+		// parentheses likely refer to a group, not a pattern.
 		return "", 0, false
 	}
 	return scanned, count, balanced == 0
@@ -238,7 +242,7 @@ loop:
 				}
 			}
 			break loop
-		case p.matchKeyword(AND), p.matchKeyword(OR):
+		case p.matchKeyword(AND), p.matchKeyword(OR), p.matchNear():
 			// Caller advances.
 			break loop
 		case p.matchUnaryKeyword(NOT):
@@ -29,6 +29,11 @@ func (*BaseMapper) MapNodes(mapper Mapper, nodes []Node) []Node {
 				mapped = append(mapped, result)
 			}
 		case Operator:
+			if v.Kind == Near {
+				// Near operators keep their distance, see withOperands.
+				mapped = append(mapped, v.withOperands(mapper.MapNodes(mapper, v.Operands))...)
+				continue
+			}
 			if result := mapper.MapOperator(mapper, v.Kind, v.Operands); result != nil {
 				mapped = append(mapped, result...)
 			}
@@ -16,7 +16,8 @@ import (
 Parser implements a parser for the following grammar:
 
 OrTerm     → AndTerm { OR AndTerm }
-AndTerm    → Term { AND Term }
+AndTerm    → NearTerm { AND NearTerm }
+NearTerm   → Term [ NEAR(N) Term ]
 Term       → (OrTerm) | Parameters
 Parameters → Parameter { " " Parameter }
 */
@@ -58,6 +59,7 @@ const (
 	Or operatorKind = iota
 	And
 	Concat
+	Near
 )
 
 // Operator is a nonterminal node of kind Kind with child nodes Operands.
@@ -65,6 +67,10 @@ type Operator struct {
 	Kind       operatorKind
 	Operands   []Node
 	Annotation Annotation
+
+	// Distance is the maximum number of lines between matches of the two
+	// operands of a Near operator, as in "lock near(5) unlock".
+	Distance int
 }
 
 func (node Pattern) String() string {
@@ -103,6 +109,8 @@ func (node Operator) String() string {
 		kind = "and"
 	case Concat:
 		kind = "concat"
+	case Near:
+		kind = fmt.Sprintf("near:%d", node.Distance)
 	}
 
 	return fmt.Sprintf("(%s %s)", kind, strings.Join(result, " "))
@@ -120,6 +128,7 @@ const (
 	DQUOTE keyword = "\""
 	SLASH  keyword = "/"
 	NOT    keyword = "not"
+	NEAR   keyword = "near"
 )
 
 func isSpace(buf []byte) bool {
@@ -253,6 +262,53 @@ func (p *parser) matchUnaryKeyword(keyword keyword) bool {
 	return strings.EqualFold(v, string(keyword))
 }
 
+// scanNear scans a near(N) keyword preceded and followed by whitespace at the
+// current position. It does not advance the position.
+func (p *parser) scanNear() (distance, advance int, ok bool) {
+	if p.pos == 0 || !isSpace(p.buf[p.pos-1:p.pos]) {
+		return 0, 0, false
+	}
+	if !p.match(NEAR) {
+		return 0, 0, false
+	}
+	buf := p.buf[p.pos+len(NEAR):]
+	if len(buf) == 0 || buf[0] != '(' {
+		return 0, 0, false
+	}
+	end := 1
+	for end < len(buf) && '0' <= buf[end] && buf[end] <= '9' {
+		end++
+	}
+	if end == 1 || end >= len(buf) || buf[end] != ')' {
+		return 0, 0, false
+	}
+	if end+1 >= len(buf) || !isSpace(buf[end+1:end+2]) {
+		return 0, 0, false
+	}
+	distance, err := strconv.Atoi(string(buf[1:end]))
+	if err != nil {
+		return 0, 0, false
+	}
+	return distance, len(NEAR) + end + 1, true
+}
+
+// matchNear returns whether a near(N) keyword is at the current position.
+func (p *parser) matchNear() bool {
+	_, _, ok := p.scanNear()
+	return ok
+}
+
+// expectNear returns the distance N of a near(N) keyword at the current
+// position, and advances the position if it succeeds.
+func (p *parser) expectNear() (int, bool) {
+	distance, advance, ok := p.scanNear()
+	if !ok {
+		return 0, false
+	}
+	p.pos += advance
+	return distance, true
+}
+
 // skipSpaces advances the input and places the parser position at the next
 // non-space value.
 func (p *parser) skipSpaces() error {
@@ -739,7 +795,7 @@ loop:
 				}
 			}
 			break loop
-		case p.matchKeyword(AND), p.matchKeyword(OR):
+		case p.matchKeyword(AND), p.matchKeyword(OR), p.matchNear():
 			// Caller advances.
 			break loop
 		case p.matchUnaryKeyword(NOT):
@@ -834,15 +890,88 @@ func newOperator(nodes []Node, kind operatorKind) []Node {
 	return []Node{Operator{Kind: kind, Operands: reduced}}
 }
 
-// parseAnd parses and-expressions.
-func (p *parser) parseAnd() ([]Node, error) {
-	var left []Node
-	var err error
+// parseLeaves scans for consecutive leaf nodes with the leaf parser of the
+// search type.
+func (p *parser) parseLeaves() ([]Node, error) {
 	if p.leafParser == SearchTypeRegex {
-		left, err = p.parseLeavesRegexp()
-	} else {
-		left, err = p.parseLeavesLiteral()
+		return p.parseLeavesRegexp()
 	}
+	return p.parseLeavesLiteral()
+}
+
+// parseNear parses near-expressions. Near operators have higher precedence
+// than And operators, and only relate two search patterns.
+func (p *parser) parseNear() ([]Node, error) {
+	left, err := p.parseLeaves()
+	if err != nil {
+		return nil, err
+	}
+	distance, ok := p.expectNear()
+	if !ok {
+		return left, nil
+	}
+	if left == nil {
+		return nil, &ExpectedOperand{Msg: fmt.Sprintf("expected operand before near at %d", p.pos)}
+	}
+	right, err := p.parseLeaves()
+	if err != nil {
+		return nil, err
+	}
+	if right == nil {
+		return nil, &ExpectedOperand{Msg: fmt.Sprintf("expected operand at %d", p.pos)}
+	}
+	if p.matchNear() {
+		return nil, errors.New("near(N) can only relate two search patterns. Combine near(N) expressions with and instead, as in (a near(5) b) and (b near(5) c)")
+	}
+	return newNearOperator(left, right, distance)
+}
+
+// newNearOperator constructs a Near operator relating the search patterns of
+// the leaf nodes left and right. Parameters of the leaf nodes are hoisted
+// out, so that "repo:foo lock near(5) unlock" scopes the whole expression.
+func newNearOperator(left, right []Node, distance int) ([]Node, error) {
+	var parameters, patterns []Node
+	for _, side := range [][]Node{left, right} {
+		var pattern []Node
+		for _, node := range side {
+			operands := []Node{node}
+			if operator, ok := node.(Operator); ok && operator.Kind == And {
+				operands = operator.Operands
+			}
+			for _, operand := range operands {
+				if _, ok := operand.(Parameter); ok {
+					parameters = append(parameters, operand)
+				} else {
+					pattern = append(pattern, operand)
+				}
+			}
+		}
+		if len(pattern) != 1 || containsAndOrExpression(pattern) || containsNegatedPattern(pattern) || !isPatternExpression(pattern) {
+			return nil, errors.New("the operands of near(N) must be search patterns, as in lock near(5) unlock")
+		}
+		patterns = append(patterns, pattern[0])
+	}
+	near := Operator{Kind: Near, Operands: patterns, Distance: distance}
+	return newOperator(append(parameters, near), And), nil
+}
+
+// withOperands returns node with its operands replaced by operands, reducing
+// them as needed. Near operators are not reduced, so that they keep their
+// distance, unless they lost an operand.
+func (node Operator) withOperands(operands []Node) []Node {
+	if node.Kind == Near {
+		if len(operands) != 2 {
+			return newOperator(operands, And)
+		}
+		node.Operands = operands
+		return []Node{node}
+	}
+	return newOperator(operands, node.Kind)
+}
+
+// parseAnd parses and-expressions.
+func (p *parser) parseAnd() ([]Node, error) {
+	left, err := p.parseNear()
 	if err != nil {
 		return nil, err
 	}
@@ -792,6 +792,36 @@ func TestParse(t *testing.T) {
 			WantGrammar:   `(and "repohascommitafter:7 days" "foo")`,
 			WantHeuristic: Same,
 		},
+		{
+			Input:         `lock near(5) unlock`,
+			WantGrammar:   `(near:5 "lock" "unlock")`,
+			WantHeuristic: Same,
+		},
+		{
+			Input:         `repo:foo lock near(5) defer unlock and file:bar`,
+			WantGrammar:   `(and "repo:foo" (near:5 "lock" (concat "defer" "unlock")) "file:bar")`,
+			WantHeuristic: Same,
+		},
+		{
+			Input:         `(a near(0) b) or c`,
+			WantGrammar:   `(or (near:0 "a" "b") "c")`,
+			WantHeuristic: Same,
+		},
+		{
+			Input:         `a near(1) b near(2) c`,
+			WantGrammar:   Spec(`near(N) can only relate two search patterns. Combine near(N) expressions with and instead, as in (a near(5) b) and (b near(5) c)`),
+			WantHeuristic: Same,
+		},
+		{
+			Input:         `(a or b) near(1) c`,
+			WantGrammar:   Spec(`the operands of near(N) must be search patterns, as in lock near(5) unlock`),
+			WantHeuristic: Same,
+		},
+		{
+			Input:         `near(1) a near(2)`,
+			WantGrammar:   `(concat "near" "1" "a" "near" "2")`,
+			WantHeuristic: `(concat "near(1)" "a" "near(2)")`,
+		},
 		// Fringe tests cases at the boundary of heuristics and invalid syntax.
 		{
 			Input:         `(0(F)(:())(:())(<0)0()`,
@@ -0,0 +1,123 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// File predicates are values of the file: field which select files by a
+// property other than their path, for example file:contains(regexp). They can
+// be negated like other file: values, except for the size bounds.
+const (
+	// FilePredicateContains selects files whose content matches a regular
+	// expression.
+	FilePredicateContains = "contains"
+
+	// FilePredicateHasOwner selects files owned by someone according to the
+	// CODEOWNERS file of the repository.
+	FilePredicateHasOwner = "has.owner"
+
+	// FilePredicateSizeMin and FilePredicateSizeMax select files by their
+	// size in bytes, such as file:size.max(10kb). The bounds are inclusive.
+	FilePredicateSizeMin = "size.min"
+	FilePredicateSizeMax = "size.max"
+)
+
+var filePredicateRegexp = regexp.MustCompile(`^(` + strings.Join([]string{
+	regexp.QuoteMeta(FilePredicateContains),
+	regexp.QuoteMeta(FilePredicateHasOwner),
+	regexp.QuoteMeta(FilePredicateSizeMin),
+	regexp.QuoteMeta(FilePredicateSizeMax),
+}, "|") + `)\((.*)\)$`)
+
+// FilePredicate is a predicate in the value of a file: field.
+type FilePredicate struct {
+	Name     string
+	Argument string
+}
+
+func (p FilePredicate) String() string {
+	return fmt.Sprintf("%s(%s)", p.Name, p.Argument)
+}
+
+// ParseFilePredicate parses the value of a file: field as a file predicate.
+// It returns false if value is not a predicate, but a path pattern.
+func ParseFilePredicate(value string) (FilePredicate, bool) {
+	m := filePredicateRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return FilePredicate{}, false
+	}
+	return FilePredicate{Name: m[1], Argument: m[2]}, true
+}
+
+// PartitionFilePredicates splits values of file: fields into path patterns and
+// file predicates.
+func PartitionFilePredicates(values []string) (patterns []string, predicates []FilePredicate) {
+	for _, v := range values {
+		if p, ok := ParseFilePredicate(v); ok {
+			predicates = append(predicates, p)
+		} else {
+			patterns = append(patterns, v)
+		}
+	}
+	return patterns, predicates
+}
+
+// validateFilePredicate returns an error if the argument of p is invalid.
+func validateFilePredicate(p FilePredicate, negated bool) error {
+	switch p.Name {
+	case FilePredicateContains:
+		if p.Argument == "" {
+			return fmt.Errorf("file:%s() requires a regular expression", p.Name)
+		}
+		if _, err := regexp.Compile(p.Argument); err != nil {
+			return fmt.Errorf("file:%s(...) has an invalid regular expression: %s", p.Name, err)
+		}
+	case FilePredicateHasOwner:
+		if p.Argument == "" {
+			return fmt.Errorf("file:%s() requires an owner, such as file:%s(@username)", p.Name, p.Name)
+		}
+	case FilePredicateSizeMin, FilePredicateSizeMax:
+		if negated {
+			return fmt.Errorf("file:%s(...) does not support negation", p.Name)
+		}
+		size, err := ParseFileSize(p.Argument)
+		if err != nil {
+			return fmt.Errorf("file:%s(...): %s", p.Name, err)
+		}
+		if size == 0 && p.Name == FilePredicateSizeMax {
+			return fmt.Errorf("file:%s(...) requires a positive size", p.Name)
+		}
+	}
+	return nil
+}
+
+var fileSizeUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"kb": 1000,
+	"mb": 1000 * 1000,
+	"gb": 1000 * 1000 * 1000,
+}
+
+var fileSizeRegexp = regexp.MustCompile(`^([0-9]+)\s*([a-zA-Z]*)$`)
+
+// ParseFileSize parses a file size such as "100", "10kb" or "2MB" into a
+// number of bytes.
+func ParseFileSize(s string) (int64, error) {
+	m := fileSizeRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid file size %q, use a number of bytes optionally followed by kb, mb or gb", s)
+	}
+	unit, ok := fileSizeUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid file size unit %q, use b, kb, mb or gb", m[2])
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil || n > (1<<62)/unit {
+		return 0, fmt.Errorf("file size %q is out of range", s)
+	}
+	return n * unit, nil
+}
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPartitionFilePredicates(t *testing.T) {
+	patterns, predicates := PartitionFilePredicates([]string{
+		`\.go$`,
+		`contains(TODO\(\w+\))`,
+		`has.owner(@alice)`,
+		`size.max(10kb)`,
+		`contains`,
+		`unknown(x)`,
+	})
+	if diff := cmp.Diff([]string{`\.go$`, `contains`, `unknown(x)`}, patterns); diff != "" {
+		t.Errorf("unexpected patterns (-want +got):\n%s", diff)
+	}
+	want := []FilePredicate{
+		{Name: FilePredicateContains, Argument: `TODO\(\w+\)`},
+		{Name: FilePredicateHasOwner, Argument: "@alice"},
+		{Name: FilePredicateSizeMax, Argument: "10kb"},
+	}
+	if diff := cmp.Diff(want, predicates); diff != "" {
+		t.Errorf("unexpected predicates (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseFileSize(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "100", want: 100},
+		{in: "100b", want: 100},
+		{in: "10kb", want: 10 * 1000},
+		{in: "2 MB", want: 2 * 1000 * 1000},
+		{in: "1gb", want: 1000 * 1000 * 1000},
+		{in: "", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "1.5mb", wantErr: true},
+		{in: "10kib", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+	}
+	for _, tt := range cases {
+		got, err := ParseFileSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFileSize(%q): got error %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFileSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
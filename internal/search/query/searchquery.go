@@ -42,6 +42,7 @@ const (
 	FieldMax       = "max"    // Deprecated alias for count
 	FieldTimeout   = "timeout"
 	FieldCombyRule = "rule"
+	FieldAggregate = "aggregate" // Groups and counts results instead of listing them, see AggregateModes.
 )
 
 var (
@@ -79,6 +80,7 @@ var (
 			FieldMax:       {Literal: types.StringType, Quoted: types.StringType, Singular: true},
 			FieldTimeout:   {Literal: types.StringType, Quoted: types.StringType, Singular: true},
 			FieldCombyRule: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldAggregate: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
 		},
 		FieldAliases: map[string]string{
 			"r":        FieldRepo,
@@ -90,6 +92,7 @@ var (
 			"until":    FieldBefore,
 			"m":        FieldMessage,
 			"msg":      FieldMessage,
+			"select":   FieldAggregate,
 		},
 	}
 )
@@ -23,6 +23,7 @@ func SubstituteAliases(nodes []Node) []Node {
 		"m":        FieldMessage,
 		"msg":      FieldMessage,
 		"revision": FieldRev,
+		"select":   FieldAggregate,
 	}
 	return MapParameter(nodes, func(field, value string, negated bool, annotation Annotation) Node {
 		if field == "content" {
@@ -253,6 +254,10 @@ func mapGlobToRegex(nodes []Node) ([]Node, error) {
 		case FieldRepo:
 			value, err = reporevToRegex(value)
 		case FieldFile, FieldRepoHasFile:
+			if _, ok := ParseFilePredicate(value); ok && field == FieldFile {
+				// File predicates are not globs.
+				break
+			}
 			if ContainsNoGlobSyntax(value) {
 				value = fuzzifyGlobPattern(value)
 			}
@@ -304,7 +309,7 @@ func Hoist(nodes []Node) ([]Node, error) {
 	}
 
 	expression, ok := nodes[0].(Operator)
-	if !ok || expression.Kind == Concat {
+	if !ok || expression.Kind == Concat || expression.Kind == Near {
 		return nil, fmt.Errorf("heuristic requires top-level and- or or-expression")
 	}
 
@@ -406,6 +411,8 @@ func distribute(prefixes [][]Node, nodes []Node) [][]Node {
 				prefixes = result
 			case And, Concat:
 				prefixes = distribute(prefixes, v.Operands)
+			case Near:
+				prefixes = product(prefixes, []Node{v})
 			}
 		case Parameter, Pattern:
 			prefixes = product(prefixes, []Node{v})
@@ -455,7 +462,7 @@ func substituteOrForRegexp(nodes []Node) []Node {
 					new = newOperator(append(new, rest...), Or)
 				}
 			} else {
-				new = append(new, newOperator(substituteOrForRegexp(v.Operands), v.Kind)...)
+				new = append(new, v.withOperands(substituteOrForRegexp(v.Operands))...)
 			}
 		case Parameter, Pattern:
 			new = append(new, node)
@@ -512,7 +519,7 @@ func substituteConcat(nodes []Node, separator string) []Node {
 					merged = Pattern{}
 				}
 			} else {
-				new = append(new, newOperator(substituteConcat(v.Operands, separator), v.Kind)...)
+				new = append(new, v.withOperands(substituteConcat(v.Operands, separator))...)
 			}
 		}
 	}
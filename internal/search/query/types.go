@@ -251,7 +251,8 @@ func (q AndOrQuery) valueToTypedValue(field, value string, label labels) []*type
 		FieldCount,
 		FieldMax,
 		FieldTimeout,
-		FieldCombyRule:
+		FieldCombyRule,
+		FieldAggregate, "select":
 		return []*types.Value{{String: &value}}
 	}
 	return []*types.Value{{String: &value}}
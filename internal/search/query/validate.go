@@ -12,30 +12,28 @@ import (
 
 // exists traverses every node in nodes and returns early as soon as fn is satisfied.
 func exists(nodes []Node, fn func(node Node) bool) bool {
-	found := false
 	for _, node := range nodes {
 		if fn(node) {
 			return true
 		}
-		if operator, ok := node.(Operator); ok {
-			return exists(operator.Operands, fn)
+		if operator, ok := node.(Operator); ok && exists(operator.Operands, fn) {
+			return true
 		}
 	}
-	return found
+	return false
 }
 
 // forAll traverses every node in nodes and returns whether all nodes satisfy fn.
 func forAll(nodes []Node, fn func(node Node) bool) bool {
-	sat := true
 	for _, node := range nodes {
 		if !fn(node) {
 			return false
 		}
-		if operator, ok := node.(Operator); ok {
-			return forAll(operator.Operands, fn)
+		if operator, ok := node.(Operator); ok && !forAll(operator.Operands, fn) {
+			return false
 		}
 	}
-	return sat
+	return true
 }
 
 // isPatternExpression returns true if every leaf node in nodes is a search
@@ -48,6 +46,36 @@ func isPatternExpression(nodes []Node) bool {
 	})
 }
 
+// isScopedExpression returns true if node is an and/or expression of search
+// patterns where some operands are subqueries with their own parameters, like
+// (repo:a file:x foo) or (repo:b file:y bar). Each subquery must itself
+// partition into parameters and a search pattern.
+func isScopedExpression(node Node) bool {
+	operator, ok := node.(Operator)
+	if !ok || (operator.Kind != And && operator.Kind != Or) {
+		return false
+	}
+	for _, operand := range operator.Operands {
+		if !isPatternExpression([]Node{operand}) && !isScopedExpression(operand) && !IsSubquery(operand) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubquery returns true if node is a parenthesized group of parameters and
+// a search pattern, like (repo:a file:x foo), nested in an and/or expression.
+// A subquery is evaluated as its own search, scoped by its parameters in
+// addition to those of the enclosing query.
+func IsSubquery(node Node) bool {
+	operator, ok := node.(Operator)
+	if !ok || operator.Kind != And || isPatternExpression([]Node{operator}) {
+		return false
+	}
+	_, pattern, err := PartitionSearchPattern(operator.Operands)
+	return err == nil && pattern != nil
+}
+
 // containsPattern returns true if any descendent of nodes is a search pattern.
 func containsPattern(node Node) bool {
 	return exists([]Node{node}, func(node Node) bool {
@@ -106,9 +134,11 @@ func processTopLevel(nodes []Node) ([]Node, error) {
 			return nodes, nil
 		} else if term.Kind == Or && isPatternExpression([]Node{term}) {
 			return nodes, nil
+		} else if term.Kind == Or && isScopedExpression(term) {
+			return nodes, nil
 		} else if term.Kind == And {
 			return term.Operands, nil
-		} else if term.Kind == Concat {
+		} else if term.Kind == Concat || term.Kind == Near {
 			return nodes, nil
 		} else {
 			return nil, &UnsupportedError{Msg: "cannot evaluate: unable to partition pure search pattern"}
@@ -121,7 +151,8 @@ func processTopLevel(nodes []Node) ([]Node, error) {
 // pattern expression and (2) other parameters that scope the evaluation of
 // search patterns (e.g., to repos, files, etc.). It validates that a query
 // contains at most one search pattern expression and that scope parameters do
-// not contain nested expressions.
+// not contain nested expressions. The search pattern expression may contain
+// subqueries with their own scope parameters, see IsSubquery.
 func PartitionSearchPattern(nodes []Node) (parameters []Node, pattern Node, err error) {
 	if len(nodes) == 1 {
 		nodes, err = processTopLevel(nodes)
@@ -132,7 +163,7 @@ func PartitionSearchPattern(nodes []Node) (parameters []Node, pattern Node, err
 
 	var patterns []Node
 	for _, node := range nodes {
-		if isPatternExpression([]Node{node}) {
+		if isPatternExpression([]Node{node}) || isScopedExpression(node) {
 			patterns = append(patterns, node)
 		} else if term, ok := node.(Parameter); ok {
 			parameters = append(parameters, term)
@@ -149,6 +180,29 @@ func PartitionSearchPattern(nodes []Node) (parameters []Node, pattern Node, err
 	return parameters, pattern, nil
 }
 
+// PartitionNear splits a query with a near(N) expression into the queries for
+// its left and right search pattern, which both keep the other parameters of
+// the query. It returns ok false if the query has no near(N) expression at the
+// top level.
+func PartitionNear(nodes []Node) (left, right []Node, distance int, ok bool) {
+	if len(nodes) == 1 {
+		if operator, isOperator := nodes[0].(Operator); isOperator && operator.Kind == And {
+			nodes = operator.Operands
+		}
+	}
+	for i, node := range nodes {
+		operator, isOperator := node.(Operator)
+		if !isOperator || operator.Kind != Near {
+			continue
+		}
+		rest := append(append([]Node{}, nodes[:i]...), nodes[i+1:]...)
+		left = append(append([]Node{}, rest...), operator.Operands[0])
+		right = append(append([]Node{}, rest...), operator.Operands[1])
+		return left, right, operator.Distance, true
+	}
+	return nil, nil, 0, false
+}
+
 // isPureSearchPattern implements a heuristic that returns true if buf, possibly
 // containing whitespace or balanced parentheses, can be treated as a search
 // pattern in the and/or grammar.
@@ -245,6 +299,10 @@ func validateField(field, value string, negated bool, seen map[string]struct{})
 		return nil
 	}
 
+	isAggregateMode := func() error {
+		return ValidateAggregateMode(value)
+	}
+
 	isUnrecognizedField := func() error {
 		return fmt.Errorf("unrecognized field %q", field)
 	}
@@ -273,6 +331,11 @@ func validateField(field, value string, negated bool, seen map[string]struct{})
 		return satisfies(isSingular, isNotNegated)
 	case
 		FieldFile:
+		if p, ok := ParseFilePredicate(value); ok {
+			if err := validateFilePredicate(p, negated); err != nil {
+				return err
+			}
+		}
 		return satisfies(isValidRegexp)
 	case
 		FieldFork,
@@ -321,6 +384,9 @@ func validateField(field, value string, negated bool, seen map[string]struct{})
 	case
 		FieldRev:
 		return satisfies(isSingular, isNotNegated)
+	case
+		FieldAggregate:
+		return satisfies(isSingular, isNotNegated, isAggregateMode)
 	default:
 		return isUnrecognizedField()
 	}
@@ -368,6 +434,24 @@ func validateCommitParameters(nodes []Node) error {
 	return nil
 }
 
+// Queries containing aggregate:author without type:diff or type:commit are not
+// valid, since only commits have authors.
+func validateAggregate(nodes []Node) error {
+	var aggregateAuthor, typeCommitExists bool
+	VisitParameter(nodes, func(field, value string, _ bool, _ Annotation) {
+		if field == FieldAggregate && value == AggregateAuthor {
+			aggregateAuthor = true
+		}
+		if field == FieldType && (value == "commit" || value == "diff") {
+			typeCommitExists = true
+		}
+	})
+	if aggregateAuthor && !typeCommitExists {
+		return fmt.Errorf("%s:%s requires type:commit or type:diff in the query", FieldAggregate, AggregateAuthor)
+	}
+	return nil
+}
+
 func validate(nodes []Node) error {
 	var err error
 	seen := map[string]struct{}{}
@@ -394,5 +478,9 @@ func validate(nodes []Node) error {
 		return err
 	}
 	err = validateCommitParameters(nodes)
+	if err != nil {
+		return err
+	}
+	err = validateAggregate(nodes)
 	return err
 }
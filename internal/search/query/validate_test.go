@@ -1,6 +1,7 @@
 package query
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -76,6 +77,30 @@ func TestAndOrQuery_Validation(t *testing.T) {
 			input: "repo:foo author:rob@saucegraph.com",
 			want:  `your query contains the field 'author', which requires type:commit or type:diff in the query`,
 		},
+		{
+			input: "aggregate:lines",
+			want:  `invalid value "lines" for field "aggregate", use one of repo, dir, capture, author`,
+		},
+		{
+			input: "select:author foo",
+			want:  "aggregate:author requires type:commit or type:diff in the query",
+		},
+		{
+			input: "file:contains([)",
+			want:  "file:contains(...) has an invalid regular expression: error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			input: "file:has.owner()",
+			want:  "file:has.owner() requires an owner, such as file:has.owner(@username)",
+		},
+		{
+			input: "-file:size.max(10kb)",
+			want:  "file:size.max(...) does not support negation",
+		},
+		{
+			input: "file:size.min(10tb)",
+			want:  `file:size.min(...): invalid file size unit "tb", use b, kb, mb or gb`,
+		},
 	}
 	for _, c := range cases {
 		t.Run("validate and/or query", func(t *testing.T) {
@@ -232,6 +257,18 @@ func TestPartitionSearchPattern(t *testing.T) {
 		},
 		{
 			input: "(file:foo x) or y",
+			want:  `(or (and "file:foo" "x") "y")`,
+		},
+		{
+			input: "(repo:a file:x foo) or (repo:b file:y bar)",
+			want:  `(or (and "repo:a" "file:x" "foo") (and "repo:b" "file:y" "bar"))`,
+		},
+		{
+			input: "repo:c ((repo:a foo) or (file:b (bar or (repo:d baz))))",
+			want:  `"repo:c" (or (and "repo:a" "foo") (and "file:b" (or "bar" (and "repo:d" "baz"))))`,
+		},
+		{
+			input: "(repo:a file:x) or foo",
 			want:  "cannot evaluate: unable to partition pure search pattern",
 		},
 		{
@@ -273,6 +310,42 @@ func TestPartitionSearchPattern(t *testing.T) {
 	}
 }
 
+func TestPartitionNear(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{
+			input: "repo:foo lock near(5) unlock",
+			want:  `near:5 "repo:foo" "lock" | "repo:foo" "unlock"`,
+		},
+		{
+			input: "lock near(0) defer unlock",
+			want:  `near:0 "lock" | "defer unlock"`,
+		},
+		{
+			input: "lock and unlock",
+			want:  "",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := ProcessAndOr(tt.input, ParserOptions{SearchType: SearchTypeLiteral})
+			if err != nil {
+				t.Fatal(err)
+			}
+			left, right, distance, ok := PartitionNear(q.(*AndOrQuery).Query)
+			var got string
+			if ok {
+				got = fmt.Sprintf("near:%d %s | %s", distance, prettyPrint(left), prettyPrint(right))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
 func TestContainsAndOrKeyword(t *testing.T) {
 	if !ContainsAndOrKeyword("foo OR bar") {
 		t.Errorf("Expected query to contain keyword")
@@ -285,16 +358,131 @@ func TestContainsAndOrKeyword(t *testing.T) {
 	}
 }
 
-func TestForAll(t *testing.T) {
-	nodes := []Node{
-		Parameter{Field: "repo", Value: "foo"},
-		Parameter{Field: "repo", Value: "bar"},
-	}
-	result := forAll(nodes, func(node Node) bool {
+func TestExists(t *testing.T) {
+	isParameter := func(node Node) bool {
 		_, ok := node.(Parameter)
 		return ok
-	})
-	if !result {
-		t.Errorf("Expected all nodes to be parameters.")
+	}
+	cases := []struct {
+		name  string
+		nodes []Node
+		want  bool
+	}{
+		{
+			name:  "empty",
+			nodes: nil,
+			want:  false,
+		},
+		{
+			name: "top level",
+			nodes: []Node{
+				Pattern{Value: "foo"},
+				Parameter{Field: "repo", Value: "bar"},
+			},
+			want: true,
+		},
+		{
+			name: "nested in operator",
+			nodes: []Node{
+				Operator{Kind: Or, Operands: []Node{
+					Pattern{Value: "foo"},
+					Parameter{Field: "repo", Value: "bar"},
+				}},
+			},
+			want: true,
+		},
+		{
+			name: "sibling after operator",
+			nodes: []Node{
+				Operator{Kind: Or, Operands: []Node{
+					Pattern{Value: "foo"},
+					Pattern{Value: "bar"},
+				}},
+				Parameter{Field: "repo", Value: "baz"},
+			},
+			want: true,
+		},
+		{
+			name: "none",
+			nodes: []Node{
+				Operator{Kind: And, Operands: []Node{
+					Pattern{Value: "foo"},
+					Pattern{Value: "bar"},
+				}},
+				Pattern{Value: "baz"},
+			},
+			want: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := exists(c.nodes, isParameter); got != c.want {
+				t.Errorf("got %t, want %t", got, c.want)
+			}
+		})
+	}
+}
+
+func TestForAll(t *testing.T) {
+	isNotPattern := func(node Node) bool {
+		_, ok := node.(Pattern)
+		return !ok
+	}
+	cases := []struct {
+		name  string
+		nodes []Node
+		want  bool
+	}{
+		{
+			name:  "empty",
+			nodes: nil,
+			want:  true,
+		},
+		{
+			name: "all parameters",
+			nodes: []Node{
+				Parameter{Field: "repo", Value: "foo"},
+				Parameter{Field: "repo", Value: "bar"},
+			},
+			want: true,
+		},
+		{
+			name: "nested parameters",
+			nodes: []Node{
+				Operator{Kind: And, Operands: []Node{
+					Parameter{Field: "repo", Value: "foo"},
+					Parameter{Field: "file", Value: "bar"},
+				}},
+				Parameter{Field: "repo", Value: "baz"},
+			},
+			want: true,
+		},
+		{
+			name: "pattern nested in operator",
+			nodes: []Node{
+				Operator{Kind: And, Operands: []Node{
+					Parameter{Field: "repo", Value: "foo"},
+					Pattern{Value: "bar"},
+				}},
+			},
+			want: false,
+		},
+		{
+			name: "sibling after operator",
+			nodes: []Node{
+				Operator{Kind: And, Operands: []Node{
+					Parameter{Field: "repo", Value: "foo"},
+				}},
+				Pattern{Value: "bar"},
+			},
+			want: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := forAll(c.nodes, isNotPattern); got != c.want {
+				t.Errorf("got %t, want %t", got, c.want)
+			}
+		})
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -49,55 +50,89 @@ func Search(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.Repo
 		return MockSearch(ctx, repo, commit, p, fetchTimeout)
 	}
 
-	tr, ctx := trace.New(ctx, "searcher.client", fmt.Sprintf("%s@%s", repo.Name, commit))
+	return doSearch(ctx, searcherURLs, repo, commit, p, fetchTimeout, nil)
+}
+
+// StreamSearch searches repo@commit with p. Unlike Search, searcher sends
+// matches as it finds them and onMatch is called with each of them as soon as
+// it is received. onMatch is called sequentially.
+//
+// If the search fails (for example because ctx hits its deadline) after some
+// matches were already passed to onMatch, those matches remain valid and the
+// error is returned as usual.
+func StreamSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.Repo, commit api.CommitID, p *search.TextPatternInfo, fetchTimeout time.Duration, onMatch func(*protocol.FileMatch)) (limitHit bool, err error) {
+	if MockSearch != nil {
+		matches, limitHit, err := MockSearch(ctx, repo, commit, p, fetchTimeout)
+		for _, m := range matches {
+			onMatch(m)
+		}
+		return limitHit, err
+	}
+
+	_, limitHit, err = doSearch(ctx, searcherURLs, repo, commit, p, fetchTimeout, onMatch)
+	return limitHit, err
+}
+
+// Replace returns a diff for every file in repo@commit in which matches of p
+// are replaced with replacement. See protocol.ReplaceRequest for the syntax of
+// replacement.
+func Replace(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.Repo, commit api.CommitID, p *search.TextPatternInfo, replacement string, fetchTimeout time.Duration) (diffs []*protocol.FileDiff, limitHit bool, err error) {
+	tr, ctx := trace.New(ctx, "searcher.client.Replace", fmt.Sprintf("%s@%s", repo.Name, commit))
 	defer func() {
 		tr.SetError(err)
 		tr.Finish()
 	}()
 
-	q := url.Values{
-		"Repo":            []string{string(repo.Name)},
-		"URL":             []string{repo.URL},
-		"Commit":          []string{string(commit)},
-		"Pattern":         []string{p.Pattern},
-		"ExcludePattern":  []string{p.ExcludePattern},
-		"IncludePatterns": p.IncludePatterns,
-		"FetchTimeout":    []string{fetchTimeout.String()},
-		"Languages":       p.Languages,
-		"CombyRule":       []string{p.CombyRule},
-
-		"PathPatternsAreRegExps": []string{"true"},
+	q, err := requestValues(ctx, repo, commit, p, fetchTimeout)
+	if err != nil {
+		return nil, false, err
 	}
-	if deadline, ok := ctx.Deadline(); ok {
-		t, err := deadline.MarshalText()
-		if err != nil {
-			return nil, false, err
-		}
-		q.Set("Deadline", string(t))
+	q.Set("Replacement", replacement)
+
+	// Use the same searcher as Search, since it likely has the archive
+	// cached already.
+	searcherURL, err := searcherURLs.Get(string(repo.Name)+"@"+string(commit), nil)
+	if err != nil {
+		return nil, false, err
 	}
-	q.Set("FileMatchLimit", strconv.FormatInt(int64(p.FileMatchLimit), 10))
-	if p.IsRegExp {
-		q.Set("IsRegExp", "true")
+
+	resp, err := searcherRequest(ctx, searcherURL+"/replace?"+q.Encode())
+	if err != nil {
+		return nil, false, err
 	}
-	if p.IsStructuralPat {
-		q.Set("IsStructuralPat", "true")
+	defer resp.Body.Close()
+
+	var r protocol.ReplaceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, false, errors.Wrap(err, "searcher response invalid")
 	}
-	if p.IsWordMatch {
-		q.Set("IsWordMatch", "true")
+	if r.DeadlineHit {
+		err = context.DeadlineExceeded
 	}
-	if p.IsCaseSensitive {
-		q.Set("IsCaseSensitive", "true")
+	diffs = make([]*protocol.FileDiff, len(r.Diffs))
+	for i := range r.Diffs {
+		diffs[i] = &r.Diffs[i]
 	}
-	if p.PathPatternsAreCaseSensitive {
-		q.Set("PathPatternsAreCaseSensitive", "true")
+	return diffs, r.LimitHit, err
+}
+
+// doSearch implements Search and StreamSearch. If onMatch is non-nil a
+// streaming request is sent and matches are only passed to onMatch, not
+// returned.
+func doSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.Repo, commit api.CommitID, p *search.TextPatternInfo, fetchTimeout time.Duration, onMatch func(*protocol.FileMatch)) (matches []*protocol.FileMatch, limitHit bool, err error) {
+	tr, ctx := trace.New(ctx, "searcher.client", fmt.Sprintf("%s@%s", repo.Name, commit))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	q, err := requestValues(ctx, repo, commit, p, fetchTimeout)
+	if err != nil {
+		return nil, false, err
 	}
-	if p.IsNegated {
-		q.Set("IsNegated", "true")
+	if onMatch != nil {
+		q.Set("Stream", "true")
 	}
-	// TEMP BACKCOMPAT: always set even if false so that searcher can distinguish new frontends that send
-	// these fields from old frontends that do not (and provide a default in the latter case).
-	q.Set("PatternMatchesContent", strconv.FormatBool(p.PatternMatchesContent))
-	q.Set("PatternMatchesPath", strconv.FormatBool(p.PatternMatchesPath))
 	rawQuery := q.Encode()
 
 	// Searcher caches the file contents for repo@commit since it is
@@ -131,11 +166,22 @@ func Search(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.Repo
 
 		url := searcherURL + "?" + rawQuery
 		tr.LazyPrintf("attempt %d: %s", attempt, url)
-		matches, limitHit, err = textSearchURL(ctx, url)
+		var streamed int
+		if onMatch == nil {
+			matches, limitHit, err = textSearchURL(ctx, url)
+		} else {
+			streamed, limitHit, err = textSearchStreamURL(ctx, url, onMatch)
+		}
 		if err == nil || errcode.IsTimeout(err) {
 			return matches, limitHit, err
 		}
 
+		// We can't retry once we have passed on matches, since another
+		// searcher instance would send them again.
+		if streamed > 0 {
+			return nil, false, err
+		}
+
 		// If we are canceled, return that error.
 		if err := ctx.Err(); err != nil {
 			return nil, false, err
@@ -153,10 +199,142 @@ func Search(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.Repo
 }
 
 func textSearchURL(ctx context.Context, url string) ([]*protocol.FileMatch, bool, error) {
-	req, err := http.NewRequest("GET", url, nil)
+	resp, err := searcherRequest(ctx, url)
 	if err != nil {
 		return nil, false, err
 	}
+	defer resp.Body.Close()
+
+	r := struct {
+		Matches     []*protocol.FileMatch
+		LimitHit    bool
+		DeadlineHit bool
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "searcher response invalid")
+	}
+	if r.DeadlineHit {
+		err = context.DeadlineExceeded
+	}
+	return r.Matches, r.LimitHit, err
+}
+
+// requestValues returns the form values of a searcher request for searching
+// repo@commit with p.
+func requestValues(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.TextPatternInfo, fetchTimeout time.Duration) (url.Values, error) {
+	q := url.Values{
+		"Repo":            []string{string(repo.Name)},
+		"URL":             []string{repo.URL},
+		"Commit":          []string{string(commit)},
+		"Pattern":         []string{p.Pattern},
+		"ExcludePattern":  []string{p.ExcludePattern},
+		"IncludePatterns": p.IncludePatterns,
+		"FetchTimeout":    []string{fetchTimeout.String()},
+		"Languages":       p.Languages,
+		"CombyRule":       []string{p.CombyRule},
+
+		"FileContentMustInclude": p.FileContentMustInclude,
+		"FileContentMustExclude": p.FileContentMustExclude,
+		"FileOwnersMustInclude":  p.FileOwnersMustInclude,
+		"FileOwnersMustExclude":  p.FileOwnersMustExclude,
+
+		"PathPatternsAreRegExps": []string{"true"},
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		t, err := deadline.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		q.Set("Deadline", string(t))
+	}
+	q.Set("FileMatchLimit", strconv.FormatInt(int64(p.FileMatchLimit), 10))
+	if p.IsRegExp {
+		q.Set("IsRegExp", "true")
+	}
+	if p.IsStructuralPat {
+		q.Set("IsStructuralPat", "true")
+	}
+	if p.IsWordMatch {
+		q.Set("IsWordMatch", "true")
+	}
+	if p.IsCaseSensitive {
+		q.Set("IsCaseSensitive", "true")
+	}
+	if p.PathPatternsAreCaseSensitive {
+		q.Set("PathPatternsAreCaseSensitive", "true")
+	}
+	if p.IsNegated {
+		q.Set("IsNegated", "true")
+	}
+	if p.NearPattern != "" {
+		q.Set("NearPattern", p.NearPattern)
+		q.Set("NearDistance", strconv.Itoa(p.NearDistance))
+	}
+	if p.FileSizeMin > 0 {
+		q.Set("FileSizeMin", strconv.FormatInt(p.FileSizeMin, 10))
+	}
+	if p.FileSizeMax > 0 {
+		q.Set("FileSizeMax", strconv.FormatInt(p.FileSizeMax, 10))
+	}
+	// TEMP BACKCOMPAT: always set even if false so that searcher can distinguish new frontends that send
+	// these fields from old frontends that do not (and provide a default in the latter case).
+	q.Set("PatternMatchesContent", strconv.FormatBool(p.PatternMatchesContent))
+	q.Set("PatternMatchesPath", strconv.FormatBool(p.PatternMatchesPath))
+	return q, nil
+}
+
+// textSearchStreamURL sends a streaming search request to url and calls
+// onMatch with every match received. It returns the number of matches passed
+// to onMatch.
+func textSearchStreamURL(ctx context.Context, url string, onMatch func(*protocol.FileMatch)) (streamed int, limitHit bool, err error) {
+	resp, err := searcherRequest(ctx, url)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event protocol.StreamEvent
+		if err := dec.Decode(&event); err != nil {
+			// If the body was cut short due to cancellation or timeout, return
+			// just that so that callers can keep the partial results.
+			if ctx.Err() != nil {
+				return streamed, false, ctx.Err()
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return streamed, false, errors.Wrap(err, "searcher response invalid")
+		}
+
+		if event.Match != nil {
+			streamed++
+			onMatch(event.Match)
+			continue
+		}
+
+		if event.Done == nil {
+			continue
+		}
+		if event.Done.Error != "" {
+			return streamed, false, errors.New(event.Done.Error)
+		}
+		if event.Done.DeadlineHit {
+			err = context.DeadlineExceeded
+		}
+		return streamed, event.Done.LimitHit, err
+	}
+}
+
+// searcherRequest sends a request to url. The caller must close the body of
+// the returned response, which is guaranteed to have a 200 status code.
+func searcherRequest(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
 	req = req.WithContext(ctx)
 
 	req, ht := nethttp.TraceRequest(ot.GetTracer(ctx), req,
@@ -174,30 +352,17 @@ func textSearchURL(ctx context.Context, url string) ([]*protocol.FileMatch, bool
 		if ctx.Err() != nil {
 			err = ctx.Err()
 		}
-		return nil, false, errors.Wrap(err, "searcher request failed")
+		return nil, errors.Wrap(err, "searcher request failed")
 	}
-	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, false, err
+			return nil, err
 		}
-		return nil, false, errors.WithStack(&searcherError{StatusCode: resp.StatusCode, Message: string(body)})
+		return nil, errors.WithStack(&searcherError{StatusCode: resp.StatusCode, Message: string(body)})
 	}
-
-	r := struct {
-		Matches     []*protocol.FileMatch
-		LimitHit    bool
-		DeadlineHit bool
-	}{}
-	err = json.NewDecoder(resp.Body).Decode(&r)
-	if err != nil {
-		return nil, false, errors.Wrap(err, "searcher response invalid")
-	}
-	if r.DeadlineHit {
-		err = context.DeadlineExceeded
-	}
-	return r.Matches, r.LimitHit, err
+	return resp, nil
 }
 
 type searcherError struct {
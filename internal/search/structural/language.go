@@ -0,0 +1,296 @@
+package structural
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Language describes the syntax the matcher needs to know about a file: which
+// delimiters must be balanced inside a hole and which parts of the file are
+// opaque, ie strings and comments. Delimiters inside opaque parts are
+// ignored, and matches never start inside them.
+type Language struct {
+	// Delimiters are pairs of opening and closing delimiters.
+	Delimiters [][2]string
+
+	// Strings are the string literals of the language.
+	Strings []StringLiteral
+
+	// LineComments start a comment which ends at the end of the line.
+	LineComments []string
+
+	// BlockComments are pairs of opening and closing comment delimiters.
+	BlockComments [][2]string
+}
+
+// StringLiteral describes a kind of string literal.
+type StringLiteral struct {
+	Open, Close string
+
+	// Raw is true if a backslash does not escape the closing delimiter.
+	Raw bool
+}
+
+var (
+	defaultDelimiters = [][2]string{{"(", ")"}, {"[", "]"}, {"{", "}"}}
+
+	doubleQuoted = StringLiteral{Open: `"`, Close: `"`}
+	singleQuoted = StringLiteral{Open: `'`, Close: `'`}
+	backQuoted   = StringLiteral{Open: "`", Close: "`"}
+
+	cComments = [][2]string{{"/*", "*/"}}
+)
+
+// Generic is used for files of unknown languages. Like the generic comby
+// matcher it knows about the common delimiters and strings, but not about
+// comments.
+var Generic = &Language{
+	Delimiters: defaultDelimiters,
+	Strings:    []StringLiteral{doubleQuoted, singleQuoted},
+}
+
+var (
+	cLike = &Language{
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{doubleQuoted, singleQuoted},
+		LineComments:  []string{"//"},
+		BlockComments: cComments,
+	}
+	golang = &Language{
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{doubleQuoted, singleQuoted, {Open: "`", Close: "`", Raw: true}},
+		LineComments:  []string{"//"},
+		BlockComments: cComments,
+	}
+	javascript = &Language{
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{doubleQuoted, singleQuoted, backQuoted},
+		LineComments:  []string{"//"},
+		BlockComments: cComments,
+	}
+	rust = &Language{
+		// Single quotes are not strings in Rust, they are also used for
+		// lifetimes.
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{doubleQuoted},
+		LineComments:  []string{"//"},
+		BlockComments: cComments,
+	}
+	css = &Language{
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{doubleQuoted, singleQuoted},
+		BlockComments: cComments,
+	}
+	python = &Language{
+		Delimiters: defaultDelimiters,
+		// Triple quoted strings must come first, so that they are not
+		// mistaken for empty strings.
+		Strings: []StringLiteral{
+			{Open: `"""`, Close: `"""`},
+			{Open: `'''`, Close: `'''`},
+			doubleQuoted,
+			singleQuoted,
+		},
+		LineComments: []string{"#"},
+	}
+	hashComments = &Language{
+		Delimiters:   defaultDelimiters,
+		Strings:      []StringLiteral{doubleQuoted, singleQuoted},
+		LineComments: []string{"#"},
+	}
+	julia = &Language{
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{{Open: `"""`, Close: `"""`}, doubleQuoted},
+		LineComments:  []string{"#"},
+		BlockComments: [][2]string{{"#=", "=#"}},
+	}
+	haskell = &Language{
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{doubleQuoted},
+		LineComments:  []string{"--"},
+		BlockComments: [][2]string{{"{-", "-}"}},
+	}
+	sql = &Language{
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{doubleQuoted, {Open: `'`, Close: `'`, Raw: true}},
+		LineComments:  []string{"--"},
+		BlockComments: cComments,
+	}
+	lisp = &Language{
+		Delimiters:   defaultDelimiters,
+		Strings:      []StringLiteral{doubleQuoted},
+		LineComments: []string{";"},
+	}
+	percentComments = &Language{
+		Delimiters:   defaultDelimiters,
+		Strings:      []StringLiteral{doubleQuoted},
+		LineComments: []string{"%"},
+	}
+	fortran = &Language{
+		Delimiters:   defaultDelimiters,
+		Strings:      []StringLiteral{doubleQuoted, singleQuoted},
+		LineComments: []string{"!"},
+	}
+	ocaml = &Language{
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{doubleQuoted},
+		BlockComments: [][2]string{{"(*", "*)"}},
+	}
+	fsharp = &Language{
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{doubleQuoted},
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"(*", "*)"}},
+	}
+	pascal = &Language{
+		// Braces are comments in Pascal, not delimiters.
+		Delimiters:    [][2]string{{"(", ")"}, {"[", "]"}},
+		Strings:       []StringLiteral{{Open: `'`, Close: `'`, Raw: true}},
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"(*", "*)"}, {"{", "}"}},
+	}
+	markup = &Language{
+		Delimiters:    defaultDelimiters,
+		Strings:       []StringLiteral{doubleQuoted, singleQuoted},
+		BlockComments: [][2]string{{"<!--", "-->"}},
+	}
+	jsonLanguage = &Language{
+		Delimiters: defaultDelimiters,
+		Strings:    []StringLiteral{doubleQuoted},
+	}
+)
+
+// languages maps a representative file extension to its language. The
+// extensions are the same as the ones used to select a comby matcher.
+var languages = map[string]*Language{
+	".s":     hashComments,
+	".sh":    hashComments,
+	".c":     cLike,
+	".h":     cLike,
+	".cc":    cLike,
+	".cpp":   cLike,
+	".cs":    cLike,
+	".css":   css,
+	".dart":  cLike,
+	".clj":   lisp,
+	".elm":   haskell,
+	".erl":   percentComments,
+	".ex":    hashComments,
+	".exs":   hashComments,
+	".f":     fortran,
+	".fsx":   fsharp,
+	".fs":    fsharp,
+	".go":    golang,
+	".html":  markup,
+	".hs":    haskell,
+	".java":  cLike,
+	".js":    javascript,
+	".jsx":   javascript,
+	".json":  jsonLanguage,
+	".jl":    julia,
+	".kt":    cLike,
+	".tex":   percentComments,
+	".lisp":  lisp,
+	".nim":   hashComments,
+	".ml":    ocaml,
+	".pas":   pascal,
+	".php":   cLike,
+	".py":    python,
+	".re":    cLike,
+	".rb":    hashComments,
+	".rs":    rust,
+	".scala": cLike,
+	".sql":   sql,
+	".swift": cLike,
+	".txt":   Generic,
+	".ts":    javascript,
+	".tsx":   javascript,
+	".xml":   markup,
+}
+
+// LanguageForExtension returns the language of files with the extension ext
+// (such as ".go"). It returns Generic for unknown extensions.
+func LanguageForExtension(ext string) *Language {
+	if l, ok := languages[strings.ToLower(ext)]; ok {
+		return l
+	}
+	return Generic
+}
+
+// opaqueEnd returns the end of the string or comment starting at offset i of
+// src. It returns -1 if none starts at i. Unterminated strings and comments
+// extend to the end of src.
+func (l *Language) opaqueEnd(src []byte, i int) int {
+	rest := src[i:]
+	for _, c := range l.BlockComments {
+		if hasPrefix(rest, c[0]) {
+			return indexAfter(src, i+len(c[0]), c[1])
+		}
+	}
+	for _, c := range l.LineComments {
+		if hasPrefix(rest, c) {
+			// The newline is not part of the comment.
+			if j := indexByteFrom(src, i, '\n'); j >= 0 {
+				return j
+			}
+			return len(src)
+		}
+	}
+	for _, s := range l.Strings {
+		if !hasPrefix(rest, s.Open) {
+			continue
+		}
+		for j := i + len(s.Open); j < len(src); j++ {
+			if !s.Raw && src[j] == '\\' {
+				j++
+				continue
+			}
+			if hasPrefix(src[j:], s.Close) {
+				return j + len(s.Close)
+			}
+		}
+		return len(src)
+	}
+	return -1
+}
+
+// opening returns the closing delimiter for the opening delimiter at the
+// start of b, if there is one.
+func (l *Language) opening(b []byte) (open, close string, ok bool) {
+	for _, d := range l.Delimiters {
+		if hasPrefix(b, d[0]) {
+			return d[0], d[1], true
+		}
+	}
+	return "", "", false
+}
+
+// closing returns true if b starts with a closing delimiter.
+func (l *Language) closing(b []byte) bool {
+	for _, d := range l.Delimiters {
+		if hasPrefix(b, d[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(b []byte, prefix string) bool {
+	return len(b) >= len(prefix) && string(b[:len(prefix)]) == prefix
+}
+
+// indexAfter returns the offset after the first occurrence of s in src at or
+// after offset i, or len(src) if there is none.
+func indexAfter(src []byte, i int, s string) int {
+	if j := bytes.Index(src[i:], []byte(s)); j >= 0 {
+		return i + j + len(s)
+	}
+	return len(src)
+}
+
+func indexByteFrom(src []byte, i int, c byte) int {
+	if j := bytes.IndexByte(src[i:], c); j >= 0 {
+		return i + j
+	}
+	return -1
+}
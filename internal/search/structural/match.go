@@ -0,0 +1,254 @@
+package structural
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// maxSteps bounds the work spent matching a template against a single file.
+// Templates with many holes can take exponential time on large files, we
+// rather report an error than tie up searcher.
+const maxSteps = 1 << 22
+
+// ErrTooComplex is returned if matching a template against a file takes too
+// long.
+var ErrTooComplex = errors.New("structural search pattern is too complex to match")
+
+// Match is a match of a template in a file.
+type Match struct {
+	// Start and End are the byte offsets of the match in the file.
+	Start, End int
+
+	// Environment maps the names of the holes in the template to the text
+	// they matched. Anonymous holes are not included.
+	Environment map[string]string
+}
+
+// binding is the text src[start:end] matched by the hole name.
+type binding struct {
+	name       string
+	start, end int
+}
+
+type matcher struct {
+	tokens []token
+	src    []byte
+	lang   *Language
+	steps  int
+}
+
+// Matches returns the non-overlapping matches of t in src, which is written
+// in lang. Matches never start inside a string or comment.
+func (t *Template) Matches(src []byte, lang *Language) ([]Match, error) {
+	m := &matcher{tokens: t.tokens, src: src, lang: lang}
+
+	var matches []Match
+	for pos := 0; pos < len(src); {
+		if end := lang.opaqueEnd(src, pos); end > pos {
+			pos = end
+			continue
+		}
+		if !isSpace(src[pos]) {
+			end, env, ok := m.match(0, pos, nil)
+			if m.steps > maxSteps {
+				return matches, ErrTooComplex
+			}
+			if ok && end > pos {
+				matches = append(matches, m.newMatch(pos, end, env))
+				pos = end
+				continue
+			}
+		}
+		_, size := utf8.DecodeRune(src[pos:])
+		pos += size
+	}
+	return matches, nil
+}
+
+func (m *matcher) newMatch(start, end int, env []binding) Match {
+	match := Match{Start: start, End: end}
+	if len(env) > 0 {
+		match.Environment = make(map[string]string, len(env))
+		for _, b := range env {
+			match.Environment[b.name] = string(m.src[b.start:b.end])
+		}
+	}
+	return match
+}
+
+// match matches the tokens starting at index i against the file starting at
+// offset pos. It returns the end of the match and the holes bound so far.
+func (m *matcher) match(i, pos int, env []binding) (int, []binding, bool) {
+	if i == len(m.tokens) {
+		return pos, env, true
+	}
+	m.steps++
+	if m.steps > maxSteps {
+		return 0, nil, false
+	}
+
+	tok := &m.tokens[i]
+	switch tok.kind {
+	case tokenText:
+		if !hasPrefix(m.src[pos:], tok.text) {
+			return 0, nil, false
+		}
+		return m.match(i+1, pos+len(tok.text), env)
+
+	case tokenSpace:
+		end := pos
+		for end < len(m.src) && isSpace(m.src[end]) {
+			end++
+		}
+		if tok.required && end == pos {
+			return 0, nil, false
+		}
+		return m.match(i+1, end, env)
+	}
+
+	// A hole which was bound before must match the same text again.
+	if bound, ok := lookup(env, tok.name); ok {
+		text := m.src[bound.start:bound.end]
+		if !hasPrefix(m.src[pos:], string(text)) {
+			return 0, nil, false
+		}
+		return m.match(i+1, pos+len(text), env)
+	}
+
+	bind := func(end int) []binding {
+		if tok.name == "" || tok.name == "_" {
+			return env
+		}
+		// Force a copy, so that backtracking does not see bindings of
+		// other candidates.
+		return append(env[:len(env):len(env)], binding{name: tok.name, start: pos, end: end})
+	}
+
+	// Holes which can only match one way.
+	end := -1
+	switch tok.hole {
+	case holeWord:
+		end = m.scan(pos, isWordRune)
+	case holeSpace:
+		end = m.scan(pos, func(r rune) bool { return r == ' ' || r == '\t' })
+	case holeLine:
+		end = len(m.src)
+		if j := indexByteFrom(m.src, pos, '\n'); j >= 0 {
+			end = j + 1
+		}
+	case holeRegexp:
+		if loc := tok.re.FindIndex(m.src[pos:]); loc != nil {
+			end = pos + loc[1]
+		}
+	}
+	switch tok.hole {
+	case holeWord, holeSpace:
+		if end <= pos {
+			return 0, nil, false
+		}
+		fallthrough
+	case holeLine, holeRegexp:
+		if end < 0 {
+			return 0, nil, false
+		}
+		return m.match(i+1, end, bind(end))
+	}
+
+	// The remaining holes match lazily, trying the shortest text first. A
+	// hole at the end of the template matches greedily instead, up to the end
+	// of the line or the enclosing delimiters.
+	last := i == len(m.tokens)-1
+	nonSpace := tok.hole == holeNonSpace
+	if last {
+		end := pos
+		for {
+			next := m.step(end, nonSpace)
+			if next < 0 || !nonSpace && m.src[end] == '\n' {
+				break
+			}
+			end = next
+		}
+		if nonSpace && end == pos {
+			return 0, nil, false
+		}
+		return end, bind(end), true
+	}
+
+	end = pos
+	if nonSpace {
+		// Non-whitespace holes match at least one character.
+		end = m.step(pos, true)
+	}
+	for end >= 0 {
+		if matchEnd, env, ok := m.match(i+1, end, bind(end)); ok {
+			return matchEnd, env, true
+		}
+		if m.steps > maxSteps {
+			return 0, nil, false
+		}
+		end = m.step(end, nonSpace)
+	}
+	return 0, nil, false
+}
+
+// step returns the offset after the unit of text starting at offset i, which
+// is a string, a comment, a balanced group of delimiters or a single
+// character. It returns -1 at the end of the file or of the enclosing
+// delimiters. If nonSpace is true, step also returns -1 at whitespace.
+func (m *matcher) step(i int, nonSpace bool) int {
+	if i >= len(m.src) {
+		return -1
+	}
+	if end := m.lang.opaqueEnd(m.src, i); end > i {
+		return end
+	}
+	if m.lang.closing(m.src[i:]) {
+		return -1
+	}
+	if open, close, ok := m.lang.opening(m.src[i:]); ok {
+		for j := i + len(open); j < len(m.src); {
+			if hasPrefix(m.src[j:], close) {
+				return j + len(close)
+			}
+			next := m.step(j, false)
+			if next < 0 {
+				// Unbalanced delimiters.
+				return -1
+			}
+			j = next
+		}
+		return -1
+	}
+	r, size := utf8.DecodeRune(m.src[i:])
+	if nonSpace && unicode.IsSpace(r) {
+		return -1
+	}
+	return i + size
+}
+
+// scan returns the offset after the runes starting at offset i for which f
+// returns true.
+func (m *matcher) scan(i int, f func(rune) bool) int {
+	for i < len(m.src) {
+		r, size := utf8.DecodeRune(m.src[i:])
+		if !f(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+func lookup(env []binding, name string) (binding, bool) {
+	if name == "" || name == "_" {
+		return binding{}, false
+	}
+	for _, b := range env {
+		if b.name == name {
+			return b, true
+		}
+	}
+	return binding{}, false
+}
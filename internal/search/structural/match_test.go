@@ -0,0 +1,152 @@
+package structural
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatches(t *testing.T) {
+	cases := []struct {
+		name     string
+		template string
+		src      string
+		ext      string
+		want     []string
+	}{{
+		name:     "balanced delimiters",
+		template: "foo(:[args])",
+		src:      "foo(a, bar(b, c)) foo() foo(x",
+		want:     []string{"foo(a, bar(b, c))", "foo()"},
+	}, {
+		name:     "delimiters in strings are ignored",
+		template: "foo(:[args])",
+		src:      `foo(")", 1)`,
+		want:     []string{`foo(")", 1)`},
+	}, {
+		name:     "no matches in comments",
+		template: "foo(:[args])",
+		src:      "/* foo(plain string) */\nfunc foo(go string) {}\n",
+		ext:      ".go",
+		want:     []string{"foo(go string)"},
+	}, {
+		name:     "comments are not special for the generic language",
+		template: "foo(:[args])",
+		src:      "/* foo(plain string) */\nfunc foo(go string) {}\n",
+		want:     []string{"foo(plain string)", "foo(go string)"},
+	}, {
+		name:     "whitespace matches any whitespace",
+		template: "if :[cond] {\n\treturn :[x]\n}",
+		src:      "if err != nil { return err }",
+		want:     []string{"if err != nil { return err }"},
+	}, {
+		name:     "whitespace can be left out between punctuation",
+		template: "a = b",
+		src:      "a=b ab = b",
+		want:     []string{"a=b"},
+	}, {
+		name:     "whitespace can not be left out between words",
+		template: "return err",
+		src:      "returnerr return  err",
+		want:     []string{"return  err"},
+	}, {
+		name:     "repeated holes match the same text",
+		template: ":[[x]] == :[[x]]",
+		src:      "a == b; c == c",
+		want:     []string{"c == c"},
+	}, {
+		name:     "anonymous holes are independent",
+		template: "(:[_], :[_])",
+		src:      "(a, b)",
+		want:     []string{"(a, b)"},
+	}, {
+		name:     "word hole",
+		template: "foo.:[[method]](",
+		src:      "foo.bar.baz( foo.qux(",
+		want:     []string{"foo.qux("},
+	}, {
+		name:     "non-space hole",
+		template: "import :[pkg.];",
+		src:      "import a.b.c;\nimport d e;",
+		want:     []string{"import a.b.c;"},
+	}, {
+		name:     "regexp hole",
+		template: "v:[n~[0-9]+]",
+		src:      "vx v12 v3",
+		want:     []string{"v12", "v3"},
+	}, {
+		name:     "line hole",
+		template: "// TODO:[rest\\n]",
+		src:      "x // TODO fix\ny\n",
+		ext:      ".txt",
+		want:     []string{"// TODO fix\n"},
+	}, {
+		name:     "trailing hole stops at the end of the line",
+		template: "x := :[value]",
+		src:      "x := f(a,\n\tb)\ny := 1\n",
+		want:     []string{"x := f(a,\n\tb)"},
+	}, {
+		name:     "raw strings",
+		template: "f(:[x])",
+		src:      "f(`)\\`)",
+		ext:      ".go",
+		want:     []string{"f(`)\\`)"},
+	}}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseTemplate(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			matches, err := tmpl.Matches([]byte(tt.src), LanguageForExtension(tt.ext))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range matches {
+				got = append(got, tt.src[m.Start:m.End])
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected matches (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMatchesEnvironment(t *testing.T) {
+	tmpl, err := ParseTemplate("fmt.Sprintf(:[format], :[_])")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := tmpl.Matches([]byte(`fmt.Sprintf("%d", x)`), LanguageForExtension(".go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	want := map[string]string{"format": `"%d"`}
+	if diff := cmp.Diff(want, matches[0].Environment); diff != "" {
+		t.Errorf("unexpected environment (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for _, template := range []string{"", "  ", ":[x~(]", ":[x~[a-z]"} {
+		if _, err := ParseTemplate(template); err == nil {
+			t.Errorf("ParseTemplate(%q): expected an error", template)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	got := Rewrite("log.Printf(:[format], :[[args]]) // :[missing]", map[string]string{
+		"format": `"%d"`,
+		"args":   "x",
+	})
+	want := `log.Printf("%d", x) // `
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
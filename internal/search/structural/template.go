@@ -0,0 +1,248 @@
+package structural
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Template is a parsed comby style match template. Text in a template matches
+// itself, whitespace matches any whitespace, and holes match parts of a file.
+// The supported holes are:
+//
+//	:[x]        any text, including newlines, in which delimiters are balanced
+//	:[[x]]      one or more word characters
+//	:[x.]       one or more non-whitespace characters
+//	:[ x]       one or more spaces or tabs
+//	:[x\n]      the rest of a line, including the newline
+//	:[x~regexp] the text matched by regexp
+//
+// Holes named "_" or with an empty name match independently of each other.
+// Holes with any other name must match the same text everywhere they appear.
+type Template struct {
+	tokens []token
+}
+
+type tokenKind int
+
+const (
+	// tokenText matches its text exactly.
+	tokenText tokenKind = iota
+	// tokenSpace matches any whitespace.
+	tokenSpace
+	tokenHole
+)
+
+type holeKind int
+
+const (
+	holeAny holeKind = iota
+	holeWord
+	holeNonSpace
+	holeSpace
+	holeLine
+	holeRegexp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+
+	// required is true if a tokenSpace must match at least one whitespace
+	// character, ie if it separates two words.
+	required bool
+
+	hole holeKind
+	name string
+	re   *regexp.Regexp
+}
+
+// ParseTemplate parses a comby style match template.
+func ParseTemplate(template string) (*Template, error) {
+	var (
+		tokens []token
+		text   strings.Builder
+	)
+	flush := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, token{kind: tokenText, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(template); {
+		if strings.HasPrefix(template[i:], ":[") {
+			tok, n, err := parseHole(template[i:])
+			if err != nil {
+				return nil, err
+			}
+			if n > 0 {
+				flush()
+				tokens = append(tokens, tok)
+				i += n
+				continue
+			}
+		}
+		if isSpace(template[i]) {
+			flush()
+			for i < len(template) && isSpace(template[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenSpace})
+			continue
+		}
+		text.WriteByte(template[i])
+		i++
+	}
+	flush()
+
+	// Leading and trailing whitespace in a template is insignificant.
+	for len(tokens) > 0 && tokens[0].kind == tokenSpace {
+		tokens = tokens[1:]
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].kind == tokenSpace {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty structural search pattern")
+	}
+
+	for i := range tokens {
+		if tokens[i].kind != tokenSpace {
+			continue
+		}
+		// Whitespace can only be left out of a file if that does not join
+		// two words. Space tokens are always surrounded by other tokens.
+		prev, next := tokens[i-1], tokens[i+1]
+		if prev.kind == tokenText && next.kind == tokenText {
+			r, _ := utf8.DecodeLastRuneInString(prev.text)
+			s, _ := utf8.DecodeRuneInString(next.text)
+			tokens[i].required = isWordRune(r) && isWordRune(s)
+		} else {
+			// A hole next to whitespace could match a word.
+			tokens[i].required = true
+		}
+	}
+
+	return &Template{tokens: tokens}, nil
+}
+
+// parseHole parses the hole at the start of s, which starts with ":[". It
+// returns the length of the hole in s, which is 0 if s does not start with
+// a hole.
+func parseHole(s string) (tok token, n int, err error) {
+	tok.kind = tokenHole
+	if strings.HasPrefix(s, ":[[") {
+		end := strings.Index(s, "]]")
+		if end < 0 || !isIdentifier(s[3:end]) {
+			return token{}, 0, nil
+		}
+		tok.hole = holeWord
+		tok.name = s[3:end]
+		tok.text = s[:end+2]
+		return tok, end + 2, nil
+	}
+
+	i := 2
+	if i < len(s) && s[i] == ' ' {
+		tok.hole = holeSpace
+		i++
+	}
+	start := i
+	for i < len(s) && isIdentifierByte(s[i]) {
+		i++
+	}
+	tok.name = s[start:i]
+	rest := s[i:]
+
+	switch {
+	case strings.HasPrefix(rest, "]"):
+		i++
+	case tok.hole == holeSpace:
+		return token{}, 0, nil
+	case strings.HasPrefix(rest, ".]"):
+		tok.hole = holeNonSpace
+		i += 2
+	case strings.HasPrefix(rest, `\n]`):
+		tok.hole = holeLine
+		i += 3
+	case strings.HasPrefix(rest, "~"):
+		end := regexpEnd(rest)
+		if end < 0 {
+			return token{}, 0, errors.Errorf("unterminated regular expression in hole %q", s)
+		}
+		tok.hole = holeRegexp
+		tok.re, err = regexp.Compile("^(?:" + rest[1:end] + ")")
+		if err != nil {
+			return token{}, 0, errors.Wrapf(err, "invalid regular expression in hole %q", s[:i+end+1])
+		}
+		i += end + 1
+	default:
+		return token{}, 0, nil
+	}
+	tok.text = s[:i]
+	return tok, i, nil
+}
+
+// regexpEnd returns the offset of the "]" which ends the regular expression
+// hole s, which starts with "~". Brackets of character classes in the
+// regular expression must be balanced.
+func regexpEnd(s string) int {
+	depth := 0
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// Rewrite returns rewrite with every hole replaced by the text the hole of
+// the same name matched in environment. Whitespace in rewrite is kept as is.
+func Rewrite(rewrite string, environment map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(rewrite); {
+		if strings.HasPrefix(rewrite[i:], ":[") {
+			if tok, n, err := parseHole(rewrite[i:]); err == nil && n > 0 {
+				b.WriteString(environment[tok.name])
+				i += n
+				continue
+			}
+		}
+		b.WriteByte(rewrite[i])
+		i++
+	}
+	return b.String()
+}
+
+func isIdentifier(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isIdentifierByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
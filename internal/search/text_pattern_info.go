@@ -9,11 +9,30 @@ func (p *TextPatternInfo) IsEmpty() bool {
 	return p.Pattern == "" && p.ExcludePattern == "" && len(p.IncludePatterns) == 0
 }
 
+// HasFilePredicates returns true if the files to search are restricted by
+// properties other than their path, see query.FilePredicate.
+func (p *TextPatternInfo) HasFilePredicates() bool {
+	return len(p.FileContentMustInclude) > 0 || len(p.FileContentMustExclude) > 0 ||
+		p.HasUnindexedFilePredicates()
+}
+
+// HasUnindexedFilePredicates returns true if the files to search are
+// restricted by their owners or size, which indexed search can't evaluate.
+func (p *TextPatternInfo) HasUnindexedFilePredicates() bool {
+	return len(p.FileOwnersMustInclude) > 0 || len(p.FileOwnersMustExclude) > 0 ||
+		p.FileSizeMin > 0 || p.FileSizeMax > 0
+}
+
 func (p *TextPatternInfo) Validate() error {
 	if p.IsRegExp {
 		if _, err := syntax.Parse(p.Pattern, syntax.Perl); err != nil {
 			return err
 		}
+		if p.NearPattern != "" {
+			if _, err := syntax.Parse(p.NearPattern, syntax.Perl); err != nil {
+				return err
+			}
+		}
 	}
 
 	if p.ExcludePattern != "" {
@@ -26,6 +45,13 @@ func (p *TextPatternInfo) Validate() error {
 			return err
 		}
 	}
+	for _, exprs := range [][]string{p.FileContentMustInclude, p.FileContentMustExclude} {
+		for _, expr := range exprs {
+			if _, err := syntax.Parse(expr, syntax.Perl); err != nil {
+				return err
+			}
+		}
+	}
 
 	return nil
 }
@@ -123,6 +123,30 @@ type TextPatternInfo struct {
 	FilePatternsReposMustInclude []string
 	FilePatternsReposMustExclude []string
 
+	// NearPattern is set for near(N) expressions like "lock near(5)
+	// unlock". Only lines matching Pattern at most NearDistance lines away
+	// from a line matching NearPattern, and vice versa, are matches.
+	// NearPattern is interpreted like Pattern.
+	NearPattern  string
+	NearDistance int
+
+	// FileContentMustInclude and FileContentMustExclude are regular
+	// expressions the content of a file must (not) match for the file to be
+	// searched. They come from file:contains(...) predicates.
+	FileContentMustInclude []string
+	FileContentMustExclude []string
+
+	// FileOwnersMustInclude and FileOwnersMustExclude are owners a file must
+	// (not) have according to the CODEOWNERS file of the repository. They
+	// come from file:has.owner(...) predicates.
+	FileOwnersMustInclude []string
+	FileOwnersMustExclude []string
+
+	// FileSizeMin and FileSizeMax are inclusive bounds on the size in bytes
+	// of the files to search. Zero means no bound.
+	FileSizeMin int64
+	FileSizeMax int64
+
 	PathPatternsAreCaseSensitive bool
 
 	PatternMatchesContent bool
@@ -143,6 +167,9 @@ func (p *TextPatternInfo) String() string {
 			args = append(args, "comby")
 		}
 	}
+	if p.NearPattern != "" {
+		args = append(args, fmt.Sprintf("near(%d):%q", p.NearDistance, p.NearPattern))
+	}
 	if p.IsWordMatch {
 		args = append(args, "word")
 	}
@@ -169,6 +196,25 @@ func (p *TextPatternInfo) String() string {
 		args = append(args, fmt.Sprintf("-repositoryPathPattern:%s", dec))
 	}
 
+	for _, inc := range p.FileContentMustInclude {
+		args = append(args, fmt.Sprintf("contains:%q", inc))
+	}
+	for _, dec := range p.FileContentMustExclude {
+		args = append(args, fmt.Sprintf("-contains:%q", dec))
+	}
+	for _, inc := range p.FileOwnersMustInclude {
+		args = append(args, fmt.Sprintf("owner:%s", inc))
+	}
+	for _, dec := range p.FileOwnersMustExclude {
+		args = append(args, fmt.Sprintf("-owner:%s", dec))
+	}
+	if p.FileSizeMin > 0 {
+		args = append(args, fmt.Sprintf("minsize:%d", p.FileSizeMin))
+	}
+	if p.FileSizeMax > 0 {
+		args = append(args, fmt.Sprintf("maxsize:%d", p.FileSizeMax))
+	}
+
 	path := "f"
 	if p.PathPatternsAreCaseSensitive {
 		path = "F"
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS search_job_results;
+DROP TABLE IF EXISTS search_job_repos;
+DROP TABLE IF EXISTS search_jobs;
+
+COMMIT;
@@ -0,0 +1,49 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS search_jobs (
+    -- Columns required by workerutil.Store
+    id serial PRIMARY KEY,
+    state text NOT NULL DEFAULT 'queued',
+    failure_message text,
+    started_at timestamp with time zone,
+    finished_at timestamp with time zone,
+    process_after timestamp with time zone,
+    num_resets integer NOT NULL DEFAULT 0,
+    -- Extra columns
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    query text NOT NULL,
+    pattern_type text NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS search_jobs_state ON search_jobs(state);
+CREATE INDEX IF NOT EXISTS search_jobs_user_id ON search_jobs(user_id);
+
+-- The repositories a search job searches, in order. Progress is tracked here
+-- instead of in search_jobs, because the worker holds a lock on the search
+-- job row for as long as the job runs.
+CREATE TABLE IF NOT EXISTS search_job_repos (
+    id serial PRIMARY KEY,
+    search_job_id integer NOT NULL REFERENCES search_jobs(id) ON DELETE CASCADE,
+    repo_id integer NOT NULL REFERENCES repo(id) ON DELETE CASCADE,
+    revs text NOT NULL DEFAULT '',
+    searched_at timestamp with time zone,
+    result_count integer NOT NULL DEFAULT 0,
+    limit_hit boolean NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS search_job_repos_search_job_id ON search_job_repos(search_job_id);
+
+CREATE TABLE IF NOT EXISTS search_job_results (
+    id bigserial PRIMARY KEY,
+    search_job_id integer NOT NULL REFERENCES search_jobs(id) ON DELETE CASCADE,
+    repo text NOT NULL,
+    commit text NOT NULL,
+    path text NOT NULL,
+    line integer NOT NULL,
+    preview text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS search_job_results_search_job_id ON search_job_results(search_job_id, id);
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS trig_delete_search_job_results ON search_jobs;
+DROP FUNCTION IF EXISTS delete_search_job_results();
+
+DELETE FROM search_job_repos WHERE search_job_id NOT IN (SELECT id FROM search_jobs);
+DELETE FROM search_job_results WHERE search_job_id NOT IN (SELECT id FROM search_jobs);
+
+ALTER TABLE search_job_repos ADD CONSTRAINT search_job_repos_search_job_id_fkey FOREIGN KEY (search_job_id) REFERENCES search_jobs(id) ON DELETE CASCADE;
+ALTER TABLE search_job_results ADD CONSTRAINT search_job_results_search_job_id_fkey FOREIGN KEY (search_job_id) REFERENCES search_jobs(id) ON DELETE CASCADE;
+
+COMMIT;
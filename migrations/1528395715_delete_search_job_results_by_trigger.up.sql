@@ -0,0 +1,27 @@
+BEGIN;
+
+-- The worker holds a FOR UPDATE lock on a search job while it runs, and it
+-- stores the progress and results of the job outside of that transaction.
+-- Inserting a row with a foreign key to search_jobs needs a KEY SHARE lock on
+-- the job, which conflicts with the worker's lock. The rows of a job are
+-- deleted by a trigger instead.
+ALTER TABLE search_job_repos DROP CONSTRAINT IF EXISTS search_job_repos_search_job_id_fkey;
+ALTER TABLE search_job_results DROP CONSTRAINT IF EXISTS search_job_results_search_job_id_fkey;
+
+CREATE OR REPLACE FUNCTION delete_search_job_results() RETURNS trigger
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    DELETE FROM search_job_repos WHERE search_job_id = OLD.id;
+    DELETE FROM search_job_results WHERE search_job_id = OLD.id;
+
+    RETURN OLD;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trig_delete_search_job_results ON search_jobs;
+CREATE TRIGGER trig_delete_search_job_results
+    AFTER DELETE ON search_jobs
+    FOR EACH ROW EXECUTE PROCEDURE delete_search_job_results();
+
+COMMIT;
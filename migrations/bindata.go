@@ -128,6 +128,10 @@
 // 1528395712_add_closing_flag_to_changesets.up.sql (105B)
 // 1528395713_add_trigger_to_delete_orphan_repos.down.sql (165B)
 // 1528395713_add_trigger_to_delete_orphan_repos.up.sql (853B)
+// 1528395714_create_search_jobs.down.sql (131B)
+// 1528395714_create_search_jobs.up.sql (1.796kB)
+// 1528395715_delete_search_job_results_by_trigger.down.sql (628B)
+// 1528395715_delete_search_job_results_by_trigger.up.sql (1.014kB)
 
 package migrations
 
@@ -2756,6 +2760,86 @@ func _1528395713_add_trigger_to_delete_orphan_reposUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __1528395714_create_search_jobsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x4e\x4d\x2c\x4a\xce\x88\xcf\xca\x4f\x8a\x2f\x4a\x2d\x2e\xcd\x29\x29\xb6\x26\x42\x61\x41\x3e\x61\x65\xc5\xd6\x5c\x5c\xce\xfe\xbe\xbe\x9e\x21\xd6\x5c\x80\x01\x00\xfb\xce\x0b\xec\x83\x00\x00\x00")
+
+func _1528395714_create_search_jobsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395714_create_search_jobsDownSql,
+		"1528395714_create_search_jobs.down.sql",
+	)
+}
+
+func _1528395714_create_search_jobsDownSql() (*asset, error) {
+	bytes, err := _1528395714_create_search_jobsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395714_create_search_jobs.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xe3, 0x64, 0x9, 0x63, 0x81, 0x84, 0x21, 0x89, 0xd0, 0xed, 0xe2, 0xca, 0x38, 0xdd, 0xf9, 0x81, 0xe5, 0x70, 0x57, 0xae, 0x0, 0xa, 0x90, 0xf, 0xcf, 0xa3, 0x75, 0xcb, 0x88, 0xf2, 0x7d, 0xa9}}
+	return a, nil
+}
+
+var __1528395714_create_search_jobsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x54\x4d\x53\x22\x41\x0c\xbd\xf3\x2b\x72\x13\xaa\xc0\xda\xbb\x27\x84\x76\x8b\x5a\x1c\x2c\x18\xab\xf4\x34\xd5\xcc\x04\xa6\xb5\xa7\x1b\x93\xb4\xe8\xfe\xfa\xad\xf9\x50\xf9\x5a\x99\xbd\xec\x09\x26\x79\x49\x3a\x79\x2f\xb9\x56\x3f\x27\xd1\x55\xa7\x33\x9a\xab\x61\xac\x20\x1e\x5e\x4f\x15\x4c\x6e\x20\x9a\xc5\xa0\x1e\x26\x8b\x78\x01\x8c\x9a\xd2\x3c\x79\xf2\x4b\x86\x6e\x07\x00\x60\x30\x80\x91\xb7\xa1\x70\x0c\x84\x2f\xc1\x10\x66\xb0\x7c\x87\xad\xa7\x67\xa4\x20\xc6\x5e\x2e\xc4\x13\x56\x58\x93\x01\x23\x19\x6d\xe1\x6e\x3e\xb9\x1d\xce\x1f\xe1\x97\x7a\xec\x57\x2e\x16\x2d\x08\x82\x6f\x52\x95\x8b\xee\xa7\x53\x18\xab\x9b\xe1\xfd\x34\x86\x8b\x97\x80\x01\xb3\x8b\x1a\xb9\xd2\xc6\x06\xc2\xa4\x40\x66\xbd\xae\x63\x3e\x73\x90\x60\x96\x68\x01\x31\x05\xb2\xe8\x62\x03\x5b\x23\x79\xf5\x09\xbf\xbd\xc3\x26\x85\x71\x86\xf3\x36\xc8\x0d\xf9\x14\x99\x13\xbd\x12\xa4\x33\x58\x17\x8a\x84\x90\x51\x18\x8c\x13\x5c\x23\x1d\xb7\xf2\xa3\xff\x31\x34\xf5\x26\xa4\x21\xad\x47\x57\x19\x03\x23\x25\x26\x3b\x0e\x9e\xab\x1b\x35\x57\xd1\x48\x2d\x2a\x0c\x77\x4d\xd6\x83\x59\x04\x63\x35\x55\xb1\x82\xd1\x70\x31\x1a\x8e\x55\x9d\xf9\x25\x20\xbd\xef\xcf\xb1\xe9\x44\x8b\x20\xb9\x44\xde\x37\x07\x73\xae\xfd\x29\xa1\x3e\x33\xbc\xe3\x76\x9c\xdf\x76\x7b\x9d\xde\x97\x66\x26\xd1\x58\x3d\xfc\x5d\x33\x49\x4d\xf3\x2c\xda\x35\x76\x2b\x63\xef\xaa\x6d\x8e\x8f\x39\x1d\x64\x69\xcc\xe5\x63\x06\x03\x88\x73\x04\xc2\x8d\x67\x23\x9e\x0c\x32\xe8\x06\x0c\x4f\x7e\xd9\xfc\x45\xee\x83\x71\xe0\x29\x43\xba\x84\x3b\xf2\x6b\x42\x66\x30\x0c\x42\x3a\x7d\xc6\x0c\x72\x24\xec\x0c\x06\x60\x1c\x0b\xea\x0c\xfc\xaa\x0c\xd8\xa9\xda\x87\x25\xa6\x3a\x30\x82\xe4\xd8\x88\x1e\x72\x6f\xb3\xb2\xa0\xf5\xe9\x33\x78\x57\xb9\xea\x98\x32\x57\x59\x9f\xfc\x16\x56\x9e\x40\x33\x58\xef\xd6\xe5\x6f\x09\xaa\x5c\xc1\xf1\x65\xbb\x15\x4c\xaa\x06\xa1\x7b\x76\xb7\xbe\x22\xce\xc8\x6b\xa7\xb3\xef\x44\x56\xd6\x3d\x97\xaa\xc4\x7c\x9f\xe3\x95\xf7\x75\xf8\xa9\xaa\x8b\x66\xd3\x1b\x96\x5a\xac\x29\x21\x07\x2b\x49\xea\x83\x93\xe3\x57\x1d\x2c\x9f\x35\x85\x91\x24\x37\x02\x4b\xef\x2d\x6a\x77\x8c\x5c\x69\xcb\xd8\x5e\xd7\x35\x11\xc9\x8e\xe1\x50\x9e\x35\xa2\xbb\x87\xe8\xb5\x3d\xb5\xe5\x55\x09\x56\x3e\x2e\xae\xc9\x60\x69\xd6\xff\x95\xec\x93\x07\xc3\x17\x85\x91\x53\x9e\x8d\x96\xfc\x94\xdd\x1a\x87\x47\x2f\x6a\x42\x08\x5f\x0d\x6e\xf7\xa3\xfe\x89\x80\x52\x00\xe7\x28\xa8\x30\xfb\x24\xf4\xa1\x21\x62\x76\x7b\x3b\x89\xaf\x3a\x7f\x06\x00\x2d\xc0\x98\xdf\x04\x07\x00\x00")
+
+func _1528395714_create_search_jobsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395714_create_search_jobsUpSql,
+		"1528395714_create_search_jobs.up.sql",
+	)
+}
+
+func _1528395714_create_search_jobsUpSql() (*asset, error) {
+	bytes, err := _1528395714_create_search_jobsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395714_create_search_jobs.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x1b, 0x6, 0x52, 0xa1, 0xdf, 0x75, 0xbe, 0xb5, 0xeb, 0xaf, 0xfe, 0xb8, 0xc3, 0xac, 0x59, 0x9e, 0x5f, 0x7b, 0xdb, 0xb8, 0x58, 0xd2, 0x53, 0xe3, 0x72, 0xe, 0x86, 0x90, 0xa7, 0xb9, 0x9f, 0x47}}
+	return a, nil
+}
+
+var __1528395715_delete_search_job_results_by_triggerDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x90\xbf\x4e\xc4\x30\x0c\x87\xf7\x3e\x85\xc7\xf6\x19\x3a\xe5\x52\xb7\x44\xf4\x1c\xe4\x18\x01\x53\x04\x24\x40\xe0\xa4\x43\x4d\x19\x78\x7b\x74\x57\x24\x72\xa0\x32\x20\xb1\xfa\xcf\xe7\xcf\xbf\x0d\x0e\x86\xda\xaa\xea\xd8\x5e\x80\xb0\x19\x06\x64\x30\x3d\xe0\xb5\x71\xe2\x60\x9e\xd2\xa3\x0f\x71\x17\xe7\xe8\x73\xbc\x9d\xee\x9f\xfc\xf3\xfe\xce\x4f\x31\xbf\xed\xe6\x0c\x96\xe0\xab\x9a\xdb\x85\xd2\x5f\x92\x16\x63\xa9\xc0\xac\x12\xea\xe6\x70\x1b\x47\x14\x84\x9e\xed\xb6\xc0\xf9\x29\xbe\xee\x33\x5c\x9d\x21\x63\x59\x4e\x01\xc8\x0a\x18\x82\xda\xe1\x88\x5a\x20\x85\xef\xbb\xb9\x69\xd7\xa9\xc7\xc3\x7f\xe7\x56\x6a\x14\x64\x10\xb5\x19\x4f\xf6\x17\x5d\xd5\x75\xa0\x2d\x39\x61\x65\x48\x7e\xf4\xcb\x08\x52\xf0\x0f\x2f\xf1\x1d\x7a\xcb\x68\x06\x82\x73\xbc\x81\xfa\xa4\xdf\x00\x63\x8f\x8c\xa4\xd1\x15\xa8\x5c\xa7\xd0\x1c\xb2\xff\x7c\x51\x2b\xa7\x55\x87\xed\xba\xd9\xf2\xf2\x6f\x6e\xc7\x89\xff\xb5\xab\xb4\xdd\x6e\x8d\xb4\xd5\xc7\x00\x16\x21\xd0\x29\x74\x02\x00\x00")
+
+func _1528395715_delete_search_job_results_by_triggerDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395715_delete_search_job_results_by_triggerDownSql,
+		"1528395715_delete_search_job_results_by_trigger.down.sql",
+	)
+}
+
+func _1528395715_delete_search_job_results_by_triggerDownSql() (*asset, error) {
+	bytes, err := _1528395715_delete_search_job_results_by_triggerDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395715_delete_search_job_results_by_trigger.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x60, 0xaa, 0x68, 0x2e, 0xc, 0x4f, 0xc0, 0x5f, 0x20, 0x0, 0x16, 0xa0, 0x63, 0xd0, 0xaf, 0x4e, 0xed, 0xa1, 0xa6, 0x5b, 0xae, 0xb7, 0x75, 0x60, 0x8a, 0x17, 0x74, 0xb2, 0x6c, 0x5a, 0xd2, 0x55}}
+	return a, nil
+}
+
+var __1528395715_delete_search_job_results_by_triggerUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x93\xcd\x6e\xe2\x3a\x1c\xc5\xf7\x7e\x8a\xb3\x40\xba\x77\xa4\x96\x17\x88\x66\x91\x26\x86\x46\x93\x26\xc8\x71\xd4\xce\x2a\x32\x89\x49\x5c\xa2\x98\xb1\x8d\x10\x6f\x3f\xb2\x81\x0a\xd4\x29\x33\xdb\xff\xc7\xcf\xe7\x1c\xdb\x4f\x74\x99\x15\x11\x21\x8f\x8f\xe0\x83\xc4\x41\x9b\xad\x34\x18\xf4\xd8\x59\x08\x2c\x4a\x86\x7a\x95\xc6\x9c\x62\xd4\xed\x16\x7a\x82\x80\x95\xc2\xb4\x03\xde\xf5\x1a\x87\x41\x8d\x12\xca\xc1\xec\x27\xfb\x00\x31\x75\x50\xce\xb3\xac\xd3\x46\x5a\xb8\x41\x62\x67\x74\x6f\xa4\xb5\xa1\x6b\xa4\xdd\x8f\xce\x42\x6f\x42\xcf\x33\xf4\xde\x59\xd5\xc9\x53\x49\x38\x38\x23\x26\x2b\x5a\xa7\xf4\x34\xf7\xa8\x6c\xb2\xd2\x38\x35\xf5\x10\x30\xfa\x80\x83\x72\x03\x04\x36\xda\x48\xd5\x4f\xd8\xca\x23\x9c\x3e\x8b\x6a\xde\xf5\xda\x62\x92\x32\xa8\xff\x41\x7f\xa2\x7a\x8e\xd9\x87\x78\x8f\x3b\x1f\xfb\xe0\xb5\xb7\x03\x5a\x3d\x6d\x46\xd5\x3a\x7b\xe2\xba\x8f\x0c\xfe\xb3\x61\x6b\x1e\x62\x31\xfa\x10\x34\x8b\xe0\x5a\x18\xe9\x49\x9d\x1c\xa5\x93\x1d\xd6\x47\x08\x38\xa3\xfa\x5e\x1a\xa8\xc9\x3a\x29\xba\x39\x89\x73\x4e\x19\x78\xfc\x94\xd3\x2b\x71\x8d\x91\x3b\x6d\x91\xb2\x72\x85\xa4\x2c\x2a\xce\xe2\xac\xe0\xc8\x16\xa0\x6f\x59\xc5\xab\x4f\xa3\xcd\x55\x41\x75\xcd\x66\x2b\x8f\xd1\xd7\xec\x53\xb8\xff\x48\x0f\xc3\x7f\xe4\x93\x84\x51\x7f\xe7\x25\x03\xa3\xab\x3c\x4e\x28\x16\x75\x91\xf0\xac\x2c\xce\xae\x9b\xcf\xa4\xff\xbf\x81\x51\x5e\xb3\xa2\xba\x84\x41\x00\x20\x8f\x8b\x65\x1d\x2f\x29\x76\xe3\xae\xb7\xbf\x46\x12\x57\x98\xcd\x48\x78\x77\x61\x20\xa5\x39\xe5\x14\x0b\x56\xbe\xdc\xea\xf3\x41\xbd\x3e\x53\x76\xe3\x51\x75\xf8\x8e\x32\x4f\xe7\xaa\x8b\xee\xaf\x07\x51\x7f\x01\x04\xc2\x49\xb5\xaf\x45\x84\x16\x69\x44\x66\xb3\x88\x90\x10\x22\x67\xd9\x72\x49\xd9\x55\x82\xde\x5a\xf3\x65\x06\x28\x8b\xab\xb3\x6c\x74\x09\xf2\xc2\xb9\xbf\x1d\xd4\xc4\x0b\xff\x6e\xce\xae\x6e\x71\xa1\xef\xbf\x24\x8d\x93\x67\xb0\xf2\x15\xf4\x8d\x26\x35\xa7\x58\xb1\x32\xa1\x69\xcd\xe8\xbd\xeb\x89\x08\x49\xca\x97\x97\x8c\x47\xe4\xf7\x00\x42\x25\x38\x78\xf6\x03\x00\x00")
+
+func _1528395715_delete_search_job_results_by_triggerUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395715_delete_search_job_results_by_triggerUpSql,
+		"1528395715_delete_search_job_results_by_trigger.up.sql",
+	)
+}
+
+func _1528395715_delete_search_job_results_by_triggerUpSql() (*asset, error) {
+	bytes, err := _1528395715_delete_search_job_results_by_triggerUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395715_delete_search_job_results_by_trigger.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xd4, 0xb0, 0x1c, 0x94, 0xc6, 0x97, 0xa5, 0x19, 0xd7, 0x2c, 0x62, 0x8c, 0x4e, 0x87, 0xe4, 0xeb, 0x28, 0x6d, 0x73, 0xa1, 0x12, 0x2a, 0x7c, 0x7b, 0xfe, 0x2a, 0x2, 0xac, 0x64, 0x8e, 0x9f, 0x92}}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -2975,6 +3059,10 @@ var _bindata = map[string]func() (*asset, error){
 	"1528395712_add_closing_flag_to_changesets.up.sql":                             _1528395712_add_closing_flag_to_changesetsUpSql,
 	"1528395713_add_trigger_to_delete_orphan_repos.down.sql":                       _1528395713_add_trigger_to_delete_orphan_reposDownSql,
 	"1528395713_add_trigger_to_delete_orphan_repos.up.sql":                         _1528395713_add_trigger_to_delete_orphan_reposUpSql,
+	"1528395714_create_search_jobs.down.sql":                                       _1528395714_create_search_jobsDownSql,
+	"1528395714_create_search_jobs.up.sql":                                         _1528395714_create_search_jobsUpSql,
+	"1528395715_delete_search_job_results_by_trigger.down.sql":                     _1528395715_delete_search_job_results_by_triggerDownSql,
+	"1528395715_delete_search_job_results_by_trigger.up.sql":                       _1528395715_delete_search_job_results_by_triggerUpSql,
 }
 
 // AssetDebug is true if the assets were built with the debug flag enabled.
@@ -3149,6 +3237,10 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"1528395712_add_closing_flag_to_changesets.up.sql":                             {_1528395712_add_closing_flag_to_changesetsUpSql, map[string]*bintree{}},
 	"1528395713_add_trigger_to_delete_orphan_repos.down.sql":                       {_1528395713_add_trigger_to_delete_orphan_reposDownSql, map[string]*bintree{}},
 	"1528395713_add_trigger_to_delete_orphan_repos.up.sql":                         {_1528395713_add_trigger_to_delete_orphan_reposUpSql, map[string]*bintree{}},
+	"1528395714_create_search_jobs.down.sql":                                       {_1528395714_create_search_jobsDownSql, map[string]*bintree{}},
+	"1528395714_create_search_jobs.up.sql":                                         {_1528395714_create_search_jobsUpSql, map[string]*bintree{}},
+	"1528395715_delete_search_job_results_by_trigger.down.sql":                     {_1528395715_delete_search_job_results_by_triggerDownSql, map[string]*bintree{}},
+	"1528395715_delete_search_job_results_by_trigger.up.sql":                       {_1528395715_delete_search_job_results_by_triggerUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory.
@@ -99,6 +99,51 @@ const AWSCodeCommitSchemaJSON = `{
         [{ "name": "go-monorepo" }, { "id": "f001337a-3450-46fd-b7d2-650c0EXAMPLE" }],
         [{ "name": "go-monorepo" }, { "name": "go-client" }]
       ]
+    },
+    "authorization": {
+      "title": "AWSCodeCommitAuthorization",
+      "description": "If non-null, enforces AWS CodeCommit repository permissions by evaluating the IAM policies of the IAM user of each Sourcegraph user. In addition to AWSCodeCommitReadOnly, the AWS access key must be allowed to call iam:GetUser, iam:ListUsers and iam:SimulatePrincipalPolicy.",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["accountID", "identityProvider"],
+      "properties": {
+        "accountID": {
+          "description": "The ID of the AWS account that owns the AWS CodeCommit repositories and IAM users.",
+          "type": "string",
+          "pattern": "^\\d{12}$",
+          "examples": ["123456789012"]
+        },
+        "identityProvider": {
+          "description": "The source of identity to use when computing permissions. This defines how to compute the IAM user to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes the names of IAM users are identical to Sourcegraph usernames and ` + "`" + `auth.enableUsernameChanges` + "`" + ` must be set to false for security reasons.",
+          "title": "AWSCodeCommitIdentityProvider",
+          "type": "object",
+          "required": ["type"],
+          "properties": {
+            "type": {
+              "type": "string",
+              "enum": ["username"]
+            }
+          },
+          "oneOf": [{ "$ref": "#/definitions/UsernameIdentity" }],
+          "!go": {
+            "taggedUnionType": true
+          }
+        }
+      }
+    }
+  },
+  "definitions": {
+    "UsernameIdentity": {
+      "title": "AWSCodeCommitUsernameIdentity",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["type"],
+      "properties": {
+        "type": {
+          "type": "string",
+          "const": "username"
+        }
+      }
     }
   }
 }
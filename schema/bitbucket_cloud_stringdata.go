@@ -114,6 +114,62 @@ const BitbucketCloudSchemaJSON = `{
         [{ "name": "myorg/myrepo" }, { "uuid": "{fceb73c7-cef6-4abe-956d-e471281126bc}" }],
         [{ "name": "myorg/myrepo" }, { "name": "myorg/myotherrepo" }, { "pattern": "^topsecretproject/.*" }]
       ]
+    },
+    "webhooks": {
+      "description": "An array of webhook configurations. Bitbucket Cloud doesn't sign webhook payloads, so the secret must be passed in the \"secret\" query parameter of the webhook URL.",
+      "type": "array",
+      "items": {
+        "type": "object",
+        "title": "BitbucketCloudWebhook",
+        "required": ["secret"],
+        "additionalProperties": false,
+        "properties": {
+          "secret": {
+            "description": "The secret used to authenticate incoming webhook requests",
+            "type": "string",
+            "minLength": 1
+          }
+        }
+      }
+    },
+    "authorization": {
+      "title": "BitbucketCloudAuthorization",
+      "description": "If non-null, enforces Bitbucket Cloud repository permissions. Requires the app password of a user who is an administrator of the configured teams (workspaces).",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["identityProvider"],
+      "properties": {
+        "identityProvider": {
+          "description": "The source of identity to use when computing permissions. This defines how to compute the Bitbucket Cloud identity to use for a given Sourcegraph user. When 'oauth' is used, only Bitbucket Cloud accounts linked to Sourcegraph users via OAuth are used. Bitbucket Cloud nicknames are chosen by their users and are therefore not used to identify accounts.",
+          "title": "BitbucketCloudIdentityProvider",
+          "type": "object",
+          "required": ["type"],
+          "properties": {
+            "type": {
+              "type": "string",
+              "enum": ["oauth"]
+            }
+          },
+          "oneOf": [{ "$ref": "#/definitions/OAuthIdentity" }],
+          "!go": {
+            "taggedUnionType": true
+          }
+        }
+      }
+    }
+  },
+  "definitions": {
+    "OAuthIdentity": {
+      "title": "BitbucketCloudOAuthIdentity",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["type"],
+      "properties": {
+        "type": {
+          "type": "string",
+          "const": "oauth"
+        }
+      }
     }
   }
 }
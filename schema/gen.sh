@@ -20,14 +20,18 @@ stringdata() {
 }
 
 stringdata aws_codecommit.schema.json AWSCodeCommitSchemaJSON
+stringdata azuredevops.schema.json AzureDevOpsSchemaJSON
 stringdata bitbucket_cloud.schema.json BitbucketCloudSchemaJSON
 stringdata bitbucket_server.schema.json BitbucketServerSchemaJSON
 stringdata campaign_spec.schema.json CampaignSpecSchemaJSON
 stringdata changeset_spec.schema.json ChangesetSpecSchemaJSON
 stringdata github.schema.json GitHubSchemaJSON
 stringdata gitlab.schema.json GitLabSchemaJSON
+stringdata gitea.schema.json GiteaSchemaJSON
 stringdata gitolite.schema.json GitoliteSchemaJSON
+stringdata mercurial.schema.json MercurialSchemaJSON
 stringdata other_external_service.schema.json OtherExternalServiceSchemaJSON
+stringdata perforce.schema.json PerforceSchemaJSON
 stringdata phabricator.schema.json PhabricatorSchemaJSON
 stringdata settings.schema.json SettingsSchemaJSON
 stringdata site.schema.json SiteSchemaJSON
@@ -8,10 +8,20 @@ import (
 	"fmt"
 )
 
+// AWSCodeCommitAuthorization description: If non-null, enforces AWS CodeCommit repository permissions by evaluating the IAM policies of the IAM user of each Sourcegraph user. In addition to AWSCodeCommitReadOnly, the AWS access key must be allowed to call iam:GetUser, iam:ListUsers and iam:SimulatePrincipalPolicy.
+type AWSCodeCommitAuthorization struct {
+	// AccountID description: The ID of the AWS account that owns the AWS CodeCommit repositories and IAM users.
+	AccountID string `json:"accountID"`
+	// IdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the IAM user to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes the names of IAM users are identical to Sourcegraph usernames and `auth.enableUsernameChanges` must be set to false for security reasons.
+	IdentityProvider AWSCodeCommitIdentityProvider `json:"identityProvider"`
+}
+
 // AWSCodeCommitConnection description: Configuration for a connection to AWS CodeCommit.
 type AWSCodeCommitConnection struct {
 	// AccessKeyID description: The AWS access key ID to use when listing and updating repositories from AWS CodeCommit. Must have the AWSCodeCommitReadOnly IAM policy.
 	AccessKeyID string `json:"accessKeyID"`
+	// Authorization description: If non-null, enforces AWS CodeCommit repository permissions by evaluating the IAM policies of the IAM user of each Sourcegraph user. In addition to AWSCodeCommitReadOnly, the AWS access key must be allowed to call iam:GetUser, iam:ListUsers and iam:SimulatePrincipalPolicy.
+	Authorization *AWSCodeCommitAuthorization `json:"authorization,omitempty"`
 	// Exclude description: A list of repositories to never mirror from AWS CodeCommit.
 	//
 	// Supports excluding by name ({"name": "git-codecommit.us-west-1.amazonaws.com/repo-name"}) or by ARN ({"id": "arn:aws:codecommit:us-west-1:999999999999:name"}).
@@ -46,6 +56,35 @@ type AWSCodeCommitGitCredentials struct {
 	Username string `json:"username"`
 }
 
+// AWSCodeCommitIdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the IAM user to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes the names of IAM users are identical to Sourcegraph usernames and `auth.enableUsernameChanges` must be set to false for security reasons.
+type AWSCodeCommitIdentityProvider struct {
+	Username *AWSCodeCommitUsernameIdentity
+}
+
+func (v AWSCodeCommitIdentityProvider) MarshalJSON() ([]byte, error) {
+	if v.Username != nil {
+		return json.Marshal(v.Username)
+	}
+	return nil, errors.New("tagged union type must have exactly 1 non-nil field value")
+}
+func (v *AWSCodeCommitIdentityProvider) UnmarshalJSON(data []byte) error {
+	var d struct {
+		DiscriminantProperty string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	switch d.DiscriminantProperty {
+	case "username":
+		return json.Unmarshal(data, &v.Username)
+	}
+	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"username"})
+}
+
+type AWSCodeCommitUsernameIdentity struct {
+	Type string `json:"type"`
+}
+
 // AuthAccessTokens description: Settings for access tokens, which enable external tools to access the Sourcegraph API with the privileges of the user.
 type AuthAccessTokens struct {
 	// Allow description: Allow or restrict the use of access tokens. The default is "all-users-create", which enables all users to create access tokens. Use "none" to disable access tokens entirely. Use "site-admin-create" to restrict creation of new tokens to admin users (existing tokens will still work until revoked).
@@ -111,12 +150,87 @@ func (v *AuthProviders) UnmarshalJSON(data []byte) error {
 	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"builtin", "saml", "openidconnect", "http-header", "github", "gitlab"})
 }
 
+// AzureDevOpsAuthorization description: If non-null, enforces Azure DevOps repository permissions. Members of a team of a private project are granted read access to all repositories of the project.
+type AzureDevOpsAuthorization struct {
+	// Domain description: The domain of the principal names of Azure DevOps users, such as example.com for the principal name alice@example.com.
+	Domain string `json:"domain"`
+	// IdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the Azure DevOps identity to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes that the principal name of the Azure DevOps user is the Sourcegraph username followed by "@" and the configured domain, and `auth.enableUsernameChanges` must be set to false for security reasons.
+	IdentityProvider AzureDevOpsIdentityProvider `json:"identityProvider"`
+}
+
+// AzureDevOpsConnection description: Configuration for a connection to Azure DevOps Services or Azure DevOps Server.
+type AzureDevOpsConnection struct {
+	// Authorization description: If non-null, enforces Azure DevOps repository permissions. Members of a team of a private project are granted read access to all repositories of the project.
+	Authorization *AzureDevOpsAuthorization `json:"authorization,omitempty"`
+	// Exclude description: A list of repositories to never mirror from Azure DevOps. Takes precedence over "orgs" and "projects" configuration.
+	//
+	// Supports excluding by name ({"name": "org/project/repo"}) or by ID ({"id": "d8b6bd34-0ab5-4b0a-b5b0-62b8b5a6c3b0"}).
+	Exclude []*ExcludedAzureDevOpsRepo `json:"exclude,omitempty"`
+	// GitURLType description: The type of Git URLs to use for cloning and fetching Git repositories on Azure DevOps.
+	//
+	// If "http", Sourcegraph will access Azure DevOps repositories using Git URLs of the form https://dev.azure.com/myorg/myproject/_git/myrepo.
+	//
+	// If "ssh", Sourcegraph will access Azure DevOps repositories using Git URLs of the form git@ssh.dev.azure.com:v3/myorg/myproject/myrepo. See the documentation for how to provide SSH private keys and known_hosts: https://docs.sourcegraph.com/admin/repo/auth#repositories-that-need-http-s-or-ssh-authentication.
+	GitURLType string `json:"gitURLType,omitempty"`
+	// Orgs description: An array of Azure DevOps organization names whose repositories should be mirrored on Sourcegraph. For Azure DevOps Server, these are the names of project collections.
+	Orgs []string `json:"orgs"`
+	// Projects description: An array of "org/project" strings identifying the projects whose repositories should be mirrored on Sourcegraph. If empty, the repositories of all projects of the configured organizations are mirrored.
+	Projects []string `json:"projects,omitempty"`
+	// RepositoryPathPattern description: The pattern used to generate the corresponding Sourcegraph repository name for an Azure DevOps repository. In the pattern, the variable "{host}" is replaced with the Azure DevOps URL's host (such as dev.azure.com), "{org}" with the organization name, "{project}" with the project name and "{repo}" with the repository name.
+	//
+	// For example, a repositoryPathPattern of "{host}/{org}/{project}/{repo}" would mean that the Azure DevOps repository at https://dev.azure.com/myorg/myproject/_git/myrepo is available on Sourcegraph at https://src.example.com/dev.azure.com/myorg/myproject/myrepo.
+	//
+	// It is important that the Sourcegraph repository name generated with this pattern be unique to this code host. If different code hosts generate repository names that collide, Sourcegraph's behavior is undefined.
+	RepositoryPathPattern string `json:"repositoryPathPattern,omitempty"`
+	// Token description: A personal access token (PAT) of an Azure DevOps user. The token needs the "Code (Read)" scope to mirror repositories, the "Code (Read & write)" scope to create pull requests with campaigns, and the "Project and Team (Read)" and "Identity (Read)" scopes if "authorization" is set. The user must have access to all repositories which should be mirrored.
+	Token string `json:"token"`
+	// Url description: URL of Azure DevOps. Use https://dev.azure.com for Azure DevOps Services. For Azure DevOps Server, use the URL of the server without the collection, such as https://ado.example.com/tfs.
+	Url string `json:"url"`
+}
+
+// AzureDevOpsIdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the Azure DevOps identity to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes that the principal name of the Azure DevOps user is the Sourcegraph username followed by "@" and the configured domain, and `auth.enableUsernameChanges` must be set to false for security reasons.
+type AzureDevOpsIdentityProvider struct {
+	Username *AzureDevOpsUsernameIdentity
+}
+
+func (v AzureDevOpsIdentityProvider) MarshalJSON() ([]byte, error) {
+	if v.Username != nil {
+		return json.Marshal(v.Username)
+	}
+	return nil, errors.New("tagged union type must have exactly 1 non-nil field value")
+}
+func (v *AzureDevOpsIdentityProvider) UnmarshalJSON(data []byte) error {
+	var d struct {
+		DiscriminantProperty string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	switch d.DiscriminantProperty {
+	case "username":
+		return json.Unmarshal(data, &v.Username)
+	}
+	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"username"})
+}
+
+type AzureDevOpsUsernameIdentity struct {
+	Type string `json:"type"`
+}
+
+// BitbucketCloudAuthorization description: If non-null, enforces Bitbucket Cloud repository permissions. Requires the app password of a user who is an administrator of the configured teams (workspaces).
+type BitbucketCloudAuthorization struct {
+	// IdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the Bitbucket Cloud identity to use for a given Sourcegraph user. When 'oauth' is used, only Bitbucket Cloud accounts linked to Sourcegraph users via OAuth are used. Bitbucket Cloud nicknames are chosen by their users and are therefore not used to identify accounts.
+	IdentityProvider BitbucketCloudIdentityProvider `json:"identityProvider"`
+}
+
 // BitbucketCloudConnection description: Configuration for a connection to Bitbucket Cloud.
 type BitbucketCloudConnection struct {
 	// ApiURL description: The API URL of Bitbucket Cloud, such as https://api.bitbucket.org. Generally, admin should not modify the value of this option because Bitbucket Cloud is a public hosting platform.
 	ApiURL string `json:"apiURL,omitempty"`
 	// AppPassword description: The app password to use when authenticating to the Bitbucket Cloud. Also set the corresponding "username" field.
 	AppPassword string `json:"appPassword"`
+	// Authorization description: If non-null, enforces Bitbucket Cloud repository permissions. Requires the app password of a user who is an administrator of the configured teams (workspaces).
+	Authorization *BitbucketCloudAuthorization `json:"authorization,omitempty"`
 	// Exclude description: A list of repositories to never mirror from Bitbucket Cloud. Takes precedence over "teams" configuration.
 	//
 	// Supports excluding by name ({"name": "myorg/myrepo"}) or by UUID ({"uuid": "{fceb73c7-cef6-4abe-956d-e471281126bd}"}).
@@ -143,6 +257,37 @@ type BitbucketCloudConnection struct {
 	Url string `json:"url"`
 	// Username description: The username to use when authenticating to the Bitbucket Cloud. Also set the corresponding "appPassword" field.
 	Username string `json:"username"`
+	// Webhooks description: An array of webhook configurations. Bitbucket Cloud doesn't sign webhook payloads, so the secret must be passed in the "secret" query parameter of the webhook URL.
+	Webhooks []*BitbucketCloudWebhook `json:"webhooks,omitempty"`
+}
+
+// BitbucketCloudIdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the Bitbucket Cloud identity to use for a given Sourcegraph user. When 'oauth' is used, only Bitbucket Cloud accounts linked to Sourcegraph users via OAuth are used. Bitbucket Cloud nicknames are chosen by their users and are therefore not used to identify accounts.
+type BitbucketCloudIdentityProvider struct {
+	Oauth *BitbucketCloudOAuthIdentity
+}
+
+func (v BitbucketCloudIdentityProvider) MarshalJSON() ([]byte, error) {
+	if v.Oauth != nil {
+		return json.Marshal(v.Oauth)
+	}
+	return nil, errors.New("tagged union type must have exactly 1 non-nil field value")
+}
+func (v *BitbucketCloudIdentityProvider) UnmarshalJSON(data []byte) error {
+	var d struct {
+		DiscriminantProperty string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	switch d.DiscriminantProperty {
+	case "oauth":
+		return json.Unmarshal(data, &v.Oauth)
+	}
+	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"oauth"})
+}
+
+type BitbucketCloudOAuthIdentity struct {
+	Type string `json:"type"`
 }
 
 // BitbucketCloudRateLimit description: Rate limit applied when making background API requests to Bitbucket Cloud.
@@ -152,6 +297,10 @@ type BitbucketCloudRateLimit struct {
 	// RequestsPerHour description: Requests per hour permitted. This is an average, calculated per second.
 	RequestsPerHour float64 `json:"requestsPerHour"`
 }
+type BitbucketCloudWebhook struct {
+	// Secret description: The secret used to authenticate incoming webhook requests
+	Secret string `json:"secret"`
+}
 
 // BitbucketServerAuthorization description: If non-null, enforces Bitbucket Server repository permissions.
 type BitbucketServerAuthorization struct {
@@ -366,6 +515,14 @@ type ExcludedAWSCodeCommitRepo struct {
 	// Name description: The name of an AWS CodeCommit repository ("repo-name") to exclude from mirroring.
 	Name string `json:"name,omitempty"`
 }
+type ExcludedAzureDevOpsRepo struct {
+	// Id description: The ID of an Azure DevOps repository (as returned by the Azure DevOps API) to exclude from mirroring. Use this to exclude the repository, even if renamed.
+	Id string `json:"id,omitempty"`
+	// Name description: The name of an Azure DevOps repository ("org/project/repo") to exclude from mirroring.
+	Name string `json:"name,omitempty"`
+	// Pattern description: Regular expression which matches against the name of an Azure DevOps repository ("org/project/repo").
+	Pattern string `json:"pattern,omitempty"`
+}
 type ExcludedBitbucketCloudRepo struct {
 	// Name description: The name of a Bitbucket Cloud repo ("myorg/myrepo") to exclude from mirroring.
 	Name string `json:"name,omitempty"`
@@ -400,6 +557,18 @@ type ExcludedGitLabProject struct {
 	// Name description: The name of a GitLab project ("group/name") to exclude from mirroring.
 	Name string `json:"name,omitempty"`
 }
+type ExcludedGiteaRepo struct {
+	// Archived description: If set to true, archived repositories will be excluded.
+	Archived bool `json:"archived,omitempty"`
+	// Forks description: If set to true, forks will be excluded.
+	Forks bool `json:"forks,omitempty"`
+	// Id description: The ID of a Gitea repository (as returned by the Gitea instance's API) to exclude from mirroring. Use this to exclude the repository, even if renamed.
+	Id int `json:"id,omitempty"`
+	// Name description: The name of a Gitea repository ("owner/name") to exclude from mirroring.
+	Name string `json:"name,omitempty"`
+	// Pattern description: Regular expression which matches against the name of a Gitea repository ("owner/name").
+	Pattern string `json:"pattern,omitempty"`
+}
 type ExcludedGitoliteRepo struct {
 	// Name description: The name of a Gitolite repo ("my-repo") to exclude from mirroring.
 	Name string `json:"name,omitempty"`
@@ -459,6 +628,16 @@ type ExternalIdentity struct {
 	GitlabProvider string `json:"gitlabProvider"`
 	Type           string `json:"type"`
 }
+type GitCloneStrategy struct {
+	// Blobless description: Clone without file contents (git clone --filter=blob:none). File contents are fetched from the code host when they are first read.
+	Blobless bool `json:"blobless,omitempty"`
+	// Depth description: Only clone and fetch this many commits of the history of each branch and tag (git clone --depth). Older commits can't be searched or browsed.
+	Depth int `json:"depth,omitempty"`
+	// Pattern description: Regular expression which matches the names of the repositories to use this strategy for.
+	Pattern string `json:"pattern"`
+	// SparsePaths description: Only fetch the contents of files in these directories. Implies blobless. Search only returns results in these directories, and reports the repositories as sparse.
+	SparsePaths []string `json:"sparsePaths,omitempty"`
+}
 
 // GitCommitDescription description: The Git commit to create with the changes.
 type GitCommitDescription struct {
@@ -641,6 +820,73 @@ type GitLabWebhook struct {
 	Secret string `json:"secret"`
 }
 
+// GiteaAuthorization description: If non-null, enforces Gitea repository permissions. Requires a token of a site admin.
+type GiteaAuthorization struct {
+	// IdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the Gitea identity to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes usernames are identical in Sourcegraph and Gitea accounts and `auth.enableUsernameChanges` must be set to false for security reasons.
+	IdentityProvider GiteaIdentityProvider `json:"identityProvider"`
+}
+
+// GiteaConnection description: Configuration for a connection to Gitea or Gogs.
+type GiteaConnection struct {
+	// Authorization description: If non-null, enforces Gitea repository permissions. Requires a token of a site admin.
+	Authorization *GiteaAuthorization `json:"authorization,omitempty"`
+	// Exclude description: A list of repositories to never mirror from this Gitea instance. Takes precedence over "orgs", "users" and "repositoryQuery" configuration.
+	//
+	// Supports excluding by name ({"name": "owner/name"}) or by ID ({"id": 42}).
+	Exclude []*ExcludedGiteaRepo `json:"exclude,omitempty"`
+	// GitURLType description: The type of Git URLs to use for cloning and fetching Git repositories on this Gitea instance.
+	//
+	// If "http", Sourcegraph will access Gitea repositories using Git URLs of the form http(s)://gitea.example.com/myteam/myproject.git (using https: if the Gitea instance uses HTTPS).
+	//
+	// If "ssh", Sourcegraph will access Gitea repositories using Git URLs of the form git@gitea.example.com:myteam/myproject.git. See the documentation for how to provide SSH private keys and known_hosts: https://docs.sourcegraph.com/admin/repo/auth#repositories-that-need-http-s-or-ssh-authentication.
+	GitURLType string `json:"gitURLType,omitempty"`
+	// Orgs description: An array of organization names identifying Gitea organizations whose repositories should be mirrored on Sourcegraph.
+	Orgs []string `json:"orgs,omitempty"`
+	// RepositoryPathPattern description: The pattern used to generate the corresponding Sourcegraph repository name for a Gitea repository. In the pattern, the variable "{host}" is replaced with the Gitea URL's host (such as gitea.example.com), and "{nameWithOwner}" is replaced with the Gitea repository's "owner/name" path (such as "myteam/myproject").
+	//
+	// For example, if your Gitea is https://gitea.example.com and your Sourcegraph is https://src.example.com, then a repositoryPathPattern of "{host}/{nameWithOwner}" would mean that a Gitea repository at https://gitea.example.com/myteam/myproject is available on Sourcegraph at https://src.example.com/gitea.example.com/myteam/myproject.
+	//
+	// It is important that the Sourcegraph repository name generated with this pattern be unique to this code host. If different code hosts generate repository names that collide, Sourcegraph's behavior is undefined.
+	RepositoryPathPattern string `json:"repositoryPathPattern,omitempty"`
+	// RepositoryQuery description: An array of strings specifying which Gitea repositories to mirror on Sourcegraph. Each string is a keyword query for the Gitea repository search API (https://try.gitea.io/api/swagger#/repository/repoSearch). The special string "all" matches all repositories visible to the token, and "none" matches no repositories.
+	RepositoryQuery []string `json:"repositoryQuery,omitempty"`
+	// Token description: An access token for a Gitea user, created in the user's settings under Applications. The token must have access to all repositories which should be mirrored. If "authorization" is set, the token must belong to a site admin.
+	Token string `json:"token"`
+	// Url description: URL of a Gitea instance, such as https://gitea.example.com.
+	Url string `json:"url"`
+	// Users description: An array of usernames identifying Gitea users whose repositories should be mirrored on Sourcegraph.
+	Users []string `json:"users,omitempty"`
+}
+
+// GiteaIdentityProvider description: The source of identity to use when computing permissions. This defines how to compute the Gitea identity to use for a given Sourcegraph user. When 'username' is used, Sourcegraph assumes usernames are identical in Sourcegraph and Gitea accounts and `auth.enableUsernameChanges` must be set to false for security reasons.
+type GiteaIdentityProvider struct {
+	Username *GiteaUsernameIdentity
+}
+
+func (v GiteaIdentityProvider) MarshalJSON() ([]byte, error) {
+	if v.Username != nil {
+		return json.Marshal(v.Username)
+	}
+	return nil, errors.New("tagged union type must have exactly 1 non-nil field value")
+}
+func (v *GiteaIdentityProvider) UnmarshalJSON(data []byte) error {
+	var d struct {
+		DiscriminantProperty string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	switch d.DiscriminantProperty {
+	case "username":
+		return json.Unmarshal(data, &v.Username)
+	}
+	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"username"})
+}
+
+type GiteaUsernameIdentity struct {
+	Type string `json:"type"`
+}
+
 // GitoliteConnection description: Configuration for a connection to Gitolite.
 type GitoliteConnection struct {
 	// Exclude description: A list of repositories to never mirror from this Gitolite instance. Supports excluding by exact name ({"name": "foo"}).
@@ -656,6 +902,16 @@ type GitoliteConnection struct {
 	// It is important that the Sourcegraph repository name generated with this prefix be unique to this code host. If different code hosts generate repository names that collide, Sourcegraph's behavior is undefined.
 	Prefix string `json:"prefix"`
 }
+type GitserverRepoQuota struct {
+	// Action description: What to do with repositories exceeding the quota. "skip" does not clone them (and removes them if they grow beyond maxRepoSizeMB), "shallow" clones only the most recent commit of each branch and tag. Repositories of other version control systems than git are always skipped.
+	Action string `json:"action,omitempty"`
+	// MaxRepoSizeMB description: The maximum size of a single repository on disk, in megabytes.
+	MaxRepoSizeMB int `json:"maxRepoSizeMB,omitempty"`
+	// MaxTotalSizeMB description: The maximum size of all repositories of the quota on a gitserver, in megabytes. Repositories which are already cloned are not removed when it is exceeded, but new repositories are handled according to action.
+	MaxTotalSizeMB int `json:"maxTotalSizeMB,omitempty"`
+	// Url description: The URL of the external service, such as https://github.com/. The quota applies to the repositories whose clone URL starts with it.
+	Url string `json:"url"`
+}
 
 // HTTPHeaderAuthProvider description: Configures the HTTP header authentication provider (which authenticates users by consulting an HTTP request header set by an authentication proxy such as https://github.com/bitly/oauth2_proxy).
 type HTTPHeaderAuthProvider struct {
@@ -715,6 +971,18 @@ type Log struct {
 	// Sentry description: Configuration for Sentry
 	Sentry *Sentry `json:"sentry,omitempty"`
 }
+
+// MercurialConnection description: Configuration for a connection to Mercurial repositories. gitserver converts each repository into a git repository with git-remote-hg, and converts new changesets incrementally.
+type MercurialConnection struct {
+	// Repos description: The paths of the repositories to convert, relative to url.
+	Repos []string `json:"repos"`
+	// RepositoryPathPattern description: The pattern used to generate the corresponding Sourcegraph repository name for the repositories. In the pattern, the variable "{base}" is replaced with the host and path of url, and "{repo}" is replaced with the repository path taken from the `repos` field.
+	//
+	// It is important that the Sourcegraph repository name generated with this pattern be unique to this code host. If different code hosts generate repository names that collide, Sourcegraph's behavior is undefined.
+	RepositoryPathPattern string `json:"repositoryPathPattern,omitempty"`
+	// Url description: The URL the repository paths in repos are relative to. It may contain credentials.
+	Url string `json:"url"`
+}
 type Notice struct {
 	// Dismissible description: Whether this notice can be dismissed (closed) by the user.
 	Dismissible bool `json:"dismissible,omitempty"`
@@ -895,6 +1163,24 @@ type ParentSourcegraph struct {
 	Url string `json:"url,omitempty"`
 }
 
+// PerforceConnection description: Configuration for a connection to Perforce depots. gitserver imports each depot into a git repository with git p4, and imports new changelists incrementally.
+type PerforceConnection struct {
+	// Depots description: The depot paths to import. Each depot path is imported as a repository.
+	Depots []string `json:"depots"`
+	// P4Passwd description: The password or ticket of p4.user (P4PASSWD).
+	P4Passwd string `json:"p4.passwd"`
+	// P4Port description: The address of the Perforce server (P4PORT). Prefix it with "ssl:" for servers which require SSL.
+	P4Port string `json:"p4.port"`
+	// P4User description: The user to authenticate to the Perforce server with (P4USER). It must be able to read the depots.
+	P4User string `json:"p4.user"`
+	// RepositoryPathPattern description: The pattern used to generate the corresponding Sourcegraph repository name for the depots. In the pattern, the variable "{host}" is replaced with the host name of p4.port, and "{depot}" is replaced with the depot path without its leading "//".
+	//
+	// For example, if p4.port is "perforce.example.com:1666" and depots contains "//depot/main", then a repositoryPathPattern of "{host}/{depot}" would mean that the depot is available on Sourcegraph at https://sourcegraph.example.com/perforce.example.com/depot/main.
+	//
+	// It is important that the Sourcegraph repository name generated with this pattern be unique to this code host. If different code hosts generate repository names that collide, Sourcegraph's behavior is undefined.
+	RepositoryPathPattern string `json:"repositoryPathPattern,omitempty"`
+}
+
 // PermissionsUserMapping description: Settings for Sourcegraph permissions, which allow the site admin to explicitly manage repository permissions via the GraphQL API. This setting cannot be enabled if repository permissions for any specific external service are enabled (i.e., when the external service's `authorization` field is set).
 type PermissionsUserMapping struct {
 	// BindID description: The type of identifier to identify a user. The default is "email", which uses the email address to identify a user. Use "username" to identify a user by their username. Changing this setting will erase any permissions created for users that do not yet exist.
@@ -1169,12 +1455,22 @@ type SiteConfiguration struct {
 	ExternalURL string `json:"externalURL,omitempty"`
 	// GitCloneURLToRepositoryName description: JSON array of configuration that maps from Git clone URL to repository name. Sourcegraph automatically resolves remote clone URLs to their proper code host. However, there may be non-remote clone URLs (e.g., in submodule declarations) that Sourcegraph cannot automatically map to a code host. In this case, use this field to specify the mapping. The mappings are tried in the order they are specified and take precedence over automatic mappings.
 	GitCloneURLToRepositoryName []*CloneURLToRepositoryName `json:"git.cloneURLToRepositoryName,omitempty"`
+	// GitCloneStrategies description: Clone strategies for very large repositories, which take too long to clone in full. The first strategy whose pattern matches the name of a repository is used to clone and fetch it. Other repositories are fully cloned. Changing the strategy of a repository only takes effect when it is recloned. Strategies do not change the fetch commands of customGitFetch, and are ignored if gitserver has SRC_GITSERVER_REFSPECS set.
+	GitCloneStrategies []*GitCloneStrategy `json:"gitCloneStrategies,omitempty"`
 	// GitMaxConcurrentClones description: Maximum number of git clone processes that will be run concurrently per gitserver to update repositories. Note: the global git update scheduler respects gitMaxConcurrentClones. However, we allow each gitserver to run upto gitMaxConcurrentClones to allow for urgent fetches. Urgent fetches are used when a user is browsing a PR and we do not have the commit yet.
 	GitMaxConcurrentClones int `json:"gitMaxConcurrentClones,omitempty"`
 	// GithubClientID description: Client ID for GitHub. (DEPRECATED)
 	GithubClientID string `json:"githubClientID,omitempty"`
 	// GithubClientSecret description: Client secret for GitHub. (DEPRECATED)
 	GithubClientSecret string `json:"githubClientSecret,omitempty"`
+	// GitserverExecAllowlist description: The git subcommands each service may run on gitserver, keyed by the name of the calling service (such as "frontend", "searcher" or "repo-updater"), which authenticates with its token in SRC_GITSERVER_SERVICE_TOKEN. The key "*" applies to services which are not listed or have no valid token, and the command "*" allows all commands. If unset, all services may run all commands. Denied commands are recorded in the gitserver exec audit log.
+	GitserverExecAllowlist map[string][]string `json:"gitserverExecAllowlist,omitempty"`
+	// GitserverRendezvousHashing description: Assign repositories to gitservers with rendezvous hashing, which only moves the repositories of added or removed gitservers. Otherwise repository names are hashed modulo the number of gitservers, which moves most repositories whenever gitservers are added or removed. Switching this on moves most repositories once, so first set SRC_GITSERVER_REBALANCE_FROM_PEERS=true on all gitservers to have them clone moved repositories from the gitserver which had them instead of from the code host.
+	GitserverRendezvousHashing bool `json:"gitserverRendezvousHashing,omitempty"`
+	// GitserverReplicationFactor description: Number of gitservers each repository is stored on. The first gitserver of a repository fetches it from the code host and replicates the changes to the others. Reads fail over to the other gitservers of a repository when its first gitserver is unavailable.
+	GitserverReplicationFactor int `json:"gitserverReplicationFactor,omitempty"`
+	// GitserverRepoQuotas description: Disk quotas for the repositories of external services on each gitserver. The first quota whose URL is a prefix of the clone URL of a repository (ignoring credentials) applies to it. Repositories which exceed maxRepoSizeMB, and new repositories once the repositories of the quota exceed maxTotalSizeMB, are either not cloned or shallow cloned.
+	GitserverRepoQuotas []*GitserverRepoQuota `json:"gitserverRepoQuotas,omitempty"`
 	// HtmlBodyBottom description: HTML to inject at the bottom of the `<body>` element on each page, for analytics scripts
 	HtmlBodyBottom string `json:"htmlBodyBottom,omitempty"`
 	// HtmlBodyTop description: HTML to inject at the top of the `<body>` element on each page, for analytics scripts
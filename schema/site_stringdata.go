@@ -341,6 +341,114 @@ const SiteSchemaJSON = `{
       "default": 5,
       "group": "External services"
     },
+    "gitserverRendezvousHashing": {
+      "description": "Assign repositories to gitservers with rendezvous hashing, which only moves the repositories of added or removed gitservers. Otherwise repository names are hashed modulo the number of gitservers, which moves most repositories whenever gitservers are added or removed. Switching this on moves most repositories once, so first set SRC_GITSERVER_REBALANCE_FROM_PEERS=true on all gitservers to have them clone moved repositories from the gitserver which had them instead of from the code host.",
+      "type": "boolean",
+      "default": false,
+      "group": "External services"
+    },
+    "gitserverReplicationFactor": {
+      "description": "Number of gitservers each repository is stored on. The first gitserver of a repository fetches it from the code host and replicates the changes to the others. Reads fail over to the other gitservers of a repository when its first gitserver is unavailable.",
+      "type": "integer",
+      "minimum": 1,
+      "default": 1,
+      "group": "External services"
+    },
+    "gitserverExecAllowlist": {
+      "description": "The git subcommands each service may run on gitserver, keyed by the name of the calling service (such as \"frontend\", \"searcher\" or \"repo-updater\"), which authenticates with its token in SRC_GITSERVER_SERVICE_TOKEN. The key \"*\" applies to services which are not listed or have no valid token, and the command \"*\" allows all commands. If unset, all services may run all commands. Denied commands are recorded in the gitserver exec audit log.",
+      "type": "object",
+      "additionalProperties": {
+        "type": "array",
+        "items": { "type": "string" }
+      },
+      "examples": [{ "searcher": ["archive", "rev-parse"], "*": ["*"] }],
+      "group": "Security"
+    },
+    "gitCloneStrategies": {
+      "description": "Clone strategies for very large repositories, which take too long to clone in full. The first strategy whose pattern matches the name of a repository is used to clone and fetch it. Other repositories are fully cloned. Changing the strategy of a repository only takes effect when it is recloned. Strategies do not change the fetch commands of customGitFetch, and are ignored if gitserver has SRC_GITSERVER_REFSPECS set.",
+      "type": "array",
+      "items": {
+        "title": "GitCloneStrategy",
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["pattern"],
+        "properties": {
+          "pattern": {
+            "description": "Regular expression which matches the names of the repositories to use this strategy for.",
+            "type": "string",
+            "format": "regex"
+          },
+          "blobless": {
+            "description": "Clone without file contents (git clone --filter=blob:none). File contents are fetched from the code host when they are first read.",
+            "type": "boolean"
+          },
+          "depth": {
+            "description": "Only clone and fetch this many commits of the history of each branch and tag (git clone --depth). Older commits can't be searched or browsed.",
+            "type": "integer",
+            "minimum": 1
+          },
+          "sparsePaths": {
+            "description": "Only fetch the contents of files in these directories. Implies blobless. Search only returns results in these directories, and reports the repositories as sparse.",
+            "type": "array",
+            "items": { "type": "string", "minLength": 1 }
+          }
+        }
+      },
+      "examples": [
+        [
+          {
+            "pattern": "^github\\.com/myorg/monorepo$",
+            "blobless": true,
+            "depth": 1000
+          }
+        ]
+      ],
+      "group": "External services"
+    },
+    "gitserverRepoQuotas": {
+      "description": "Disk quotas for the repositories of external services on each gitserver. The first quota whose URL is a prefix of the clone URL of a repository (ignoring credentials) applies to it. Repositories which exceed maxRepoSizeMB, and new repositories once the repositories of the quota exceed maxTotalSizeMB, are either not cloned or shallow cloned.",
+      "type": "array",
+      "items": {
+        "title": "GitserverRepoQuota",
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["url"],
+        "properties": {
+          "url": {
+            "description": "The URL of the external service, such as https://github.com/. The quota applies to the repositories whose clone URL starts with it.",
+            "type": "string",
+            "minLength": 1
+          },
+          "maxRepoSizeMB": {
+            "description": "The maximum size of a single repository on disk, in megabytes.",
+            "type": "integer",
+            "minimum": 1
+          },
+          "maxTotalSizeMB": {
+            "description": "The maximum size of all repositories of the quota on a gitserver, in megabytes. Repositories which are already cloned are not removed when it is exceeded, but new repositories are handled according to action.",
+            "type": "integer",
+            "minimum": 1
+          },
+          "action": {
+            "description": "What to do with repositories exceeding the quota. \"skip\" does not clone them (and removes them if they grow beyond maxRepoSizeMB), \"shallow\" clones only the most recent commit of each branch and tag. Repositories of other version control systems than git are always skipped.",
+            "type": "string",
+            "enum": ["skip", "shallow"],
+            "default": "skip"
+          }
+        }
+      },
+      "examples": [
+        [
+          {
+            "url": "https://github.com/",
+            "maxRepoSizeMB": 5000,
+            "maxTotalSizeMB": 500000,
+            "action": "shallow"
+          }
+        ]
+      ],
+      "group": "External services"
+    },
     "repoListUpdateInterval": {
       "description": "Interval (in minutes) for checking code hosts (such as GitHub, Gitolite, etc.) for new repositories.",
       "type": "integer",
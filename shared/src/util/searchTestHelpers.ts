@@ -124,6 +124,7 @@ export const SEARCH_RESULT = {
     missing: [] as IRepository[],
     cloning: [] as IRepository[],
     timedout: [] as IRepository[],
+    sparse: [] as IRepository[],
     indexUnavailable: false,
     dynamicFilters: [
         { value: 'file:\\.yml$', label: 'file:\\.yml$', count: 1, limitHit: false, kind: 'file' },
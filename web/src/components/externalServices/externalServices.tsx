@@ -7,12 +7,16 @@ import GitIcon from 'mdi-react/GitIcon'
 import GitLabIcon from 'mdi-react/GitlabIcon'
 import React from 'react'
 import awsCodeCommitSchemaJSON from '../../../../schema/aws_codecommit.schema.json'
+import azureDevOpsSchemaJSON from '../../../../schema/azuredevops.schema.json'
 import bitbucketCloudSchemaJSON from '../../../../schema/bitbucket_cloud.schema.json'
 import bitbucketServerSchemaJSON from '../../../../schema/bitbucket_server.schema.json'
+import giteaSchemaJSON from '../../../../schema/gitea.schema.json'
 import githubSchemaJSON from '../../../../schema/github.schema.json'
 import gitlabSchemaJSON from '../../../../schema/gitlab.schema.json'
 import gitoliteSchemaJSON from '../../../../schema/gitolite.schema.json'
+import mercurialSchemaJSON from '../../../../schema/mercurial.schema.json'
 import otherExternalServiceSchemaJSON from '../../../../schema/other_external_service.schema.json'
+import perforceSchemaJSON from '../../../../schema/perforce.schema.json'
 import phabricatorSchemaJSON from '../../../../schema/phabricator.schema.json'
 import { PhabricatorIcon } from '../../../../shared/src/components/icons'
 import { EditorAction } from '../../site-admin/configHelpers'
@@ -1057,6 +1061,257 @@ const PHABRICATOR_SERVICE: AddExternalServiceOptions = {
         },
     ],
 }
+const GITEA: AddExternalServiceOptions = {
+    kind: ExternalServiceKind.GITEA,
+    title: 'Gitea',
+    icon: GitIcon,
+    jsonSchema: giteaSchemaJSON,
+    defaultDisplayName: 'Gitea',
+    defaultConfig: `{
+  "url": "https://gitea.example.com",
+  "token": "<access token>",
+  "orgs": []
+}`,
+    instructions: (
+        <div>
+            <ol>
+                <li>
+                    In the configuration below, set <Field>url</Field> to the URL of the Gitea (or Gogs) instance.
+                </li>
+                <li>
+                    Create an access token in the Gitea user settings under <strong>Applications</strong> and set it
+                    as the <Field>token</Field>.
+                </li>
+                <li>
+                    Add the organizations or users whose repositories should be mirrored to the <Field>orgs</Field> or{' '}
+                    <Field>users</Field> arrays, or set <Field>repositoryQuery</Field> to <code>["all"]</code>.
+                </li>
+            </ol>
+            <p>
+                See{' '}
+                <a
+                    rel="noopener noreferrer"
+                    target="_blank"
+                    href="https://docs.sourcegraph.com/admin/external_service/gitea#configuration"
+                >
+                    the docs for more advanced options
+                </a>
+                .
+            </p>
+        </div>
+    ),
+    editorActions: [
+        {
+            id: 'addOrg',
+            label: 'Add an organization',
+            run: (config: string) => {
+                const value = '<organization name>'
+                const edits = setProperty(config, ['orgs', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'addUser',
+            label: 'Add a user',
+            run: (config: string) => {
+                const value = '<username>'
+                const edits = setProperty(config, ['users', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'excludeRepo',
+            label: 'Exclude a repository',
+            run: (config: string) => {
+                const value = { name: '<owner>/<repository>' }
+                const edits = setProperty(config, ['exclude', -1], value, defaultFormattingOptions)
+                return { edits, selectText: '<owner>/<repository>' }
+            },
+        },
+        {
+            id: 'enforcePermissions',
+            label: 'Enforce permissions',
+            run: (config: string) => {
+                const value = { identityProvider: { type: 'username' } }
+                const edits = setProperty(config, ['authorization'], value, defaultFormattingOptions)
+                return { edits, selectText: '"username"' }
+            },
+        },
+    ],
+}
+const AZURE_DEVOPS: AddExternalServiceOptions = {
+    kind: ExternalServiceKind.AZUREDEVOPS,
+    title: 'Azure DevOps',
+    icon: GitIcon,
+    jsonSchema: azureDevOpsSchemaJSON,
+    defaultDisplayName: 'Azure DevOps',
+    defaultConfig: `{
+  "url": "https://dev.azure.com",
+  "token": "<personal access token>",
+  "orgs": []
+}`,
+    instructions: (
+        <div>
+            <ol>
+                <li>
+                    If you use Azure DevOps Server, set <Field>url</Field> to the URL of its collection root, such as{' '}
+                    <code>https://ado.example.com/tfs/</code>.
+                </li>
+                <li>
+                    Create a personal access token with the <strong>Code (Read)</strong> scope, plus{' '}
+                    <strong>Code (Read &amp; write)</strong> for campaigns, and set it as the <Field>token</Field>.
+                </li>
+                <li>
+                    Add the organizations whose repositories should be mirrored to the <Field>orgs</Field> array, or
+                    restrict mirroring to some projects with <Field>projects</Field>.
+                </li>
+            </ol>
+            <p>
+                See{' '}
+                <a
+                    rel="noopener noreferrer"
+                    target="_blank"
+                    href="https://docs.sourcegraph.com/admin/external_service/azuredevops#configuration"
+                >
+                    the docs for more advanced options
+                </a>
+                .
+            </p>
+        </div>
+    ),
+    editorActions: [
+        {
+            id: 'addOrg',
+            label: 'Add an organization',
+            run: (config: string) => {
+                const value = '<organization name>'
+                const edits = setProperty(config, ['orgs', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'addProject',
+            label: 'Add a project',
+            run: (config: string) => {
+                const value = '<organization>/<project>'
+                const edits = setProperty(config, ['projects', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+        {
+            id: 'excludeRepo',
+            label: 'Exclude a repository',
+            run: (config: string) => {
+                const value = { name: '<organization>/<project>/<repository>' }
+                const edits = setProperty(config, ['exclude', -1], value, defaultFormattingOptions)
+                return { edits, selectText: '<organization>/<project>/<repository>' }
+            },
+        },
+        {
+            id: 'enforcePermissions',
+            label: 'Enforce permissions',
+            run: (config: string) => {
+                const value = { identityProvider: { type: 'username' }, domain: '<domain>' }
+                const edits = setProperty(config, ['authorization'], value, defaultFormattingOptions)
+                return { edits, selectText: '<domain>' }
+            },
+        },
+    ],
+}
+const MERCURIAL: AddExternalServiceOptions = {
+    kind: ExternalServiceKind.MERCURIAL,
+    title: 'Mercurial',
+    icon: GitIcon,
+    jsonSchema: mercurialSchemaJSON,
+    defaultDisplayName: 'Mercurial',
+    defaultConfig: `{
+  "url": "https://hg.example.com",
+  "repos": []
+}`,
+    instructions: (
+        <div>
+            <ol>
+                <li>
+                    In the configuration below, set <Field>url</Field> to the URL of the Mercurial server.
+                </li>
+                <li>
+                    Add the paths of the repositories on the Mercurial server to the <Field>repos</Field> array.
+                </li>
+            </ol>
+            <p>
+                See{' '}
+                <a
+                    rel="noopener noreferrer"
+                    target="_blank"
+                    href="https://docs.sourcegraph.com/admin/external_service/mercurial#configuration"
+                >
+                    the docs for more advanced options
+                </a>
+                .
+            </p>
+        </div>
+    ),
+    editorActions: [
+        {
+            id: 'addRepo',
+            label: 'Add a repository',
+            run: (config: string) => {
+                const value = 'path/to/repo'
+                const edits = setProperty(config, ['repos', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+    ],
+}
+const PERFORCE: AddExternalServiceOptions = {
+    kind: ExternalServiceKind.PERFORCE,
+    title: 'Perforce',
+    icon: GitIcon,
+    jsonSchema: perforceSchemaJSON,
+    defaultDisplayName: 'Perforce',
+    defaultConfig: `{
+  "p4.port": "perforce.example.com:1666",
+  "p4.user": "<user>",
+  "p4.passwd": "<password>",
+  "depots": []
+}`,
+    instructions: (
+        <div>
+            <ol>
+                <li>
+                    In the configuration below, set <Field>p4.port</Field>, <Field>p4.user</Field> and{' '}
+                    <Field>p4.passwd</Field> to the connection settings of the Perforce server. The user must be
+                    able to read the depots.
+                </li>
+                <li>
+                    Add the depots to sync, such as <code>//depot/main</code>, to the <Field>depots</Field> array.
+                </li>
+            </ol>
+            <p>
+                See{' '}
+                <a
+                    rel="noopener noreferrer"
+                    target="_blank"
+                    href="https://docs.sourcegraph.com/admin/external_service/perforce#configuration"
+                >
+                    the docs for more advanced options
+                </a>
+                .
+            </p>
+        </div>
+    ),
+    editorActions: [
+        {
+            id: 'addDepot',
+            label: 'Add a depot',
+            run: (config: string) => {
+                const value = '//depot/path'
+                const edits = setProperty(config, ['depots', -1], value, defaultFormattingOptions)
+                return { edits, selectText: value }
+            },
+        },
+    ],
+}
 const GENERIC_GIT: AddExternalServiceOptions = {
     kind: ExternalServiceKind.OTHER,
     title: 'Generic Git host',
@@ -1121,8 +1376,12 @@ export const codeHostExternalServices: Record<string, AddExternalServiceOptions>
     bitbucket: BITBUCKET_CLOUD,
     bitbucketserver: BITBUCKET_SERVER,
     aws_codecommit: AWS_CODE_COMMIT,
+    azuredevops: AZURE_DEVOPS,
     srcservegit: SRC_SERVE_GIT,
     gitolite: GITOLITE,
+    gitea: GITEA,
+    mercurial: MERCURIAL,
+    perforce: PERFORCE,
     git: GENERIC_GIT,
 }
 
@@ -1141,7 +1400,11 @@ export const defaultExternalServices: Record<ExternalServiceKind, AddExternalSer
     [ExternalServiceKind.BITBUCKETSERVER]: BITBUCKET_SERVER,
     [ExternalServiceKind.GITLAB]: GITLAB_DOTCOM,
     [ExternalServiceKind.GITOLITE]: GITOLITE,
+    [ExternalServiceKind.GITEA]: GITEA,
+    [ExternalServiceKind.MERCURIAL]: MERCURIAL,
+    [ExternalServiceKind.PERFORCE]: PERFORCE,
     [ExternalServiceKind.PHABRICATOR]: PHABRICATOR_SERVICE,
     [ExternalServiceKind.OTHER]: GENERIC_GIT,
     [ExternalServiceKind.AWSCODECOMMIT]: AWS_CODE_COMMIT,
+    [ExternalServiceKind.AZUREDEVOPS]: AZURE_DEVOPS,
 }
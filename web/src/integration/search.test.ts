@@ -19,6 +19,7 @@ const searchResults = (): SearchResult => ({
             cloning: [],
             repositoriesCount: 372,
             timedout: [],
+            sparse: [],
             indexUnavailable: false,
             dynamicFilters: [
                 {
@@ -54,6 +54,9 @@ export function search(
                                 timedout {
                                     name
                                 }
+                                sparse {
+                                    name
+                                }
                                 indexUnavailable
                                 dynamicFilters {
                                     value
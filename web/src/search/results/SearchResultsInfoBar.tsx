@@ -95,6 +95,7 @@ export const SearchResultsInfoBar: React.FunctionComponent<SearchResultsInfoBarP
                 </small>
             )}
             {(props.results.timedout.length > 0 ||
+                props.results.sparse.length > 0 ||
                 props.results.cloning.length > 0 ||
                 props.results.results.length > 0 ||
                 props.results.missing.length > 0 ||
@@ -171,6 +172,19 @@ export const SearchResultsInfoBar: React.FunctionComponent<SearchResultsInfoBarP
                             </div>
                         )}
 
+                        {props.results.sparse.length > 0 && (
+                            <div
+                                className="search-results-info-bar__notice"
+                                data-tooltip={props.results.sparse.map(repo => repo.name).join('\n')}
+                            >
+                                <span>
+                                    <AlertCircleIcon className="icon-inline" /> {props.results.sparse.length}{' '}
+                                    {pluralize('repository', props.results.sparse.length, 'repositories')} only
+                                    partially searched (sparse clones)
+                                </span>
+                            </div>
+                        )}
+
                         {props.results.cloning.length > 0 && (
                             <div
                                 className="search-results-info-bar__notice"
@@ -3,12 +3,16 @@ import { fetchAllConfigAndSettings, fetchMonitoringStats } from './backend'
 import React, { useMemo } from 'react'
 import { DynamicallyImportedMonacoSettingsEditor } from '../settings/DynamicallyImportedMonacoSettingsEditor'
 import awsCodeCommitJSON from '../../../schema/aws_codecommit.schema.json'
+import azureDevOpsSchemaJSON from '../../../schema/azuredevops.schema.json'
 import bitbucketCloudSchemaJSON from '../../../schema/bitbucket_cloud.schema.json'
 import bitbucketServerSchemaJSON from '../../../schema/bitbucket_server.schema.json'
+import giteaSchemaJSON from '../../../schema/gitea.schema.json'
 import githubSchemaJSON from '../../../schema/github.schema.json'
 import gitlabSchemaJSON from '../../../schema/gitlab.schema.json'
 import gitoliteSchemaJSON from '../../../schema/gitolite.schema.json'
+import mercurialSchemaJSON from '../../../schema/mercurial.schema.json'
 import otherExternalServiceSchemaJSON from '../../../schema/other_external_service.schema.json'
+import perforceSchemaJSON from '../../../schema/perforce.schema.json'
 import phabricatorSchemaJSON from '../../../schema/phabricator.schema.json'
 import settingsSchemaJSON from '../../../schema/settings.schema.json'
 import siteSchemaJSON from '../../../schema/site.schema.json'
@@ -31,11 +35,15 @@ interface JSONSchema {
 
 const externalServices: Record<ExternalServiceKind, JSONSchema> = {
     AWSCODECOMMIT: awsCodeCommitJSON,
+    AZUREDEVOPS: azureDevOpsSchemaJSON,
     BITBUCKETCLOUD: bitbucketCloudSchemaJSON,
     BITBUCKETSERVER: bitbucketServerSchemaJSON,
+    GITEA: giteaSchemaJSON,
     GITHUB: githubSchemaJSON,
     GITLAB: gitlabSchemaJSON,
     GITOLITE: gitoliteSchemaJSON,
+    MERCURIAL: mercurialSchemaJSON,
+    PERFORCE: perforceSchemaJSON,
     OTHER: otherExternalServiceSchemaJSON,
     PHABRICATOR: phabricatorSchemaJSON,
 }